@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// authCmd groups the commands managing the tokens of the git hosts
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Store git host tokens in the OS keychain",
+}
+
+// authLoginCmd stores the token of a host in the OS keychain
+var authLoginCmd = &cobra.Command{
+	Use:   "login <host>",
+	Short: "Store a personal access token for a git host in the OS keychain",
+	Long: `Store a personal access token in the OS keychain: the macOS Keychain, the
+Windows Credential Manager, or the Secret Service (GNOME Keyring, KWallet)
+through secret-tool on Linux. The network operations send it to the HTTPS
+remotes of the host, so no token has to sit in a config file.
+
+The token is read from stdin. On a terminal it is prompted for without being
+echoed.`,
+	Example: `  goktor auth login github.com
+  echo "$GITLAB_TOKEN" | goktor auth login gitlab.mycorp.com`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host := args[0]
+		token, err := readToken(cmd, host)
+		if err != nil {
+			return err
+		}
+		if err := service.StoreToken(cmd.Context(), host, token); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Token for %s stored in the keychain\n", host)
+		return nil
+	},
+}
+
+// authLogoutCmd removes the token of a host from the OS keychain
+var authLogoutCmd = &cobra.Command{
+	Use:          "logout <host>",
+	Short:        "Remove the token of a git host from the OS keychain",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host := args[0]
+		if err := service.DeleteToken(cmd.Context(), host); err != nil {
+			if errors.Is(err, service.ErrTokenNotFound) {
+				return fmt.Errorf("no token stored for %s", host)
+			}
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Token for %s removed from the keychain\n", host)
+		return nil
+	},
+}
+
+// readToken reads the token of host from the first line of stdin, prompting
+// for it without echo on a terminal
+func readToken(cmd *cobra.Command, host string) (string, error) {
+	in := cmd.InOrStdin()
+	if in == os.Stdin && isTerminal(os.Stdin) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Token for %s: ", host)
+		if restore, err := disableEcho(os.Stdin); err == nil {
+			defer fmt.Fprintln(cmd.ErrOrStderr())
+			defer restore()
+		}
+	}
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read the token: %w", err)
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", errors.New("no token given on stdin")
+	}
+	return token, nil
+}
+
+func init() {
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestReadToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "line", input: "ghp_secret\n", want: "ghp_secret"},
+		{name: "no newline", input: "  ghp_secret ", want: "ghp_secret"},
+		{name: "first line only", input: "ghp_secret\nextra\n", want: "ghp_secret"},
+		{name: "empty", input: "", wantErr: true},
+		{name: "blank", input: " \n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.SetIn(strings.NewReader(tt.input))
+			got, err := readToken(cmd, "github.com")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// cleanCmd deletes the content of temporary and cache directories once confirmed
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Delete temporary files, browser caches and package caches",
+	Long: `Delete the content of well-known temporary and cache directories, grouped in
+profiles:
+
+  temp           the temporary directories (%TEMP%, $TMPDIR, /tmp, /var/tmp)
+  browser-cache  the disk caches of Chrome, Chromium, Edge, Firefox and Safari
+  package-cache  the npm, yarn and pip caches, the Go module and build caches
+
+Only the directories of the current platform that exist are considered. The
+reclaimable space is reported per directory, then their content is deleted once
+confirmed. Use --older-than to keep the recently modified entries. Sockets, pipes
+and devices are never deleted. temp keeps the entries modified in the last day,
+the entries of root and other users, and the session directories .X11-unix,
+ssh-*, tmux-* and systemd-private-*.
+
+Add profiles, or targets to the built-in ones, in the "clean" section of the
+config file. Paths accept ~, ${VAR} environment variables and glob patterns;
+min_age, keep (entry name patterns) and shared (only clean the entries of the
+current user) make a profile more careful:
+
+  {"clean": {"profiles": [{"name": "package-cache", "targets": [{"path": "~/.gradle/caches", "os": ["linux"]}]}]}}
+
+Protected paths are never deleted unless --force-unsafe is set.`,
+	Example: `  goktor clean --list
+  goktor clean --profile temp --older-than 7d
+  goktor clean --profile browser-cache,package-cache --dry-run`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, _ := cmd.Flags().GetStringSlice("profile")
+		list, _ := cmd.Flags().GetBool("list")
+		yes, _ := cmd.Flags().GetBool("yes")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		profiles, err := service.NewCleanProfiles(GlobalConfig.Clean)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if list {
+			printCleanProfiles(out, profiles)
+			return nil
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no profile given, use --profile or --list to show the available ones")
+		}
+
+		var olderThan time.Duration
+		if value, _ := cmd.Flags().GetString("older-than"); value != "" {
+			if olderThan, err = service.ParseAge(value); err != nil {
+				return fmt.Errorf("invalid --older-than: %w", err)
+			}
+		}
+
+		var items []service.CleanItem
+		now := time.Now()
+		for _, name := range names {
+			profile, err := service.FindCleanProfile(profiles, name)
+			if err != nil {
+				return err
+			}
+			found, err := service.FindCleanItems(cmd.Context(), profile, olderThan, now)
+			if err != nil {
+				return err
+			}
+			items = append(items, found...)
+		}
+		GlobalUsage.Count("entries", len(items))
+
+		printCleanTargets(out, items)
+		if len(items) == 0 || dryRun {
+			return nil
+		}
+
+		if !yes {
+			if !isTerminal(os.Stdin) {
+				return fmt.Errorf("refusing to delete without confirmation, add --yes when stdin is not a terminal")
+			}
+			if !confirm(cmd.InOrStdin(), out, fmt.Sprintf("Delete %d entries?", len(items))) {
+				return nil
+			}
+		}
+
+		guard, err := newPathGuard(cmd)
+		if err != nil {
+			return err
+		}
+		removed, err := service.RemoveCleanItems(cmd.Context(), items, guard)
+		var reclaimed model.FileSystem
+		for _, item := range removed {
+			reclaimed.Size += item.Size
+		}
+		GlobalUsage.Count("removed", len(removed))
+		fmt.Fprintf(out, "Removed %d of %d entries, %s reclaimed\n", len(removed), len(items), reclaimed.GetFormattedSize())
+		return partialError(err)
+	},
+}
+
+// printCleanProfiles prints the profiles with the directories they clean on this platform
+func printCleanProfiles(out io.Writer, profiles []service.CleanProfile) {
+	for _, profile := range profiles {
+		fmt.Fprintf(out, "%s: %s\n", profile.Name, profile.Description)
+		dirs := profile.TargetDirs()
+		if len(dirs) == 0 {
+			fmt.Fprintln(out, "  (no directory on this system)")
+		}
+		for _, dir := range dirs {
+			fmt.Fprintf(out, "  %s\n", dir)
+		}
+	}
+}
+
+// printCleanTargets prints the reclaimable space of every target directory and the total
+func printCleanTargets(out io.Writer, items []service.CleanItem) {
+	if len(items) == 0 {
+		fmt.Fprintln(out, "Nothing to clean")
+		return
+	}
+
+	type target struct {
+		profile, path string
+		size          int64
+		entries       int
+	}
+	var targets []*target
+	byPath := make(map[string]*target)
+	var total model.FileSystem
+	for _, item := range items {
+		t, ok := byPath[item.Target]
+		if !ok {
+			t = &target{profile: item.Profile, path: item.Target}
+			byPath[item.Target] = t
+			targets = append(targets, t)
+		}
+		t.size += item.Size
+		t.entries++
+		total.Size += item.Size
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tTARGET\tRECLAIMABLE\tENTRIES")
+	for _, t := range targets {
+		size := model.FileSystem{Size: t.size}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", t.profile, t.path, size.GetFormattedSize(), t.entries)
+	}
+	_ = w.Flush()
+	fmt.Fprintf(out, "\n%d entries, %s reclaimable\n", len(items), total.GetFormattedSize())
+}
+
+func init() {
+	cleanCmd.Flags().StringSliceP("profile", "p", nil, "profiles to clean: temp, browser-cache, package-cache or a configured one")
+	cleanCmd.Flags().Bool("list", false, "list the profiles and the directories they clean")
+	cleanCmd.Flags().BoolP("yes", "y", false, "delete without asking for confirmation")
+	cleanCmd.Flags().Bool("dry-run", false, "only report the reclaimable space")
+	cleanCmd.Flags().String("older-than", "", "only delete entries last modified more than this long ago (e.g. 7d, 2w)")
+	addForceUnsafeFlag(cleanCmd)
+	cleanCmd.MarkFlagsMutuallyExclusive("profile", "list")
+}
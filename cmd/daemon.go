@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nanaki-93/goktor/cmd/mr_repo"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// daemonCmd runs the configured jobs on a schedule
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Fetch repositories and refresh scan caches on a schedule",
+	Long: `Run jobs at once, then every --every interval until interrupted, so the
+repositories are fresh and the scans fast when you sit down to work:
+
+  fetch  fetch origin of every repository of a workspace of the config, or of
+         the repositories in a directory, without touching the branches
+  scan   scan a directory to refresh the scan cache of du and folder-list
+
+Jobs come from the "daemon" section of the config and from the --fetch,
+--fetch-dir and --scan flags. Every job result is logged; with --status-file
+the outcome of the last run is also written as JSON.
+
+  {"daemon": {"every": "30m", "status_file": "~/.goktor/daemon.json",
+              "jobs": [{"kind": "fetch", "workspace": "work"}, {"kind": "scan", "dir": "~"}]}}
+
+Run it from a login item, a systemd user unit or a scheduled task, or use
+--once from cron.`,
+	Example: `  goktor daemon --every 30m --fetch work --scan ~/projects
+  goktor daemon --once --fetch-dir ~/src --status-file ~/.goktor/daemon.json`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := daemonConfigFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if len(config.Jobs) == 0 {
+			return fmt.Errorf("no job to run, add daemon.jobs to the config or use --fetch, --fetch-dir or --scan")
+		}
+		every, err := config.Interval()
+		if err != nil {
+			return fmt.Errorf("invalid --every: %w", err)
+		}
+		statusPath, err := config.StatusPath()
+		if err != nil {
+			return err
+		}
+		once, _ := cmd.Flags().GetBool("once")
+
+		status := service.DaemonStatus{PID: os.Getpid(), Started: time.Now(), Every: every}
+		run := func(ctx context.Context, next time.Time) {
+			status.Runs++
+			status.LastRun = time.Now()
+			status.Jobs = nil
+			status.Failures = 0
+			for _, job := range config.Jobs {
+				if ctx.Err() != nil {
+					break
+				}
+				result := runDaemonJob(ctx, job)
+				if result.Error != "" || result.Failed > 0 {
+					status.Failures++
+				}
+				status.Jobs = append(status.Jobs, result)
+			}
+			if !once {
+				status.NextRun = next
+				GlobalLogger.Info("Daemon run done", "run", status.Runs, "next", next.Format(time.RFC3339))
+			}
+			if statusPath != "" {
+				if err := service.WriteDaemonStatus(statusPath, status); err != nil {
+					GlobalLogger.Warn("failed to write the daemon status", "error", err)
+				}
+			}
+		}
+
+		if once {
+			run(cmd.Context(), time.Time{})
+			GlobalUsage.Count("failures", status.Failures)
+			if status.Failures > 0 {
+				return fmt.Errorf("%d of %d jobs failed: %w", status.Failures, len(config.Jobs), service.ErrPartialFailure)
+			}
+			return nil
+		}
+		GlobalLogger.Info("Daemon started", "every", every.String(), "jobs", len(config.Jobs))
+		service.RunEvery(cmd.Context(), every, run)
+		GlobalUsage.Count("runs", status.Runs)
+		GlobalLogger.Info("Daemon stopped", "runs", status.Runs)
+		return nil
+	},
+}
+
+// daemonConfigFromFlags returns the daemon section of the config with the
+// interval and status file of the flags, and the jobs of the flags appended
+func daemonConfigFromFlags(cmd *cobra.Command) (service.DaemonConfig, error) {
+	config := GlobalConfig.Daemon
+	config.Jobs = append([]service.DaemonJob(nil), config.Jobs...)
+	if every, _ := cmd.Flags().GetString("every"); every != "" {
+		config.Every = every
+	}
+	if statusFile, _ := cmd.Flags().GetString("status-file"); statusFile != "" {
+		config.StatusFile = statusFile
+	}
+	workspaces, _ := cmd.Flags().GetStringSlice("fetch")
+	for _, workspace := range workspaces {
+		config.Jobs = append(config.Jobs, service.DaemonJob{Kind: service.DaemonJobFetch, Workspace: workspace})
+	}
+	fetchDirs, _ := cmd.Flags().GetStringSlice("fetch-dir")
+	for _, dir := range fetchDirs {
+		config.Jobs = append(config.Jobs, service.DaemonJob{Kind: service.DaemonJobFetch, Dir: dir})
+	}
+	scanDirs, _ := cmd.Flags().GetStringSlice("scan")
+	for _, dir := range scanDirs {
+		config.Jobs = append(config.Jobs, service.DaemonJob{Kind: service.DaemonJobScan, Dir: dir})
+	}
+	return config, config.Validate()
+}
+
+// runDaemonJob runs job and logs its outcome
+func runDaemonJob(ctx context.Context, job service.DaemonJob) service.DaemonJobStatus {
+	result := service.DaemonJobStatus{Kind: job.Kind, Target: job.Target(), Started: time.Now()}
+	var err error
+	switch job.Kind {
+	case service.DaemonJobFetch:
+		err = daemonFetch(ctx, job, &result)
+	case service.DaemonJobScan:
+		err = daemonScan(ctx, job, &result)
+	}
+	result.Duration = time.Since(result.Started).Round(time.Millisecond)
+	if err != nil {
+		result.Error = err.Error()
+		GlobalLogger.Error("Daemon job failed", "job", job.Kind, "target", result.Target, "error", err)
+		return result
+	}
+	GlobalLogger.Info("Daemon job done", "job", job.Kind, "target", result.Target,
+		"done", result.Done, "failed", result.Failed, "duration", result.Duration.String())
+	return result
+}
+
+// daemonFetch fetches origin of every repository of the job
+func daemonFetch(ctx context.Context, job service.DaemonJob, result *service.DaemonJobStatus) error {
+	var repos []string
+	if job.Workspace != "" {
+		var missing []string
+		var err error
+		if repos, missing, err = service.WorkspaceRepos(GlobalConfig, job.Workspace); err != nil {
+			return err
+		}
+		for _, repo := range missing {
+			GlobalLogger.Warn("Workspace repository not found", "workspace", job.Workspace, "repo", repo)
+		}
+	} else {
+		dir, err := job.Directory()
+		if err != nil {
+			return err
+		}
+		if repos, err = mr_repo.ListRepoDirs(dir); err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+	}
+
+	gs := service.NewGitService(GlobalLogger)
+	gs.SetCredentials(GlobalConfig.Credentials)
+	gs.SetProxy(GlobalConfig.Proxy.Or(service.ProxyFromEnvironment()))
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := gs.FetchLatest(ctx, repo, service.Options{}); err != nil {
+			result.Failed++
+			GlobalLogger.Warn("FetchLatest failed", "repo", repo, "error", err)
+			continue
+		}
+		result.Done++
+	}
+	return nil
+}
+
+// daemonScan scans the directory of the job with the default options of du
+// and folder-list, saving the scan cache each of them reads
+func daemonScan(ctx context.Context, job service.DaemonJob, result *service.DaemonJobStatus) error {
+	dir, err := job.Directory()
+	if err != nil {
+		return err
+	}
+	storage, err := service.ParseStorageType(GlobalConfig.Scan.Storage)
+	if err != nil {
+		return err
+	}
+	for _, scope := range [][]string{
+		duCacheScope(false, false, ""),
+		folderListCacheScope(false, -1, false, ""),
+	} {
+		cache := openScanCache(dir, scope...)
+		if cache == nil {
+			return fmt.Errorf("failed to locate the scan cache")
+		}
+		fs := service.NewServiceWithLogger(GlobalLogger)
+		fs.SetStorage(storage)
+		fs.SetWorkers(GlobalConfig.Scan.Workers)
+		fs.SetScanCache(cache)
+		_, err := fs.ListDirectories(ctx, dir)
+		finishScanCache(cache, err)
+		if err != nil {
+			result.Failed++
+			return fmt.Errorf("failed to scan %s: %w", dir, err)
+		}
+		result.Done++
+	}
+	return nil
+}
+
+func init() {
+	daemonCmd.Flags().String("every", "", "time between two runs, such as 30m or 2h (defaults to daemon.every in the config, or 30m)")
+	daemonCmd.Flags().Bool("once", false, "run the jobs once and exit, failing when a job fails")
+	daemonCmd.Flags().String("status-file", "", "write the outcome of the last run to this JSON file (defaults to daemon.status_file in the config)")
+	daemonCmd.Flags().StringSlice("fetch", nil, "fetch the repositories of these workspaces of the config")
+	_ = daemonCmd.RegisterFlagCompletionFunc("fetch", mr_repo.CompleteWorkspaces)
+	daemonCmd.Flags().StringSlice("fetch-dir", nil, "fetch the repositories in these directories")
+	daemonCmd.Flags().StringSlice("scan", nil, "refresh the scan cache of these directories")
+	daemonCmd.MarkFlagsMutuallyExclusive("every", "once")
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/cmd/mr_repo"
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// dashboardCmd scores the health of every repository in a workspace
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Summarize the health of every repository in a workspace",
+	Long: `Inspect every git repository directly under a directory and combine its status
+(uncommitted changes, detached HEAD, missing remote), stale branches and disk usage
+into a health score from 0 to 100, per repository and for the whole workspace.
+No network access is needed: run fetch or update-branches first for fresh remote state.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		staleDays, _ := cmd.Flags().GetInt("stale-days")
+		if staleDays < 1 {
+			return fmt.Errorf("--stale-days must be at least 1")
+		}
+		sizeLimit, err := dashboardSizeLimit(cmd)
+		if err != nil {
+			return err
+		}
+		htmlPath, _ := cmd.Flags().GetString("html")
+
+		if dir == "" {
+			if dir, err = os.Getwd(); err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+		}
+
+		repoDirs, err := mr_repo.ListRepoDirs(dir)
+		if err != nil {
+			return err
+		}
+
+		opts := service.DashboardOptions{
+			StaleAfter: time.Duration(staleDays) * 24 * time.Hour,
+			SizeLimit:  sizeLimit,
+		}
+		dashboard, err := service.BuildDashboard(cmd.Context(), service.NewGitService(GlobalLogger), service.NewServiceWithLogger(GlobalLogger), repoDirs, opts)
+		if err != nil {
+			return err
+		}
+		GlobalUsage.Count("repos", len(dashboard.Repos))
+
+		printDashboard(dashboard)
+
+		if htmlPath != "" {
+			file, err := os.Create(htmlPath)
+			if err != nil {
+				return fmt.Errorf("failed to create HTML report: %w", err)
+			}
+			defer file.Close()
+			if err := service.WriteDashboardHTML(file, dashboard); err != nil {
+				return fmt.Errorf("failed to write HTML report: %w", err)
+			}
+			fmt.Println("HTML report written to", htmlPath)
+		}
+		return nil
+	},
+}
+
+func printDashboard(dashboard *service.Dashboard) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tSCORE\tBRANCH\tSTALE\tSIZE\tISSUES")
+	for _, repo := range dashboard.Repos {
+		branch, stale := "-", "-"
+		if repo.Status != nil {
+			branch = repo.Status.Branch
+			if branch == "" {
+				branch = "(detached)"
+			}
+			stale = fmt.Sprint(len(repo.Status.StaleBranches))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			repo.Name, repo.Score, branch, stale, model.FormatSize(repo.Size), strings.Join(repo.Issues, ", "))
+	}
+	_ = w.Flush()
+	fmt.Printf("\nWorkspace health: %d/100 across %d repositories\n", dashboard.Score, len(dashboard.Repos))
+}
+
+// dashboardSizeLimit returns the --size-limit, or the deprecated
+// --size-limit-mb when it is given instead
+func dashboardSizeLimit(cmd *cobra.Command) (int64, error) {
+	if cmd.Flags().Changed("size-limit-mb") && !cmd.Flags().Changed("size-limit") {
+		megabytes, _ := cmd.Flags().GetInt64("size-limit-mb")
+		return megabytes * model.MiB, nil
+	}
+	value, _ := cmd.Flags().GetString("size-limit")
+	limit, err := model.ParseSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --size-limit: %w", err)
+	}
+	return limit, nil
+}
+
+func init() {
+	dashboardCmd.Flags().StringP("dir", "d", "", "workspace directory containing the repositories (defaults to current directory)")
+	dashboardCmd.Flags().Int("stale-days", 90, "days without commits after which a branch or repository is stale")
+	dashboardCmd.Flags().String("size-limit", "1GB", "flag repositories larger than this size, e.g. 500MB or 2GB")
+	dashboardCmd.Flags().Int64("size-limit-mb", 1024, "flag repositories larger than this many megabytes")
+	_ = dashboardCmd.Flags().MarkDeprecated("size-limit-mb", "use --size-limit instead")
+	dashboardCmd.Flags().String("html", "", "also write the dashboard as an HTML report to this file")
+}
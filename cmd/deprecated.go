@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// deprecatedCommands maps the names of the renamed commands to the path of
+// the command replacing them, so existing scripts keep working
+var deprecatedCommands = map[string][]string{
+	"fileList":    {"file-list"},
+	"folderList":  {"folder-list"},
+	"updateRepos": {"mr-repo", "update-branches"},
+}
+
+// addDeprecatedCommands registers every deprecated name under root as a
+// hidden command running the command replacing it
+func addDeprecatedCommands(root *cobra.Command) {
+	for _, name := range slices.Sorted(maps.Keys(deprecatedCommands)) {
+		target, _, err := root.Find(deprecatedCommands[name])
+		if err != nil || target == root {
+			continue
+		}
+		root.AddCommand(deprecatedCommand(name, target))
+	}
+}
+
+// deprecatedCommand returns a hidden command named name running target with
+// its flags, arguments and help. cobra prints the deprecation notice to
+// stderr when it runs. The hooks of the parents of target below the root,
+// such as the mr-repo --output handling, run as its own hooks.
+func deprecatedCommand(name string, target *cobra.Command) *cobra.Command {
+	path := strings.TrimPrefix(target.CommandPath(), target.Root().Name()+" ")
+	deprecated := &cobra.Command{
+		Use:               name + strings.TrimPrefix(target.Use, target.Name()),
+		Short:             target.Short,
+		Long:              target.Long,
+		Example:           target.Example,
+		Hidden:            true,
+		Deprecated:        fmt.Sprintf("use %q instead", "goktor "+path),
+		Args:              target.Args,
+		ValidArgs:         target.ValidArgs,
+		ValidArgsFunction: target.ValidArgsFunction,
+		SilenceUsage:      target.SilenceUsage,
+		RunE:              target.RunE,
+	}
+
+	flags := deprecated.Flags()
+	flags.AddFlagSet(target.Flags())
+	flags.AddFlagSet(target.PersistentFlags())
+	rootFlags := target.Root().PersistentFlags()
+	target.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if rootFlags.Lookup(flag.Name) == nil {
+			flags.AddFlag(flag)
+		}
+	})
+
+	preRuns := []func(*cobra.Command, []string) error{target.PreRunE}
+	postRuns := []func(*cobra.Command, []string) error{target.PostRunE}
+	for parent := target.Parent(); parent != nil && parent.HasParent(); parent = parent.Parent() {
+		preRuns = append([]func(*cobra.Command, []string) error{parent.PersistentPreRunE}, preRuns...)
+		postRuns = append(postRuns, parent.PersistentPostRunE)
+	}
+	deprecated.PreRunE = chainRunE(preRuns)
+	deprecated.PostRunE = chainRunE(postRuns)
+	return deprecated
+}
+
+// chainRunE returns a hook running every non-nil hook of hooks in order,
+// stopping at the first error
+func chainRunE(hooks []func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		for _, hook := range hooks {
+			if hook == nil {
+				continue
+			}
+			if err := hook(cmd, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
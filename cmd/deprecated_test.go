@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestDeprecatedCommands(t *testing.T) {
+	for name, path := range deprecatedCommands {
+		target, _, err := RootCmd.Find(path)
+		if err != nil || target.Name() != path[len(path)-1] {
+			t.Fatalf("%s replaced by %v, which is not a command", name, path)
+		}
+		deprecated, _, err := RootCmd.Find([]string{name})
+		if err != nil || deprecated.Name() != name || !deprecated.Hidden || deprecated.Deprecated == "" {
+			t.Fatalf("Find(%s) = %v, %v, want a hidden deprecated command", name, deprecated, err)
+		}
+		if deprecated.Long != target.Long {
+			t.Errorf("%s help differs from %v", name, path)
+		}
+		target.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
+			if deprecated.Flags().Lookup(flag.Name) == nil {
+				t.Errorf("%s has no --%s flag of %v", name, flag.Name, path)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want, err := runGoktor(t, []string{"file-list", "--dir", dir})
+	if err != nil {
+		t.Fatalf("file-list error = %v", err)
+	}
+	got, err := runGoktor(t, []string{"fileList", "--dir", dir})
+	if err != nil {
+		t.Fatalf("fileList error = %v", err)
+	}
+	notice, got, _ := strings.Cut(got, "\n")
+	if notice != `Command "fileList" is deprecated, use "goktor file-list" instead` || got != want {
+		t.Errorf("fileList output = %q, %q, want the notice then %q", notice, got, want)
+	}
+
+	// the mr-repo hooks run for updateRepos
+	if _, err := runGoktor(t, []string{"updateRepos", "--dir", dir, "--output", "yaml"}); err == nil || !strings.Contains(err.Error(), "unknown --output") {
+		t.Errorf("updateRepos --output yaml error = %v, want the mr-repo validation", err)
+	}
+}
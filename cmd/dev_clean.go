@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// devCleanCmd finds build and dependency directories and deletes them once confirmed
+var devCleanCmd = &cobra.Command{
+	Use:   "dev-clean",
+	Short: "Delete build and dependency directories such as node_modules and target",
+	Long: `Walk a directory and find the build and dependency directories that can be
+recreated from their project: node_modules, target, .venv, dist, __pycache__,
+.gradle and build. Directories such as target or dist only match next to their
+project file (Cargo.toml, pom.xml, package.json...). The reclaimable space is
+reported per project, then the directories are deleted once confirmed.
+
+Add rules in the "dev_clean" section of the config file; a rule named like a
+built-in one replaces it:
+
+  {"dev_clean": {"rules": [{"name": "cmake", "dir": "cmake-build-*", "markers": ["CMakeLists.txt"]}]}}
+
+Protected paths (system directories, the home directory and its dot-directories,
+and the "safety.protected_paths" of the config file) are never deleted unless
+--force-unsafe is set.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		yes, _ := cmd.Flags().GetBool("yes")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if dir == "" {
+			var err error
+			if dir, err = os.Getwd(); err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+		}
+
+		rules, err := service.NewDevCleanRules(GlobalConfig.DevClean)
+		if err != nil {
+			return err
+		}
+		artifacts, err := service.FindDevArtifacts(cmd.Context(), dir, rules)
+		if err != nil {
+			return err
+		}
+		GlobalUsage.Count("artifacts", len(artifacts))
+
+		out := cmd.OutOrStdout()
+		printDevProjects(out, service.GroupDevArtifacts(artifacts))
+		if len(artifacts) == 0 || dryRun {
+			return nil
+		}
+
+		if !yes {
+			if !isTerminal(os.Stdin) {
+				return fmt.Errorf("refusing to delete without confirmation, add --yes when stdin is not a terminal")
+			}
+			if !confirm(cmd.InOrStdin(), out, fmt.Sprintf("Delete %d directories?", len(artifacts))) {
+				return nil
+			}
+		}
+
+		guard, err := newPathGuard(cmd)
+		if err != nil {
+			return err
+		}
+		removed, err := service.RemoveDevArtifacts(cmd.Context(), artifacts, guard)
+		var reclaimed model.FileSystem
+		for _, artifact := range removed {
+			reclaimed.Size += artifact.Size
+		}
+		GlobalUsage.Count("removed", len(removed))
+		fmt.Fprintf(out, "Removed %d of %d directories, %s reclaimed\n", len(removed), len(artifacts), reclaimed.GetFormattedSize())
+		return partialError(err)
+	},
+}
+
+// printDevProjects prints the artifacts of every project and the total reclaimable space
+func printDevProjects(out io.Writer, projects []service.DevProject) {
+	if len(projects) == 0 {
+		fmt.Fprintln(out, "No build or dependency directories found")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tRECLAIMABLE\tDIRECTORIES")
+	var total model.FileSystem
+	for _, project := range projects {
+		names := make([]string, len(project.Artifacts))
+		for i, artifact := range project.Artifacts {
+			names[i] = strings.TrimPrefix(artifact.Path, project.Path+string(os.PathSeparator))
+		}
+		size := model.FileSystem{Size: project.Size}
+		total.Size += project.Size
+		fmt.Fprintf(w, "%s\t%s\t%s\n", project.Path, size.GetFormattedSize(), strings.Join(names, ", "))
+	}
+	_ = w.Flush()
+	fmt.Fprintf(out, "\n%d projects, %s reclaimable\n", len(projects), total.GetFormattedSize())
+}
+
+// newPathGuard protects the built-in and configured paths, unless --force-unsafe is set
+func newPathGuard(cmd *cobra.Command) (*service.PathGuard, error) {
+	force, _ := cmd.Flags().GetBool("force-unsafe")
+	if force {
+		GlobalLogger.Warn("--force-unsafe set, protected paths may be modified")
+	}
+	return service.NewPathGuard(GlobalConfig.Safety, force)
+}
+
+// addForceUnsafeFlag registers --force-unsafe on a destructive command
+func addForceUnsafeFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("force-unsafe", false, "also modify protected paths such as system directories and home dot-directories")
+}
+
+// confirm asks question on out and reports whether the answer read from in is yes
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func init() {
+	devCleanCmd.Flags().StringP("dir", "d", "", "directory to clean (defaults to current directory)")
+	devCleanCmd.Flags().BoolP("yes", "y", false, "delete without asking for confirmation")
+	devCleanCmd.Flags().Bool("dry-run", false, "only report the reclaimable space")
+	addForceUnsafeFlag(devCleanCmd)
+}
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
 
 	"github.com/nanaki-93/goktor/model"
 	"github.com/nanaki-93/goktor/service"
@@ -10,12 +13,25 @@ import (
 
 // diffCmd represents the diff command
 var diffCmd = &cobra.Command{
-	Use:   "diff",
-	Short: "Get the diff from 2 input files",
+	Use:   "diff [pathA pathB]",
+	Short: "Get the diff from 2 input files, or compare two scans",
 	Long: `Receive in input 2 csv files with 2 column, a key and a content.
 The diff compare the 2 content between the 2 files searching with the key.
-The output will be a csv file with 2 column, a key and a result Yes for equal content, No for different content.`,
+The output will be a csv file with 2 column, a key and a result Yes for equal content, No for different content.
+
+With two directories, or scans saved by the scan command (--scan, once or twice),
+report the files and directories added, removed, or changed in size between them,
+largest change first. Entries are matched by their path relative to the scanned
+directories; directories are compared by cumulative size. A saved scan followed by
+a directory shows what changed since that scan:
+
+  goktor diff --scan 3 /srv`,
+	Args: cobra.MaximumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 || cmd.Flags().Changed("scan") {
+			return diffScans(cmd, args)
+		}
+
 		leftFile, err := cmd.Flags().GetString("left")
 		if err != nil {
 			return fmt.Errorf("failed to get left flag: %w", err)
@@ -65,10 +81,109 @@ The output will be a csv file with 2 column, a key and a result Yes for equal co
 	},
 }
 
+// scanSide is one side of a scan comparison
+type scanSide struct {
+	label   string
+	root    string
+	entries []model.FileSystem
+}
+
+// diffScans compares the saved scans of --scan followed by the directories
+// of args, two in total
+func diffScans(cmd *cobra.Command, args []string) error {
+	scanIDs, _ := cmd.Flags().GetInt64Slice("scan")
+	if len(scanIDs)+len(args) != 2 {
+		return fmt.Errorf("diff compares two directories or saved scans, got %d", len(scanIDs)+len(args))
+	}
+	value, _ := cmd.Flags().GetString("min-change")
+	minChange, err := model.ParseSize(value)
+	if err != nil {
+		return fmt.Errorf("invalid --min-change: %w", err)
+	}
+
+	var sides []scanSide
+	if len(scanIDs) > 0 {
+		index, err := openScanIndex(cmd)
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+		for _, id := range scanIDs {
+			scan, entries, err := index.LoadScan(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			label := fmt.Sprintf("%s (scan %d, %s)", scan.Root, scan.ID, scan.ScannedAt.Format("2006-01-02 15:04"))
+			sides = append(sides, scanSide{label: label, root: scan.Root, entries: entries})
+		}
+	}
+	for _, dir := range args {
+		fs := service.NewServiceWithLogger(GlobalLogger)
+		root, err := fs.ListDirectories(cmd.Context(), dir)
+		if err != nil {
+			return fmt.Errorf("failed to list directories: %w", err)
+		}
+		sides = append(sides, scanSide{label: root.FullPath, root: root.FullPath, entries: service.ScanEntries(root)})
+	}
+
+	dirsOnly, _ := cmd.Flags().GetBool("dirs")
+	limit, _ := cmd.Flags().GetInt("limit")
+	var changes []service.ScanChange
+	for _, change := range service.DiffScans(sides[0].root, sides[0].entries, sides[1].root, sides[1].entries) {
+		if (dirsOnly && !change.IsDir) || abs64(change.Delta()) < minChange {
+			continue
+		}
+		if limit > 0 && len(changes) == limit {
+			break
+		}
+		changes = append(changes, change)
+	}
+
+	GlobalUsage.Count("changes", len(changes))
+	fmt.Printf("Comparing %s with %s\n\n", sides[0].label, sides[1].label)
+	printScanChanges(os.Stdout, changes)
+	return nil
+}
+
+func printScanChanges(out io.Writer, changes []service.ScanChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGE\tDELTA\tBEFORE\tAFTER\tPATH")
+	for _, change := range changes {
+		before, after := model.FileSystem{Size: change.Before}, model.FileSystem{Size: change.After}
+		delta := model.FileSystem{Size: abs64(change.Delta())}
+		sign := "+"
+		if change.Delta() < 0 {
+			sign = "-"
+		}
+		path := change.Path
+		if change.IsDir {
+			path += "/"
+		}
+		fmt.Fprintf(w, "%s\t%s%s\t%s\t%s\t%s\n", change.Kind, sign, delta.GetFormattedSize(), before.GetFormattedSize(), after.GetFormattedSize(), path)
+	}
+	_ = w.Flush()
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
 func init() {
 	diffCmd.Flags().StringP("left", "l", "", "left file to compare")
 	diffCmd.Flags().StringP("right", "r", "", "right file to compare")
 	diffCmd.Flags().StringP("delimiter", "d", "\t", "delimiter for columns")
 	diffCmd.Flags().BoolP("output", "o", false, "write all the detail for the diff")
 	diffCmd.Flags().BoolP("header", "H", false, "the input has header or not")
+	diffCmd.Flags().Int64Slice("scan", nil, "id of a scan saved by the scan command to compare, before the directories")
+	diffCmd.Flags().String("db", "", "SQLite database holding the --scan scans (defaults to ~/.goktor/index.db)")
+	diffCmd.Flags().Bool("dirs", false, "only report directories when comparing scans")
+	diffCmd.Flags().String("min-change", "0", "only report entries whose size changed by at least this much, e.g. 100MB")
+	diffCmd.Flags().Int("limit", 50, "maximum number of changes reported when comparing scans, 0 for all")
 }
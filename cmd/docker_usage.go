@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// dockerUsageCmd reports the space used by Docker images, containers, volumes and build cache
+var dockerUsageCmd = &cobra.Command{
+	Use:   "docker-usage",
+	Short: "Report the space used by Docker images, containers, volumes and build cache",
+	Long: `Report the space used by Docker images, containers, local volumes and build
+cache, and how much of it is reclaimable because no container uses it, like
+docker system df.
+
+The Docker API is queried at --host, $DOCKER_HOST or the default socket. When it
+cannot be reached, or with --data-root, the directories of the Docker data root
+(/var/lib/docker by default) are sized instead, which needs read access to it
+and cannot tell the reclaimable space.`,
+	Example: `  goktor docker-usage
+  goktor docker-usage --list --limit 5
+  sudo goktor docker-usage --data-root /var/lib/docker`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host, _ := cmd.Flags().GetString("host")
+		dataRoot, _ := cmd.Flags().GetString("data-root")
+		list, _ := cmd.Flags().GetBool("list")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		var usage *service.DockerUsage
+		if !cmd.Flags().Changed("data-root") {
+			client, err := service.NewDockerClient(host)
+			if err == nil {
+				usage, err = client.DiskUsage(cmd.Context())
+			}
+			if err != nil {
+				GlobalLogger.Warn("docker API unavailable, sizing the data root", "error", err, "data_root", dataRoot)
+			}
+		}
+		if usage == nil {
+			var err error
+			if usage, err = service.DockerDataRootUsage(dataRoot); err != nil {
+				return err
+			}
+		}
+
+		printDockerUsage(cmd.OutOrStdout(), usage, list, limit)
+		return nil
+	},
+}
+
+// printDockerUsage prints one line per category and, with list, their largest items
+func printDockerUsage(out io.Writer, usage *service.DockerUsage, list bool, limit int) {
+	fmt.Fprintf(out, "Source: %s\n\n", usage.Source)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tTOTAL\tACTIVE\tSIZE\tRECLAIMABLE")
+	var reclaimable model.FileSystem
+	for _, category := range usage.Categories {
+		size := model.FileSystem{Size: category.Size}
+		active, free := "-", "-"
+		if category.Reclaimable >= 0 {
+			active = fmt.Sprint(category.Active)
+			freed := model.FileSystem{Size: category.Reclaimable}
+			free = freed.GetFormattedSize()
+			if category.Size > 0 {
+				free += fmt.Sprintf(" (%.0f%%)", float64(category.Reclaimable)*100/float64(category.Size))
+			}
+			reclaimable.Size += category.Reclaimable
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", category.Name, len(category.Items), active, size.GetFormattedSize(), free)
+	}
+	_ = w.Flush()
+	total := model.FileSystem{Size: usage.Size()}
+	fmt.Fprintf(out, "\nTotal: %s, %s reclaimable\n", total.GetFormattedSize(), reclaimable.GetFormattedSize())
+
+	if !list {
+		return
+	}
+	for _, category := range usage.Categories {
+		if len(category.Items) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\n%s:\n", category.Name)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSIZE\tACTIVE")
+		for i, item := range category.Items {
+			if limit > 0 && i == limit {
+				fmt.Fprintf(w, "... %d more\t\t\t\n", len(category.Items)-limit)
+				break
+			}
+			size := model.FileSystem{Size: item.Size}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", item.ID, item.Name, size.GetFormattedSize(), item.Active)
+		}
+		_ = w.Flush()
+	}
+}
+
+func init() {
+	dockerUsageCmd.Flags().String("host", service.DefaultDockerHost(), "docker API address (unix:// socket or tcp:// address)")
+	dockerUsageCmd.Flags().String("data-root", service.DefaultDockerDataRoot(), "docker data root to size instead of querying the API")
+	dockerUsageCmd.Flags().Bool("list", false, "list the images, containers, volumes and build cache records")
+	dockerUsageCmd.Flags().Int("limit", 10, "maximum number of items listed per type with --list, 0 for all")
+	dockerUsageCmd.MarkFlagsMutuallyExclusive("host", "data-root")
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// duCmd prints the cumulative size of the directories down to a depth
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Summarize disk usage as an indented tree of directory sizes",
+	Long: `Scan a directory and print the cumulative size of the directories down to --depth
+levels below it, as an indented tree with the share of each directory in its parent.
+Deeper directories are not printed but still counted in the sizes, a lighter
+overview than the full listing of folder-list. --on-disk adds the space the
+directories take on disk, which differs for sparse and compressed files.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		depth, _ := cmd.Flags().GetInt("depth")
+		followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+		onDisk, _ := cmd.Flags().GetBool("on-disk")
+		if depth < 0 {
+			return fmt.Errorf("invalid --depth %d, it cannot be negative", depth)
+		}
+
+		if dir == "" {
+			var err error
+			if dir, err = os.Getwd(); err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+		}
+
+		storage, err := scanStorage(cmd)
+		if err != nil {
+			return err
+		}
+		workers, err := scanWorkers(cmd)
+		if err != nil {
+			return err
+		}
+
+		fs := service.NewServiceWithLogger(GlobalLogger)
+		fs.SetStorage(storage)
+		fs.SetWorkers(workers)
+		fs.SetFollowSymlinks(followSymlinks)
+		fs.SetAllocatedSize(onDisk)
+		ownedBy, err := ownedByFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		fs.SetOwnedBy(ownedBy)
+		cache := scanCache(cmd, dir, duCacheScope(followSymlinks, onDisk, ownedBy)...)
+		fs.SetScanCache(cache)
+		progress, stopProgress := startProgress(cmd)
+		fs.SetProgress(progress)
+		defer stopProgress()
+
+		root, err := fs.ListDirectories(cmd.Context(), dir)
+		finishScanCache(cache, err)
+		if err != nil {
+			return fmt.Errorf("failed to list directories: %w", err)
+		}
+		stopProgress()
+
+		usage := service.SummarizeDiskUsage(root, depth)
+		GlobalUsage.Count("directories", len(root.FlattenDirectory()))
+		printDiskUsage(os.Stdout, usage, onDisk)
+		printSkippedSummary(os.Stdout, fs.SkippedPaths())
+		return skippedError(fs.SkippedPaths())
+	},
+}
+
+// duCacheScope returns the scan cache scope of du, the options changing what it reads
+func duCacheScope(followSymlinks, onDisk bool, ownedBy string) []string {
+	return []string{fmt.Sprint(followSymlinks), fmt.Sprint(onDisk), ownedBy}
+}
+
+// printDiskUsage prints the directories of usage indented by depth, the root
+// with its full path and the others with their name, and with onDisk the
+// space they take on disk
+func printDiskUsage(out io.Writer, usage service.DiskUsage, onDisk bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	if onDisk {
+		fmt.Fprint(w, "ON DISK\t")
+	}
+	fmt.Fprintln(w, "SIZE\tSHARE\tFILES\t  PATH")
+	for _, dir := range usage.Flatten() {
+		size := model.FileSystem{Size: dir.Size}
+		name := dir.Name
+		if dir.Depth == 0 {
+			name = dir.Path
+		}
+		if onDisk {
+			allocated := model.FileSystem{Size: dir.Allocated}
+			fmt.Fprintf(w, "%s\t", allocated.GetFormattedSize())
+		}
+		fmt.Fprintf(w, "%s\t%.1f%%\t%d\t  %s%s\n", size.GetFormattedSize(), dir.Share, dir.Files, strings.Repeat("  ", dir.Depth), name+depthLimitedMark(dir))
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	duCmd.Flags().StringP("dir", "d", "", "directory to scan (defaults to current directory)")
+	duCmd.Flags().Int("depth", 1, "number of directory levels printed below the scanned directory")
+	duCmd.Flags().Bool("follow-symlinks", false, "scan the directories symlinks point to; each directory is still counted once")
+	addScanCacheFlags(duCmd)
+	addOnDiskFlag(duCmd)
+	addOwnedByFlag(duCmd)
+	duCmd.Flags().String("storage", "", "storage type used to tune scan concurrency: auto, ssd, hdd or network (defaults to scan.storage in the config)")
+	duCmd.Flags().Int("workers", 0, "number of directories read at a time, overriding the --storage tuning (defaults to scan.workers in the config)")
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package cmd
+
+import (
+	"errors"
+	"os"
+)
+
+// disableEcho is not supported on this platform: the typed text stays visible
+func disableEcho(f *os.File) (restore func(), err error) {
+	return nil, errors.New("hiding the typed text is not supported on this platform")
+}
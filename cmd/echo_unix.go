@@ -0,0 +1,26 @@
+//go:build linux || darwin
+
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// disableEcho stops the terminal f from echoing what is typed, until restore
+// is called
+func disableEcho(f *os.File) (restore func(), err error) {
+	fd := int(f.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	previous := *termios
+	termios.Lflag &^= unix.ECHO
+	termios.Lflag |= unix.ICANON | unix.ISIG
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+	return func() { _ = unix.IoctlSetTermios(fd, ioctlSetTermios, &previous) }, nil
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// disableEcho stops the console f from echoing what is typed, until restore
+// is called
+func disableEcho(f *os.File) (restore func(), err error) {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return nil, err
+	}
+	if err := windows.SetConsoleMode(handle, mode&^windows.ENABLE_ECHO_INPUT|windows.ENABLE_LINE_INPUT); err != nil {
+		return nil, err
+	}
+	return func() { _ = windows.SetConsoleMode(handle, mode) }, nil
+}
@@ -7,7 +7,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/nanaki-93/goktor/model"
 	"github.com/nanaki-93/goktor/service"
 	"github.com/spf13/cobra"
 )
@@ -16,7 +18,15 @@ import (
 var fileListCmd = &cobra.Command{
 	Use:   "file-list",
 	Short: "List files and their sizes",
-	Long:  `List all files recursively with their sizes in the specified directory.`,
+	Long: `List all files recursively with their sizes in the specified directory.
+Use --older-than and --newer-than (e.g. 90d, 2w, 1y, 36h) to keep only the files
+by last modification time. Files are listed by name unless --sort orders them by
+size or mtime; --reverse inverts the order. Only the files directly in the directory
+are listed, unless --max-depth also lists the files of the subdirectories down to
+that many levels.
+
+With --stream, files are printed unsorted as soon as they are read, so listing
+trees of millions of files starts at once and uses little memory.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		dirToScan, err := cmd.Flags().GetString("dir")
 		if err != nil {
@@ -31,18 +41,130 @@ var fileListCmd = &cobra.Command{
 			}
 		}
 
+		pager, err := cmd.Flags().GetBool("pager")
+		if err != nil {
+			return fmt.Errorf("failed to get pager flag: %w", err)
+		}
+
+		ageFilter, err := ageFilterFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		order, err := sortOrderFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if order.Key == service.SortByCount {
+			return fmt.Errorf("invalid --sort: %s only applies to directories", service.SortByCount)
+		}
+
 		fs := service.NewFileService()
-		res, err := fs.ListFiles(dirToScan)
+		maxDepth, _ := cmd.Flags().GetInt("max-depth")
+		if maxDepth < 0 {
+			return fmt.Errorf("invalid --max-depth %d, it cannot be negative", maxDepth)
+		}
+		fs.SetMaxDepth(maxDepth)
+		onDisk, _ := cmd.Flags().GetBool("on-disk")
+		fs.SetAllocatedSize(onDisk)
+		ownedBy, err := ownedByFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		fs.SetOwnedBy(ownedBy)
+		if stream, _ := cmd.Flags().GetBool("stream"); stream {
+			return streamFiles(cmd, fs, dirToScan, ageFilter, pager)
+		}
+		progress, stopProgress := startProgress(cmd)
+		fs.SetProgress(progress)
+		defer stopProgress()
+		res, err := fs.ListFiles(cmd.Context(), dirToScan)
 		if err != nil {
 			return fmt.Errorf("failed to list files: %w", err)
 		}
 
-		fs.PrintFiles(res)
-		return nil
+		stopProgress()
+		res = service.FilterByAge(res, ageFilter, time.Now())
+		service.SortFiles(res, order)
+
+		out, closeOutput, err := openOutput(pager)
+		if err != nil {
+			return err
+		}
+		fs.SetOutput(out)
+		GlobalUsage.Count("files", len(res))
+		if err := fs.PrintFiles(res); err != nil {
+			_ = closeOutput()
+			return err
+		}
+		printLockedSummary(out, fs.LockedFiles())
+		printSkippedSummary(out, fs.SkippedPaths())
+		if err := closeOutput(); err != nil {
+			return err
+		}
+		return skippedError(fs.SkippedPaths())
 	},
 }
 
+// streamFiles prints the files of dir matching ageFilter as they are read,
+// holding none of them
+func streamFiles(cmd *cobra.Command, fs service.FileService, dir string, ageFilter service.AgeFilter, pager bool) error {
+	out, closeOutput, err := openOutput(pager)
+	if err != nil {
+		return err
+	}
+	fs.SetOutput(out)
+	now := time.Now()
+	count := 0
+	err = fs.WalkFiles(cmd.Context(), dir, func(file model.FileSystem) error {
+		if !ageFilter.Match(file, now) {
+			return nil
+		}
+		count++
+		return fs.PrintFiles([]model.FileSystem{file})
+	})
+	GlobalUsage.Count("files", count)
+	if err != nil {
+		_ = closeOutput()
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	printLockedSummary(out, fs.LockedFiles())
+	printSkippedSummary(out, fs.SkippedPaths())
+	if err := closeOutput(); err != nil {
+		return err
+	}
+	return skippedError(fs.SkippedPaths())
+}
+
+// ageFilterFromFlags builds the modification time filter from --older-than and --newer-than
+func ageFilterFromFlags(cmd *cobra.Command) (service.AgeFilter, error) {
+	var filter service.AgeFilter
+	if value, _ := cmd.Flags().GetString("older-than"); value != "" {
+		age, err := service.ParseAge(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --older-than: %w", err)
+		}
+		filter.OlderThan = age
+	}
+	if value, _ := cmd.Flags().GetString("newer-than"); value != "" {
+		age, err := service.ParseAge(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --newer-than: %w", err)
+		}
+		filter.NewerThan = age
+	}
+	return filter, nil
+}
+
 func init() {
 	fileListCmd.Flags().StringP("dir", "d", "", "Directory to scan (defaults to current directory)")
-
+	fileListCmd.Flags().Bool("pager", false, "pipe the output into $PAGER")
+	fileListCmd.Flags().String("older-than", "", "only list files last modified more than this long ago (e.g. 90d, 2w, 1y)")
+	fileListCmd.Flags().String("newer-than", "", "only list files modified within this duration (e.g. 7d, 36h)")
+	addSortFlags(fileListCmd, service.SortByName)
+	addOnDiskFlag(fileListCmd)
+	addOwnedByFlag(fileListCmd)
+	fileListCmd.Flags().Int("max-depth", 0, "also list the files of the subdirectories down to this many levels")
+	fileListCmd.Flags().Bool("stream", false, "print the files unsorted as soon as they are read, with bounded memory")
+	fileListCmd.MarkFlagsMutuallyExclusive("stream", "sort")
+	fileListCmd.MarkFlagsMutuallyExclusive("stream", "reverse")
 }
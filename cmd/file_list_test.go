@@ -44,6 +44,14 @@ func TestFileListCmd(t *testing.T) {
 			args:    []string{"file-list", "-d", "/nonexistent/path"},
 			wantErr: true,
 		},
+		{
+			name: "invalid age filter",
+			setup: func(t *testing.T) string {
+				return ""
+			},
+			args:    []string{"file-list", "--older-than", "soon"},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
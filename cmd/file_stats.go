@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// fileStatsCmd breaks the files of a directory down by type
+var fileStatsCmd = &cobra.Command{
+	Use:   "file-stats",
+	Short: "Break down files by category and extension",
+	Long: `Scan a directory recursively and report the number of files and their cumulative
+size per category (images, videos, audio, archives, documents, code, logs, other),
+and optionally per extension, as a table or as JSON.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		byExtension, _ := cmd.Flags().GetBool("extensions")
+		followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+
+		if dir == "" {
+			var err error
+			if dir, err = os.Getwd(); err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+		}
+
+		storage, err := scanStorage(cmd)
+		if err != nil {
+			return err
+		}
+		workers, err := scanWorkers(cmd)
+		if err != nil {
+			return err
+		}
+
+		fs := service.NewServiceWithLogger(GlobalLogger)
+		fs.SetStorage(storage)
+		fs.SetWorkers(workers)
+		fs.SetFollowSymlinks(followSymlinks)
+		checkpoint := scanCheckpoint(cmd, dir, fmt.Sprint(followSymlinks))
+		fs.SetCheckpoint(checkpoint)
+		cache := scanCache(cmd, dir, fmt.Sprint(followSymlinks))
+		fs.SetScanCache(cache)
+		progress, stopProgress := startProgress(cmd)
+		fs.SetProgress(progress)
+		defer stopProgress()
+
+		root, err := fs.ListDirectories(cmd.Context(), dir)
+		finishScanCheckpoint(checkpoint, err)
+		finishScanCache(cache, err)
+		if err != nil {
+			return fmt.Errorf("failed to list directories: %w", err)
+		}
+		stopProgress()
+
+		stats := service.AggregateFileStats(root)
+		GlobalUsage.Count("files", stats.Files)
+
+		if asJSON {
+			// the summary goes to stderr so stdout stays valid JSON
+			printSkippedSummary(os.Stderr, fs.SkippedPaths())
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(stats); err != nil {
+				return err
+			}
+			return skippedError(fs.SkippedPaths())
+		}
+		printFileStats(os.Stdout, stats, byExtension)
+		printSkippedSummary(os.Stdout, fs.SkippedPaths())
+		return skippedError(fs.SkippedPaths())
+	},
+}
+
+func printFileStats(out io.Writer, stats service.FileStats, byExtension bool) {
+	printFileGroups(out, "CATEGORY", stats.Categories, stats.Size)
+	if byExtension {
+		fmt.Fprintln(out)
+		printFileGroups(out, "EXTENSION", stats.Extensions, stats.Size)
+	}
+	total := model.FileSystem{Size: stats.Size}
+	fmt.Fprintf(out, "\n%d files, %s\n", stats.Files, total.GetFormattedSize())
+}
+
+func printFileGroups(out io.Writer, header string, groups []service.FileGroupStats, totalSize int64) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tFILES\tSIZE\tSHARE\n", header)
+	for _, group := range groups {
+		size := model.FileSystem{Size: group.Size}
+		share := 0.0
+		if totalSize > 0 {
+			share = float64(group.Size) * 100 / float64(totalSize)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.1f%%\n", group.Name, group.Files, size.GetFormattedSize(), share)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	fileStatsCmd.Flags().StringP("dir", "d", "", "directory to scan (defaults to current directory)")
+	fileStatsCmd.Flags().Bool("json", false, "print the breakdown as JSON")
+	fileStatsCmd.Flags().Bool("extensions", false, "also break the files down by extension")
+	fileStatsCmd.Flags().Bool("follow-symlinks", false, "scan the directories symlinks point to; each directory is still counted once")
+	addScanCacheFlags(fileStatsCmd)
+	fileStatsCmd.Flags().Bool("resume", false, "reuse the directories completed by the previous interrupted scan, if unmodified since")
+	fileStatsCmd.Flags().String("storage", "", "storage type used to tune scan concurrency: auto, ssd, hdd or network (defaults to scan.storage in the config)")
+	fileStatsCmd.Flags().Int("workers", 0, "number of directories read at a time, overriding the --storage tuning (defaults to scan.workers in the config)")
+}
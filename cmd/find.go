@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// findCmd searches a tree for files by name, size and age
+var findCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Search a directory tree for files by name, size and age",
+	Long: `Walk a directory tree concurrently and print every file matching all the filters
+as soon as it is found: --name is a glob matched against the file name, --regex a
+regular expression matched against the full path, --min-size and --max-size bound
+the size, --older-than and --newer-than the modification time.
+
+Files are printed in the order they are found. --paths prints only their paths,
+one per line, to feed other commands; --print0 ends each path with a NUL byte
+instead, for xargs -0, so paths holding spaces or newlines stay whole.`,
+	Example: `  goktor find --name '*.iso' --min-size 1GB
+  goktor find --dir ~/Downloads --older-than 1y --print0 | xargs -0 rm`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		if dir == "" {
+			var err error
+			if dir, err = os.Getwd(); err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+		}
+		query, err := findQueryFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		storage, err := scanStorage(cmd)
+		if err != nil {
+			return err
+		}
+		workers, err := scanWorkers(cmd)
+		if err != nil {
+			return err
+		}
+		followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+		maxDepth, _ := cmd.Flags().GetInt("max-depth")
+		pathsOnly, _ := cmd.Flags().GetBool("paths")
+		print0, _ := cmd.Flags().GetBool("print0")
+		pathsOnly = pathsOnly || print0
+
+		fs := service.NewServiceWithLogger(GlobalLogger)
+		fs.SetStorage(storage)
+		fs.SetWorkers(workers)
+		fs.SetFollowSymlinks(followSymlinks)
+		fs.SetMaxDepth(maxDepth)
+		ownedBy, err := ownedByFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		fs.SetOwnedBy(ownedBy)
+
+		out, closeOutput, err := openOutput(false)
+		if err != nil {
+			return err
+		}
+		var count int
+		var total int64
+		err = fs.FindFiles(cmd.Context(), dir, query, func(file model.FileSystem) error {
+			count++
+			total += file.Size
+			return printFoundFile(out, file, pathsOnly, print0)
+		})
+		if err != nil {
+			_ = closeOutput()
+			return fmt.Errorf("failed to search %s: %w", dir, err)
+		}
+
+		GlobalUsage.Count("files", count)
+		if !pathsOnly {
+			size := model.FileSystem{Size: total}
+			fmt.Fprintf(out, "\n%d files, %s\n", count, size.GetFormattedSize())
+			printSkippedSummary(out, fs.SkippedPaths())
+		}
+		if err := closeOutput(); err != nil {
+			return err
+		}
+		return skippedError(fs.SkippedPaths())
+	},
+}
+
+// findQueryFromFlags builds the query of the filter flags
+func findQueryFromFlags(cmd *cobra.Command) (service.FindQuery, error) {
+	query := service.FindQuery{Now: time.Now()}
+	query.Name, _ = cmd.Flags().GetString("name")
+	if value, _ := cmd.Flags().GetString("regex"); value != "" {
+		regex, err := regexp.Compile(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid --regex: %w", err)
+		}
+		query.Regex = regex
+	}
+
+	var err error
+	if value, _ := cmd.Flags().GetString("min-size"); value != "" {
+		if query.MinSize, err = model.ParseSize(value); err != nil {
+			return query, fmt.Errorf("invalid --min-size: %w", err)
+		}
+	}
+	if value, _ := cmd.Flags().GetString("max-size"); value != "" {
+		if query.MaxSize, err = model.ParseSize(value); err != nil {
+			return query, fmt.Errorf("invalid --max-size: %w", err)
+		}
+	}
+	if query.Age, err = ageFilterFromFlags(cmd); err != nil {
+		return query, err
+	}
+	if err := query.Validate(); err != nil {
+		return query, fmt.Errorf("invalid --name: %w", err)
+	}
+	return query, nil
+}
+
+// printFoundFile prints file, failing when out is closed so the search stops.
+// With print0 only the path is printed, terminated by a NUL byte.
+func printFoundFile(out io.Writer, file model.FileSystem, pathOnly bool, print0 bool) error {
+	var err error
+	switch {
+	case print0:
+		_, err = fmt.Fprintf(out, "%s\x00", file.FullPath)
+	case pathOnly:
+		_, err = fmt.Fprintln(out, file.FullPath)
+	default:
+		_, err = fmt.Fprintf(out, "%10s  %s  %s\n", file.GetFormattedSize(), file.ModTime.Format(time.DateOnly), file.FullPath)
+	}
+	return err
+}
+
+func init() {
+	findCmd.Flags().StringP("dir", "d", "", "directory to search (defaults to current directory)")
+	findCmd.Flags().String("name", "", "glob matched against the file name, e.g. '*.iso'")
+	findCmd.Flags().String("regex", "", "regular expression matched against the full path")
+	findCmd.Flags().String("min-size", "", "only print files of at least this size, e.g. 1GB")
+	findCmd.Flags().String("max-size", "", "only print files of at most this size")
+	findCmd.Flags().String("older-than", "", "only print files last modified more than this long ago (e.g. 90d, 2w, 1y)")
+	findCmd.Flags().String("newer-than", "", "only print files modified within this duration (e.g. 7d, 36h)")
+	findCmd.Flags().Int("max-depth", -1, "stop the search this many levels below the directory, -1 for no limit")
+	findCmd.Flags().Bool("follow-symlinks", false, "search the directories symlinks point to; each directory is still searched once")
+	addOwnedByFlag(findCmd)
+	findCmd.Flags().Bool("paths", false, "print only the paths of the files, one per line")
+	findCmd.Flags().Bool("print0", false, "print only the paths of the files, each terminated by a NUL byte, for xargs -0")
+	findCmd.Flags().String("storage", "", "storage type used to tune search concurrency: auto, ssd, hdd or network (defaults to scan.storage in the config)")
+	findCmd.Flags().Int("workers", 0, "number of directories read at a time, overriding the --storage tuning (defaults to scan.workers in the config)")
+}
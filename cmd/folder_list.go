@@ -5,19 +5,54 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
 
+	"github.com/nanaki-93/goktor/model"
 	"github.com/nanaki-93/goktor/service"
 
 	"github.com/spf13/cobra"
 )
 
+// Output formats of folder-list
+const (
+	folderOutputText = "text"
+	folderOutputHTML = "html"
+)
+
 // folderListCmd represents the folderList command
 var folderListCmd = &cobra.Command{
 	Use:   "folder-list",
 	Short: "List directories and their sizes",
 	Long: `List all directories recursively with their total sizes.
-You can specify a directory to scan or use the current directory.`,
+You can specify a directory to scan or use the current directory.
+Only directories larger than --min-size (10GB by default) are printed, largest first
+unless --sort orders them by name, count (of files) or mtime; --reverse inverts the order.
+Symlinks are listed but not followed unless --follow-symlinks is set; a directory
+reached again through a link is scanned once and reported at the end.
+Completed top-level directories are checkpointed; after a cancelled or crashed scan,
+--resume reuses the ones not modified since.
+
+With --watch the scan keeps running: filesystem notifications update the sizes in
+place and the --top largest directories are printed again every --interval while
+something changes. --min-size only applies in watch mode when set explicitly.
+
+With --tree the hierarchy is printed instead, with the cumulative size of every
+directory, each level sorted by size; --min-size then hides the smaller subtrees.
+
+--max-depth stops the scan that many levels below the directory for a fast overview
+of huge volumes: the directories at that level are listed as partial, without the
+content of their subdirectories in their sizes.
+
+--owned-by only counts the files of one user, to find where the space of a user
+goes on a shared server.
+
+--output html writes a standalone HTML report to --out instead, with a treemap and a
+sortable table of the directories larger than --min-size, to share with people who
+don't run the CLI.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		dirToScan, err := cmd.Flags().GetString("dir")
@@ -33,18 +68,358 @@ You can specify a directory to scan or use the current directory.`,
 			}
 		}
 
-		fs := service.NewFileService()
+		fastNTFS, err := cmd.Flags().GetBool("fast-ntfs")
+		if err != nil {
+			return fmt.Errorf("failed to get fast-ntfs flag: %w", err)
+		}
+
+		minSize, err := cmd.Flags().GetString("min-size")
+		if err != nil {
+			return fmt.Errorf("failed to get min-size flag: %w", err)
+		}
+		limit, err := model.ParseSize(minSize)
+		if err != nil {
+			return fmt.Errorf("invalid --min-size: %w", err)
+		}
 
-		res, err := fs.ListDirectories(dirToScan)
+		followSymlinks, err := cmd.Flags().GetBool("follow-symlinks")
 		if err != nil {
-			return fmt.Errorf("failed to list directories: %w", err)
+			return fmt.Errorf("failed to get follow-symlinks flag: %w", err)
 		}
 
-		fs.PrintDirectories(service.ReorderDirectory(res), fs.GetSizeFilter())
-		return nil
+		storage, err := scanStorage(cmd)
+		if err != nil {
+			return err
+		}
+		workers, err := scanWorkers(cmd)
+		if err != nil {
+			return err
+		}
+		order, err := sortOrderFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		output, _ := cmd.Flags().GetString("output")
+		reportPath, _ := cmd.Flags().GetString("out")
+		switch output {
+		case folderOutputText:
+		case folderOutputHTML:
+			if reportPath == "" {
+				return fmt.Errorf("--output html needs --out, the file to write the report to")
+			}
+		default:
+			return fmt.Errorf("unknown --output %q, expected %q or %q", output, folderOutputText, folderOutputHTML)
+		}
+
+		ownedBy, err := ownedByFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		onDisk, _ := cmd.Flags().GetBool("on-disk")
+		fs := service.NewFileService()
+		fs.SetAllocatedSize(onDisk)
+		fs.SetOwnedBy(ownedBy)
+		if maxDepth, _ := cmd.Flags().GetInt("max-depth"); maxDepth >= 0 {
+			fs.SetMaxDepth(maxDepth)
+		}
+		fs.SetStorage(storage)
+		fs.SetWorkers(workers)
+		fs.SetMinSize(limit)
+		fs.SetFollowSymlinks(followSymlinks)
+		progress, stopProgress := startProgress(cmd)
+		fs.SetProgress(progress)
+		defer stopProgress()
+
+		if watch, _ := cmd.Flags().GetBool("watch"); watch {
+			if !cmd.Flags().Changed("min-size") {
+				limit = 0
+			}
+			return watchFolders(cmd, fs, dirToScan, limit, stopProgress)
+		}
+
+		maxDepth, _ := cmd.Flags().GetInt("max-depth")
+		scope := folderListCacheScope(followSymlinks, maxDepth, onDisk, ownedBy)
+		checkpoint := scanCheckpoint(cmd, dirToScan, scope...)
+		fs.SetCheckpoint(checkpoint)
+		cache := scanCache(cmd, dirToScan, scope...)
+		fs.SetScanCache(cache)
+
+		var res model.Directory
+		if fastNTFS {
+			res, err = fs.ListDirectoriesMFT(cmd.Context(), dirToScan)
+			if err != nil {
+				GlobalLogger.Warn("fast NTFS scan unavailable, falling back to the normal walker", "error", err)
+			}
+		}
+		if !fastNTFS || err != nil {
+			res, err = fs.ListDirectories(cmd.Context(), dirToScan)
+			finishScanCheckpoint(checkpoint, err)
+			finishScanCache(cache, err)
+			if err != nil {
+				return fmt.Errorf("failed to list directories: %w", err)
+			}
+		}
+
+		stopProgress()
+		GlobalUsage.Count("directories", len(res.FlattenDirectory()))
+		if output == folderOutputHTML {
+			if err := writeDiskUsageReport(reportPath, service.SummarizeDiskUsage(res, -1), service.ReportOptions{MinSize: limit, OnDisk: onDisk}); err != nil {
+				return err
+			}
+			fmt.Println("HTML report written to", reportPath)
+		} else if tree, _ := cmd.Flags().GetBool("tree"); tree {
+			printDirectoryTree(os.Stdout, service.SummarizeDiskUsage(res, -1), limit, plainOutput, onDisk)
+		} else {
+			fs.PrintDirectories(service.ReorderDirectory(res, order), fs.GetSizeFilter())
+		}
+		printLockedSummary(os.Stdout, fs.LockedFiles())
+		printRevisitedSummary(os.Stdout, fs.RevisitedDirs())
+		printSkippedSummary(os.Stdout, fs.SkippedPaths())
+		return skippedError(fs.SkippedPaths())
 	},
 }
 
+// writeDiskUsageReport writes usage as an HTML report to path
+func writeDiskUsageReport(path string, usage service.DiskUsage, opts service.ReportOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create HTML report: %w", err)
+	}
+	if err := service.WriteDiskUsageHTML(file, usage, opts); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return file.Close()
+}
+
+// watchFolders keeps printing the largest directories of dir until the command is cancelled
+func watchFolders(cmd *cobra.Command, fs service.FileService, dir string, limit int64, stopProgress func()) error {
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("invalid --interval %s", interval)
+	}
+	top, _ := cmd.Flags().GetInt("top")
+	clearScreen := isTerminal(os.Stdout) && !plainOutput
+
+	return fs.WatchDirectories(cmd.Context(), dir, interval, func(index *service.SizeIndex) {
+		stopProgress()
+		printWatchTop(os.Stdout, dir, index.Top(top), limit, clearScreen)
+	})
+}
+
+// printWatchTop prints the largest directories above limit, replacing the
+// previous listing when clearScreen is set
+func printWatchTop(out io.Writer, dir string, top []model.Directory, limit int64, clearScreen bool) {
+	if clearScreen {
+		fmt.Fprint(out, "\033[H\033[2J")
+	}
+	fmt.Fprintf(out, "Largest directories in %s, updated %s\n\n", dir, time.Now().Format(time.TimeOnly))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SIZE\tPATH")
+	for _, d := range top {
+		if d.Size < limit {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%s\n", d.GetFormattedSize(), d.FullPath)
+	}
+	_ = w.Flush()
+	if !clearScreen {
+		fmt.Fprintln(out)
+	}
+}
+
+// sortOrderFromFlags returns the listing order of --sort and --reverse
+func sortOrderFromFlags(cmd *cobra.Command) (service.SortOrder, error) {
+	key, _ := cmd.Flags().GetString("sort")
+	reverse, _ := cmd.Flags().GetBool("reverse")
+	order, err := service.ParseSortOrder(key, reverse)
+	if err != nil {
+		return order, fmt.Errorf("invalid --sort: %w", err)
+	}
+	return order, nil
+}
+
+// addSortFlags registers --sort, defaulting to key, and --reverse on a listing command
+func addSortFlags(cmd *cobra.Command, key string) {
+	cmd.Flags().String("sort", key, "order of the listing: size, name, count or mtime")
+	cmd.Flags().Bool("reverse", false, "reverse the order of the listing")
+}
+
+// addOnDiskFlag registers --on-disk on a scanning command
+func addOnDiskFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("on-disk", false, "also report the space taken on disk, which differs from the size for sparse and compressed files")
+}
+
+// addOwnedByFlag registers --owned-by on a scanning command
+func addOwnedByFlag(cmd *cobra.Command) {
+	cmd.Flags().String("owned-by", "", "only count the files owned by this user name or id (a SID on Windows)")
+}
+
+// ownedByFromFlags resolves the owner id of --owned-by, empty when unset
+func ownedByFromFlags(cmd *cobra.Command) (string, error) {
+	name, _ := cmd.Flags().GetString("owned-by")
+	if name == "" {
+		return "", nil
+	}
+	owner, err := service.LookupOwner(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid --owned-by: %w", err)
+	}
+	return owner, nil
+}
+
+// scanStorage resolves the storage type from --storage, then the config file
+func scanStorage(cmd *cobra.Command) (service.StorageType, error) {
+	value, err := cmd.Flags().GetString("storage")
+	if err != nil {
+		return "", fmt.Errorf("failed to get storage flag: %w", err)
+	}
+	if value == "" {
+		value = GlobalConfig.Scan.Storage
+	}
+	return service.ParseStorageType(value)
+}
+
+// scanWorkers resolves the scan concurrency from --workers, then the config
+// file; 0 leaves it to the storage type
+func scanWorkers(cmd *cobra.Command) (int, error) {
+	workers, _ := cmd.Flags().GetInt("workers")
+	if workers < 0 {
+		return 0, fmt.Errorf("invalid --workers %d, it cannot be negative", workers)
+	}
+	if workers == 0 {
+		workers = GlobalConfig.Scan.Workers
+	}
+	return workers, nil
+}
+
+// scanCheckpoint returns the checkpoint of a scan of dir, the one left by an
+// unfinished scan when --resume is set; scope holds the options changing the
+// result. A nil checkpoint disables checkpointing.
+func scanCheckpoint(cmd *cobra.Command, dir string, scope ...string) *service.Checkpoint {
+	checkpointDir, err := service.DefaultCheckpointDir()
+	if err != nil {
+		GlobalLogger.Warn("failed to locate checkpoints, the scan cannot be resumed", "error", err)
+		return nil
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	path := service.CheckpointPath(checkpointDir, cmd.CommandPath(), append([]string{dir}, scope...)...)
+	if resume, _ := cmd.Flags().GetBool("resume"); resume {
+		checkpoint, err := service.LoadCheckpoint(path, cmd.CommandPath())
+		if err == nil {
+			GlobalLogger.Info("Resuming scan", "directories", checkpoint.Len())
+			return checkpoint
+		}
+		GlobalLogger.Warn("Starting over", "reason", err)
+	}
+	return service.NewCheckpoint(path, cmd.CommandPath())
+}
+
+// scanCache returns the scan cache of dir when --cache or scan.cache in the
+// config enables it and --no-cache is not set, nil otherwise
+func scanCache(cmd *cobra.Command, dir string, scope ...string) *service.ScanCache {
+	enabled := GlobalConfig.Scan.Cache
+	if on, _ := cmd.Flags().GetBool("cache"); on {
+		enabled = true
+	}
+	if off, _ := cmd.Flags().GetBool("no-cache"); off {
+		enabled = false
+	}
+	if !enabled {
+		return nil
+	}
+	return openScanCache(dir, scope...)
+}
+
+// openScanCache loads the scan cache of dir for scope, an empty one when it
+// is unreadable, or nil when the cache directory cannot be located
+func openScanCache(dir string, scope ...string) *service.ScanCache {
+	cacheDir, err := service.DefaultScanCacheDir()
+	if err != nil {
+		GlobalLogger.Warn("failed to locate the scan cache, scanning without it", "error", err)
+		return nil
+	}
+	path := service.ScanCachePath(cacheDir, dir, scope...)
+	cache, err := service.LoadScanCache(path)
+	if err != nil {
+		GlobalLogger.Warn("ignoring unreadable scan cache", "error", err)
+		return service.NewScanCache(path)
+	}
+	return cache
+}
+
+// folderListCacheScope returns the scan cache and checkpoint scope of
+// folder-list, the options changing what it reads
+func folderListCacheScope(followSymlinks bool, maxDepth int, onDisk bool, ownedBy string) []string {
+	return []string{fmt.Sprint(followSymlinks), fmt.Sprint(maxDepth), fmt.Sprint(onDisk), ownedBy}
+}
+
+// finishScanCache saves the cache of a completed scan; an interrupted scan
+// keeps the previous cache
+func finishScanCache(cache *service.ScanCache, scanErr error) {
+	if cache == nil || scanErr != nil {
+		return
+	}
+	hits, misses := cache.Stats()
+	GlobalLogger.Info("scan cache", "reused", hits, "read", misses)
+	if err := cache.Save(); err != nil {
+		GlobalLogger.Warn("failed to save the scan cache", "error", err)
+	}
+}
+
+// addScanCacheFlags registers --cache and --no-cache on a scanning command
+func addScanCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("cache", false, "reuse the previous scan of the directories whose modification time did not change")
+	cmd.Flags().Bool("no-cache", false, "read every directory, even when scan.cache is enabled in the config")
+	cmd.MarkFlagsMutuallyExclusive("cache", "no-cache")
+}
+
+// finishScanCheckpoint removes the checkpoint of a completed scan, otherwise
+// keeps it so the next scan can --resume
+func finishScanCheckpoint(checkpoint *service.Checkpoint, scanErr error) {
+	if checkpoint == nil {
+		return
+	}
+	if scanErr == nil {
+		if err := checkpoint.Remove(); err != nil {
+			GlobalLogger.Warn("failed to remove checkpoint", "error", err)
+		}
+		return
+	}
+	if err := checkpoint.Save(); err != nil {
+		GlobalLogger.Warn("failed to write checkpoint", "error", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Checkpoint saved, rerun with --resume to skip the completed directories")
+}
+
 func init() {
 	folderListCmd.Flags().StringP("dir", "d", "", "Directory to scan (defaults to current directory)")
+	folderListCmd.Flags().String("storage", "", "storage type used to tune scan concurrency: auto, ssd, hdd or network (defaults to scan.storage in the config)")
+	folderListCmd.Flags().Int("workers", 0, "number of directories read at a time, overriding the --storage tuning (defaults to scan.workers in the config)")
+	folderListCmd.Flags().String("min-size", "10GB", "only print directories larger than this size, e.g. 500MB or 2GB")
+	folderListCmd.Flags().Bool("follow-symlinks", false, "scan the directories symlinks point to; each directory is still counted once")
+	addScanCacheFlags(folderListCmd)
+	addSortFlags(folderListCmd, service.SortBySize)
+	addOnDiskFlag(folderListCmd)
+	addOwnedByFlag(folderListCmd)
+	folderListCmd.Flags().Bool("resume", false, "reuse the directories completed by the previous interrupted scan, if unmodified since")
+	folderListCmd.Flags().Bool("watch", false, "keep watching the directory and print the largest directories as they change")
+	folderListCmd.Flags().Duration("interval", 2*time.Second, "how often --watch prints the directories again when something changed")
+	folderListCmd.Flags().Int("top", 20, "number of directories printed by --watch")
+	folderListCmd.Flags().Bool("tree", false, "print the directory hierarchy with cumulative sizes instead of a flat list")
+	folderListCmd.MarkFlagsMutuallyExclusive("tree", "watch")
+	folderListCmd.Flags().String("output", folderOutputText, "output format: text, or html to write a report with a treemap to --out")
+	folderListCmd.Flags().String("out", "", "file the --output html report is written to")
+	folderListCmd.MarkFlagsMutuallyExclusive("output", "watch")
+	folderListCmd.MarkFlagsMutuallyExclusive("output", "tree")
+	folderListCmd.Flags().Int("max-depth", -1, "stop the scan this many levels below the directory, -1 for no limit")
+	folderListCmd.Flags().Bool("fast-ntfs", false, "read the NTFS master file table directly to scan a whole volume (Windows, administrator)")
+	folderListCmd.MarkFlagsMutuallyExclusive("max-depth", "watch")
+	folderListCmd.MarkFlagsMutuallyExclusive("max-depth", "fast-ntfs")
+	folderListCmd.MarkFlagsMutuallyExclusive("on-disk", "fast-ntfs")
+	folderListCmd.MarkFlagsMutuallyExclusive("owned-by", "fast-ntfs")
 }
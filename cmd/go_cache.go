@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/cmd/mr_repo"
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// goCacheCmd reports the size of the Go module and build caches and prunes unused modules
+var goCacheCmd = &cobra.Command{
+	Use:   "go-cache",
+	Short: "Report the Go module and build cache sizes and prune unused modules",
+	Long: `Report the size of the Go module cache (GOMODCACHE, $GOPATH/pkg/mod by default)
+and of the build cache (GOCACHE), and the largest modules of the module cache
+with the space taken by all their versions.
+
+With --prune, the module versions that no go.mod file of the workspaces
+requires are deleted once confirmed. The workspaces are the --workspace ones of
+the config, every configured workspace by default, and the --dir directories;
+their go.sum files are read too, so the dependencies of the required modules
+are kept. The go command downloads a pruned module again when it is needed.`,
+	Example: `  goktor go-cache
+  goktor go-cache --limit 50
+  goktor go-cache --prune --dir ~/projects --dry-run`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		prune, _ := cmd.Flags().GetBool("prune")
+		yes, _ := cmd.Flags().GetBool("yes")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		caches, err := service.FindGoCaches(cmd.Context())
+		if err != nil {
+			return err
+		}
+		modules, err := service.ListCachedModules(cmd.Context(), caches.ModCache)
+		if err != nil {
+			return err
+		}
+		GlobalUsage.Count("modules", len(modules))
+
+		out := cmd.OutOrStdout()
+		printGoCaches(out, caches, modules, limit)
+		if !prune {
+			return nil
+		}
+
+		roots, err := goCacheRoots(cmd)
+		if err != nil {
+			return err
+		}
+		refs, err := service.ModuleReferences(cmd.Context(), roots)
+		if err != nil {
+			return err
+		}
+		unused := service.UnreferencedModules(modules, refs)
+		printUnusedModules(out, unused, len(roots))
+		if len(unused) == 0 || dryRun {
+			return nil
+		}
+
+		if !yes {
+			if !isTerminal(os.Stdin) {
+				return fmt.Errorf("refusing to delete without confirmation, add --yes when stdin is not a terminal")
+			}
+			if !confirm(cmd.InOrStdin(), out, fmt.Sprintf("Delete %d module versions?", len(unused))) {
+				return nil
+			}
+		}
+
+		guard, err := newPathGuard(cmd)
+		if err != nil {
+			return err
+		}
+		removed, err := service.PruneModules(cmd.Context(), caches.ModCache, unused, guard)
+		var reclaimed model.FileSystem
+		for _, m := range removed {
+			reclaimed.Size += m.Size
+		}
+		GlobalUsage.Count("removed", len(removed))
+		fmt.Fprintf(out, "Removed %d of %d module versions, %s reclaimed\n", len(removed), len(unused), reclaimed.GetFormattedSize())
+		return partialError(err)
+	},
+}
+
+// goCacheRoots returns the directories searched for go.mod files: the
+// repositories of the selected workspaces and the --dir directories
+func goCacheRoots(cmd *cobra.Command) ([]string, error) {
+	workspaces, _ := cmd.Flags().GetStringSlice("workspace")
+	roots, _ := cmd.Flags().GetStringSlice("dir")
+	if len(workspaces) == 0 && len(roots) == 0 {
+		for name := range GlobalConfig.Workspaces {
+			workspaces = append(workspaces, name)
+		}
+		slices.Sort(workspaces)
+	}
+	for _, name := range workspaces {
+		repos, missing, err := service.WorkspaceRepos(GlobalConfig, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range missing {
+			GlobalLogger.Warn("Workspace repository not found", "workspace", name, "repo", repo)
+		}
+		roots = append(roots, repos...)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no workspace configured, add --dir or --workspace to find the modules in use")
+	}
+	return roots, nil
+}
+
+// printGoCaches prints the cache sizes and the limit largest modules, all
+// versions summed up
+func printGoCaches(out io.Writer, caches service.GoCaches, modules []service.CachedModule, limit int) {
+	type moduleUsage struct {
+		path     string
+		versions int
+		size     int64
+	}
+	var usages []*moduleUsage
+	byPath := make(map[string]*moduleUsage)
+	for _, m := range modules {
+		usage, ok := byPath[m.Path]
+		if !ok {
+			usage = &moduleUsage{path: m.Path}
+			byPath[m.Path] = usage
+			usages = append(usages, usage)
+		}
+		usage.versions++
+		usage.size += m.Size
+	}
+	slices.SortStableFunc(usages, func(a, b *moduleUsage) int {
+		switch {
+		case a.size > b.size:
+			return -1
+		case a.size < b.size:
+			return 1
+		}
+		return 0
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CACHE\tPATH\tSIZE")
+	modSize, buildSize := caches.Sizes()
+	modCache, build := model.FileSystem{Size: modSize}, model.FileSystem{Size: buildSize}
+	fmt.Fprintf(w, "modules\t%s\t%s\n", caches.ModCache, modCache.GetFormattedSize())
+	if caches.BuildCache != "" {
+		fmt.Fprintf(w, "build\t%s\t%s\n", caches.BuildCache, build.GetFormattedSize())
+	}
+	_ = w.Flush()
+
+	if len(usages) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%d modules, %d versions\n", len(usages), len(modules))
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tVERSIONS\tSIZE")
+	for i, usage := range usages {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(w, "... %d more\t\t\n", len(usages)-limit)
+			break
+		}
+		size := model.FileSystem{Size: usage.size}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", usage.path, usage.versions, size.GetFormattedSize())
+	}
+	_ = w.Flush()
+}
+
+// printUnusedModules prints the module versions no workspace references
+func printUnusedModules(out io.Writer, unused []service.CachedModule, roots int) {
+	if len(unused) == 0 {
+		fmt.Fprintf(out, "\nEvery cached module is used by the %d searched directories\n", roots)
+		return
+	}
+	fmt.Fprintf(out, "\nNot used by the %d searched directories:\n", roots)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tSIZE")
+	var total model.FileSystem
+	for _, m := range unused {
+		size := model.FileSystem{Size: m.Size}
+		total.Size += m.Size
+		fmt.Fprintf(w, "%s\t%s\n", m, size.GetFormattedSize())
+	}
+	_ = w.Flush()
+	fmt.Fprintf(out, "\n%d module versions, %s reclaimable\n", len(unused), total.GetFormattedSize())
+}
+
+func init() {
+	goCacheCmd.Flags().Int("limit", 20, "number of modules listed, 0 for all")
+	goCacheCmd.Flags().Bool("prune", false, "delete the module versions no go.mod of the workspaces requires")
+	goCacheCmd.Flags().StringSliceP("workspace", "w", nil, "workspaces of the config whose go.mod files are kept (defaults to all of them)")
+	_ = goCacheCmd.RegisterFlagCompletionFunc("workspace", mr_repo.CompleteWorkspaces)
+	goCacheCmd.Flags().StringSliceP("dir", "d", nil, "directories whose go.mod files are kept, repeatable")
+	goCacheCmd.Flags().BoolP("yes", "y", false, "delete without asking for confirmation")
+	goCacheCmd.Flags().Bool("dry-run", false, "with --prune, only report the unused modules")
+	addForceUnsafeFlag(goCacheCmd)
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/nanaki-93/goktor/model"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// updateGolden rewrites testdata/golden instead of comparing against it:
+//
+//	go test ./cmd -run TestGoldenOutputs -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// fixtureTime dates every fixture commit, so ages and stale branches never drift
+var fixtureTime = time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+
+// goldenCase runs goktor with args and compares its stdout with
+// testdata/golden/<name>.golden. "{ws}" in args is the fixture workspace.
+type goldenCase struct {
+	name string
+	args []string
+	// posixOnly cases depend on POSIX permission bits
+	posixOnly bool
+}
+
+func TestGoldenOutputs(t *testing.T) {
+	ws := newFixtureWorkspace(t)
+
+	cases := []goldenCase{
+		{name: "file-list", args: []string{"file-list", "-d", "{ws}/files"}},
+		{name: "folder-list", args: []string{"folder-list", "-d", "{ws}/files", "--min-size", "0"}},
+		{name: "folder-list-tree", args: []string{"folder-list", "-d", "{ws}/files", "--min-size", "0", "--tree"}},
+		{name: "folder-list-tree-ascii", args: []string{"folder-list", "-d", "{ws}/files", "--min-size", "0", "--tree", "--no-color"}},
+		{name: "folder-list-sort-name", args: []string{"folder-list", "-d", "{ws}/files", "--min-size", "0", "--sort", "name", "--reverse"}},
+		{name: "folder-list-max-depth", args: []string{"folder-list", "-d", "{ws}", "--min-size", "0", "--max-depth", "1", "--tree"}},
+		{name: "file-list-max-depth", args: []string{"file-list", "-d", "{ws}/files", "--max-depth", "1", "--sort", "size"}},
+		{name: "folder-list-min-size", args: []string{"folder-list", "-d", "{ws}/files", "--min-size", "1KB"}},
+		{name: "du", args: []string{"du", "-d", "{ws}/files", "--depth", "2"}},
+		{name: "du-si", args: []string{"--si", "du", "-d", "{ws}/files", "--depth", "2"}},
+		{name: "file-stats", args: []string{"file-stats", "-d", "{ws}/files", "--extensions"}},
+		{name: "file-stats-json", args: []string{"file-stats", "-d", "{ws}/files", "--json"}},
+		{name: "hash", args: []string{"hash", "{ws}/files"}},
+		{name: "perms-audit", args: []string{"perms", "audit", "-d", "{ws}/perms"}, posixOnly: true},
+		{name: "usage-empty", args: []string{"usage"}},
+		{name: "mr-repo-schema", args: []string{"mr-repo", "--schema"}},
+		{name: "mr-repo-status", args: []string{"mr-repo", "status", "--root", "{ws}/repos"}},
+		{name: "mr-repo-fetch-estimate", args: []string{"mr-repo", "fetch", "--estimate", "--root", "{ws}/repos"}},
+		{name: "mr-repo-pull", args: []string{"mr-repo", "pull", "--root", "{ws}/repos"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.posixOnly && runtime.GOOS == "windows" {
+				t.Skip("POSIX permission bits not available")
+			}
+			args := make([]string, len(tc.args))
+			for i, arg := range tc.args {
+				args[i] = filepath.FromSlash(strings.ReplaceAll(arg, "{ws}", ws))
+			}
+
+			out, err := runGoktor(t, args)
+			if err != nil {
+				t.Fatalf("goktor %v error = %v", tc.args, err)
+			}
+			assertGolden(t, tc.name, normalizeOutput(out, ws))
+		})
+	}
+}
+
+// runGoktor executes the root command with args and returns what it wrote to
+// stdout. Logs go to a temp file and flags are reset before and after the run,
+// as cobra keeps flag values between executions, like the size units of --si.
+func runGoktor(t *testing.T, args []string) (string, error) {
+	t.Helper()
+	resetFlags(RootCmd)
+	t.Cleanup(func() {
+		resetFlags(RootCmd)
+		model.SetSizeUnits(model.IEC)
+	})
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	RootCmd.SetOut(writer)
+	RootCmd.SetErr(io.Discard)
+	defer func() {
+		os.Stdout = stdout
+		RootCmd.SetOut(nil)
+		RootCmd.SetErr(nil)
+	}()
+
+	captured := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, reader)
+		captured <- buf.String()
+	}()
+
+	RootCmd.SetArgs(append(args, "--log-file", filepath.Join(t.TempDir(), "goktor.log")))
+	runErr := RootCmd.Execute()
+	_ = writer.Close()
+	return <-captured, runErr
+}
+
+// resetFlags restores the default value of every flag of cmd and its subcommands
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			_ = slice.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// normalizeOutput replaces the fixture location with a placeholder and uses
+// forward slashes, so goldens are the same on every machine
+func normalizeOutput(out string, ws string) string {
+	out = strings.ReplaceAll(out, ws, "<workspace>")
+	if runtime.GOOS == "windows" {
+		out = strings.ReplaceAll(out, `\`, "/")
+		out = strings.ReplaceAll(out, "\r\n", "\n")
+	}
+	return out
+}
+
+func assertGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run with -update to create it: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s, run with -update if the change is intended\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+// newFixtureWorkspace builds, in a temp dir used as home directory:
+//
+//	files/     a small tree of text, code and image files
+//	perms/     a world-writable file
+//	repos/     alpha, cloned from remotes/alpha.git with a stale feature branch,
+//	           and beta, without origin and with uncommitted changes
+func newFixtureWorkspace(t *testing.T) string {
+	t.Helper()
+	ws, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temp dir: %v", err)
+	}
+	t.Setenv("HOME", filepath.Join(ws, "home"))
+	t.Setenv("USERPROFILE", filepath.Join(ws, "home"))
+	t.Setenv("GOKTOR_USAGE_LOG", "")
+
+	writeFixture(t, ws, "files/a.txt", "hello", 0o644)
+	writeFixture(t, ws, "files/sub/main.go", "package main\n", 0o644)
+	writeFixture(t, ws, "files/sub/pic.png", strings.Repeat("\x00", 2048), 0o644)
+	writeFixture(t, ws, "perms/shared.txt", "shared", 0o644)
+	if err := os.Chmod(filepath.Join(ws, "perms", "shared.txt"), 0o666); err != nil {
+		t.Fatalf("failed to chmod fixture: %v", err)
+	}
+
+	remote := filepath.Join(ws, "remotes", "alpha.git")
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatalf("failed to init remote: %v", err)
+	}
+	alpha := fixtureRepo(t, filepath.Join(ws, "repos", "alpha"))
+	head, _ := alpha.Head()
+	if err := alpha.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), head.Hash())); err != nil {
+		t.Fatalf("failed to create feature branch: %v", err)
+	}
+	if _, err := alpha.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatalf("failed to create origin: %v", err)
+	}
+	if err := alpha.Push(&git.PushOptions{RefSpecs: []config.RefSpec{"refs/heads/*:refs/heads/*"}}); err != nil {
+		t.Fatalf("failed to push fixture: %v", err)
+	}
+	if err := alpha.Fetch(&git.FetchOptions{}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		t.Fatalf("failed to fetch fixture: %v", err)
+	}
+
+	fixtureRepo(t, filepath.Join(ws, "repos", "beta"))
+	writeFixture(t, ws, "repos/beta/README.md", "changed", 0o644)
+	return ws
+}
+
+// fixtureRepo creates a repository with a README committed at fixtureTime
+func fixtureRepo(t *testing.T, path string) *git.Repository {
+	t.Helper()
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatalf("failed to init %s: %v", path, err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "README.md"), []byte("fixture"), 0o644); err != nil {
+		t.Fatalf("failed to write README: %v", err)
+	}
+	worktree, _ := repo.Worktree()
+	if _, err := worktree.Add("README.md"); err != nil {
+		t.Fatalf("failed to add README: %v", err)
+	}
+	signature := &object.Signature{Name: "Fixture", Email: "fixture@example.com", When: fixtureTime}
+	if _, err := worktree.Commit("initial commit", &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+		t.Fatalf("failed to commit README: %v", err)
+	}
+	return repo
+}
+
+func writeFixture(t *testing.T, ws string, name string, content string, mode os.FileMode) {
+	t.Helper()
+	path := filepath.Join(ws, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// hashCmd prints the checksums of a file or of every file of a directory
+var hashCmd = &cobra.Command{
+	Use:   "hash <path>",
+	Short: "Compute the checksums of a file or of every file of a directory",
+	Long: `Compute the checksum of a file, or of every regular file below a directory,
+hashing up to --workers files in parallel. The manifest lists one "<sum>  <path>"
+line per file, with paths relative to the directory, in the format checked by
+sha256sum -c and the like. It is printed, or written to --out.
+
+The tree checksum is the checksum of the manifest: two directories have the same
+tree checksum when they hold the same files with the same content, which makes it
+a quick check of a backup made by archive or mirror.`,
+	Example: `  goktor hash --algo sha256 ~/backup --out backup.sha256
+  goktor hash ./release.iso`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		algo, _ := cmd.Flags().GetString("algo")
+		workers, _ := cmd.Flags().GetInt("workers")
+		outPath, _ := cmd.Flags().GetString("out")
+		if workers < 0 {
+			return fmt.Errorf("invalid --workers %d, it cannot be negative", workers)
+		}
+
+		tree, err := service.HashTree(cmd.Context(), args[0], algo, workers)
+		if err != nil {
+			return err
+		}
+		GlobalUsage.Count("files", len(tree.Files))
+
+		summary := os.Stderr
+		if outPath != "" {
+			file, err := os.Create(outPath)
+			if err != nil {
+				return fmt.Errorf("failed to create manifest: %w", err)
+			}
+			if err := tree.WriteManifest(file); err != nil {
+				file.Close()
+				return fmt.Errorf("failed to write manifest: %w", err)
+			}
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("failed to write manifest: %w", err)
+			}
+			summary = os.Stdout
+			fmt.Fprintln(summary, "Manifest written to", outPath)
+		} else if err := tree.WriteManifest(os.Stdout); err != nil {
+			return err
+		}
+
+		size := model.FileSystem{Size: tree.Size()}
+		fmt.Fprintf(summary, "Tree %s: %s (%d files, %s)\n", tree.Algorithm, tree.Sum, len(tree.Files), size.GetFormattedSize())
+		return nil
+	},
+}
+
+func init() {
+	hashCmd.Flags().String("algo", "sha256", "hash algorithm: md5, sha1, sha256 or sha512")
+	hashCmd.Flags().Int("workers", 0, "number of files hashed in parallel, the number of CPUs by default")
+	hashCmd.Flags().String("out", "", "write the manifest to this file instead of printing it")
+}
@@ -0,0 +1,99 @@
+package mr_repo
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// completionConfig returns the config the completions read. The root hook
+// loading it ignores the flags of the command line being completed, so a
+// --config on that line is read here.
+func completionConfig(cmd *cobra.Command) *service.Config {
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		if config, err := service.LoadConfig(path); err == nil {
+			return config
+		}
+	}
+	return mrRepoConfig
+}
+
+// CompleteWorkspaces suggests the names of the workspaces of the config
+func CompleteWorkspaces(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return service.WorkspaceNames(completionConfig(cmd)), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRepos suggests the directory names of the repositories of the
+// workspace roots for --repo, falling back to directories for a path
+func completeRepos(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if strings.HasPrefix(toComplete, ".") || strings.ContainsAny(toComplete, `/\`) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+	var names []string
+	for _, repo := range completionRepos(cmd, false) {
+		names = append(names, filepath.Base(repo))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeBranches suggests the local and remote branches of the selected
+// repositories
+func completeBranches(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	var names []string
+	for _, repo := range completionRepos(cmd, true) {
+		branches, err := service.BranchNames(repo)
+		if err != nil {
+			continue
+		}
+		names = append(names, branches...)
+	}
+	slices.Sort(names)
+	return slices.Compact(names), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionRepos returns the repositories selected by the workspace flags of
+// the command line being completed, without --repo unless withRepo is set.
+// Unlike workspaceRepos it logs nothing, the shell reads stdout.
+func completionRepos(cmd *cobra.Command, withRepo bool) []string {
+	filter := repoFilterFromFlags(cmd)
+	if !withRepo {
+		filter.repo = ""
+	} else if repo, _ := cmd.Flags().GetString("repo"); repo != "" && isRepoPath(repo) {
+		if abs, err := filepath.Abs(repo); err == nil {
+			return []string{abs}
+		}
+	}
+
+	var repos []string
+	if workspace, _ := cmd.Flags().GetString("workspace"); workspace != "" {
+		repos, _, _ = service.WorkspaceRepos(completionConfig(cmd), workspace)
+	} else {
+		roots, _ := cmd.Flags().GetStringSlice("root")
+		if len(roots) == 0 {
+			dir, err := workingDir(cmd)
+			if err != nil {
+				return nil
+			}
+			roots = []string{dir}
+		}
+		for _, root := range roots {
+			dirs, err := ListRepoDirs(root)
+			if err != nil {
+				continue
+			}
+			repos = append(repos, dirs...)
+		}
+	}
+	return filter.filterRepos(repos)
+}
+
+// registerWorkspaceCompletions completes the --repo and --workspace flags
+// added by addWorkspaceFlags, and the --protect branches
+func registerWorkspaceCompletions(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("repo", completeRepos)
+	_ = cmd.RegisterFlagCompletionFunc("workspace", CompleteWorkspaces)
+	_ = cmd.RegisterFlagCompletionFunc("protect", completeBranches)
+}
@@ -0,0 +1,279 @@
+package mr_repo
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Move inactive repositories out of the workspace, or snapshot them to tar.gz",
+	Long: `For every git project in the current directory, archive the repositories without
+commits or fetches for --inactive-months. Repositories with uncommitted changes are
+never archived, nor are repositories whose local branches hold commits missing from
+origin, unless --allow-unmerged is set.
+
+Archived repositories are moved into --to, or replaced by a git bundle of all their
+refs with --bundle; repositories with untracked or ignored files or a stash, which a
+bundle would lose, are not bundled. The archive directory keeps a goktor-archive.json
+manifest with the original location, remote and date of every archived repository; a
+bundle is restored with "git clone <name>.bundle".
+
+With --out instead of --to, every repository is left in place and snapshot to a
+timestamped <name>-<YYYYMMDD-HHMMSS>.tar.gz archive of its worktree in --out, or of
+its .git directory alone with --bare. --include-files keeps only the files matching
+one of its patterns and --exclude-files drops the matching ones; a pattern matches
+the path of a file relative to the repository, one of its parent directories, or
+its name.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if out, _ := cmd.Flags().GetString("out"); out != "" {
+			return runTarArchive(cmd, out)
+		}
+		archiveDir, _ := cmd.Flags().GetString("to")
+		months, _ := cmd.Flags().GetInt("inactive-months")
+		bundle, _ := cmd.Flags().GetBool("bundle")
+		allowUnmerged, _ := cmd.Flags().GetBool("allow-unmerged")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if months < 1 {
+			return fmt.Errorf("--inactive-months must be at least 1")
+		}
+		archiveDir, err := filepath.Abs(archiveDir)
+		if err != nil {
+			return fmt.Errorf("invalid --to: %w", err)
+		}
+		mode := service.ArchiveModeMove
+		if bundle {
+			mode = service.ArchiveModeBundle
+		}
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+		manifest, err := service.LoadArchiveManifest(archiveDir)
+		if err != nil {
+			return err
+		}
+
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		criteria := service.ArchiveCriteria{
+			InactiveFor:   time.Duration(months) * 30 * 24 * time.Hour,
+			AllowUnmerged: allowUnmerged,
+		}
+		opts := gitOptions(cmd)
+		opts.DryRun = dryRun
+		var archived []service.ArchiveEntry
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			if repoDir == archiveDir {
+				run.Set(i, service.RunStatusDone, nil, nil)
+				continue
+			}
+			check, err := gs.CheckArchivable(ctx, repoDir, criteria, opts)
+			if err != nil {
+				mrRepoLogger.Warn("CheckArchivable failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			if check.KeepReason != "" {
+				mrRepoLogger.Info("Kept repository", "repo", repoDir, "reason", check.KeepReason, "last_activity", check.LastActivity)
+				run.SetResult(i, check)
+				run.Set(i, service.RunStatusDone, map[string]int{"kept": 1}, nil)
+				continue
+			}
+			if !dryRun && confirmer.asking() && confirmer.skip(run, i, archivePlan(check, archiveDir, mode)) {
+				continue
+			}
+
+			entry, err := gs.ArchiveRepository(ctx, repoDir, archiveDir, mode, opts)
+			if err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("ArchiveRepository failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			mrRepoUsage.Count("archived", 1)
+			archived = append(archived, *entry)
+			run.SetResult(i, entry)
+			run.Set(i, service.RunStatusDone, map[string]int{"archived": 1}, nil)
+			if dryRun {
+				mrRepoLogger.Info("DryRun repository to archive", "repo", repoDir, "path", entry.Path)
+				continue
+			}
+			mrRepoLogger.Info("Archived repository", "repo", repoDir, "path", entry.Path)
+
+			// saved after every repository, so an interrupted run never loses track of a move
+			manifest.Entries = append(manifest.Entries, *entry)
+			if err := service.SaveArchiveManifest(archiveDir, manifest); err != nil {
+				return err
+			}
+		}
+
+		printArchiveSummary(mrRepoOut, archived, len(repoDirs), dryRun)
+		return nil
+	},
+}
+
+// runTarArchive snapshots every repository of the workspace to a tar.gz archive in outDir
+func runTarArchive(cmd *cobra.Command, outDir string) error {
+	bare, _ := cmd.Flags().GetBool("bare")
+	include, _ := cmd.Flags().GetStringSlice("include-files")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude-files")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	skipSpaceCheck, _ := cmd.Flags().GetBool("skip-space-check")
+	outDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return fmt.Errorf("invalid --out: %w", err)
+	}
+
+	repoDirs, err := workspaceRepos(cmd)
+	if err != nil {
+		return err
+	}
+	if !dryRun && !skipSpaceCheck {
+		if err := service.CheckTarArchiveSpace(repoDirs, outDir, bare); err != nil {
+			return fmt.Errorf("disk space preflight failed: %w", err)
+		}
+	}
+	confirmer, err := startConfirmer(cmd)
+	if err != nil {
+		return err
+	}
+	gs := newGitService(cmd)
+	ctx := cmd.Context()
+	run := newRunRecord(cmd, repoDirs)
+	defer finishRun(ctx, run)
+
+	// one timestamp for the whole run, so the archives of a batch sort together
+	now := time.Now()
+	var archives []service.TarArchive
+	for i, repoDir := range repoDirs {
+		if ctx.Err() != nil || confirmer.stopped() {
+			break
+		}
+		if run.Start(i) != nil {
+			continue
+		}
+		if !dryRun && confirmer.asking() && confirmer.skip(run, i, fmt.Sprintf("write %s", filepath.Join(outDir, service.TarArchiveName(repoDir, bare, now)))) {
+			continue
+		}
+		opts := service.TarArchiveOptions{Options: gitOptions(cmd), Bare: bare, Include: include, Exclude: exclude, Now: now}
+		opts.DryRun = dryRun
+		archive, err := gs.TarArchiveRepository(ctx, repoDir, outDir, opts)
+		if err != nil {
+			mrRepoUsage.Count("failed", 1)
+			mrRepoLogger.Warn("TarArchiveRepository failed", "repo", repoDir, "error", err)
+			run.Set(i, runStatus(ctx, err), nil, err)
+			continue
+		}
+		mrRepoUsage.Count("archived", 1)
+		archives = append(archives, *archive)
+		run.SetResult(i, archive)
+		run.Set(i, service.RunStatusDone, map[string]int{"archived": 1}, nil)
+	}
+
+	printTarArchives(mrRepoOut, archives, len(repoDirs), dryRun)
+	return nil
+}
+
+// printTarArchives prints a row per tar.gz archive and their total size
+func printTarArchives(out io.Writer, archives []service.TarArchive, total int, dryRun bool) {
+	verb := "Archived"
+	if dryRun {
+		verb = "Would archive"
+	}
+	var written model.FileSystem
+	if len(archives) > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ARCHIVE\tFILES\tSIZE\tCOMPRESSED")
+		for _, archive := range archives {
+			size := model.FileSystem{Size: archive.Size}
+			compressed := "-"
+			if archive.Compressed > 0 {
+				written.Size += archive.Compressed
+				file := model.FileSystem{Size: archive.Compressed}
+				compressed = file.GetFormattedSize()
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", archive.Path, archive.Files, size.GetFormattedSize(), compressed)
+		}
+		_ = w.Flush()
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintf(out, "%s %d of %d repositories", verb, len(archives), total)
+	if written.Size > 0 {
+		fmt.Fprintf(out, ", %s written", written.GetFormattedSize())
+	}
+	fmt.Fprintln(out)
+}
+
+// archivePlan describes how archive stores an archivable repository
+func archivePlan(check *service.ArchiveCheck, archiveDir string, mode string) string {
+	lastActivity := check.LastActivity.Format(time.DateOnly)
+	if mode == service.ArchiveModeBundle {
+		return fmt.Sprintf("replace by a git bundle in %s, inactive since %s", archiveDir, lastActivity)
+	}
+	return fmt.Sprintf("move to %s, inactive since %s", archiveDir, lastActivity)
+}
+
+// printArchiveSummary prints a row per archived repository and the space reclaimed
+func printArchiveSummary(out io.Writer, archived []service.ArchiveEntry, total int, dryRun bool) {
+	verb := "Archived"
+	if dryRun {
+		verb = "Would archive"
+	}
+	var reclaimed int64
+	if len(archived) > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPOSITORY\tLAST ACTIVITY\tRECLAIMED\tARCHIVED TO")
+		for _, entry := range archived {
+			reclaimed += entry.Reclaimed
+			size := model.FileSystem{Size: entry.Reclaimed}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Name, entry.LastActivity.Format(time.DateOnly), size.GetFormattedSize(), entry.Path)
+		}
+		_ = w.Flush()
+		fmt.Fprintln(out)
+	}
+	size := model.FileSystem{Size: reclaimed}
+	fmt.Fprintf(out, "%s %d of %d repositories, %s reclaimed\n", verb, len(archived), total, size.GetFormattedSize())
+}
+
+func init() {
+	addInteractiveFlag(archiveCmd)
+	archiveCmd.Flags().String("to", "", "archive directory receiving the repositories or bundles")
+	archiveCmd.Flags().Int("inactive-months", 6, "archive repositories without commits or fetches for this many months")
+	archiveCmd.Flags().Bool("bundle", false, "replace each repository with a git bundle of all its refs instead of moving it")
+	archiveCmd.Flags().Bool("allow-unmerged", false, "also archive repositories whose branches hold commits missing from origin")
+	archiveCmd.Flags().String("out", "", "directory receiving a timestamped tar.gz snapshot of every repository, left in place")
+	archiveCmd.Flags().Bool("bare", false, "with --out, archive the .git directory instead of the worktree")
+	archiveCmd.Flags().StringSlice("include-files", nil, "with --out, archive only the files matching these glob patterns")
+	archiveCmd.Flags().StringSlice("exclude-files", nil, "with --out, leave out the files matching these glob patterns")
+	archiveCmd.Flags().BoolP("dry-run", "d", false, "only report the repositories that would be archived")
+	archiveCmd.Flags().Bool("skip-space-check", false, "with --out, skip the free disk space preflight check")
+	archiveCmd.MarkFlagsOneRequired("to", "out")
+	archiveCmd.MarkFlagsMutuallyExclusive("to", "out")
+	archiveCmd.MarkFlagsMutuallyExclusive("out", "bundle")
+	archiveCmd.MarkFlagsMutuallyExclusive("out", "allow-unmerged")
+}
@@ -0,0 +1,126 @@
+package mr_repo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "Create a branch in every repository",
+	Long: `For every git project in the current directory, create the --create branch from
+--from, a branch, tag or commit (the default branch of origin by default), without
+checking it out. A --from branch is taken from origin when it has it; add --fetch to
+start from its latest state. With --push the branch is pushed to origin and tracks
+it; a branch whose push failed is removed again so the run can be retried.
+Repositories that already have the branch are left as they are.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("create")
+		from, _ := cmd.Flags().GetString("from")
+		fetch, _ := cmd.Flags().GetBool("fetch")
+		push, _ := cmd.Flags().GetBool("push")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		results := make([]*service.CreateBranchResult, len(repoDirs))
+		errs := make([]error, len(repoDirs))
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			opts := service.CreateBranchOptions{Options: gitOptions(cmd), From: from, Fetch: fetch, Push: push}
+			if !dryRun && confirmer.asking() && confirmer.skip(run, i, branchPlan(ctx, gs, repoDir, name, opts)) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			opts.DryRun = dryRun
+			results[i], errs[i] = gs.CreateBranch(ctx, repoDir, name, opts)
+			if errs[i] != nil {
+				mrRepoUsage.Count(service.RunStatusFailed, 1)
+				mrRepoLogger.Warn("CreateBranch failed", "repo", repoDir, "error", errs[i])
+				run.Set(i, runStatus(ctx, errs[i]), nil, errs[i])
+				continue
+			}
+			mrRepoUsage.Count(results[i].Status, 1)
+			run.SetResult(i, results[i])
+			run.Set(i, service.RunStatusDone, map[string]int{results[i].Status: 1}, nil)
+		}
+
+		printBranchResults(mrRepoOut, repoDirs, results, errs, dryRun)
+		return nil
+	},
+}
+
+// branchPlan describes the branch created in the repository, or is empty when
+// it exists or its base is missing
+func branchPlan(ctx context.Context, gs service.GitService, repoPath string, name string, opts service.CreateBranchOptions) string {
+	opts.DryRun = true
+	result, err := gs.CreateBranch(ctx, repoPath, name, opts)
+	if err != nil {
+		return fmt.Sprintf("create %s (%v)", name, err)
+	}
+	if result.Status == service.BranchStatusExists || result.Status == service.BranchStatusMissingBase {
+		return ""
+	}
+	plan := fmt.Sprintf("create %s from %s at %s", name, result.From, shortHash(result.Commit))
+	if opts.Push {
+		plan += " and push it"
+	}
+	return plan
+}
+
+// printBranchResults prints the outcome of every processed repository
+func printBranchResults(out io.Writer, repoDirs []string, results []*service.CreateBranchResult, errs []error, dryRun bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tSTATUS\tFROM\tCOMMIT")
+	for i, repoDir := range repoDirs {
+		name := filepath.Base(repoDir)
+		switch result := results[i]; {
+		case errs[i] != nil:
+			fmt.Fprintf(w, "%s\tfailed: %v\t\t\n", name, errs[i])
+		case result == nil:
+			continue
+		default:
+			status := result.Status
+			if dryRun && (status == service.BranchStatusCreated || status == service.BranchStatusPushed) {
+				status = "would be " + status
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, status, result.From, shortHash(result.Commit))
+		}
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	addInteractiveFlag(branchCmd)
+	branchCmd.Flags().String("create", "", "name of the branch to create")
+	branchCmd.Flags().String("from", "", "branch, tag or commit to start from (defaults to the default branch of origin)")
+	branchCmd.Flags().Bool("fetch", false, "fetch origin first to start from its latest state")
+	branchCmd.Flags().Bool("push", false, "push the new branch to origin and track it")
+	branchCmd.Flags().BoolP("dry-run", "d", false, "only report the branches that would be created")
+	_ = branchCmd.RegisterFlagCompletionFunc("from", completeBranches)
+	_ = branchCmd.MarkFlagRequired("create")
+}
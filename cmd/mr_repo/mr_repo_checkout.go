@@ -0,0 +1,127 @@
+package mr_repo
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var checkoutCmd = &cobra.Command{
+	Use:   "checkout",
+	Short: "Check out a branch in every repository that has it",
+	Long: `For every git project in the current directory, check out --branch. When only
+origin has the branch, a local branch tracking it is created first; add --fetch to
+find branches pushed since the last fetch. Repositories without the branch are
+listed at the end, and repositories with uncommitted changes or untracked files are
+skipped.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		branch, _ := cmd.Flags().GetString("branch")
+		fetch, _ := cmd.Flags().GetBool("fetch")
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		checkpoint := startCheckpoint(cmd, repoDirs...)
+		defer finishCheckpoint(checkpoint, run)
+		defer finishRun(ctx, run)
+
+		statuses := map[string]int{}
+		var missing []string
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			if resumeRepo(checkpoint, run, i) {
+				continue
+			}
+			if confirmer.asking() && confirmer.skip(run, i, checkoutPlan(ctx, gs, repoDir, branch, gitOptions(cmd))) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			result, err := gs.CheckoutBranch(ctx, repoDir, branch, service.CheckoutOptions{Options: gitOptions(cmd), Fetch: fetch})
+			if err != nil {
+				statuses[service.RunStatusFailed]++
+				mrRepoLogger.Warn("CheckoutBranch failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			statuses[result.Status]++
+			mrRepoUsage.Count(result.Status, 1)
+			switch result.Status {
+			case service.CheckoutStatusMissing:
+				missing = append(missing, filepath.Base(repoDir))
+			case service.CheckoutStatusSkipped:
+				mrRepoLogger.Warn("Skipped repository", "repo", repoDir, "reason", result.SkipReason)
+			default:
+				mrRepoLogger.Info("Checked out branch", "repo", repoDir, "branch", branch, "status", result.Status)
+			}
+			run.SetResult(i, result)
+			run.Set(i, service.RunStatusDone, map[string]int{result.Status: 1}, service.SkipError(result.SkipReason))
+			checkpointRepo(checkpoint, run, i)
+		}
+
+		printCheckoutSummary(branch, statuses, missing)
+		return nil
+	},
+}
+
+// checkoutPlan describes the checkout, or is empty when branch is already checked out
+func checkoutPlan(ctx context.Context, gs service.GitService, repoPath string, branch string, opts service.Options) string {
+	status, err := gs.RepoStatus(ctx, repoPath, 0, opts)
+	if err == nil && status.Branch == branch {
+		return ""
+	}
+	if err == nil && status.Branch != "" {
+		return fmt.Sprintf("check out %s instead of %s", branch, status.Branch)
+	}
+	return fmt.Sprintf("check out %s", branch)
+}
+
+func printCheckoutSummary(branch string, statuses map[string]int, missing []string) {
+	if len(statuses) == 0 {
+		return
+	}
+	var parts []string
+	for _, status := range []string{
+		service.CheckoutStatusSwitched,
+		service.CheckoutStatusTracking,
+		service.CheckoutStatusCurrent,
+		service.CheckoutStatusMissing,
+		service.CheckoutStatusSkipped,
+		service.RunStatusFailed,
+	} {
+		if statuses[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", statuses[status], status))
+		}
+	}
+	fmt.Fprintln(mrRepoOut, "Checkout summary:", strings.Join(parts, ", "))
+	if len(missing) > 0 {
+		fmt.Fprintf(mrRepoOut, "No %s branch in: %s\n", branch, strings.Join(missing, ", "))
+	}
+}
+
+func init() {
+	addResumeFlag(checkoutCmd)
+	addInteractiveFlag(checkoutCmd)
+	checkoutCmd.Flags().StringP("branch", "b", "", "branch to check out")
+	checkoutCmd.Flags().Bool("fetch", false, "fetch origin first to find newly pushed branches")
+	_ = checkoutCmd.RegisterFlagCompletionFunc("branch", completeBranches)
+	_ = checkoutCmd.MarkFlagRequired("branch")
+}
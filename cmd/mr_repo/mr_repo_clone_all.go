@@ -0,0 +1,155 @@
+package mr_repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var cloneAllCmd = &cobra.Command{
+	Use:   "clone-all",
+	Short: "Clone all repositories of an organization or group",
+	Long: `List the repositories of a GitHub organization or a GitLab group (including subgroups)
+and clone into the current directory every repository that is not already present.
+The token is read from --token, or GITHUB_TOKEN / GITLAB_TOKEN.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, _ := cmd.Flags().GetString("token")
+		useSSH, _ := cmd.Flags().GetBool("ssh")
+		skipSpaceCheck, _ := cmd.Flags().GetBool("skip-space-check")
+
+		provider, owner, token, err := providerFromFlags(cmd, token)
+		if err != nil {
+			return err
+		}
+
+		currDir, err := workingDir(cmd)
+		if err != nil {
+			return err
+		}
+
+		repos, err := provider.ListRepositories(cmd.Context(), owner)
+		if err != nil {
+			return err
+		}
+		mrRepoLogger.Info("repositories found", "count", len(repos))
+
+		checkpoint := startCheckpoint(cmd, currDir, owner)
+
+		filter := repoFilterFromFlags(cmd)
+		var missing []service.RemoteRepository
+		var required int64
+		for _, repo := range repos {
+			if !filter.matches(repo.Name) {
+				continue
+			}
+			repoPath := filepath.Join(currDir, repo.Name)
+			if _, err := os.Stat(repoPath); err == nil {
+				if !checkpoint.Interrupted(repoPath) {
+					mrRepoLogger.Info("Skipped existing repository", "repo", repo.Name)
+					continue
+				}
+				// the previous run stopped while cloning: the directory may be incomplete
+				mrRepoLogger.Warn("Removing interrupted clone", "repo", repo.Name)
+				if err := os.RemoveAll(repoPath); err != nil {
+					return fmt.Errorf("failed to remove interrupted clone %s: %w", repo.Name, err)
+				}
+			}
+			missing = append(missing, repo)
+			required += repo.Size
+		}
+
+		if !skipSpaceCheck {
+			if err := service.CheckDiskSpace(currDir, required); err != nil {
+				return fmt.Errorf("disk space preflight failed: %w", err)
+			}
+		}
+
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		repoPaths := make([]string, len(missing))
+		for i, repo := range missing {
+			repoPaths[i] = filepath.Join(currDir, repo.Name)
+		}
+		run := newRunRecord(cmd, repoPaths)
+		defer finishCheckpoint(checkpoint, run)
+		defer finishRun(ctx, run)
+
+		for i, repo := range missing {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			repoPath := repoPaths[i]
+			remoteURL := repo.CloneURL
+			if useSSH {
+				remoteURL = repo.SSHURL
+			}
+			if confirmer.asking() && confirmer.skip(run, i, "clone "+remoteURL) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+
+			if err := checkpoint.Begin(repoPath); err != nil {
+				mrRepoLogger.Warn("failed to write checkpoint", "error", err)
+			}
+			opts := gitOptions(cmd)
+			opts.Auth = service.TokenAuth(remoteURL, token)
+			if err := gs.CloneRepository(ctx, remoteURL, repoPath, opts); err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("CloneRepository failed", "repo", repo.Name, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			mrRepoUsage.Count("cloned", 1)
+			run.Set(i, service.RunStatusDone, nil, nil)
+			checkpointRepo(checkpoint, run, i)
+			mrRepoLogger.Info("Cloned repository", "repo", repo.Name)
+		}
+		return nil
+	},
+}
+
+// providerFromFlags builds the provider selected by --github-org or --gitlab-group,
+// returning the owner to list and the token, defaulted from the provider env var
+func providerFromFlags(cmd *cobra.Command, token string) (service.Provider, string, string, error) {
+	org, _ := cmd.Flags().GetString("github-org")
+	group, _ := cmd.Flags().GetString("gitlab-group")
+	gitlabURL, _ := cmd.Flags().GetString("gitlab-url")
+
+	switch {
+	case org != "" && group != "":
+		return nil, "", "", fmt.Errorf("--github-org and --gitlab-group are mutually exclusive")
+	case org != "":
+		if token == "" {
+			token = os.Getenv("GITHUB_TOKEN")
+		}
+		return service.NewGitHubProvider(token, proxyFromFlags(cmd)), org, token, nil
+	case group != "":
+		if token == "" {
+			token = os.Getenv("GITLAB_TOKEN")
+		}
+		return service.NewGitLabProvider(gitlabURL, token, proxyFromFlags(cmd)), group, token, nil
+	}
+	return nil, "", "", fmt.Errorf("one of --github-org or --gitlab-group is required")
+}
+
+func init() {
+	addInteractiveFlag(cloneAllCmd)
+	addResumeFlag(cloneAllCmd)
+	cloneAllCmd.Flags().String("github-org", "", "GitHub organization to clone")
+	cloneAllCmd.Flags().String("gitlab-group", "", "GitLab group to clone, including subgroups")
+	cloneAllCmd.Flags().String("gitlab-url", service.DefaultGitLabURL, "GitLab instance URL for self-hosted installations")
+	cloneAllCmd.Flags().String("token", "", "API and HTTPS clone token (defaults to GITHUB_TOKEN or GITLAB_TOKEN)")
+	cloneAllCmd.Flags().Bool("ssh", false, "clone using SSH URLs instead of HTTPS")
+	cloneAllCmd.Flags().Bool("skip-space-check", false, "skip the free disk space preflight check")
+}
@@ -0,0 +1,82 @@
+package mr_repo
+
+import (
+	"errors"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var convertRemoteCmd = &cobra.Command{
+	Use:   "convert-remote",
+	Short: "Convert remote URLs between SSH and HTTPS for all repositories",
+	Long: `Convert the remote URLs of all git projects in the current directory between the
+SSH form (git@host:org/repo.git) and the HTTPS form (https://host/org/repo.git).
+Each converted remote is verified with a fetch and rolled back when it fails,
+unless --force is set. Local remotes are left untouched.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		remoteName, _ := cmd.Flags().GetString("remote")
+		to, _ := cmd.Flags().GetString("to")
+
+		protocol, err := service.ParseRemoteProtocol(to)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		opts := gitOptions(cmd)
+		opts.Remote, opts.Force = remoteName, force
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		for i, absPath := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			if confirmer.asking() && confirmer.skip(run, i, remotePlan(absPath, remoteName, service.RemoteChange{Protocol: protocol})) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			err := gs.ConvertRemote(ctx, absPath, protocol, opts)
+			if errors.Is(err, service.ErrRemoteUnchanged) {
+				mrRepoUsage.Count("unchanged", 1)
+				mrRepoLogger.Info("remote already uses the protocol, skipping", "repo", absPath, "protocol", protocol)
+				run.Set(i, service.RunStatusDone, nil, nil)
+				continue
+			}
+			if err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("ConvertRemote failed", "repo", absPath, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			mrRepoUsage.Count("converted", 1)
+			run.Set(i, service.RunStatusDone, nil, nil)
+		}
+		return nil
+	},
+}
+
+func init() {
+	addInteractiveFlag(convertRemoteCmd)
+	convertRemoteCmd.Flags().String("to", "", "target protocol: ssh or https")
+	convertRemoteCmd.Flags().BoolP("force", "f", false, "keep the converted URL even when the fetch fails")
+	convertRemoteCmd.Flags().String("remote", "origin", "name of the remote to convert (e.g. upstream)")
+	_ = convertRemoteCmd.MarkFlagRequired("to")
+}
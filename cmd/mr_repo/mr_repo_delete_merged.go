@@ -3,16 +3,17 @@ package mr_repo
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/nanaki-93/goktor/service"
 	"github.com/spf13/cobra"
 )
 
 var deleteMergedCmd = &cobra.Command{
-	Use:          "delete-merged",
-	Short:        "Delete all the merged branches with a end date",
-	Long:         `Delete all the merged branches with a end date passed as a mandatory argument, with format YYYY-MM-DD`,
+	Use:   "delete-merged",
+	Short: "Delete all the merged branches with a end date",
+	Long: `Delete all the merged branches with a end date passed as a mandatory argument, with format YYYY-MM-DD.
+The tip of each deleted branch is kept on the remote for --keep-days days and can be
+brought back with restore-branch. Use --keep-days 0 to delete without backup.`,
 	SilenceUsage: true,
 	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -23,47 +24,63 @@ var deleteMergedCmd = &cobra.Command{
 		}
 
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		keepDays, _ := cmd.Flags().GetInt("keep-days")
 
-		currDir, err := os.Getwd()
+		currDir, err := targetRepo(cmd)
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return err
 		}
 
-		gs := service.NewGitService(mrRepoLogger)
+		gs := newGitService(cmd)
+		gs.SetIdentity(identityFromFlags(cmd))
 
-		deletedBranches, err := gs.DeleteMergedBranches(ctx, currDir, endDate, dryRun)
+		run := newRunRecord(cmd, []string{currDir})
+		defer finishRun(ctx, run)
+
+		if err := run.Start(0); err != nil {
+			return err
+		}
+		opts := gitOptions(cmd)
+		opts.DryRun = dryRun
+		deletedBranches, err := gs.DeleteMergedBranches(ctx, currDir, endDate, keepDays, opts)
 		if err != nil {
+			run.Set(0, runStatus(ctx, err), nil, err)
 			return fmt.Errorf("failed to Delete merged branches: %w", err)
 		}
 
 		for _, result := range deletedBranches {
 			logResult(result, dryRun)
 		}
+		run.Set(0, service.RunStatusDone, nil, nil)
+		run.SetResult(0, deletedBranches)
 
 		return nil
 	},
 }
 
 func logResult(result service.DeleteMergedBranchesResult, dryRun bool) {
+	mrRepoUsage.Count("deleted", len(result.Deleted))
+	mrRepoUsage.Count("failed", len(result.Failed))
 	if dryRun {
 		for _, branch := range result.DryRun {
-			mrRepoLogger.Info("DryRun branch to delete:", branch)
+			mrRepoLogger.Info("DryRun branch to delete", "branch", branch)
 		}
 		return
 	}
 
 	for _, branch := range result.Deleted {
-		mrRepoLogger.Info("Removed branch: ", branch)
+		mrRepoLogger.Info("Removed branch", "branch", branch)
 	}
 	for _, branch := range result.Skipped {
-		mrRepoLogger.Info("Skipped branch: ", branch)
+		mrRepoLogger.Info("Skipped branch", "branch", branch)
 	}
 	for _, branch := range result.Failed {
-		mrRepoLogger.Warn("Failed branch: ", branch)
+		mrRepoLogger.Warn("Failed branch", "branch", branch)
 	}
 }
 
 func init() {
 
 	deleteMergedCmd.Flags().BoolP("dry-run", "d", false, "dry run")
+	deleteMergedCmd.Flags().Int("keep-days", 30, "days a deleted branch stays restorable with restore-branch, 0 disables the backup")
 }
@@ -0,0 +1,155 @@
+package mr_repo
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "Fetch origin of every repository without touching the branches",
+	Long: `For every git project in the current directory, fetch the branches and tags of origin.
+
+With --estimate nothing is downloaded: origin is asked for its refs, the changed ones
+are counted and the download is approximated from the repository size reported by
+GitHub or GitLab, minus what is already stored locally. Use it on metered connections
+to decide which repositories to defer. API tokens are read from GITHUB_TOKEN and
+GITLAB_TOKEN; repositories hosted elsewhere are reported with an unknown size.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		estimate, _ := cmd.Flags().GetBool("estimate")
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+
+		if estimate {
+			providers, err := estimateProviders(cmd)
+			if err != nil {
+				return err
+			}
+			// nothing is downloaded, so the run is reported but not kept in the runs store
+			run := newRunRecord(cmd, repoDirs)
+			estimates := make([]*service.FetchEstimate, len(repoDirs))
+			for i, repoDir := range repoDirs {
+				if ctx.Err() != nil {
+					break
+				}
+				if run.Start(i) != nil {
+					continue
+				}
+				if estimates[i], err = gs.EstimateFetch(ctx, repoDir, providers, gitOptions(cmd)); err != nil {
+					mrRepoLogger.Warn("EstimateFetch failed", "repo", repoDir, "error", err)
+					run.Set(i, runStatus(ctx, err), nil, err)
+					continue
+				}
+				run.SetResult(i, estimates[i])
+				run.Set(i, service.RunStatusDone, nil, nil)
+			}
+			printFetchEstimates(mrRepoOut, repoDirs, estimates)
+			run.Interrupted = ctx.Err() != nil
+			reportRun(run)
+			return ctx.Err()
+		}
+
+		run := newRunRecord(cmd, repoDirs)
+		checkpoint := startCheckpoint(cmd, repoDirs...)
+		defer finishCheckpoint(checkpoint, run)
+		defer finishRun(ctx, run)
+
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil {
+				break
+			}
+			if resumeRepo(checkpoint, run, i) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			if err := gs.FetchLatest(ctx, repoDir, gitOptions(cmd)); err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("FetchLatest failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			mrRepoUsage.Count("fetched", 1)
+			mrRepoLogger.Info("Fetched repository", "repo", repoDir)
+			run.Set(i, service.RunStatusDone, nil, nil)
+			checkpointRepo(checkpoint, run, i)
+		}
+		return nil
+	},
+}
+
+// estimateProviders returns the providers sizing the repositories hosted on
+// github.com and on the --gitlab-url instance
+func estimateProviders(cmd *cobra.Command) (map[string]service.Provider, error) {
+	gitlabURL, _ := cmd.Flags().GetString("gitlab-url")
+	parsed, err := url.Parse(gitlabURL)
+	if err != nil || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid --gitlab-url %q", gitlabURL)
+	}
+	proxy := proxyFromFlags(cmd)
+	return map[string]service.Provider{
+		"github.com":      service.NewGitHubProvider(os.Getenv("GITHUB_TOKEN"), proxy),
+		parsed.Hostname(): service.NewGitLabProvider(gitlabURL, os.Getenv("GITLAB_TOKEN"), proxy),
+	}, nil
+}
+
+// printFetchEstimates prints a row per repository and the total of the known
+// sizes; a nil estimate is a repository that could not be queried
+func printFetchEstimates(out io.Writer, repoDirs []string, estimates []*service.FetchEstimate) {
+	var total int64
+	var pending, unknown int
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tCHANGED REFS\tDOWNLOAD")
+	for i, estimate := range estimates {
+		name := filepath.Base(repoDirs[i])
+		if estimate == nil {
+			unknown++
+			fmt.Fprintf(w, "%s\t-\t(unreachable)\n", name)
+			continue
+		}
+		size := model.FileSystem{Size: estimate.Bytes}
+		switch estimate.Source {
+		case service.FetchSizeUpToDate:
+			fmt.Fprintf(w, "%s\t%d\tnothing\n", name, estimate.Refs)
+			continue
+		case service.FetchSizeUnknown:
+			unknown++
+			fmt.Fprintf(w, "%s\t%d\tunknown\n", name, estimate.Refs)
+		default:
+			total += estimate.Bytes
+			fmt.Fprintf(w, "%s\t%d\t~%s\n", name, estimate.Refs, size.GetFormattedSize())
+		}
+		pending++
+	}
+	_ = w.Flush()
+
+	totalSize := model.FileSystem{Size: total}
+	fmt.Fprintf(out, "\n%d of %d repositories have updates, estimated download ~%s", pending, len(estimates), totalSize.GetFormattedSize())
+	if unknown > 0 {
+		fmt.Fprintf(out, " (%d repositories of unknown size not counted)", unknown)
+	}
+	fmt.Fprintln(out)
+}
+
+func init() {
+	addResumeFlag(fetchCmd)
+	fetchCmd.Flags().Bool("estimate", false, "only estimate the download size of each repository, without fetching")
+	fetchCmd.Flags().String("gitlab-url", service.DefaultGitLabURL, "GitLab instance used to size repositories hosted on self-hosted installations")
+}
@@ -0,0 +1,99 @@
+package mr_repo
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Repack objects and delete loose objects in every repository",
+	Long: `For every git project in the current directory, pack the objects reachable from the
+refs into a single pack, delete the loose objects now packed and the unreachable ones
+older than --prune-older-than, then report the space reclaimed in each .git directory.
+
+Like git gc, the objects referenced by the reflogs, the stash entries, the index and
+the linked worktrees are kept. Use --use-cli to run "git gc" instead, which is faster
+on large repositories.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		useCLI, _ := cmd.Flags().GetBool("use-cli")
+		pruneAge, _ := cmd.Flags().GetDuration("prune-older-than")
+		if pruneAge <= 0 {
+			return fmt.Errorf("--prune-older-than must be positive")
+		}
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		opts := service.GCOptions{Options: gitOptions(cmd), UseCLI: useCLI, PruneAge: pruneAge}
+		results := make([]*service.GCResult, len(repoDirs))
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil {
+				break
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			result, err := gs.GarbageCollect(ctx, repoDir, opts)
+			if err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("GarbageCollect failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			mrRepoUsage.Count("collected", 1)
+			results[i] = result
+			run.SetResult(i, result)
+			run.Set(i, service.RunStatusDone, map[string]int{
+				"packed": result.PackedObjects,
+				"pruned": result.PrunedObjects,
+			}, nil)
+		}
+
+		printGCSummary(mrRepoOut, repoDirs, results)
+		return nil
+	},
+}
+
+// printGCSummary prints the .git size of every collected repository before
+// and after the collection, then the total space reclaimed
+func printGCSummary(out io.Writer, repoDirs []string, results []*service.GCResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tBEFORE\tAFTER\tRECLAIMED")
+	var total int64
+	collected := 0
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		collected++
+		total += result.Reclaimed
+		before := model.FileSystem{Size: result.SizeBefore}
+		after := model.FileSystem{Size: result.SizeAfter}
+		reclaimed := model.FileSystem{Size: result.Reclaimed}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", filepath.Base(repoDirs[i]), before.GetFormattedSize(), after.GetFormattedSize(), reclaimed.GetFormattedSize())
+	}
+	_ = w.Flush()
+	size := model.FileSystem{Size: total}
+	fmt.Fprintf(out, "\nCollected %d of %d repositories, %s reclaimed\n", collected, len(repoDirs), size.GetFormattedSize())
+}
+
+func init() {
+	gcCmd.Flags().Bool("use-cli", false, `run "git gc" instead of the built-in repack`)
+	gcCmd.Flags().Duration("prune-older-than", service.DefaultGCPruneAge, "delete the unreachable loose objects older than this")
+}
@@ -0,0 +1,128 @@
+package mr_repo
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Create or update a bare mirror of every repository",
+	Long: `For every git project in the current directory, update the bare mirror <name>.git
+under --to with all its branches, tags, remote-tracking branches and stashes; refs
+deleted from the repository are deleted from the mirror too. --to is a directory,
+where missing mirrors are created, or the base URL of a git server, where the
+mirrors must already exist. Repositories with the same name cannot share --to, and
+a local mirror is refused to any repository but the one that created it. Restore a
+repository with git clone <to>/<name>.git.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetString("to")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		skipSpaceCheck, _ := cmd.Flags().GetBool("skip-space-check")
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+		if err := service.CheckMirrorTargets(repoDirs, to); err != nil {
+			return err
+		}
+		if !dryRun && !skipSpaceCheck {
+			if err := service.CheckMirrorSpace(repoDirs, to); err != nil {
+				return fmt.Errorf("disk space preflight failed: %w", err)
+			}
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		checkpoint := startCheckpoint(cmd, repoDirs...)
+		defer finishCheckpoint(checkpoint, run)
+		defer finishRun(ctx, run)
+
+		results := make([]*service.MirrorResult, len(repoDirs))
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil {
+				break
+			}
+			if resumeRepo(checkpoint, run, i) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			opts := gitOptions(cmd)
+			opts.DryRun = dryRun
+			if results[i], err = gs.MirrorRepository(ctx, repoDir, to, opts); err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("MirrorRepository failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			mrRepoUsage.Count("mirrored", 1)
+			run.SetResult(i, results[i])
+			run.Set(i, service.RunStatusDone, nil, nil)
+			checkpointRepo(checkpoint, run, i)
+		}
+
+		printMirrors(mrRepoOut, repoDirs, results, dryRun)
+		return nil
+	},
+}
+
+// printMirrors prints the mirror of every repository; a nil result is a
+// repository that failed or was not processed
+func printMirrors(out io.Writer, repoDirs []string, results []*service.MirrorResult, dryRun bool) {
+	var total model.FileSystem
+	mirrored := 0
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tMIRROR\tSTATUS\tSIZE")
+	for i, result := range results {
+		name := filepath.Base(repoDirs[i])
+		if result == nil {
+			fmt.Fprintf(w, "%s\t-\tfailed\t-\n", name)
+			continue
+		}
+		mirrored++
+		status := "updated"
+		switch {
+		case dryRun && result.Created:
+			status = "would be created"
+		case dryRun:
+			status = "would be updated"
+		case result.Created:
+			status = "created"
+		case result.UpToDate:
+			status = "up to date"
+		}
+		size := "-"
+		if result.Size > 0 {
+			total.Size += result.Size
+			mirror := model.FileSystem{Size: result.Size}
+			size = mirror.GetFormattedSize()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, result.Target, status, size)
+	}
+	_ = w.Flush()
+	fmt.Fprintf(out, "\n%d of %d repositories mirrored", mirrored, len(results))
+	if total.Size > 0 {
+		fmt.Fprintf(out, ", %s of local mirrors", total.GetFormattedSize())
+	}
+	fmt.Fprintln(out)
+}
+
+func init() {
+	addResumeFlag(mirrorCmd)
+	mirrorCmd.Flags().String("to", "", "directory of the mirrors, or base URL of a git server hosting them")
+	mirrorCmd.Flags().BoolP("dry-run", "d", false, "only report the mirrors that would be created or updated")
+	mirrorCmd.Flags().Bool("skip-space-check", false, "skip the free disk space preflight check of new local mirrors")
+	_ = mirrorCmd.MarkFlagRequired("to")
+}
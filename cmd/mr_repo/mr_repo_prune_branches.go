@@ -0,0 +1,113 @@
+package mr_repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var pruneBranchesCmd = &cobra.Command{
+	Use:   "prune-branches",
+	Short: "Delete local branches that are merged or whose upstream is gone",
+	Long: `For every git project in the current directory, delete the local branches whose
+upstream was removed from origin or that are fully merged into the default branch.
+The current branch, the default branch and protected branches are never deleted:
+the --protected list, the branches.protected config entry and --protect.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		protected, _ := cmd.Flags().GetStringSlice("protected")
+		protected = append(protected, protectedBranches(cmd)...)
+
+		gs := newGitService(cmd)
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		for i, absPath := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			opts := gitOptions(cmd)
+			if !dryRun && confirmer.asking() && confirmer.skip(run, i, prunePlan(ctx, gs, absPath, protected, opts)) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			opts.DryRun = dryRun
+			result, err := gs.PruneBranches(ctx, absPath, protected, opts)
+			if err != nil {
+				mrRepoLogger.Warn("PruneBranches failed", "repo", absPath, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			logPruneResult(absPath, result, dryRun)
+			run.SetResult(i, result)
+			run.Set(i, service.RunStatusDone, map[string]int{
+				"deleted": len(result.Deleted),
+				"dry_run": len(result.DryRun),
+				"failed":  len(result.Failed),
+			}, nil)
+		}
+		return nil
+	},
+}
+
+// prunePlan describes the branches prune-branches deletes in the repository,
+// or is empty when there is none
+func prunePlan(ctx context.Context, gs service.GitService, repoPath string, protected []string, opts service.Options) string {
+	opts.DryRun = true
+	result, err := gs.PruneBranches(ctx, repoPath, protected, opts)
+	if err != nil {
+		return fmt.Sprintf("delete the stale branches (%v)", err)
+	}
+	if len(result.DryRun) == 0 {
+		return ""
+	}
+	branches := make([]string, len(result.DryRun))
+	for i, branch := range result.DryRun {
+		branches[i] = fmt.Sprintf("%s (%s)", branch, result.Reasons[branch])
+	}
+	return "delete " + strings.Join(branches, ", ")
+}
+
+func logPruneResult(repoPath string, result *service.PruneBranchesResult, dryRun bool) {
+	mrRepoUsage.Count("repos", 1)
+	mrRepoUsage.Count("deleted", len(result.Deleted))
+	mrRepoUsage.Count("failed", len(result.Failed))
+	if dryRun {
+		for _, branch := range result.DryRun {
+			mrRepoLogger.Info("DryRun branch to delete", "repo", repoPath, "branch", branch, "reason", result.Reasons[branch])
+		}
+		return
+	}
+
+	for _, branch := range result.Deleted {
+		mrRepoLogger.Info("Removed branch", "repo", repoPath, "branch", branch, "reason", result.Reasons[branch])
+	}
+	for _, branch := range result.Failed {
+		mrRepoLogger.Warn("Failed branch", "repo", repoPath, "branch", branch)
+	}
+}
+
+func init() {
+	addInteractiveFlag(pruneBranchesCmd)
+	pruneBranchesCmd.Flags().BoolP("dry-run", "d", false, "dry run")
+	pruneBranchesCmd.Flags().StringSlice("protected", service.DefaultProtectedBranches, "branches (glob patterns) that are never deleted")
+	_ = pruneBranchesCmd.RegisterFlagCompletionFunc("protected", completeBranches)
+}
@@ -0,0 +1,129 @@
+package mr_repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var pullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Fetch and fast-forward the checked-out branch of every repository",
+	Long: `For every git project in the current directory, fetch origin and fast-forward the
+checked-out branch to its upstream. Diverged branches are reported, or rebased with
+--rebase; a conflicting rebase is aborted. Repositories with uncommitted changes or a
+detached HEAD are skipped.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rebase, _ := cmd.Flags().GetBool("rebase")
+
+		gs := newGitService(cmd)
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Rebasing commits, so every repository needs an identity before starting
+		identity := identityFromFlags(cmd)
+		if rebase {
+			if err := service.CheckIdentities(repoDirs, identity); err != nil {
+				return err
+			}
+		}
+		gs.SetIdentity(identity)
+
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		checkpoint := startCheckpoint(cmd, repoDirs...)
+		defer finishCheckpoint(checkpoint, run)
+		defer finishRun(ctx, run)
+
+		statuses := map[string]int{}
+		for i, absPath := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			if resumeRepo(checkpoint, run, i) {
+				continue
+			}
+			if confirmer.asking() && confirmer.skip(run, i, pullPlan(ctx, gs, absPath, rebase, gitOptions(cmd))) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			result, err := gs.PullCurrentBranch(ctx, absPath, service.PullOptions{Options: gitOptions(cmd), Rebase: rebase})
+			if err != nil {
+				statuses[service.RunStatusFailed]++
+				mrRepoLogger.Warn("PullCurrentBranch failed", "repo", absPath, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			statuses[result.Status]++
+			mrRepoUsage.Count(result.Status, 1)
+			logPullResult(absPath, result)
+			run.SetResult(i, result)
+			run.Set(i, service.RunStatusDone, map[string]int{result.Status: 1}, service.SkipError(result.SkipReason))
+			checkpointRepo(checkpoint, run, i)
+		}
+
+		printPullSummary(statuses)
+		return nil
+	},
+}
+
+// pullPlan describes the branch pull updates in the repository
+func pullPlan(ctx context.Context, gs service.GitService, repoPath string, rebase bool, opts service.Options) string {
+	branch := "the checked-out branch"
+	if status, err := gs.RepoStatus(ctx, repoPath, 0, opts); err == nil && status.Branch != "" {
+		branch = status.Branch
+	}
+	if rebase {
+		return fmt.Sprintf("pull %s from its upstream, rebasing local commits when it diverged", branch)
+	}
+	return fmt.Sprintf("fast-forward %s to its upstream", branch)
+}
+
+func logPullResult(repoPath string, result *service.PullResult) {
+	switch result.Status {
+	case service.PullStatusSkipped:
+		mrRepoLogger.Warn("Skipped repository", "repo", repoPath, "reason", result.SkipReason)
+	case service.PullStatusDiverged, service.PullStatusConflict:
+		mrRepoLogger.Warn("Branch not pulled", "repo", repoPath, "branch", result.Branch, "status", result.Status)
+	default:
+		mrRepoLogger.Info("Pulled branch", "repo", repoPath, "branch", result.Branch, "status", result.Status)
+	}
+}
+
+func printPullSummary(statuses map[string]int) {
+	if len(statuses) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(statuses))
+	for status := range statuses {
+		keys = append(keys, status)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, status := range keys {
+		parts = append(parts, fmt.Sprintf("%d %s", statuses[status], status))
+	}
+	fmt.Fprintln(mrRepoOut, "Pull summary:", strings.Join(parts, ", "))
+}
+
+func init() {
+	addResumeFlag(pullCmd)
+	addInteractiveFlag(pullCmd)
+	pullCmd.Flags().Bool("rebase", false, "rebase local commits onto the upstream when the branch diverged")
+}
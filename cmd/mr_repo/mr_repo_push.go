@@ -0,0 +1,153 @@
+package mr_repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Push the checked-out branch of every repository to origin",
+	Long: `For every git project in the current directory, push the checked-out branch, or every
+local branch with --all-branches, to the branch of the same name on origin. Branches
+are compared with what the last fetch saw of origin: branches behind origin are left
+alone, and diverged branches are rejected unless --force-with-lease is given, which
+force-pushes them as long as origin did not move since. Credentials are resolved for
+the origin of every repository. Use --dry-run to preview the pushes.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allBranches, _ := cmd.Flags().GetBool("all-branches")
+		forceWithLease, _ := cmd.Flags().GetBool("force-with-lease")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		statuses := map[string]int{}
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			opts := service.PushOptions{Options: gitOptions(cmd), AllBranches: allBranches, ForceWithLease: forceWithLease}
+			if !dryRun && confirmer.asking() && confirmer.skip(run, i, pushPlan(ctx, gs, repoDir, opts)) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			opts.DryRun = dryRun
+			result, err := gs.PushBranches(ctx, repoDir, opts)
+			if err != nil {
+				statuses[service.RunStatusFailed]++
+				mrRepoLogger.Warn("PushBranches failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			counts := logPushResult(repoDir, result, dryRun)
+			for status, n := range counts {
+				statuses[status] += n
+				mrRepoUsage.Count(status, n)
+			}
+			run.SetResult(i, result)
+			run.Set(i, service.RunStatusDone, counts, nil)
+		}
+
+		printPushSummary(statuses, dryRun)
+		return nil
+	},
+}
+
+// pushPlan describes the branches pushed in the repository, or is empty when
+// there is nothing to push
+func pushPlan(ctx context.Context, gs service.GitService, repoPath string, opts service.PushOptions) string {
+	opts.DryRun = true
+	result, err := gs.PushBranches(ctx, repoPath, opts)
+	if err != nil {
+		return fmt.Sprintf("push to origin (%v)", err)
+	}
+	var pushes []string
+	for branch, status := range result.Branches {
+		switch status {
+		case service.PushStatusPushed, service.PushStatusCreated, service.PushStatusForced:
+			pushes = append(pushes, fmt.Sprintf("%s (%s)", branch, status))
+		}
+	}
+	if len(pushes) == 0 {
+		return ""
+	}
+	sort.Strings(pushes)
+	return "push " + strings.Join(pushes, ", ")
+}
+
+// logPushResult logs every branch of result and returns the number of branches per status
+func logPushResult(repoPath string, result *service.PushResult, dryRun bool) map[string]int {
+	counts := map[string]int{}
+	if result.SkipReason != "" {
+		mrRepoLogger.Warn("Skipped repository", "repo", repoPath, "reason", result.SkipReason)
+		counts[service.RunStatusSkipped] = 1
+		return counts
+	}
+	branches := make([]string, 0, len(result.Branches))
+	for branch := range result.Branches {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	for _, branch := range branches {
+		status := result.Branches[branch]
+		counts[status]++
+		switch {
+		case result.Errors[branch] != "":
+			mrRepoLogger.Warn("Branch not pushed", "repo", repoPath, "branch", branch, "status", status, "error", result.Errors[branch])
+		case dryRun:
+			mrRepoLogger.Info("DryRun branch to push", "repo", repoPath, "branch", branch, "status", status)
+		default:
+			mrRepoLogger.Info("Pushed branch", "repo", repoPath, "branch", branch, "status", status)
+		}
+	}
+	return counts
+}
+
+func printPushSummary(statuses map[string]int, dryRun bool) {
+	if len(statuses) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(statuses))
+	for status := range statuses {
+		keys = append(keys, status)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, status := range keys {
+		parts = append(parts, fmt.Sprintf("%d %s", statuses[status], status))
+	}
+	title := "Push summary:"
+	if dryRun {
+		title = "Push summary (dry run):"
+	}
+	fmt.Fprintln(mrRepoOut, title, strings.Join(parts, ", "))
+}
+
+func init() {
+	addInteractiveFlag(pushCmd)
+	pushCmd.Flags().Bool("all-branches", false, "push every local branch instead of the checked-out one")
+	pushCmd.Flags().Bool("force-with-lease", false, "force-push diverged branches unless origin moved since the last fetch")
+	pushCmd.Flags().BoolP("dry-run", "d", false, "only report what would be pushed")
+}
@@ -0,0 +1,89 @@
+package mr_repo
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var restoreBranchCmd = &cobra.Command{
+	Use:   "restore-branch [branch]",
+	Short: "Restore a remote branch deleted by delete-merged",
+	Long: `Recreate on origin a branch removed by delete-merged, at the commit it pointed to
+when it was deleted. Branches stay restorable for the --keep-days window of the
+delete-merged run. Use --list to show the restorable branches of the repository
+in the current directory.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, _ := cmd.Flags().GetBool("list")
+		if !list && len(args) == 0 {
+			return fmt.Errorf("a branch arg is required, or use --list")
+		}
+
+		currDir, err := targetRepo(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, []string{currDir})
+		defer reportRun(run)
+		if err := run.Start(0); err != nil {
+			return err
+		}
+
+		if list {
+			deleted, err := gs.ListDeletedBranches(ctx, currDir, gitOptions(cmd))
+			if err != nil {
+				run.Set(0, runStatus(ctx, err), nil, err)
+				return err
+			}
+			run.SetResult(0, deleted)
+			run.Set(0, service.RunStatusDone, nil, nil)
+			printDeletedBranches(deleted)
+			return nil
+		}
+
+		if err := gs.RestoreBranch(ctx, currDir, args[0], gitOptions(cmd)); err != nil {
+			run.Set(0, runStatus(ctx, err), nil, err)
+			return err
+		}
+		mrRepoUsage.Count("restored", 1)
+		run.Set(0, service.RunStatusDone, map[string]int{"restored": 1}, nil)
+		fmt.Fprintln(mrRepoOut, "Restored branch", args[0])
+		return nil
+	},
+}
+
+func printDeletedBranches(deleted []service.DeletedBranch) {
+	if len(deleted) == 0 {
+		fmt.Fprintln(mrRepoOut, "No restorable branches")
+		return
+	}
+	w := tabwriter.NewWriter(mrRepoOut, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tCOMMIT\tDELETED\tEXPIRES")
+	now := time.Now()
+	for _, branch := range deleted {
+		deletedAt, expires := "-", "never"
+		if !branch.DeletedAt.IsZero() {
+			deletedAt = branch.DeletedAt.Format("2006-01-02")
+		}
+		if !branch.ExpiresAt.IsZero() {
+			expires = branch.ExpiresAt.Format("2006-01-02")
+			if branch.Expired(now) {
+				expires += " (expired)"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%.8s\t%s\t%s\n", branch.Branch, branch.Hash, deletedAt, expires)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	restoreBranchCmd.Flags().BoolP("list", "l", false, "list the restorable branches instead of restoring one")
+}
@@ -0,0 +1,95 @@
+package mr_repo
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var sizeCmd = &cobra.Command{
+	Use:   "size",
+	Short: "Break down the disk usage of every repository",
+	Long: `Show, for every git project in the current directory, the size of the checked-out
+files against the size of the .git directory, the number of packfiles and loose
+objects, and the largest file versions stored in the history with a path each was
+committed at. Use it to find the repositories bloated by committed binaries.
+Only local data is read.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		top, _ := cmd.Flags().GetInt("top")
+		if top < 0 {
+			return fmt.Errorf("--top cannot be negative")
+		}
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		// size only reads, so the run is reported but not kept in the runs store
+		run := newRunRecord(cmd, repoDirs)
+		sizes := make([]*service.RepoSize, len(repoDirs))
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil {
+				break
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			if sizes[i], err = gs.RepoSize(ctx, repoDir, top, gitOptions(cmd)); err != nil {
+				mrRepoLogger.Warn("RepoSize failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			run.SetResult(i, sizes[i])
+			run.Set(i, service.RunStatusDone, nil, nil)
+		}
+		mrRepoUsage.Count("repos", len(repoDirs))
+
+		printRepoSizes(mrRepoOut, repoDirs, sizes)
+		run.Interrupted = ctx.Err() != nil
+		reportRun(run)
+		return ctx.Err()
+	},
+}
+
+// printRepoSizes prints a row per measured repository, then its largest blobs
+func printRepoSizes(out io.Writer, repoDirs []string, sizes []*service.RepoSize) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tWORKTREE\t.GIT\tPACKFILES\tLOOSE OBJECTS")
+	for i, size := range sizes {
+		if size == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", filepath.Base(repoDirs[i]), model.FormatSize(size.WorktreeSize), model.FormatSize(size.GitSize), size.Packfiles, size.LooseObjects)
+	}
+	_ = w.Flush()
+
+	for i, size := range sizes {
+		if size == nil || len(size.LargestBlobs) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\nLargest blobs of %s:\n", filepath.Base(repoDirs[i]))
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, blob := range size.LargestBlobs {
+			blobPath := blob.Path
+			if blobPath == "" {
+				blobPath = "(unreachable)"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", model.FormatSize(blob.Size), shortHash(blob.Hash), blobPath)
+		}
+		_ = w.Flush()
+	}
+}
+
+func init() {
+	sizeCmd.Flags().Int("top", 5, "number of largest blobs listed per repository (0 to skip the history scan)")
+}
@@ -0,0 +1,267 @@
+package mr_repo
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var stashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "List, apply or drop the stashes of every repository",
+	Long: `Inventory the stashes of every git project in the current directory, then apply or
+drop them. Forgotten stashes get in the way of batch branch updates: review them with
+"stash list" before running update-branches --autostash. The git executable is required.`,
+	Args: cobra.NoArgs,
+}
+
+var stashListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the stashes of every repository",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		// listing only reads, so the run is reported but not kept in the runs store
+		run := newRunRecord(cmd, repoDirs)
+		stashes := make([][]service.Stash, len(repoDirs))
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil {
+				break
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			if stashes[i], err = gs.ListStashes(ctx, repoDir, gitOptions(cmd)); err != nil {
+				mrRepoLogger.Warn("ListStashes failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			run.SetResult(i, stashes[i])
+			run.Set(i, service.RunStatusDone, map[string]int{"stashes": len(stashes[i])}, nil)
+		}
+
+		printStashes(mrRepoOut, repoDirs, stashes)
+		run.Interrupted = ctx.Err() != nil
+		reportRun(run)
+		return ctx.Err()
+	},
+}
+
+var stashApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a stash in every repository that has one",
+	Long: `Apply stash@{--index} (the latest stash by default) in every repository that has it.
+With --pop the stash is dropped once applied. A stash conflicting with the worktree
+is reported as failed and kept.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, _ := cmd.Flags().GetInt("index")
+		pop, _ := cmd.Flags().GetBool("pop")
+		if index < 0 {
+			return fmt.Errorf("--index cannot be negative")
+		}
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			stashes, err := gs.ListStashes(ctx, repoDir, gitOptions(cmd))
+			if err != nil {
+				mrRepoLogger.Warn("ListStashes failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			if index >= len(stashes) {
+				run.Set(i, service.RunStatusDone, map[string]int{"applied": 0}, nil)
+				continue
+			}
+			stash := stashes[index]
+			if confirmer.asking() && confirmer.skip(run, i, fmt.Sprintf("apply %s %q stashed on %s", stash.Ref(), stash.Message, stash.Branch)) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			if err := gs.ApplyStash(ctx, repoDir, index, pop, gitOptions(cmd)); err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("ApplyStash failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			mrRepoUsage.Count("applied", 1)
+			mrRepoLogger.Info("Applied stash", "repo", repoDir, "stash", stash.Ref())
+			run.SetResult(i, []service.Stash{stash})
+			run.Set(i, service.RunStatusDone, map[string]int{"applied": 1}, nil)
+		}
+		return nil
+	},
+}
+
+var stashDropCmd = &cobra.Command{
+	Use:   "drop",
+	Short: "Drop stashes in every repository",
+	Long: `Drop stash@{--index} (the latest stash by default) in every repository that has it.
+With --all instead every stash is dropped, and with --older-than (e.g. 90d, 2w) only
+the stashes created before are, across all indexes. Dropped stashes can only be recovered
+from their hash, which is logged; use --dry-run to review them first.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, _ := cmd.Flags().GetInt("index")
+		all, _ := cmd.Flags().GetBool("all")
+		olderThanFlag, _ := cmd.Flags().GetString("older-than")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if index < 0 {
+			return fmt.Errorf("--index cannot be negative")
+		}
+		var olderThan time.Duration
+		if olderThanFlag != "" {
+			var err error
+			if olderThan, err = service.ParseAge(olderThanFlag); err != nil {
+				return fmt.Errorf("invalid --older-than: %w", err)
+			}
+		}
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		opts := gitOptions(cmd)
+		opts.DryRun = dryRun
+		cutoff := time.Now().Add(-olderThan)
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			stashes, err := gs.ListStashes(ctx, repoDir, opts)
+			if err != nil {
+				mrRepoLogger.Warn("ListStashes failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+
+			var selected []service.Stash
+			for _, stash := range stashes {
+				if olderThan > 0 {
+					if stash.Created.Before(cutoff) {
+						selected = append(selected, stash)
+					}
+				} else if all || stash.Index == index {
+					selected = append(selected, stash)
+				}
+			}
+			if len(selected) == 0 {
+				run.Set(i, service.RunStatusDone, map[string]int{"dropped": 0}, nil)
+				continue
+			}
+			if !dryRun && confirmer.asking() && confirmer.skip(run, i, fmt.Sprintf("drop %s", describeStashes(selected))) {
+				continue
+			}
+
+			if run.Start(i) != nil {
+				continue
+			}
+			// highest index first, so the indexes left to drop do not shift
+			var dropped []service.Stash
+			for j := len(selected) - 1; j >= 0; j-- {
+				stash := selected[j]
+				if err = gs.DropStash(ctx, repoDir, stash.Index, opts); err != nil {
+					break
+				}
+				mrRepoLogger.Info("Dropped stash", "repo", repoDir, "stash", stash.Ref(), "hash", stash.Hash, "dry_run", dryRun)
+				dropped = append(dropped, stash)
+			}
+			mrRepoUsage.Count("dropped", len(dropped))
+			run.SetResult(i, dropped)
+			if err != nil {
+				mrRepoLogger.Warn("DropStash failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), map[string]int{"dropped": len(dropped)}, err)
+				continue
+			}
+			run.Set(i, service.RunStatusDone, map[string]int{"dropped": len(dropped)}, nil)
+		}
+		return nil
+	},
+}
+
+// describeStashes lists the stashes with their message for a prompt
+func describeStashes(stashes []service.Stash) string {
+	descriptions := make([]string, len(stashes))
+	for i, stash := range stashes {
+		descriptions[i] = fmt.Sprintf("%s %q", stash.Ref(), stash.Message)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// printStashes prints a row per stash, then how many repositories have stashes
+func printStashes(out io.Writer, repoDirs []string, stashes [][]service.Stash) {
+	total, repos := 0, 0
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tSTASH\tBRANCH\tCREATED\tMESSAGE")
+	for i, repoStashes := range stashes {
+		if len(repoStashes) > 0 {
+			repos++
+		}
+		for _, stash := range repoStashes {
+			total++
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", filepath.Base(repoDirs[i]), stash.Ref(), stash.Branch, stash.Created.Format(time.DateOnly), stash.Message)
+		}
+	}
+	_ = w.Flush()
+	fmt.Fprintf(out, "\n%d stashes in %d of %d repositories\n", total, repos, len(repoDirs))
+}
+
+func init() {
+	addInteractiveFlag(stashApplyCmd)
+	stashApplyCmd.Flags().Int("index", 0, "n of the stash@{n} to apply")
+	stashApplyCmd.Flags().Bool("pop", false, "drop the stash once applied")
+
+	addInteractiveFlag(stashDropCmd)
+	stashDropCmd.Flags().Int("index", 0, "n of the stash@{n} to drop")
+	stashDropCmd.Flags().Bool("all", false, "drop every stash")
+	stashDropCmd.Flags().String("older-than", "", "drop the stashes created more than this long ago, e.g. 90d, 2w or 36h")
+	stashDropCmd.Flags().BoolP("dry-run", "d", false, "only report the stashes that would be dropped")
+	stashDropCmd.MarkFlagsMutuallyExclusive("index", "all", "older-than")
+
+	stashCmd.AddCommand(stashListCmd)
+	stashCmd.AddCommand(stashApplyCmd)
+	stashCmd.AddCommand(stashDropCmd)
+}
@@ -0,0 +1,153 @@
+package mr_repo
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the branch and local state of every repository",
+	Long: `Show, for every git project in the current directory or in each --root directory,
+the checked-out branch, uncommitted changes, the origin remote, stale branches and
+the last commit date. Each root gets its own section, followed by combined totals.
+Only local data is read: run update-branches first for fresh remote state.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		staleDays, _ := cmd.Flags().GetInt("stale-days")
+		if staleDays < 1 {
+			return fmt.Errorf("--stale-days must be at least 1")
+		}
+
+		roots, err := workspaceRoots(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		staleAfter := time.Duration(staleDays) * 24 * time.Hour
+
+		reposByRoot := make([][]string, len(roots))
+		var allRepos []string
+		for i, root := range roots {
+			repoDirs, err := rootRepos(cmd, root)
+			if err != nil {
+				return err
+			}
+			reposByRoot[i] = repoDirs
+			allRepos = append(allRepos, repoDirs...)
+		}
+		// status only reads, so the run is reported but not kept in the runs store
+		run := newRunRecord(cmd, allRepos)
+
+		var total statusTotals
+		offset := 0
+		for r, root := range roots {
+			var totals statusTotals
+			w := tabwriter.NewWriter(mrRepoOut, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "== %s\n", root)
+			fmt.Fprintln(w, "REPOSITORY\tBRANCH\tDEFAULT\tCHANGES\tORIGIN\tSTALE\tLAST COMMIT")
+			for j, repoDir := range reposByRoot[r] {
+				if ctx.Err() != nil {
+					break
+				}
+				i := offset + j
+				if run.Start(i) != nil {
+					continue
+				}
+				status, err := gs.RepoStatus(ctx, repoDir, staleAfter, gitOptions(cmd))
+				if err != nil {
+					totals.errors++
+					mrRepoLogger.Debug("RepoStatus failed", "repo", repoDir, "error", err)
+					run.Set(i, runStatus(ctx, err), nil, err)
+					fmt.Fprintf(w, "%s\t(%v)\t\t\t\t\t\n", filepath.Base(repoDir), err)
+					continue
+				}
+				totals.add(status)
+				run.SetResult(i, status)
+				run.Set(i, service.RunStatusDone, nil, nil)
+				printRepoStatus(w, filepath.Base(repoDir), status)
+			}
+			_ = w.Flush()
+			fmt.Fprintln(mrRepoOut, totals)
+			fmt.Fprintln(mrRepoOut)
+			total.merge(totals)
+			offset += len(reposByRoot[r])
+		}
+
+		mrRepoUsage.Count("repos", total.repos)
+		if len(roots) > 1 {
+			fmt.Fprintf(mrRepoOut, "Total across %d roots: %s\n", len(roots), total)
+		}
+		run.Interrupted = ctx.Err() != nil
+		reportRun(run)
+		return ctx.Err()
+	},
+}
+
+// statusTotals counts the repositories of a root, or of all roots
+type statusTotals struct {
+	repos, dirty, detached, noOrigin, stale, errors int
+}
+
+func (t *statusTotals) add(status *service.RepoStatus) {
+	t.repos++
+	if status.Dirty {
+		t.dirty++
+	}
+	if status.Branch == "" {
+		t.detached++
+	}
+	if !status.HasRemote {
+		t.noOrigin++
+	}
+	if len(status.StaleBranches) > 0 {
+		t.stale++
+	}
+}
+
+func (t *statusTotals) merge(other statusTotals) {
+	t.repos += other.repos
+	t.dirty += other.dirty
+	t.detached += other.detached
+	t.noOrigin += other.noOrigin
+	t.stale += other.stale
+	t.errors += other.errors
+}
+
+func (t statusTotals) String() string {
+	return fmt.Sprintf("%d repositories: %d with changes, %d detached, %d without origin, %d with stale branches, %d unreadable",
+		t.repos, t.dirty, t.detached, t.noOrigin, t.stale, t.errors)
+}
+
+func printRepoStatus(w io.Writer, name string, status *service.RepoStatus) {
+	branch, defaultBranch, changes, origin := status.Branch, status.DefaultBranch, "clean", "yes"
+	if branch == "" {
+		branch = "(detached)"
+	}
+	if defaultBranch == "" {
+		defaultBranch = "-"
+	}
+	if status.Kind == service.RepoKindBare {
+		changes = "bare"
+	} else if status.Dirty {
+		changes = "uncommitted"
+	}
+	if !status.HasRemote {
+		origin = "missing"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+		name, branch, defaultBranch, changes, origin, len(status.StaleBranches), status.LastCommit.Format("2006-01-02"))
+}
+
+func init() {
+	statusCmd.Flags().Int("stale-days", 90, "days without commits after which a branch is stale")
+}
@@ -0,0 +1,102 @@
+package mr_repo
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var syncForkCmd = &cobra.Command{
+	Use:   "sync-fork",
+	Short: "Bring the default branch of every fork up to date with its upstream",
+	Long: `For every git project in the current directory with an upstream remote, fetch
+upstream, fast-forward the local default branch of upstream and push it to origin.
+Forks whose local or origin branch has commits missing from upstream are listed at the
+end for a manual merge. Repositories without the upstream remote are skipped, and so
+are forks whose checked-out default branch has uncommitted changes.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		upstream, _ := cmd.Flags().GetString("upstream")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+
+		statuses := map[string]int{}
+		var manual []string
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil {
+				break
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			opts := gitOptions(cmd)
+			opts.DryRun = dryRun
+			result, err := gs.SyncFork(ctx, repoDir, service.ForkSyncOptions{Options: opts, Upstream: upstream})
+			if err != nil {
+				statuses[service.RunStatusFailed]++
+				mrRepoLogger.Warn("SyncFork failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			statuses[result.Status]++
+			mrRepoUsage.Count(result.Status, 1)
+			switch result.Status {
+			case service.ForkStatusDiverged:
+				manual = append(manual, fmt.Sprintf("%s (%s: %s)", filepath.Base(repoDir), result.Branch, result.Reason))
+				mrRepoLogger.Warn("Fork needs a manual merge", "repo", repoDir, "branch", result.Branch, "reason", result.Reason)
+			case service.ForkStatusSkipped:
+				mrRepoLogger.Debug("Skipped repository", "repo", repoDir, "reason", result.Reason)
+			default:
+				mrRepoLogger.Info("Synced fork", "repo", repoDir, "branch", result.Branch, "status", result.Status)
+			}
+			run.SetResult(i, result)
+			run.Set(i, service.RunStatusDone, map[string]int{result.Status: 1}, service.SkipError(result.Reason))
+		}
+
+		printForkSummary(statuses, manual)
+		return nil
+	},
+}
+
+func printForkSummary(statuses map[string]int, manual []string) {
+	if len(statuses) == 0 {
+		return
+	}
+	var parts []string
+	for _, status := range []string{
+		service.ForkStatusSynced,
+		service.ForkStatusUpToDate,
+		service.ForkStatusDiverged,
+		service.ForkStatusSkipped,
+		service.RunStatusFailed,
+	} {
+		if statuses[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", statuses[status], status))
+		}
+	}
+	fmt.Fprintln(mrRepoOut, "Fork sync summary:", strings.Join(parts, ", "))
+	if len(manual) > 0 {
+		fmt.Fprintln(mrRepoOut, "Manual merge needed:")
+		for _, repo := range manual {
+			fmt.Fprintln(mrRepoOut, "  "+repo)
+		}
+	}
+}
+
+func init() {
+	syncForkCmd.Flags().String("upstream", service.DefaultUpstreamRemote, "remote of the forked repository")
+	syncForkCmd.Flags().BoolP("dry-run", "d", false, "only report the forks that would be synced")
+}
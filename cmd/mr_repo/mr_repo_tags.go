@@ -0,0 +1,141 @@
+package mr_repo
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List the tags of every repository, or sync them with origin",
+	Long: `List the tags of every git project in the current directory, filtered by the --list
+glob patterns (e.g. "v1.*"). Fetching gets the new tags of origin but never drops the
+ones deleted there: --sync fetches the missing and moved tags and deletes the local
+tags origin does not have, including tags never pushed, like git fetch --prune-tags.
+Use --dry-run to review the changes first.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		patterns, _ := cmd.Flags().GetStringSlice("list")
+		sync, _ := cmd.Flags().GetBool("sync")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if dryRun && !sync {
+			return fmt.Errorf("--dry-run only applies to --sync")
+		}
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+		gs := newGitService(cmd)
+		ctx := cmd.Context()
+
+		if !sync {
+			// listing only reads, so the run is reported but not kept in the runs store
+			run := newRunRecord(cmd, repoDirs)
+			tags := make([][]service.Tag, len(repoDirs))
+			for i, repoDir := range repoDirs {
+				if ctx.Err() != nil {
+					break
+				}
+				if run.Start(i) != nil {
+					continue
+				}
+				if tags[i], err = gs.ListTags(ctx, repoDir, patterns, gitOptions(cmd)); err != nil {
+					mrRepoLogger.Warn("ListTags failed", "repo", repoDir, "error", err)
+					run.Set(i, runStatus(ctx, err), nil, err)
+					continue
+				}
+				run.SetResult(i, tags[i])
+				run.Set(i, service.RunStatusDone, map[string]int{"tags": len(tags[i])}, nil)
+			}
+			printTags(mrRepoOut, repoDirs, tags)
+			run.Interrupted = ctx.Err() != nil
+			reportRun(run)
+			return ctx.Err()
+		}
+
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
+		for i, repoDir := range repoDirs {
+			if ctx.Err() != nil {
+				break
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			opts := gitOptions(cmd)
+			opts.DryRun = dryRun
+			result, err := gs.SyncTags(ctx, repoDir, opts)
+			if err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("SyncTags failed", "repo", repoDir, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			logTagSync(repoDir, result, dryRun)
+			run.SetResult(i, result)
+			run.Set(i, service.RunStatusDone, map[string]int{
+				"added":   len(result.Added),
+				"updated": len(result.Updated),
+				"pruned":  len(result.Pruned),
+			}, nil)
+		}
+		return nil
+	},
+}
+
+func logTagSync(repoPath string, result *service.TagSyncResult, dryRun bool) {
+	mrRepoUsage.Count("added", len(result.Added))
+	mrRepoUsage.Count("pruned", len(result.Pruned))
+	action := "Synced tags"
+	if dryRun {
+		action = "DryRun tags to sync"
+	}
+	if len(result.Added)+len(result.Updated)+len(result.Pruned) == 0 {
+		mrRepoLogger.Info("Tags up to date", "repo", repoPath)
+		return
+	}
+	mrRepoLogger.Info(action, "repo", repoPath,
+		"added", strings.Join(result.Added, ","),
+		"updated", strings.Join(result.Updated, ","),
+		"pruned", strings.Join(result.Pruned, ","))
+}
+
+// printTags prints a row per tag and the total; repositories without tags, or
+// that could not be read, have no row
+func printTags(out io.Writer, repoDirs []string, tags [][]service.Tag) {
+	total, repos := 0, 0
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tTAG\tCOMMIT\tDATE")
+	for i, repoTags := range tags {
+		if len(repoTags) == 0 {
+			continue
+		}
+		repos++
+		total += len(repoTags)
+		name := filepath.Base(repoDirs[i])
+		for _, tag := range repoTags {
+			date := "-"
+			if !tag.Date.IsZero() {
+				date = tag.Date.Format("2006-01-02")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, tag.Name, shortHash(tag.Commit), date)
+		}
+	}
+	_ = w.Flush()
+	fmt.Fprintf(out, "\n%d tags in %d of %d repositories\n", total, repos, len(repoDirs))
+}
+
+func init() {
+	tagsCmd.Flags().StringSlice("list", nil, "only list the tags matching one of these glob patterns, such as \"v1.*\"")
+	tagsCmd.Flags().Bool("sync", false, "fetch the tags of origin and delete the local tags it does not have")
+	tagsCmd.Flags().BoolP("dry-run", "d", false, "only report the tags --sync would change")
+	tagsCmd.MarkFlagsMutuallyExclusive("list", "sync")
+}
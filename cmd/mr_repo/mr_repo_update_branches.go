@@ -0,0 +1,288 @@
+package mr_repo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var updateBranchesCmd = &cobra.Command{
+	Use:   "update-branches",
+	Short: "Align local branches with their remote counterparts",
+	Long: `Fetch every git project in the current directory and hard-reset each local branch
+to its origin counterpart. The checked-out branch is never touched. After the update
+every branch is verified against the remote hash. Repositories with uncommitted
+changes are skipped unless --autostash is set. Protected branches (--protect and the
+branches.protected config entry) are only fast-forwarded: a protected branch with
+commits missing from origin is left as it is. With --recurse-submodules, the submodules
+are then fetched and checked out at the commit recorded by the checked-out branch,
+and reported in their own table.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		followRedirects, _ := cmd.Flags().GetBool("follow-redirects")
+		autoStash, _ := cmd.Flags().GetBool("autostash")
+		noCheckout, _ := cmd.Flags().GetBool("no-checkout")
+		branches, _ := cmd.Flags().GetStringSlice("branches")
+		excludeBranches, _ := cmd.Flags().GetStringSlice("exclude-branches")
+		recurseSubmodules, _ := cmd.Flags().GetBool("recurse-submodules")
+		opts := service.UpdateOptions{
+			Options:           gitOptions(cmd),
+			AutoStash:         autoStash,
+			NoCheckout:        noCheckout,
+			Branches:          branches,
+			ExcludeBranches:   excludeBranches,
+			Protected:         protectedBranches(cmd),
+			RecurseSubmodules: recurseSubmodules,
+		}
+
+		gs := newGitService(cmd)
+
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Stashing commits, so every repository needs an identity before starting
+		identity := identityFromFlags(cmd)
+		if autoStash {
+			if err := service.CheckIdentities(repoDirs, identity); err != nil {
+				return err
+			}
+		}
+		gs.SetIdentity(identity)
+
+		confirmer, err := startConfirmer(cmd)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		run := newRunRecord(cmd, repoDirs)
+		checkpoint := startCheckpoint(cmd, repoDirs...)
+		defer finishCheckpoint(checkpoint, run)
+		defer finishRun(ctx, run)
+
+		var timings []repoTiming
+		for i, absPath := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			if resumeRepo(checkpoint, run, i) {
+				continue
+			}
+			if confirmer.asking() && confirmer.skip(run, i, updatePlan(absPath, opts)) {
+				continue
+			}
+			if run.Start(i) != nil {
+				continue
+			}
+			checkRemoteRedirect(ctx, gs, absPath, followRedirects, opts.Options)
+
+			result, err := gs.UpdateAllBranchesProject(ctx, absPath, opts)
+			if result != nil {
+				logUpdateResult(absPath, result)
+				timings = append(timings, repoTiming{repo: filepath.Base(absPath), result: result})
+				run.SetResult(i, result)
+			}
+			if err != nil {
+				mrRepoLogger.Warn("UpdateAllBranchesProject failed", "repo", absPath, "error", err)
+				run.Set(i, runStatus(ctx, err), updateCounts(result), err)
+				continue
+			}
+			run.Set(i, service.RunStatusDone, updateCounts(result), service.SkipError(result.SkipReason))
+			checkpointRepo(checkpoint, run, i)
+		}
+		printTimingSummary(mrRepoOut, timings)
+		printSubmoduleSummary(mrRepoOut, timings)
+		return nil
+	},
+}
+
+// updatePlan describes the branches update-branches resets in the repository
+func updatePlan(repoPath string, opts service.UpdateOptions) string {
+	plan, err := service.PlanBranchUpdate(repoPath, opts)
+	if err != nil {
+		return fmt.Sprintf("fetch and reset the branches (%v)", err)
+	}
+	remote := opts.Remote
+	if remote == "" {
+		remote = service.DefaultRemote
+	}
+	if len(plan.Reset) == 0 {
+		return fmt.Sprintf("fetch %s and reset the branches it moves, none behind as of the last fetch", remote)
+	}
+	description := fmt.Sprintf("fetch %s and reset %s as of the last fetch", remote, strings.Join(plan.Reset, ", "))
+	if len(plan.Protected) > 0 {
+		description += fmt.Sprintf(", only fast-forwarding %s", strings.Join(plan.Protected, ", "))
+	}
+	return description
+}
+
+func updateCounts(result *service.UpdateResult) map[string]int {
+	if result == nil {
+		return nil
+	}
+	return map[string]int{
+		"updated": len(result.Updated),
+		"skipped": len(result.Skipped),
+		"failed":  len(result.Failed),
+	}
+}
+
+type repoTiming struct {
+	repo   string
+	result *service.UpdateResult
+}
+
+// printTimingSummary lists repositories from slowest to fastest with their
+// fetch time and slowest branch, to spot the repos that hold up a run.
+func printTimingSummary(out io.Writer, timings []repoTiming) {
+	if len(timings) == 0 {
+		return
+	}
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].result.TotalTime > timings[j].result.TotalTime
+	})
+
+	var total time.Duration
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tTOTAL\tFETCH\tBRANCHES\tSLOWEST BRANCH")
+	for _, timing := range timings {
+		total += timing.result.TotalTime
+		slowest, slowestTime := slowestBranch(timing.result.BranchTimes)
+		slowestCol := "-"
+		if slowest != "" {
+			slowestCol = fmt.Sprintf("%s (%s)", slowest, slowestTime.Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			timing.repo,
+			timing.result.TotalTime.Round(time.Millisecond),
+			timing.result.FetchTime.Round(time.Millisecond),
+			len(timing.result.BranchTimes),
+			slowestCol)
+	}
+	fmt.Fprintf(w, "TOTAL\t%s\t\t\t\n", total.Round(time.Millisecond))
+	_ = w.Flush()
+}
+
+// printSubmoduleSummary lists the submodules synced in every repository
+func printSubmoduleSummary(out io.Writer, timings []repoTiming) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := false
+	for _, timing := range timings {
+		for _, submodule := range timing.result.Submodules {
+			if !header {
+				fmt.Fprintln(w, "\nREPOSITORY\tSUBMODULE\tCOMMIT\tSTATUS")
+				header = true
+			}
+			status := "up to date"
+			switch {
+			case submodule.Error != "":
+				status = "failed: " + submodule.Error
+			case submodule.Updated:
+				status = "updated"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", timing.repo, submodule.Path, shortHash(submodule.Commit), status)
+		}
+	}
+	_ = w.Flush()
+}
+
+// shortHash abbreviates a commit hash for tables
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+func slowestBranch(times map[string]time.Duration) (string, time.Duration) {
+	var name string
+	var slowest time.Duration
+	for branch, duration := range times {
+		if duration > slowest || (duration == slowest && branch < name) {
+			name, slowest = branch, duration
+		}
+	}
+	return name, slowest
+}
+
+// checkRemoteRedirect surfaces moved remotes, updating origin when follow is set
+func checkRemoteRedirect(ctx context.Context, gs service.GitService, repoPath string, follow bool, opts service.Options) {
+	opts.DryRun = !follow
+	newRemote, err := gs.ResolveRemoteRedirect(ctx, repoPath, opts)
+	if err != nil {
+		mrRepoLogger.Debug("redirect check failed", "repo", repoPath, "error", err)
+		return
+	}
+	if newRemote == "" {
+		return
+	}
+	if follow {
+		mrRepoLogger.Info("Remote moved, origin updated", "repo", repoPath, "remote", newRemote)
+		return
+	}
+	mrRepoLogger.Warn("Remote moved, rerun with --follow-redirects to update origin", "repo", repoPath, "remote", newRemote)
+}
+
+func logUpdateResult(repoPath string, result *service.UpdateResult) {
+	mrRepoUsage.Count("repos", 1)
+	mrRepoUsage.Count("updated", len(result.Updated))
+	mrRepoUsage.Count("skipped", len(result.Skipped))
+	mrRepoUsage.Count("failed", len(result.Failed))
+	if result.SkipReason != "" {
+		mrRepoLogger.Warn("Skipped repository", "repo", repoPath, "reason", result.SkipReason)
+		return
+	}
+	for _, branch := range result.Updated {
+		if result.Verified[branch] {
+			mrRepoLogger.Info("Updated branch", "repo", repoPath, "branch", branch)
+		} else {
+			mrRepoLogger.Warn("Updated branch not verified", "repo", repoPath, "branch", branch)
+		}
+	}
+	for _, branch := range result.Skipped {
+		mrRepoLogger.Info("Skipped branch", "repo", repoPath, "branch", branch)
+	}
+	for _, branch := range result.Failed {
+		mrRepoLogger.Warn("Failed branch", "repo", repoPath, "branch", branch)
+	}
+	if result.RestoreError != "" {
+		mrRepoLogger.Error("Original branch not restored", "repo", repoPath, "branch", result.OriginalHead, "error", result.RestoreError)
+	}
+	if result.StashLeft {
+		mrRepoLogger.Error("Uncommitted changes left in git stash, restore them with git stash pop", "repo", repoPath)
+	}
+	if result.SubmoduleError != "" {
+		mrRepoLogger.Warn("Submodules not synced", "repo", repoPath, "error", result.SubmoduleError)
+	}
+	for _, submodule := range result.Submodules {
+		if submodule.Error != "" {
+			mrRepoLogger.Warn("Failed submodule", "repo", repoPath, "submodule", submodule.Path, "error", submodule.Error)
+		}
+	}
+	if !result.WorktreeClean {
+		mrRepoLogger.Warn("Worktree not clean after update", "repo", repoPath)
+	}
+}
+
+func init() {
+	addResumeFlag(updateBranchesCmd)
+	addInteractiveFlag(updateBranchesCmd)
+	updateBranchesCmd.Flags().Bool("autostash", false, "stash uncommitted changes before the update and restore them afterwards")
+	updateBranchesCmd.Flags().Bool("no-checkout", false, "fast-forward branch refs without checking them out, falling back to checkout when needed")
+	updateBranchesCmd.Flags().StringSlice("branches", nil, "only update branches matching these glob patterns (e.g. release/*)")
+	updateBranchesCmd.Flags().StringSlice("exclude-branches", nil, "never update branches matching these glob patterns")
+	_ = updateBranchesCmd.RegisterFlagCompletionFunc("branches", completeBranches)
+	_ = updateBranchesCmd.RegisterFlagCompletionFunc("exclude-branches", completeBranches)
+	updateBranchesCmd.Flags().Bool("recurse-submodules", false, "fetch the submodules after the update and check them out at the commit recorded by the checked-out branch")
+	updateBranchesCmd.Flags().Bool("follow-redirects", false, "update origin when the provider reports the repository has moved")
+}
@@ -1,58 +1,121 @@
 package mr_repo
 
 import (
-	"context"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/nanaki-93/goktor/service"
 	"github.com/spf13/cobra"
 )
 
 var updateRemoteCmd = &cobra.Command{
-	Use:   "update-remote",
+	Use:   "update-remote [new-remote]",
 	Short: "Update remote URLs for all repositories",
 	Long: `Update the remote repository URL for all git projects in the current directory.
-a new remote URL is required.`,
+a new remote URL is required. Use --remote to rewrite a remote other than origin;
+extra URLs configured on the remote are kept.
+
+Instead of a new remote, --rewrite applies a sed-style regex substitution to every
+URL of the remote, e.g. 's#old-host.com/group#new-host.com/newgroup#'. Use it
+when the remote path does not end with the project name, as with nested GitLab groups.`,
 	SilenceUsage: true,
-	Args:         cobra.ExactArgs(1),
+	Args:         cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		force, _ := cmd.Flags().GetBool("force")
+		remoteName, _ := cmd.Flags().GetString("remote")
+		rewriteExpr, _ := cmd.Flags().GetString("rewrite")
 
-		newRemote := args[0]
-
-		if newRemote == "" {
+		var rewrite *service.RemoteRewrite
+		var newRemote string
+		switch {
+		case rewriteExpr != "" && len(args) > 0:
+			return fmt.Errorf("a new remote arg and --rewrite are mutually exclusive")
+		case rewriteExpr != "":
+			var err error
+			if rewrite, err = service.ParseRemoteRewrite(rewriteExpr); err != nil {
+				return err
+			}
+		case len(args) == 0 || args[0] == "":
 			return fmt.Errorf("a new remote arg is required")
+		default:
+			newRemote = args[0]
 		}
 
-		currDir, err := os.Getwd()
+		gs := newGitService(cmd)
+
+		repoDirs, err := workspaceRepos(cmd)
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return err
 		}
 
-		gs := service.NewGitService(mrRepoLogger)
-
-		entries, err := os.ReadDir(currDir)
+		ctx := cmd.Context()
+		opts := gitOptions(cmd)
+		opts.Remote, opts.Force = remoteName, force
+		confirmer, err := startConfirmer(cmd)
 		if err != nil {
-			return fmt.Errorf("failed to read directory: %w", err)
+			return err
 		}
+		change := service.RemoteChange{NewRemote: newRemote, Rewrite: rewrite}
+		run := newRunRecord(cmd, repoDirs)
+		defer finishRun(ctx, run)
 
-		for _, entry := range entries {
-			if !entry.IsDir() {
+		for i, absPath := range repoDirs {
+			if ctx.Err() != nil || confirmer.stopped() {
+				break
+			}
+			if confirmer.asking() && confirmer.skip(run, i, remotePlan(absPath, remoteName, change)) {
 				continue
 			}
-
-			absPath := filepath.Join(currDir, entry.Name())
-
-			if err := gs.UpdateRemote(context.Background(), absPath, newRemote, force); err != nil {
-				mrRepoLogger.Warn("UpdateRemote: ", absPath, err.Error())
+			if run.Start(i) != nil {
+				continue
 			}
+			if rewrite != nil {
+				err = gs.RewriteRemote(ctx, absPath, rewrite, opts)
+			} else {
+				err = gs.UpdateRemote(ctx, absPath, newRemote, opts)
+			}
+			if errors.Is(err, service.ErrRemoteUnchanged) {
+				mrRepoUsage.Count("unchanged", 1)
+				mrRepoLogger.Info("remote URL does not match the rewrite, skipping", "repo", absPath)
+				run.Set(i, service.RunStatusDone, nil, nil)
+				continue
+			}
+			if err != nil {
+				mrRepoUsage.Count("failed", 1)
+				mrRepoLogger.Warn("UpdateRemote failed", "repo", absPath, "error", err)
+				run.Set(i, runStatus(ctx, err), nil, err)
+				continue
+			}
+			mrRepoUsage.Count("updated", 1)
+			run.Set(i, service.RunStatusDone, nil, nil)
 		}
 		return nil
 	},
 }
 
+// remotePlan describes the URLs change gives to the remote of the repository,
+// or is empty when they stay the same
+func remotePlan(repoPath string, remoteName string, change service.RemoteChange) string {
+	from, to, err := service.PlanRemoteChange(repoPath, remoteName, change)
+	if errors.Is(err, service.ErrRemoteUnchanged) {
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("change the URLs of %s (%v)", remoteName, err)
+	}
+	var changes []string
+	for i := range to {
+		if to[i] != from[i] {
+			changes = append(changes, fmt.Sprintf("%s -> %s", from[i], to[i]))
+		}
+	}
+	return fmt.Sprintf("set %s URL %s", remoteName, strings.Join(changes, ", "))
+}
+
 func init() {
+	addInteractiveFlag(updateRemoteCmd)
 	updateRemoteCmd.Flags().BoolP("force", "f", false, "force the update")
+	updateRemoteCmd.Flags().String("remote", "origin", "name of the remote to rewrite (e.g. upstream)")
+	updateRemoteCmd.Flags().String("rewrite", "", "sed-style substitution applied to the remote URLs, e.g. 's#old-host/group#new-host/group#'")
 }
@@ -84,6 +84,11 @@ func setupTestDir(t *testing.T, nRepos int) (string, func()) {
 }
 
 func TestUpdateRemoteCmd(t *testing.T) {
+	// Keep the runs store out of the real home directory
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
 	tests := []struct {
 		name      string
 		args      []string
@@ -114,6 +119,22 @@ func TestUpdateRemoteCmd(t *testing.T) {
 				return setupTestDir(t, 2)
 			},
 		},
+		{
+			name:      "invalid rewrite expression",
+			args:      []string{"mr-repo", "update-remote", "--rewrite", "old-host#new-host"},
+			wantError: true,
+			setup: func(t *testing.T) (string, func()) {
+				return setupTestDir(t, 2)
+			},
+		},
+		{
+			name:      "rewrite with new remote",
+			args:      []string{"mr-repo", "update-remote", "https://github.com/neworg", "--rewrite", "s#a#b#"},
+			wantError: true,
+			setup: func(t *testing.T) (string, func()) {
+				return setupTestDir(t, 2)
+			},
+		},
 		{
 			name:      "short flag -a",
 			args:      []string{"mr-repo", "update-remote", "-a", "https://github.com/neworg"},
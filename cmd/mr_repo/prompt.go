@@ -0,0 +1,144 @@
+package mr_repo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// answer is the reply to the --interactive prompt shown before a repository
+type answer int
+
+const (
+	// answerYes works on the repository
+	answerYes answer = iota
+	// answerNo skips the repository
+	answerNo
+	// answerAll works on the repository and every following one without asking
+	answerAll
+	// answerQuit skips the repository and every following one
+	answerQuit
+)
+
+// prompter asks the user a question about a repository
+type prompter interface {
+	Ask(question string) (answer, error)
+}
+
+// linePrompter writes the questions to out and reads one answer per line from
+// in, asking again until the answer is known
+type linePrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newLinePrompter(in io.Reader, out io.Writer) *linePrompter {
+	return &linePrompter{in: bufio.NewReader(in), out: out}
+}
+
+// Ask returns answerQuit along with the error when in is closed or fails
+func (p *linePrompter) Ask(question string) (answer, error) {
+	for {
+		fmt.Fprintf(p.out, "%s [y/n/a/q] ", question)
+		line, err := p.in.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return answerYes, nil
+		case "n", "no":
+			return answerNo, nil
+		case "a", "all":
+			return answerAll, nil
+		case "q", "quit":
+			return answerQuit, nil
+		}
+		if err != nil {
+			fmt.Fprintln(p.out)
+			return answerQuit, err
+		}
+		fmt.Fprintln(p.out, "Answer y (yes), n (skip this repository), a (yes to all) or q (quit)")
+	}
+}
+
+// newPrompter returns the prompter of --interactive. Questions go to stderr,
+// so they never mix with the run record printed by --output json.
+var newPrompter = func(cmd *cobra.Command) (prompter, error) {
+	if !isTerminal(os.Stdin) {
+		return nil, errors.New("--interactive needs a terminal on stdin")
+	}
+	return newLinePrompter(cmd.InOrStdin(), cmd.ErrOrStderr()), nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// repoConfirmer shows the planned change of every repository and asks before
+// touching it with --interactive; without it every repository is confirmed
+type repoConfirmer struct {
+	prompter prompter
+	all      bool
+	quit     bool
+}
+
+// startConfirmer returns the confirmer of cmd, asking when --interactive is set
+func startConfirmer(cmd *cobra.Command) (*repoConfirmer, error) {
+	if interactive, _ := cmd.Flags().GetBool("interactive"); !interactive {
+		return &repoConfirmer{}, nil
+	}
+	p, err := newPrompter(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return &repoConfirmer{prompter: p}, nil
+}
+
+// asking reports whether the next repository needs a plan to show
+func (c *repoConfirmer) asking() bool {
+	return c.prompter != nil && !c.all && !c.quit
+}
+
+// stopped reports whether the user quit, so no further repository is touched
+func (c *repoConfirmer) stopped() bool {
+	return c.quit
+}
+
+// skip shows plan for the repository at index i of run and reports whether
+// the user declined it, in which case it is recorded as skipped. An empty plan
+// means there is nothing to change, and nothing is asked.
+func (c *repoConfirmer) skip(run *service.RunRecord, i int, plan string) bool {
+	if !c.asking() || plan == "" {
+		return c.quit
+	}
+	reply, err := c.prompter.Ask(fmt.Sprintf("%s\n  %s\nProceed?", run.Repos[i].Repo, plan))
+	if err != nil {
+		mrRepoLogger.Warn("failed to read the answer, stopping", "error", err)
+	}
+	switch reply {
+	case answerYes:
+		return false
+	case answerAll:
+		c.all = true
+		return false
+	case answerQuit:
+		c.quit = true
+		return true
+	default:
+		mrRepoLogger.Info("Skipped repository on request", "repo", run.Repos[i].Repo)
+		run.Set(i, service.RunStatusSkipped, nil, nil)
+		return true
+	}
+}
+
+func addInteractiveFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP("interactive", "i", false, "show the planned change of every repository and ask y (yes), n (skip), a (yes to all) or q (quit) before touching it")
+}
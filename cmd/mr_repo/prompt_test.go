@@ -0,0 +1,106 @@
+package mr_repo
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// scriptedPrompter answers the questions from a list and records them
+type scriptedPrompter struct {
+	answers   []answer
+	questions []string
+}
+
+func (p *scriptedPrompter) Ask(question string) (answer, error) {
+	p.questions = append(p.questions, question)
+	reply := p.answers[0]
+	p.answers = p.answers[1:]
+	return reply, nil
+}
+
+func TestLinePrompter(t *testing.T) {
+	var out bytes.Buffer
+	p := newLinePrompter(strings.NewReader("maybe\nA\n"), &out)
+	reply, err := p.Ask("Proceed?")
+	if err != nil || reply != answerAll {
+		t.Errorf("Ask() = %v, %v, want answerAll", reply, err)
+	}
+	if strings.Count(out.String(), "Proceed? [y/n/a/q]") != 2 {
+		t.Errorf("an unknown answer should ask again, output = %q", out.String())
+	}
+
+	reply, err = newLinePrompter(strings.NewReader(""), &out).Ask("Proceed?")
+	if err == nil || reply != answerQuit {
+		t.Errorf("Ask() at end of input = %v, %v, want answerQuit and an error", reply, err)
+	}
+}
+
+func TestRepoConfirmer(t *testing.T) {
+	SetLogger(&service.DefaultLogger{})
+	run := service.NewRunRecord("test", []string{"/ws/a", "/ws/b", "/ws/c", "/ws/d"})
+	p := &scriptedPrompter{answers: []answer{answerNo, answerAll}}
+	c := &repoConfirmer{prompter: p}
+
+	if !c.skip(run, 0, "change a") || run.Repos[0].Status != service.RunStatusSkipped {
+		t.Errorf("a declined repository should be skipped, status = %q", run.Repos[0].Status)
+	}
+	if c.skip(run, 1, "") {
+		t.Errorf("a repository without change should not be skipped")
+	}
+	if c.skip(run, 2, "change c") || c.skip(run, 3, "change d") {
+		t.Errorf("every repository should go on after answering all")
+	}
+	if len(p.questions) != 2 || !strings.Contains(p.questions[0], "/ws/a") || !strings.Contains(p.questions[0], "change a") {
+		t.Errorf("questions = %q", p.questions)
+	}
+
+	c = &repoConfirmer{prompter: &scriptedPrompter{answers: []answer{answerQuit}}}
+	if !c.skip(run, 0, "change a") || !c.stopped() || c.asking() {
+		t.Errorf("quitting should skip the repository and stop the run")
+	}
+}
+
+func TestUpdateRemoteCmd_Interactive(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	testDir, cleanup := setupTestDir(t, 3)
+	defer cleanup()
+
+	p := &scriptedPrompter{answers: []answer{answerNo, answerQuit}}
+	defaultPrompter := newPrompter
+	newPrompter = func(*cobra.Command) (prompter, error) { return p, nil }
+	defer func() {
+		newPrompter = defaultPrompter
+		_ = updateRemoteCmd.Flags().Set("interactive", "false")
+	}()
+
+	MrRepoCmd.SetArgs([]string{"update-remote", "git@github.com:neworg", "--interactive", "--root", testDir})
+	if err := MrRepoCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if len(p.questions) != 2 || !strings.Contains(p.questions[0], "https://github.com/oldorg/project.git -> git@github.com:neworg/project.git") {
+		t.Errorf("questions = %q", p.questions)
+	}
+	for i := 1; i <= 3; i++ {
+		repo, err := git.PlainOpen(filepath.Join(testDir, fmt.Sprintf("test-repo-%d", i)))
+		if err != nil {
+			t.Fatalf("failed to open repo: %v", err)
+		}
+		remote, err := repo.Remote("origin")
+		if err != nil {
+			t.Fatalf("failed to read origin: %v", err)
+		}
+		if url := remote.Config().URLs[0]; url != "https://github.com/oldorg/project.git" {
+			t.Errorf("test-repo-%d origin = %s, want it untouched", i, url)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package mr_repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// addWorkspaceFlags adds the flags selecting the repositories the commands work on
+func addWorkspaceFlags(flags *pflag.FlagSet) {
+	flags.StringSlice("root", nil, "workspace directory containing the repositories, repeatable (defaults to the current directory)")
+	flags.StringSlice("include", nil, `only work on the repositories whose directory name matches one of these glob patterns, such as "service-*"`)
+	flags.StringSlice("exclude", nil, "skip the repositories whose directory name matches one of these glob patterns")
+	flags.String("repo", "", "only work on this repository: a directory name in the workspace roots, or a path")
+	flags.String("dir", "", "parent directory of the repositories, instead of the current directory")
+	flags.StringP("workspace", "w", "", "work on the repositories of this workspace of the config instead of a directory, from anywhere")
+}
+
+// workingDir returns the absolute --dir directory, or the current directory
+func workingDir(cmd *cobra.Command) (string, error) {
+	if dir, _ := cmd.Flags().GetString("dir"); dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", fmt.Errorf("invalid --dir %s: %w", dir, err)
+		}
+		return abs, nil
+	}
+	currDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	return currDir, nil
+}
+
+// workspaceFromConfig returns the repositories of the --workspace of the
+// config, nil without --workspace
+func workspaceFromConfig(cmd *cobra.Command) ([]string, error) {
+	name, _ := cmd.Flags().GetString("workspace")
+	if name == "" {
+		return nil, nil
+	}
+	repos, missing, err := service.WorkspaceRepos(mrRepoConfig, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range missing {
+		mrRepoLogger.Warn("Workspace repository not found", "workspace", name, "repo", repo)
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("workspace %s has no existing repository", name)
+	}
+	return repos, nil
+}
+
+// workspaceRoots returns the absolute --root directories, or the --dir or
+// current directory when none is given, without duplicates. A --repo given as a path
+// makes its parent directory the only root, and --workspace makes the parent
+// directories of its repositories the roots.
+func workspaceRoots(cmd *cobra.Command) ([]string, error) {
+	if workspace, _ := cmd.Flags().GetString("workspace"); workspace != "" {
+		repos, err := workspaceFromConfig(cmd)
+		if err != nil {
+			return nil, err
+		}
+		var roots []string
+		for _, repo := range repos {
+			if root := filepath.Dir(repo); !slices.Contains(roots, root) {
+				roots = append(roots, root)
+			}
+		}
+		return roots, nil
+	}
+	if repo, _ := cmd.Flags().GetString("repo"); repo != "" && isRepoPath(repo) {
+		abs, err := filepath.Abs(repo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repo %s: %w", repo, err)
+		}
+		return []string{filepath.Dir(abs)}, nil
+	}
+
+	roots, _ := cmd.Flags().GetStringSlice("root")
+	if len(roots) == 0 {
+		currDir, err := workingDir(cmd)
+		if err != nil {
+			return nil, err
+		}
+		return []string{currDir}, nil
+	}
+
+	var absRoots []string
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		if !slices.Contains(absRoots, abs) {
+			absRoots = append(absRoots, abs)
+		}
+	}
+	return absRoots, nil
+}
+
+// isRepoPath reports whether --repo is a path rather than a directory name
+func isRepoPath(repo string) bool {
+	return repo == "." || repo == ".." || filepath.Base(filepath.Clean(repo)) != repo
+}
+
+// workspaceRepos returns the repositories of every workspace root selected by
+// --repo, --include and --exclude, root by root
+func workspaceRepos(cmd *cobra.Command) ([]string, error) {
+	roots, err := workspaceRoots(cmd)
+	if err != nil {
+		return nil, err
+	}
+	var repoDirs []string
+	for _, root := range roots {
+		dirs, err := rootRepos(cmd, root)
+		if err != nil {
+			return nil, err
+		}
+		repoDirs = append(repoDirs, dirs...)
+	}
+	if repo, _ := cmd.Flags().GetString("repo"); repo != "" && len(repoDirs) == 0 {
+		return nil, fmt.Errorf("repository %s not found in %v", repo, roots)
+	}
+	if len(repoDirs) == 0 {
+		if filter := repoFilterFromFlags(cmd); len(filter.include) > 0 || len(filter.exclude) > 0 {
+			mrRepoLogger.Warn("No repository matches --include and --exclude")
+		} else {
+			mrRepoLogger.Warn("No repository found", "roots", roots)
+		}
+	}
+	return repoDirs, nil
+}
+
+// rootRepos returns the repositories of root selected by --repo, --include and
+// --exclude; with --workspace, only the workspace repositories of root
+func rootRepos(cmd *cobra.Command, root string) ([]string, error) {
+	var dirs []string
+	if workspace, _ := cmd.Flags().GetString("workspace"); workspace != "" {
+		repos, _, err := service.WorkspaceRepos(mrRepoConfig, workspace)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if filepath.Dir(repo) == root {
+				dirs = append(dirs, repo)
+			}
+		}
+	} else {
+		var err error
+		if dirs, err = ListRepoDirs(root); err != nil {
+			return nil, fmt.Errorf("%s: %w", root, err)
+		}
+	}
+	return repoFilterFromFlags(cmd).filterRepos(dirs), nil
+}
+
+// targetRepo returns the repository of the commands working on a single one:
+// the --repo one, or the --dir or current directory. A --repo name found in
+// several roots is an error rather than a guess.
+func targetRepo(cmd *cobra.Command) (string, error) {
+	if repo, _ := cmd.Flags().GetString("repo"); repo != "" {
+		repoDirs, err := workspaceRepos(cmd)
+		if err != nil {
+			return "", err
+		}
+		if len(repoDirs) > 1 {
+			return "", fmt.Errorf("repository %s is ambiguous, pass its path: %v", repo, repoDirs)
+		}
+		return repoDirs[0], nil
+	}
+	return workingDir(cmd)
+}
+
+// RepoDir is a repository found under a workspace root
+type RepoDir struct {
+	// Path is the absolute path of the repository
+	Path string
+	// Kind tells a bare repository, such as a mirror, from a worktree
+	Kind service.RepoKind
+}
+
+// ListRepos returns the repositories among the immediate child directories
+// of root, worktrees and bare repositories alike; the other directories are
+// skipped
+func ListRepos(root string) ([]RepoDir, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var repos []RepoDir
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(root, entry.Name())
+		if kind := service.DetectRepoKind(path); kind != service.RepoKindNone {
+			repos = append(repos, RepoDir{Path: path, Kind: kind})
+		}
+	}
+	return repos, nil
+}
+
+// ListRepoDirs returns the absolute paths of the repositories of ListRepos
+func ListRepoDirs(root string) ([]string, error) {
+	repos, err := ListRepos(root)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, len(repos))
+	for i, repo := range repos {
+		dirs[i] = repo.Path
+	}
+	return dirs, nil
+}
+
+// repoFilter selects the repositories of a workspace by directory name from
+// --repo, --include and --exclude
+type repoFilter struct {
+	repo    string
+	include []string
+	exclude []string
+}
+
+func repoFilterFromFlags(cmd *cobra.Command) repoFilter {
+	repo, _ := cmd.Flags().GetString("repo")
+	include, _ := cmd.Flags().GetStringSlice("include")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	if repo != "" && isRepoPath(repo) {
+		if abs, err := filepath.Abs(repo); err == nil {
+			repo = filepath.Base(abs)
+		}
+	}
+	return repoFilter{repo: repo, include: include, exclude: exclude}
+}
+
+// validate checks the --include and --exclude glob patterns
+func (f repoFilter) validate() error {
+	for _, pattern := range append(append([]string(nil), f.include...), f.exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid repository pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the repository directory named name is selected:
+// it is the --repo one, matches an --include pattern if any and no --exclude pattern
+func (f repoFilter) matches(name string) bool {
+	if f.repo != "" && name != f.repo {
+		return false
+	}
+	if len(f.include) > 0 && !matchAnyPattern(f.include, name) {
+		return false
+	}
+	return !matchAnyPattern(f.exclude, name)
+}
+
+func matchAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRepos returns the repository directories selected by f
+func (f repoFilter) filterRepos(repoDirs []string) []string {
+	var selected []string
+	for _, repoDir := range repoDirs {
+		if f.matches(filepath.Base(repoDir)) {
+			selected = append(selected, repoDir)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,203 @@
+package mr_repo
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+func TestWorkspaceRepos_Filters(t *testing.T) {
+	SetLogger(&service.DefaultLogger{})
+	root := t.TempDir()
+	for _, name := range []string{"service-a", "service-b", "web", "service-legacy"} {
+		if err := os.MkdirAll(filepath.Join(root, name, ".git"), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no filter", want: []string{"service-a", "service-b", "service-legacy", "web"}},
+		{name: "include", args: []string{"--include", "service-*"}, want: []string{"service-a", "service-b", "service-legacy"}},
+		{name: "include and exclude", args: []string{"--include", "service-*", "--exclude", "*-legacy"}, want: []string{"service-a", "service-b"}},
+		{name: "repo name", args: []string{"--repo", "web"}, want: []string{"web"}},
+		{name: "repo path", args: []string{"--root", t.TempDir(), "--repo", filepath.Join(root, "service-b")}, want: []string{"service-b"}},
+		{name: "unknown repo", args: []string{"--repo", "api"}, wantErr: true},
+		{name: "nothing included", args: []string{"--include", "api-*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addWorkspaceFlags(cmd.Flags())
+			args := tt.args
+			if !slices.Contains(args, "--root") {
+				args = append([]string{"--root", root}, args...)
+			}
+			if err := cmd.ParseFlags(args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+
+			repoDirs, err := workspaceRepos(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("workspaceRepos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, repoDir := range repoDirs {
+				names = append(names, filepath.Base(repoDir))
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("workspaceRepos() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoFilter_Validate(t *testing.T) {
+	if err := (repoFilter{include: []string{"service-["}}).validate(); err == nil {
+		t.Errorf("validate() should reject a malformed pattern")
+	}
+	if err := (repoFilter{include: []string{"service-*"}, exclude: []string{"*-legacy"}}).validate(); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+}
+
+func TestListRepos(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"app/.git", "mirror.git/objects", "mirror.git/refs", "docs"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	for _, file := range []string{"mirror.git/HEAD", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(root, file), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", file, err)
+		}
+	}
+
+	repos, err := ListRepos(root)
+	if err != nil {
+		t.Fatalf("ListRepos() error = %v", err)
+	}
+	want := []RepoDir{
+		{Path: filepath.Join(root, "app"), Kind: service.RepoKindWorktree},
+		{Path: filepath.Join(root, "mirror.git"), Kind: service.RepoKindBare},
+	}
+	if !slices.Equal(repos, want) {
+		t.Errorf("ListRepos() = %v, want %v", repos, want)
+	}
+}
+
+func TestWorkspaceRepos_ConfigWorkspace(t *testing.T) {
+	SetLogger(&service.DefaultLogger{})
+	work, oss := t.TempDir(), t.TempDir()
+	for _, dir := range []string{filepath.Join(work, "api"), filepath.Join(work, "web"), filepath.Join(oss, "lib-a", ".git"), filepath.Join(oss, "lib-b", ".git"), filepath.Join(oss, "lib-docs")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	defer SetConfig(mrRepoConfig)
+	SetConfig(&service.Config{Workspaces: map[string][]string{
+		"mixed": {filepath.Join(work, "api"), filepath.Join(oss, "lib-*"), filepath.Join(work, "gone")},
+	}})
+
+	cmd := &cobra.Command{}
+	addWorkspaceFlags(cmd.Flags())
+	if err := cmd.ParseFlags([]string{"--workspace", "mixed", "--exclude", "lib-b"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	repoDirs, err := workspaceRepos(cmd)
+	if err != nil {
+		t.Fatalf("workspaceRepos() error = %v", err)
+	}
+	want := []string{filepath.Join(work, "api"), filepath.Join(oss, "lib-a")}
+	if !slices.Equal(repoDirs, want) {
+		t.Errorf("workspaceRepos() = %v, want %v", repoDirs, want)
+	}
+
+	cmd = &cobra.Command{}
+	addWorkspaceFlags(cmd.Flags())
+	if err := cmd.ParseFlags([]string{"--workspace", "infra"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	if _, err := workspaceRepos(cmd); err == nil {
+		t.Errorf("workspaceRepos() should reject an unknown workspace")
+	}
+}
+
+func TestWorkspaceRoots_Dir(t *testing.T) {
+	dir := t.TempDir()
+	cmd := &cobra.Command{}
+	addWorkspaceFlags(cmd.Flags())
+	if err := cmd.ParseFlags([]string{"--dir", dir}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	roots, err := workspaceRoots(cmd)
+	if err != nil {
+		t.Fatalf("workspaceRoots() error = %v", err)
+	}
+	if !slices.Equal(roots, []string{dir}) {
+		t.Errorf("workspaceRoots() = %v, want [%s]", roots, dir)
+	}
+	if repo, err := targetRepo(cmd); err != nil || repo != dir {
+		t.Errorf("targetRepo() = %s, %v, want %s", repo, err, dir)
+	}
+}
+
+func TestTargetRepo_Ambiguous(t *testing.T) {
+	SetLogger(&service.DefaultLogger{})
+	var roots []string
+	for range 2 {
+		root := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(root, "web", ".git"), 0755); err != nil {
+			t.Fatalf("failed to create web: %v", err)
+		}
+		roots = append(roots, root)
+	}
+	cmd := &cobra.Command{}
+	addWorkspaceFlags(cmd.Flags())
+	if err := cmd.ParseFlags([]string{"--root", roots[0], "--root", roots[1], "--repo", "web"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	if repo, err := targetRepo(cmd); err == nil {
+		t.Errorf("targetRepo() = %s, want an error for a name found in both roots", repo)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"service-a", "service-b", "web"} {
+		if err := os.MkdirAll(filepath.Join(root, name, ".git"), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	defer SetConfig(mrRepoConfig)
+	SetConfig(&service.Config{Workspaces: map[string][]string{"work": {root}, "oss": {root}}})
+
+	cmd := &cobra.Command{}
+	addWorkspaceFlags(cmd.Flags())
+	if err := cmd.ParseFlags([]string{"--dir", root, "--include", "service-*", "--repo", "web"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	repos, _ := completeRepos(cmd, nil, "")
+	if want := []string{"service-a", "service-b"}; !slices.Equal(repos, want) {
+		t.Errorf("completeRepos() = %v, want %v", repos, want)
+	}
+	if _, directive := completeRepos(cmd, nil, "../"); directive != cobra.ShellCompDirectiveFilterDirs {
+		t.Errorf("completeRepos() directive = %v for a path, want FilterDirs", directive)
+	}
+	if workspaces, _ := CompleteWorkspaces(cmd, nil, ""); !slices.Equal(workspaces, []string{"oss", "work"}) {
+		t.Errorf("CompleteWorkspaces() = %v, want [oss work]", workspaces)
+	}
+	// the directories are not repositories
+	if branches, _ := completeBranches(cmd, nil, ""); len(branches) != 0 {
+		t.Errorf("completeBranches() = %v, want none", branches)
+	}
+}
@@ -5,23 +5,197 @@ Copyright © 2025 Marco Andreose <andreose.marco93@gmail.com>
 package mr_repo
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/nanaki-93/goktor/service"
 	"github.com/spf13/cobra"
 )
 
 var mrRepoLogger service.Logger
+var mrRepoUsage *service.UsageTracker
+var mrRepoConfig = &service.Config{}
+
+// Formats of --output
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// mrRepoOut receives the human readable output of the commands. It is
+// discarded with --output json, which prints the run record instead.
+var mrRepoOut io.Writer = os.Stdout
+
+// jsonOutput is set by --output json
+var jsonOutput bool
 
 func SetLogger(logger service.Logger) {
 	mrRepoLogger = logger
 }
 
+func SetUsageTracker(usage *service.UsageTracker) {
+	mrRepoUsage = usage
+}
+
+func SetConfig(config *service.Config) {
+	mrRepoConfig = config
+}
+
+// newGitService returns a git service authenticating with the credentials
+// of the config, through the proxy of the flags
+func newGitService(cmd *cobra.Command) service.GitService {
+	gs := service.NewGitService(mrRepoLogger)
+	gs.SetCredentials(mrRepoConfig.Credentials)
+	gs.SetProxy(proxyFromFlags(cmd))
+	return gs
+}
+
+// proxyFromFlags returns the proxy of --proxy and --no-proxy, falling back to
+// the proxy section of the config, then to the environment
+func proxyFromFlags(cmd *cobra.Command) service.ProxyConfig {
+	var proxy service.ProxyConfig
+	if url, _ := cmd.Flags().GetString("proxy"); url != "" {
+		proxy.HTTP, proxy.HTTPS = url, url
+	}
+	proxy.NoProxy, _ = cmd.Flags().GetStringSlice("no-proxy")
+	return proxy.Or(mrRepoConfig.Proxy).Or(service.ProxyFromEnvironment())
+}
+
+// identityFromFlags returns the commit identity from --git-name and
+// --git-email, falling back to the identity section of the config
+func identityFromFlags(cmd *cobra.Command) service.Identity {
+	identity := mrRepoConfig.Identity
+	if name, _ := cmd.Flags().GetString("git-name"); name != "" {
+		identity.Name = name
+	}
+	if email, _ := cmd.Flags().GetString("git-email"); email != "" {
+		identity.Email = email
+	}
+	return identity
+}
+
+// gitOptions returns the options shared by every git operation of cmd: the
+// --timeout bounding each repository
+func gitOptions(cmd *cobra.Command) service.Options {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	return service.Options{Timeout: timeout}
+}
+
+// protectedBranches returns the branch patterns of the branches section of
+// the config and of --protect
+func protectedBranches(cmd *cobra.Command) []string {
+	protect, _ := cmd.Flags().GetStringSlice("protect")
+	return append(append([]string(nil), mrRepoConfig.Branches.Protected...), protect...)
+}
+
 var MrRepoCmd = &cobra.Command{
 	Use:   "mr-repo",
 	Short: "Manage multiple repositories",
-	Long:  `Commands to manage multiple git repositories in a directory.`,
+	Long: `Commands to manage multiple git repositories in a directory.
+Batch commands work on the current directory or --dir, on every --root directory in one run,
+or on the repositories of a --workspace defined in the config, from anywhere.
+Use --include and --exclude glob patterns, or --repo, to target a subset of the repositories.
+
+Every batch command saves a versioned JSON run record in ~/.goktor/runs;
+use --schema to print its JSON Schema. With --output json, every command prints
+its run record on stdout instead of tables, and logs go to stderr.`,
+	Args: cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		finishedRun = nil
+		output, _ := cmd.Flags().GetString("output")
+		switch output {
+		case OutputText:
+			jsonOutput, mrRepoOut = false, os.Stdout
+		case OutputJSON:
+			jsonOutput, mrRepoOut = true, io.Discard
+		default:
+			return fmt.Errorf("unknown --output %q, expected %q or %q", output, OutputText, OutputJSON)
+		}
+		if timeout, _ := cmd.Flags().GetDuration("timeout"); timeout < 0 {
+			return fmt.Errorf("--timeout cannot be negative")
+		}
+		protect, _ := cmd.Flags().GetStringSlice("protect")
+		if err := service.ValidateBranchPatterns(protect); err != nil {
+			return fmt.Errorf("invalid --protect: %w", err)
+		}
+		if err := proxyFromFlags(cmd).Validate(); err != nil {
+			return fmt.Errorf("invalid --proxy: %w", err)
+		}
+		if err := repoFilterFromFlags(cmd).validate(); err != nil {
+			return err
+		}
+		if workspace, _ := cmd.Flags().GetString("workspace"); workspace != "" {
+			roots, _ := cmd.Flags().GetStringSlice("root")
+			repo, _ := cmd.Flags().GetString("repo")
+			dir, _ := cmd.Flags().GetString("dir")
+			if len(roots) > 0 || dir != "" || (repo != "" && isRepoPath(repo)) {
+				return fmt.Errorf("--workspace cannot be combined with --root, --dir or a --repo path")
+			}
+		}
+		if dir, _ := cmd.Flags().GetString("dir"); dir != "" {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return fmt.Errorf("--dir %s is not a directory", dir)
+			}
+		}
+		return nil
+	},
+	// the batch commands run to completion on the failing repositories; the
+	// failures turn into the partial failure exit code
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		return runFailure(finishedRun)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if schema, _ := cmd.Flags().GetBool("schema"); schema {
+			return printRunSchema(cmd.OutOrStdout())
+		}
+		return cmd.Help()
+	},
+}
+
+// printRunSchema writes the JSON Schema of the run records
+func printRunSchema(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(service.RunSchema()); err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	return nil
 }
 
 func init() {
+	MrRepoCmd.Flags().Bool("schema", false, "print the JSON Schema of the run records and exit")
+	addWorkspaceFlags(MrRepoCmd.PersistentFlags())
+	MrRepoCmd.PersistentFlags().String("git-name", "", "author name of the commits goktor creates (defaults to the repository git config)")
+	MrRepoCmd.PersistentFlags().String("git-email", "", "author email of the commits goktor creates (defaults to the repository git config)")
+	MrRepoCmd.PersistentFlags().StringSlice("protect", nil, `branches (glob patterns such as "release/*") never deleted or hard-reset, added to branches.protected of the config`)
+	MrRepoCmd.PersistentFlags().Duration("timeout", 0, "give up on a repository whose operation takes longer than this, such as 2m, and go on with the next one (0 for no limit)")
+	MrRepoCmd.PersistentFlags().StringP("output", "o", OutputText, "output format: text, or json to print the run record with per-repository results, durations and errors")
+	MrRepoCmd.PersistentFlags().String("pre-hook", "", "shell command run in every repository before it is processed; a failure skips the repository (overrides hooks.pre of the config)")
+	MrRepoCmd.PersistentFlags().String("post-hook", "", "shell command run in every repository once processed, with its run status in GOKTOR_RESULT (overrides hooks.post of the config)")
+	MrRepoCmd.PersistentFlags().String("proxy", "", "proxy URL of the HTTP(S) remotes, such as http://proxy.mycorp.com:3128 (overrides proxy of the config and HTTP_PROXY/HTTPS_PROXY)")
+	MrRepoCmd.PersistentFlags().StringSlice("no-proxy", nil, `hosts reached without the proxy, such as "localhost,.mycorp.com" (overrides proxy.no_proxy of the config and NO_PROXY)`)
+	registerWorkspaceCompletions(MrRepoCmd)
+
 	MrRepoCmd.AddCommand(updateRemoteCmd)
+	MrRepoCmd.AddCommand(convertRemoteCmd)
 	MrRepoCmd.AddCommand(deleteMergedCmd)
+	MrRepoCmd.AddCommand(restoreBranchCmd)
+	MrRepoCmd.AddCommand(updateBranchesCmd)
+	MrRepoCmd.AddCommand(cloneAllCmd)
+	MrRepoCmd.AddCommand(pruneBranchesCmd)
+	MrRepoCmd.AddCommand(pullCmd)
+	MrRepoCmd.AddCommand(checkoutCmd)
+	MrRepoCmd.AddCommand(branchCmd)
+	MrRepoCmd.AddCommand(pushCmd)
+	MrRepoCmd.AddCommand(syncForkCmd)
+	MrRepoCmd.AddCommand(mirrorCmd)
+	MrRepoCmd.AddCommand(tagsCmd)
+	MrRepoCmd.AddCommand(fetchCmd)
+	MrRepoCmd.AddCommand(statusCmd)
+	MrRepoCmd.AddCommand(archiveCmd)
+	MrRepoCmd.AddCommand(gcCmd)
+	MrRepoCmd.AddCommand(sizeCmd)
+	MrRepoCmd.AddCommand(stashCmd)
 }
@@ -0,0 +1,266 @@
+package mr_repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// newRunRecord starts the run record of cmd over repoDirs, with the hooks of
+// hooksFromFlags run around every repository
+func newRunRecord(cmd *cobra.Command, repoDirs []string) *service.RunRecord {
+	run := service.NewRunRecord(cmd.CommandPath(), repoDirs)
+	hooks := hooksFromFlags(cmd)
+	if hooks.Pre == "" && hooks.Post == "" {
+		return run
+	}
+	ctx := cmd.Context()
+	run.Hook = func(kind string, repo service.RunRepo) error {
+		shellCommand, hookCtx := hooks.Pre, ctx
+		if kind == service.HookPost {
+			// post hooks also run for the repository in flight when the run is interrupted
+			shellCommand, hookCtx = hooks.Post, context.WithoutCancel(ctx)
+		}
+		if shellCommand == "" {
+			return nil
+		}
+		out, err := service.RunHook(hookCtx, shellCommand, kind, run.Command, repo)
+		if err != nil {
+			mrRepoLogger.Warn("Hook failed", "repo", repo.Repo, "hook", kind, "error", err)
+			return err
+		}
+		mrRepoLogger.Debug("Hook done", "repo", repo.Repo, "hook", kind, "output", out)
+		return nil
+	}
+	return run
+}
+
+// hooksFromFlags returns the hooks of --pre-hook and --post-hook, falling back
+// to the hooks section of the config
+func hooksFromFlags(cmd *cobra.Command) service.HooksConfig {
+	hooks := mrRepoConfig.Hooks
+	if pre, _ := cmd.Flags().GetString("pre-hook"); pre != "" {
+		hooks.Pre = pre
+	}
+	if post, _ := cmd.Flags().GetString("post-hook"); post != "" {
+		hooks.Post = post
+	}
+	return hooks
+}
+
+// runStatus classifies a repository error: interrupted when the run was
+// cancelled while the repository was in flight, failed otherwise
+func runStatus(ctx context.Context, err error) string {
+	if ctx.Err() != nil {
+		return service.RunStatusInterrupted
+	}
+	return service.RunStatusFailed
+}
+
+// finishedRun is the run of the executed command once finishRun is done, read
+// by runFailure to set the exit code
+var finishedRun *service.RunRecord
+
+// runFailure returns an error wrapping service.ErrPartialFailure when some
+// repositories of run failed, nil otherwise
+func runFailure(run *service.RunRecord) error {
+	if run == nil {
+		return nil
+	}
+	failed := run.StatusCounts()[service.RunStatusFailed]
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d repositories failed: %w", failed, len(run.Repos), service.ErrPartialFailure)
+}
+
+// finishRun prints a partial summary when the run was interrupted and keeps
+// the run in the runs store, so what already happened is never lost. With
+// --output json the run record is printed instead of the summaries.
+func finishRun(ctx context.Context, run *service.RunRecord) {
+	run.Interrupted = ctx.Err() != nil
+	run.Finished = time.Now()
+	finishedRun = run
+	defer reportRun(run)
+
+	if len(run.Roots()) > 1 {
+		printRootSummary(mrRepoOut, run)
+	}
+	printErrorHints(mrRepoOut, run)
+
+	if run.Interrupted {
+		counts := run.StatusCounts()
+		fmt.Fprintf(mrRepoOut, "\nInterrupted after %d of %d repositories: %d done, %d failed, %d interrupted, %d not started\n",
+			counts[service.RunStatusDone]+counts[service.RunStatusFailed]+counts[service.RunStatusInterrupted],
+			len(run.Repos),
+			counts[service.RunStatusDone],
+			counts[service.RunStatusFailed],
+			counts[service.RunStatusInterrupted],
+			counts[service.RunStatusPending])
+	}
+
+	dir, err := service.DefaultRunsDir()
+	if err != nil {
+		mrRepoLogger.Warn("failed to locate runs store", "error", err)
+		return
+	}
+	path, err := service.SaveRun(dir, run)
+	if err != nil {
+		mrRepoLogger.Warn("failed to save run", "error", err)
+		return
+	}
+	if run.Interrupted {
+		fmt.Fprintln(mrRepoOut, "Run summary saved to", path)
+	}
+	mrRepoLogger.Debug("run saved", "path", path)
+}
+
+// errorHints explains the failures of the typed service errors
+var errorHints = []struct {
+	code string
+	hint string
+}{
+	{service.ErrorCodeAuthRequired, "%d repositories were refused for missing or invalid credentials, add them to ~/.netrc or set GIT_ASKPASS"},
+	{service.ErrorCodeNoRemote, "%d repositories have no origin remote"},
+	{service.ErrorCodeNotARepository, "%d directories are not git repositories"},
+	{service.ErrorCodeDirtyWorktree, "%d repositories have uncommitted changes or untracked files, commit or stash them and rerun"},
+}
+
+// printErrorHints prints a hint for each kind of typed error the run failed on
+func printErrorHints(out io.Writer, run *service.RunRecord) {
+	counts := map[string]int{}
+	for _, repo := range run.Repos {
+		if repo.ErrorCode != "" {
+			counts[repo.ErrorCode]++
+		}
+	}
+	for _, h := range errorHints {
+		if counts[h.code] > 0 {
+			fmt.Fprintf(out, "Hint: "+h.hint+"\n", counts[h.code])
+		}
+	}
+}
+
+// reportRun prints the run record on stdout with --output json
+func reportRun(run *service.RunRecord) {
+	if !jsonOutput {
+		return
+	}
+	if run.Finished.IsZero() {
+		run.Finished = time.Now()
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(run); err != nil {
+		mrRepoLogger.Warn("failed to print run record", "error", err)
+	}
+}
+
+// printRootSummary prints the repository statuses per workspace root, then the combined totals
+func printRootSummary(out io.Writer, run *service.RunRecord) {
+	statuses := []string{service.RunStatusDone, service.RunStatusFailed, service.RunStatusInterrupted, service.RunStatusSkipped, service.RunStatusPending}
+	perRoot := run.RootStatusCounts()
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nROOT\tREPOS\tDONE\tFAILED\tINTERRUPTED\tSKIPPED\tNOT STARTED")
+	row := func(name string, counts map[string]int) {
+		total := 0
+		for _, status := range statuses {
+			total += counts[status]
+		}
+		fmt.Fprintf(w, "%s\t%d", name, total)
+		for _, status := range statuses {
+			fmt.Fprintf(w, "\t%d", counts[status])
+		}
+		fmt.Fprintln(w)
+	}
+	for _, root := range run.Roots() {
+		row(root, perRoot[root])
+	}
+	row("total", run.StatusCounts())
+	_ = w.Flush()
+}
+
+// startCheckpoint returns the checkpoint of the command run over scope: the one
+// left by an unfinished run when --resume is set, a new one otherwise. A nil
+// checkpoint disables checkpointing.
+func startCheckpoint(cmd *cobra.Command, scope ...string) *service.Checkpoint {
+	dir, err := service.DefaultCheckpointDir()
+	if err != nil {
+		mrRepoLogger.Warn("failed to locate checkpoints, the run cannot be resumed", "error", err)
+		return nil
+	}
+	path := service.CheckpointPath(dir, cmd.CommandPath(), scope...)
+	if resume, _ := cmd.Flags().GetBool("resume"); resume {
+		checkpoint, err := service.LoadCheckpoint(path, cmd.CommandPath())
+		if err == nil {
+			fmt.Fprintf(mrRepoOut, "Resuming: %d repositories completed by the previous run\n", checkpoint.Len())
+			return checkpoint
+		}
+		mrRepoLogger.Warn("Starting over", "reason", err)
+	}
+	return service.NewCheckpoint(path, cmd.CommandPath())
+}
+
+// resumeRepo reports whether the repository at index i was completed by the
+// resumed run and has not changed since, copying its recorded outcome into run
+func resumeRepo(checkpoint *service.Checkpoint, run *service.RunRecord, i int) bool {
+	repoDir := run.Repos[i].Repo
+	fingerprint, err := service.RepoFingerprint(repoDir)
+	if err != nil {
+		return false
+	}
+	var repo service.RunRepo
+	if !checkpoint.Restore(repoDir, fingerprint, &repo) {
+		return false
+	}
+	run.Repos[i] = repo
+	mrRepoLogger.Info("Completed by the previous run", "repo", repoDir)
+	return true
+}
+
+// checkpointRepo records the repository at index i as completed, with the
+// outcome already set in run
+func checkpointRepo(checkpoint *service.Checkpoint, run *service.RunRecord, i int) {
+	repoDir := run.Repos[i].Repo
+	fingerprint, err := service.RepoFingerprint(repoDir)
+	if err != nil {
+		mrRepoLogger.Debug("repository not checkpointed", "repo", repoDir, "error", err)
+		return
+	}
+	if err := checkpoint.Complete(repoDir, fingerprint, run.Repos[i]); err != nil {
+		mrRepoLogger.Warn("failed to write checkpoint", "error", err)
+	}
+}
+
+// finishCheckpoint removes the checkpoint once every repository is done,
+// otherwise keeps it so the next run can --resume
+func finishCheckpoint(checkpoint *service.Checkpoint, run *service.RunRecord) {
+	if checkpoint == nil {
+		return
+	}
+	if run.StatusCounts()[service.RunStatusDone] == len(run.Repos) {
+		if err := checkpoint.Remove(); err != nil {
+			mrRepoLogger.Warn("failed to remove checkpoint", "error", err)
+		}
+		return
+	}
+	if err := checkpoint.Save(); err != nil {
+		mrRepoLogger.Warn("failed to write checkpoint", "error", err)
+		return
+	}
+	fmt.Fprintln(mrRepoOut, "Checkpoint saved, rerun with --resume to skip the completed repositories")
+	mrRepoLogger.Debug("checkpoint saved", "path", checkpoint.Path())
+}
+
+// addResumeFlag registers --resume on a command that checkpoints its progress
+func addResumeFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("resume", false, "skip the repositories completed by the previous interrupted or failed run, if unchanged since")
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+)
+
+const (
+	outputFlushInterval = 100 * time.Millisecond
+	outputBufferSize    = 64 * 1024
+)
+
+// throttledWriter buffers output and flushes it at most every interval, so
+// long listings over slow terminals (e.g. SSH) are sent in a few large writes
+// instead of one write per line
+type throttledWriter struct {
+	mu      sync.Mutex
+	buf     *bufio.Writer
+	done    chan struct{}
+	stopped sync.WaitGroup
+}
+
+func newThrottledWriter(w io.Writer, interval time.Duration) *throttledWriter {
+	t := &throttledWriter{buf: bufio.NewWriterSize(w, outputBufferSize), done: make(chan struct{})}
+	t.stopped.Add(1)
+	go func() {
+		defer t.stopped.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.done:
+				return
+			case <-ticker.C:
+				t.mu.Lock()
+				t.buf.Flush()
+				t.mu.Unlock()
+			}
+		}
+	}()
+	return t
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.Write(p)
+}
+
+// Close stops the flush loop and writes any pending output
+func (t *throttledWriter) Close() error {
+	close(t.done)
+	t.stopped.Wait()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.Flush()
+}
+
+// openOutput returns the writer listings are printed to: the standard input
+// of $PAGER when pager is set, a rate-limited stdout otherwise. The returned
+// close function flushes the output and waits for the pager to exit.
+func openOutput(pager bool) (io.Writer, func() error, error) {
+	if !pager {
+		w := newThrottledWriter(os.Stdout, outputFlushInterval)
+		return w, w.Close, nil
+	}
+
+	pagerCmd := os.Getenv("PAGER")
+	if pagerCmd == "" {
+		pagerCmd = "less"
+		if runtime.GOOS == "windows" {
+			pagerCmd = "more"
+		}
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.Command(shell, flag, pagerCmd)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open pager input: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start pager %q: %w", pagerCmd, err)
+	}
+
+	w := bufio.NewWriterSize(stdin, outputBufferSize)
+	return w, func() error {
+		w.Flush()
+		stdin.Close()
+		return cmd.Wait()
+	}, nil
+}
+
+// printLockedSummary reports the files skipped because another process held
+// them locked. Nothing is printed when the scan met no locked file.
+func printLockedSummary(out io.Writer, locked []model.FileSystem) {
+	if len(locked) == 0 {
+		return
+	}
+	total := model.FileSystem{}
+	for _, file := range locked {
+		total.Size += file.Size
+	}
+	fmt.Fprintf(out, "Locked: %d files, %s (sizes from directory metadata)\n", len(locked), total.GetFormattedSize())
+	for _, file := range locked {
+		fmt.Fprintf(out, "  %s (%s)\n", file.FullPath, file.GetFormattedSize())
+	}
+}
+
+// printRevisitedSummary reports the directories skipped because the scan had
+// already counted them through another path, such as a symlink loop
+func printRevisitedSummary(out io.Writer, skipped []model.FileSystem) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "Already scanned: %d directories skipped\n", len(skipped))
+	for _, dir := range skipped {
+		if dir.Symlink {
+			fmt.Fprintf(out, "  %s -> %s\n", dir.FullPath, dir.LinkTarget)
+			continue
+		}
+		fmt.Fprintf(out, "  %s\n", dir.FullPath)
+	}
+}
+
+// printSkippedSummary reports the paths the scan could not read, whose content
+// is missing from the sizes. Nothing is printed when every path was read.
+func printSkippedSummary(out io.Writer, skipped []service.ScanError) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "Skipped: %d paths could not be read\n", len(skipped))
+	for _, scanErr := range skipped {
+		fmt.Fprintf(out, "  %s (%s)\n", scanErr.Path, skippedReason(scanErr.Err))
+	}
+}
+
+// skippedError returns an error wrapping service.ErrPartialFailure when the
+// scan could not read some paths, nil otherwise
+func skippedError(skipped []service.ScanError) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d paths could not be read: %w", len(skipped), service.ErrPartialFailure)
+}
+
+// partialError marks err, the failures of a command that went on with the
+// other paths, as a partial failure
+func partialError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", service.ErrPartialFailure, err)
+}
+
+func skippedReason(err error) string {
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		return "permission denied"
+	case errors.Is(err, os.ErrNotExist):
+		return "removed during the scan"
+	default:
+		return err.Error()
+	}
+}
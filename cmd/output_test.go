@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+)
+
+func TestThrottledWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newThrottledWriter(&buf, time.Hour)
+
+	w.Write([]byte("line 1\n"))
+	w.Write([]byte("line 2\n"))
+
+	if buf.Len() != 0 {
+		t.Errorf("output flushed before interval: %q", buf.String())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if buf.String() != "line 1\nline 2\n" {
+		t.Errorf("got %q after Close", buf.String())
+	}
+}
+
+func TestPrintSkippedSummary(t *testing.T) {
+	var buf bytes.Buffer
+	printSkippedSummary(&buf, nil)
+	if buf.Len() != 0 {
+		t.Errorf("summary printed without skipped paths: %q", buf.String())
+	}
+
+	printSkippedSummary(&buf, []service.ScanError{
+		{Path: "/data/private", Err: fmt.Errorf("permission denied reading directory: /data/private: %w", os.ErrPermission)},
+		{Path: "/data/tmp", Err: os.ErrNotExist},
+	})
+	want := "Skipped: 2 paths could not be read\n  /data/private (permission denied)\n  /data/tmp (removed during the scan)\n"
+	if buf.String() != want {
+		t.Errorf("summary = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintLockedSummary(t *testing.T) {
+	var buf bytes.Buffer
+	printLockedSummary(&buf, nil)
+	if buf.Len() != 0 {
+		t.Errorf("summary printed without locked files: %q", buf.String())
+	}
+
+	printLockedSummary(&buf, []model.FileSystem{
+		{Name: "pagefile.sys", FullPath: `C:\pagefile.sys`, Size: 2 * 1024 * 1024, Locked: true},
+		{Name: "mail.ost", FullPath: `C:\mail.ost`, Size: 1024 * 1024, Locked: true},
+	})
+	out := buf.String()
+	if !strings.HasPrefix(out, "Locked: 2 files, 3.00 MiB") {
+		t.Errorf("summary = %q, want count and total size", out)
+	}
+	if !strings.Contains(out, `C:\pagefile.sys (2.00 MiB)`) {
+		t.Errorf("summary = %q, want each locked file listed", out)
+	}
+}
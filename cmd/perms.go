@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// permsCmd groups the file permission commands
+var permsCmd = &cobra.Command{
+	Use:   "perms",
+	Short: "Check file permissions",
+}
+
+// permsAuditCmd flags risky permissions under a directory and optionally fixes them
+var permsAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Flag world-writable files, executables in data directories and setuid bits",
+	Long: `Walk a directory and report world-writable files and directories, executable
+files inside data directories (data, assets, static...), and setuid/setgid files.
+With --fix-mode the offending bits are cleared according to the "perms" policy of
+the config file, except on protected paths such as system directories unless
+--force-unsafe is set. Symlinks are not followed.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		fix, _ := cmd.Flags().GetBool("fix-mode")
+
+		if dir == "" {
+			var err error
+			if dir, err = os.Getwd(); err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+		}
+
+		policy, err := service.NewPermsPolicy(GlobalConfig.Perms)
+		if err != nil {
+			return err
+		}
+
+		issues, err := service.AuditPermissions(cmd.Context(), dir, policy)
+		if err != nil {
+			return err
+		}
+		GlobalUsage.Count("issues", len(issues))
+
+		var fixErr error
+		if fix {
+			guard, err := newPathGuard(cmd)
+			if err != nil {
+				return err
+			}
+			fixErr = service.FixPermissions(issues, policy, guard)
+		}
+		printPermIssues(os.Stdout, issues, fix)
+		return partialError(fixErr)
+	},
+}
+
+func printPermIssues(out io.Writer, issues []service.PermIssue, fix bool) {
+	if len(issues) == 0 {
+		fmt.Fprintln(out, "No permission issues found")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "MODE\tISSUES\tPATH"
+	if fix {
+		header = "MODE\tNEW MODE\tISSUES\tPATH"
+	}
+	fmt.Fprintln(w, header)
+
+	fixed := 0
+	for _, issue := range issues {
+		kinds := make([]string, len(issue.Kinds))
+		for i, kind := range issue.Kinds {
+			kinds[i] = string(kind)
+		}
+		if !fix {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", issue.Mode, strings.Join(kinds, ", "), issue.Path)
+			continue
+		}
+		newMode := "-"
+		if issue.Fixed {
+			newMode = issue.FixedMode.String()
+			fixed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Mode, newMode, strings.Join(kinds, ", "), issue.Path)
+	}
+	_ = w.Flush()
+
+	if fix {
+		fmt.Fprintf(out, "\n%d issues found, %d fixed\n", len(issues), fixed)
+	} else {
+		fmt.Fprintf(out, "\n%d issues found, run with --fix-mode to apply the policy\n", len(issues))
+	}
+}
+
+func init() {
+	permsAuditCmd.Flags().StringP("dir", "d", "", "directory to audit (defaults to current directory)")
+	permsAuditCmd.Flags().Bool("fix-mode", false, "clear the offending permission bits according to the perms policy")
+	addForceUnsafeFlag(permsAuditCmd)
+	permsCmd.AddCommand(permsAuditCmd)
+}
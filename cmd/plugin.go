@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// pluginsCmd lists the external subcommands found on PATH
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List the goktor-<name> plugins found on PATH",
+	Long: `Any executable named goktor-<name> on PATH runs as "goktor <name>", like git
+plugins. The arguments after the name are passed through untouched, and the
+global flags given before it reach the plugin as environment variables:
+
+  GOKTOR_BIN         the goktor executable
+  GOKTOR_CONFIG      the config file, --config or ~/.goktor/config.json
+  GOKTOR_LOG_LEVEL   debug with --verbose, error with --quiet, info otherwise
+  GOKTOR_LOG_FORMAT  text or json, from --log-format
+  GOKTOR_LOG_FILE    the --log-file path, empty for stdout
+  NO_COLOR           set with --no-color
+
+Built-in commands always win over a plugin of the same name. The exit code of
+the plugin is the exit code of goktor.`,
+	Example: `  goktor plugins
+  goktor --config ~/work.json jira-sync --project OPS   # runs goktor-jira-sync`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		plugins := service.ListPlugins()
+		if len(plugins) == 0 {
+			fmt.Fprintf(out, "No %s* executable found on PATH\n", service.PluginPrefix)
+			return nil
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPATH")
+		for _, plugin := range plugins {
+			path := plugin.Path
+			if isBuiltinCommand(plugin.Name) {
+				path += " (shadowed by the built-in command)"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", plugin.Name, path)
+		}
+		return w.Flush()
+	},
+}
+
+// pluginInvocation is a command line running a plugin
+type pluginInvocation struct {
+	plugin service.Plugin
+	// globalArgs are the global flags before the plugin name
+	globalArgs []string
+	// args are passed to the plugin
+	args []string
+}
+
+// findPluginInvocation returns the plugin run by the command line args, false
+// when they run a built-in command or name no plugin on PATH
+func findPluginInvocation(args []string) (*pluginInvocation, bool) {
+	globalArgs, name, rest := splitGlobalArgs(RootCmd.PersistentFlags(), args)
+	if name == "" || isBuiltinCommand(name) {
+		return nil, false
+	}
+	plugin, err := service.FindPlugin(name)
+	if err != nil {
+		return nil, false
+	}
+	return &pluginInvocation{plugin: plugin, globalArgs: globalArgs, args: rest}, true
+}
+
+// splitGlobalArgs splits args at the first argument that is neither a global
+// flag nor the value of one: the command name
+func splitGlobalArgs(flags *pflag.FlagSet, args []string) (globalArgs []string, name string, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args[:i], "", nil
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return args[:i], arg, args[i+1:]
+		}
+		if strings.Contains(arg, "=") {
+			continue
+		}
+		var flag *pflag.Flag
+		if long, ok := strings.CutPrefix(arg, "--"); ok {
+			flag = flags.Lookup(long)
+		} else if len(arg) == 2 {
+			flag = flags.ShorthandLookup(arg[1:])
+		}
+		if flag != nil && flag.NoOptDefVal == "" {
+			// the next argument is the value of the flag
+			i++
+		}
+	}
+	return args, "", nil
+}
+
+// isBuiltinCommand reports whether name runs a command of goktor, including
+// the help and completion commands cobra adds on execution and the deprecated
+// names
+func isBuiltinCommand(name string) bool {
+	if name == "help" || name == "completion" || strings.HasPrefix(name, "__") {
+		return true
+	}
+	for _, cmd := range RootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPlugin runs the plugin of invocation with the environment of its global
+// flags and returns the exit code of goktor
+func runPlugin(invocation *pluginInvocation) int {
+	env, err := pluginEnvironment(invocation.globalArgs)
+	if err == nil {
+		var code int
+		if code, err = service.RunPlugin(invocation.plugin, invocation.args, env); err == nil {
+			if code < 0 {
+				return ExitCancelled
+			}
+			return code
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return ExitFatal
+}
+
+// pluginEnvironment parses the global flags given before the plugin name
+func pluginEnvironment(globalArgs []string) (service.PluginEnvironment, error) {
+	flags := RootCmd.PersistentFlags()
+	if err := flags.Parse(globalArgs); err != nil {
+		return service.PluginEnvironment{}, err
+	}
+
+	env := service.PluginEnvironment{LogLevel: service.LogLevelInfo}
+	if verbose, _ := flags.GetBool("verbose"); verbose {
+		env.LogLevel = service.LogLevelDebug
+	}
+	if quiet, _ := flags.GetBool("quiet"); quiet {
+		env.LogLevel = service.LogLevelError
+	}
+	env.LogFormat, _ = flags.GetString("log-format")
+	if path, _ := flags.GetString("log-file"); path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return env, fmt.Errorf("invalid --log-file %s: %w", path, err)
+		}
+		env.LogFile = abs
+	}
+	// NO_COLOR and TERM are inherited
+	env.NoColor, _ = flags.GetBool("no-color")
+
+	configPath, _ := flags.GetString("config")
+	if configPath == "" {
+		configPath, _ = service.DefaultConfigPath()
+	} else if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	env.ConfigPath = configPath
+	if exe, err := os.Executable(); err == nil {
+		env.Executable = exe
+	}
+	return env, nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitGlobalArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantGlobal []string
+		wantName   string
+		wantRest   []string
+	}{
+		{[]string{"sync", "--dry-run", "x"}, nil, "sync", []string{"--dry-run", "x"}},
+		{[]string{"--config", "a.json", "-v", "sync", "-v"}, []string{"--config", "a.json", "-v"}, "sync", []string{"-v"}},
+		{[]string{"--log-format=json", "--notify", "sync"}, []string{"--log-format=json", "--notify"}, "sync", nil},
+		{[]string{"--verbose"}, []string{"--verbose"}, "", nil},
+		{[]string{"--", "sync"}, nil, "", nil},
+	}
+	for _, tt := range tests {
+		global, name, rest := splitGlobalArgs(RootCmd.PersistentFlags(), tt.args)
+		if !slices.Equal(global, tt.wantGlobal) || name != tt.wantName || !slices.Equal(rest, tt.wantRest) {
+			t.Errorf("splitGlobalArgs(%q) = %q, %q, %q, want %q, %q, %q",
+				tt.args, global, name, rest, tt.wantGlobal, tt.wantName, tt.wantRest)
+		}
+	}
+
+	for name, want := range map[string]bool{"du": true, "help": true, "__complete": true, "jira-sync": false} {
+		if got := isBuiltinCommand(name); got != want {
+			t.Errorf("isBuiltinCommand(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
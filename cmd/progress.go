@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// progressSpinner renders the latest scan progress on a single terminal line
+type progressSpinner struct {
+	out     io.Writer
+	latest  atomic.Pointer[service.ScanProgress]
+	done    chan struct{}
+	once    sync.Once
+	stopped sync.WaitGroup
+}
+
+// startProgress starts a spinner on stderr when it is a terminal and returns
+// the callback to register on the file service. The returned stop function
+// clears the progress line and is safe to call more than once. --quiet and
+// plain output disable the spinner.
+func startProgress(cmd *cobra.Command) (service.ProgressFunc, func()) {
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet || plainOutput || !isTerminal(os.Stderr) {
+		return nil, func() {}
+	}
+
+	s := &progressSpinner{out: os.Stderr, done: make(chan struct{})}
+	s.stopped.Add(1)
+	go s.run()
+
+	return func(p service.ScanProgress) { s.latest.Store(&p) }, s.stop
+}
+
+func (s *progressSpinner) run() {
+	defer s.stopped.Done()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		select {
+		case <-s.done:
+			fmt.Fprint(s.out, "\r\033[K")
+			return
+		case <-ticker.C:
+			p := s.latest.Load()
+			if p == nil {
+				continue
+			}
+			size := model.FileSystem{Size: p.Bytes}
+			fmt.Fprintf(s.out, "\r\033[K%s %d entries, %s  %s",
+				spinnerFrames[frame%len(spinnerFrames)], p.Entries, size.GetFormattedSize(), truncatePath(p.CurrentPath, 60))
+		}
+	}
+}
+
+func (s *progressSpinner) stop() {
+	s.once.Do(func() {
+		close(s.done)
+		s.stopped.Wait()
+	})
+}
+
+func truncatePath(path string, max int) string {
+	if len(path) <= max {
+		return path
+	}
+	return "..." + path[len(path)-max+3:]
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
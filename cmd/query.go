@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// queryCmd filters the scans saved by scan
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Filter the files and directories of a saved scan",
+	Long: `Query the SQLite index written by scan without walking the filesystem again.
+Entries of the latest scan (or of --scan) are filtered by size, extension,
+modification time and path glob, and listed largest first. Directories are
+listed with their cumulative size. --scans lists the saved scans instead.
+
+In --path, * and ? also match the path separator: "*/node_modules" matches every
+node_modules directory.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := openScanIndex(cmd)
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		if listScans, _ := cmd.Flags().GetBool("scans"); listScans {
+			scans, err := index.Scans(cmd.Context())
+			if err != nil {
+				return err
+			}
+			printIndexedScans(os.Stdout, scans)
+			return nil
+		}
+
+		query, err := indexQueryFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		entries, err := index.Query(cmd.Context(), query)
+		if err != nil {
+			return err
+		}
+		GlobalUsage.Count("entries", len(entries))
+		printIndexedEntries(os.Stdout, entries)
+		return nil
+	},
+}
+
+// indexQueryFromFlags builds the query of the filter flags
+func indexQueryFromFlags(cmd *cobra.Command) (service.IndexQuery, error) {
+	query := service.IndexQuery{Now: time.Now()}
+	query.ScanID, _ = cmd.Flags().GetInt64("scan")
+	query.Extensions, _ = cmd.Flags().GetStringSlice("ext")
+	query.PathGlob, _ = cmd.Flags().GetString("path")
+	query.DirsOnly, _ = cmd.Flags().GetBool("dirs")
+	query.FilesOnly, _ = cmd.Flags().GetBool("files")
+	query.Limit, _ = cmd.Flags().GetInt("limit")
+
+	var err error
+	if value, _ := cmd.Flags().GetString("min-size"); value != "" {
+		if query.MinSize, err = model.ParseSize(value); err != nil {
+			return query, fmt.Errorf("invalid --min-size: %w", err)
+		}
+	}
+	if value, _ := cmd.Flags().GetString("max-size"); value != "" {
+		if query.MaxSize, err = model.ParseSize(value); err != nil {
+			return query, fmt.Errorf("invalid --max-size: %w", err)
+		}
+	}
+	query.Age, err = ageFilterFromFlags(cmd)
+	return query, err
+}
+
+func printIndexedEntries(out io.Writer, entries []model.FileSystem) {
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No matching entries")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SIZE\tMODIFIED\tPATH")
+	for _, entry := range entries {
+		modified := "-"
+		if !entry.ModTime.IsZero() {
+			modified = entry.ModTime.Format(time.DateOnly)
+		}
+		path := entry.FullPath
+		if entry.IsDir {
+			path += string(os.PathSeparator)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", entry.GetFormattedSize(), modified, path)
+	}
+	_ = w.Flush()
+}
+
+func printIndexedScans(out io.Writer, scans []service.IndexedScan) {
+	if len(scans) == 0 {
+		fmt.Fprintln(out, "No saved scans, run goktor scan first")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCAN\tSCANNED AT\tENTRIES\tROOT")
+	for _, scan := range scans {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", scan.ID, scan.ScannedAt.Format("2006-01-02 15:04"), scan.Entries, scan.Root)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	queryCmd.Flags().String("db", "", "SQLite database written by scan (defaults to ~/.goktor/index.db)")
+	queryCmd.Flags().Int64("scan", 0, "id of the scan to query, the latest one by default")
+	queryCmd.Flags().Bool("scans", false, "list the saved scans")
+	queryCmd.Flags().String("min-size", "", "only list entries of at least this size, e.g. 500MB")
+	queryCmd.Flags().String("max-size", "", "only list entries of at most this size")
+	queryCmd.Flags().StringSlice("ext", nil, "only list files with these extensions, e.g. log,tmp")
+	queryCmd.Flags().String("older-than", "", "only list entries last modified more than this long ago (e.g. 90d, 2w, 1y)")
+	queryCmd.Flags().String("newer-than", "", "only list entries modified within this duration (e.g. 7d, 36h)")
+	queryCmd.Flags().String("path", "", "only list entries whose full path matches this glob")
+	queryCmd.Flags().Bool("dirs", false, "only list directories")
+	queryCmd.Flags().Bool("files", false, "only list files")
+	queryCmd.Flags().Int("limit", 50, "maximum number of entries listed, 0 for all")
+	queryCmd.MarkFlagsMutuallyExclusive("dirs", "files")
+	queryCmd.MarkFlagsMutuallyExclusive("dirs", "ext")
+}
@@ -6,44 +6,297 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"slices"
+	"time"
 
 	"github.com/nanaki-93/goktor/cmd/mr_repo"
+	"github.com/nanaki-93/goktor/model"
 	"github.com/nanaki-93/goktor/service"
 	"github.com/spf13/cobra"
 )
 
 var GlobalLogger service.Logger
 
+// GlobalConfig is the user configuration loaded before every command
+var GlobalConfig = &service.Config{}
+
+// GlobalUsage collects result counts for the opt-in usage log
+var GlobalUsage = service.NewUsageTracker()
+
+// usageLogEnv enables the local usage log when set to a non-empty value
+const usageLogEnv = "GOKTOR_USAGE_LOG"
+
+// Exit codes of goktor, for scripts and CI
+const (
+	// ExitOK is returned when the command succeeded
+	ExitOK = 0
+	// ExitFatal is returned when the command failed
+	ExitFatal = 1
+	// ExitPartial is returned when a batch command ran to completion but
+	// failed on some repositories or paths
+	ExitPartial = 2
+	// ExitCancelled is returned when the command was interrupted
+	ExitCancelled = 3
+)
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "goktor",
 	Short: "A CLI tool for managing directories and repositories",
 	Long: `Goktor is a command-line utility for analyzing directory structures,
 listing files and their sizes, and managing multiple git repositories.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		debug, _ := cmd.Flags().GetBool("verbose")
-		GlobalLogger = service.NewLogger(debug)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		logger, err := newGlobalLogger(cmd)
+		if err != nil {
+			return err
+		}
+		GlobalLogger = logger
+		plainOutput = noColor(cmd)
+		model.SetSizeUnits(sizeUnits(cmd))
+
+		config, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		GlobalConfig = config
+
 		mr_repo.SetLogger(GlobalLogger)
+		mr_repo.SetConfig(GlobalConfig)
+		mr_repo.SetUsageTracker(GlobalUsage)
+		return nil
 	},
 }
 
+// logFile is the --log-file destination, closed once the command returns
+var logFile *os.File
+
+// plainOutput is set by --no-color, NO_COLOR or a dumb terminal: the output
+// is plain ASCII text, without the escape sequences of the progress spinner
+// and of the screen refresh of folder-list --watch
+var plainOutput bool
+
+// noColor reports whether the output must stay plain ASCII text
+func noColor(cmd *cobra.Command) bool {
+	if flag, _ := cmd.Flags().GetBool("no-color"); flag {
+		return true
+	}
+	return os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+}
+
+// sizeUnits returns the units of the printed and parsed sizes: powers of
+// 1000 with --si, of 1024 otherwise
+func sizeUnits(cmd *cobra.Command) model.SizeUnits {
+	if si, _ := cmd.Flags().GetBool("si"); si {
+		return model.SI
+	}
+	return model.IEC
+}
+
+// newGlobalLogger builds the logger from the --verbose, --quiet, --log-format and --log-file flags
+func newGlobalLogger(cmd *cobra.Command) (service.Logger, error) {
+	debug, _ := cmd.Flags().GetBool("verbose")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	format, _ := cmd.Flags().GetString("log-format")
+	path, _ := cmd.Flags().GetString("log-file")
+
+	opts := service.LoggerOptions{Debug: debug, Quiet: quiet, Format: format}
+	if path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		logFile = file
+		opts.Output = file
+	} else if output, _ := cmd.Flags().GetString("output"); output == mr_repo.OutputJSON {
+		// stdout carries the JSON report
+		opts.Output = os.Stderr
+	}
+
+	return service.NewLoggerWithOptions(opts)
+}
+
+// loadConfig reads the --config file, ~/.goktor/config.json by default
+func loadConfig(cmd *cobra.Command) (*service.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		var err error
+		if path, err = service.DefaultConfigPath(); err != nil {
+			return &service.Config{}, nil
+		}
+	}
+	return service.LoadConfig(path)
+}
+
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
-		GlobalLogger.Error("Failed to execute command: \n", err, "\n")
+	// plugins handle Ctrl+C themselves, so they run before goktor catches it
+	if invocation, ok := findPluginInvocation(os.Args[1:]); ok {
+		os.Exit(runPlugin(invocation))
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	start := time.Now()
+	executed, err := RootCmd.ExecuteContextC(ctx)
+	recordUsage(executed, start, err)
+	notifyFinished(ctx, executed, start, err)
+
+	code := exitCode(ctx, err)
+	if code != ExitOK && code != ExitCancelled && GlobalLogger != nil {
+		GlobalLogger.Error("Failed to execute command", "error", err)
+	}
+	if logFile != nil {
+		_ = logFile.Close()
+	}
+	if code != ExitOK && code != ExitCancelled {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
 	}
+	if code != ExitOK {
+		os.Exit(code)
+	}
+}
+
+// exitCode maps the outcome of a command to the exit code contract: an
+// interrupted command is cancelled whatever it returned, and the errors
+// wrapping service.ErrPartialFailure are partial failures
+func exitCode(ctx context.Context, err error) int {
+	switch {
+	case ctx.Err() != nil || errors.Is(err, context.Canceled):
+		return ExitCancelled
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, service.ErrPartialFailure):
+		return ExitPartial
+	default:
+		return ExitFatal
+	}
+}
+
+// interruptContext returns a context cancelled by the first Ctrl+C, letting
+// batch commands stop at a safe point and report what they did. A second
+// Ctrl+C exits immediately.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			return
+		}
+		fmt.Fprintln(os.Stderr, "\nInterrupted: stopping after the current operation, press Ctrl+C again to abort")
+		cancel()
+		<-signals
+		os.Exit(ExitCancelled)
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// recordUsage appends the executed command to the local usage log when the
+// user opted in through GOKTOR_USAGE_LOG. Nothing is ever sent over the network.
+func recordUsage(executed *cobra.Command, start time.Time, err error) {
+	if os.Getenv(usageLogEnv) == "" || executed == nil || executed == usageCmd {
+		return
+	}
+	path, pathErr := service.DefaultUsageLogPath()
+	if pathErr != nil {
+		return
+	}
+	record := service.UsageRecord{
+		Time:     start,
+		Command:  executed.CommandPath(),
+		Duration: time.Since(start),
+		Success:  err == nil,
+		Counts:   GlobalUsage.Counts(),
+	}
+	if writeErr := service.AppendUsage(path, record); writeErr != nil && GlobalLogger != nil {
+		GlobalLogger.Debug("failed to record usage", "error", writeErr)
+	}
+}
+
+// notifyFinished shows a desktop notification with the outcome of the
+// executed command when --notify is set. Interrupted commands are not
+// notified, the user being at the terminal.
+func notifyFinished(ctx context.Context, executed *cobra.Command, start time.Time, err error) {
+	if executed == nil || ctx.Err() != nil {
+		return
+	}
+	if notify, _ := executed.Flags().GetBool("notify"); !notify {
+		return
+	}
+	if notifyErr := service.Notify(context.Background(), "goktor", notificationMessage(executed.CommandPath(), time.Since(start), GlobalUsage.Counts(), err)); notifyErr != nil && GlobalLogger != nil {
+		GlobalLogger.Warn("failed to send the desktop notification", "error", notifyErr)
+	}
+}
+
+// notificationMessage summarizes a finished command: its outcome, duration
+// and result counts
+func notificationMessage(command string, elapsed time.Duration, counts map[string]int, err error) string {
+	elapsed = elapsed.Round(time.Second)
+	if err != nil {
+		return fmt.Sprintf("%s failed after %s: %v", command, elapsed, err)
+	}
+	message := fmt.Sprintf("%s finished in %s", command, elapsed)
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for i, key := range keys {
+		sep := ", "
+		if i == 0 {
+			sep = ": "
+		}
+		message += fmt.Sprintf("%s%d %s", sep, counts[key], key)
+	}
+	return message
 }
 
 func init() {
 	RootCmd.PersistentFlags().BoolP("verbose", "v", false, "enable verbose output")
+	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "only log errors, and hide the progress spinner")
+	RootCmd.PersistentFlags().Bool("no-color", false, "plain ASCII output without terminal escape sequences (also set by NO_COLOR)")
+	RootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	RootCmd.PersistentFlags().Bool("si", false, "print and parse sizes in powers of 1000 (kB, MB, GB) instead of 1024 (KiB, MiB, GiB)")
+	RootCmd.PersistentFlags().String("config", "", "config file (defaults to ~/.goktor/config.json)")
+	RootCmd.PersistentFlags().String("log-file", "", "append log entries to this file instead of stdout")
+	RootCmd.PersistentFlags().String("log-format", service.LogFormatText, "log entry format: text or json")
+	RootCmd.PersistentFlags().Bool("notify", false, "show a desktop notification when the command finishes, for long scans and batch git operations")
 	RootCmd.CompletionOptions.DisableDefaultCmd = false
+	// the mr-repo --output hook runs after the root one loading the logger and config
+	cobra.EnableTraverseRunHooks = true
 
 	// Add subcommands here
 	RootCmd.AddCommand(fileListCmd)
 	RootCmd.AddCommand(folderListCmd)
+	RootCmd.AddCommand(duCmd)
+	RootCmd.AddCommand(scanCmd)
+	RootCmd.AddCommand(queryCmd)
+	RootCmd.AddCommand(findCmd)
+	RootCmd.AddCommand(hashCmd)
+	RootCmd.AddCommand(fileStatsCmd)
 	RootCmd.AddCommand(mr_repo.MrRepoCmd)
 	RootCmd.AddCommand(diffCmd)
+	RootCmd.AddCommand(usageCmd)
+	RootCmd.AddCommand(dashboardCmd)
+	RootCmd.AddCommand(permsCmd)
+	RootCmd.AddCommand(devCleanCmd)
+	RootCmd.AddCommand(cleanCmd)
+	RootCmd.AddCommand(dockerUsageCmd)
+	RootCmd.AddCommand(goCacheCmd)
+	RootCmd.AddCommand(daemonCmd)
+	RootCmd.AddCommand(pluginsCmd)
+	RootCmd.AddCommand(authCmd)
+	addDeprecatedCommands(RootCmd)
 }
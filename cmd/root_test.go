@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+func TestNotificationMessage(t *testing.T) {
+	got := notificationMessage("goktor mr-repo fetch", 95*time.Second+300*time.Millisecond, map[string]int{"fetched": 12, "failed": 1}, nil)
+	if want := "goktor mr-repo fetch finished in 1m35s: 1 failed, 12 fetched"; got != want {
+		t.Errorf("notificationMessage() = %q, want %q", got, want)
+	}
+	got = notificationMessage("goktor du", 2*time.Second, nil, errors.New("permission denied"))
+	if want := "goktor du failed after 2s: permission denied"; got != want {
+		t.Errorf("notificationMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want int
+	}{
+		{"success", context.Background(), nil, ExitOK},
+		{"fatal", context.Background(), errors.New("invalid --depth"), ExitFatal},
+		{"partial", context.Background(), fmt.Errorf("2 of 5 repositories failed: %w", service.ErrPartialFailure), ExitPartial},
+		{"interrupted", cancelled, nil, ExitCancelled},
+		{"interrupted with failures", cancelled, service.ErrPartialFailure, ExitCancelled},
+		{"cancelled operation", context.Background(), fmt.Errorf("scan: %w", context.Canceled), ExitCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCommandNamesUnique guards the single registration of every command: no
+// two commands of a parent share a name or alias
+func TestCommandNamesUnique(t *testing.T) {
+	var walk func(parent *cobra.Command)
+	walk = func(parent *cobra.Command) {
+		seen := make(map[string]string)
+		for _, child := range parent.Commands() {
+			for _, name := range append([]string{child.Name()}, child.Aliases...) {
+				if other, ok := seen[name]; ok {
+					t.Errorf("%s: %q is registered by both %s and %s", parent.CommandPath(), name, other, child.Name())
+				}
+				seen[name] = child.Name()
+			}
+			walk(child)
+		}
+	}
+	walk(RootCmd)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// scanCmd saves a scan of a directory to the SQLite index
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Save every file and directory of a scan to a SQLite index",
+	Long: `Scan a directory and save every file and directory with its size, extension and
+modification time to a SQLite database (~/.goktor/index.db unless --db is set).
+Each run adds a new scan; query filters the saved scans without walking the
+filesystem again.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+		onDisk, _ := cmd.Flags().GetBool("on-disk")
+
+		if dir == "" {
+			var err error
+			if dir, err = os.Getwd(); err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+		}
+		index, err := openScanIndex(cmd)
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		storage, err := scanStorage(cmd)
+		if err != nil {
+			return err
+		}
+		workers, err := scanWorkers(cmd)
+		if err != nil {
+			return err
+		}
+		fs := service.NewServiceWithLogger(GlobalLogger)
+		fs.SetStorage(storage)
+		fs.SetWorkers(workers)
+		fs.SetFollowSymlinks(followSymlinks)
+		fs.SetAllocatedSize(onDisk)
+		progress, stopProgress := startProgress(cmd)
+		fs.SetProgress(progress)
+		defer stopProgress()
+
+		scannedAt := time.Now()
+		root, err := fs.ListDirectories(cmd.Context(), dir)
+		if err != nil {
+			return fmt.Errorf("failed to list directories: %w", err)
+		}
+		stopProgress()
+
+		scanID, err := index.SaveScan(cmd.Context(), root, scannedAt)
+		if err != nil {
+			return err
+		}
+		GlobalUsage.Count("files", int(root.FileCount))
+		fmt.Printf("Saved scan %d of %s: %d files in %d directories\n", scanID, root.FullPath, root.FileCount, root.DirCount+1)
+		printSkippedSummary(os.Stdout, fs.SkippedPaths())
+		return skippedError(fs.SkippedPaths())
+	},
+}
+
+// openScanIndex opens the index of --db, ~/.goktor/index.db by default
+func openScanIndex(cmd *cobra.Command) (*service.ScanIndex, error) {
+	path, _ := cmd.Flags().GetString("db")
+	if path == "" {
+		var err error
+		if path, err = service.DefaultScanIndexPath(); err != nil {
+			return nil, err
+		}
+	}
+	return service.OpenScanIndex(path)
+}
+
+func init() {
+	scanCmd.Flags().StringP("dir", "d", "", "directory to scan (defaults to current directory)")
+	scanCmd.Flags().String("db", "", "SQLite database the scan is saved to (defaults to ~/.goktor/index.db)")
+	scanCmd.Flags().Bool("follow-symlinks", false, "scan the directories symlinks point to; each directory is still counted once")
+	addOnDiskFlag(scanCmd)
+	scanCmd.Flags().String("storage", "", "storage type used to tune scan concurrency: auto, ssd, hdd or network (defaults to scan.storage in the config)")
+	scanCmd.Flags().Int("workers", 0, "number of directories read at a time, overriding the --storage tuning (defaults to scan.workers in the config)")
+}
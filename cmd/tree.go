@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nanaki-93/goktor/model"
+	"github.com/nanaki-93/goktor/service"
+)
+
+// treeBranches are the box-drawing prefixes of printDirectoryTree, and
+// asciiTreeBranches their plain ASCII counterparts for --no-color
+var (
+	treeBranches      = [4]string{"├── ", "└── ", "│   ", "    "}
+	asciiTreeBranches = [4]string{"|-- ", "`-- ", "|   ", "    "}
+)
+
+// printDirectoryTree prints usage as a tree of directories with their
+// cumulative size, and with onDisk the space they take on disk, each level
+// sorted by size. Directories smaller than limit are left out, except the root.
+func printDirectoryTree(out io.Writer, usage service.DiskUsage, limit int64, ascii bool, onDisk bool) {
+	branches := treeBranches
+	if ascii {
+		branches = asciiTreeBranches
+	}
+	fmt.Fprintf(out, "%s (%s)\n", usage.Path, treeSizes(usage, onDisk))
+	printTreeLevel(out, usage.SubDirs, "", limit, branches, onDisk)
+}
+
+// treeSizes formats the sizes and file count of dir
+func treeSizes(dir service.DiskUsage, onDisk bool) string {
+	size := model.FileSystem{Size: dir.Size}
+	if !onDisk {
+		return size.GetFormattedSize() + ", " + fileCount(dir.Files)
+	}
+	allocated := model.FileSystem{Size: dir.Allocated}
+	return size.GetFormattedSize() + ", " + allocated.GetFormattedSize() + " on disk, " + fileCount(dir.Files)
+}
+
+func printTreeLevel(out io.Writer, dirs []service.DiskUsage, prefix string, limit int64, branches [4]string, onDisk bool) {
+	var shown []service.DiskUsage
+	for _, dir := range dirs {
+		if dir.Size >= limit {
+			shown = append(shown, dir)
+		}
+	}
+	for i, dir := range shown {
+		branch, indent := branches[0], branches[2]
+		if i == len(shown)-1 {
+			branch, indent = branches[1], branches[3]
+		}
+		fmt.Fprintf(out, "%s%s%s (%s)%s\n", prefix, branch, dir.Name, treeSizes(dir, onDisk), depthLimitedMark(dir))
+		printTreeLevel(out, dir.SubDirs, prefix+indent, limit, branches, onDisk)
+	}
+}
+
+// depthLimitedMark flags the directories whose content below was not scanned
+func depthLimitedMark(dir service.DiskUsage) string {
+	if dir.DepthLimited {
+		return " [not scanned deeper]"
+	}
+	return ""
+}
+
+// fileCount formats a number of files
+func fileCount(files int64) string {
+	if files == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", files)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nanaki-93/goktor/service"
+)
+
+func TestPrintDirectoryTree(t *testing.T) {
+	usage := service.DiskUsage{Path: "/root", Size: 3000, Files: 12, SubDirs: []service.DiskUsage{
+		{Name: "big", Size: 2000, Files: 9, SubDirs: []service.DiskUsage{
+			{Name: "a", Size: 1500, Files: 1},
+			{Name: "tiny", Size: 10},
+		}},
+		{Name: "small", Size: 1000, SubDirs: []service.DiskUsage{{Name: "b", Size: 1000}}},
+	}}
+
+	var buf bytes.Buffer
+	printDirectoryTree(&buf, usage, 100, false, false)
+	want := `/root (2.93 KiB, 12 files)
+├── big (1.95 KiB, 9 files)
+│   └── a (1.46 KiB, 1 file)
+└── small (1000 bytes, 0 files)
+    └── b (1000 bytes, 0 files)
+`
+	if buf.String() != want {
+		t.Errorf("tree =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printDirectoryTree(&buf, usage, 1500, true, false)
+	want = "/root (2.93 KiB, 12 files)\n`-- big (1.95 KiB, 9 files)\n    `-- a (1.46 KiB, 1 file)\n"
+	if buf.String() != want {
+		t.Errorf("ascii tree =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nanaki-93/goktor/service"
+	"github.com/spf13/cobra"
+)
+
+// usageCmd summarizes the local usage log
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Summarize the local usage log",
+	Long: `Summarize the commands recorded in ~/.goktor/usage.log: how often each one ran,
+how often it failed, how long it took and the result counts it reported.
+Recording is opt-in: set GOKTOR_USAGE_LOG=1 to enable it. Nothing is sent over the network.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := service.DefaultUsageLogPath()
+		if err != nil {
+			return err
+		}
+
+		records, err := service.ReadUsage(path)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			fmt.Printf("No usage recorded in %s. Set %s=1 to enable recording.\n", path, usageLogEnv)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COMMAND\tRUNS\tFAILED\tTOTAL\tAVERAGE\tRESULTS")
+		for _, summary := range service.SummarizeUsage(records) {
+			average := summary.TotalDuration / time.Duration(summary.Runs)
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n",
+				summary.Command, summary.Runs, summary.Failures,
+				summary.TotalDuration.Round(time.Millisecond), average.Round(time.Millisecond),
+				formatCounts(summary.Counts))
+		}
+		return w.Flush()
+	},
+}
+
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", key, counts[key]))
+	}
+	return strings.Join(parts, " ")
+}
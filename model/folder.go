@@ -1,31 +1,78 @@
 package model
 
-import "fmt"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
 
 type FileSystem struct {
 	Name     string
 	FullPath string
 	Size     int64
 	IsDir    bool
+	// Allocated is the space taken on disk, which differs from Size for
+	// sparse and compressed files; zero when it was not measured
+	Allocated int64
+	// Locked is set when the file was held open by another process; its size
+	// then comes from the directory metadata
+	Locked bool
+	// Extension is the lower-case file extension without the dot, empty for
+	// directories and files without one
+	Extension string
+	// ModTime is the last modification time, zero when unknown
+	ModTime time.Time
+	// Mode holds the type and permission bits
+	Mode os.FileMode
+	// Owner is the numeric UID of the owner on Unix and its SID on Windows,
+	// and Group the numeric GID on Unix; both are empty unless the scan read them
+	Owner string
+	Group string
+	// Symlink is set for symbolic links. Unless the scan follows them, links
+	// are listed as files with the size of the link itself.
+	Symlink bool
+	// LinkTarget is the target of a symbolic link, as stored in the link
+	LinkTarget string
 }
 
-func (f *FileSystem) GetFormattedSize() string {
-	switch {
-	case f.Size < 1024:
-		return fmt.Sprintf("%d bytes", f.Size)
-	case f.Size < 1024*1024:
-		return fmt.Sprintf("%.2f KB", float64(f.Size)/1024)
-	case f.Size < 1024*1024*1024:
-		return fmt.Sprintf("%.2f MB", float64(f.Size)/(1024*1024))
-	default:
-		return fmt.Sprintf("%.2f GB", float64(f.Size)/(1024*1024*1024))
+// FileExtension returns the lower-case extension of name without the dot.
+// Dot files such as ".gitignore" have no extension.
+func FileExtension(name string) string {
+	ext := filepath.Ext(name)
+	if ext == "" || ext == name {
+		return ""
 	}
+	return strings.ToLower(ext[1:])
+}
+
+// GetFormattedSize returns the size of f formatted by FormatSize
+func (f *FileSystem) GetFormattedSize() string {
+	return FormatSize(f.Size)
 }
 
 type Directory struct {
 	FileSystem
 	SubDirs []Directory
 	Files   []FileSystem
+	// FileCount and DirCount are the number of files and of directories in
+	// the whole tree below the directory
+	FileCount int64
+	DirCount  int64
+	// DepthLimited is set when the scan stopped at its maximum depth before
+	// the subdirectories of the directory: their content is missing from the
+	// sizes and counts
+	DepthLimited bool
+}
+
+// UpdateCounts sets FileCount and DirCount from the files and subdirectories
+// of d, whose own counts must already be set
+func (d *Directory) UpdateCounts() {
+	d.FileCount, d.DirCount = int64(len(d.Files)), int64(len(d.SubDirs))
+	for _, subDir := range d.SubDirs {
+		d.FileCount += subDir.FileCount
+		d.DirCount += subDir.DirCount
+	}
 }
 
 func (d *Directory) FlattenDirectory() []Directory {
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Binary (IEC) size multiples
+const (
+	KiB int64 = 1 << (10 * (iota + 1))
+	MiB
+	GiB
+	TiB
+)
+
+// Decimal (SI) size multiples
+const (
+	KB int64 = 1000
+	MB       = 1000 * KB
+	GB       = 1000 * MB
+	TB       = 1000 * GB
+)
+
+// SizeUnits selects the multiples sizes are printed and parsed with
+type SizeUnits int32
+
+const (
+	// IEC sizes are powers of 1024, printed as KiB, MiB, GiB and TiB
+	IEC SizeUnits = iota
+	// SI sizes are powers of 1000, printed as kB, MB, GB and TB
+	SI
+)
+
+var sizeUnits atomic.Int32
+
+// SetSizeUnits selects the units of FormatSize and of the unsuffixed
+// multiples accepted by ParseSize, IEC until it is called
+func SetSizeUnits(units SizeUnits) {
+	sizeUnits.Store(int32(units))
+}
+
+// CurrentSizeUnits returns the units selected by SetSizeUnits
+func CurrentSizeUnits() SizeUnits {
+	return SizeUnits(sizeUnits.Load())
+}
+
+// multiples returns the size of a kilo, mega, giga and tera of units, and
+// their symbols
+func (u SizeUnits) multiples() ([4]int64, [4]string) {
+	if u == SI {
+		return [4]int64{KB, MB, GB, TB}, [4]string{"kB", "MB", "GB", "TB"}
+	}
+	return [4]int64{KiB, MiB, GiB, TiB}, [4]string{"KiB", "MiB", "GiB", "TiB"}
+}
+
+// FormatSize returns size with two decimals in the largest unit it reaches,
+// or as a number of bytes below a kilo
+func FormatSize(size int64) string {
+	multiples, symbols := CurrentSizeUnits().multiples()
+	if size < multiples[0] {
+		return fmt.Sprintf("%d bytes", size)
+	}
+	i := len(multiples) - 1
+	for size < multiples[i] {
+		i--
+	}
+	return fmt.Sprintf("%.2f %s", float64(size)/float64(multiples[i]), symbols[i])
+}
+
+// ParseSize parses a human-readable size such as "500MB", "2GB", "1.5 GiB" or
+// a plain number of bytes. Units are case-insensitive. The IEC ones (KiB,
+// MiB...) are always powers of 1024, while K, KB, M, MB... follow the units
+// selected by SetSizeUnits: powers of 1024 by default, of 1000 with SI.
+func ParseSize(value string) (int64, error) {
+	trimmed := strings.TrimSpace(value)
+	end := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end < 0 {
+		end = len(trimmed)
+	}
+
+	number, err := strconv.ParseFloat(trimmed[:end], 64)
+	unit, ok := parseSizeUnit(strings.ToLower(strings.TrimSpace(trimmed[end:])))
+	if err != nil || !ok || number < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected e.g. 500MB, 2GB or 1048576", value)
+	}
+	return int64(number * float64(unit)), nil
+}
+
+// parseSizeUnit returns the multiplier of the lower-case unit suffix
+func parseSizeUnit(unit string) (int64, bool) {
+	if unit == "" || unit == "b" {
+		return 1, true
+	}
+	iec, _ := IEC.multiples()
+	multiples, _ := CurrentSizeUnits().multiples()
+	for i, prefix := range []string{"k", "m", "g", "t"} {
+		switch unit {
+		case prefix, prefix + "b":
+			return multiples[i], true
+		case prefix + "ib":
+			return iec[i], true
+		}
+	}
+	return 0, false
+}
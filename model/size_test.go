@@ -0,0 +1,68 @@
+package model
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		units   SizeUnits
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", IEC, 1048576, false},
+		{"512B", IEC, 512, false},
+		{"500MB", IEC, 500 * MiB, false},
+		{"2GB", IEC, 2 * GiB, false},
+		{"2gb", IEC, 2 * GiB, false},
+		{"1.5 GiB", IEC, GiB + GiB/2, false},
+		{"10k", IEC, 10 * KiB, false},
+		{"1TB", IEC, TiB, false},
+		{"500MB", SI, 500 * MB, false},
+		{"10k", SI, 10 * KB, false},
+		{"1.5 GiB", SI, GiB + GiB/2, false},
+		{"", IEC, 0, true},
+		{"GB", IEC, 0, true},
+		{"-1GB", IEC, 0, true},
+		{"2 furlongs", IEC, 0, true},
+		{"1.2.3MB", IEC, 0, true},
+	}
+	t.Cleanup(func() { SetSizeUnits(IEC) })
+	for _, tt := range tests {
+		SetSizeUnits(tt.units)
+		got, err := ParseSize(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size  int64
+		units SizeUnits
+		want  string
+	}{
+		{0, IEC, "0 bytes"},
+		{1023, IEC, "1023 bytes"},
+		{1024, IEC, "1.00 KiB"},
+		{1536 * KiB, IEC, "1.50 MiB"},
+		{3 * GiB, IEC, "3.00 GiB"},
+		{2048 * TiB, IEC, "2048.00 TiB"},
+		{999, SI, "999 bytes"},
+		{1000, SI, "1.00 kB"},
+		{1024, SI, "1.02 kB"},
+		{1500 * MB, SI, "1.50 GB"},
+		{2 * TB, SI, "2.00 TB"},
+	}
+	t.Cleanup(func() { SetSizeUnits(IEC) })
+	for _, tt := range tests {
+		SetSizeUnits(tt.units)
+		if got := FormatSize(tt.size); got != tt.want {
+			t.Errorf("FormatSize(%d) with units %d = %q, want %q", tt.size, tt.units, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// ageUnits are the day-based units accepted by ParseAge on top of time.ParseDuration
+var ageUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// ParseAge parses an age such as "90d", "2w", "1y" or any time.ParseDuration
+// value ("36h", "1h30m")
+func ParseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+	if unit, ok := ageUnits[value[len(value)-1:]]; ok {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q, expected e.g. 90d, 2w, 1y or 36h", value)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q, expected e.g. 90d, 2w, 1y or 36h", value)
+	}
+	return age, nil
+}
+
+// AgeFilter selects files by modification time. A zero bound is not applied;
+// when a bound is set, files with an unknown modification time never match.
+type AgeFilter struct {
+	// OlderThan keeps files last modified more than this long ago
+	OlderThan time.Duration
+	// NewerThan keeps files modified within this duration
+	NewerThan time.Duration
+}
+
+// IsZero reports whether the filter lets every file through
+func (f AgeFilter) IsZero() bool {
+	return f.OlderThan == 0 && f.NewerThan == 0
+}
+
+// Match reports whether file passes the filter at now
+func (f AgeFilter) Match(file model.FileSystem, now time.Time) bool {
+	if f.IsZero() {
+		return true
+	}
+	if file.ModTime.IsZero() {
+		return false
+	}
+	age := now.Sub(file.ModTime)
+	if f.OlderThan > 0 && age <= f.OlderThan {
+		return false
+	}
+	if f.NewerThan > 0 && age > f.NewerThan {
+		return false
+	}
+	return true
+}
+
+// FilterByAge returns the files matching the filter at now
+func FilterByAge(files []model.FileSystem, filter AgeFilter, now time.Time) []model.FileSystem {
+	if filter.IsZero() {
+		return files
+	}
+	var filtered []model.FileSystem
+	for _, file := range files {
+		if filter.Match(file, now) {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90d", 90 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"1y", 365 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{" 1h30m ", 90 * time.Minute, false},
+		{"", 0, true},
+		{"d", 0, true},
+		{"-3d", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAge(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAge(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFilterByAge(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	files := []model.FileSystem{
+		{Name: "fresh", ModTime: now.Add(-time.Hour)},
+		{Name: "month", ModTime: now.Add(-30 * 24 * time.Hour)},
+		{Name: "ancient", ModTime: now.Add(-400 * 24 * time.Hour)},
+		{Name: "unknown"},
+	}
+	names := func(files []model.FileSystem) []string {
+		var result []string
+		for _, file := range files {
+			result = append(result, file.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		filter AgeFilter
+		want   []string
+	}{
+		{AgeFilter{}, []string{"fresh", "month", "ancient", "unknown"}},
+		{AgeFilter{OlderThan: 90 * 24 * time.Hour}, []string{"ancient"}},
+		{AgeFilter{NewerThan: 7 * 24 * time.Hour}, []string{"fresh"}},
+		{AgeFilter{OlderThan: 7 * 24 * time.Hour, NewerThan: 90 * 24 * time.Hour}, []string{"month"}},
+	}
+	for _, tt := range tests {
+		got := names(FilterByAge(files, tt.filter, now))
+		if len(got) != len(tt.want) {
+			t.Errorf("FilterByAge(%+v) = %v, want %v", tt.filter, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("FilterByAge(%+v) = %v, want %v", tt.filter, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package service
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocatedSize returns the space the file at path takes on disk, from the
+// st_blocks of its stat, in 512-byte units whatever the block size
+func allocatedSize(_ string, info os.FileInfo) (int64, error) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(stat.Blocks) * 512, nil
+	}
+	return info.Size(), nil
+}
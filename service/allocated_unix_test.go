@@ -0,0 +1,38 @@
+//go:build !windows
+
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSystemService_AllocatedSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	sparse, err := os.Create(filepath.Join(tmpDir, "sparse.img"))
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := sparse.Truncate(10 << 20); err != nil {
+		t.Fatalf("failed to extend file: %v", err)
+	}
+	sparse.Close()
+
+	service := NewFileService()
+	service.SetAllocatedSize(true)
+	result, err := service.ListDirectories(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Size != 10<<20 {
+		t.Errorf("size = %d, want the apparent size of the file", result.Size)
+	}
+	if result.Allocated >= result.Size {
+		t.Errorf("allocated = %d, want less than the size of a sparse file", result.Allocated)
+	}
+	if usage := SummarizeDiskUsage(result, 0); usage.Allocated != result.Allocated {
+		t.Errorf("SummarizeDiskUsage().Allocated = %d, want %d", usage.Allocated, result.Allocated)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package service
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// invalidFileSize is returned by GetCompressedFileSizeW on failure, or as the
+// low part of a size, the error then telling them apart
+const invalidFileSize = 0xFFFFFFFF
+
+var procGetCompressedFileSizeW = kernel32.NewProc("GetCompressedFileSizeW")
+
+// allocatedSize returns the space the file at path takes on disk from
+// GetCompressedFileSizeW, which accounts for NTFS compression and sparse files.
+// When the file cannot be queried, such as a file held open by the system, the
+// size from info is returned with the error.
+func allocatedSize(path string, info os.FileInfo) (int64, error) {
+	if procGetCompressedFileSizeW.Find() != nil {
+		return info.Size(), nil
+	}
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return info.Size(), nil
+	}
+	var high uint32
+	low, _, e := procGetCompressedFileSizeW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&high)))
+	if uint32(low) == invalidFileSize && e != windows.ERROR_SUCCESS {
+		return info.Size(), &os.PathError{Op: "GetCompressedFileSize", Path: path, Err: e}
+	}
+	return int64(high)<<32 | int64(uint32(low)), nil
+}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Archive modes of ArchiveRepository
+const (
+	// ArchiveModeMove moves the repository directory into the archive
+	ArchiveModeMove = "move"
+	// ArchiveModeBundle replaces the repository with a git bundle of all its refs
+	ArchiveModeBundle = "bundle"
+)
+
+// Reasons a repository is kept in the workspace
+const (
+	ArchiveKeepActive   = "active"
+	ArchiveKeepDirty    = "uncommitted changes"
+	ArchiveKeepUnmerged = "unmerged branches"
+)
+
+// stashRef is the ref of the latest stash entry, the older ones being kept in its reflog
+const stashRef plumbing.ReferenceName = "refs/stash"
+
+// ArchiveManifestName is the file listing the archived repositories of an archive directory
+const ArchiveManifestName = "goktor-archive.json"
+
+// ArchiveSchemaVersion is the version of the ArchiveManifest JSON layout
+const ArchiveSchemaVersion = 1
+
+// ArchiveCriteria decides when a repository is inactive enough to be archived
+type ArchiveCriteria struct {
+	// InactiveFor is the minimum time since the last commit and the last fetch
+	InactiveFor time.Duration
+	// AllowUnmerged archives repositories whose local branches hold commits
+	// no remote-tracking branch contains
+	AllowUnmerged bool
+}
+
+// ArchiveCheck is the outcome of CheckArchivable
+type ArchiveCheck struct {
+	// LastActivity is the latest of the branch tips commit dates and the last fetch
+	LastActivity time.Time `json:"last_activity"`
+	// Unmerged lists the local branches with commits missing from the remote
+	Unmerged []string `json:"unmerged,omitempty"`
+	// KeepReason is why the repository stays in the workspace, empty when it can be archived
+	KeepReason string `json:"keep_reason,omitempty"`
+}
+
+// ArchiveEntry records an archived repository in the archive manifest
+type ArchiveEntry struct {
+	Name string `json:"name"`
+	// Source is where the repository was in the workspace
+	Source string `json:"source"`
+	// Path is the archived directory or bundle
+	Path   string `json:"path"`
+	Mode   string `json:"mode"`
+	Remote string `json:"remote,omitempty"`
+	// LastActivity is the last commit or fetch before archiving
+	LastActivity time.Time `json:"last_activity"`
+	Archived     time.Time `json:"archived"`
+	// Reclaimed is the space freed in the workspace: the repository size, minus the bundle size for bundles
+	Reclaimed int64 `json:"reclaimed"`
+}
+
+// ArchiveManifest lists the repositories archived into a directory
+type ArchiveManifest struct {
+	SchemaVersion int            `json:"schema_version"`
+	Entries       []ArchiveEntry `json:"entries"`
+}
+
+// CheckArchivable tells whether the repository at repoPath meets criteria,
+// using local data only. Repositories with uncommitted changes are always kept.
+func (gs *GitModelService) CheckArchivable(ctx context.Context, repoPath string, criteria ArchiveCriteria, opts Options) (*ArchiveCheck, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	check := &ArchiveCheck{}
+	var tips []*plumbing.Reference
+	if check.LastActivity, tips, err = lastActivity(repo); err != nil {
+		return nil, err
+	}
+
+	if time.Since(check.LastActivity) < criteria.InactiveFor {
+		check.KeepReason = ArchiveKeepActive
+		return check, nil
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	dirty, err := hasUncommittedChanges(worktree)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		check.KeepReason = ArchiveKeepDirty
+		return check, nil
+	}
+
+	if check.Unmerged, err = gs.unmergedBranches(ctx, repo, tips); err != nil {
+		return nil, err
+	}
+	if len(check.Unmerged) > 0 && !criteria.AllowUnmerged {
+		check.KeepReason = ArchiveKeepUnmerged
+	}
+	return check, nil
+}
+
+// lastActivity returns the latest of the last fetch and the branch tips
+// commit dates, with the branch tips
+func lastActivity(repo *git.Repository) (time.Time, []*plumbing.Reference, error) {
+	last := lastFetch(repo)
+	branches, err := repo.Branches()
+	if err != nil {
+		return last, nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	var tips []*plumbing.Reference
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to load branch %s: %w", ref.Name().Short(), err)
+		}
+		if commit.Committer.When.After(last) {
+			last = commit.Committer.When
+		}
+		tips = append(tips, ref)
+		return nil
+	})
+	return last, tips, err
+}
+
+// unmergedBranches returns the branches whose tip no remote-tracking branch of
+// the remote contains; without remote every branch is unmerged
+func (gs *GitModelService) unmergedBranches(ctx context.Context, repo *git.Repository, tips []*plumbing.Reference) ([]string, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+	prefix := plumbing.NewRemoteReferenceName(gs.remoteName(), "").String()
+	var remoteTips []plumbing.Hash
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && strings.HasPrefix(ref.Name().String(), prefix) {
+			remoteTips = append(remoteTips, ref.Hash())
+		}
+		return nil
+	})
+
+	var unmerged []string
+	for _, tip := range tips {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !containedInAny(repo, tip.Hash(), remoteTips) {
+			unmerged = append(unmerged, tip.Name().Short())
+		}
+	}
+	return unmerged, nil
+}
+
+// containedInAny reports whether commit is one of tips or an ancestor of one
+func containedInAny(repo *git.Repository, hash plumbing.Hash, tips []plumbing.Hash) bool {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return false
+	}
+	for _, tip := range tips {
+		if tip == hash {
+			return true
+		}
+		tipCommit, err := repo.CommitObject(tip)
+		if err != nil {
+			continue
+		}
+		if ok, err := commit.IsAncestor(tipCommit); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lastFetch returns when the repository was last fetched: the modification
+// time of FETCH_HEAD, written by git, or of the newest remote-tracking ref,
+// updated by go-git fetches. It is zero when the repository was never fetched.
+func lastFetch(repo *git.Repository) time.Time {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return time.Time{}
+	}
+	gitDir := storage.Filesystem().Root()
+	var last time.Time
+	if info, err := os.Stat(filepath.Join(gitDir, "FETCH_HEAD")); err == nil {
+		last = info.ModTime()
+	}
+	_ = filepath.WalkDir(filepath.Join(gitDir, "refs", "remotes"), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+		return nil
+	})
+	return last
+}
+
+// ArchiveRepository moves the repository at repoPath into archiveDir, or
+// replaces it with archiveDir/<name>.bundle in ArchiveModeBundle. A bundle
+// only keeps committed history, so the bundle mode refuses repositories with
+// untracked files. With opts.DryRun nothing is changed and the returned entry
+// reports the repository size as reclaimed space.
+func (gs *GitModelService) ArchiveRepository(ctx context.Context, repoPath string, archiveDir string, mode string, opts Options) (*ArchiveEntry, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(repoPath)
+	entry := &ArchiveEntry{
+		Name:     name,
+		Source:   repoPath,
+		Mode:     mode,
+		Remote:   remoteURL(repo, gs.remoteName()),
+		Archived: time.Now(),
+	}
+	entry.LastActivity, _, _ = lastActivity(repo)
+	size := pathSize(repoPath)
+
+	switch mode {
+	case ArchiveModeMove:
+		entry.Path = filepath.Join(archiveDir, name)
+	case ArchiveModeBundle:
+		entry.Path = filepath.Join(archiveDir, name+".bundle")
+		if err := checkBundleable(ctx, repo, repoPath); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown archive mode %q", mode)
+	}
+	if _, err := os.Lstat(entry.Path); err == nil {
+		return nil, fmt.Errorf("%s already exists in the archive", filepath.Base(entry.Path))
+	}
+
+	if opts.DryRun {
+		entry.Reclaimed = size
+		return entry, nil
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	if mode == ArchiveModeMove {
+		if err := moveDir(repoPath, entry.Path); err != nil {
+			return nil, err
+		}
+		entry.Reclaimed = size
+		return entry, nil
+	}
+
+	if _, err := runGit(ctx, repoPath, "bundle", "create", entry.Path, "--all"); err != nil {
+		_ = os.Remove(entry.Path)
+		return nil, fmt.Errorf("failed to create bundle: %w", err)
+	}
+	if _, err := runGit(ctx, repoPath, "bundle", "verify", entry.Path); err != nil {
+		_ = os.Remove(entry.Path)
+		return nil, fmt.Errorf("bundle verification failed: %w", err)
+	}
+	if err := os.RemoveAll(repoPath); err != nil {
+		return nil, fmt.Errorf("bundle created but failed to remove the repository: %w", err)
+	}
+	entry.Reclaimed = size - pathSize(entry.Path)
+	gs.logger.Debug("repository bundled", "bundle", entry.Path)
+	return entry, nil
+}
+
+// checkBundleable fails when replacing repoPath by a bundle of its refs would
+// lose data: modified, untracked or ignored files, which a bundle does not
+// hold, or a stash, whose older entries only live in the reflog
+func checkBundleable(ctx context.Context, repo *git.Repository, repoPath string) error {
+	if _, err := repo.Reference(stashRef, false); err == nil {
+		return fmt.Errorf("repository has stashed changes a bundle would lose")
+	}
+	out, err := runGit(ctx, repoPath, "status", "--porcelain", "--ignored", "--untracked-files=all")
+	if err != nil {
+		return fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	if out != "" {
+		return fmt.Errorf("worktree has modified, untracked or ignored files a bundle would lose")
+	}
+	return nil
+}
+
+// moveDir renames src to dst, copying then removing src when they are on
+// different volumes
+func moveDir(src string, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) {
+		return fmt.Errorf("failed to move %s: %w", src, err)
+	}
+	if err := copyTree(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return fmt.Errorf("failed to copy %s to the archive: %w", src, err)
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("copied to the archive but failed to remove %s: %w", src, err)
+	}
+	return nil
+}
+
+// copyTree copies the directory tree src to dst, keeping file modes and symlinks
+func copyTree(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// pathSize returns the total size of the files under path
+func pathSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// LoadArchiveManifest reads the manifest of archiveDir, empty when the
+// directory holds no archive yet
+func LoadArchiveManifest(archiveDir string) (*ArchiveManifest, error) {
+	manifest := &ArchiveManifest{SchemaVersion: ArchiveSchemaVersion}
+	data, err := os.ReadFile(filepath.Join(archiveDir, ArchiveManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid archive manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// SaveArchiveManifest writes the manifest of archiveDir
+func SaveArchiveManifest(archiveDir string, manifest *ArchiveManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive manifest: %w", err)
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(archiveDir, ArchiveManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write archive manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// TarArchiveOptions configures TarArchiveRepository
+type TarArchiveOptions struct {
+	Options
+	// Bare archives the .git directory instead of the worktree
+	Bare bool
+	// Include keeps only the files matching one of these patterns, all files
+	// when empty. Exclude drops the files matching one of its patterns. A
+	// pattern matches the slash separated path of a file relative to the
+	// archived directory, one of its parent directories, or its name.
+	Include []string
+	Exclude []string
+	// Now is the time stamped in the archive name, time.Now when zero
+	Now time.Time
+}
+
+// TarArchive is a tar.gz snapshot of a repository
+type TarArchive struct {
+	// Path is the archive file
+	Path string `json:"path"`
+	Bare bool   `json:"bare,omitempty"`
+	// Files is the number of regular files archived
+	Files int `json:"files"`
+	// Size is the uncompressed size of the archived files, and Compressed
+	// the size of the archive; Compressed is zero in a dry run
+	Size       int64 `json:"size"`
+	Compressed int64 `json:"compressed,omitempty"`
+}
+
+// TarArchiveName returns the name of the archive of the repository at
+// repoPath taken at now: <name>-<YYYYMMDD-HHMMSS>.tar.gz, or
+// <name>.git-<YYYYMMDD-HHMMSS>.tar.gz for bare archives
+func TarArchiveName(repoPath string, bare bool, now time.Time) string {
+	name := filepath.Base(repoPath)
+	if bare {
+		name += ".git"
+	}
+	return name + "-" + now.Format("20060102-150405") + ".tar.gz"
+}
+
+// CheckTarArchiveSpace verifies outDir has room for the tar.gz archives of
+// repoDirs, taking the uncompressed size of what they archive as the bound
+func CheckTarArchiveSpace(repoDirs []string, outDir string, bare bool) error {
+	var required int64
+	for _, repoDir := range repoDirs {
+		if bare {
+			repoDir = filepath.Join(repoDir, git.GitDirName)
+		}
+		required += pathSize(repoDir)
+	}
+	return CheckDiskSpace(existingAncestor(outDir), required)
+}
+
+// TarArchiveRepository writes a tar.gz snapshot of the worktree of repoPath,
+// without its .git directory, or of the .git directory alone with opts.Bare,
+// into outDir. The archive holds a single top-level directory named after the
+// repository (<name>.git when bare). The repository is left untouched; with
+// opts.DryRun only the files that would be archived are counted.
+func (gs *GitModelService) TarArchiveRepository(ctx context.Context, repoPath string, outDir string, opts TarArchiveOptions) (*TarArchive, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts.Options)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+
+	if _, err := openRepo(repoPath); err != nil {
+		return nil, err
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	src, prefix := repoPath, filepath.Base(repoPath)
+	if opts.Bare {
+		src, prefix = filepath.Join(repoPath, git.GitDirName), prefix+".git"
+		if info, err := os.Stat(src); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory, cannot archive it", src)
+		}
+	}
+	archive := &TarArchive{
+		Path: filepath.Join(outDir, TarArchiveName(repoPath, opts.Bare, now)),
+		Bare: opts.Bare,
+	}
+	if _, err := os.Lstat(archive.Path); err == nil {
+		return nil, fmt.Errorf("%s already exists", archive.Path)
+	}
+
+	if opts.DryRun {
+		err := walkArchived(ctx, src, !opts.Bare, opts, func(_ string, _ string, info fs.FileInfo) error {
+			if info.Mode().IsRegular() {
+				archive.Files++
+				archive.Size += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		gs.logger.Info("dry-run: would archive repository", "archive", archive.Path, "files", archive.Files)
+		return archive, nil
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// written under a temporary name, so an interrupted run never leaves a
+	// truncated archive looking complete
+	tmp := archive.Path + ".partial"
+	if err := writeTarGz(ctx, tmp, src, prefix, !opts.Bare, opts, archive); err != nil {
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, archive.Path); err != nil {
+		_ = os.Remove(tmp)
+		return nil, fmt.Errorf("failed to save archive: %w", err)
+	}
+	if info, err := os.Stat(archive.Path); err == nil {
+		archive.Compressed = info.Size()
+	}
+	gs.logger.Info("archived repository", "archive", archive.Path, "files", archive.Files)
+	return archive, nil
+}
+
+// writeTarGz writes the files of src selected by opts to the tar.gz file
+// dst, under the directory prefix, and counts them in archive
+func writeTarGz(ctx context.Context, dst string, src string, prefix string, skipGitDir bool, opts TarArchiveOptions, archive *TarArchive) error {
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	err = walkArchived(ctx, src, skipGitDir, opts, func(file string, rel string, info fs.FileInfo) error {
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			var err error
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		if _, err := io.Copy(tw, in); err != nil {
+			return err
+		}
+		archive.Files++
+		archive.Size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return out.Close()
+}
+
+// walkArchived calls fn for the directories, regular files and symlinks
+// under src selected by opts.Include and opts.Exclude, with their slash
+// separated path relative to src. Directories are passed before their
+// content and only when included as a whole; skipGitDir leaves out the .git
+// directory at the root of src.
+func walkArchived(ctx context.Context, src string, skipGitDir bool, opts TarArchiveOptions, fn func(file string, rel string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(src, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if file == src {
+			return nil
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skipGitDir && rel == git.GitDirName {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchesPath(rel, opts.Exclude) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(opts.Include) > 0 && !matchesPath(rel, opts.Include) {
+			// for a directory, a file further down may still be included
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		return fn(file, rel, info)
+	})
+}
+
+// matchesPath reports whether the slash separated path rel, one of its
+// parent directories, or its name matches one of patterns
+func matchesPath(rel string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	if matchesAny(path.Base(rel), patterns) {
+		return true
+	}
+	for dir := rel; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if matchesAny(dir, patterns) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitModelService_TarArchiveRepository(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, _, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+	for name, content := range map[string]string{"debug.log": "noise", "build/out.bin": "binary", "src/main.go": "package main"} {
+		file := filepath.Join(repoPath, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	outDir := filepath.Join(t.TempDir(), "backups")
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	opts := TarArchiveOptions{Exclude: []string{"*.log", "build"}, Now: now}
+
+	if err := CheckTarArchiveSpace([]string{repoPath}, outDir, false); err != nil {
+		t.Errorf("CheckTarArchiveSpace() error = %v", err)
+	}
+	dryOpts := opts
+	dryOpts.DryRun = true
+	dry, err := gs.TarArchiveRepository(ctx, repoPath, outDir, dryOpts)
+	if err != nil {
+		t.Fatalf("TarArchiveRepository() dry run error = %v", err)
+	}
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Errorf("dry run created the output directory")
+	}
+
+	archive, err := gs.TarArchiveRepository(ctx, repoPath, outDir, opts)
+	if err != nil {
+		t.Fatalf("TarArchiveRepository() error = %v", err)
+	}
+	name := filepath.Base(repoPath)
+	if filepath.Base(archive.Path) != name+"-20240501-123000.tar.gz" {
+		t.Errorf("Path = %s, want a timestamped name", archive.Path)
+	}
+	if archive.Files != dry.Files || archive.Size != dry.Size || archive.Compressed == 0 {
+		t.Errorf("archive = %+v, dry run = %+v", archive, dry)
+	}
+	entries := tarEntries(t, archive.Path)
+	if !slices.Contains(entries, name+"/src/main.go") {
+		t.Errorf("entries = %v, want src/main.go", entries)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry, ".git/") || strings.HasSuffix(entry, ".log") || strings.Contains(entry, "build/") {
+			t.Errorf("entry %s should not be archived", entry)
+		}
+	}
+	if _, err := gs.TarArchiveRepository(ctx, repoPath, outDir, opts); err == nil {
+		t.Errorf("TarArchiveRepository() should refuse to overwrite an archive")
+	}
+
+	bare, err := gs.TarArchiveRepository(ctx, repoPath, outDir, TarArchiveOptions{Bare: true, Include: []string{"HEAD", "refs"}, Now: now})
+	if err != nil {
+		t.Fatalf("TarArchiveRepository() bare error = %v", err)
+	}
+	entries = tarEntries(t, bare.Path)
+	if !slices.Contains(entries, name+".git/HEAD") || slices.Contains(entries, name+".git/config") {
+		t.Errorf("bare entries = %v, want HEAD and refs only", entries)
+	}
+}
+
+func tarEntries(t *testing.T, archive string) []string {
+	t.Helper()
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("invalid gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatalf("invalid tar: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+}
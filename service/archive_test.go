@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGitModelService_CheckArchivable(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+
+	t.Run("recent activity", func(t *testing.T) {
+		repoPath, _, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+		check, err := gs.CheckArchivable(ctx, repoPath, ArchiveCriteria{InactiveFor: 24 * time.Hour}, Options{})
+		if err != nil {
+			t.Fatalf("CheckArchivable() error = %v", err)
+		}
+		if check.KeepReason != ArchiveKeepActive {
+			t.Errorf("KeepReason = %q, want %q", check.KeepReason, ArchiveKeepActive)
+		}
+	})
+
+	t.Run("pushed and clean", func(t *testing.T) {
+		repoPath, _, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+		check, err := gs.CheckArchivable(ctx, repoPath, ArchiveCriteria{}, Options{})
+		if err != nil {
+			t.Fatalf("CheckArchivable() error = %v", err)
+		}
+		if check.KeepReason != "" || len(check.Unmerged) != 0 {
+			t.Errorf("check = %+v, want archivable", check)
+		}
+	})
+
+	t.Run("unpushed commit", func(t *testing.T) {
+		repoPath, _, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+		commitTestFile(t, repoPath, "local.txt", "local only")
+		check, err := gs.CheckArchivable(ctx, repoPath, ArchiveCriteria{}, Options{})
+		if err != nil {
+			t.Fatalf("CheckArchivable() error = %v", err)
+		}
+		if check.KeepReason != ArchiveKeepUnmerged || len(check.Unmerged) != 1 {
+			t.Errorf("check = %+v, want one unmerged branch", check)
+		}
+		check, err = gs.CheckArchivable(ctx, repoPath, ArchiveCriteria{AllowUnmerged: true}, Options{})
+		if err != nil {
+			t.Fatalf("CheckArchivable() error = %v", err)
+		}
+		if check.KeepReason != "" {
+			t.Errorf("KeepReason = %q with AllowUnmerged, want archivable", check.KeepReason)
+		}
+	})
+
+	t.Run("uncommitted changes", func(t *testing.T) {
+		repoPath, _, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+		if err := os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte("changed"), 0644); err != nil {
+			t.Fatalf("failed to modify file: %v", err)
+		}
+		check, err := gs.CheckArchivable(ctx, repoPath, ArchiveCriteria{AllowUnmerged: true}, Options{})
+		if err != nil {
+			t.Fatalf("CheckArchivable() error = %v", err)
+		}
+		if check.KeepReason != ArchiveKeepDirty {
+			t.Errorf("KeepReason = %q, want %q", check.KeepReason, ArchiveKeepDirty)
+		}
+	})
+}
+
+func TestGitModelService_ArchiveRepository(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+
+	t.Run("move", func(t *testing.T) {
+		repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+		archiveDir := filepath.Join(t.TempDir(), "archive")
+
+		dry, err := gs.ArchiveRepository(ctx, repoPath, archiveDir, ArchiveModeMove, Options{DryRun: true})
+		if err != nil {
+			t.Fatalf("ArchiveRepository() dry run error = %v", err)
+		}
+		if _, err := os.Stat(repoPath); err != nil || dry.Reclaimed == 0 {
+			t.Fatalf("dry run should keep the repository and report its size, entry = %+v", dry)
+		}
+
+		entry, err := gs.ArchiveRepository(ctx, repoPath, archiveDir, ArchiveModeMove, Options{})
+		if err != nil {
+			t.Fatalf("ArchiveRepository() error = %v", err)
+		}
+		if _, err := os.Stat(repoPath); !os.IsNotExist(err) {
+			t.Errorf("repository still in the workspace")
+		}
+		if _, err := os.Stat(filepath.Join(entry.Path, ".git")); err != nil {
+			t.Errorf("archived repository missing: %v", err)
+		}
+		if entry.Remote != bareDir || entry.Reclaimed != dry.Reclaimed || entry.LastActivity.IsZero() {
+			t.Errorf("entry = %+v", entry)
+		}
+
+		manifest, err := LoadArchiveManifest(archiveDir)
+		if err != nil {
+			t.Fatalf("LoadArchiveManifest() error = %v", err)
+		}
+		manifest.Entries = append(manifest.Entries, *entry)
+		if err := SaveArchiveManifest(archiveDir, manifest); err != nil {
+			t.Fatalf("SaveArchiveManifest() error = %v", err)
+		}
+		reloaded, err := LoadArchiveManifest(archiveDir)
+		if err != nil {
+			t.Fatalf("LoadArchiveManifest() error = %v", err)
+		}
+		if len(reloaded.Entries) != 1 || reloaded.Entries[0].Source != repoPath || reloaded.SchemaVersion != ArchiveSchemaVersion {
+			t.Errorf("manifest = %+v", reloaded)
+		}
+	})
+
+	t.Run("bundle", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git executable not available")
+		}
+		t.Setenv("GIT_AUTHOR_NAME", "Test User")
+		t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+		t.Setenv("GIT_COMMITTER_NAME", "Test User")
+		t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+		repoPath, _, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+		archiveDir := t.TempDir()
+
+		if err := os.WriteFile(filepath.Join(repoPath, "untracked.txt"), []byte("lost"), 0644); err != nil {
+			t.Fatalf("failed to write untracked file: %v", err)
+		}
+		if _, err := gs.ArchiveRepository(ctx, repoPath, archiveDir, ArchiveModeBundle, Options{}); err == nil {
+			t.Fatalf("ArchiveRepository() should refuse to bundle untracked files")
+		}
+		if err := os.Remove(filepath.Join(repoPath, "untracked.txt")); err != nil {
+			t.Fatalf("failed to remove untracked file: %v", err)
+		}
+
+		exclude := filepath.Join(repoPath, ".git", "info", "exclude")
+		if err := os.MkdirAll(filepath.Dir(exclude), 0755); err != nil {
+			t.Fatalf("failed to create info directory: %v", err)
+		}
+		if err := os.WriteFile(exclude, []byte("*.env\n"), 0644); err != nil {
+			t.Fatalf("failed to write exclude: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(repoPath, "secrets.env"), []byte("lost"), 0644); err != nil {
+			t.Fatalf("failed to write ignored file: %v", err)
+		}
+		if _, err := gs.ArchiveRepository(ctx, repoPath, archiveDir, ArchiveModeBundle, Options{}); err == nil {
+			t.Fatalf("ArchiveRepository() should refuse to bundle ignored files")
+		}
+		if err := os.Remove(filepath.Join(repoPath, "secrets.env")); err != nil {
+			t.Fatalf("failed to remove ignored file: %v", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte("stashed"), 0644); err != nil {
+			t.Fatalf("failed to modify file: %v", err)
+		}
+		if _, err := runGit(ctx, repoPath, "stash", "push"); err != nil {
+			t.Fatalf("git stash failed: %v", err)
+		}
+		if _, err := gs.ArchiveRepository(ctx, repoPath, archiveDir, ArchiveModeBundle, Options{}); err == nil {
+			t.Fatalf("ArchiveRepository() should refuse to bundle a stash")
+		}
+		if _, err := runGit(ctx, repoPath, "stash", "drop"); err != nil {
+			t.Fatalf("git stash drop failed: %v", err)
+		}
+
+		entry, err := gs.ArchiveRepository(ctx, repoPath, archiveDir, ArchiveModeBundle, Options{})
+		if err != nil {
+			t.Fatalf("ArchiveRepository() error = %v", err)
+		}
+		if _, err := os.Stat(repoPath); !os.IsNotExist(err) {
+			t.Errorf("repository still in the workspace")
+		}
+		restored := filepath.Join(t.TempDir(), "restored")
+		if _, err := runGit(ctx, archiveDir, "clone", entry.Path, restored); err != nil {
+			t.Fatalf("bundle cannot be cloned: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(restored, "test.txt")); err != nil {
+			t.Errorf("restored clone misses test.txt: %v", err)
+		}
+	})
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// authSource resolves credentials for a remote endpoint. It returns a nil
+// AuthMethod when it has nothing to offer for that endpoint.
+type authSource func(ctx context.Context, endpoint *transport.Endpoint) (transport.AuthMethod, error)
+
+// defaultAuthSources are consulted in order before a network operation
+var defaultAuthSources = []authSource{keychainAuth, netrcAuth}
+
+// defaultInteractiveSources are consulted only after the remote rejected the
+// first attempt for missing or invalid credentials
+var defaultInteractiveSources = []authSource{askPassAuth}
+
+// withAuth runs op with the first credentials resolved for remoteURL and the
+// proxy of the remote. When the remote answers that authentication is
+// required, the interactive sources (GIT_ASKPASS, SSH_ASKPASS) are asked and
+// op is retried once. Credentials set through Options.Auth are used as they
+// are. Rejected credentials give an error wrapping ErrAuthRequired.
+func (gs *GitModelService) withAuth(ctx context.Context, remoteURL string, op func(auth transport.AuthMethod, proxy transport.ProxyOptions) error) error {
+	proxy, err := gs.proxy.proxyOptions(remoteURL)
+	if err != nil {
+		return err
+	}
+	if gs.auth != nil {
+		return typedError(op(gs.auth, proxy))
+	}
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil || endpoint.Protocol == "file" {
+		return typedError(op(nil, proxy))
+	}
+
+	auth, err := resolveAuth(ctx, endpoint, gs.authSources)
+	if err != nil {
+		return err
+	}
+
+	err = op(auth, proxy)
+	if !isAuthError(err) {
+		return typedError(err)
+	}
+	err = typedError(err)
+
+	interactive, resolveErr := resolveAuth(ctx, endpoint, gs.interactiveSources)
+	if resolveErr != nil {
+		return fmt.Errorf("%w (credential prompt failed: %v)", err, resolveErr)
+	}
+	if interactive == nil {
+		return err
+	}
+	gs.logger.Debug("retrying with interactive credentials", "host", endpoint.Host)
+	return typedError(op(interactive, proxy))
+}
+
+func resolveAuth(ctx context.Context, endpoint *transport.Endpoint, sources []authSource) (transport.AuthMethod, error) {
+	for _, source := range sources {
+		auth, err := source(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if auth != nil {
+			return auth, nil
+		}
+	}
+	return nil, nil
+}
+
+func isAuthError(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed)
+}
+
+// netrcAuth reads the login and password of the matching machine entry from
+// $NETRC, ~/.netrc or, on Windows, ~/_netrc
+func netrcAuth(_ context.Context, endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	if endpoint.Protocol != "http" && endpoint.Protocol != "https" {
+		return nil, nil
+	}
+
+	path := netrcPath()
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	login, password, ok := parseNetrc(string(data), endpoint.Host)
+	if !ok {
+		return nil, nil
+	}
+	return &githttp.BasicAuth{Username: login, Password: password}, nil
+}
+
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(home, ".netrc")
+	if runtime.GOOS == "windows" {
+		if _, err := os.Stat(path); err != nil {
+			return filepath.Join(home, "_netrc")
+		}
+	}
+	return path
+}
+
+// parseNetrc returns the credentials of the machine entry for host, falling
+// back to the default entry
+func parseNetrc(data string, host string) (string, string, bool) {
+	type entry struct{ login, password string }
+	var current, matched, fallback *entry
+
+	tokens := netrcTokens(data)
+	for i := 0; i < len(tokens); i++ {
+		next := func() string {
+			if i+1 < len(tokens) {
+				i++
+				return tokens[i]
+			}
+			return ""
+		}
+		switch tokens[i] {
+		case "machine":
+			current = &entry{}
+			if next() == host && matched == nil {
+				matched = current
+			}
+		case "default":
+			current = &entry{}
+			fallback = current
+		case "login":
+			if current != nil {
+				current.login = next()
+			}
+		case "password":
+			if current != nil {
+				current.password = next()
+			}
+		case "account":
+			next()
+		}
+	}
+
+	if matched == nil {
+		matched = fallback
+	}
+	if matched == nil || matched.login == "" {
+		return "", "", false
+	}
+	return matched.login, matched.password, true
+}
+
+// netrcTokens splits a netrc file into tokens, dropping comments and macdef
+// bodies, which run until the next blank line
+func netrcTokens(data string) []string {
+	var tokens []string
+	inMacro := false
+	for _, line := range strings.Split(data, "\n") {
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, field := range strings.Fields(line) {
+			if field == "macdef" {
+				inMacro = true
+				break
+			}
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+// askPassAuth asks GIT_ASKPASS for HTTP credentials and SSH_ASKPASS for the
+// passphrase of the default SSH private key
+func askPassAuth(ctx context.Context, endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	switch endpoint.Protocol {
+	case "http", "https":
+		helper := os.Getenv("GIT_ASKPASS")
+		if helper == "" {
+			return nil, nil
+		}
+		base := fmt.Sprintf("%s://%s", endpoint.Protocol, endpoint.Host)
+		username := endpoint.User
+		if username == "" {
+			u, err := runAskPass(ctx, helper, fmt.Sprintf("Username for '%s': ", base))
+			if err != nil {
+				return nil, err
+			}
+			username = u
+		}
+		password, err := runAskPass(ctx, helper, fmt.Sprintf("Password for '%s://%s@%s': ", endpoint.Protocol, username, endpoint.Host))
+		if err != nil {
+			return nil, err
+		}
+		return &githttp.BasicAuth{Username: username, Password: password}, nil
+
+	case "ssh":
+		helper := os.Getenv("SSH_ASKPASS")
+		if helper == "" {
+			return nil, nil
+		}
+		keyFile := defaultSSHKey()
+		if keyFile == "" {
+			return nil, nil
+		}
+		passphrase, err := runAskPass(ctx, helper, fmt.Sprintf("Enter passphrase for key '%s': ", keyFile))
+		if err != nil {
+			return nil, err
+		}
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		return gitssh.NewPublicKeysFromFile(user, keyFile, passphrase)
+	}
+	return nil, nil
+}
+
+func runAskPass(ctx context.Context, helper string, prompt string) (string, error) {
+	out, err := runCommand(ctx, "", helper, prompt)
+	if err != nil {
+		return "", fmt.Errorf("askpass helper failed: %w", err)
+	}
+	return out, nil
+}
+
+func defaultSSHKey() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		path := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func TestParseNetrc(t *testing.T) {
+	data := `# company credentials
+machine github.com login octocat password gh-token
+machine gitlab.example.com
+	login deploy
+	password gl-token
+macdef init
+	cd /pub
+	machine evil.com login nope password nope
+
+default login anonymous password guest
+`
+	tests := []struct {
+		host         string
+		wantLogin    string
+		wantPassword string
+	}{
+		{host: "github.com", wantLogin: "octocat", wantPassword: "gh-token"},
+		{host: "gitlab.example.com", wantLogin: "deploy", wantPassword: "gl-token"},
+		{host: "evil.com", wantLogin: "anonymous", wantPassword: "guest"},
+		{host: "other.com", wantLogin: "anonymous", wantPassword: "guest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			login, password, ok := parseNetrc(data, tt.host)
+			if !ok || login != tt.wantLogin || password != tt.wantPassword {
+				t.Errorf("parseNetrc() = %q, %q, %v, want %q, %q", login, password, ok, tt.wantLogin, tt.wantPassword)
+			}
+		})
+	}
+
+	if _, _, ok := parseNetrc("machine github.com login a password b", "other.com"); ok {
+		t.Error("parseNetrc() matched a host without entry or default")
+	}
+}
+
+func TestNetrcAuth(t *testing.T) {
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	os.WriteFile(netrc, []byte("machine git.example.com login user password secret\n"), 0600)
+	t.Setenv("NETRC", netrc)
+
+	endpoint, _ := transport.NewEndpoint("https://git.example.com/org/repo.git")
+	auth, err := netrcAuth(context.Background(), endpoint)
+	if err != nil {
+		t.Fatalf("netrcAuth() error = %v", err)
+	}
+
+	basic, ok := auth.(*githttp.BasicAuth)
+	if !ok || basic.Username != "user" || basic.Password != "secret" {
+		t.Errorf("netrcAuth() = %v, want basic auth user/secret", auth)
+	}
+}
+
+func TestAskPassAuth(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping shell script helper on Windows")
+	}
+
+	helper := filepath.Join(t.TempDir(), "askpass.sh")
+	os.WriteFile(helper, []byte("#!/bin/sh\ncase \"$1\" in Username*) echo alice;; *) echo s3cret;; esac\n"), 0755)
+	t.Setenv("GIT_ASKPASS", helper)
+
+	endpoint, _ := transport.NewEndpoint("https://git.example.com/org/repo.git")
+	auth, err := askPassAuth(context.Background(), endpoint)
+	if err != nil {
+		t.Fatalf("askPassAuth() error = %v", err)
+	}
+
+	basic, ok := auth.(*githttp.BasicAuth)
+	if !ok || basic.Username != "alice" || basic.Password != "s3cret" {
+		t.Errorf("askPassAuth() = %v, want basic auth alice/s3cret", auth)
+	}
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/nanaki-93/goktor/model"
+)
+
+// CheckpointSchemaVersion is the version of the Checkpoint JSON layout
+const CheckpointSchemaVersion = 1
+
+// CheckpointInterval is the minimum time between two checkpoint writes while
+// a run is progressing; the final state is always written by Save
+const CheckpointInterval = 5 * time.Second
+
+// ErrNoCheckpoint is returned by LoadCheckpoint when there is nothing to resume
+var ErrNoCheckpoint = errors.New("no checkpoint to resume")
+
+// CheckpointEntry is a completed target of a long run. Fingerprint captures
+// the state of the target when it was completed, so a resumed run can tell
+// whether the recorded result is still valid.
+type CheckpointEntry struct {
+	Fingerprint string          `json:"fingerprint"`
+	Result      json.RawMessage `json:"result,omitempty"`
+}
+
+// Checkpoint persists the completed targets of a long run, such as the
+// repositories of a batch command or the top-level directories of a scan, so
+// a cancelled or crashed run can resume instead of starting over. It is safe
+// for concurrent use; the methods of a nil Checkpoint do nothing.
+type Checkpoint struct {
+	SchemaVersion int                        `json:"schema_version"`
+	Command       string                     `json:"command"`
+	Updated       time.Time                  `json:"updated"`
+	Entries       map[string]CheckpointEntry `json:"entries"`
+
+	path     string
+	mu       sync.Mutex
+	lastSave time.Time
+}
+
+// DefaultCheckpointDir returns ~/.goktor/checkpoints
+func DefaultCheckpointDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goktor", "checkpoints"), nil
+}
+
+// CheckpointPath returns the checkpoint file in dir of command run with
+// scope, such as its target directories and the options changing its result
+func CheckpointPath(dir string, command string, scope ...string) string {
+	sum := sha256.Sum256([]byte(command + "\x00" + strings.Join(scope, "\x00")))
+	return filepath.Join(dir, strings.ReplaceAll(command, " ", "-")+"-"+hex.EncodeToString(sum[:6])+".json")
+}
+
+// NewCheckpoint starts an empty checkpoint of command, written to path
+func NewCheckpoint(path string, command string) *Checkpoint {
+	return &Checkpoint{
+		SchemaVersion: CheckpointSchemaVersion,
+		Command:       command,
+		Entries:       map[string]CheckpointEntry{},
+		path:          path,
+		lastSave:      time.Now(),
+	}
+}
+
+// LoadCheckpoint reads the checkpoint of command at path, ErrNoCheckpoint
+// when the previous run completed or never started
+func LoadCheckpoint(path string, command string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoCheckpoint
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	checkpoint := NewCheckpoint(path, command)
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if checkpoint.SchemaVersion != CheckpointSchemaVersion {
+		return nil, fmt.Errorf("checkpoint %s has schema version %d, want %d", path, checkpoint.SchemaVersion, CheckpointSchemaVersion)
+	}
+	if checkpoint.Command != command {
+		return nil, fmt.Errorf("checkpoint %s belongs to %q", path, checkpoint.Command)
+	}
+	if checkpoint.Entries == nil {
+		checkpoint.Entries = map[string]CheckpointEntry{}
+	}
+	return checkpoint, nil
+}
+
+// Restore reports whether target was completed with the given fingerprint,
+// decoding its recorded result into result when not nil. An entry whose
+// fingerprint no longer matches is dropped, so the target is processed again.
+func (c *Checkpoint) Restore(target string, fingerprint string, result any) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.Entries[target]
+	if !ok {
+		return false
+	}
+	if entry.Fingerprint != fingerprint {
+		delete(c.Entries, target)
+		return false
+	}
+	if result != nil && entry.Result != nil {
+		if err := json.Unmarshal(entry.Result, result); err != nil {
+			delete(c.Entries, target)
+			return false
+		}
+	}
+	return true
+}
+
+// Complete records target as completed and writes the checkpoint when the
+// last write is older than CheckpointInterval
+func (c *Checkpoint) Complete(target string, fingerprint string, result any) error {
+	if c == nil {
+		return nil
+	}
+	entry := CheckpointEntry{Fingerprint: fingerprint}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode checkpoint result: %w", err)
+		}
+		entry.Result = data
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[target] = entry
+	if time.Since(c.lastSave) < CheckpointInterval {
+		return nil
+	}
+	return c.save()
+}
+
+// Begin records target as started and writes the checkpoint, so a crash
+// while it is processed is detected by Interrupted after resuming
+func (c *Checkpoint) Begin(target string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[target] = CheckpointEntry{}
+	return c.save()
+}
+
+// Interrupted reports whether target was started but never completed
+func (c *Checkpoint) Interrupted(target string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.Entries[target]
+	return ok && entry.Fingerprint == ""
+}
+
+// Len returns the number of completed targets
+func (c *Checkpoint) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	completed := 0
+	for _, entry := range c.Entries {
+		if entry.Fingerprint != "" {
+			completed++
+		}
+	}
+	return completed
+}
+
+// Path returns the file the checkpoint is written to
+func (c *Checkpoint) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.path
+}
+
+// Save writes the checkpoint
+func (c *Checkpoint) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save()
+}
+
+// save writes the checkpoint to a temp file renamed over the previous one, so
+// a crash while writing never leaves a truncated checkpoint
+func (c *Checkpoint) save() error {
+	c.Updated = time.Now()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	c.lastSave = time.Now()
+	return nil
+}
+
+// Remove deletes the checkpoint file once the run completed
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// RepoFingerprint hashes every ref of the repository at repoPath with its
+// target, so any fetch, commit, reset or branch change invalidates it. It
+// fails when the directory is not a readable repository, such as a clone
+// that was interrupted before writing its refs.
+func RepoFingerprint(repoPath string) (string, error) {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs: %w", err)
+	}
+	lines := []string{"HEAD " + head.Hash().String()}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			lines = append(lines, ref.Name().String()+" "+ref.Hash().String())
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs: %w", err)
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SetCheckpoint makes the recursive scans record every completed top-level
+// directory in checkpoint and reuse the ones it already holds. A directory is
+// reused while its modification time is unchanged; changes deeper in the tree
+// since the checkpoint are picked up by the next scan without --resume.
+func (fs *FileSystemService) SetCheckpoint(checkpoint *Checkpoint) {
+	fs.checkpoint = checkpoint
+}
+
+// checkpointedDirectory returns the checkpointed scan of path when it is still
+// valid, otherwise scans it and records the result once the subtree is complete
+func (fs *FileSystemService) checkpointedDirectory(ctx context.Context, path string, filter func(model.Directory) bool) (model.Directory, error) {
+	fingerprint := dirFingerprint(path)
+	var dir model.Directory
+	if fingerprint != "" && fs.checkpoint.Restore(path, fingerprint, &dir) {
+		fs.logger.Debug("reusing checkpointed directory", "path", path)
+		var size int64
+		for _, sub := range dir.FlattenDirectory() {
+			size += sub.Size
+		}
+		fs.reportProgress(path, 0, size)
+		return dir, nil
+	}
+
+	dir, err := fs.scanDirectory(ctx, path, filter)
+	if err != nil || ctx.Err() != nil || fingerprint == "" {
+		return dir, err
+	}
+	if err := fs.checkpoint.Complete(path, fingerprint, dir); err != nil {
+		fs.logger.Warn("failed to write checkpoint", "path", path, "error", err)
+	}
+	return dir, nil
+}
+
+// dirFingerprint returns the modification time of the directory at path, or
+// "" when it cannot be read
+func dirFingerprint(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return info.ModTime().UTC().Format(time.RFC3339Nano)
+}
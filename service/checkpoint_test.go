@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestCheckpoint_SaveAndResume(t *testing.T) {
+	path := CheckpointPath(filepath.Join(t.TempDir(), "checkpoints"), "goktor mr-repo pull", "/ws/a", "/ws/b")
+
+	if _, err := LoadCheckpoint(path, "goktor mr-repo pull"); !errors.Is(err, ErrNoCheckpoint) {
+		t.Fatalf("LoadCheckpoint() before any run error = %v, want ErrNoCheckpoint", err)
+	}
+
+	checkpoint := NewCheckpoint(path, "goktor mr-repo pull")
+	if err := checkpoint.Complete("/ws/a", "fp-a", RunRepo{Repo: "/ws/a", Status: RunStatusDone}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if err := checkpoint.Begin("/ws/b"); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	resumed, err := LoadCheckpoint(path, "goktor mr-repo pull")
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+	if resumed.Len() != 1 {
+		t.Errorf("Len() = %d, want 1 completed target", resumed.Len())
+	}
+	if !resumed.Interrupted("/ws/b") || resumed.Interrupted("/ws/a") {
+		t.Errorf("Interrupted() should only report /ws/b")
+	}
+
+	var repo RunRepo
+	if !resumed.Restore("/ws/a", "fp-a", &repo) || repo.Status != RunStatusDone {
+		t.Errorf("Restore() = %+v, want the recorded outcome", repo)
+	}
+	if resumed.Restore("/ws/a", "fp-changed", nil) {
+		t.Errorf("Restore() with a changed fingerprint should fail")
+	}
+	if resumed.Restore("/ws/a", "fp-a", nil) {
+		t.Errorf("Restore() should drop an entry whose fingerprint changed")
+	}
+
+	if _, err := LoadCheckpoint(path, "goktor mr-repo fetch"); err == nil {
+		t.Errorf("LoadCheckpoint() of another command should fail")
+	}
+
+	if err := resumed.Remove(); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := LoadCheckpoint(path, "goktor mr-repo pull"); !errors.Is(err, ErrNoCheckpoint) {
+		t.Errorf("LoadCheckpoint() after Remove() error = %v, want ErrNoCheckpoint", err)
+	}
+}
+
+func TestCheckpoint_Nil(t *testing.T) {
+	var checkpoint *Checkpoint
+	if checkpoint.Restore("a", "fp", nil) || checkpoint.Interrupted("a") || checkpoint.Len() != 0 {
+		t.Errorf("nil checkpoint should hold nothing")
+	}
+	if err := checkpoint.Complete("a", "fp", nil); err != nil {
+		t.Errorf("Complete() on nil checkpoint error = %v", err)
+	}
+}
+
+func TestRepoFingerprint(t *testing.T) {
+	repoPath, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	before, err := RepoFingerprint(repoPath)
+	if err != nil {
+		t.Fatalf("RepoFingerprint() error = %v", err)
+	}
+	if again, _ := RepoFingerprint(repoPath); again != before {
+		t.Errorf("RepoFingerprint() is not stable: %s != %s", again, before)
+	}
+
+	commitTestFile(t, repoPath, "next.txt", "next")
+	after, err := RepoFingerprint(repoPath)
+	if err != nil {
+		t.Fatalf("RepoFingerprint() error = %v", err)
+	}
+	if after == before {
+		t.Errorf("RepoFingerprint() should change after a commit")
+	}
+
+	if _, err := RepoFingerprint(t.TempDir()); err == nil {
+		t.Errorf("RepoFingerprint() of a plain directory should fail")
+	}
+}
+
+func TestFileSystemService_ScanCheckpoint(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(root, name, "nested"), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name, "nested", "file.bin"), make([]byte, 10), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "scan.json")
+
+	// an interrupted scan that completed a, recorded with a marker size
+	previous := NewCheckpoint(path, "goktor folder-list")
+	aPath := filepath.Join(root, "a")
+	marker := model.Directory{FileSystem: model.FileSystem{Name: "a", FullPath: aPath, IsDir: true, Size: 12345}}
+	if err := previous.Complete(aPath, dirFingerprint(aPath), marker); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if err := previous.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	checkpoint, err := LoadCheckpoint(path, "goktor folder-list")
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+	fs := NewServiceWithLimit(0)
+	fs.SetCheckpoint(checkpoint)
+	dir, err := fs.ListDirectories(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ListDirectories() error = %v", err)
+	}
+
+	sizes := map[string]int64{}
+	for _, sub := range dir.SubDirs {
+		sizes[sub.Name] = sub.Size
+	}
+	if sizes["a"] != 12345 {
+		t.Errorf("a size = %d, want the checkpointed 12345", sizes["a"])
+	}
+	if _, ok := sizes["b"]; !ok {
+		t.Errorf("b missing from the resumed scan: %v", sizes)
+	}
+	if checkpoint.Len() != 2 {
+		t.Errorf("checkpoint holds %d directories, want a and b", checkpoint.Len())
+	}
+
+	// a directory modified since the checkpoint is scanned again
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(aPath, later, later); err != nil {
+		t.Fatalf("failed to touch a: %v", err)
+	}
+	dir, err = fs.ListDirectories(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ListDirectories() error = %v", err)
+	}
+	for _, sub := range dir.SubDirs {
+		if sub.Name == "a" && sub.Size == 12345 {
+			t.Errorf("modified directory a should not be restored from the checkpoint")
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// builtinCleanProfiles holds the built-in clean profiles, in the layout of
+// the "clean" section of the configuration
+//
+//go:embed clean_profiles.json
+var builtinCleanProfiles []byte
+
+// CleanTarget is a directory whose content a clean profile deletes
+type CleanTarget struct {
+	// Path may start with ~ for the home directory, hold ${VAR} environment
+	// variables and filepath.Match patterns. A target whose variables are not
+	// all set is skipped.
+	Path string `json:"path"`
+	// OS restricts the target to these GOOS values, every platform when empty
+	OS []string `json:"os,omitempty"`
+}
+
+// CleanProfile is a named set of directories whose content can be deleted
+type CleanProfile struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// MinAge keeps the entries modified more recently, such as "1d", even
+	// when a shorter age is asked for
+	MinAge string `json:"min_age,omitempty"`
+	// Keep lists filepath.Match patterns of entry names never deleted, such as
+	// the sockets directories of running sessions
+	Keep []string `json:"keep,omitempty"`
+	// Shared marks directories used by every user, such as /tmp: only the
+	// entries of the current user are listed there, never those of root
+	Shared  bool          `json:"shared,omitempty"`
+	Targets []CleanTarget `json:"targets"`
+}
+
+// CleanConfig is the "clean" section of the configuration
+type CleanConfig struct {
+	// Profiles are added to the built-in profiles; the targets of a profile
+	// named like a built-in one are added to it
+	Profiles []CleanProfile `json:"profiles,omitempty"`
+}
+
+// CleanItem is an entry of a target directory that clean deletes
+type CleanItem struct {
+	Profile string
+	// Target is the target directory holding the entry
+	Target  string
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// NewCleanProfiles merges the configured profiles into the built-in ones
+func NewCleanProfiles(cfg CleanConfig) ([]CleanProfile, error) {
+	var builtin CleanConfig
+	if err := json.Unmarshal(builtinCleanProfiles, &builtin); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in clean profiles: %w", err)
+	}
+	profiles := builtin.Profiles
+	for _, profile := range cfg.Profiles {
+		if profile.Name == "" || len(profile.Targets) == 0 {
+			return nil, fmt.Errorf("clean profile %+v needs a name and targets", profile)
+		}
+		for _, target := range profile.Targets {
+			if _, err := filepath.Match(filepath.FromSlash(target.Path), ""); err != nil || target.Path == "" {
+				return nil, fmt.Errorf("invalid path %q of clean profile %s", target.Path, profile.Name)
+			}
+		}
+		for _, pattern := range profile.Keep {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid keep pattern %q of clean profile %s", pattern, profile.Name)
+			}
+		}
+		if profile.MinAge != "" {
+			if _, err := ParseAge(profile.MinAge); err != nil {
+				return nil, fmt.Errorf("invalid min_age of clean profile %s: %w", profile.Name, err)
+			}
+		}
+		i := slices.IndexFunc(profiles, func(p CleanProfile) bool { return p.Name == profile.Name })
+		if i < 0 {
+			profiles = append(profiles, profile)
+			continue
+		}
+		profiles[i].Targets = append(profiles[i].Targets, profile.Targets...)
+		profiles[i].Keep = append(profiles[i].Keep, profile.Keep...)
+		if profile.Description != "" {
+			profiles[i].Description = profile.Description
+		}
+		if profile.MinAge != "" {
+			profiles[i].MinAge = profile.MinAge
+		}
+		profiles[i].Shared = profiles[i].Shared || profile.Shared
+	}
+	return profiles, nil
+}
+
+// FindCleanProfile returns the profile called name
+func FindCleanProfile(profiles []CleanProfile, name string) (CleanProfile, error) {
+	names := make([]string, len(profiles))
+	for i, profile := range profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+		names[i] = profile.Name
+	}
+	return CleanProfile{}, fmt.Errorf("unknown clean profile %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// TargetDirs returns the existing directories the targets of the profile
+// designate on this platform
+func (p CleanProfile) TargetDirs() []string {
+	var dirs []string
+	for _, target := range p.Targets {
+		if len(target.OS) > 0 && !slices.Contains(target.OS, runtime.GOOS) {
+			continue
+		}
+		pattern, ok := expandCleanPath(target.Path)
+		if !ok {
+			continue
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() && !slices.Contains(dirs, match) {
+				dirs = append(dirs, match)
+			}
+		}
+	}
+	return dirs
+}
+
+// expandCleanPath expands ~ and the environment variables of path; it
+// reports false when a variable is not set, rather than cleaning a path
+// relative to the root
+func expandCleanPath(path string) (string, bool) {
+	complete := true
+	path = os.Expand(path, func(name string) string {
+		value := os.Getenv(name)
+		if value == "" {
+			complete = false
+		}
+		return value
+	})
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = home + path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path)), complete && path != ""
+}
+
+// FindCleanItems lists the entries of the target directories of profile, with
+// the size they take, largest first. With olderThan, or the longer MinAge of
+// the profile, only the entries not modified for that long at now are listed.
+// Entries matching a Keep pattern of the profile, sockets, pipes and devices
+// are never listed, nor in Shared profiles the entries of root or other users.
+func FindCleanItems(ctx context.Context, profile CleanProfile, olderThan time.Duration, now time.Time) ([]CleanItem, error) {
+	if profile.MinAge != "" {
+		minAge, err := ParseAge(profile.MinAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_age of clean profile %s: %w", profile.Name, err)
+		}
+		olderThan = max(olderThan, minAge)
+	}
+	var items []CleanItem
+	for _, dir := range profile.TargetDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return items, err
+			}
+			if keepCleanEntry(profile, entry.Name()) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || info.Mode()&(fs.ModeSocket|fs.ModeNamedPipe|fs.ModeDevice) != 0 || profile.Shared && ownedByOthers(info) {
+				continue
+			}
+			if olderThan > 0 && now.Sub(info.ModTime()) <= olderThan {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			size := info.Size()
+			if entry.IsDir() {
+				size = pathSize(path)
+			}
+			items = append(items, CleanItem{Profile: profile.Name, Target: dir, Path: path, Size: size, ModTime: info.ModTime()})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Size > items[j].Size })
+	return items, nil
+}
+
+// keepCleanEntry reports whether name matches a Keep pattern of profile
+func keepCleanEntry(profile CleanProfile, name string) bool {
+	return slices.ContainsFunc(profile.Keep, func(pattern string) bool {
+		ok, _ := filepath.Match(pattern, name)
+		return ok
+	})
+}
+
+// RemoveCleanItems deletes the items, going on after a failure and leaving
+// the ones protected by guard. Read-only directories, such as the Go module
+// cache, are made writable first. It returns the removed items and the joined
+// errors of the others.
+func RemoveCleanItems(ctx context.Context, items []CleanItem, guard *PathGuard) ([]CleanItem, error) {
+	var removed []CleanItem
+	var errs []error
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := guard.Check(item.Path); err != nil {
+			errs = append(errs, fmt.Errorf("refusing to remove %w", err))
+			continue
+		}
+		if err := removeWritable(item.Path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", item.Path, err))
+			continue
+		}
+		removed = append(removed, item)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// removeWritable removes path like os.RemoveAll, adding the write permission
+// to the read-only directories below it when a first attempt fails
+func removeWritable(path string) error {
+	if err := os.RemoveAll(path); err == nil || !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	_ = filepath.WalkDir(path, func(dir string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				_ = os.Chmod(dir, info.Mode().Perm()|0o700)
+			}
+		}
+		return nil
+	})
+	return os.RemoveAll(path)
+}
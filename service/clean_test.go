@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewCleanProfiles(t *testing.T) {
+	profiles, err := NewCleanProfiles(CleanConfig{Profiles: []CleanProfile{
+		{Name: "temp", Targets: []CleanTarget{{Path: "~/scratch"}}},
+		{Name: "logs", Targets: []CleanTarget{{Path: "/var/log/app"}}},
+	}})
+	if err != nil {
+		t.Fatalf("NewCleanProfiles() error = %v", err)
+	}
+	temp, err := FindCleanProfile(profiles, "temp")
+	if err != nil {
+		t.Fatalf("FindCleanProfile(temp) error = %v", err)
+	}
+	if last := temp.Targets[len(temp.Targets)-1]; last.Path != "~/scratch" {
+		t.Errorf("temp targets end with %+v, want the configured ~/scratch", last)
+	}
+	for _, name := range []string{"browser-cache", "package-cache", "logs"} {
+		if _, err := FindCleanProfile(profiles, name); err != nil {
+			t.Errorf("FindCleanProfile(%s) error = %v", name, err)
+		}
+	}
+	if _, err := FindCleanProfile(profiles, "nope"); err == nil {
+		t.Error("FindCleanProfile(nope) succeeded, want an error")
+	}
+
+	if _, err := NewCleanProfiles(CleanConfig{Profiles: []CleanProfile{{Name: "bad", Targets: []CleanTarget{{Path: "[x"}}}}}); err == nil {
+		t.Error("NewCleanProfiles() accepted a malformed pattern")
+	}
+}
+
+func TestFindAndRemoveCleanItems(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GOKTOR_CLEAN_TEST", root)
+	t.Setenv("GOKTOR_CLEAN_UNSET", "")
+	cache := filepath.Join(root, "cache")
+	write := func(path string, size int, age time.Duration) {
+		t.Helper()
+		full := filepath.Join(cache, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(full), err)
+		}
+		if err := os.WriteFile(full, make([]byte, size), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(full, modTime, modTime); err != nil {
+			t.Fatalf("failed to set the time of %s: %v", path, err)
+		}
+	}
+	write("old.bin", 300, 48*time.Hour)
+	write("new.bin", 100, 0)
+	write("mod/pkg/file.go", 50, 0)
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(cache, "mod"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	// read-only like the Go module cache
+	if err := os.Chmod(filepath.Join(cache, "mod", "pkg"), 0555); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := CleanProfile{Name: "test", Targets: []CleanTarget{
+		{Path: "${GOKTOR_CLEAN_TEST}/cach?"},
+		{Path: "${GOKTOR_CLEAN_UNSET}/tmp"},
+		{Path: "${GOKTOR_CLEAN_TEST}/cache", OS: []string{"plan9"}},
+	}}
+	if dirs := profile.TargetDirs(); len(dirs) != 1 || dirs[0] != cache {
+		t.Fatalf("TargetDirs() = %v, want [%s]", dirs, cache)
+	}
+
+	items, err := FindCleanItems(context.Background(), profile, 0, time.Now())
+	if err != nil {
+		t.Fatalf("FindCleanItems() error = %v", err)
+	}
+	if len(items) != 3 || items[0].Size != 300 || items[2].Size != 50 {
+		t.Fatalf("FindCleanItems() = %+v, want 3 entries largest first", items)
+	}
+
+	items, err = FindCleanItems(context.Background(), profile, 24*time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("FindCleanItems() error = %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("FindCleanItems(24h) = %+v, want old.bin and mod", items)
+	}
+
+	guard, err := NewPathGuard(SafetyConfig{ProtectedPaths: []string{filepath.Join(cache, "old.bin")}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := RemoveCleanItems(context.Background(), items, guard)
+	if !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("RemoveCleanItems() error = %v, want ErrProtectedPath", err)
+	}
+	if len(removed) != 1 || filepath.Base(removed[0].Path) != "mod" {
+		t.Fatalf("RemoveCleanItems() removed %+v, want mod", removed)
+	}
+	for path, want := range map[string]bool{"old.bin": true, "new.bin": true, "mod": false} {
+		if _, err := os.Stat(filepath.Join(cache, path)); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", path, err == nil, want)
+		}
+	}
+}
+
+func TestFindCleanItems_Kept(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GOKTOR_CLEAN_TEST", root)
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"ssh-agent1", "old.tmp", "new.tmp"} {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if name != "new.tmp" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatalf("failed to set the time of %s: %v", name, err)
+			}
+		}
+	}
+	if runtime.GOOS != "windows" {
+		listener, err := net.Listen("unix", filepath.Join(root, "agent.sock"))
+		if err != nil {
+			t.Fatalf("failed to create socket: %v", err)
+		}
+		defer listener.Close()
+		if err := os.Chtimes(filepath.Join(root, "agent.sock"), old, old); err != nil {
+			t.Fatalf("failed to set the time of the socket: %v", err)
+		}
+	}
+
+	profile := CleanProfile{Name: "test", MinAge: "1d", Keep: []string{"ssh-*"}, Targets: []CleanTarget{{Path: "${GOKTOR_CLEAN_TEST}"}}}
+	items, err := FindCleanItems(context.Background(), profile, time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("FindCleanItems() error = %v", err)
+	}
+	if len(items) != 1 || filepath.Base(items[0].Path) != "old.tmp" {
+		t.Errorf("FindCleanItems() = %+v, want only old.tmp", items)
+	}
+
+	// the test files belong to the current user, which may be root
+	profile.Shared = true
+	items, err = FindCleanItems(context.Background(), profile, 0, time.Now())
+	if err != nil {
+		t.Fatalf("FindCleanItems() error = %v", err)
+	}
+	want := runtime.GOOS == "windows" || os.Getuid() != 0
+	if got := slices.ContainsFunc(items, func(item CleanItem) bool { return filepath.Base(item.Path) == "old.tmp" }); got != want {
+		t.Errorf("FindCleanItems() of a shared profile = %+v, want old.tmp listed = %v", items, want)
+	}
+}
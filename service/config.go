@@ -0,0 +1,111 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the user configuration read from ~/.goktor/config.json.
+// Every section is optional; missing values keep the built-in defaults.
+type Config struct {
+	Scan ScanConfig `json:"scan"`
+	// Identity is the author of the commits goktor creates; empty fields fall
+	// back to the git config of each repository
+	Identity Identity `json:"identity"`
+	// Perms is the policy of the permission audit
+	Perms PermsConfig `json:"perms"`
+	// DevClean adds build and dependency directories to the dev-clean rules
+	DevClean DevCleanConfig `json:"dev_clean"`
+	// Clean adds profiles and targets to the clean profiles
+	Clean CleanConfig `json:"clean"`
+	// Safety adds paths the destructive commands refuse to touch
+	Safety SafetyConfig `json:"safety"`
+	// Branches lists the branches the mr-repo commands never delete or hard-reset
+	Branches BranchesConfig `json:"branches"`
+	// Hooks are shell commands the mr-repo commands run around every repository
+	Hooks HooksConfig `json:"hooks"`
+	// Daemon sets the interval and jobs of goktor daemon
+	Daemon DaemonConfig `json:"daemon"`
+	// Credentials authenticate the remotes per host, the first match winning
+	Credentials []Credential `json:"credentials,omitempty"`
+	// Proxy routes the HTTP(S) remotes through a proxy; empty fields fall
+	// back to HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Proxy ProxyConfig `json:"proxy"`
+	// Workspaces are named groups of repository paths or glob patterns, such
+	// as "~/oss/*", selected with --workspace
+	Workspaces map[string][]string `json:"workspaces,omitempty"`
+}
+
+// ScanConfig tunes the directory scanners
+type ScanConfig struct {
+	// Storage forces the storage type used to size scanner concurrency:
+	// "ssd", "hdd", "network", or "auto" (default) to detect it
+	Storage string `json:"storage,omitempty"`
+	// Workers is the number of directories read at a time, as --workers
+	// sets it; 0 sizes it from the storage type
+	Workers int `json:"workers,omitempty"`
+	// Cache reuses the previous scan of the directories that did not change,
+	// as --cache does
+	Cache bool `json:"cache,omitempty"`
+}
+
+// DefaultConfigPath returns ~/.goktor/config.json
+func DefaultConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goktor", "config.json"), nil
+}
+
+// LoadConfig reads the configuration at path. A missing file yields an empty config.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if _, err := ParseStorageType(cfg.Scan.Storage); err != nil {
+		return nil, fmt.Errorf("invalid scan.storage in %s: %w", path, err)
+	}
+	if cfg.Scan.Workers < 0 {
+		return nil, fmt.Errorf("invalid scan.workers in %s: %d is negative", path, cfg.Scan.Workers)
+	}
+	if _, err := NewPermsPolicy(cfg.Perms); err != nil {
+		return nil, fmt.Errorf("invalid perms.fix in %s: %w", path, err)
+	}
+	if err := ValidateBranchPatterns(cfg.Branches.Protected); err != nil {
+		return nil, fmt.Errorf("invalid branches.protected in %s: %w", path, err)
+	}
+	if err := ValidateCredentials(cfg.Credentials); err != nil {
+		return nil, fmt.Errorf("invalid credentials in %s: %w", path, err)
+	}
+	if err := cfg.Proxy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid proxy in %s: %w", path, err)
+	}
+	if err := ValidateWorkspaces(cfg.Workspaces); err != nil {
+		return nil, fmt.Errorf("invalid workspaces in %s: %w", path, err)
+	}
+	if _, err := NewDevCleanRules(cfg.DevClean); err != nil {
+		return nil, fmt.Errorf("invalid dev_clean.rules in %s: %w", path, err)
+	}
+	if _, err := NewCleanProfiles(cfg.Clean); err != nil {
+		return nil, fmt.Errorf("invalid clean.profiles in %s: %w", path, err)
+	}
+	if err := cfg.Daemon.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid daemon in %s: %w", path, err)
+	}
+	if err := ValidateProtectedPaths(cfg.Safety.ProtectedPaths); err != nil {
+		return nil, fmt.Errorf("invalid safety.protected_paths in %s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadConfig() missing file error = %v", err)
+	}
+	if cfg.Scan.Storage != "" {
+		t.Errorf("missing file config = %+v, want empty", cfg)
+	}
+
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte(`{"scan": {"storage": "hdd"}}`), 0644)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Scan.Storage != "hdd" {
+		t.Errorf("Scan.Storage = %q, want hdd", cfg.Scan.Storage)
+	}
+
+	os.WriteFile(path, []byte(`{"scan": {"storage": "floppy"}}`), 0644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for invalid storage")
+	}
+
+	os.WriteFile(path, []byte(`{"branches": {"protected": ["release/[0-9"]}}`), 0644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for invalid protected branch pattern")
+	}
+
+	os.WriteFile(path, []byte(`{"credentials": [{"host": "github.com"}]}`), 0644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for a credential without token_env or ssh_key")
+	}
+
+	os.WriteFile(path, []byte(`{`), 0644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for malformed config")
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// Credential authenticates the remotes whose host matches Host, so a
+// workspace mixing hosts uses the right account for every repository
+type Credential struct {
+	// Host is the host name of the remotes, or a path.Match pattern such as
+	// "*.mycorp.com"
+	Host string `json:"host"`
+	// Username is sent with the token, and is the SSH user; it defaults to the
+	// user of the remote URL, then to "git"
+	Username string `json:"username,omitempty"`
+	// TokenEnv names the environment variable holding the token or password
+	// of the HTTPS remotes, so the config file holds no secret
+	TokenEnv string `json:"token_env,omitempty"`
+	// AllowHTTP sends the token to plain http:// remotes too, where it
+	// travels unencrypted; only for servers on a trusted network
+	AllowHTTP bool `json:"allow_http,omitempty"`
+	// SSHKey is the private key file of the SSH remotes
+	SSHKey string `json:"ssh_key,omitempty"`
+}
+
+// ValidateCredentials checks the host patterns of the credentials and that
+// each of them authenticates something
+func ValidateCredentials(credentials []Credential) error {
+	for i, credential := range credentials {
+		if credential.Host == "" {
+			return fmt.Errorf("credential %d has no host", i+1)
+		}
+		if _, err := path.Match(credential.Host, ""); err != nil {
+			return fmt.Errorf("credential %d: invalid host pattern %q: %w", i+1, credential.Host, err)
+		}
+		if credential.TokenEnv == "" && credential.SSHKey == "" {
+			return fmt.Errorf("credential for %s needs token_env or ssh_key", credential.Host)
+		}
+	}
+	return nil
+}
+
+// matchCredential returns the first credential whose pattern matches host
+func matchCredential(credentials []Credential, host string) (Credential, bool) {
+	host = strings.ToLower(host)
+	for _, credential := range credentials {
+		if ok, _ := path.Match(strings.ToLower(credential.Host), host); ok {
+			return credential, true
+		}
+	}
+	return Credential{}, false
+}
+
+// credentialsAuth returns the source of the credentials of the config. The
+// first credential matching the remote host is used; it offers nothing when
+// it has no secret for the protocol of the remote, leaving it to the next
+// sources. Tokens go to plain http:// remotes only with AllowHTTP.
+func credentialsAuth(credentials []Credential) authSource {
+	return func(_ context.Context, endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+		credential, ok := matchCredential(credentials, endpoint.Host)
+		if !ok {
+			return nil, nil
+		}
+		username := credential.Username
+		if username == "" {
+			username = endpoint.User
+		}
+		if username == "" {
+			username = "git"
+		}
+
+		switch endpoint.Protocol {
+		case "http", "https":
+			if credential.TokenEnv == "" || endpoint.Protocol == "http" && !credential.AllowHTTP {
+				return nil, nil
+			}
+			token := os.Getenv(credential.TokenEnv)
+			if token == "" {
+				return nil, fmt.Errorf("credential for %s: environment variable %s is not set", endpoint.Host, credential.TokenEnv)
+			}
+			return &githttp.BasicAuth{Username: username, Password: token}, nil
+
+		case "ssh":
+			if credential.SSHKey == "" {
+				return nil, nil
+			}
+			keyFile, err := expandHome(credential.SSHKey)
+			if err != nil {
+				return nil, err
+			}
+			auth, err := gitssh.NewPublicKeysFromFile(username, keyFile, "")
+			if err != nil {
+				return nil, fmt.Errorf("credential for %s: failed to read SSH key %s: %w", endpoint.Host, keyFile, err)
+			}
+			return auth, nil
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials []Credential
+		wantErr     bool
+	}{
+		{name: "token", credentials: []Credential{{Host: "github.com", TokenEnv: "GITHUB_TOKEN"}}},
+		{name: "pattern", credentials: []Credential{{Host: "*.mycorp.com", SSHKey: "~/.ssh/id_work"}}},
+		{name: "no host", credentials: []Credential{{TokenEnv: "TOKEN"}}, wantErr: true},
+		{name: "bad pattern", credentials: []Credential{{Host: "[mycorp.com", TokenEnv: "TOKEN"}}, wantErr: true},
+		{name: "no secret", credentials: []Credential{{Host: "github.com", Username: "octocat"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCredentials(tt.credentials); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCredentialsAuth(t *testing.T) {
+	t.Setenv("GOKTOR_TEST_GITHUB_TOKEN", "gh-token")
+	t.Setenv("GOKTOR_TEST_CORP_TOKEN", "corp-token")
+	source := credentialsAuth([]Credential{
+		{Host: "github.com", TokenEnv: "GOKTOR_TEST_GITHUB_TOKEN"},
+		{Host: "*.mycorp.com", Username: "deploy", TokenEnv: "GOKTOR_TEST_CORP_TOKEN"},
+		{Host: "gitlab.com", TokenEnv: "GOKTOR_TEST_UNSET_TOKEN"},
+		{Host: "git.lan", TokenEnv: "GOKTOR_TEST_CORP_TOKEN", AllowHTTP: true},
+	})
+
+	tests := []struct {
+		url          string
+		wantUser     string
+		wantPassword string
+		wantNil      bool
+		wantErr      bool
+	}{
+		{url: "https://github.com/org/repo.git", wantUser: "git", wantPassword: "gh-token"},
+		{url: "https://octocat@GitHub.com/org/repo.git", wantUser: "octocat", wantPassword: "gh-token"},
+		{url: "https://gitlab.mycorp.com/team/repo.git", wantUser: "deploy", wantPassword: "corp-token"},
+		{url: "https://bitbucket.org/team/repo.git", wantNil: true},
+		{url: "git@github.com:org/repo.git", wantNil: true},
+		{url: "https://gitlab.com/team/repo.git", wantErr: true},
+		{url: "http://github.com/org/repo.git", wantNil: true},
+		{url: "http://git.lan/team/repo.git", wantUser: "git", wantPassword: "corp-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			endpoint, err := transport.NewEndpoint(tt.url)
+			if err != nil {
+				t.Fatalf("NewEndpoint() error = %v", err)
+			}
+			auth, err := source(context.Background(), endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("credentialsAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantNil {
+				if auth != nil {
+					t.Errorf("credentialsAuth() = %v, want nil", auth)
+				}
+				return
+			}
+			basic, ok := auth.(*githttp.BasicAuth)
+			if !ok || basic.Username != tt.wantUser || basic.Password != tt.wantPassword {
+				t.Errorf("credentialsAuth() = %v, want basic auth %s/%s", auth, tt.wantUser, tt.wantPassword)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Kinds of daemon jobs
+const (
+	// DaemonJobFetch fetches origin of every repository of a workspace of the
+	// config or of a directory
+	DaemonJobFetch = "fetch"
+	// DaemonJobScan scans a directory to refresh the scan cache of du and
+	// folder-list
+	DaemonJobScan = "scan"
+)
+
+// DefaultDaemonInterval is the time between two daemon runs when neither
+// --every nor daemon.every is set
+const DefaultDaemonInterval = 30 * time.Minute
+
+// DaemonJob is an operation the daemon runs on every tick
+type DaemonJob struct {
+	Kind string `json:"kind"`
+	// Workspace is the workspace of the config a fetch job works on
+	Workspace string `json:"workspace,omitempty"`
+	// Dir is the directory a scan job scans, or holding the repositories of
+	// a fetch job; a leading ~ is the home directory
+	Dir string `json:"dir,omitempty"`
+}
+
+// Target returns the workspace or directory the job works on
+func (j DaemonJob) Target() string {
+	if j.Workspace != "" {
+		return "workspace " + j.Workspace
+	}
+	return j.Dir
+}
+
+// Directory returns Dir with a leading ~ expanded
+func (j DaemonJob) Directory() (string, error) {
+	return expandHome(j.Dir)
+}
+
+// Validate reports a job of unknown kind or without its target
+func (j DaemonJob) Validate() error {
+	switch j.Kind {
+	case DaemonJobFetch:
+		if (j.Workspace == "") == (j.Dir == "") {
+			return fmt.Errorf("fetch job needs either a workspace or a dir")
+		}
+	case DaemonJobScan:
+		if j.Dir == "" || j.Workspace != "" {
+			return fmt.Errorf("scan job needs a dir")
+		}
+	default:
+		return fmt.Errorf("unknown job kind %q, expected %q or %q", j.Kind, DaemonJobFetch, DaemonJobScan)
+	}
+	return nil
+}
+
+// DaemonConfig is the "daemon" section of the configuration
+type DaemonConfig struct {
+	// Every is the time between two runs, such as "30m" or "2h"
+	Every string `json:"every,omitempty"`
+	// StatusFile receives the result of the last run as JSON
+	StatusFile string `json:"status_file,omitempty"`
+	// Jobs run in order on every tick
+	Jobs []DaemonJob `json:"jobs,omitempty"`
+}
+
+// Interval returns Every, DefaultDaemonInterval when it is not set
+func (c DaemonConfig) Interval() (time.Duration, error) {
+	if c.Every == "" {
+		return DefaultDaemonInterval, nil
+	}
+	every, err := ParseAge(c.Every)
+	if err != nil {
+		return 0, err
+	}
+	if every < time.Minute {
+		return 0, fmt.Errorf("interval %s is shorter than a minute", every)
+	}
+	return every, nil
+}
+
+// StatusPath returns StatusFile with a leading ~ expanded, empty when no
+// status file is written
+func (c DaemonConfig) StatusPath() (string, error) {
+	if c.StatusFile == "" {
+		return "", nil
+	}
+	return expandHome(c.StatusFile)
+}
+
+// Validate checks the interval and the jobs
+func (c DaemonConfig) Validate() error {
+	if _, err := c.Interval(); err != nil {
+		return err
+	}
+	for i, job := range c.Jobs {
+		if err := job.Validate(); err != nil {
+			return fmt.Errorf("job %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// DaemonJobStatus is the outcome of a job in the last run
+type DaemonJobStatus struct {
+	Kind     string        `json:"kind"`
+	Target   string        `json:"target"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	// Done and Failed count the repositories fetched or the scan caches refreshed
+	Done   int    `json:"done"`
+	Failed int    `json:"failed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DaemonStatus is written to the status file after every run
+type DaemonStatus struct {
+	PID     int               `json:"pid"`
+	Started time.Time         `json:"started"`
+	Every   time.Duration     `json:"every"`
+	Runs    int               `json:"runs"`
+	LastRun time.Time         `json:"last_run"`
+	NextRun time.Time         `json:"next_run,omitzero"`
+	Jobs    []DaemonJobStatus `json:"jobs"`
+	// Failures counts the jobs of the last run that failed
+	Failures int `json:"failures"`
+}
+
+// WriteDaemonStatus writes status to path as JSON, replacing the previous
+// status atomically so readers never see a partial file
+func WriteDaemonStatus(path string, status DaemonStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode daemon status: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create status directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write daemon status: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write daemon status: %w", err)
+	}
+	return nil
+}
+
+// RunEvery calls run at once, then every interval until ctx is cancelled. A
+// run longer than interval delays the next one rather than overlapping it.
+func RunEvery(ctx context.Context, interval time.Duration, run func(ctx context.Context, next time.Time)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		run(ctx, time.Now().Add(interval))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
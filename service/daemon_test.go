@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDaemonConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  DaemonConfig
+		wantErr bool
+	}{
+		{"defaults", DaemonConfig{}, false},
+		{"jobs", DaemonConfig{Every: "2h", Jobs: []DaemonJob{
+			{Kind: DaemonJobFetch, Workspace: "work"},
+			{Kind: DaemonJobFetch, Dir: "~/src"},
+			{Kind: DaemonJobScan, Dir: "~"},
+		}}, false},
+		{"too frequent", DaemonConfig{Every: "10s"}, true},
+		{"bad interval", DaemonConfig{Every: "often"}, true},
+		{"unknown kind", DaemonConfig{Jobs: []DaemonJob{{Kind: "gc", Dir: "/"}}}, true},
+		{"fetch without target", DaemonConfig{Jobs: []DaemonJob{{Kind: DaemonJobFetch}}}, true},
+		{"fetch with both targets", DaemonConfig{Jobs: []DaemonJob{{Kind: DaemonJobFetch, Workspace: "w", Dir: "/"}}}, true},
+		{"scan of a workspace", DaemonConfig{Jobs: []DaemonJob{{Kind: DaemonJobScan, Workspace: "w"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if every, _ := (DaemonConfig{}).Interval(); every != DefaultDaemonInterval {
+		t.Errorf("Interval() = %s, want %s", every, DefaultDaemonInterval)
+	}
+}
+
+func TestWriteDaemonStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "daemon.json")
+	status := DaemonStatus{PID: 42, Runs: 3, Jobs: []DaemonJobStatus{{Kind: DaemonJobScan, Target: "/data", Done: 2}}}
+	if err := WriteDaemonStatus(path, status); err != nil {
+		t.Fatalf("WriteDaemonStatus() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got DaemonStatus
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid status file: %v", err)
+	}
+	if got.PID != 42 || got.Runs != 3 || len(got.Jobs) != 1 || got.Jobs[0].Done != 2 {
+		t.Errorf("status = %+v, want %+v", got, status)
+	}
+}
+
+func TestRunEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	RunEvery(ctx, time.Millisecond, func(ctx context.Context, next time.Time) {
+		runs++
+		if runs == 3 {
+			cancel()
+		}
+	})
+	if runs != 3 {
+		t.Errorf("run called %d times, want 3", runs)
+	}
+}
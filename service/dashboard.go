@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// Health score penalties, deducted from 100
+const (
+	penaltyError       = 100
+	penaltyDirty       = 20
+	penaltyDetached    = 10
+	penaltyNoRemote    = 15
+	penaltyStaleBranch = 5
+	maxStalePenalty    = 30
+	penaltyOversized   = 10
+	penaltyInactive    = 10
+)
+
+// DashboardOptions tunes how repositories are scored
+type DashboardOptions struct {
+	// StaleAfter marks branches and repositories without commits for this long
+	StaleAfter time.Duration
+	// SizeLimit flags repositories whose checkout is larger than this many bytes
+	SizeLimit int64
+}
+
+// RepoHealth is the scored summary of one repository
+type RepoHealth struct {
+	Name   string
+	Path   string
+	Status *RepoStatus
+	Size   int64
+	// Score goes from 0 (broken) to 100 (healthy)
+	Score  int
+	Issues []string
+}
+
+// Dashboard is the scored summary of a workspace
+type Dashboard struct {
+	GeneratedAt time.Time
+	Repos       []RepoHealth
+	// Score is the average score of the repositories
+	Score int
+}
+
+// BuildDashboard inspects every repository in repoDirs, skipping directories
+// that are not git repositories, and scores its health.
+func BuildDashboard(ctx context.Context, gs GitService, fs FileService, repoDirs []string, opts DashboardOptions) (*Dashboard, error) {
+	dashboard := &Dashboard{GeneratedAt: time.Now()}
+
+	total := 0
+	for _, repoPath := range repoDirs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		health := RepoHealth{Name: filepath.Base(repoPath), Path: repoPath}
+		status, err := gs.RepoStatus(ctx, repoPath, opts.StaleAfter, Options{})
+		if errors.Is(err, ErrNotARepository) {
+			continue
+		}
+		if err != nil {
+			health.Issues = append(health.Issues, err.Error())
+		} else {
+			health.Status = status
+			if dir, err := fs.ListDirectories(ctx, repoPath); err == nil {
+				health.Size = totalSize(dir)
+			}
+		}
+
+		scoreRepo(&health, opts)
+		total += health.Score
+		dashboard.Repos = append(dashboard.Repos, health)
+	}
+
+	if len(dashboard.Repos) > 0 {
+		dashboard.Score = total / len(dashboard.Repos)
+	}
+	return dashboard, nil
+}
+
+// totalSize sums the files of a scanned directory tree
+func totalSize(dir model.Directory) int64 {
+	var size int64
+	for _, d := range dir.FlattenDirectory() {
+		size += d.Size
+	}
+	return size
+}
+
+// scoreRepo fills Score and Issues from the repository status
+func scoreRepo(health *RepoHealth, opts DashboardOptions) {
+	score := 100
+	status := health.Status
+	if status == nil {
+		score -= penaltyError
+	} else {
+		if status.Dirty {
+			score -= penaltyDirty
+			health.Issues = append(health.Issues, "uncommitted changes")
+		}
+		if status.Branch == "" {
+			score -= penaltyDetached
+			health.Issues = append(health.Issues, "detached HEAD")
+		}
+		if !status.HasRemote {
+			score -= penaltyNoRemote
+			health.Issues = append(health.Issues, "no origin remote")
+		}
+		if n := len(status.StaleBranches); n > 0 {
+			score -= min(n*penaltyStaleBranch, maxStalePenalty)
+			health.Issues = append(health.Issues, fmt.Sprintf("%d stale branches", n))
+		}
+		if opts.StaleAfter > 0 && time.Since(status.LastCommit) > opts.StaleAfter {
+			score -= penaltyInactive
+			health.Issues = append(health.Issues, "no recent commits")
+		}
+	}
+	if opts.SizeLimit > 0 && health.Size > opts.SizeLimit {
+		score -= penaltyOversized
+		health.Issues = append(health.Issues, fmt.Sprintf("larger than %s", model.FormatSize(opts.SizeLimit)))
+	}
+	health.Score = max(score, 0)
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"size": model.FormatSize,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Goktor dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.good { background: #dff0d8; } .fair { background: #fcf8e3; } .poor { background: #f2dede; }
+</style>
+</head>
+<body>
+<h1>Workspace health: {{.Score}}/100</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04"}}</p>
+<table>
+<tr><th>Repository</th><th>Score</th><th>Branch</th><th>Stale branches</th><th>Size</th><th>Issues</th></tr>
+{{range .Repos}}<tr class="{{if ge .Score 80}}good{{else if ge .Score 50}}fair{{else}}poor{{end}}">
+<td title="{{.Path}}">{{.Name}}</td><td>{{.Score}}</td>
+<td>{{if .Status}}{{or .Status.Branch "(detached)"}}{{end}}</td>
+<td>{{if .Status}}{{len .Status.StaleBranches}}{{end}}</td>
+<td>{{size .Size}}</td>
+<td>{{range $i, $issue := .Issues}}{{if $i}}, {{end}}{{$issue}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteDashboardHTML renders the dashboard as a standalone HTML page
+func WriteDashboardHTML(w io.Writer, dashboard *Dashboard) error {
+	return dashboardTemplate.Execute(w, dashboard)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestScoreRepo(t *testing.T) {
+	opts := DashboardOptions{StaleAfter: 24 * time.Hour, SizeLimit: model.MiB}
+	tests := []struct {
+		name       string
+		health     RepoHealth
+		wantScore  int
+		wantIssues int
+	}{
+		{
+			name:      "healthy",
+			health:    RepoHealth{Status: &RepoStatus{Branch: "main", HasRemote: true, LastCommit: time.Now()}},
+			wantScore: 100,
+		},
+		{
+			name: "dirty with stale branches",
+			health: RepoHealth{Status: &RepoStatus{
+				Branch: "main", HasRemote: true, Dirty: true, LastCommit: time.Now(),
+				StaleBranches: map[string]string{"a": StaleReasonInactive, "b": PruneReasonUpstreamGone},
+			}},
+			wantScore:  70,
+			wantIssues: 2,
+		},
+		{
+			name: "many stale branches are capped",
+			health: RepoHealth{Status: &RepoStatus{
+				Branch: "main", HasRemote: true, LastCommit: time.Now(),
+				StaleBranches: map[string]string{"a": "", "b": "", "c": "", "d": "", "e": "", "f": "", "g": "", "h": ""},
+			}},
+			wantScore:  70,
+			wantIssues: 1,
+		},
+		{
+			name:       "detached, no remote, inactive and oversized",
+			health:     RepoHealth{Size: 2 * model.MiB, Status: &RepoStatus{LastCommit: time.Now().Add(-48 * time.Hour)}},
+			wantScore:  55,
+			wantIssues: 4,
+		},
+		{
+			name:       "unreadable",
+			health:     RepoHealth{Issues: []string{"failed to open repo"}},
+			wantScore:  0,
+			wantIssues: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scoreRepo(&tt.health, opts)
+			if tt.health.Score != tt.wantScore {
+				t.Errorf("Score = %d, want %d (issues %v)", tt.health.Score, tt.wantScore, tt.health.Issues)
+			}
+			if len(tt.health.Issues) != tt.wantIssues {
+				t.Errorf("Issues = %v, want %d", tt.health.Issues, tt.wantIssues)
+			}
+		})
+	}
+}
+
+func TestWriteDashboardHTML(t *testing.T) {
+	dashboard := &Dashboard{
+		GeneratedAt: time.Now(),
+		Score:       70,
+		Repos: []RepoHealth{
+			{Name: "api<script>", Score: 70, Status: &RepoStatus{}, Issues: []string{"uncommitted changes", "detached HEAD"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDashboardHTML(&buf, dashboard); err != nil {
+		t.Fatalf("WriteDashboardHTML() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Workspace health: 70/100", "api&lt;script&gt;", "(detached)", "uncommitted changes, detached HEAD"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML report missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DevCleanRule matches a build or dependency directory that can be recreated
+// from its project, such as node_modules next to a package.json
+type DevCleanRule struct {
+	Name string `json:"name"`
+	// Dir is the directory name, or a filepath.Match pattern such as "cmake-build-*"
+	Dir string `json:"dir"`
+	// Markers are the files of which at least one must sit next to the
+	// directory for it to match; any directory named Dir matches when empty
+	Markers []string `json:"markers,omitempty"`
+}
+
+// DefaultDevCleanRules are the well-known build and dependency directories
+var DefaultDevCleanRules = []DevCleanRule{
+	{Name: "node", Dir: "node_modules", Markers: []string{"package.json"}},
+	{Name: "rust", Dir: "target", Markers: []string{"Cargo.toml"}},
+	{Name: "maven", Dir: "target", Markers: []string{"pom.xml"}},
+	{Name: "venv", Dir: ".venv"},
+	{Name: "pycache", Dir: "__pycache__"},
+	{Name: "dist", Dir: "dist", Markers: []string{"package.json", "setup.py", "pyproject.toml"}},
+	{Name: "gradle", Dir: ".gradle", Markers: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}},
+	{Name: "build", Dir: "build", Markers: []string{"build.gradle", "build.gradle.kts", "CMakeLists.txt"}},
+}
+
+// DevCleanConfig is the "dev_clean" section of the configuration
+type DevCleanConfig struct {
+	// Rules are added to DefaultDevCleanRules; a rule named like a default one replaces it
+	Rules []DevCleanRule `json:"rules,omitempty"`
+}
+
+// DevArtifact is a directory matched by a dev-clean rule
+type DevArtifact struct {
+	// Project is the directory holding the artifact
+	Project string
+	Path    string
+	Rule    string
+	Size    int64
+}
+
+// DevProject groups the artifacts found in a project
+type DevProject struct {
+	Path      string
+	Artifacts []DevArtifact
+	Size      int64
+}
+
+// NewDevCleanRules merges the configured rules into the defaults
+func NewDevCleanRules(cfg DevCleanConfig) ([]DevCleanRule, error) {
+	rules := append([]DevCleanRule(nil), DefaultDevCleanRules...)
+	for _, rule := range cfg.Rules {
+		if rule.Name == "" || rule.Dir == "" {
+			return nil, fmt.Errorf("dev-clean rule %+v needs a name and a dir", rule)
+		}
+		if _, err := filepath.Match(rule.Dir, ""); err != nil || filepath.Base(rule.Dir) != rule.Dir {
+			return nil, fmt.Errorf("invalid dir %q of dev-clean rule %s", rule.Dir, rule.Name)
+		}
+		replaced := false
+		for i := range rules {
+			if rules[i].Name == rule.Name {
+				rules[i] = rule
+				replaced = true
+			}
+		}
+		if !replaced {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// matchDevCleanRule returns the rule matching the directory at path, if any
+func matchDevCleanRule(rules []DevCleanRule, path string) (DevCleanRule, bool) {
+	name := filepath.Base(path)
+	for _, rule := range rules {
+		if ok, _ := filepath.Match(rule.Dir, name); !ok {
+			continue
+		}
+		if len(rule.Markers) == 0 {
+			return rule, true
+		}
+		for _, marker := range rule.Markers {
+			if _, err := os.Stat(filepath.Join(filepath.Dir(path), marker)); err == nil {
+				return rule, true
+			}
+		}
+	}
+	return DevCleanRule{}, false
+}
+
+// FindDevArtifacts walks root and returns the directories matched by rules,
+// largest first. Matched directories are not descended into, nor are .git
+// directories; symlinks are not followed and unreadable directories are skipped.
+func FindDevArtifacts(ctx context.Context, root string, rules []DevCleanRule) ([]DevArtifact, error) {
+	var artifacts []DevArtifact
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		if rule, ok := matchDevCleanRule(rules, path); ok {
+			artifacts = append(artifacts, DevArtifact{
+				Project: filepath.Dir(path),
+				Path:    path,
+				Rule:    rule.Name,
+				Size:    pathSize(path),
+			})
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return artifacts, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	sort.SliceStable(artifacts, func(i, j int) bool { return artifacts[i].Size > artifacts[j].Size })
+	return artifacts, nil
+}
+
+// GroupDevArtifacts groups artifacts by project, largest project first
+func GroupDevArtifacts(artifacts []DevArtifact) []DevProject {
+	var projects []DevProject
+	index := map[string]int{}
+	for _, artifact := range artifacts {
+		i, ok := index[artifact.Project]
+		if !ok {
+			i = len(projects)
+			index[artifact.Project] = i
+			projects = append(projects, DevProject{Path: artifact.Project})
+		}
+		projects[i].Artifacts = append(projects[i].Artifacts, artifact)
+		projects[i].Size += artifact.Size
+	}
+	sort.SliceStable(projects, func(i, j int) bool { return projects[i].Size > projects[j].Size })
+	return projects
+}
+
+// RemoveDevArtifacts deletes the artifacts, going on after a failure and
+// leaving the ones protected by guard. It returns the removed artifacts and
+// the joined errors of the others.
+func RemoveDevArtifacts(ctx context.Context, artifacts []DevArtifact, guard *PathGuard) ([]DevArtifact, error) {
+	var removed []DevArtifact
+	var errs []error
+	for _, artifact := range artifacts {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := guard.Check(artifact.Path); err != nil {
+			errs = append(errs, fmt.Errorf("refusing to remove %w", err))
+			continue
+		}
+		if err := os.RemoveAll(artifact.Path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", artifact.Path, err))
+			continue
+		}
+		removed = append(removed, artifact)
+	}
+	return removed, errors.Join(errs...)
+}
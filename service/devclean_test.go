@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindDevArtifacts(t *testing.T) {
+	root := t.TempDir()
+	write := func(path string, size int) {
+		t.Helper()
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(full), err)
+		}
+		if err := os.WriteFile(full, make([]byte, size), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	write("web/package.json", 10)
+	write("web/node_modules/left-pad/index.js", 300)
+	write("web/node_modules/nested/node_modules/x.js", 50)
+	write("crate/Cargo.toml", 10)
+	write("crate/target/debug/bin", 200)
+	write("notes/target/keep.txt", 100) // no project file next to it
+	write("tool/cmake-build-debug/out.o", 70)
+	write("tool/CMakeLists.txt", 10)
+
+	rules, err := NewDevCleanRules(DevCleanConfig{Rules: []DevCleanRule{
+		{Name: "cmake", Dir: "cmake-build-*", Markers: []string{"CMakeLists.txt"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewDevCleanRules() error = %v", err)
+	}
+	artifacts, err := FindDevArtifacts(context.Background(), root, rules)
+	if err != nil {
+		t.Fatalf("FindDevArtifacts() error = %v", err)
+	}
+
+	want := []DevArtifact{
+		{Project: filepath.Join(root, "web"), Path: filepath.Join(root, "web", "node_modules"), Rule: "node", Size: 350},
+		{Project: filepath.Join(root, "crate"), Path: filepath.Join(root, "crate", "target"), Rule: "rust", Size: 200},
+		{Project: filepath.Join(root, "tool"), Path: filepath.Join(root, "tool", "cmake-build-debug"), Rule: "cmake", Size: 70},
+	}
+	if len(artifacts) != len(want) {
+		t.Fatalf("artifacts = %+v, want %+v", artifacts, want)
+	}
+	for i := range want {
+		if artifacts[i] != want[i] {
+			t.Errorf("artifacts[%d] = %+v, want %+v", i, artifacts[i], want[i])
+		}
+	}
+
+	projects := GroupDevArtifacts(artifacts)
+	if len(projects) != 3 || projects[0].Size != 350 {
+		t.Errorf("projects = %+v", projects)
+	}
+
+	guard, _ := NewPathGuard(SafetyConfig{}, false)
+	removed, err := RemoveDevArtifacts(context.Background(), artifacts, guard)
+	if err != nil || len(removed) != 3 {
+		t.Fatalf("RemoveDevArtifacts() = %d, %v", len(removed), err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "web", "node_modules")); !os.IsNotExist(err) {
+		t.Errorf("node_modules still exists")
+	}
+	if _, err := os.Stat(filepath.Join(root, "notes", "target", "keep.txt")); err != nil {
+		t.Errorf("unmatched target was touched: %v", err)
+	}
+}
+
+func TestNewDevCleanRules(t *testing.T) {
+	rules, err := NewDevCleanRules(DevCleanConfig{Rules: []DevCleanRule{{Name: "dist", Dir: "dist", Markers: []string{"vite.config.js"}}}})
+	if err != nil {
+		t.Fatalf("NewDevCleanRules() error = %v", err)
+	}
+	if len(rules) != len(DefaultDevCleanRules) {
+		t.Errorf("a rule named like a default one should replace it, got %d rules", len(rules))
+	}
+
+	for _, rule := range []DevCleanRule{
+		{Name: "", Dir: "out"},
+		{Name: "nested", Dir: "a/b"},
+		{Name: "pattern", Dir: "[build"},
+	} {
+		if _, err := NewDevCleanRules(DevCleanConfig{Rules: []DevCleanRule{rule}}); err == nil {
+			t.Errorf("NewDevCleanRules(%+v) should fail", rule)
+		}
+	}
+}
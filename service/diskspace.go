@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// spaceSafetyMargin is applied to size estimates, as checkouts and pack
+// indexes take more room than the provider-reported repository size
+const spaceSafetyMargin = 1.2
+
+// errDiskSpaceUnsupported is returned by freeDiskSpace on the platforms it
+// cannot read the free space of
+var errDiskSpaceUnsupported = errors.New("free disk space not supported on this platform")
+
+// CheckDiskSpace verifies the volume holding path has room for required bytes.
+// The check passes on the platforms where the free space cannot be read.
+func CheckDiskSpace(path string, required int64) error {
+	free, err := freeDiskSpace(path)
+	if errors.Is(err, errDiskSpaceUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read free space of %s: %w", path, err)
+	}
+
+	needed := int64(float64(required) * spaceSafetyMargin)
+	if int64(free) < needed {
+		return fmt.Errorf("not enough free space on %s: %s required, %s available",
+			path, model.FormatSize(needed), model.FormatSize(int64(free)))
+	}
+	return nil
+}
+
+// existingAncestor returns path, or its closest parent that exists, so the
+// free space of a directory about to be created can be read
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
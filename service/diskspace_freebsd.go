@@ -0,0 +1,17 @@
+//go:build freebsd || dragonfly
+
+package service
+
+import "golang.org/x/sys/unix"
+
+func freeDiskSpace(path string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	// Bavail is signed here: it goes negative once the reserved blocks are used
+	if stat.Bavail < 0 {
+		return 0, nil
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
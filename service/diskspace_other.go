@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !openbsd && !windows
+
+package service
+
+// freeDiskSpace cannot read the free space on this platform.
+func freeDiskSpace(string) (uint64, error) {
+	return 0, errDiskSpaceUnsupported
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := CheckDiskSpace(tmpDir, 1); err != nil {
+		t.Errorf("CheckDiskSpace() with 1 byte error = %v", err)
+	}
+	if err := CheckDiskSpace(tmpDir, 1<<62); err == nil {
+		t.Error("CheckDiskSpace() expected error for impossible requirement, got nil")
+	}
+	if err := CheckDiskSpace("/nonexistent/path", 1); err == nil {
+		t.Error("CheckDiskSpace() expected error for non-existent path, got nil")
+	}
+}
+
+func TestExistingAncestor(t *testing.T) {
+	tmpDir := t.TempDir()
+	if got := existingAncestor(filepath.Join(tmpDir, "a", "b")); got != tmpDir {
+		t.Errorf("existingAncestor() = %q, want %q", got, tmpDir)
+	}
+	if got := existingAncestor(tmpDir); got != tmpDir {
+		t.Errorf("existingAncestor() = %q, want %q", got, tmpDir)
+	}
+}
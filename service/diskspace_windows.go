@@ -0,0 +1,17 @@
+//go:build windows
+
+package service
+
+import "golang.org/x/sys/windows"
+
+func freeDiskSpace(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
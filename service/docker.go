@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Docker usage categories, in the order of docker system df
+const (
+	DockerImages     = "Images"
+	DockerContainers = "Containers"
+	DockerVolumes    = "Local Volumes"
+	DockerBuildCache = "Build Cache"
+)
+
+// DockerItem is an image, container, volume or build cache record
+type DockerItem struct {
+	ID   string
+	Name string
+	Size int64
+	// Active is set for running containers and the images, volumes and cache
+	// records they use
+	Active bool
+}
+
+// DockerCategory is the space used by one kind of Docker artifact
+type DockerCategory struct {
+	Name   string
+	Active int
+	Size   int64
+	// Reclaimable is the space freed by removing the unused artifacts, -1
+	// when unknown
+	Reclaimable int64
+	// Items are sorted by size, largest first
+	Items []DockerItem
+}
+
+// DockerUsage is the disk usage of a Docker installation
+type DockerUsage struct {
+	// Source is the Docker API address or the data root that was inspected
+	Source     string
+	Categories []DockerCategory
+}
+
+// dockerDF is the response of GET /system/df
+type dockerDF struct {
+	LayersSize int64
+	Images     []struct {
+		ID         string `json:"Id"`
+		RepoTags   []string
+		Size       int64
+		SharedSize int64
+		Containers int64
+	}
+	Containers []struct {
+		ID     string `json:"Id"`
+		Names  []string
+		Image  string
+		SizeRw int64
+		State  string
+	}
+	Volumes []struct {
+		Name      string
+		UsageData *struct {
+			Size     int64
+			RefCount int64
+		}
+	}
+	BuildCache []struct {
+		ID          string
+		Type        string
+		Description string
+		Size        int64
+		InUse       bool
+		Shared      bool
+	}
+}
+
+// DefaultDockerHost is the Docker API address used when DOCKER_HOST is not set
+func DefaultDockerHost() string {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return host
+	}
+	if runtime.GOOS == "windows" {
+		return "npipe:////./pipe/docker_engine"
+	}
+	return "unix:///var/run/docker.sock"
+}
+
+// DefaultDockerDataRoot is the data root of a Docker engine installed with
+// its defaults. Docker Desktop on macOS keeps it inside its virtual machine,
+// only reachable through the API.
+func DefaultDockerDataRoot() string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("ProgramData"), "docker")
+	case "darwin":
+		return ""
+	default:
+		return "/var/lib/docker"
+	}
+}
+
+// DockerClient queries the Docker Engine API
+type DockerClient struct {
+	host    string
+	baseURL string
+	client  *http.Client
+}
+
+// NewDockerClient connects to the Docker API at host, a unix:// socket or a
+// tcp:// or http:// address. Named pipes are not supported.
+func NewDockerClient(host string) (*DockerClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+	c := &DockerClient{host: host, client: &http.Client{Timeout: 2 * time.Minute}}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		c.baseURL = "http://docker"
+		c.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+	case "tcp", "http":
+		c.baseURL = "http://" + u.Host
+	case "https":
+		c.baseURL = "https://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host %q, expected unix://, tcp:// or http://", host)
+	}
+	return c, nil
+}
+
+// DiskUsage returns the space used by images, containers, volumes and build
+// cache, as reported by GET /system/df
+func (c *DockerClient) DiskUsage(ctx context.Context) (*DockerUsage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/system/df", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach docker at %s: %w", c.host, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from docker at %s", res.Status, c.host)
+	}
+	var df dockerDF
+	if err := json.NewDecoder(res.Body).Decode(&df); err != nil {
+		return nil, fmt.Errorf("failed to decode docker disk usage: %w", err)
+	}
+	usage := df.usage()
+	usage.Source = c.host
+	return usage, nil
+}
+
+// usage computes the categories the way docker system df does
+func (df dockerDF) usage() *DockerUsage {
+	images := DockerCategory{Name: DockerImages, Size: df.LayersSize}
+	var used int64
+	for _, image := range df.Images {
+		name := "<none>"
+		if len(image.RepoTags) > 0 {
+			name = image.RepoTags[0]
+		}
+		item := DockerItem{ID: shortDockerID(image.ID), Name: name, Size: image.Size, Active: image.Containers > 0}
+		if item.Active {
+			images.Active++
+			used += image.Size
+			if image.SharedSize > 0 {
+				used -= image.SharedSize
+			}
+		}
+		images.Items = append(images.Items, item)
+	}
+	images.Reclaimable = max(images.Size-used, 0)
+
+	containers := DockerCategory{Name: DockerContainers}
+	for _, container := range df.Containers {
+		name := shortDockerID(container.ID)
+		if len(container.Names) > 0 {
+			name = strings.TrimPrefix(container.Names[0], "/")
+		}
+		item := DockerItem{ID: shortDockerID(container.ID), Name: name, Size: container.SizeRw, Active: container.State == "running"}
+		containers.Size += item.Size
+		if item.Active {
+			containers.Active++
+		} else {
+			containers.Reclaimable += item.Size
+		}
+		containers.Items = append(containers.Items, item)
+	}
+
+	volumes := DockerCategory{Name: DockerVolumes}
+	for _, volume := range df.Volumes {
+		item := DockerItem{Name: volume.Name}
+		if volume.UsageData != nil {
+			item.Size = max(volume.UsageData.Size, 0)
+			item.Active = volume.UsageData.RefCount > 0
+		}
+		volumes.Size += item.Size
+		if item.Active {
+			volumes.Active++
+		} else {
+			volumes.Reclaimable += item.Size
+		}
+		volumes.Items = append(volumes.Items, item)
+	}
+
+	cache := DockerCategory{Name: DockerBuildCache}
+	for _, record := range df.BuildCache {
+		item := DockerItem{ID: shortDockerID(record.ID), Name: record.Type, Size: record.Size, Active: record.InUse}
+		if record.Description != "" {
+			item.Name = record.Description
+		}
+		if !record.Shared {
+			cache.Size += item.Size
+			if !item.Active {
+				cache.Reclaimable += item.Size
+			}
+		}
+		if item.Active {
+			cache.Active++
+		}
+		cache.Items = append(cache.Items, item)
+	}
+
+	usage := &DockerUsage{Categories: []DockerCategory{images, containers, volumes, cache}}
+	usage.sortItems()
+	return usage
+}
+
+// DockerDataRootUsage sizes the directories of a Docker data root, such as
+// /var/lib/docker, when the API cannot be reached. Image layers and the
+// writable layers of containers share the storage driver directory, which is
+// counted with the images. Active and reclaimable space are unknown.
+func DockerDataRootUsage(root string) (*DockerUsage, error) {
+	if root == "" {
+		return nil, fmt.Errorf("no docker data root on %s, use the docker API", runtime.GOOS)
+	}
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("failed to read docker data root: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("docker data root %s is not a directory", root)
+	}
+
+	dirCategory := func(name string, dirs ...string) DockerCategory {
+		category := DockerCategory{Name: name, Reclaimable: -1}
+		for _, dir := range dirs {
+			entries, _ := os.ReadDir(filepath.Join(root, dir))
+			for _, entry := range entries {
+				path := filepath.Join(root, dir, entry.Name())
+				size := pathSize(path)
+				category.Size += size
+				if entry.IsDir() {
+					category.Items = append(category.Items, DockerItem{ID: entry.Name(), Name: filepath.Join(dir, entry.Name()), Size: size})
+				}
+			}
+		}
+		return category
+	}
+
+	images := dirCategory(DockerImages, "image", "overlay2", "windowsfilter", "vfs", "btrfs", "zfs")
+	// the layer directories are no images, list the image configs instead
+	images.Items = nil
+	configs, _ := filepath.Glob(filepath.Join(root, "image", "*", "imagedb", "content", "sha256", "*"))
+	for _, config := range configs {
+		images.Items = append(images.Items, DockerItem{ID: shortDockerID(filepath.Base(config))})
+	}
+
+	usage := &DockerUsage{Source: root, Categories: []DockerCategory{
+		images,
+		dirCategory(DockerContainers, "containers"),
+		dirCategory(DockerVolumes, "volumes"),
+		dirCategory(DockerBuildCache, "buildkit"),
+	}}
+	usage.sortItems()
+	return usage, nil
+}
+
+func (u *DockerUsage) sortItems() {
+	for i := range u.Categories {
+		items := u.Categories[i].Items
+		sort.SliceStable(items, func(a, b int) bool { return items[a].Size > items[b].Size })
+	}
+}
+
+// Size is the space used by every category
+func (u *DockerUsage) Size() int64 {
+	var size int64
+	for _, category := range u.Categories {
+		size += category.Size
+	}
+	return size
+}
+
+// shortDockerID trims the digest algorithm and keeps 12 characters, like the
+// docker CLI
+func shortDockerID(id string) string {
+	if _, digest, ok := strings.Cut(id, ":"); ok {
+		id = digest
+	}
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const dockerDFResponse = `{
+  "LayersSize": 1000,
+  "Images": [
+    {"Id": "sha256:aaaaaaaaaaaaaaaaaaaa", "RepoTags": ["app:latest"], "Size": 600, "SharedSize": 100, "Containers": 1},
+    {"Id": "sha256:bbbbbbbbbbbbbbbbbbbb", "RepoTags": null, "Size": 400, "SharedSize": 100, "Containers": 0}
+  ],
+  "Containers": [
+    {"Id": "cccccccccccccccccccc", "Names": ["/web"], "SizeRw": 50, "State": "running"},
+    {"Id": "dddddddddddddddddddd", "Names": ["/old"], "SizeRw": 30, "State": "exited"}
+  ],
+  "Volumes": [
+    {"Name": "data", "UsageData": {"Size": 200, "RefCount": 1}},
+    {"Name": "orphan", "UsageData": {"Size": 70, "RefCount": 0}}
+  ],
+  "BuildCache": [
+    {"ID": "e1", "Type": "regular", "Size": 90, "InUse": false, "Shared": false},
+    {"ID": "e2", "Type": "regular", "Size": 40, "InUse": false, "Shared": true}
+  ]
+}`
+
+func TestDockerClient_DiskUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/system/df" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(dockerDFResponse))
+	}))
+	defer server.Close()
+
+	client, err := NewDockerClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("NewDockerClient() error = %v", err)
+	}
+	usage, err := client.DiskUsage(context.Background())
+	if err != nil {
+		t.Fatalf("DiskUsage() error = %v", err)
+	}
+
+	want := []struct {
+		name              string
+		items, active     int
+		size, reclaimable int64
+	}{
+		{DockerImages, 2, 1, 1000, 500},
+		{DockerContainers, 2, 1, 80, 30},
+		{DockerVolumes, 2, 1, 270, 70},
+		{DockerBuildCache, 2, 0, 90, 90},
+	}
+	if len(usage.Categories) != len(want) {
+		t.Fatalf("got %d categories, want %d", len(usage.Categories), len(want))
+	}
+	for i, w := range want {
+		got := usage.Categories[i]
+		if got.Name != w.name || len(got.Items) != w.items || got.Active != w.active || got.Size != w.size || got.Reclaimable != w.reclaimable {
+			t.Errorf("category %d = %s items=%d active=%d size=%d reclaimable=%d, want %+v",
+				i, got.Name, len(got.Items), got.Active, got.Size, got.Reclaimable, w)
+		}
+	}
+	if first := usage.Categories[0].Items[0]; first.ID != "aaaaaaaaaaaa" || first.Name != "app:latest" {
+		t.Errorf("largest image = %+v, want app:latest with a short ID", first)
+	}
+	if name := usage.Categories[1].Items[0].Name; name != "web" {
+		t.Errorf("largest container = %s, want web", name)
+	}
+
+	if _, err := NewDockerClient("ssh://host"); err == nil {
+		t.Error("NewDockerClient(ssh://) succeeded, want an error")
+	}
+}
+
+func TestDockerDataRootUsage(t *testing.T) {
+	root := t.TempDir()
+	write := func(path string, size int) {
+		t.Helper()
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("image/overlay2/imagedb/content/sha256/0123456789abcdef", 10)
+	write("overlay2/layer1/diff/bin", 500)
+	write("containers/abc/log.json", 20)
+	write("volumes/data/_data/db", 300)
+	write("volumes/metadata.db", 5)
+
+	usage, err := DockerDataRootUsage(root)
+	if err != nil {
+		t.Fatalf("DockerDataRootUsage() error = %v", err)
+	}
+	sizes := map[string]int64{DockerImages: 510, DockerContainers: 20, DockerVolumes: 305, DockerBuildCache: 0}
+	for _, category := range usage.Categories {
+		if category.Size != sizes[category.Name] || category.Reclaimable != -1 {
+			t.Errorf("%s size = %d reclaimable = %d, want %d and -1", category.Name, category.Size, category.Reclaimable, sizes[category.Name])
+		}
+	}
+	if images := usage.Categories[0].Items; len(images) != 1 || images[0].ID != "0123456789ab" {
+		t.Errorf("images = %+v, want the one image config", images)
+	}
+	if volumes := usage.Categories[2].Items; len(volumes) != 1 || volumes[0].ID != "data" {
+		t.Errorf("volumes = %+v, want data", volumes)
+	}
+
+	if _, err := DockerDataRootUsage(filepath.Join(root, "missing")); err == nil {
+		t.Error("DockerDataRootUsage() succeeded on a missing data root")
+	}
+}
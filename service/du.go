@@ -0,0 +1,69 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// DiskUsage is the total size of a directory, its own files and everything
+// below it, as printed by the du command
+type DiskUsage struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Size is the cumulative size of the directory, and Files and Dirs the
+	// number of files and directories below it
+	Size  int64 `json:"size"`
+	Files int64 `json:"files"`
+	Dirs  int64 `json:"dirs"`
+	// Allocated is the cumulative space taken on disk, zero unless the scan
+	// measured it
+	Allocated int64 `json:"allocated,omitempty"`
+	// Share is the percentage of the size of the parent directory, 100 for
+	// the root of the summary
+	Share float64 `json:"share"`
+	// DepthLimited is set when the scan did not read below the directory
+	DepthLimited bool `json:"depth_limited,omitempty"`
+	// Depth is 0 for the root of the summary
+	Depth int `json:"depth"`
+	// SubDirs are the subdirectories down to the requested depth, largest
+	// first; the ones below are only counted in Size
+	SubDirs []DiskUsage `json:"subdirs,omitempty"`
+}
+
+// SummarizeDiskUsage computes the cumulative size of root and of its
+// subdirectories, keeping the directories down to depth levels below root,
+// or all of them when depth is negative
+func SummarizeDiskUsage(root model.Directory, depth int) DiskUsage {
+	usage := summarizeDiskUsage(root, 0, depth)
+	usage.Share = 100
+	return usage
+}
+
+func summarizeDiskUsage(dir model.Directory, level int, depth int) DiskUsage {
+	usage := DiskUsage{Name: dir.Name, Path: dir.FullPath, Size: dir.Size, Files: dir.FileCount, Dirs: dir.DirCount, Allocated: dir.Allocated, DepthLimited: dir.DepthLimited, Depth: level}
+	for _, subDir := range dir.SubDirs {
+		sub := summarizeDiskUsage(subDir, level+1, depth)
+		usage.Size += sub.Size
+		usage.Allocated += sub.Allocated
+		if depth < 0 || level < depth {
+			usage.SubDirs = append(usage.SubDirs, sub)
+		}
+	}
+	for i := range usage.SubDirs {
+		if usage.Size > 0 {
+			usage.SubDirs[i].Share = float64(usage.SubDirs[i].Size) * 100 / float64(usage.Size)
+		}
+	}
+	sort.SliceStable(usage.SubDirs, func(i, j int) bool { return usage.SubDirs[i].Size > usage.SubDirs[j].Size })
+	return usage
+}
+
+// Flatten returns the directory followed by its subdirectories, depth first
+func (u DiskUsage) Flatten() []DiskUsage {
+	result := []DiskUsage{u}
+	for _, sub := range u.SubDirs {
+		result = append(result, sub.Flatten()...)
+	}
+	return result
+}
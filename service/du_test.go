@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestSummarizeDiskUsage(t *testing.T) {
+	dir := func(name string, size int64, subDirs ...model.Directory) model.Directory {
+		return model.Directory{FileSystem: model.FileSystem{Name: name, FullPath: "/" + name, Size: size, IsDir: true}, SubDirs: subDirs}
+	}
+	root := dir("root", 100,
+		dir("small", 100),
+		dir("big", 200, dir("deep", 300, dir("deeper", 100))),
+	)
+
+	usage := SummarizeDiskUsage(root, 1)
+	if usage.Size != 800 || usage.Share != 100 || usage.Depth != 0 {
+		t.Fatalf("root = %+v, want 800 bytes at 100%%", usage)
+	}
+	if len(usage.SubDirs) != 2 || usage.SubDirs[0].Name != "big" || usage.SubDirs[1].Name != "small" {
+		t.Fatalf("subdirs = %+v, want big then small", usage.SubDirs)
+	}
+	big := usage.SubDirs[0]
+	if big.Size != 600 || big.Share != 75 || big.Depth != 1 {
+		t.Errorf("big = %+v, want 600 bytes at 75%%", big)
+	}
+	if len(big.SubDirs) != 0 {
+		t.Errorf("big subdirs = %+v, want none beyond depth 1", big.SubDirs)
+	}
+
+	flat := SummarizeDiskUsage(root, 3).Flatten()
+	if len(flat) != 5 || flat[2].Name != "deep" || flat[2].Share != 400.0/6 {
+		t.Errorf("Flatten() = %+v, want every directory depth first", flat)
+	}
+	if zero := SummarizeDiskUsage(dir("empty", 0, dir("sub", 0)), 1); zero.SubDirs[0].Share != 0 {
+		t.Errorf("share of an empty parent = %v, want 0", zero.SubDirs[0].Share)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Errors the GitService operations return wrapped, to be tested with errors.Is
+var (
+	// ErrNotARepository is returned for a path that is not a git repository
+	ErrNotARepository = errors.New("not a git repository")
+	// ErrNoOriginRemote is returned when the remote of the operation, origin
+	// unless Options.Remote names another one, is not configured
+	ErrNoOriginRemote = errors.New("remote not configured")
+	// ErrDirtyWorktree is returned when uncommitted changes or untracked files
+	// prevent an operation, see SkipError
+	ErrDirtyWorktree = errors.New("uncommitted changes in the worktree")
+	// ErrAuthRequired is returned when the remote rejects missing or invalid
+	// credentials
+	ErrAuthRequired = errors.New("authentication required")
+	// ErrPartialFailure is returned by a batch command that ran to completion
+	// but failed on some repositories or paths
+	ErrPartialFailure = errors.New("completed with failures")
+)
+
+// Codes of the typed errors, recorded in RunRepo.ErrorCode
+const (
+	ErrorCodeNotARepository = "not_a_repository"
+	ErrorCodeNoRemote       = "no_remote"
+	ErrorCodeDirtyWorktree  = "dirty_worktree"
+	ErrorCodeAuthRequired   = "auth_required"
+)
+
+// ErrorCode returns the code of the typed error wrapped in err, or an empty
+// string when err is not one of them
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNotARepository):
+		return ErrorCodeNotARepository
+	case errors.Is(err, ErrNoOriginRemote):
+		return ErrorCodeNoRemote
+	case errors.Is(err, ErrDirtyWorktree):
+		return ErrorCodeDirtyWorktree
+	case errors.Is(err, ErrAuthRequired):
+		return ErrorCodeAuthRequired
+	}
+	return ""
+}
+
+// SkipError returns the typed error behind the skip reason of a result:
+// ErrDirtyWorktree for SkipReasonDirtyWorktree, nil for the other reasons.
+// Batch commands record it with the skipped repository, so its ErrorCode is kept.
+func SkipError(reason string) error {
+	if reason == SkipReasonDirtyWorktree {
+		return ErrDirtyWorktree
+	}
+	return nil
+}
+
+// typedError wraps the go-git errors matching one of the typed errors with
+// it, keeping the original error in the chain
+func typedError(err error) error {
+	var typed error
+	switch {
+	case err == nil || ErrorCode(err) != "":
+		return err
+	case errors.Is(err, git.ErrRepositoryNotExists):
+		typed = ErrNotARepository
+	case errors.Is(err, git.ErrRemoteNotFound):
+		typed = ErrNoOriginRemote
+	case errors.Is(err, git.ErrUnstagedChanges), errors.Is(err, git.ErrWorktreeNotClean):
+		typed = ErrDirtyWorktree
+	case isAuthError(err):
+		typed = ErrAuthRequired
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", typed, err)
+}
+
+// openRepo opens the repository at repoPath, failing with ErrNotARepository
+// when there is none
+func openRepo(repoPath string) (*git.Repository, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repo: %w", typedError(err))
+	}
+	return repo, nil
+}
+
+// getRemote returns the named remote of repo, failing with ErrNoOriginRemote
+// when it is not configured
+func getRemote(repo *git.Repository, remoteName string) (*git.Remote, error) {
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s remote: %w", remoteName, typedError(err))
+	}
+	return remote, nil
+}
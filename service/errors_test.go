@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestTypedError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+		code string
+	}{
+		{git.ErrRepositoryNotExists, ErrNotARepository, ErrorCodeNotARepository},
+		{git.ErrRemoteNotFound, ErrNoOriginRemote, ErrorCodeNoRemote},
+		{git.ErrUnstagedChanges, ErrDirtyWorktree, ErrorCodeDirtyWorktree},
+		{fmt.Errorf("push: %w", transport.ErrAuthenticationRequired), ErrAuthRequired, ErrorCodeAuthRequired},
+		{errors.New("other"), nil, ""},
+	}
+	for _, tt := range tests {
+		err := typedError(tt.err)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("typedError(%v) = %v, should keep the original error", tt.err, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("typedError(%v) = %v, want it to wrap %v", tt.err, err, tt.want)
+		}
+		if code := ErrorCode(err); code != tt.code {
+			t.Errorf("ErrorCode(%v) = %q, want %q", err, code, tt.code)
+		}
+	}
+	if typedError(nil) != nil {
+		t.Errorf("typedError(nil) should be nil")
+	}
+	if code := ErrorCode(SkipError(SkipReasonDirtyWorktree)); code != ErrorCodeDirtyWorktree {
+		t.Errorf("ErrorCode(SkipError(%q)) = %q, want %q", SkipReasonDirtyWorktree, code, ErrorCodeDirtyWorktree)
+	}
+	if err := SkipError(SkipReasonDetachedHead); err != nil {
+		t.Errorf("SkipError(%q) = %v, want nil", SkipReasonDetachedHead, err)
+	}
+}
+
+func TestGitModelService_TypedErrors(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+
+	if _, err := gs.RepoStatus(ctx, t.TempDir(), 0, Options{}); !errors.Is(err, ErrNotARepository) {
+		t.Errorf("RepoStatus() error = %v, want ErrNotARepository", err)
+	}
+
+	repoPath, cleanup := setupTestRepo(t)
+	defer cleanup()
+	if _, err := gs.PushBranches(ctx, repoPath, PushOptions{}); !errors.Is(err, ErrNoOriginRemote) {
+		t.Errorf("PushBranches() error = %v, want ErrNoOriginRemote", err)
+	}
+	if err := gs.UpdateRemote(ctx, repoPath, "https://example.com", Options{}); !errors.Is(err, ErrNoOriginRemote) {
+		t.Errorf("UpdateRemote() error = %v, want ErrNoOriginRemote", err)
+	}
+}
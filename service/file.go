@@ -1,47 +1,114 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/nanaki-93/goktor/model"
 
 	"os"
 	"path/filepath"
-	"sort"
-	"sync"
+	"strings"
+	"time"
 )
 
 const (
-	OneGb      = 1024 * 1024 * 1024
-	OneMb      = 1024 * 1024
-	OneKb      = 1024
 	maxWorkers = 10
 )
 
 type FileService interface {
-	ListDirectories(path string) (model.Directory, error)
-	ListDirectoriesWithFilter(path string, filter func(model.Directory) bool) (model.Directory, error)
-	ListFiles(path string) ([]model.FileSystem, error)
+	ListDirectories(ctx context.Context, path string) (model.Directory, error)
+	ListDirectoriesWithFilter(ctx context.Context, path string, filter func(model.Directory) bool) (model.Directory, error)
+	ListDirectoriesMFT(ctx context.Context, path string) (model.Directory, error)
+	ListFiles(ctx context.Context, path string) ([]model.FileSystem, error)
+	// WalkFiles calls fn with every file ListFiles returns as soon as it is
+	// read, without holding them; an error of fn stops the walk and is returned
+	WalkFiles(ctx context.Context, path string, fn func(model.FileSystem) error) error
+	// FindFiles walks path concurrently and calls found with every file matching query
+	FindFiles(ctx context.Context, path string, query FindQuery, found func(model.FileSystem) error) error
 	PrintDirectories(directories []model.Directory, filter func(model.Directory) bool)
-	PrintFiles(files []model.FileSystem)
+	// PrintFiles prints the files, stopping at the first failed write, such as
+	// a closed pipe, whose error it returns
+	PrintFiles(files []model.FileSystem) error
 	GetSizeFilter() func(model.Directory) bool
+	// SetMinSize sets the size a directory must exceed to pass GetSizeFilter
+	SetMinSize(size int64)
+	SetProgress(progress ProgressFunc)
+	SetOutput(out io.Writer)
+	// SetStorage overrides the detected storage type used to size scan concurrency
+	SetStorage(storage StorageType)
+	// SetWorkers sets the number of directories read at a time by a scan, 0
+	// to size it from the storage type
+	SetWorkers(workers int)
+	// LockedFiles returns the files found locked by other processes during the scans
+	LockedFiles() []model.FileSystem
+	// SkippedPaths returns the paths the scans failed to read and went on without
+	SkippedPaths() []ScanError
+	// SetFollowSymlinks makes the recursive scans descend into symlinked directories
+	SetFollowSymlinks(follow bool)
+	// SetAllocatedSize makes the scans measure the space files take on disk
+	SetAllocatedSize(enabled bool)
+	// SetOwnership makes the scans read the owner and group of files
+	SetOwnership(enabled bool)
+	// SetOwnedBy keeps only the files owned by the owner id, as returned by LookupOwner
+	SetOwnedBy(owner string)
+	// SetMaxDepth limits the levels the scans descend below the scanned directory
+	SetMaxDepth(depth int)
+	// RevisitedDirs returns the directories skipped because they were already scanned through another path
+	RevisitedDirs() []model.FileSystem
+	// SetCheckpoint records the scanned top-level directories in checkpoint and reuses the ones it already holds
+	SetCheckpoint(checkpoint *Checkpoint)
+	// SetScanCache reuses the directories of cache whose modification time is unchanged
+	SetScanCache(cache *ScanCache)
+	// UpdateIndex applies a change of path to a size index without rescanning the tree
+	UpdateIndex(ctx context.Context, index *SizeIndex, path string) ([]string, error)
+	// WatchDirectories scans path and keeps its size index updated from filesystem notifications
+	WatchDirectories(ctx context.Context, path string, interval time.Duration, onChange func(*SizeIndex)) error
 }
 type FileSystemService struct {
-	limit  int64
-	logger Logger
+	limit    int64
+	logger   Logger
+	progress ProgressFunc
+	counter  scanCounter
+	out      io.Writer
+	locked   lockedFiles
+	errors   scanErrors
+	storage  StorageType
+	// workers overrides the concurrency sized from the storage type when set
+	workers int
+	// pool is shared by every directory of the running scan
+	pool *workerPool
+
+	followSymlinks bool
+	visited        visitedDirs
+
+	allocated bool
+	ownership bool
+	ownedBy   string
+
+	// maxDepth is applied when limitDepth is set
+	maxDepth   int
+	limitDepth bool
+
+	checkpoint *Checkpoint
+	scanRoot   string
+	cache      *ScanCache
 }
 
 func NewFileService() FileService {
 	return &FileSystemService{
-		limit:  OneGb * 10, // 1 GB
+		limit:  DefaultMinSize,
 		logger: &DefaultLogger{},
+		out:    os.Stdout,
 	}
 }
 
 func NewServiceWithLogger(logger Logger) FileService {
 	return &FileSystemService{
-		limit:  OneGb * 10,
+		limit:  DefaultMinSize,
 		logger: logger,
+		out:    os.Stdout,
 	}
 }
 
@@ -49,34 +116,164 @@ func NewServiceWithLimit(limit int64) FileService {
 	return &FileSystemService{
 		limit:  limit,
 		logger: &DefaultLogger{},
+		out:    os.Stdout,
 	}
 }
 
-func (fs *FileSystemService) PrintFiles(files []model.FileSystem) {
+// SetProgress registers a callback notified as directories are scanned
+func (fs *FileSystemService) SetProgress(progress ProgressFunc) {
+	fs.progress = progress
+}
+
+// SetStorage overrides storage detection; StorageAuto restores it
+func (fs *FileSystemService) SetStorage(storage StorageType) {
+	fs.storage = storage
+}
+
+// SetWorkers overrides the concurrency of the scans; 0 restores the sizing
+// from the storage type
+func (fs *FileSystemService) SetWorkers(workers int) {
+	fs.workers = workers
+}
+
+// scanWorkers returns the number of directories read at a time when scanning
+// path: the SetWorkers one, or the one suited to the storage type of path
+func (fs *FileSystemService) scanWorkers(path string) int {
+	if fs.workers > 0 {
+		fs.logger.Debug("scan concurrency", "path", path, "workers", fs.workers)
+		return fs.workers
+	}
+	storage := fs.storage
+	if storage == "" || storage == StorageAuto {
+		storage = DetectStorage(path)
+	}
+	workers := WorkersForStorage(storage)
+	fs.logger.Debug("scan concurrency", "path", path, "storage", storage, "workers", workers)
+	return workers
+}
+
+// SetMinSize sets the threshold of GetSizeFilter, DefaultMinSize by default
+func (fs *FileSystemService) SetMinSize(size int64) {
+	fs.limit = size
+}
+
+// SetAllocatedSize makes the scans fill the Allocated size of files and
+// directories, the space they take on disk. It costs one more system call
+// per file on Windows.
+func (fs *FileSystemService) SetAllocatedSize(enabled bool) {
+	fs.allocated = enabled
+}
+
+// SetOwnership makes the scans fill the Owner and Group of files. It costs
+// one more system call per file on Windows.
+func (fs *FileSystemService) SetOwnership(enabled bool) {
+	fs.ownership = enabled
+}
+
+// SetOwnedBy makes the scans leave out the files of other owners, which are
+// then neither listed nor counted in the directory sizes. An empty owner
+// keeps every file.
+func (fs *FileSystemService) SetOwnedBy(owner string) {
+	fs.ownedBy = owner
+	if owner != "" {
+		fs.ownership = true
+	}
+}
+
+// ownedFile reports whether file passes the SetOwnedBy filter
+func (fs *FileSystemService) ownedFile(file model.FileSystem) bool {
+	return fs.ownedBy == "" || file.Owner == fs.ownedBy
+}
+
+// SetMaxDepth stops ListDirectories depth levels below the scanned directory,
+// the directories at that level being marked DepthLimited, and makes
+// ListFiles list the files of the subdirectories down to depth levels instead
+// of the files of the scanned directory only. A negative depth removes the limit.
+func (fs *FileSystemService) SetMaxDepth(depth int) {
+	fs.maxDepth, fs.limitDepth = depth, depth >= 0
+}
+
+// depthOf returns how many levels path is below the scan root
+func (fs *FileSystemService) depthOf(path string) int {
+	rel, err := filepath.Rel(fs.scanRoot, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// SetOutput sets the writer used by the Print methods, os.Stdout by default
+func (fs *FileSystemService) SetOutput(out io.Writer) {
+	fs.out = out
+}
+
+// LockedFiles returns the files skipped because another process held them
+// open or locked. Their sizes are taken from the directory metadata.
+func (fs *FileSystemService) LockedFiles() []model.FileSystem {
+	return fs.locked.list()
+}
+
+func (fs *FileSystemService) PrintFiles(files []model.FileSystem) error {
 	for _, file := range files {
-		fmt.Println("Name:", file.Name)
-		fmt.Println("Path:", file.FullPath)
-		fmt.Println("Size:", file.GetFormattedSize())
-		fmt.Println("-----")
+		var b strings.Builder
+		fmt.Fprintln(&b, "Name:", file.Name)
+		fmt.Fprintln(&b, "Path:", file.FullPath)
+		fmt.Fprintln(&b, "Size:", file.GetFormattedSize())
+		if fs.allocated {
+			onDisk := model.FileSystem{Size: file.Allocated}
+			fmt.Fprintln(&b, "On disk:", onDisk.GetFormattedSize())
+		}
+		if fs.ownership {
+			fmt.Fprintln(&b, "Mode:", file.Mode)
+			fmt.Fprintln(&b, "Owner:", ownerName(file.Owner))
+		}
+		if file.Symlink {
+			fmt.Fprintln(&b, "Link:", file.LinkTarget)
+		}
+		fmt.Fprintln(&b, "-----")
+		if _, err := io.WriteString(fs.out, b.String()); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (fs *FileSystemService) PrintDirectories(directories []model.Directory, filter func(model.Directory) bool) {
 	for _, dir := range directories {
 		if filter(dir) {
-			fmt.Println("Name:", dir.Name)
-			fmt.Println("Path:", dir.FullPath)
-			fmt.Println("Size:", dir.GetFormattedSize())
-			fmt.Println("-----")
+			fmt.Fprintln(fs.out, "Name:", dir.Name)
+			fmt.Fprintln(fs.out, "Path:", dir.FullPath)
+			fmt.Fprintln(fs.out, "Size:", dir.GetFormattedSize())
+			if fs.allocated {
+				onDisk := model.FileSystem{Size: dir.Allocated}
+				fmt.Fprintln(fs.out, "On disk:", onDisk.GetFormattedSize())
+			}
+			fmt.Fprintln(fs.out, "Files:", dir.FileCount)
+			fmt.Fprintln(fs.out, "Dirs:", dir.DirCount)
+			if dir.DepthLimited {
+				fmt.Fprintln(fs.out, "Partial: subdirectories below --max-depth not scanned")
+			}
+			if dir.Symlink {
+				fmt.Fprintln(fs.out, "Link:", dir.LinkTarget)
+			}
+			fmt.Fprintln(fs.out, "-----")
 		}
 	}
 }
-func (fs *FileSystemService) ListDirectories(path string) (model.Directory, error) {
-	return fs.ListDirectoriesWithFilter(path, func(model.Directory) bool { return true })
+func (fs *FileSystemService) ListDirectories(ctx context.Context, path string) (model.Directory, error) {
+	return fs.ListDirectoriesWithFilter(ctx, path, func(model.Directory) bool { return true })
 }
 
-func (fs *FileSystemService) ListDirectoriesWithFilter(path string, filter func(model.Directory) bool) (model.Directory, error) {
-	root, err := fs.getDirectoryRecursively(path, filter)
+// ListDirectoriesWithFilter scans path recursively. The scan stops as soon as
+// ctx is cancelled and the context error is returned.
+func (fs *FileSystemService) ListDirectoriesWithFilter(ctx context.Context, path string, filter func(model.Directory) bool) (model.Directory, error) {
+	fs.pool = newWorkerPool(fs.scanWorkers(path))
+	fs.visited.reset()
+	fs.scanRoot = path
+	root, err := fs.getDirectoryRecursively(ctx, path, filter)
+	if err == nil {
+		err = ctx.Err()
+	}
 	if err != nil {
 		fs.handleError(err, path)
 		return model.Directory{}, err
@@ -84,17 +281,68 @@ func (fs *FileSystemService) ListDirectoriesWithFilter(path string, filter func(
 	return root, nil
 }
 
-func (fs *FileSystemService) getDirectoryRecursively(path string, filter func(model.Directory) bool) (model.Directory, error) {
-	entries, err := fs.readDirectory(path)
-	if err != nil {
+// ListDirectoriesMFT scans a whole NTFS volume by reading its master file table
+// directly. It returns ErrMFTUnavailable when the fast path cannot be used.
+func (fs *FileSystemService) ListDirectoriesMFT(ctx context.Context, path string) (model.Directory, error) {
+	if err := ctx.Err(); err != nil {
 		return model.Directory{}, err
 	}
+	return scanVolumeMFT(ctx, path)
+}
+
+func (fs *FileSystemService) getDirectoryRecursively(ctx context.Context, path string, filter func(model.Directory) bool) (model.Directory, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Directory{}, err
+	}
+	if !fs.enterDir(path) {
+		return model.Directory{}, nil
+	}
+	if fs.checkpoint != nil && path != fs.scanRoot && filepath.Dir(path) == fs.scanRoot {
+		return fs.checkpointedDirectory(ctx, path, filter)
+	}
+	return fs.scanDirectory(ctx, path, filter)
+}
 
-	dir, subDirPaths := fs.manageDirEntries(path, entries)
+// scanDirectory reads path, or takes it from the scan cache, and scans its subdirectories
+func (fs *FileSystemService) scanDirectory(ctx context.Context, path string, filter func(model.Directory) bool) (model.Directory, error) {
+	var (
+		dir         model.Directory
+		subDirPaths []string
+		cached      bool
+		modTime     time.Time
+	)
+	if fs.cache != nil {
+		dir, subDirPaths, modTime, cached = fs.cachedDirectory(path)
+	}
+	if cached {
+		fs.reportProgress(path, len(dir.Files)+len(subDirPaths), dir.Size)
+	} else {
+		entries, err := fs.readDirectory(path)
+		if err != nil {
+			return model.Directory{}, err
+		}
+		dir, subDirPaths = fs.manageDirEntries(path, entries)
+		if fs.followSymlinks {
+			link := dirLinkModel(path)
+			dir.Symlink, dir.LinkTarget = link.Symlink, link.LinkTarget
+		}
+		if fs.cache != nil && !modTime.IsZero() {
+			fs.cache.store(path, modTime, dir, subDirPaths)
+		}
+		fs.reportProgress(path, len(entries), dir.Size)
+	}
 
+	if fs.limitDepth && len(subDirPaths) > 0 && fs.depthOf(path) >= fs.maxDepth {
+		dir.DepthLimited = true
+		subDirPaths = nil
+	}
 	if len(subDirPaths) > 0 {
-		dir.SubDirs = fs.processSubDirectories(subDirPaths, filter)
+		dir.SubDirs = fs.processSubDirectories(ctx, subDirPaths, filter)
+		if err := ctx.Err(); err != nil {
+			return model.Directory{}, err
+		}
 	}
+	dir.UpdateCounts()
 
 	if filter(dir) {
 		return dir, nil
@@ -103,7 +351,7 @@ func (fs *FileSystemService) getDirectoryRecursively(path string, filter func(mo
 }
 
 func (fs *FileSystemService) readDirectory(path string) ([]os.DirEntry, error) {
-	entries, err := os.ReadDir(path)
+	entries, err := readDirFast(path)
 	if err != nil {
 		if os.IsPermission(err) {
 			return nil, fmt.Errorf("permission denied reading directory: %s: %w", path, err)
@@ -118,46 +366,44 @@ func (fs *FileSystemService) manageDirEntries(path string, entries []os.DirEntry
 		dir         model.Directory
 		subDirPaths []string
 		folderSize  int64
+		allocated   int64
 	)
 	for _, entry := range entries {
+		if fs.followSymlinks && isSymlinkedDir(entry, filepath.Join(path, entry.Name())) {
+			subDirPaths = append(subDirPaths, filepath.Join(path, entry.Name()))
+			continue
+		}
 		if !entry.IsDir() {
 			fileModel := fs.toFileSystemModel(path, entry)
+			if !fs.ownedFile(fileModel) {
+				continue
+			}
 			dir.Files = append(dir.Files, fileModel)
 			folderSize += fileModel.Size
+			allocated += fileModel.Allocated
 		} else {
 			subDirPaths = append(subDirPaths, filepath.Join(path, entry.Name()))
 		}
 	}
-	return fs.toDirModel(path, dir, folderSize), subDirPaths
+	dir = fs.toDirModel(path, dir, folderSize)
+	dir.Allocated = allocated
+	return dir, subDirPaths
 }
 
-func (fs *FileSystemService) processSubDirectories(paths []string, filter func(model.Directory) bool) []model.Directory {
+func (fs *FileSystemService) processSubDirectories(ctx context.Context, paths []string, filter func(model.Directory) bool) []model.Directory {
+	// every task writes its own index, results needs no lock
 	results := make([]model.Directory, len(paths))
-	semaphore := make(chan struct{}, maxWorkers)
-
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	for i, subPath := range paths {
-		wg.Add(1)
-		go func(index int, subPath string) {
-			defer wg.Done()
-			semaphore <- struct{}{}        // Acquire semaphore
-			defer func() { <-semaphore }() // Release semaphore
-
-			subDir, err := fs.getDirectoryRecursively(subPath, filter)
-			if err != nil {
-				fs.logger.Debug("error processing subdirectory", "path", subPath, "error", err)
-				return
+	fs.pool.run(ctx, len(paths), func(i int) {
+		subDir, err := fs.getDirectoryRecursively(ctx, paths[i], filter)
+		if err != nil {
+			fs.logger.Debug("error processing subdirectory", "path", paths[i], "error", err)
+			if ctx.Err() == nil {
+				fs.errors.add(paths[i], err)
 			}
-
-			mu.Lock()
-			results[index] = subDir
-			mu.Unlock()
-		}(i, subPath)
-	}
-	wg.Wait()
-
+			return
+		}
+		results[i] = subDir
+	})
 	return fs.filterResults(results)
 }
 func (fs *FileSystemService) filterResults(results []model.Directory) []model.Directory {
@@ -184,24 +430,57 @@ func (fs *FileSystemService) toDirModel(path string, dir model.Directory, folder
 }
 
 func (fs *FileSystemService) toFileSystemModel(path string, file os.DirEntry) model.FileSystem {
-	info, err := file.Info()
-	if err != nil {
-		fs.logger.Debug("failed to get file info", "file", file, "error", err)
-		return model.FileSystem{Name: file.Name()}
-	}
 	fullPath, err := filepath.Abs(filepath.Join(path, file.Name()))
 	if err != nil {
 		fs.logger.Debug("failed to get absolute path", "path", path, "error", err)
 		fullPath = filepath.Join(path, file.Name())
 	}
+	info, err := file.Info()
+	if err != nil {
+		if isLockedError(err) {
+			locked := model.FileSystem{Name: file.Name(), FullPath: fullPath, Size: entryMetadataSize(file), Extension: model.FileExtension(file.Name())}
+			fs.addLocked(&locked)
+			return locked
+		}
+		fs.logger.Debug("failed to get file info", "file", file, "error", err)
+		fs.errors.add(fullPath, err)
+		return model.FileSystem{Name: file.Name()}
+	}
 	subFile := model.FileSystem{
 		Name:     file.Name(),
 		FullPath: fullPath,
 		Size:     info.Size(),
 		IsDir:    file.IsDir(),
+		ModTime:  info.ModTime(),
+		Mode:     info.Mode(),
+	}
+	if fs.allocated {
+		// the enumerator metadata needs no open file, unlike the allocated
+		// size; a file held open by another process keeps the metadata size
+		subFile.Allocated, err = allocatedSize(fullPath, info)
+	}
+	if fs.ownership {
+		subFile.Owner, subFile.Group = fileOwner(fullPath, info)
+	}
+	if !subFile.IsDir {
+		subFile.Extension = model.FileExtension(file.Name())
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		subFile.Symlink = true
+		subFile.LinkTarget, _ = os.Readlink(filepath.Join(path, file.Name()))
+	}
+	if isLockedError(err) {
+		fs.addLocked(&subFile)
 	}
 	return subFile
 }
+
+// addLocked marks file as held by another process and records it for LockedFiles
+func (fs *FileSystemService) addLocked(file *model.FileSystem) {
+	file.Locked = true
+	fs.locked.add(*file)
+}
+
 func (fs *FileSystemService) handleError(err error, path string) {
 	if os.IsPermission(err) {
 		fs.logger.Error("permission denied reading directory", "path", path)
@@ -216,24 +495,76 @@ func (fs *FileSystemService) GetSizeFilter() func(model.Directory) bool {
 	}
 }
 
-func (fs *FileSystemService) ListFiles(path string) ([]model.FileSystem, error) {
-	entries, err := fs.readDirectory(path)
+// ListFiles lists the files directly in path, or down to the depth set with
+// SetMaxDepth. Subdirectories that cannot be read are recorded in SkippedPaths.
+func (fs *FileSystemService) ListFiles(ctx context.Context, path string) ([]model.FileSystem, error) {
+	var files []model.FileSystem
+	err := fs.WalkFiles(ctx, path, func(file model.FileSystem) error {
+		files = append(files, file)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return files, nil
+}
 
-	var files []model.FileSystem
+// WalkFiles lists the files directly in path, and those of its subdirectories
+// down to the SetMaxDepth levels, depth first. Only the entries of the
+// directories being walked are held, whatever the number of files.
+func (fs *FileSystemService) WalkFiles(ctx context.Context, path string, fn func(model.FileSystem) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	depth := 0
+	if fs.limitDepth {
+		depth = fs.maxDepth
+	}
+	entries, err := fs.readDirectory(path)
+	if err != nil {
+		return err
+	}
+	return fs.walkEntries(ctx, path, entries, depth, fn)
+}
+
+// walkEntries calls fn with the files of entries, the content of path, and
+// walks its subdirectories while depth is positive. The subdirectories that
+// cannot be read are recorded in SkippedPaths.
+func (fs *FileSystemService) walkEntries(ctx context.Context, path string, entries []os.DirEntry, depth int, fn func(model.FileSystem) error) error {
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if !entry.IsDir() {
-			files = append(files, fs.toFileSystemModel(path, entry))
+			file := fs.toFileSystemModel(path, entry)
+			if !fs.ownedFile(file) {
+				continue
+			}
+			fs.reportProgress(file.FullPath, 1, file.Size)
+			if err := fn(file); err != nil {
+				return err
+			}
+			continue
+		}
+		if depth > 0 {
+			subPath := filepath.Join(path, entry.Name())
+			subEntries, err := fs.readDirectory(subPath)
+			if err != nil {
+				fs.errors.add(subPath, err)
+				continue
+			}
+			if err := fs.walkEntries(ctx, subPath, subEntries, depth-1, fn); err != nil {
+				return err
+			}
 		}
 	}
-	return files, nil
+	return nil
 }
 
-func ReorderDirectory(directory model.Directory) []model.Directory {
-
+// ReorderDirectory flattens directory and its subdirectories into a single
+// list ordered by order
+func ReorderDirectory(directory model.Directory, order SortOrder) []model.Directory {
 	result := directory.FlattenDirectory()
-	sort.Sort(model.BySize(result))
+	sortDirectories(result, order)
 	return result
 }
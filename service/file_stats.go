@@ -0,0 +1,116 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// File categories reported by AggregateFileStats
+const (
+	CategoryImages    = "images"
+	CategoryVideos    = "videos"
+	CategoryAudio     = "audio"
+	CategoryArchives  = "archives"
+	CategoryDocuments = "documents"
+	CategoryCode      = "code"
+	CategoryLogs      = "logs"
+	CategoryOther     = "other"
+)
+
+var extensionCategories = map[string]string{}
+
+func init() {
+	for category, extensions := range map[string][]string{
+		CategoryImages:    {"jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff", "webp", "svg", "ico", "heic", "raw", "psd"},
+		CategoryVideos:    {"mp4", "mkv", "avi", "mov", "wmv", "flv", "webm", "m4v", "mpg", "mpeg"},
+		CategoryAudio:     {"mp3", "wav", "flac", "aac", "ogg", "m4a", "wma"},
+		CategoryArchives:  {"zip", "tar", "gz", "tgz", "bz2", "xz", "7z", "rar", "zst", "jar", "war", "iso", "dmg"},
+		CategoryDocuments: {"pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx", "odt", "ods", "txt", "md", "rtf", "csv"},
+		CategoryCode: {"go", "java", "kt", "js", "ts", "jsx", "tsx", "py", "rb", "php", "c", "h", "cpp", "hpp", "cs",
+			"rs", "swift", "scala", "sh", "ps1", "sql", "html", "css", "scss", "json", "xml", "yaml", "yml", "toml"},
+		CategoryLogs: {"log", "out", "err", "trace"},
+	} {
+		for _, ext := range extensions {
+			extensionCategories[ext] = category
+		}
+	}
+}
+
+// FileCategory returns the category of a lower-case file extension
+func FileCategory(extension string) string {
+	if category, ok := extensionCategories[extension]; ok {
+		return category
+	}
+	return CategoryOther
+}
+
+// FileGroupStats counts the files of one category or extension
+type FileGroupStats struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+// FileStats is the breakdown of a scanned tree by category and extension,
+// each sorted by cumulative size, largest first
+type FileStats struct {
+	Files      int              `json:"files"`
+	Size       int64            `json:"size"`
+	Categories []FileGroupStats `json:"categories"`
+	Extensions []FileGroupStats `json:"extensions"`
+}
+
+// AggregateFileStats groups every file of the tree by category and extension.
+// Files without extension are counted under the "(none)" extension.
+func AggregateFileStats(root model.Directory) FileStats {
+	categories := map[string]*FileGroupStats{}
+	extensions := map[string]*FileGroupStats{}
+	stats := FileStats{}
+
+	var walk func(dir model.Directory)
+	walk = func(dir model.Directory) {
+		for _, file := range dir.Files {
+			stats.Files++
+			stats.Size += file.Size
+			addToGroup(categories, FileCategory(file.Extension), file.Size)
+			extension := file.Extension
+			if extension == "" {
+				extension = "(none)"
+			}
+			addToGroup(extensions, extension, file.Size)
+		}
+		for _, sub := range dir.SubDirs {
+			walk(sub)
+		}
+	}
+	walk(root)
+
+	stats.Categories = sortedGroups(categories)
+	stats.Extensions = sortedGroups(extensions)
+	return stats
+}
+
+func addToGroup(groups map[string]*FileGroupStats, name string, size int64) {
+	group, ok := groups[name]
+	if !ok {
+		group = &FileGroupStats{Name: name}
+		groups[name] = group
+	}
+	group.Files++
+	group.Size += size
+}
+
+func sortedGroups(groups map[string]*FileGroupStats) []FileGroupStats {
+	sorted := make([]FileGroupStats, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, *group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Size != sorted[j].Size {
+			return sorted[i].Size > sorted[j].Size
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestFileExtension(t *testing.T) {
+	tests := map[string]string{
+		"photo.JPG":      "jpg",
+		"backup.tar.gz":  "gz",
+		"Makefile":       "",
+		".gitignore":     "",
+		"archive.v2.zip": "zip",
+	}
+	for name, want := range tests {
+		if got := model.FileExtension(name); got != want {
+			t.Errorf("FileExtension(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAggregateFileStats(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel string, size int) {
+		path := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, make([]byte, size), 0644)
+	}
+	write("a.png", 100)
+	write("photos/b.JPG", 300)
+	write("src/main.go", 50)
+	write("logs/app.log", 500)
+	write("logs/old/app.log", 200)
+	write("README", 10)
+
+	fs := NewFileService()
+	dir, err := fs.ListDirectories(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ListDirectories() error = %v", err)
+	}
+	stats := AggregateFileStats(dir)
+
+	if stats.Files != 6 || stats.Size != 1160 {
+		t.Errorf("totals = %d files, %d bytes, want 6 files, 1160 bytes", stats.Files, stats.Size)
+	}
+	wantCategories := []FileGroupStats{
+		{Name: CategoryLogs, Files: 2, Size: 700},
+		{Name: CategoryImages, Files: 2, Size: 400},
+		{Name: CategoryCode, Files: 1, Size: 50},
+		{Name: CategoryOther, Files: 1, Size: 10},
+	}
+	if len(stats.Categories) != len(wantCategories) {
+		t.Fatalf("categories = %+v, want %+v", stats.Categories, wantCategories)
+	}
+	for i, want := range wantCategories {
+		if stats.Categories[i] != want {
+			t.Errorf("category %d = %+v, want %+v", i, stats.Categories[i], want)
+		}
+	}
+
+	extensions := map[string]FileGroupStats{}
+	for _, group := range stats.Extensions {
+		extensions[group.Name] = group
+	}
+	if got := extensions["jpg"]; got.Files != 1 || got.Size != 300 {
+		t.Errorf("jpg = %+v, want 1 file of 300 bytes", got)
+	}
+	if got := extensions["(none)"]; got.Files != 1 {
+		t.Errorf("(none) = %+v, want 1 file", got)
+	}
+}
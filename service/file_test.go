@@ -1,9 +1,14 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/nanaki-93/goktor/model"
@@ -16,7 +21,9 @@ func TestFileSystemService_ProcessSubDirectoriesRecursively(t *testing.T) {
 		setup         func(t *testing.T) string
 		filter        func(model.Directory) bool
 		expectedCount int
-		wantErr       bool
+		// expectedSkipped is the number of paths reported by SkippedPaths
+		expectedSkipped int
+		wantErr         bool
 	}{
 		{
 			name: "nested directories with filter",
@@ -48,11 +55,11 @@ func TestFileSystemService_ProcessSubDirectoriesRecursively(t *testing.T) {
 			setup: func(t *testing.T) string {
 				tmpDir := t.TempDir()
 				os.MkdirAll(filepath.Join(tmpDir, "large"), 0755)
-				os.WriteFile(filepath.Join(tmpDir, "large", "file.txt"), make([]byte, 2*OneGb), 0644)
+				os.WriteFile(filepath.Join(tmpDir, "large", "file.txt"), make([]byte, 2*model.GiB), 0644)
 				os.MkdirAll(filepath.Join(tmpDir, "small"), 0755)
 				return tmpDir
 			},
-			filter:        func(d model.Directory) bool { return d.Size > OneGb },
+			filter:        func(d model.Directory) bool { return d.Size > model.GiB },
 			expectedCount: 1,
 			wantErr:       false,
 		},
@@ -76,9 +83,10 @@ func TestFileSystemService_ProcessSubDirectoriesRecursively(t *testing.T) {
 				t.Cleanup(func() { os.Chmod(restrictedDir, 0755) })
 				return tmpDir
 			},
-			filter:        func(d model.Directory) bool { return true },
-			expectedCount: 1, // Skipped due to permission error
-			wantErr:       false,
+			filter:          func(d model.Directory) bool { return true },
+			expectedCount:   1, // Skipped due to permission error
+			expectedSkipped: 1,
+			wantErr:         false,
 		},
 	}
 
@@ -87,7 +95,7 @@ func TestFileSystemService_ProcessSubDirectoriesRecursively(t *testing.T) {
 			tmpDir := tt.setup(t)
 			service := NewFileService()
 
-			result, err := service.ListDirectoriesWithFilter(tmpDir, tt.filter)
+			result, err := service.ListDirectoriesWithFilter(context.Background(), tmpDir, tt.filter)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("got error %v, wantErr %v", err, tt.wantErr)
@@ -97,10 +105,13 @@ func TestFileSystemService_ProcessSubDirectoriesRecursively(t *testing.T) {
 				return
 			}
 
-			flatResult := ReorderDirectory(result)
+			flatResult := ReorderDirectory(result, SortOrder{})
 			if len(flatResult) != tt.expectedCount {
 				t.Errorf("got %d directories, want %d", len(flatResult), tt.expectedCount)
 			}
+			if skipped := service.SkippedPaths(); len(skipped) != tt.expectedSkipped {
+				t.Errorf("got %d skipped paths, want %d: %v", len(skipped), tt.expectedSkipped, skipped)
+			}
 		})
 	}
 }
@@ -117,25 +128,218 @@ func TestFileSystemService_ConcurrentSubDirectoryProcessing(t *testing.T) {
 	}
 
 	service := NewFileService()
-	result, err := service.ListDirectoriesWithFilter(tmpDir, func(d model.Directory) bool { return true })
+	result, err := service.ListDirectoriesWithFilter(context.Background(), tmpDir, func(d model.Directory) bool { return true })
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	flatResult := ReorderDirectory(result)
+	flatResult := ReorderDirectory(result, SortOrder{})
 	if len(flatResult) != 16 {
 		t.Errorf("got %d directories, want at least 15", len(flatResult))
 	}
 }
 
+// TestFileSystemService_SequentialStorage verifies a sequential HDD scan finds the same tree
+func TestFileSystemService_SequentialStorage(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 1; i <= 5; i++ {
+		dirPath := filepath.Join(tmpDir, "dir"+strconv.Itoa(i), "nested")
+		os.MkdirAll(dirPath, 0755)
+		os.WriteFile(filepath.Join(dirPath, "file.txt"), []byte("content"), 0644)
+	}
+
+	service := NewFileService()
+	service.SetStorage(StorageHDD)
+	result, err := service.ListDirectories(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := len(ReorderDirectory(result, SortOrder{})); got != 11 {
+		t.Errorf("got %d directories, want 11", got)
+	}
+	if result.Size != 0 || result.SubDirs[0].SubDirs[0].Size != 7 {
+		t.Errorf("unexpected sizes in sequential scan: %+v", result)
+	}
+	if result.FileCount != 5 || result.DirCount != 10 || result.SubDirs[0].FileCount != 1 || result.SubDirs[0].DirCount != 1 {
+		t.Errorf("FileCount = %d, DirCount = %d, want 5 files in 10 directories", result.FileCount, result.DirCount)
+	}
+}
+
 // TestGetDirectoryRecursivelyWithErrors tests error handling in recursive calls
 func TestFileSystemService_RecursiveErrorHandling(t *testing.T) {
 
 	service := NewFileService()
-	_, err := service.ListDirectoriesWithFilter("/nonexistent/path", func(d model.Directory) bool { return true })
+	_, err := service.ListDirectoriesWithFilter(context.Background(), "/nonexistent/path", func(d model.Directory) bool { return true })
 
 	if err == nil {
 		t.Error("expected error for non-existent path, got nil")
 	}
 }
+
+// TestFileSystemService_ProgressReporting verifies the progress callback sees every scanned entry
+func TestFileSystemService_ProgressReporting(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("12345"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "sub", "b.txt"), []byte("123"), 0644)
+
+	var mu sync.Mutex
+	var last ScanProgress
+	service := NewFileService()
+	service.SetProgress(func(p ScanProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p.Entries > last.Entries {
+			last = p
+		}
+	})
+
+	if _, err := service.ListDirectories(context.Background(), tmpDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if last.Entries != 3 {
+		t.Errorf("got %d entries, want 3", last.Entries)
+	}
+	if last.Bytes != 8 {
+		t.Errorf("got %d bytes, want 8", last.Bytes)
+	}
+}
+
+// TestFileSystemService_ContextCancellation verifies a cancelled context stops the scan
+func TestFileSystemService_ContextCancellation(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "sub1", "sub2"), 0755)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	service := NewFileService()
+	if _, err := service.ListDirectories(ctx, tmpDir); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if _, err := service.ListFiles(ctx, tmpDir); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if _, err := service.ListDirectoriesMFT(ctx, tmpDir); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
+
+// TestFileSystemService_MaxDepth verifies the scans stop at the maximum depth
+func TestFileSystemService_MaxDepth(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a", filepath.Join("a", "b"), filepath.Join("a", "b", "c")} {
+		os.MkdirAll(filepath.Join(tmpDir, dir), 0755)
+		os.WriteFile(filepath.Join(tmpDir, dir, "file.txt"), []byte("content"), 0644)
+	}
+
+	service := NewFileService()
+	service.SetMaxDepth(1)
+	result, err := service.ListDirectories(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := result.SubDirs[0]
+	if len(a.SubDirs) != 0 || !a.DepthLimited || result.DepthLimited {
+		t.Errorf("a = %+v, want it depth limited without subdirectories", a)
+	}
+	if a.Size != 7 || result.FileCount != 1 {
+		t.Errorf("size = %d, files = %d, want only the files above the limit", a.Size, result.FileCount)
+	}
+
+	files, err := service.ListFiles(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].FullPath != filepath.Join(tmpDir, "a", "file.txt") {
+		t.Errorf("ListFiles() = %+v, want the file of a only", files)
+	}
+
+	service.SetMaxDepth(-1)
+	if files, _ := service.ListFiles(context.Background(), tmpDir); len(files) != 0 {
+		t.Errorf("ListFiles() without limit = %+v, want the files of the directory only", files)
+	}
+}
+
+// BenchmarkListDirectories scans a wide and deep tree with a few pool sizes:
+// go test ./service -bench ListDirectories -benchmem
+func BenchmarkListDirectories(b *testing.B) {
+	root := b.TempDir()
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 20; j++ {
+			dir := filepath.Join(root, "d"+strconv.Itoa(i), "s"+strconv.Itoa(j))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	for _, workers := range []int{1, 4, 16, 64} {
+		b.Run("workers="+strconv.Itoa(workers), func(b *testing.B) {
+			fs := NewServiceWithLogger(&DefaultLogger{})
+			fs.SetWorkers(workers)
+			for i := 0; i < b.N; i++ {
+				if _, err := fs.ListDirectories(context.Background(), root); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileSystemService_WalkFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, path := range []string{"a.txt", "b.txt", filepath.Join("sub", "c.txt")} {
+		full := filepath.Join(tmpDir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte("content"), 0644)
+	}
+	os.MkdirAll(filepath.Join(tmpDir, "sub", "deeper"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "sub", "deeper", "d.txt"), []byte("content"), 0644)
+
+	service := NewFileService()
+	service.SetMaxDepth(1)
+	var walked []string
+	err := service.WalkFiles(context.Background(), tmpDir, func(file model.FileSystem) error {
+		walked = append(walked, file.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkFiles() error = %v", err)
+	}
+	slices.Sort(walked)
+	if got := strings.Join(walked, ","); got != "a.txt,b.txt,c.txt" {
+		t.Errorf("WalkFiles() walked %s, want a.txt,b.txt,c.txt", got)
+	}
+
+	stop := errors.New("stop")
+	walked = nil
+	err = service.WalkFiles(context.Background(), tmpDir, func(file model.FileSystem) error {
+		walked = append(walked, file.Name)
+		return stop
+	})
+	if !errors.Is(err, stop) || len(walked) != 1 {
+		t.Errorf("WalkFiles() = %v after %v, want the error of fn after the first file", err, walked)
+	}
+}
+
+func TestFileSystemService_PrintFilesWriteError(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	reader.Close()
+	defer writer.Close()
+
+	fs := NewFileService()
+	fs.SetOutput(writer)
+	if err := fs.PrintFiles([]model.FileSystem{{Name: "a.txt", FullPath: "/a.txt"}}); err == nil {
+		t.Error("PrintFiles() to a closed pipe succeeded")
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// FindQuery selects the files reported by FindFiles. Zero fields are not
+// applied.
+type FindQuery struct {
+	// Name is a glob matched against the file name, such as "*.iso"
+	Name string
+	// Regex is matched against the full path
+	Regex *regexp.Regexp
+	// MinSize and MaxSize bound the size in bytes
+	MinSize int64
+	MaxSize int64
+	// Age filters on the modification time, relative to Now
+	Age AgeFilter
+	Now time.Time
+}
+
+// Validate reports a malformed Name glob
+func (q FindQuery) Validate() error {
+	if _, err := filepath.Match(q.Name, ""); err != nil {
+		return fmt.Errorf("invalid name pattern %q: %w", q.Name, err)
+	}
+	return nil
+}
+
+// matchPath applies the filters known before the file is stat'ed
+func (q FindQuery) matchPath(name, path string) bool {
+	if q.Name != "" {
+		if ok, _ := filepath.Match(q.Name, name); !ok {
+			return false
+		}
+	}
+	return q.Regex == nil || q.Regex.MatchString(path)
+}
+
+// Match reports whether file passes every filter of the query
+func (q FindQuery) Match(file model.FileSystem) bool {
+	if !q.matchPath(file.Name, file.FullPath) {
+		return false
+	}
+	if file.Size < q.MinSize || (q.MaxSize > 0 && file.Size > q.MaxSize) {
+		return false
+	}
+	return q.Age.Match(file, q.Now)
+}
+
+// FindFiles walks path concurrently and calls found with every file matching
+// query as soon as it is read; found is never called concurrently, and its
+// first error stops the search and is returned. The depth limit and symlink
+// options of the service apply, and the directories that cannot be read are
+// recorded in SkippedPaths.
+func (fs *FileSystemService) FindFiles(ctx context.Context, path string, query FindQuery, found func(model.FileSystem) error) error {
+	if err := query.Validate(); err != nil {
+		return err
+	}
+	fs.pool = newWorkerPool(fs.scanWorkers(path))
+	fs.visited.reset()
+	fs.scanRoot = path
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var mu sync.Mutex
+	var foundErr error
+	emit := func(file model.FileSystem) {
+		mu.Lock()
+		defer mu.Unlock()
+		if foundErr != nil {
+			return
+		}
+		if foundErr = found(file); foundErr != nil {
+			cancel()
+		}
+	}
+	err := fs.findInDirectory(ctx, path, query, emit)
+	if foundErr != nil {
+		return foundErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		fs.handleError(err, path)
+	}
+	return err
+}
+
+func (fs *FileSystemService) findInDirectory(ctx context.Context, path string, query FindQuery, emit func(model.FileSystem)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !fs.enterDir(path) {
+		return nil
+	}
+	entries, err := fs.readDirectory(path)
+	if err != nil {
+		return err
+	}
+
+	var subDirPaths []string
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+		if entry.IsDir() || (fs.followSymlinks && isSymlinkedDir(entry, entryPath)) {
+			subDirPaths = append(subDirPaths, entryPath)
+			continue
+		}
+		if !query.matchPath(entry.Name(), entryPath) {
+			continue
+		}
+		if file := fs.toFileSystemModel(path, entry); file.FullPath != "" && fs.ownedFile(file) && query.Match(file) {
+			emit(file)
+		}
+	}
+	fs.reportProgress(path, len(entries), 0)
+
+	if fs.limitDepth && fs.depthOf(path) >= fs.maxDepth {
+		return nil
+	}
+	fs.findInSubDirectories(ctx, subDirPaths, query, emit)
+	return ctx.Err()
+}
+
+// findInSubDirectories searches paths on the worker pool of the search, like
+// processSubDirectories
+func (fs *FileSystemService) findInSubDirectories(ctx context.Context, paths []string, query FindQuery, emit func(model.FileSystem)) {
+	fs.pool.run(ctx, len(paths), func(i int) {
+		if err := fs.findInDirectory(ctx, paths[i], query, emit); err != nil && ctx.Err() == nil {
+			fs.logger.Debug("error searching subdirectory", "path", paths[i], "error", err)
+			fs.errors.add(paths[i], err)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestFileSystemService_FindFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(path string, size int, age time.Duration) {
+		full := filepath.Join(tmpDir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, make([]byte, size), 0644)
+		mtime := time.Now().Add(-age)
+		os.Chtimes(full, mtime, mtime)
+	}
+	write("small.iso", 10, 0)
+	write(filepath.Join("images", "big.iso"), 2000, 0)
+	write(filepath.Join("images", "old", "ancient.iso"), 3000, 400*24*time.Hour)
+	write(filepath.Join("images", "notes.txt"), 5000, 0)
+
+	tests := []struct {
+		name     string
+		query    FindQuery
+		maxDepth int
+		want     []string
+	}{
+		{"name", FindQuery{Name: "*.iso"}, -1, []string{"ancient.iso", "big.iso", "small.iso"}},
+		{"size", FindQuery{Name: "*.iso", MinSize: 1000, MaxSize: 2500}, -1, []string{"big.iso"}},
+		{"age", FindQuery{Age: AgeFilter{OlderThan: 30 * 24 * time.Hour}, Now: time.Now()}, -1, []string{"ancient.iso"}},
+		{"regex", FindQuery{Regex: regexp.MustCompile(`images[/\\].*\.(txt|iso)$`)}, -1, []string{"ancient.iso", "big.iso", "notes.txt"}},
+		{"depth", FindQuery{Name: "*.iso"}, 1, []string{"big.iso", "small.iso"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewFileService()
+			service.SetMaxDepth(tt.maxDepth)
+			var got []string
+			err := service.FindFiles(context.Background(), tmpDir, tt.query, func(file model.FileSystem) error {
+				got = append(got, file.Name)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("FindFiles() error = %v", err)
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("FindFiles() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("FindFiles() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	if err := NewFileService().FindFiles(context.Background(), tmpDir, FindQuery{Name: "["}, func(model.FileSystem) error { return nil }); err == nil {
+		t.Errorf("FindFiles() with a malformed pattern should fail")
+	}
+}
+
+func TestFileSystemService_FindFilesStopsOnError(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		dir := filepath.Join(tmpDir, "dir"+strconv.Itoa(i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stop := errors.New("output closed")
+	calls := 0
+	err := NewFileService().FindFiles(context.Background(), tmpDir, FindQuery{}, func(model.FileSystem) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("FindFiles() = %v after %d calls, want the error of found after 1", err, calls)
+	}
+}
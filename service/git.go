@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,20 +15,103 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 )
 
 // UpdateResult contains statistics about the operation
 type UpdateResult struct {
-	Updated   []string
-	Skipped   []string
-	Failed    []string
-	TotalTime string
+	Updated []string `json:"updated"`
+	Skipped []string `json:"skipped"`
+	Failed  []string `json:"failed"`
+	// TotalTime is the wall-clock duration of the whole repository update
+	TotalTime time.Duration `json:"total_time"`
+	// FetchTime is the time spent fetching from origin
+	FetchTime time.Duration `json:"fetch_time"`
+	// BranchTimes holds the time spent updating each processed branch
+	BranchTimes map[string]time.Duration `json:"branch_times,omitempty"`
+	// Verified reports, per updated branch, whether the local ref matched the
+	// remote hash when re-read after the update
+	Verified map[string]bool `json:"verified,omitempty"`
+	// WorktreeClean reports whether the worktree was clean on the restored branch
+	WorktreeClean bool `json:"worktree_clean"`
+	// SkipReason is set when the whole repository was skipped
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Stashed reports whether local changes were stashed and restored around the update
+	Stashed bool `json:"stashed"`
+	// StashLeft is set when the stashed changes could not be restored and are
+	// still in git stash
+	StashLeft bool `json:"stash_left,omitempty"`
+	// Interrupted is set when the context was cancelled before every branch was
+	// processed. The original branch and stashed changes are restored regardless.
+	Interrupted bool `json:"interrupted"`
+	// Excluded lists the branches left untouched by the Branches/ExcludeBranches patterns
+	Excluded []string `json:"excluded,omitempty"`
+	// Protected lists the protected branches left as they were because their
+	// update was not a fast-forward; they are also listed in Skipped
+	Protected []string `json:"protected,omitempty"`
+	// Submodules holds the submodules synced after the update with RecurseSubmodules
+	Submodules []SubmoduleResult `json:"submodules,omitempty"`
+	// SubmoduleError is set when the submodules could not be listed or synced at all
+	SubmoduleError string `json:"submodule_error,omitempty"`
+	// OriginalHead is the branch checked out before the update
+	OriginalHead string `json:"original_head,omitempty"`
+	// HeadRestored is set once OriginalHead is checked out again after other
+	// branches were checked out, even when the update failed midway;
+	// RestoreError is set when that checkout failed
+	HeadRestored bool   `json:"head_restored,omitempty"`
+	RestoreError string `json:"restore_error,omitempty"`
+	// Bare is set for a bare repository, whose branch refs are updated
+	// without checkout
+	Bare bool `json:"bare,omitempty"`
 }
+
+// UpdateOptions configures UpdateAllBranchesProject
+type UpdateOptions struct {
+	Options
+	// AutoStash stashes uncommitted changes before the update and restores them
+	// afterwards instead of skipping a dirty repository
+	AutoStash bool
+	// NoCheckout fast-forwards branch refs directly in the ref store, only
+	// falling back to checkout and hard reset when the update is not a fast-forward
+	NoCheckout bool
+	// Branches limits the update to branches matching one of these glob
+	// patterns (e.g. "release/*"); every branch is updated when empty
+	Branches []string
+	// ExcludeBranches skips branches matching one of these glob patterns
+	ExcludeBranches []string
+	// Protected are glob patterns of branches that are only ever fast-forwarded:
+	// a protected branch with commits missing from the remote is never hard-reset.
+	// The default branch of the remote is always protected.
+	Protected []string
+	// RecurseSubmodules fetches the submodules after the update and checks them
+	// out at the commit recorded by the restored branch
+	RecurseSubmodules bool
+}
+
+// selectsBranch reports whether the include/exclude patterns select branchName
+func (opts UpdateOptions) selectsBranch(branchName string) bool {
+	if len(opts.Branches) > 0 && !matchesAny(branchName, opts.Branches) {
+		return false
+	}
+	return !matchesAny(branchName, opts.ExcludeBranches)
+}
+
+const SkipReasonDirtyWorktree = "dirty worktree"
+
+// Reasons UpdateAllBranchesProject skips a whole repository, besides
+// SkipReasonDirtyWorktree and SkipReasonDetachedHead
+const (
+	// SkipReasonNoCommits is a repository whose current branch has no commit yet
+	SkipReasonNoCommits = "no commits"
+	// SkipReasonNoRemote is a repository without the remote to update from
+	SkipReasonNoRemote = "no origin remote"
+)
+
 type DeleteMergedBranchesResult struct {
-	Deleted []string
-	DryRun  []string
-	Skipped []string
-	Failed  []string
+	Deleted []string `json:"deleted"`
+	DryRun  []string `json:"dry_run"`
+	Skipped []string `json:"skipped"`
+	Failed  []string `json:"failed"`
 }
 type releaseHistory struct {
 	Branch  string
@@ -39,82 +124,318 @@ type mergedReleaseInfo struct {
 
 // GitService defines operations for git repositories
 type GitService interface {
-	UpdateAllBranchesProject(ctx context.Context, path string) (*UpdateResult, error)
-	UpdateRemote(ctx context.Context, path string, newRemote string, force bool) error
-	FetchLatest(ctx context.Context, path string) error
-	DeleteMergedBranches(ctx context.Context, repoPath string, endDate string, dryRun bool) ([]DeleteMergedBranchesResult, error)
+	UpdateAllBranchesProject(ctx context.Context, path string, opts UpdateOptions) (*UpdateResult, error)
+	UpdateRemote(ctx context.Context, path string, newRemote string, opts Options) error
+	RewriteRemote(ctx context.Context, path string, rewrite *RemoteRewrite, opts Options) error
+	ConvertRemote(ctx context.Context, path string, protocol string, opts Options) error
+	FetchLatest(ctx context.Context, path string, opts Options) error
+	EstimateFetch(ctx context.Context, repoPath string, providers map[string]Provider, opts Options) (*FetchEstimate, error)
+	DeleteMergedBranches(ctx context.Context, repoPath string, endDate string, keepDays int, opts Options) ([]DeleteMergedBranchesResult, error)
+	ListDeletedBranches(ctx context.Context, repoPath string, opts Options) ([]DeletedBranch, error)
+	RestoreBranch(ctx context.Context, repoPath string, branchName string, opts Options) error
+	ResolveRemoteRedirect(ctx context.Context, repoPath string, opts Options) (string, error)
+	CloneRepository(ctx context.Context, remoteURL string, repoPath string, opts Options) error
+	DefaultBranch(ctx context.Context, repoPath string, opts Options) (string, error)
+	PruneBranches(ctx context.Context, repoPath string, protected []string, opts Options) (*PruneBranchesResult, error)
+	RepoStatus(ctx context.Context, repoPath string, staleAfter time.Duration, opts Options) (*RepoStatus, error)
+	PullCurrentBranch(ctx context.Context, repoPath string, opts PullOptions) (*PullResult, error)
+	CheckoutBranch(ctx context.Context, repoPath string, branch string, opts CheckoutOptions) (*CheckoutResult, error)
+	CreateBranch(ctx context.Context, repoPath string, name string, opts CreateBranchOptions) (*CreateBranchResult, error)
+	PushBranches(ctx context.Context, repoPath string, opts PushOptions) (*PushResult, error)
+	SyncFork(ctx context.Context, repoPath string, opts ForkSyncOptions) (*ForkSyncResult, error)
+	MirrorRepository(ctx context.Context, repoPath string, to string, opts Options) (*MirrorResult, error)
+	ListTags(ctx context.Context, repoPath string, patterns []string, opts Options) ([]Tag, error)
+	SyncTags(ctx context.Context, repoPath string, opts Options) (*TagSyncResult, error)
+	CheckArchivable(ctx context.Context, repoPath string, criteria ArchiveCriteria, opts Options) (*ArchiveCheck, error)
+	ArchiveRepository(ctx context.Context, repoPath string, archiveDir string, mode string, opts Options) (*ArchiveEntry, error)
+	TarArchiveRepository(ctx context.Context, repoPath string, outDir string, opts TarArchiveOptions) (*TarArchive, error)
+	GarbageCollect(ctx context.Context, repoPath string, opts GCOptions) (*GCResult, error)
+	RepoSize(ctx context.Context, repoPath string, top int, opts Options) (*RepoSize, error)
+	ListStashes(ctx context.Context, repoPath string, opts Options) ([]Stash, error)
+	ApplyStash(ctx context.Context, repoPath string, index int, pop bool, opts Options) error
+	DropStash(ctx context.Context, repoPath string, index int, opts Options) error
+	// SetIdentity sets the author and committer of the commits created by the
+	// service; missing fields fall back to the repository git config
+	SetIdentity(identity Identity)
+	// SetProgressSink sets the sink receiving the ProgressEvent of every
+	// operation, nil to stop sending them
+	SetProgressSink(sink ProgressSink)
+	// SetCredentials sets the credentials of the remote hosts, tried before
+	// the netrc entries
+	SetCredentials(credentials []Credential)
+	// SetProxy sets the proxy of the HTTP(S) remotes, read from the
+	// environment until it is called
+	SetProxy(proxy ProxyConfig)
 }
 
 // GitModelService implements GitService
 type GitModelService struct {
-	logger Logger
+	logger             Logger
+	identity           Identity
+	authSources        []authSource
+	interactiveSources []authSource
+	proxy              ProxyConfig
+
+	events ProgressSink
+
+	// remote, auth and progress are set per operation by withOptions, and
+	// repoPath by withRepo
+	remote   string
+	auth     transport.AuthMethod
+	progress io.Writer
+	repoPath string
 }
 
 // NewGitService creates a new git service with default logger
 func NewGitService(logger Logger) GitService {
 	return &GitModelService{
-		logger: logger,
+		logger:             logger,
+		authSources:        defaultAuthSources,
+		interactiveSources: defaultInteractiveSources,
+		proxy:              ProxyFromEnvironment(),
+	}
+}
+
+// SetIdentity sets the identity used for the commits created by the service
+func (gs *GitModelService) SetIdentity(identity Identity) {
+	gs.identity = identity
+}
+
+// SetCredentials sets the credentials resolved per remote host before the
+// default sources
+func (gs *GitModelService) SetCredentials(credentials []Credential) {
+	gs.authSources = defaultAuthSources
+	if len(credentials) > 0 {
+		gs.authSources = append([]authSource{credentialsAuth(credentials)}, defaultAuthSources...)
 	}
 }
 
+// SetProxy sets the proxy of the HTTP(S) remotes
+func (gs *GitModelService) SetProxy(proxy ProxyConfig) {
+	gs.proxy = proxy
+}
+
+// SetProgressSink sets the sink receiving the progress events of the operations
+func (gs *GitModelService) SetProgressSink(sink ProgressSink) {
+	gs.events = sink
+}
+
+// withFields returns a copy of the service whose logger carries the given fields
+func (gs *GitModelService) withFields(args ...interface{}) *GitModelService {
+	scoped := *gs
+	scoped.logger = gs.logger.With(args...)
+	return &scoped
+}
+
+// withRepo returns a copy of the service working on the repository at
+// repoPath, whose logger carries it and the given fields
+func (gs *GitModelService) withRepo(repoPath string, args ...interface{}) *GitModelService {
+	scoped := gs.withFields(append([]interface{}{"repo", repoPath}, args...)...)
+	scoped.repoPath = repoPath
+	return scoped
+}
+
 // FetchLatest fetches latest updates from remote without modifying branches
-func (gs *GitModelService) FetchLatest(ctx context.Context, repoPath string) error {
-	repo, err := git.PlainOpen(repoPath)
+func (gs *GitModelService) FetchLatest(ctx context.Context, repoPath string, opts Options) (err error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	defer func() { gs.emit(ProgressEvent{Kind: EventRepoDone, Err: err}) }()
+
+	repo, err := openRepo(repoPath)
 	if err != nil {
-		return fmt.Errorf("failed to open repo: %w", err)
+		return err
 	}
 
 	return gs.fetch(ctx, repo)
 }
 
 func (gs *GitModelService) fetch(ctx context.Context, repo *git.Repository) error {
-	err := repo.FetchContext(ctx, &git.FetchOptions{
-		RemoteName: "origin",
-		Force:      true,
-		Tags:       git.AllTags,
+	return gs.fetchRemote(ctx, repo, gs.remoteName())
+}
+
+// fetchRemote fetches every branch and tag of the named remote, with the
+// fetch refspecs of the remote unless refSpecs are given
+func (gs *GitModelService) fetchRemote(ctx context.Context, repo *git.Repository, remoteName string, refSpecs ...config.RefSpec) error {
+	gs.emit(ProgressEvent{Kind: EventFetchStarted, Remote: remoteName})
+	err := gs.withAuth(ctx, remoteURL(repo, remoteName), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		return repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName:   remoteName,
+			RefSpecs:     refSpecs,
+			Force:        true,
+			Tags:         git.AllTags,
+			Auth:         auth,
+			ProxyOptions: proxy,
+			Progress:     gs.progress,
+		})
 	})
 	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
-		return fmt.Errorf("fetch failed: %w", err)
+		err = fmt.Errorf("fetch failed: %w", err)
+		gs.emit(ProgressEvent{Kind: EventFetchDone, Remote: remoteName, Err: err})
+		return err
+	}
+	gs.emit(ProgressEvent{Kind: EventFetchDone, Remote: remoteName})
+	return nil
+}
+
+// CloneRepository clones remoteURL into repoPath, naming the remote after
+// opts.Remote. Use TokenAuth to clone over HTTPS with a provider API token.
+func (gs *GitModelService) CloneRepository(ctx context.Context, remoteURL string, repoPath string, opts Options) error {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+
+	gs.logger.Debug("cloning repository", "url", remoteURL, "path", repoPath)
+	err := gs.withAuth(ctx, remoteURL, func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		_, err := git.PlainCloneContext(ctx, repoPath, false, &git.CloneOptions{
+			URL:          remoteURL,
+			RemoteName:   gs.remoteName(),
+			Auth:         auth,
+			ProxyOptions: proxy,
+			Progress:     gs.progress,
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clone %s: %w", remoteURL, err)
 	}
 	return nil
 }
 
 // UpdateAllBranchesProject aligns all local branches with their remote counterparts
-func (gs *GitModelService) UpdateAllBranchesProject(ctx context.Context, repoPath string) (*UpdateResult, error) {
+// Repositories with uncommitted changes are skipped unless opts.AutoStash is set,
+// and so are repositories without commits, on a detached HEAD or without the
+// remote, each with its UpdateResult.SkipReason.
+// When ctx is cancelled mid-run the partial result is returned along with the error.
+func (gs *GitModelService) UpdateAllBranchesProject(ctx context.Context, repoPath string, opts UpdateOptions) (_ *UpdateResult, err error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts.Options)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	defer func() { gs.emit(ProgressEvent{Kind: EventRepoDone, Err: err}) }()
 	result := &UpdateResult{
-		Updated: []string{},
-		Skipped: []string{},
-		Failed:  []string{},
+		Updated:     []string{},
+		Skipped:     []string{},
+		Failed:      []string{},
+		Verified:    map[string]bool{},
+		BranchTimes: map[string]time.Duration{},
 	}
+	start := time.Now()
+	defer func() { result.TotalTime = time.Since(start) }()
 
-	repo, err := git.PlainOpen(repoPath)
+	repo, err := openRepo(repoPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repo: %w", err)
+		return nil, err
+	}
+	if isBare(repo) {
+		return gs.updateBareRepository(ctx, repo, opts, result)
+	}
+
+	// repositories the update cannot work on are skipped with a clear reason
+	// before any network access, instead of failing halfway
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return gs.skipUpdate(result, SkipReasonNoCommits), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return gs.skipUpdate(result, SkipReasonDetachedHead), nil
+	}
+	if _, err := getRemote(repo, gs.remoteName()); errors.Is(err, ErrNoOriginRemote) {
+		return gs.skipUpdate(result, SkipReasonNoRemote), nil
+	} else if err != nil {
+		return nil, err
 	}
+	currentBranch := head.Name().Short()
+	result.OriginalHead = currentBranch
 
 	// Fetch latest updates from remote
 	gs.logger.Info("fetching latest updates from remote")
+	fetchStart := time.Now()
 	if err := gs.fetch(ctx, repo); err != nil {
 		return nil, err
 	}
+	result.FetchTime = time.Since(fetchStart)
 
-	currentBranch, err := gs.getCurrentBranch(repo)
+	gs.logger.With("branch", currentBranch).Info("protecting current branch")
+
+	// the default branch is shared by everyone, so it is never hard-reset
+	protected := opts.Protected
+	if defaultBranch, err := gs.defaultBranch(ctx, repo); err == nil {
+		protected = append(slices.Clip(protected), defaultBranch)
+	} else {
+		gs.logger.Debug("default branch not detected", "error", err)
+	}
+
+	worktree, err := repo.Worktree()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
 	}
-	gs.logger.Info("protecting current branch", "branch", currentBranch)
 
-	branches, err := repo.Branches()
+	dirty, err := hasUncommittedChanges(worktree)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list branches: %w", err)
+		return nil, err
+	}
+	// the checkout and reset of a branch deletes untracked files: they make
+	// the worktree dirty, unless opts.NoCheckout only checks out the branches
+	// that are not a fast-forward, which are then left as they are
+	untracked, err := hasUntrackedFiles(worktree)
+	if err != nil {
+		return nil, err
+	}
+	if untracked && !opts.NoCheckout {
+		dirty = true
+	}
+	if dirty && !opts.AutoStash {
+		gs.logger.Warn("skipping repository with uncommitted changes")
+		result.SkipReason = SkipReasonDirtyWorktree
+		result.Skipped = append(result.Skipped, currentBranch)
+		return result, nil
+	}
+	if dirty {
+		gs.logger.Info("stashing uncommitted changes")
+		if _, err := gs.runGitAs(ctx, repoPath, "stash", "push", "--include-untracked", "-m", "goktor autostash"); err != nil {
+			return nil, fmt.Errorf("failed to stash changes: %w", err)
+		}
+		result.Stashed = true
+		// whatever way the update ends, the stash is popped on the original
+		// branch, after the deferred restore of HEAD below; it stays put when
+		// that restore failed, popping it on another branch would only make
+		// things worse
+		defer func() {
+			if result.RestoreError == "" {
+				gs.logger.Info("restoring stashed changes")
+				_, popErr := runGit(context.WithoutCancel(ctx), repoPath, "stash", "pop")
+				if popErr == nil {
+					return
+				}
+				if err == nil {
+					err = fmt.Errorf("failed to restore stashed changes: %w", popErr)
+				}
+			}
+			result.StashLeft = true
+			gs.logger.Error("uncommitted changes are kept in the stash, restore them with git stash pop")
+			if err != nil {
+				err = fmt.Errorf("%w; uncommitted changes are kept in the stash", err)
+			}
+		}()
 	}
 
-	worktree, err := repo.Worktree()
+	branches, err := repo.Branches()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get worktree: %w", err)
+		return nil, fmt.Errorf("failed to list branches: %w", err)
 	}
 
-	// Process each branch
+	// Process each branch. Whatever happens once a branch is checked out,
+	// the original HEAD is checked out again before returning.
+	checkedOut := false
+	defer func() {
+		if checkedOut && !result.HeadRestored && result.RestoreError == "" {
+			if restoreErr := gs.restoreHead(worktree, head, result); err == nil {
+				err = restoreErr
+			}
+		}
+	}()
 	err = branches.ForEach(func(ref *plumbing.Reference) error {
 		// Check context cancellation
 		select {
@@ -127,38 +448,204 @@ func (gs *GitModelService) UpdateAllBranchesProject(ctx context.Context, repoPat
 
 		// Skip current branch to protect uncommitted changes
 		if branchName == currentBranch {
-			gs.logger.Debug("skipping current branch", "branch", branchName)
+			gs.logger.With("branch", branchName).Debug("skipping current branch")
 			result.Skipped = append(result.Skipped, branchName)
 			return nil
 		}
 
+		if !opts.selectsBranch(branchName) {
+			gs.logger.With("branch", branchName).Debug("skipping branch excluded by patterns")
+			result.Excluded = append(result.Excluded, branchName)
+			return nil
+		}
+
+		branchStart := time.Now()
+		defer func() { result.BranchTimes[branchName] = time.Since(branchStart) }()
+
+		if matchesAny(branchName, protected) {
+			fastForwarded, err := gs.fastForwardBranch(repo, branchName, ref, result)
+			if err != nil {
+				result.Failed = append(result.Failed, branchName)
+				gs.logger.With("branch", branchName).Error("failed to fast-forward branch", "error", err)
+				return nil
+			}
+			if !fastForwarded {
+				gs.logger.With("branch", branchName).Warn("protected branch is not a fast-forward of the remote, left as is")
+				result.Skipped = append(result.Skipped, branchName)
+				result.Protected = append(result.Protected, branchName)
+			}
+			return nil
+		}
+
+		if opts.NoCheckout {
+			fastForwarded, err := gs.fastForwardBranch(repo, branchName, ref, result)
+			if err != nil {
+				result.Failed = append(result.Failed, branchName)
+				gs.logger.With("branch", branchName).Error("failed to fast-forward branch", "error", err)
+				return nil
+			}
+			if fastForwarded {
+				return nil
+			}
+			if untracked && !result.Stashed {
+				gs.logger.With("branch", branchName).Warn("branch is not a fast-forward and its checkout would delete untracked files, left as is")
+				result.Skipped = append(result.Skipped, branchName)
+				return nil
+			}
+		}
+
+		checkedOut = true
 		if err := gs.updateBranch(repo, worktree, branchName, ref, result); err != nil {
 			result.Failed = append(result.Failed, branchName)
-			gs.logger.Error("failed to update branch", "branch", branchName, "error", err)
+			gs.logger.With("branch", branchName).Error("failed to update branch", "error", err)
 			return nil
 		}
 		return nil
 	})
 
-	if err != nil {
-		return nil, fmt.Errorf("failed processing branches: %w", err)
+	// A cancelled context stops the loop between branches; the repository is
+	// still restored below so an interrupted run never leaves it half updated
+	interrupted := err != nil && ctx.Err() != nil
+	if err != nil && !interrupted {
+		return result, fmt.Errorf("failed processing branches: %w", err)
+	}
+	if interrupted {
+		gs.logger.Warn("update interrupted, restoring repository state")
+		result.Interrupted = true
 	}
 
-	// Checkout back to original branch
-	if err := worktree.Checkout(&git.CheckoutOptions{
-		Branch: plumbing.NewBranchReferenceName(currentBranch),
-	}); err != nil {
-		return nil, fmt.Errorf("failed to checkout back to %s: %w", currentBranch, err)
+	// Checkout back to original branch before the stash is popped
+	if checkedOut {
+		if err := gs.restoreHead(worktree, head, result); err != nil {
+			return result, err
+		}
+	}
+
+	if opts.RecurseSubmodules && !interrupted {
+		submodules, err := gs.syncSubmodules(ctx, repoPath, worktree)
+		if err != nil {
+			gs.logger.Error("failed to sync submodules", "error", err)
+			result.SubmoduleError = err.Error()
+		}
+		result.Submodules = submodules
+	}
+
+	if err := gs.verifyUpdate(repo, worktree, result); err != nil {
+		return nil, fmt.Errorf("failed to verify update: %w", err)
 	}
 
 	gs.logger.Info("update completed",
 		"updated", len(result.Updated),
 		"skipped", len(result.Skipped),
-		"failed", len(result.Failed))
+		"failed", len(result.Failed),
+		"fetch_time", result.FetchTime,
+		"total_time", time.Since(start))
 
+	if interrupted {
+		return result, fmt.Errorf("update interrupted: %w", ctx.Err())
+	}
 	return result, nil
 }
 
+// restoreHead checks out head again, its branch or its commit when it was
+// detached, and records the outcome in result
+func (gs *GitModelService) restoreHead(worktree *git.Worktree, head *plumbing.Reference, result *UpdateResult) error {
+	opts := &git.CheckoutOptions{Hash: head.Hash()}
+	if head.Name().IsBranch() {
+		opts = &git.CheckoutOptions{Branch: head.Name()}
+	}
+	if err := worktree.Checkout(opts); err != nil {
+		result.RestoreError = err.Error()
+		gs.logger.Error("failed to restore the original HEAD", "head", result.OriginalHead, "error", err)
+		return fmt.Errorf("failed to checkout back to %s: %w", result.OriginalHead, err)
+	}
+	result.HeadRestored = true
+	return nil
+}
+
+// skipUpdate records reason as the reason the whole repository was skipped
+func (gs *GitModelService) skipUpdate(result *UpdateResult, reason string) *UpdateResult {
+	gs.logger.Warn("skipping repository", "reason", reason)
+	result.SkipReason = reason
+	return result
+}
+
+// verifyUpdate re-reads every updated ref and compares it with its remote
+// counterpart, then checks the worktree of the restored branch is clean;
+// worktree is nil for a bare repository.
+func (gs *GitModelService) verifyUpdate(repo *git.Repository, worktree *git.Worktree, result *UpdateResult) error {
+	for _, branchName := range result.Updated {
+		localRef, err := repo.Reference(plumbing.NewBranchReferenceName(branchName), true)
+		if err != nil {
+			result.Verified[branchName] = false
+			gs.logger.With("branch", branchName).Warn("verification failed: local branch not found")
+			continue
+		}
+		remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(gs.remoteName(), branchName), true)
+		if err != nil {
+			result.Verified[branchName] = false
+			gs.logger.With("branch", branchName).Warn("verification failed: remote branch not found")
+			continue
+		}
+
+		result.Verified[branchName] = localRef.Hash() == remoteRef.Hash()
+		if !result.Verified[branchName] {
+			gs.logger.With("branch", branchName).Warn("verification failed: branch differs from remote",
+				"local", localRef.Hash().String(),
+				"remote", remoteRef.Hash().String())
+		}
+	}
+
+	if worktree == nil {
+		// a bare repository has no worktree to leave dirty
+		result.WorktreeClean = true
+		return nil
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	result.WorktreeClean = status.IsClean()
+	if !result.WorktreeClean {
+		gs.logger.Warn("worktree is not clean after update")
+	}
+	return nil
+}
+
+// hasUncommittedChanges reports staged or unstaged modifications of tracked
+// files. Untracked files are ignored: callers which check out other branches
+// with go-git must also check hasUntrackedFiles, as that checkout deletes them.
+func hasUncommittedChanges(worktree *git.Worktree) (bool, error) {
+	status, err := worktree.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	for _, file := range status {
+		if file.Staging == git.Untracked && file.Worktree == git.Untracked {
+			continue
+		}
+		if file.Staging != git.Unmodified || file.Worktree != git.Unmodified {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hasUntrackedFiles reports files of the worktree that are neither tracked
+// nor ignored
+func hasUntrackedFiles(worktree *git.Worktree) (bool, error) {
+	status, err := worktree.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	for _, file := range status {
+		if file.Worktree == git.Untracked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (gs *GitModelService) getCurrentBranch(repo *git.Repository) (string, error) {
 	head, err := repo.Head()
 	if err != nil {
@@ -169,9 +656,10 @@ func (gs *GitModelService) getCurrentBranch(repo *git.Repository) (string, error
 
 // updateBranch updates a single branch
 func (gs *GitModelService) updateBranch(repo *git.Repository, worktree *git.Worktree, branchName string, ref *plumbing.Reference, result *UpdateResult) error {
-	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branchName), true)
+	log := gs.logger.With("branch", branchName)
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(gs.remoteName(), branchName), true)
 	if err != nil {
-		gs.logger.Warn("remote tracking branch not found", "branch", branchName)
+		log.Warn("remote tracking branch not found")
 		result.Skipped = append(result.Skipped, branchName)
 		return nil
 	}
@@ -190,56 +678,150 @@ func (gs *GitModelService) updateBranch(repo *git.Repository, worktree *git.Work
 		return err
 	}
 
-	gs.logger.Info("branch updated", "branch", branchName)
+	log.Info("branch updated")
 	result.Updated = append(result.Updated, branchName)
+	gs.emit(ProgressEvent{Kind: EventBranchUpdated, Branch: branchName})
 	return nil
 }
 
-// UpdateRemote updates the origin remote URL and verifies connectivity
-func (gs *GitModelService) UpdateRemote(ctx context.Context, repoPath string, newRemote string, force bool) error {
-	repo, err := git.PlainOpen(repoPath)
+// fastForwardBranch moves a branch ref to its remote counterpart without
+// touching the worktree. It returns false when the update is not a
+// fast-forward and the caller has to fall back to checkout and reset.
+func (gs *GitModelService) fastForwardBranch(repo *git.Repository, branchName string, ref *plumbing.Reference, result *UpdateResult) (bool, error) {
+	log := gs.logger.With("branch", branchName)
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(gs.remoteName(), branchName), true)
+	if err != nil {
+		log.Warn("remote tracking branch not found")
+		result.Skipped = append(result.Skipped, branchName)
+		return true, nil
+	}
+
+	if ref.Hash() != remoteRef.Hash() {
+		localCommit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return false, fmt.Errorf("failed to load local commit: %w", err)
+		}
+		remoteCommit, err := repo.CommitObject(remoteRef.Hash())
+		if err != nil {
+			return false, fmt.Errorf("failed to load remote commit: %w", err)
+		}
+		isFastForward, err := localCommit.IsAncestor(remoteCommit)
+		if err != nil {
+			return false, fmt.Errorf("failed to check ancestry: %w", err)
+		}
+		if !isFastForward {
+			log.Debug("not a fast-forward, falling back to checkout")
+			return false, nil
+		}
+
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(ref.Name(), remoteRef.Hash())); err != nil {
+			return false, fmt.Errorf("failed to set reference: %w", err)
+		}
+	}
+
+	log.Info("branch fast-forwarded")
+	result.Updated = append(result.Updated, branchName)
+	gs.emit(ProgressEvent{Kind: EventBranchUpdated, Branch: branchName})
+	return true, nil
+}
+
+// UpdateRemote rewrites the fetch URL of opts.Remote and verifies connectivity.
+// Extra URLs configured on the remote (push mirrors) are kept as they are.
+func (gs *GitModelService) UpdateRemote(ctx context.Context, repoPath string, newRemote string, opts Options) error {
+	return gs.updateRemoteURLs(ctx, repoPath, opts, RemoteChange{NewRemote: newRemote}.apply)
+}
+
+// ErrRemoteUnchanged is returned when a rewrite or conversion leaves every URL
+// of the remote as it was; the repository is not touched
+var ErrRemoteUnchanged = errors.New("remote URLs unchanged")
+
+// RewriteRemote applies a sed-style substitution to every URL of a remote. It
+// returns ErrRemoteUnchanged when the expression matches none of the URLs.
+func (gs *GitModelService) RewriteRemote(ctx context.Context, repoPath string, rewrite *RemoteRewrite, opts Options) error {
+	return gs.updateRemoteURLs(ctx, repoPath, opts, RemoteChange{Rewrite: rewrite}.apply)
+}
+
+// ConvertRemote switches the URLs of a remote to SSH or HTTPS (see
+// ConvertRemoteURL). URLs that cannot be converted, such as local paths, are
+// kept. It returns ErrRemoteUnchanged when every URL already uses the protocol.
+func (gs *GitModelService) ConvertRemote(ctx context.Context, repoPath string, protocol string, opts Options) error {
+	return gs.updateRemoteURLs(ctx, repoPath, opts, RemoteChange{Protocol: protocol}.apply)
+}
+
+// updateRemoteURLs replaces the URLs of opts.Remote with the ones built by newURLs, then
+// fetches to verify them, restoring the old URLs when the fetch fails unless opts.Force is set
+func (gs *GitModelService) updateRemoteURLs(ctx context.Context, repoPath string, opts Options, newURLs func([]string) ([]string, error)) error {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	remoteName := gs.remoteName()
+	gs = gs.withRepo(repoPath, "remote", remoteName)
+	repo, err := openRepo(repoPath)
 	if err != nil {
-		return fmt.Errorf("failed to open repo: %w", err)
+		return err
 	}
 
-	gs.logger.Debug("updating remote", "repo", repoPath)
+	gs.logger.Debug("updating remote")
 
 	cfg, err := repo.Storer.Config()
 	if err != nil {
 		return fmt.Errorf("failed to get config: %w", err)
 	}
 
-	remoteCfg, ok := cfg.Remotes["origin"]
-	if !ok {
-		return fmt.Errorf("remote 'origin' not found in config")
+	remoteCfg, ok := cfg.Remotes[remoteName]
+	if !ok || len(remoteCfg.URLs) == 0 {
+		return fmt.Errorf("remote '%s' not found in config: %w", remoteName, ErrNoOriginRemote)
 	}
 
-	oldRemote := remoteCfg.URLs[0]
-	newRemoteURL := parseRemoteURL(newRemote, oldRemote)
+	oldURLs := append([]string(nil), remoteCfg.URLs...)
+	urls, err := newURLs(append([]string(nil), oldURLs...))
+	if err != nil {
+		return err
+	}
 
-	gs.logger.Debug("updating remote", "from", oldRemote, "to", newRemoteURL)
+	if opts.DryRun {
+		gs.logger.Info("dry-run: would update remote", "from", oldURLs[0], "to", urls[0])
+		return nil
+	}
+	gs.logger.Debug("updating remote", "from", oldURLs[0], "to", urls[0])
 
-	remoteCfg.URLs = []string{newRemoteURL}
+	if err := setRemoteURLs(cfg, remoteCfg, urls); err != nil {
+		return err
+	}
 	if err := repo.Storer.SetConfig(cfg); err != nil {
 		return fmt.Errorf("failed to set config: %w", err)
 	}
 
 	fetchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	if err := gs.fetch(fetchCtx, repo); err != nil {
-		if force {
+	if err := gs.fetchRemote(fetchCtx, repo, remoteName); err != nil {
+		if opts.Force {
 			gs.logger.Warn("fetch failed but force flag is set, skipping rollback", "error", err)
 			return nil
 		}
-		remoteCfg.URLs = []string{oldRemote}
-		if rollbackErr := repo.Storer.SetConfig(cfg); rollbackErr != nil {
+		rollbackErr := setRemoteURLs(cfg, remoteCfg, oldURLs)
+		if rollbackErr == nil {
+			rollbackErr = repo.Storer.SetConfig(cfg)
+		}
+		if rollbackErr != nil {
 			return fmt.Errorf("fetch failed and rollback failed: fetch=%w, rollback=%w", err, rollbackErr)
 		}
 		return fmt.Errorf("fetch failed, rollback completed: %w", err)
 
 	}
 
-	gs.logger.Info("remote updated successfully: ", "new remote", newRemoteURL)
+	gs.logger.Info("remote updated successfully", "new remote", urls[0])
+	return nil
+}
+
+// setRemoteURLs replaces the URLs of a remote, keeping their order. go-git
+// keeps the position of values already present in the raw config, so the
+// old URLs are dropped from it first.
+func setRemoteURLs(cfg *config.Config, remote *config.RemoteConfig, urls []string) error {
+	remote.URLs = nil
+	if _, err := cfg.Marshal(); err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	remote.URLs = urls
 	return nil
 }
 
@@ -257,6 +839,19 @@ func buildLocalRemote(newRemote string, oldRemote string) string {
 	return filepath.Join(newRemote, projectName)
 }
 
+// remoteURL returns the first URL of a remote, or an empty string
+func remoteURL(repo *git.Repository, remoteName string) string {
+	remote, err := repo.Remote(remoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+	return remote.Config().URLs[0]
+}
+
+func isHTTPRemote(remote string) bool {
+	return strings.HasPrefix(remote, "http://") || strings.HasPrefix(remote, "https://")
+}
+
 func isNetworkRemote(remote string) bool {
 	return strings.HasPrefix(remote, "http://") ||
 		strings.HasPrefix(remote, "https://") ||
@@ -289,7 +884,12 @@ func buildNetworkRemote(newRemote, oldRemote string) string {
 	return path.Join(newRemote, projectName+".git")
 }
 
-func (gs *GitModelService) DeleteMergedBranches(ctx context.Context, repoPath string, endDate string, dryRun bool) ([]DeleteMergedBranchesResult, error) {
+// DeleteMergedBranches deletes the remote feature/, bugfix/ and hotfix/
+// branches merged into a release branch before endDate. When keepDays is
+// positive each branch tip is first backed up under refs/goktor/deleted/ on the
+// remote, restorable with RestoreBranch for keepDays days; expired backups are
+// purged on every run except dry runs.
+func (gs *GitModelService) DeleteMergedBranches(ctx context.Context, repoPath string, endDate string, keepDays int, opts Options) ([]DeleteMergedBranchesResult, error) {
 	if ctx == nil {
 		return nil, fmt.Errorf("context cannot be nil")
 	}
@@ -299,21 +899,44 @@ func (gs *GitModelService) DeleteMergedBranches(ctx context.Context, repoPath st
 	if endDate == "" {
 		return nil, fmt.Errorf("end date cannot be empty")
 	}
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	dryRun := opts.DryRun
+	remoteName := gs.remoteName()
 
 	cutoff, err := time.Parse("2006-01-02", endDate)
 	if err != nil {
 		return nil, fmt.Errorf("invalid end date %q, expected YYYY-MM-DD: %w", endDate, err)
 	}
 
-	repo, err := git.PlainOpen(repoPath)
+	repo, err := openRepo(repoPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository: %w", err)
+		return nil, err
 	}
 
 	if err := gs.fetch(ctx, repo); err != nil {
 		return nil, err
 	}
 
+	var softDelete *softDeletePolicy
+	if keepDays > 0 {
+		tagger, err := ResolveIdentity(repoPath, gs.identity)
+		if err != nil {
+			tagger = goktorIdentity
+		}
+		softDelete = &softDeletePolicy{keep: time.Duration(keepDays) * 24 * time.Hour, tagger: tagger}
+	}
+	if !dryRun {
+		purged, err := gs.purgeExpiredBranches(ctx, repo, remoteName)
+		if err != nil {
+			gs.logger.Warn("failed to purge expired branch backups", "error", err)
+		}
+		if len(purged) > 0 {
+			gs.logger.Info("purged expired branch backups", "branches", purged)
+		}
+	}
+
 	featureResults := &DeleteMergedBranchesResult{
 		Deleted: []string{},
 		DryRun:  []string{},
@@ -321,14 +944,14 @@ func (gs *GitModelService) DeleteMergedBranches(ctx context.Context, repoPath st
 		Failed:  []string{},
 	}
 
-	remoteBranches, err := gs.remoteBranches(repo, "origin")
+	remoteBranches, err := gs.remoteBranches(repo, remoteName)
 	if err != nil {
 		return nil, err
 	}
 
 	gs.logger.Info("getting release branches")
-	releaseBranches := filterRemoteBranches(remoteBranches, "origin/release/")
-	gs.logger.Info("releases branches:", len(releaseBranches))
+	releaseBranches := filterRemoteBranches(remoteBranches, remoteName+"/release/")
+	gs.logger.Info("release branches", "count", len(releaseBranches))
 	releaseHistories, err := gs.buildReleaseHistories(repo, releaseBranches, cutoff)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build release histories: %w", err)
@@ -338,27 +961,27 @@ func (gs *GitModelService) DeleteMergedBranches(ctx context.Context, repoPath st
 	if err != nil {
 		return nil, fmt.Errorf("failed to index release ancestry: %w", err)
 	}
-	gs.logger.Info("indexed release ancestry commits:", len(releaseIndex))
+	gs.logger.Info("indexed release ancestry commits", "count", len(releaseIndex))
 
 	gs.logger.Info("getting feature branches")
-	featureBranches := filterRemoteBranches(remoteBranches, "origin/feature/")
-	gs.logger.Info("feature branches:", len(featureBranches))
+	featureBranches := filterRemoteBranches(remoteBranches, remoteName+"/feature/")
+	gs.logger.Info("feature branches", "count", len(featureBranches))
 	gs.logger.Info("getting bugfix branches")
-	bugfixBranches := filterRemoteBranches(remoteBranches, "origin/bugfix/")
-	gs.logger.Info("bugfix branches:", len(bugfixBranches))
+	bugfixBranches := filterRemoteBranches(remoteBranches, remoteName+"/bugfix/")
+	gs.logger.Info("bugfix branches", "count", len(bugfixBranches))
 	gs.logger.Info("getting hotfix branches")
-	hotfixBranches := filterRemoteBranches(remoteBranches, "origin/hotfix/")
-	gs.logger.Info("hotfix branches:", len(hotfixBranches))
+	hotfixBranches := filterRemoteBranches(remoteBranches, remoteName+"/hotfix/")
+	gs.logger.Info("hotfix branches", "count", len(hotfixBranches))
 
-	featureResults, err = gs.deleteMergedBranches(ctx, featureBranches, repo, releaseIndex, cutoff, dryRun)
+	featureResults, err = gs.deleteMergedBranches(ctx, featureBranches, repo, releaseIndex, cutoff, dryRun, softDelete)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete feature merged branches: %w", err)
 	}
-	bugfixResults, err := gs.deleteMergedBranches(ctx, bugfixBranches, repo, releaseIndex, cutoff, dryRun)
+	bugfixResults, err := gs.deleteMergedBranches(ctx, bugfixBranches, repo, releaseIndex, cutoff, dryRun, softDelete)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete bugfix merged branches: %w", err)
 	}
-	hotfixResults, err := gs.deleteMergedBranches(ctx, hotfixBranches, repo, releaseIndex, cutoff, dryRun)
+	hotfixResults, err := gs.deleteMergedBranches(ctx, hotfixBranches, repo, releaseIndex, cutoff, dryRun, softDelete)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete hotfix merged branches: %w", err)
 	}
@@ -371,7 +994,7 @@ func (gs *GitModelService) DeleteMergedBranches(ctx context.Context, repoPath st
 	return result, nil
 }
 
-func (gs *GitModelService) deleteMergedBranches(ctx context.Context, branchesToDelete []string, repo *git.Repository, releaseIndex map[plumbing.Hash]mergedReleaseInfo, cutoff time.Time, dryRun bool) (*DeleteMergedBranchesResult, error) {
+func (gs *GitModelService) deleteMergedBranches(ctx context.Context, branchesToDelete []string, repo *git.Repository, releaseIndex map[plumbing.Hash]mergedReleaseInfo, cutoff time.Time, dryRun bool, softDelete *softDeletePolicy) (*DeleteMergedBranchesResult, error) {
 	result := &DeleteMergedBranchesResult{
 		Deleted: []string{},
 		DryRun:  []string{},
@@ -386,11 +1009,12 @@ func (gs *GitModelService) deleteMergedBranches(ctx context.Context, branchesToD
 		default:
 		}
 
-		gs.logger.Info("inspecting branch", "branch", branchToDelete)
+		log := gs.logger.With("branch", branchToDelete)
+		log.Info("inspecting branch")
 		mergedAt, mergedInto, ok, err := gs.findMergedIntoReleaseDate(repo, branchToDelete, releaseIndex, cutoff)
 		if err != nil {
 			result.Failed = append(result.Failed, branchToDelete)
-			gs.logger.Error("failed to inspect branch", "branch", branchToDelete, "error", err)
+			log.Error("failed to inspect branch", "error", err)
 			continue
 		}
 
@@ -400,33 +1024,35 @@ func (gs *GitModelService) deleteMergedBranches(ctx context.Context, branchesToD
 		}
 
 		if mergedAt.After(cutoff) {
-			gs.logger.Debug("branch merged after cutoff date",
-				"branch", branchToDelete,
+			log.Debug("branch merged after cutoff date",
 				"merged_at", mergedAt.Format(time.RFC3339),
 				"merged_into", mergedInto)
 			result.Skipped = append(result.Skipped, branchToDelete)
 			continue
 		}
 
-		remoteBranchName := strings.TrimPrefix(branchToDelete, "origin/")
+		remoteBranchName := strings.TrimPrefix(branchToDelete, gs.remoteName()+"/")
 
 		if dryRun {
-			gs.logger.Info("dry-run: would delete remote branch",
-				"branch", remoteBranchName,
+			log.Info("dry-run: would delete remote branch",
 				"merged_at", mergedAt.Format("2006-01-02"),
 				"merged_into", mergedInto)
 			result.DryRun = append(result.DryRun, remoteBranchName)
 			continue
 		}
 
-		if err := gs.deleteRemoteBranch(repo, "origin", remoteBranchName); err != nil {
+		if softDelete != nil {
+			err = gs.softDeleteRemoteBranch(ctx, repo, gs.remoteName(), remoteBranchName, softDelete)
+		} else {
+			err = gs.deleteRemoteBranch(ctx, repo, gs.remoteName(), remoteBranchName)
+		}
+		if err != nil {
 			result.Failed = append(result.Failed, remoteBranchName)
-			gs.logger.Error("failed to delete remote branch", "branch", remoteBranchName, "error", err)
+			log.Error("failed to delete remote branch", "error", err)
 			continue
 		}
 
-		gs.logger.Info("deleted remote branch",
-			"branch", remoteBranchName,
+		log.Info("deleted remote branch",
 			"merged_at", mergedAt.Format("2006-01-02"),
 			"merged_into", mergedInto)
 
@@ -482,7 +1108,7 @@ func filterRemoteBranches(branches []string, prefix string) []string {
 }
 
 func (gs *GitModelService) findMergedIntoReleaseDate(repo *git.Repository, featureBranch string, releaseIndex map[plumbing.Hash]mergedReleaseInfo, cutoff time.Time) (time.Time, string, bool, error) {
-	featureRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", strings.TrimPrefix(featureBranch, "origin/")), true)
+	featureRef, err := repo.Reference(plumbing.NewRemoteReferenceName(gs.remoteName(), strings.TrimPrefix(featureBranch, gs.remoteName()+"/")), true)
 	if err != nil {
 		return time.Time{}, "", false, fmt.Errorf("failed to resolve feature branch %s: %w", featureBranch, err)
 	}
@@ -544,14 +1170,19 @@ func (gs *GitModelService) firstParentMergeDateBeforeCutoff(featureCommit *objec
 	return time.Time{}, false, nil
 }
 
-func (gs *GitModelService) deleteRemoteBranch(repo *git.Repository, remoteName string, branchName string) error {
+func (gs *GitModelService) deleteRemoteBranch(ctx context.Context, repo *git.Repository, remoteName string, branchName string) error {
 	refName := plumbing.NewBranchReferenceName(branchName)
 
-	err := repo.Push(&git.PushOptions{
-		RemoteName: remoteName,
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(":" + refName.String()),
-		},
+	err := gs.withAuth(ctx, remoteURL(repo, remoteName), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		return repo.PushContext(ctx, &git.PushOptions{
+			RemoteName: remoteName,
+			RefSpecs: []config.RefSpec{
+				config.RefSpec(":" + refName.String()),
+			},
+			Auth:         auth,
+			ProxyOptions: proxy,
+			Progress:     gs.progress,
+		})
 	})
 
 	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
@@ -565,7 +1196,7 @@ func (gs *GitModelService) buildReleaseHistories(repo *git.Repository, releaseBr
 	histories := make([]releaseHistory, 0, len(releaseBranches))
 
 	for _, releaseBranch := range releaseBranches {
-		releaseRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", strings.TrimPrefix(releaseBranch, "origin/")), true)
+		releaseRef, err := repo.Reference(plumbing.NewRemoteReferenceName(gs.remoteName(), strings.TrimPrefix(releaseBranch, gs.remoteName()+"/")), true)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve release branch %s: %w", releaseBranch, err)
 		}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// RepoKind tells the repositories with a worktree from the bare ones
+type RepoKind string
+
+const (
+	// RepoKindNone is a directory that is not a git repository
+	RepoKindNone RepoKind = ""
+	// RepoKindWorktree is a repository checked out in a worktree
+	RepoKindWorktree RepoKind = "worktree"
+	// RepoKindBare is a repository without worktree, such as the mirrors of
+	// goktor mr-repo mirror or a git clone --bare or --mirror
+	RepoKindBare RepoKind = "bare"
+)
+
+// DetectRepoKind classifies the directory at path without opening the
+// repository: a worktree holds a .git directory or file, and a bare
+// repository holds HEAD, objects and refs itself
+func DetectRepoKind(path string) RepoKind {
+	if _, err := os.Stat(filepath.Join(path, git.GitDirName)); err == nil {
+		return RepoKindWorktree
+	}
+	head, err := os.Stat(filepath.Join(path, "HEAD"))
+	if err != nil || head.IsDir() {
+		return RepoKindNone
+	}
+	for _, dir := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(path, dir)); err != nil || !info.IsDir() {
+			return RepoKindNone
+		}
+	}
+	return RepoKindBare
+}
+
+// isBare reports whether repo was opened without worktree
+func isBare(repo *git.Repository) bool {
+	_, err := repo.Worktree()
+	return errors.Is(err, git.ErrIsBareRepository)
+}
+
+// updateBareRepository is the ref-only UpdateAllBranchesProject of a bare
+// repository. A mirror, whose fetch refspec writes the branches themselves,
+// is updated by the fetch alone. Otherwise the remote branches are fetched to
+// their remote-tracking refs and every local branch is moved to its remote
+// counterpart: protected ones only when it is a fast-forward, the others
+// whatever their history, as there is no work to lose without a worktree.
+func (gs *GitModelService) updateBareRepository(ctx context.Context, repo *git.Repository, opts UpdateOptions, result *UpdateResult) (*UpdateResult, error) {
+	result.Bare = true
+	remote, err := getRemote(repo, gs.remoteName())
+	if errors.Is(err, ErrNoOriginRemote) {
+		return gs.skipUpdate(result, SkipReasonNoRemote), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	before, err := branchHashes(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	mirror := fetchesIntoBranches(remote.Config().Fetch)
+	var refSpecs []config.RefSpec
+	if len(remote.Config().Fetch) == 0 {
+		// git clone --bare configures no fetch refspec
+		refSpecs = []config.RefSpec{config.RefSpec(fmt.Sprintf(config.DefaultFetchRefSpec, gs.remoteName()))}
+	}
+	gs.logger.Info("fetching latest updates from remote", "bare", true, "mirror", mirror)
+	fetchStart := time.Now()
+	if err := gs.fetchRemote(ctx, repo, gs.remoteName(), refSpecs...); err != nil {
+		return nil, err
+	}
+	result.FetchTime = time.Since(fetchStart)
+
+	if mirror {
+		after, err := branchHashes(repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, branchName := range slices.Sorted(maps.Keys(after)) {
+			if hash, ok := before[branchName]; !ok || hash != after[branchName] {
+				result.Updated = append(result.Updated, branchName)
+				gs.emit(ProgressEvent{Kind: EventBranchUpdated, Branch: branchName})
+			}
+		}
+		if err := gs.verifyMirror(ctx, repo, remote, result); err != nil {
+			return nil, fmt.Errorf("failed to verify update: %w", err)
+		}
+		result.WorktreeClean = true
+		gs.logger.Info("mirror updated", "updated", len(result.Updated))
+		return result, nil
+	}
+
+	protected := opts.Protected
+	if defaultBranch, err := gs.defaultBranch(ctx, repo); err == nil {
+		protected = append(slices.Clip(protected), defaultBranch)
+	}
+
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		branchName := ref.Name().Short()
+		if !opts.selectsBranch(branchName) {
+			result.Excluded = append(result.Excluded, branchName)
+			return nil
+		}
+		branchStart := time.Now()
+		defer func() { result.BranchTimes[branchName] = time.Since(branchStart) }()
+
+		log := gs.logger.With("branch", branchName)
+		fastForwarded, err := gs.fastForwardBranch(repo, branchName, ref, result)
+		if err != nil {
+			result.Failed = append(result.Failed, branchName)
+			log.Error("failed to fast-forward branch", "error", err)
+			return nil
+		}
+		if fastForwarded {
+			return nil
+		}
+		if matchesAny(branchName, protected) {
+			log.Warn("protected branch is not a fast-forward of the remote, left as is")
+			result.Skipped = append(result.Skipped, branchName)
+			result.Protected = append(result.Protected, branchName)
+			return nil
+		}
+		remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(gs.remoteName(), branchName), true)
+		if err == nil {
+			err = repo.Storer.SetReference(plumbing.NewHashReference(ref.Name(), remoteRef.Hash()))
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, branchName)
+			log.Error("failed to update branch", "error", err)
+			return nil
+		}
+		log.Info("branch reset to remote")
+		result.Updated = append(result.Updated, branchName)
+		gs.emit(ProgressEvent{Kind: EventBranchUpdated, Branch: branchName})
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			result.Interrupted = true
+			return result, fmt.Errorf("update interrupted: %w", err)
+		}
+		return result, fmt.Errorf("failed processing branches: %w", err)
+	}
+
+	if err := gs.verifyUpdate(repo, nil, result); err != nil {
+		return nil, fmt.Errorf("failed to verify update: %w", err)
+	}
+	gs.logger.Info("update completed",
+		"updated", len(result.Updated),
+		"skipped", len(result.Skipped),
+		"failed", len(result.Failed),
+		"fetch_time", result.FetchTime)
+	return result, nil
+}
+
+// verifyMirror compares every updated branch of a mirror with the branch the
+// remote advertises, as a mirror has no remote-tracking refs to compare with
+func (gs *GitModelService) verifyMirror(ctx context.Context, repo *git.Repository, remote *git.Remote, result *UpdateResult) error {
+	if len(result.Updated) == 0 {
+		return nil
+	}
+	var advertised []*plumbing.Reference
+	err := gs.withAuth(ctx, remoteURL(repo, gs.remoteName()), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		var err error
+		advertised, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth, ProxyOptions: proxy})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list remote refs: %w", err)
+	}
+	remoteHashes := map[string]plumbing.Hash{}
+	for _, ref := range advertised {
+		if ref.Name().IsBranch() {
+			remoteHashes[ref.Name().Short()] = ref.Hash()
+		}
+	}
+
+	for _, branchName := range result.Updated {
+		localRef, err := repo.Reference(plumbing.NewBranchReferenceName(branchName), true)
+		remoteHash, ok := remoteHashes[branchName]
+		result.Verified[branchName] = err == nil && ok && localRef.Hash() == remoteHash
+		if !result.Verified[branchName] {
+			gs.logger.With("branch", branchName).Warn("verification failed: local branch differs from the remote")
+		}
+	}
+	return nil
+}
+
+// branchHashes returns the commit of every local branch
+func branchHashes(repo *git.Repository) (map[string]plumbing.Hash, error) {
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	hashes := map[string]plumbing.Hash{}
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		hashes[ref.Name().Short()] = ref.Hash()
+		return nil
+	})
+	return hashes, err
+}
+
+// fetchesIntoBranches reports whether one of refSpecs writes the fetched
+// branches to local branches, as the +refs/*:refs/* of git clone --mirror does
+func fetchesIntoBranches(refSpecs []config.RefSpec) bool {
+	branch := plumbing.NewBranchReferenceName("main")
+	for _, refSpec := range refSpecs {
+		if refSpec.Match(branch) && strings.HasPrefix(refSpec.Dst(branch).String(), "refs/heads/") {
+			return true
+		}
+	}
+	return false
+}
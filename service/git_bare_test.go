@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestDetectRepoKind(t *testing.T) {
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	tests := []struct {
+		path string
+		want RepoKind
+	}{
+		{repoPath, RepoKindWorktree},
+		{bareDir, RepoKindBare},
+		{t.TempDir(), RepoKindNone},
+		{filepath.Join(repoPath, "test.txt"), RepoKindNone},
+	}
+	for _, tt := range tests {
+		if got := DetectRepoKind(tt.path); got != tt.want {
+			t.Errorf("DetectRepoKind(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// cloneBare clones remote with git clone and the given option, --bare or --mirror
+func cloneBare(t *testing.T, remote string, option string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not available")
+	}
+	clonePath := filepath.Join(t.TempDir(), "clone.git")
+	if _, err := runGit(context.Background(), t.TempDir(), "clone", option, remote, clonePath); err != nil {
+		t.Fatalf("git clone %s failed: %v", option, err)
+	}
+	return clonePath
+}
+
+// setRemoteBranch points branch of the remote to hash, returning its previous commit
+func setRemoteBranch(t *testing.T, bareDir, branch string, hash plumbing.Hash) plumbing.Hash {
+	t.Helper()
+	remote, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatalf("failed to open remote: %v", err)
+	}
+	ref, err := remote.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", branch, err)
+	}
+	if err := remote.Storer.SetReference(plumbing.NewHashReference(ref.Name(), hash)); err != nil {
+		t.Fatalf("failed to move %s: %v", branch, err)
+	}
+	return ref.Hash()
+}
+
+// setupRewoundRemote returns the remote of setupTestRepoWithBranches with
+// feature and develop on master, and the clone of it made with option,
+// --bare or --mirror. Once cloned, feature and develop are rewound to their
+// first commit, so the update of the clone is not a fast-forward.
+func setupRewoundRemote(t *testing.T, option string) (string, string, plumbing.Hash, func()) {
+	t.Helper()
+	_, bareDir, cleanup := setupTestRepoWithBranches(t)
+	remote, err := git.PlainOpen(bareDir)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to open remote: %v", err)
+	}
+	master, err := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to read master: %v", err)
+	}
+	first := setRemoteBranch(t, bareDir, "feature", master.Hash())
+	setRemoteBranch(t, bareDir, "develop", master.Hash())
+	clonePath := cloneBare(t, bareDir, option)
+	setRemoteBranch(t, bareDir, "feature", first)
+	setRemoteBranch(t, bareDir, "develop", first)
+	pushRemoteCommit(t, bareDir, "remote.txt")
+	return bareDir, clonePath, first, cleanup
+}
+
+func TestGitModelService_UpdateAllBranchesProject_Bare(t *testing.T) {
+	bareDir, clonePath, first, cleanup := setupRewoundRemote(t, "--bare")
+	defer cleanup()
+	remote, _ := git.PlainOpen(bareDir)
+	master, _ := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	clone, _ := git.PlainOpen(clonePath)
+	developBefore, _ := clone.Reference(plumbing.NewBranchReferenceName("develop"), true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gs := NewGitService(&DefaultLogger{})
+	result, err := gs.UpdateAllBranchesProject(ctx, clonePath, UpdateOptions{Protected: []string{"develop"}})
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+	if !result.Bare || len(result.Failed) != 0 {
+		t.Fatalf("result = %+v, want a bare update without failures", result)
+	}
+	if !slices.Contains(result.Updated, "master") || !slices.Contains(result.Updated, "feature") {
+		t.Errorf("Updated = %v, want master and feature", result.Updated)
+	}
+	if !slices.Equal(result.Protected, []string{"develop"}) {
+		t.Errorf("Protected = %v, want develop", result.Protected)
+	}
+
+	for branch, want := range map[string]plumbing.Hash{"master": master.Hash(), "feature": first, "develop": developBefore.Hash()} {
+		ref, err := clone.Reference(plumbing.NewBranchReferenceName(branch), true)
+		if err != nil || ref.Hash() != want {
+			t.Errorf("%s = %v, %v, want %s", branch, ref, err, want)
+		}
+	}
+	if !result.Verified["master"] || !result.Verified["feature"] || !result.WorktreeClean {
+		t.Errorf("Verified = %v, WorktreeClean = %v, want updated branches verified on a clean repository", result.Verified, result.WorktreeClean)
+	}
+
+	status, err := gs.RepoStatus(ctx, clonePath, 24*time.Hour, Options{})
+	if err != nil {
+		t.Fatalf("RepoStatus() error = %v", err)
+	}
+	if status.Kind != RepoKindBare || status.Dirty || !status.HasRemote {
+		t.Errorf("status = %+v, want a clean bare repository with remote", status)
+	}
+}
+
+func TestGitModelService_UpdateAllBranchesProject_Mirror(t *testing.T) {
+	bareDir, clonePath, first, cleanup := setupRewoundRemote(t, "--mirror")
+	defer cleanup()
+	remote, _ := git.PlainOpen(bareDir)
+	master, _ := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+
+	gs := NewGitService(&DefaultLogger{})
+	result, err := gs.UpdateAllBranchesProject(context.Background(), clonePath, UpdateOptions{})
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+	if !result.Bare || !slices.Equal(result.Updated, []string{"develop", "feature", "master"}) {
+		t.Errorf("result = %+v, want every branch updated", result)
+	}
+	for _, branch := range result.Updated {
+		if !result.Verified[branch] {
+			t.Errorf("%s was not verified against the remote", branch)
+		}
+	}
+	if !result.WorktreeClean {
+		t.Error("WorktreeClean = false for a mirror")
+	}
+
+	clone, _ := git.PlainOpen(clonePath)
+	for branch, want := range map[string]plumbing.Hash{"master": master.Hash(), "feature": first, "develop": first} {
+		ref, err := clone.Reference(plumbing.NewBranchReferenceName(branch), true)
+		if err != nil || ref.Hash() != want {
+			t.Errorf("%s = %v, %v, want %s", branch, ref, err, want)
+		}
+	}
+	if _, err := clone.Reference(plumbing.NewRemoteReferenceName("origin", "master"), true); err == nil {
+		t.Error("mirror update recorded remote-tracking refs")
+	}
+}
+
+func TestFetchesIntoBranches(t *testing.T) {
+	tests := []struct {
+		refSpecs []config.RefSpec
+		want     bool
+	}{
+		{nil, false},
+		{[]config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"}, false},
+		{[]config.RefSpec{"+refs/*:refs/*"}, true},
+		{[]config.RefSpec{"+refs/heads/*:refs/heads/*"}, true},
+	}
+	for _, tt := range tests {
+		if got := fetchesIntoBranches(tt.refSpecs); got != tt.want {
+			t.Errorf("fetchesIntoBranches(%v) = %v, want %v", tt.refSpecs, got, tt.want)
+		}
+	}
+}
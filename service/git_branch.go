@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Outcomes of CreateBranch
+const (
+	BranchStatusCreated     = "created"
+	BranchStatusPushed      = "created and pushed"
+	BranchStatusExists      = "exists"
+	BranchStatusMissingBase = "missing base"
+)
+
+// CreateBranchOptions configures CreateBranch
+type CreateBranchOptions struct {
+	Options
+	// From is the branch, tag or commit the new branch starts from. A branch
+	// is taken from the remote when it has it. The default branch of the
+	// remote is used when empty.
+	From string
+	// Fetch fetches the remote first, so the branch starts from its latest state
+	Fetch bool
+	// Push pushes the new branch to the remote and makes it its upstream
+	Push bool
+}
+
+// CreateBranchResult is the outcome of creating a branch in a repository
+type CreateBranchResult struct {
+	Branch string `json:"branch"`
+	From   string `json:"from"`
+	Status string `json:"status"`
+	// Commit is the commit the branch was created at, or would be with a dry run
+	Commit string `json:"commit,omitempty"`
+}
+
+// CreateBranch creates branch name from opts.From in repoPath without checking
+// it out. An existing branch is left as it is and reported with
+// BranchStatusExists, and a base found nowhere with BranchStatusMissingBase.
+// With opts.Push the branch is pushed, and removed again when the push fails
+// so the run can be retried. With opts.DryRun the branch is only resolved.
+func (gs *GitModelService) CreateBranch(ctx context.Context, repoPath string, name string, opts CreateBranchOptions) (*CreateBranchResult, error) {
+	if name == "" {
+		return nil, fmt.Errorf("branch name cannot be empty")
+	}
+	refName := plumbing.NewBranchReferenceName(name)
+	if err := refName.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid branch name %q: %w", name, err)
+	}
+	gs, ctx, cancel := gs.withOptions(ctx, opts.Options)
+	defer cancel()
+	gs = gs.withRepo(repoPath, "branch", name)
+	remoteName := gs.remoteName()
+	result := &CreateBranchResult{Branch: name, From: opts.From}
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Fetch {
+		if err := gs.fetch(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := repo.Reference(refName, false); err == nil {
+		result.Status = BranchStatusExists
+		return result, nil
+	}
+	if result.From == "" {
+		if result.From, err = gs.defaultBranch(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+	base, err := resolveBase(repo, remoteName, result.From)
+	if err != nil {
+		gs.logger.Debug("base not found", "from", result.From, "error", err)
+		result.Status = BranchStatusMissingBase
+		return result, nil
+	}
+	result.Commit = base.String()
+
+	result.Status = BranchStatusCreated
+	if opts.Push {
+		result.Status = BranchStatusPushed
+	}
+	if opts.DryRun {
+		gs.logger.Info("dry-run: would create branch", "from", result.From, "commit", result.Commit)
+		return result, nil
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(refName, base)); err != nil {
+		return nil, fmt.Errorf("failed to create branch: %w", err)
+	}
+	gs.logger.Info("created branch", "from", result.From, "commit", result.Commit)
+	if !opts.Push {
+		return result, nil
+	}
+
+	err = gs.withAuth(ctx, remoteURL(repo, remoteName), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		return repo.PushContext(ctx, &git.PushOptions{
+			RemoteName:   remoteName,
+			RefSpecs:     []config.RefSpec{config.RefSpec(refName.String() + ":" + refName.String())},
+			Auth:         auth,
+			ProxyOptions: proxy,
+			Progress:     gs.progress,
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		if removeErr := repo.Storer.RemoveReference(refName); removeErr != nil {
+			gs.logger.Warn("failed to remove the unpushed branch", "error", removeErr)
+		}
+		return nil, fmt.Errorf("failed to push %s: %w", name, err)
+	}
+	if err := repo.CreateBranch(&config.Branch{Name: name, Remote: remoteName, Merge: refName}); err != nil && !errors.Is(err, git.ErrBranchExists) {
+		return nil, fmt.Errorf("failed to set upstream: %w", err)
+	}
+	gs.logger.Info("pushed branch", "remote", remoteName)
+	return result, nil
+}
+
+// resolveBase returns the commit of from: the remote-tracking or local branch
+// of that name, or else any revision such as a tag or a commit hash
+func resolveBase(repo *git.Repository, remoteName string, from string) (plumbing.Hash, error) {
+	if hash, err := branchTip(repo, remoteName, from); err == nil {
+		return hash, nil
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(from))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return *hash, nil
+}
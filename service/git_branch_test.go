@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestGitModelService_CreateBranch(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	pushed, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	// a local commit missing from origin must not end up in the new branch
+	commitTestFile(t, repoPath, "local.txt", "local only")
+
+	dry, err := gs.CreateBranch(ctx, repoPath, "release/1.4", CreateBranchOptions{Options: Options{DryRun: true}, Push: true})
+	if err != nil {
+		t.Fatalf("CreateBranch() dry run error = %v", err)
+	}
+	if dry.Status != BranchStatusPushed || dry.From != "master" || dry.Commit != pushed.Hash().String() {
+		t.Errorf("dry run = %+v, want release/1.4 from origin/master", dry)
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName("release/1.4"), false); err == nil {
+		t.Fatalf("dry run created the branch")
+	}
+
+	result, err := gs.CreateBranch(ctx, repoPath, "release/1.4", CreateBranchOptions{Push: true})
+	if err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	if result.Status != BranchStatusPushed || result.Commit != pushed.Hash().String() {
+		t.Errorf("result = %+v", result)
+	}
+	bare, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatalf("failed to open bare repo: %v", err)
+	}
+	if ref, err := bare.Reference(plumbing.NewBranchReferenceName("release/1.4"), false); err != nil || ref.Hash() != pushed.Hash() {
+		t.Errorf("release/1.4 not pushed to origin: %v", err)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	if upstream := cfg.Branches["release/1.4"]; upstream == nil || upstream.Remote != DefaultRemote {
+		t.Errorf("release/1.4 upstream = %+v, want origin", upstream)
+	}
+	if head, _ := repo.Head(); head.Name().Short() != "master" {
+		t.Errorf("HEAD = %s, the new branch should not be checked out", head.Name().Short())
+	}
+
+	again, err := gs.CreateBranch(ctx, repoPath, "release/1.4", CreateBranchOptions{})
+	if err != nil || again.Status != BranchStatusExists {
+		t.Errorf("CreateBranch() = %+v, %v, want %q", again, err, BranchStatusExists)
+	}
+
+	missing, err := gs.CreateBranch(ctx, repoPath, "hotfix", CreateBranchOptions{From: "no-such-branch"})
+	if err != nil || missing.Status != BranchStatusMissingBase {
+		t.Errorf("CreateBranch() = %+v, %v, want %q", missing, err, BranchStatusMissingBase)
+	}
+
+	if _, err := gs.CreateBranch(ctx, repoPath, "bad..name", CreateBranchOptions{}); err == nil {
+		t.Errorf("CreateBranch() should reject an invalid branch name")
+	}
+}
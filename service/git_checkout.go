@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Outcomes of CheckoutBranch
+const (
+	CheckoutStatusCurrent  = "already checked out"
+	CheckoutStatusSwitched = "switched"
+	CheckoutStatusTracking = "created from remote"
+	CheckoutStatusMissing  = "missing"
+	CheckoutStatusSkipped  = "skipped"
+)
+
+// CheckoutOptions configures CheckoutBranch
+type CheckoutOptions struct {
+	Options
+	// Fetch fetches the remote first, so branches pushed since the last fetch
+	// are found
+	Fetch bool
+}
+
+// CheckoutResult is the outcome of checking out a branch in a repository
+type CheckoutResult struct {
+	Branch string `json:"branch"`
+	Status string `json:"status"`
+	// Previous is the branch checked out before, empty when HEAD was detached
+	Previous string `json:"previous,omitempty"`
+	// SkipReason is set when Status is CheckoutStatusSkipped
+	SkipReason string `json:"skip_reason,omitempty"`
+}
+
+// CheckoutBranch checks out branch in repoPath. When only the remote-tracking
+// branch exists, a local branch tracking it is created first. A branch found
+// nowhere gives CheckoutStatusMissing; repositories with uncommitted changes
+// or untracked files are skipped, as the checkout would delete untracked files.
+func (gs *GitModelService) CheckoutBranch(ctx context.Context, repoPath string, branch string, opts CheckoutOptions) (*CheckoutResult, error) {
+	if branch == "" {
+		return nil, fmt.Errorf("branch name cannot be empty")
+	}
+	gs, ctx, cancel := gs.withOptions(ctx, opts.Options)
+	defer cancel()
+	gs = gs.withRepo(repoPath, "branch", branch)
+	remoteName := gs.remoteName()
+	result := &CheckoutResult{Branch: branch}
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Fetch {
+		if err := gs.fetch(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if head.Name().IsBranch() {
+		result.Previous = head.Name().Short()
+	}
+	if result.Previous == branch {
+		result.Status = CheckoutStatusCurrent
+		return result, nil
+	}
+
+	localName := plumbing.NewBranchReferenceName(branch)
+	_, err = repo.Reference(localName, true)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to read branch: %w", err)
+	}
+	hasLocal := err == nil
+	var remoteRef *plumbing.Reference
+	if !hasLocal {
+		remoteRef, err = repo.Reference(plumbing.NewRemoteReferenceName(remoteName, branch), true)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			result.Status = CheckoutStatusMissing
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read remote branch: %w", err)
+		}
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	dirty, err := hasUncommittedChanges(worktree)
+	if err != nil {
+		return nil, err
+	}
+	if !dirty {
+		// go-git removes the untracked files when switching branches
+		if dirty, err = hasUntrackedFiles(worktree); err != nil {
+			return nil, err
+		}
+	}
+	if dirty {
+		gs.logger.Warn("skipping repository with uncommitted changes or untracked files")
+		result.Status, result.SkipReason = CheckoutStatusSkipped, SkipReasonDirtyWorktree
+		return result, nil
+	}
+
+	result.Status = CheckoutStatusSwitched
+	if !hasLocal {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(localName, remoteRef.Hash())); err != nil {
+			return nil, fmt.Errorf("failed to create branch: %w", err)
+		}
+		if err := repo.CreateBranch(&config.Branch{Name: branch, Remote: remoteName, Merge: localName}); err != nil && !errors.Is(err, git.ErrBranchExists) {
+			return nil, fmt.Errorf("failed to set upstream: %w", err)
+		}
+		gs.logger.Info("created branch tracking the remote", "remote", remoteName)
+		result.Status = CheckoutStatusTracking
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: localName}); err != nil {
+		return nil, fmt.Errorf("failed to checkout %s: %w", branch, err)
+	}
+	gs.logger.Info("checked out branch", "previous", result.Previous)
+	return result, nil
+}
+
+// BranchNames returns the local branches of repoPath and the branches of its
+// remotes without the remote name, sorted and without duplicates
+func BranchNames(repoPath string) ([]string, error) {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+	var names []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		switch {
+		case ref.Name().IsBranch():
+			names = append(names, ref.Name().Short())
+		case ref.Name().IsRemote():
+			// refs/remotes/<remote>/<branch>, without the symbolic <remote>/HEAD
+			_, branch, ok := strings.Cut(strings.TrimPrefix(ref.Name().String(), "refs/remotes/"), "/")
+			if ok && branch != "HEAD" {
+				names = append(names, branch)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate references: %w", err)
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
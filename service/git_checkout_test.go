@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestGitModelService_CheckoutBranch(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	bare, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatalf("failed to open bare repo: %v", err)
+	}
+	if err := bare.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("develop"), head.Hash())); err != nil {
+		t.Fatalf("failed to create the remote develop: %v", err)
+	}
+
+	result, err := gs.CheckoutBranch(ctx, repoPath, "develop", CheckoutOptions{})
+	if err != nil {
+		t.Fatalf("CheckoutBranch() error = %v", err)
+	}
+	if result.Status != CheckoutStatusMissing {
+		t.Errorf("Status = %q before fetching, want %q", result.Status, CheckoutStatusMissing)
+	}
+
+	result, err = gs.CheckoutBranch(ctx, repoPath, "develop", CheckoutOptions{Fetch: true})
+	if err != nil {
+		t.Fatalf("CheckoutBranch() error = %v", err)
+	}
+	if result.Status != CheckoutStatusTracking || result.Previous != "master" {
+		t.Errorf("result = %+v, want develop created from origin", result)
+	}
+	head, _ = repo.Head()
+	if head.Name().Short() != "develop" {
+		t.Errorf("HEAD = %s, want develop", head.Name().Short())
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	if upstream := cfg.Branches["develop"]; upstream == nil || upstream.Remote != DefaultRemote || upstream.Merge.Short() != "develop" {
+		t.Errorf("develop upstream = %+v, want origin/develop", upstream)
+	}
+
+	result, err = gs.CheckoutBranch(ctx, repoPath, "develop", CheckoutOptions{})
+	if err != nil || result.Status != CheckoutStatusCurrent {
+		t.Errorf("CheckoutBranch() = %+v, %v, want %q", result, err, CheckoutStatusCurrent)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	result, err = gs.CheckoutBranch(ctx, repoPath, "master", CheckoutOptions{})
+	if err != nil || result.Status != CheckoutStatusSkipped || result.SkipReason != SkipReasonDirtyWorktree {
+		t.Errorf("CheckoutBranch() = %+v, %v, want a dirty worktree skip", result, err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte("test content"), 0644); err != nil {
+		t.Fatalf("failed to restore file: %v", err)
+	}
+
+	untracked := filepath.Join(repoPath, "notes.txt")
+	if err := os.WriteFile(untracked, []byte("notes"), 0644); err != nil {
+		t.Fatalf("failed to create untracked file: %v", err)
+	}
+	result, err = gs.CheckoutBranch(ctx, repoPath, "master", CheckoutOptions{})
+	if err != nil || result.Status != CheckoutStatusSkipped || result.SkipReason != SkipReasonDirtyWorktree {
+		t.Errorf("CheckoutBranch() = %+v, %v, want a dirty worktree skip for untracked files", result, err)
+	}
+	if _, err := os.Stat(untracked); err != nil {
+		t.Errorf("untracked file lost: %v", err)
+	}
+	if err := os.Remove(untracked); err != nil {
+		t.Fatalf("failed to remove untracked file: %v", err)
+	}
+
+	result, err = gs.CheckoutBranch(ctx, repoPath, "master", CheckoutOptions{})
+	if err != nil || result.Status != CheckoutStatusSwitched || result.Previous != "develop" {
+		t.Errorf("CheckoutBranch() = %+v, %v, want switched back to master", result, err)
+	}
+}
+
+func TestBranchNames(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	for _, ref := range []*plumbing.Reference{
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), head.Hash()),
+		plumbing.NewHashReference(plumbing.NewRemoteReferenceName(DefaultRemote, "release/1.0"), head.Hash()),
+		plumbing.NewHashReference(plumbing.NewRemoteReferenceName(DefaultRemote, "master"), head.Hash()),
+		plumbing.NewSymbolicReference(plumbing.NewRemoteHEADReferenceName(DefaultRemote), plumbing.NewRemoteReferenceName(DefaultRemote, "master")),
+	} {
+		if err := repo.Storer.SetReference(ref); err != nil {
+			t.Fatalf("failed to create %s: %v", ref.Name(), err)
+		}
+	}
+
+	names, err := BranchNames(repoPath)
+	if err != nil {
+		t.Fatalf("BranchNames() error = %v", err)
+	}
+	if want := []string{"feature", "master", "release/1.0"}; !slices.Equal(names, want) {
+		t.Errorf("BranchNames() = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// DefaultBranch returns the default branch of the remote of repoPath. It is
+// read from refs/remotes/<remote>/HEAD when set, otherwise from the HEAD
+// advertised by the remote, which is then recorded as refs/remotes/<remote>/HEAD
+// like git remote set-head --auto does. When the remote cannot tell, main or
+// master is picked if one of them exists.
+func (gs *GitModelService) DefaultBranch(ctx context.Context, repoPath string, opts Options) (string, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return "", err
+	}
+	return gs.defaultBranch(ctx, repo)
+}
+
+// defaultBranch detects the default branch of repo, asking the remote when
+// refs/remotes/<remote>/HEAD is not set
+func (gs *GitModelService) defaultBranch(ctx context.Context, repo *git.Repository) (string, error) {
+	remoteName := gs.remoteName()
+	if name, ok := remoteHeadBranch(repo, remoteName); ok {
+		return name, nil
+	}
+	name, err := gs.advertisedHeadBranch(ctx, repo, remoteName)
+	if err != nil {
+		gs.logger.Debug("failed to ask the remote for its default branch", "error", err)
+	} else if name != "" {
+		head := plumbing.NewSymbolicReference(remoteHeadRef(remoteName), plumbing.NewRemoteReferenceName(remoteName, name))
+		if err := repo.Storer.SetReference(head); err != nil {
+			gs.logger.Debug("failed to record the remote default branch", "error", err)
+		}
+		return name, nil
+	}
+	return guessDefaultBranch(repo, remoteName)
+}
+
+// localDefaultBranch detects the default branch of repo without contacting the remote
+func localDefaultBranch(repo *git.Repository, remoteName string) (string, error) {
+	if name, ok := remoteHeadBranch(repo, remoteName); ok {
+		return name, nil
+	}
+	return guessDefaultBranch(repo, remoteName)
+}
+
+func remoteHeadRef(remoteName string) plumbing.ReferenceName {
+	return plumbing.ReferenceName("refs/remotes/" + remoteName + "/HEAD")
+}
+
+// remoteHeadBranch returns the branch refs/remotes/<remote>/HEAD points to
+func remoteHeadBranch(repo *git.Repository, remoteName string) (string, bool) {
+	ref, err := repo.Reference(remoteHeadRef(remoteName), false)
+	if err != nil || ref.Type() != plumbing.SymbolicReference {
+		return "", false
+	}
+	prefix := "refs/remotes/" + remoteName + "/"
+	target := ref.Target().String()
+	if !strings.HasPrefix(target, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(target, prefix), true
+}
+
+// advertisedHeadBranch lists the refs of the remote and returns the branch its
+// HEAD points to. Servers that do not advertise the HEAD symref give the only
+// branch at the HEAD commit, preferring main and master when several are.
+func (gs *GitModelService) advertisedHeadBranch(ctx context.Context, repo *git.Repository, remoteName string) (string, error) {
+	remote, err := getRemote(repo, remoteName)
+	if err != nil {
+		return "", err
+	}
+	var advertised []*plumbing.Reference
+	err = gs.withAuth(ctx, remoteURL(repo, remoteName), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		var err error
+		advertised, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth, ProxyOptions: proxy})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list remote refs: %w", err)
+	}
+
+	var head *plumbing.Reference
+	for _, ref := range advertised {
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+		}
+	}
+	if head == nil {
+		return "", nil
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		if !head.Target().IsBranch() {
+			return "", nil
+		}
+		return head.Target().Short(), nil
+	}
+
+	var candidates []string
+	for _, ref := range advertised {
+		if ref.Name().IsBranch() && ref.Hash() == head.Hash() {
+			candidates = append(candidates, ref.Name().Short())
+		}
+	}
+	for _, name := range []string{"main", "master"} {
+		for _, candidate := range candidates {
+			if candidate == name {
+				return name, nil
+			}
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	return "", nil
+}
+
+// guessDefaultBranch picks main or master, whichever exists as a remote-tracking
+// or local branch
+func guessDefaultBranch(repo *git.Repository, remoteName string) (string, error) {
+	for _, name := range []string{"main", "master"} {
+		if _, err := branchTip(repo, remoteName, name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("default branch not found: the remote has no HEAD and neither main nor master exists")
+}
+
+// branchTip returns the commit of branch name, preferring its remote-tracking ref
+func branchTip(repo *git.Repository, remoteName string, name string) (plumbing.Hash, error) {
+	for _, refName := range []plumbing.ReferenceName{
+		plumbing.NewRemoteReferenceName(remoteName, name),
+		plumbing.NewBranchReferenceName(name),
+	} {
+		if ref, err := repo.Reference(refName, true); err == nil {
+			return ref.Hash(), nil
+		}
+	}
+	return plumbing.ZeroHash, fmt.Errorf("branch %s not found", name)
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestGitModelService_DefaultBranch(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	if err := repo.Push(&git.PushOptions{
+		RemoteName: DefaultRemote,
+		RefSpecs:   []config.RefSpec{"refs/heads/master:refs/heads/trunk"},
+	}); err != nil {
+		t.Fatalf("failed to push trunk: %v", err)
+	}
+	bare, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatalf("failed to open bare repo: %v", err)
+	}
+	if err := bare.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("trunk"))); err != nil {
+		t.Fatalf("failed to move the remote HEAD: %v", err)
+	}
+
+	if name, err := localDefaultBranch(repo, DefaultRemote); err != nil || name != "master" {
+		t.Errorf("localDefaultBranch() = %q, %v, want the master guess", name, err)
+	}
+
+	name, err := gs.DefaultBranch(ctx, repoPath, Options{})
+	if err != nil {
+		t.Fatalf("DefaultBranch() error = %v", err)
+	}
+	if name != "trunk" {
+		t.Errorf("DefaultBranch() = %q, want the remote HEAD trunk", name)
+	}
+	if name, ok := remoteHeadBranch(repo, DefaultRemote); !ok || name != "trunk" {
+		t.Errorf("refs/remotes/origin/HEAD = %q, %v, want it recorded as trunk", name, ok)
+	}
+
+	// once recorded, refs/remotes/origin/HEAD wins without asking the remote
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(remoteHeadRef(DefaultRemote), plumbing.NewRemoteReferenceName(DefaultRemote, "develop"))); err != nil {
+		t.Fatalf("failed to set origin/HEAD: %v", err)
+	}
+	if name, err := gs.DefaultBranch(ctx, repoPath, Options{}); err != nil || name != "develop" {
+		t.Errorf("DefaultBranch() = %q, %v, want develop from origin/HEAD", name, err)
+	}
+}
+
+func TestGitModelService_PruneBranches_DefaultBranch(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	if err := repo.Push(&git.PushOptions{
+		RemoteName: DefaultRemote,
+		RefSpecs:   []config.RefSpec{"refs/heads/master:refs/heads/trunk"},
+	}); err != nil {
+		t.Fatalf("failed to push trunk: %v", err)
+	}
+	bare, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatalf("failed to open bare repo: %v", err)
+	}
+	if err := bare.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("trunk"))); err != nil {
+		t.Fatalf("failed to move the remote HEAD: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("trunk"), head.Hash())); err != nil {
+		t.Fatalf("failed to create trunk: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("topic"), head.Hash())); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	result, err := gs.PruneBranches(ctx, repoPath, nil, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("PruneBranches() error = %v", err)
+	}
+	// master is checked out and trunk is the default branch of the remote
+	if len(result.DryRun) != 1 || result.DryRun[0] != "topic" {
+		t.Errorf("DryRun = %v, want only topic", result.DryRun)
+	}
+	if len(result.Protected) != 2 {
+		t.Errorf("Protected = %v, want master and trunk", result.Protected)
+	}
+}
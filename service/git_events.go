@@ -0,0 +1,47 @@
+package service
+
+import "time"
+
+// Kinds of ProgressEvent
+const (
+	// EventFetchStarted and EventFetchDone surround the fetch of a remote;
+	// EventFetchDone carries the error of a failed fetch
+	EventFetchStarted = "fetch_started"
+	EventFetchDone    = "fetch_done"
+	// EventBranchUpdated is sent for every branch moved to its remote
+	// counterpart by UpdateAllBranchesProject or PullCurrentBranch
+	EventBranchUpdated = "branch_updated"
+	// EventRepoDone is sent once FetchLatest, UpdateAllBranchesProject or
+	// PullCurrentBranch is over, with the error they return
+	EventRepoDone = "repo_done"
+)
+
+// ProgressEvent is a step of a GitService operation on a repository
+type ProgressEvent struct {
+	Kind string
+	// Repo is the path of the repository the operation works on
+	Repo string
+	// Remote is set for the fetch events and Branch for EventBranchUpdated
+	Remote string
+	Branch string
+	Err    error
+	Time   time.Time
+}
+
+// ProgressSink receives the progress events of a GitService. Operations run
+// concurrently on several repositories call it from their goroutines, so it
+// must be safe for concurrent use and must not block.
+type ProgressSink func(ProgressEvent)
+
+// emit sends event to the progress sink, if any, stamped with the repository
+// of the operation and the current time
+func (gs *GitModelService) emit(event ProgressEvent) {
+	if gs.events == nil {
+		return
+	}
+	if event.Repo == "" {
+		event.Repo = gs.repoPath
+	}
+	event.Time = time.Now()
+	gs.events(event)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGitModelService_ProgressSink(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+
+	var mu sync.Mutex
+	var events []ProgressEvent
+	gs := NewGitService(&DefaultLogger{})
+	gs.SetProgressSink(func(event ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := gs.UpdateAllBranchesProject(ctx, repoPath, UpdateOptions{})
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+
+	var kinds, branches []string
+	for _, event := range events {
+		if event.Repo != repoPath || event.Time.IsZero() {
+			t.Errorf("event %+v should carry the repository and a time", event)
+		}
+		if event.Err != nil {
+			t.Errorf("event %+v should not carry an error", event)
+		}
+		if len(kinds) == 0 || kinds[len(kinds)-1] != event.Kind {
+			kinds = append(kinds, event.Kind)
+		}
+		if event.Kind == EventBranchUpdated {
+			branches = append(branches, event.Branch)
+		}
+	}
+	want := []string{EventFetchStarted, EventFetchDone, EventBranchUpdated, EventRepoDone}
+	if !slices.Equal(kinds, want) {
+		t.Errorf("event kinds = %v, want %v", kinds, want)
+	}
+	if !slices.Equal(branches, result.Updated) {
+		t.Errorf("updated branch events = %v, want %v", branches, result.Updated)
+	}
+
+	events = nil
+	if err := gs.FetchLatest(ctx, t.TempDir(), Options{}); err == nil {
+		t.Fatalf("FetchLatest() should fail outside a repository")
+	}
+	if len(events) != 1 || events[0].Kind != EventRepoDone || events[0].Err == nil {
+		t.Errorf("events = %+v, want a single failed repo done", events)
+	}
+
+	gs.SetProgressSink(nil)
+	if err := gs.FetchLatest(ctx, repoPath, Options{}); err != nil {
+		t.Fatalf("FetchLatest() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("events = %+v, no event should be sent without a sink", events)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Sources of a FetchEstimate size
+const (
+	FetchSizeUpToDate = "up-to-date"
+	FetchSizeProvider = "provider"
+	FetchSizeUnknown  = "unknown"
+)
+
+// FetchEstimate is the expected download of the next fetch from a remote
+type FetchEstimate struct {
+	// Refs counts the advertised branches and tags that are new or moved
+	Refs int `json:"refs"`
+	// Missing counts the changed refs whose target is not in the local object store
+	Missing int `json:"missing"`
+	// Bytes approximates the download, -1 when it cannot be estimated
+	Bytes int64 `json:"bytes"`
+	// Source tells how Bytes was obtained: up-to-date, provider or unknown
+	Source string `json:"source"`
+}
+
+// EstimateFetch compares the refs advertised by opts.Remote with the local
+// remote-tracking branches and tags, without downloading any object. When
+// objects are missing, the size is approximated as the repository size
+// reported by the provider hosting the remote minus the local object store;
+// providers is keyed by host name and may be nil.
+func (gs *GitModelService) EstimateFetch(ctx context.Context, repoPath string, providers map[string]Provider, opts Options) (*FetchEstimate, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	remoteName := gs.remoteName()
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := getRemote(repo, remoteName)
+	if err != nil {
+		return nil, err
+	}
+
+	url := remoteURL(repo, remoteName)
+	var advertised []*plumbing.Reference
+	err = gs.withAuth(ctx, url, func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		var err error
+		advertised, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth, ProxyOptions: proxy})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote refs: %w", err)
+	}
+
+	estimate := &FetchEstimate{}
+	for _, ref := range advertised {
+		local, ok := fetchedRefName(remoteName, ref)
+		if !ok {
+			continue
+		}
+		if current, err := repo.Reference(local, false); err == nil && current.Hash() == ref.Hash() {
+			continue
+		}
+		estimate.Refs++
+		if repo.Storer.HasEncodedObject(ref.Hash()) != nil {
+			estimate.Missing++
+		}
+	}
+
+	if estimate.Missing == 0 {
+		estimate.Source = FetchSizeUpToDate
+		return estimate, nil
+	}
+
+	estimate.Bytes, estimate.Source = -1, FetchSizeUnknown
+	host, path, err := splitRemoteURL(url)
+	if err != nil {
+		return estimate, nil
+	}
+	provider, ok := providers[host]
+	if !ok {
+		return estimate, nil
+	}
+	size, err := provider.RepositorySize(ctx, path)
+	if err != nil {
+		gs.logger.Debug("provider size lookup failed", "error", err)
+		return estimate, nil
+	}
+	if size == 0 {
+		return estimate, nil
+	}
+	estimate.Bytes, estimate.Source = max(size-objectStoreSize(repo), 0), FetchSizeProvider
+	return estimate, nil
+}
+
+// fetchedRefName returns the local ref a fetch of remoteName updates for an
+// advertised ref, false for refs the fetch ignores
+func fetchedRefName(remoteName string, ref *plumbing.Reference) (plumbing.ReferenceName, bool) {
+	if ref.Type() != plumbing.HashReference {
+		return "", false
+	}
+	switch name := ref.Name(); {
+	case name.IsBranch():
+		return plumbing.NewRemoteReferenceName(remoteName, name.Short()), true
+	case name.IsTag():
+		return name, true
+	}
+	return "", false
+}
+
+// objectStoreSize returns the size of the loose objects and packs of repo
+func objectStoreSize(repo *git.Repository) int64 {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return 0
+	}
+	var size int64
+	_ = filepath.WalkDir(filepath.Join(storage.Filesystem().Root(), "objects"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, ".idx") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
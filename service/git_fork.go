@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// DefaultUpstreamRemote is the remote a fork is synced from when ForkSyncOptions.Upstream is empty
+const DefaultUpstreamRemote = "upstream"
+
+// Outcomes of SyncFork
+const (
+	ForkStatusUpToDate = "up to date"
+	ForkStatusSynced   = "synced"
+	ForkStatusDiverged = "diverged"
+	ForkStatusSkipped  = "skipped"
+)
+
+const SkipReasonNoUpstreamRemote = "no upstream remote"
+
+// Reasons of ForkStatusDiverged
+const (
+	DivergedLocal  = "the local branch has commits missing from upstream"
+	DivergedOrigin = "origin has commits missing from upstream"
+)
+
+// ForkSyncOptions configures SyncFork
+type ForkSyncOptions struct {
+	Options
+	// Upstream is the remote of the forked repository, DefaultUpstreamRemote when empty
+	Upstream string
+}
+
+// ForkSyncResult is the outcome of syncing a fork with its upstream
+type ForkSyncResult struct {
+	// Branch is the default branch of upstream
+	Branch string `json:"branch"`
+	Status string `json:"status"`
+	// Reason tells why the fork was skipped or needs a manual merge
+	Reason string `json:"reason,omitempty"`
+	// From and To are the origin branch hashes before and after the sync
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// SyncFork fetches the upstream remote of repoPath, fast-forwards the local
+// default branch of upstream to it and pushes that branch to origin (opts.Remote).
+// When the local or the origin branch has commits missing from upstream the
+// fork is reported as diverged and left for a manual merge. A checked-out
+// default branch with uncommitted changes is skipped, and repositories without
+// the upstream remote too. With opts.DryRun nothing is changed besides the fetches.
+func (gs *GitModelService) SyncFork(ctx context.Context, repoPath string, opts ForkSyncOptions) (*ForkSyncResult, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts.Options)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	originName := gs.remoteName()
+	upstreamName := opts.Upstream
+	if upstreamName == "" {
+		upstreamName = DefaultUpstreamRemote
+	}
+	result := &ForkSyncResult{}
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repo.Remote(upstreamName); err != nil {
+		result.Status, result.Reason = ForkStatusSkipped, SkipReasonNoUpstreamRemote
+		return result, nil
+	}
+	if _, err := getRemote(repo, originName); err != nil {
+		return nil, err
+	}
+
+	if err := gs.fetchRemote(ctx, repo, upstreamName); err != nil {
+		return nil, err
+	}
+	if err := gs.fetchRemote(ctx, repo, originName); err != nil {
+		return nil, err
+	}
+
+	upstream := *gs
+	upstream.remote = upstreamName
+	if result.Branch, err = upstream.defaultBranch(ctx, repo); err != nil {
+		return nil, err
+	}
+	gs = gs.withFields("branch", result.Branch)
+	upstreamRef, err := repo.Reference(plumbing.NewRemoteReferenceName(upstreamName, result.Branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s/%s: %w", upstreamName, result.Branch, err)
+	}
+	target := upstreamRef.Hash()
+
+	localName := plumbing.NewBranchReferenceName(result.Branch)
+	local := plumbing.ZeroHash
+	if ref, err := repo.Reference(localName, true); err == nil {
+		local = ref.Hash()
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to read branch: %w", err)
+	}
+	origin := plumbing.ZeroHash
+	if ref, err := repo.Reference(plumbing.NewRemoteReferenceName(originName, result.Branch), true); err == nil {
+		origin = ref.Hash()
+		result.From = origin.String()
+	}
+	result.To = result.From
+
+	if !local.IsZero() {
+		contained, err := isAncestorCommit(repo, local, target)
+		if err != nil {
+			return nil, err
+		}
+		if !contained {
+			result.Status, result.Reason = ForkStatusDiverged, DivergedLocal
+			return result, nil
+		}
+	}
+	if !origin.IsZero() {
+		contained, err := isAncestorCommit(repo, origin, target)
+		if err != nil {
+			return nil, err
+		}
+		if !contained {
+			result.Status, result.Reason = ForkStatusDiverged, DivergedOrigin
+			return result, nil
+		}
+	}
+	if local == target && origin == target {
+		result.Status = ForkStatusUpToDate
+		return result, nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	checkedOut := head.Name() == localName
+	var worktree *git.Worktree
+	if checkedOut && local != target {
+		if worktree, err = repo.Worktree(); err != nil {
+			return nil, fmt.Errorf("failed to get worktree: %w", err)
+		}
+		dirty, err := hasUncommittedChanges(worktree)
+		if err != nil {
+			return nil, err
+		}
+		if dirty {
+			result.Status, result.Reason = ForkStatusSkipped, SkipReasonDirtyWorktree
+			return result, nil
+		}
+	}
+
+	result.Status, result.To = ForkStatusSynced, target.String()
+	if opts.DryRun {
+		gs.logger.Info("dry-run: would sync fork", "from", result.From, "to", result.To)
+		return result, nil
+	}
+
+	if local != target {
+		if worktree != nil {
+			if err := mergeFastForward(ctx, repoPath, target); err != nil {
+				return nil, fmt.Errorf("failed to fast-forward %s: %w", result.Branch, err)
+			}
+		} else if err := repo.Storer.SetReference(plumbing.NewHashReference(localName, target)); err != nil {
+			return nil, fmt.Errorf("failed to fast-forward %s: %w", result.Branch, err)
+		}
+		gs.logger.Info("fast-forwarded branch to upstream", "upstream", upstreamName)
+	}
+	if origin != target {
+		if err := gs.pushBranch(ctx, repo, originName, plumbing.NewHashReference(localName, target), false); err != nil {
+			return nil, err
+		}
+		gs.logger.Info("pushed branch", "remote", originName)
+	}
+	return result, nil
+}
+
+// isAncestorCommit reports whether commit a is an ancestor of, or is, commit b
+func isAncestorCommit(repo *git.Repository, a, b plumbing.Hash) (bool, error) {
+	if a == b {
+		return true, nil
+	}
+	ca, err := repo.CommitObject(a)
+	if err != nil {
+		return false, fmt.Errorf("failed to load commit %s: %w", a, err)
+	}
+	cb, err := repo.CommitObject(b)
+	if err != nil {
+		return false, fmt.Errorf("failed to load commit %s: %w", b, err)
+	}
+	ok, err := ca.IsAncestor(cb)
+	if err != nil {
+		return false, fmt.Errorf("failed to check ancestry: %w", err)
+	}
+	return ok, nil
+}
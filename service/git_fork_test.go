@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestGitModelService_SyncFork(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not available")
+	}
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	result, err := gs.SyncFork(ctx, repoPath, ForkSyncOptions{})
+	if err != nil {
+		t.Fatalf("SyncFork() error = %v", err)
+	}
+	if result.Status != ForkStatusSkipped || result.Reason != SkipReasonNoUpstreamRemote {
+		t.Errorf("result = %+v, want a skip without upstream", result)
+	}
+
+	upstreamDir := filepath.Join(t.TempDir(), "upstream.git")
+	if _, err := git.PlainClone(upstreamDir, true, &git.CloneOptions{URL: bareDir}); err != nil {
+		t.Fatalf("failed to create upstream: %v", err)
+	}
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: DefaultUpstreamRemote, URLs: []string{upstreamDir}}); err != nil {
+		t.Fatalf("failed to add upstream: %v", err)
+	}
+	pushRemoteCommit(t, upstreamDir, "upstream.txt")
+
+	dry, err := gs.SyncFork(ctx, repoPath, ForkSyncOptions{Options: Options{DryRun: true}})
+	if err != nil {
+		t.Fatalf("SyncFork() dry run error = %v", err)
+	}
+	if dry.Status != ForkStatusSynced || dry.Branch != "master" || dry.From == dry.To {
+		t.Errorf("dry run = %+v, want master to sync", dry)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "upstream.txt")); err == nil {
+		t.Fatalf("dry run updated the worktree")
+	}
+
+	untracked := filepath.Join(repoPath, "notes.txt")
+	if err := os.WriteFile(untracked, []byte("notes"), 0644); err != nil {
+		t.Fatalf("failed to create untracked file: %v", err)
+	}
+	result, err = gs.SyncFork(ctx, repoPath, ForkSyncOptions{})
+	if err != nil {
+		t.Fatalf("SyncFork() error = %v", err)
+	}
+	if _, err := os.Stat(untracked); err != nil {
+		t.Errorf("untracked file lost: %v", err)
+	}
+	if result.Status != ForkStatusSynced || result.To != dry.To {
+		t.Errorf("result = %+v, want master synced to %s", result, dry.To)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "upstream.txt")); err != nil {
+		t.Errorf("checked-out master not fast-forwarded: %v", err)
+	}
+	bare, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatalf("failed to open origin: %v", err)
+	}
+	if ref, err := bare.Reference(plumbing.NewBranchReferenceName("master"), true); err != nil || ref.Hash().String() != result.To {
+		t.Errorf("origin master not pushed: %v", err)
+	}
+
+	result, err = gs.SyncFork(ctx, repoPath, ForkSyncOptions{})
+	if err != nil || result.Status != ForkStatusUpToDate {
+		t.Errorf("SyncFork() = %+v, %v, want %q", result, err, ForkStatusUpToDate)
+	}
+
+	pushRemoteCommit(t, bareDir, "origin.txt")
+	pushRemoteCommit(t, upstreamDir, "upstream2.txt")
+	result, err = gs.SyncFork(ctx, repoPath, ForkSyncOptions{})
+	if err != nil {
+		t.Fatalf("SyncFork() error = %v", err)
+	}
+	if result.Status != ForkStatusDiverged || result.Reason != DivergedOrigin {
+		t.Errorf("result = %+v, want origin diverged", result)
+	}
+}
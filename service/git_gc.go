@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// DefaultGCPruneAge is how old an unreachable loose object must be to be
+// deleted by GarbageCollect, the default of git gc
+const DefaultGCPruneAge = 14 * 24 * time.Hour
+
+// GCOptions configures GarbageCollect
+type GCOptions struct {
+	Options
+	// UseCLI runs "git gc" instead of the built-in repack and prune
+	UseCLI bool
+	// PruneAge is how old an unreachable loose object must be to be deleted,
+	// DefaultGCPruneAge when zero
+	PruneAge time.Duration
+}
+
+// GCResult is the space taken by the .git directory before and after GarbageCollect
+type GCResult struct {
+	SizeBefore int64 `json:"size_before"`
+	SizeAfter  int64 `json:"size_after"`
+	// Reclaimed is SizeBefore - SizeAfter, zero when the repository grew
+	Reclaimed int64 `json:"reclaimed"`
+	// PackedObjects and PrunedObjects are the loose objects moved into the new
+	// pack and the unreachable ones deleted; the git CLI does not report them
+	PackedObjects int  `json:"packed_objects"`
+	PrunedObjects int  `json:"pruned_objects"`
+	UsedCLI       bool `json:"used_cli"`
+}
+
+// GarbageCollect repacks the objects of the repository at repoPath reachable
+// from its refs, reflogs, index and linked worktrees into a single pack and
+// deletes the loose objects, keeping the unreachable ones younger than
+// opts.PruneAge
+func (gs *GitModelService) GarbageCollect(ctx context.Context, repoPath string, opts GCOptions) (*GCResult, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts.Options)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	pruneAge := opts.PruneAge
+	if pruneAge <= 0 {
+		pruneAge = DefaultGCPruneAge
+	}
+
+	gitDir := filepath.Join(repoPath, ".git")
+	result := &GCResult{SizeBefore: pathSize(gitDir), UsedCLI: opts.UseCLI}
+	if opts.UseCLI {
+		prune := fmt.Sprintf("--prune=%d.seconds.ago", int64(pruneAge.Seconds()))
+		if _, err := runGit(ctx, repoPath, "gc", "--quiet", prune); err != nil {
+			return nil, fmt.Errorf("git gc failed: %w", err)
+		}
+	} else if err := gs.repack(ctx, repoPath, time.Now().Add(-pruneAge), result); err != nil {
+		return nil, err
+	}
+
+	result.SizeAfter = pathSize(gitDir)
+	result.Reclaimed = max(result.SizeBefore-result.SizeAfter, 0)
+	gs.logger.Info("garbage collected", "before", result.SizeBefore, "after", result.SizeAfter,
+		"packed", result.PackedObjects, "pruned", result.PrunedObjects)
+	return result, nil
+}
+
+// repack packs every object reachable from the refs, the reflogs, the index
+// and the linked worktrees into a new pack, which deletes their loose copies,
+// then deletes the packs and the unreachable loose objects last written before
+// pruneBefore. go-git RepackObjects is not used as it only walks the refs,
+// losing stash@{1} and older and the commits only the reflogs still hold.
+func (gs *GitModelService) repack(ctx context.Context, repoPath string, pruneBefore time.Time, result *GCResult) error {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return err
+	}
+	los, ok := repo.Storer.(storer.LooseObjectStorer)
+	if !ok {
+		return git.ErrLooseObjectsNotSupported
+	}
+	pos, ok := repo.Storer.(storer.PackedObjectStorer)
+	if !ok {
+		return git.ErrPackedObjectsNotSupported
+	}
+	pfw, ok := repo.Storer.(storer.PackfileWriter)
+	if !ok {
+		return fmt.Errorf("repository storage cannot write packs")
+	}
+
+	roots, err := gcRoots(repo, filepath.Join(repoPath, git.GitDirName))
+	if err != nil {
+		return err
+	}
+	reachable := map[plumbing.Hash]bool{}
+	for _, root := range roots {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := walkObjects(repo.Storer, root, reachable); err != nil {
+			return err
+		}
+	}
+	oldPacks, err := pos.ObjectPacks()
+	if err != nil {
+		return fmt.Errorf("failed to list packs: %w", err)
+	}
+
+	gs.logger.Debug("repacking objects", "objects", len(reachable))
+	var newPack plumbing.Hash
+	if len(reachable) > 0 {
+		if newPack, err = writePack(repo, pfw, reachable); err != nil {
+			return err
+		}
+	}
+
+	var loose []plumbing.Hash
+	if err := los.ForEachObjectHash(func(hash plumbing.Hash) error {
+		loose = append(loose, hash)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to list loose objects: %w", err)
+	}
+	for _, hash := range loose {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if reachable[hash] {
+			if err := los.DeleteLooseObject(hash); err != nil {
+				return fmt.Errorf("failed to delete loose object %s: %w", hash, err)
+			}
+			result.PackedObjects++
+			continue
+		}
+		written, err := los.LooseObjectTime(hash)
+		if err != nil || !written.Before(pruneBefore) {
+			continue
+		}
+		if err := los.DeleteLooseObject(hash); err != nil {
+			return fmt.Errorf("failed to delete loose object %s: %w", hash, err)
+		}
+		result.PrunedObjects++
+	}
+
+	// every reachable object is in the new pack: the old packs only hold
+	// unreachable ones, deleted once as old as the loose ones
+	for _, pack := range oldPacks {
+		if pack == newPack {
+			continue
+		}
+		if err := pos.DeleteOldObjectPackAndIndex(pack, pruneBefore); err != nil {
+			return fmt.Errorf("failed to delete pack %s: %w", pack, err)
+		}
+	}
+	return nil
+}
+
+// writePack writes the objects into a new pack and returns its hash
+func writePack(repo *git.Repository, pfw storer.PackfileWriter, objects map[plumbing.Hash]bool) (_ plumbing.Hash, err error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to get config: %w", err)
+	}
+	hashes := make([]plumbing.Hash, 0, len(objects))
+	for hash := range objects {
+		hashes = append(hashes, hash)
+	}
+	w, err := pfw.PackfileWriter()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to create pack: %w", err)
+	}
+	defer func() {
+		if closeErr := w.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write pack: %w", closeErr)
+		}
+	}()
+	hash, err := packfile.NewEncoder(w, repo.Storer, false).Encode(hashes, cfg.Pack.Window)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to write pack: %w", err)
+	}
+	return hash, nil
+}
+
+// gcRoots returns the objects git gc keeps: the targets of the refs, every
+// entry of the reflogs (the stash entries included), the index entries, and
+// the HEAD, reflog and index of each linked worktree
+func gcRoots(repo *git.Repository, gitDir string) ([]plumbing.Hash, error) {
+	var roots []plumbing.Hash
+	refs, err := repo.Storer.IterReferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+	if err := refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			roots = append(roots, ref.Hash())
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	logDirs := []string{filepath.Join(gitDir, "logs")}
+	indexes := []string{filepath.Join(gitDir, "index")}
+	worktrees, _ := filepath.Glob(filepath.Join(gitDir, "worktrees", "*"))
+	for _, worktree := range worktrees {
+		logDirs = append(logDirs, filepath.Join(worktree, "logs"))
+		indexes = append(indexes, filepath.Join(worktree, "index"))
+		if head, err := os.ReadFile(filepath.Join(worktree, "HEAD")); err == nil {
+			if hash, ok := parseHash(strings.TrimSpace(string(head))); ok {
+				roots = append(roots, hash)
+			}
+		}
+	}
+	for _, logDir := range logDirs {
+		hashes, err := reflogHashes(logDir)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, hashes...)
+	}
+	for _, path := range indexes {
+		hashes, err := indexHashes(path)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, hashes...)
+	}
+	return roots, nil
+}
+
+// reflogHashes returns the old and new hash of every entry of the reflogs under logDir
+func reflogHashes(logDir string) ([]plumbing.Hash, error) {
+	var hashes []plumbing.Hash
+	err := filepath.WalkDir(logDir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			for _, field := range fields[:2] {
+				if hash, ok := parseHash(field); ok && !hash.IsZero() {
+					hashes = append(hashes, hash)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reflogs: %w", err)
+	}
+	return hashes, nil
+}
+
+// indexHashes returns the blobs staged in the index file at path, none when it is missing
+func indexHashes(path string) ([]plumbing.Hash, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	defer f.Close()
+	idx := &index.Index{}
+	if err := index.NewDecoder(f).Decode(idx); err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", path, err)
+	}
+	var hashes []plumbing.Hash
+	for _, entry := range idx.Entries {
+		if entry.Mode != filemode.Submodule {
+			hashes = append(hashes, entry.Hash)
+		}
+	}
+	return hashes, nil
+}
+
+// parseHash parses a full hexadecimal object name
+func parseHash(s string) (plumbing.Hash, bool) {
+	if len(s) != 2*len(plumbing.ZeroHash) {
+		return plumbing.ZeroHash, false
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return plumbing.ZeroHash, false
+	}
+	return plumbing.NewHash(s), true
+}
+
+// walkObjects adds root and the objects it reaches to seen. Missing objects
+// are skipped: reflogs may name objects pruned long ago, and shallow clones
+// miss the parents of their oldest commits.
+func walkObjects(s storer.EncodedObjectStorer, root plumbing.Hash, seen map[plumbing.Hash]bool) error {
+	stack := []plumbing.Hash{root}
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[hash] {
+			continue
+		}
+		obj, err := s.EncodedObject(plumbing.AnyObject, hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+		seen[hash] = true
+		switch obj.Type() {
+		case plumbing.CommitObject:
+			commit, err := object.DecodeCommit(s, obj)
+			if err != nil {
+				return fmt.Errorf("failed to read commit %s: %w", hash, err)
+			}
+			stack = append(stack, commit.TreeHash)
+			stack = append(stack, commit.ParentHashes...)
+		case plumbing.TreeObject:
+			tree, err := object.DecodeTree(s, obj)
+			if err != nil {
+				return fmt.Errorf("failed to read tree %s: %w", hash, err)
+			}
+			for _, entry := range tree.Entries {
+				// a submodule entry is a commit of another repository
+				if entry.Mode != filemode.Submodule {
+					stack = append(stack, entry.Hash)
+				}
+			}
+		case plumbing.TagObject:
+			tag, err := object.DecodeTag(s, obj)
+			if err != nil {
+				return fmt.Errorf("failed to read tag %s: %w", hash, err)
+			}
+			stack = append(stack, tag.Target)
+		}
+	}
+	return nil
+}
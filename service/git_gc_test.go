@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ageLooseObject backdates the loose object file of hash
+func ageLooseObject(t *testing.T, repoPath string, hash plumbing.Hash) {
+	t.Helper()
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	path := filepath.Join(repoPath, ".git", "objects", hash.String()[:2], hash.String()[2:])
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("failed to age object %s: %v", hash, err)
+	}
+}
+
+func TestGitModelService_GarbageCollect(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+
+	t.Run("built-in", func(t *testing.T) {
+		repoPath, _, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+		repo, err := git.PlainOpen(repoPath)
+		if err != nil {
+			t.Fatalf("failed to open repo: %v", err)
+		}
+
+		// an old unreachable blob is pruned, an old staged one is kept
+		obj := repo.Storer.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, _ := obj.Writer()
+		_, _ = w.Write([]byte("dangling"))
+		_ = w.Close()
+		dangling, err := repo.Storer.SetEncodedObject(obj)
+		if err != nil {
+			t.Fatalf("failed to write blob: %v", err)
+		}
+		ageLooseObject(t, repoPath, dangling)
+		if err := os.WriteFile(filepath.Join(repoPath, "staged.txt"), []byte("staged"), 0644); err != nil {
+			t.Fatalf("failed to write staged.txt: %v", err)
+		}
+		worktree, _ := repo.Worktree()
+		staged, err := worktree.Add("staged.txt")
+		if err != nil {
+			t.Fatalf("failed to stage: %v", err)
+		}
+		ageLooseObject(t, repoPath, staged)
+
+		result, err := gs.GarbageCollect(ctx, repoPath, GCOptions{})
+		if err != nil {
+			t.Fatalf("GarbageCollect() error = %v", err)
+		}
+		if result.PackedObjects == 0 || result.PrunedObjects != 1 || result.SizeBefore == 0 || result.UsedCLI {
+			t.Errorf("result = %+v, want the reachable objects packed and the dangling blob pruned", result)
+		}
+
+		repo, err = git.PlainOpen(repoPath)
+		if err != nil {
+			t.Fatalf("failed to reopen repo: %v", err)
+		}
+		head, err := repo.Head()
+		if err != nil {
+			t.Fatalf("failed to read HEAD: %v", err)
+		}
+		if _, err := repo.CommitObject(head.Hash()); err != nil {
+			t.Errorf("HEAD commit lost: %v", err)
+		}
+		if _, err := repo.BlobObject(staged); err != nil {
+			t.Errorf("staged blob lost: %v", err)
+		}
+		if _, err := repo.BlobObject(dangling); err == nil {
+			t.Errorf("dangling blob still present")
+		}
+	})
+
+	t.Run("built-in keeps reflogs and stashes", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git executable not available")
+		}
+		t.Setenv("GIT_AUTHOR_NAME", "Test User")
+		t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+		t.Setenv("GIT_COMMITTER_NAME", "Test User")
+		t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+		repoPath, _, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+
+		// a commit only the HEAD reflog holds, and two stash entries
+		commitTestFile(t, repoPath, "dropped.txt", "dropped")
+		dropped, err := runGit(ctx, repoPath, "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatalf("git rev-parse failed: %v", err)
+		}
+		if _, err := runGit(ctx, repoPath, "reset", "--hard", "HEAD~1"); err != nil {
+			t.Fatalf("git reset failed: %v", err)
+		}
+		for _, content := range []string{"first stash", "second stash"} {
+			if err := os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte(content), 0644); err != nil {
+				t.Fatalf("failed to modify test.txt: %v", err)
+			}
+			if _, err := runGit(ctx, repoPath, "stash", "push"); err != nil {
+				t.Fatalf("git stash failed: %v", err)
+			}
+		}
+		// every object is old enough to be pruned if it were unreachable
+		old := time.Now().Add(-30 * 24 * time.Hour)
+		_ = filepath.WalkDir(filepath.Join(repoPath, ".git", "objects"), func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				_ = os.Chtimes(path, old, old)
+			}
+			return nil
+		})
+
+		if _, err := gs.GarbageCollect(ctx, repoPath, GCOptions{}); err != nil {
+			t.Fatalf("GarbageCollect() error = %v", err)
+		}
+		for _, rev := range []string{"stash@{0}", "stash@{1}", strings.TrimSpace(dropped)} {
+			if _, err := runGit(ctx, repoPath, "show", "--quiet", rev); err != nil {
+				t.Errorf("%s lost: %v", rev, err)
+			}
+		}
+		if _, err := runGit(ctx, repoPath, "fsck", "--no-dangling"); err != nil {
+			t.Errorf("repository corrupted: %v", err)
+		}
+	})
+
+	t.Run("cli", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git executable not available")
+		}
+		repoPath, _, cleanup := setupTestRepoWithRemote(t)
+		defer cleanup()
+
+		result, err := gs.GarbageCollect(ctx, repoPath, GCOptions{UseCLI: true})
+		if err != nil {
+			t.Fatalf("GarbageCollect() error = %v", err)
+		}
+		if !result.UsedCLI || result.SizeAfter == 0 {
+			t.Errorf("result = %+v", result)
+		}
+		if _, err := runGit(ctx, repoPath, "fsck", "--no-dangling"); err != nil {
+			t.Errorf("repository corrupted: %v", err)
+		}
+	})
+}
@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -284,7 +289,7 @@ func TestGitModelService_FetchLatest(t *testing.T) {
 			defer cancel()
 
 			service := NewGitService(&DefaultLogger{})
-			err := service.FetchLatest(ctx, repoPath)
+			err := service.FetchLatest(ctx, repoPath, Options{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("FetchLatest() error = %v, wantErr %v", err, tt.wantErr)
@@ -351,7 +356,7 @@ func TestGitModelService_UpdateAllBranchesProject(t *testing.T) {
 			defer cancel()
 
 			service := NewGitService(&DefaultLogger{})
-			result, err := service.UpdateAllBranchesProject(ctx, repoPath)
+			result, err := service.UpdateAllBranchesProject(ctx, repoPath, UpdateOptions{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("UpdateAllBranchesProject() error = %v, wantErr %v", err, tt.wantErr)
@@ -374,6 +379,16 @@ func TestGitModelService_UpdateAllBranchesProject(t *testing.T) {
 			if len(result.Failed) > 0 {
 				t.Errorf("Failed branches = %v", result.Failed)
 			}
+
+			for _, branch := range result.Updated {
+				if !result.Verified[branch] {
+					t.Errorf("Branch %s was not verified against remote", branch)
+				}
+			}
+
+			if !result.WorktreeClean {
+				t.Error("Worktree should be clean after update")
+			}
 		})
 	}
 }
@@ -388,7 +403,7 @@ func TestGitModelService_ContextCancellation(t *testing.T) {
 	cancel() // Cancel immediately
 
 	service := NewGitService(&DefaultLogger{})
-	_, err := service.UpdateAllBranchesProject(ctx, repoPath)
+	_, err := service.UpdateAllBranchesProject(ctx, repoPath, UpdateOptions{})
 
 	if err == nil {
 		t.Error("Expected error from cancelled context, got nil")
@@ -491,7 +506,7 @@ func TestGitModelService_UpdateRemote(t *testing.T) {
 		defer cancel()
 
 		service := NewGitService(&DefaultLogger{})
-		err = service.UpdateRemote(ctx, tmpDir, "https://github.com/neworg", false)
+		err = service.UpdateRemote(ctx, tmpDir, "https://github.com/neworg", Options{})
 
 		// Should error because fetch will fail (remote doesn't exist)
 		if err == nil {
@@ -538,7 +553,7 @@ func TestGitModelService_UpdateRemote(t *testing.T) {
 		defer cancel()
 
 		service := NewGitService(&DefaultLogger{})
-		err = service.UpdateRemote(ctx, tmpDir, "https://github.com/neworg", false)
+		err = service.UpdateRemote(ctx, tmpDir, "https://github.com/neworg", Options{})
 
 		if err == nil {
 			t.Error("UpdateRemote() expected error for missing origin, got nil")
@@ -550,7 +565,7 @@ func TestGitModelService_UpdateRemote(t *testing.T) {
 		defer cancel()
 
 		service := NewGitService(&DefaultLogger{})
-		err := service.UpdateRemote(ctx, "/non/existent/path", "https://github.com/neworg", false)
+		err := service.UpdateRemote(ctx, "/non/existent/path", "https://github.com/neworg", Options{})
 
 		if err == nil {
 			t.Error("UpdateRemote() expected error for non-existent path, got nil")
@@ -589,7 +604,7 @@ func TestGitModelService_UpdateRemote(t *testing.T) {
 		defer cancel()
 
 		service := NewGitService(&DefaultLogger{})
-		err = service.UpdateRemote(ctx, tmpDir, "https://github.com/nonexistent", false)
+		err = service.UpdateRemote(ctx, tmpDir, "https://github.com/nonexistent", Options{})
 
 		// Should error because fetch fails
 		if err == nil {
@@ -640,7 +655,7 @@ func TestGitModelService_UpdateRemote_ProjectNamePreservation(t *testing.T) {
 	defer cancel()
 
 	service := NewGitService(&DefaultLogger{})
-	err = service.UpdateRemote(ctx, tmpDir, "https://github.com/newcompany", false)
+	err = service.UpdateRemote(ctx, tmpDir, "https://github.com/newcompany", Options{})
 
 	// Will fail fetch, but check the URL construction logic
 	repo, _ = git.PlainOpen(tmpDir)
@@ -717,7 +732,7 @@ func TestGitModelService_UpdateRemote_LocalFilePaths(t *testing.T) {
 		defer cancel()
 
 		service := NewGitService(&DefaultLogger{})
-		err = service.UpdateRemote(ctx, tmpDir, newBareDir, false)
+		err = service.UpdateRemote(ctx, tmpDir, newBareDir, Options{})
 
 		if err != nil {
 			t.Errorf("UpdateRemote() should succeed with valid local paths, got error: %v", err)
@@ -788,7 +803,7 @@ func TestGitModelService_UpdateRemote_LocalFilePaths(t *testing.T) {
 		defer cancel()
 
 		service := NewGitService(&DefaultLogger{})
-		err = service.UpdateRemote(ctx, tmpDir, newBareDir, false)
+		err = service.UpdateRemote(ctx, tmpDir, newBareDir, Options{})
 
 		if err != nil {
 			t.Errorf("UpdateRemote() should succeed, got error: %v", err)
@@ -863,7 +878,7 @@ func TestGitModelService_UpdateRemote_LocalFilePaths(t *testing.T) {
 		defer cancel()
 
 		service := NewGitService(&DefaultLogger{})
-		err = service.UpdateRemote(ctx, tmpDir, newBareDir, false)
+		err = service.UpdateRemote(ctx, tmpDir, newBareDir, Options{})
 
 		if err != nil {
 			t.Errorf("UpdateRemote() should succeed, got error: %v", err)
@@ -879,3 +894,907 @@ func TestGitModelService_UpdateRemote_LocalFilePaths(t *testing.T) {
 		}
 	})
 }
+
+// TestGitModelService_CloneRepository tests cloning from a local bare repository
+func TestGitModelService_CloneRepository(t *testing.T) {
+	_, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	target := filepath.Join(t.TempDir(), "clone")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service := NewGitService(&DefaultLogger{})
+	if err := service.CloneRepository(ctx, bareDir, target, Options{}); err != nil {
+		t.Fatalf("CloneRepository() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(target, "test.txt")); err != nil {
+		t.Errorf("cloned worktree is missing test.txt: %v", err)
+	}
+
+	if err := service.CloneRepository(ctx, bareDir, target, Options{}); err == nil {
+		t.Error("CloneRepository() expected error for existing target, got nil")
+	}
+}
+
+// TestGitModelService_PruneBranches tests pruning of merged and gone branches
+func TestGitModelService_PruneBranches(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		t.Run(fmt.Sprintf("dry run %v", dryRun), func(t *testing.T) {
+			repoPath, _, cleanup := setupTestRepoWithBranches(t)
+			defer cleanup()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			service := NewGitService(&DefaultLogger{})
+			result, err := service.PruneBranches(ctx, repoPath, DefaultProtectedBranches, Options{DryRun: dryRun})
+			if err != nil {
+				t.Fatalf("PruneBranches() error = %v", err)
+			}
+
+			pruned := result.Deleted
+			if dryRun {
+				pruned = result.DryRun
+			}
+			if len(pruned) != 1 || pruned[0] != "feature" {
+				t.Errorf("pruned branches = %v, want [feature]", pruned)
+			}
+			if result.Reasons["feature"] != PruneReasonMerged {
+				t.Errorf("reason = %q, want %q", result.Reasons["feature"], PruneReasonMerged)
+			}
+
+			repo, _ := git.PlainOpen(repoPath)
+			_, err = repo.Reference(plumbing.NewBranchReferenceName("feature"), true)
+			if dryRun && err != nil {
+				t.Errorf("dry run deleted feature branch: %v", err)
+			}
+			if !dryRun && err == nil {
+				t.Error("feature branch still exists after prune")
+			}
+			if _, err := repo.Reference(plumbing.NewBranchReferenceName("develop"), true); err != nil {
+				t.Errorf("protected develop branch was deleted: %v", err)
+			}
+		})
+	}
+}
+
+// TestGitModelService_UpdateAllBranchesProject_DirtyWorktree tests dirty repositories are skipped or autostashed
+func TestGitModelService_UpdateAllBranchesProject_DirtyWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test User")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+
+	tests := []struct {
+		name        string
+		file        string
+		opts        UpdateOptions
+		wantSkip    string
+		wantUpdated int
+	}{
+		{name: "dirty worktree is skipped", file: "test.txt", opts: UpdateOptions{}, wantSkip: SkipReasonDirtyWorktree, wantUpdated: 0},
+		{name: "dirty worktree is autostashed", file: "test.txt", opts: UpdateOptions{AutoStash: true}, wantSkip: "", wantUpdated: 2},
+		{name: "untracked file is skipped", file: "untracked.txt", opts: UpdateOptions{}, wantSkip: SkipReasonDirtyWorktree, wantUpdated: 0},
+		{name: "untracked file is autostashed", file: "untracked.txt", opts: UpdateOptions{AutoStash: true}, wantSkip: "", wantUpdated: 2},
+		{name: "untracked file is kept without checkout", file: "untracked.txt", opts: UpdateOptions{NoCheckout: true}, wantSkip: "", wantUpdated: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoPath, _, cleanup := setupTestRepoWithBranches(t)
+			defer cleanup()
+
+			testFile := filepath.Join(repoPath, tt.file)
+			if err := os.WriteFile(testFile, []byte("local change"), 0644); err != nil {
+				t.Fatalf("failed to modify file: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			service := NewGitService(&DefaultLogger{})
+			result, err := service.UpdateAllBranchesProject(ctx, repoPath, tt.opts)
+			if err != nil {
+				t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+			}
+
+			if result.SkipReason != tt.wantSkip {
+				t.Errorf("SkipReason = %q, want %q", result.SkipReason, tt.wantSkip)
+			}
+			if len(result.Updated) != tt.wantUpdated {
+				t.Errorf("Updated branches = %v, want %d", result.Updated, tt.wantUpdated)
+			}
+
+			content, _ := os.ReadFile(testFile)
+			if string(content) != "local change" {
+				t.Errorf("local change lost, file content = %q", content)
+			}
+		})
+	}
+}
+
+// TestGitModelService_UpdateAllBranchesProject_StashLeft tests changes that
+// cannot be popped after the update are reported as left in the stash
+func TestGitModelService_UpdateAllBranchesProject_StashLeft(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test User")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test User")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	repoPath, _, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+	if err := os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte("local change"), 0644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+
+	service := NewGitService(&DefaultLogger{})
+	// an index lock taken during the update makes the stash pop fail
+	indexLock := filepath.Join(repoPath, ".git", "index.lock")
+	service.SetProgressSink(func(event ProgressEvent) {
+		if event.Kind == EventBranchUpdated {
+			_ = os.WriteFile(indexLock, nil, 0644)
+		}
+	})
+	result, err := service.UpdateAllBranchesProject(context.Background(), repoPath, UpdateOptions{AutoStash: true})
+	if err := os.Remove(indexLock); err != nil {
+		t.Fatalf("failed to remove the index lock: %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "kept in the stash") {
+		t.Fatalf("UpdateAllBranchesProject() error = %v, want the changes kept in the stash", err)
+	}
+	if result == nil || !result.Stashed || !result.StashLeft {
+		t.Errorf("result = %+v, want StashLeft", result)
+	}
+	if out, err := runGit(context.Background(), repoPath, "stash", "list"); err != nil || !strings.Contains(out, "goktor autostash") {
+		t.Errorf("stash list = %q, %v, want the autostash", out, err)
+	}
+}
+
+// TestGitModelService_UpdateAllBranchesProject_SkipReasons tests repositories the update cannot work on are skipped with a reason
+func TestGitModelService_UpdateAllBranchesProject_SkipReasons(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*testing.T) (string, func())
+		wantSkip string
+	}{
+		{
+			name: "no commits",
+			setup: func(t *testing.T) (string, func()) {
+				tmpDir := t.TempDir()
+				if _, err := git.PlainInit(tmpDir, false); err != nil {
+					t.Fatalf("failed to init repo: %v", err)
+				}
+				return tmpDir, func() {}
+			},
+			wantSkip: SkipReasonNoCommits,
+		},
+		{
+			name: "detached HEAD",
+			setup: func(t *testing.T) (string, func()) {
+				repoPath, _, cleanup := setupTestRepoWithRemote(t)
+				repo, err := git.PlainOpen(repoPath)
+				if err != nil {
+					t.Fatalf("failed to open repo: %v", err)
+				}
+				head, err := repo.Head()
+				if err != nil {
+					t.Fatalf("failed to get HEAD: %v", err)
+				}
+				if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, head.Hash())); err != nil {
+					t.Fatalf("failed to detach HEAD: %v", err)
+				}
+				return repoPath, cleanup
+			},
+			wantSkip: SkipReasonDetachedHead,
+		},
+		{
+			name:     "no origin remote",
+			setup:    setupTestRepo,
+			wantSkip: SkipReasonNoRemote,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoPath, cleanup := tt.setup(t)
+			defer cleanup()
+
+			service := NewGitService(&DefaultLogger{})
+			result, err := service.UpdateAllBranchesProject(context.Background(), repoPath, UpdateOptions{})
+			if err != nil {
+				t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+			}
+			if result.SkipReason != tt.wantSkip {
+				t.Errorf("SkipReason = %q, want %q", result.SkipReason, tt.wantSkip)
+			}
+		})
+	}
+}
+
+// TestGitModelService_UpdateAllBranchesProject_NoCheckout tests ref-only fast-forward updates
+func TestGitModelService_UpdateAllBranchesProject_NoCheckout(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, _ := repo.Head()
+
+	// Move origin/feature one commit ahead of the local feature branch
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "feature"), head.Hash())); err != nil {
+		t.Fatalf("failed to move remote ref: %v", err)
+	}
+	if err := repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + head.Hash().String() + ":refs/heads/feature")},
+	}); err != nil {
+		t.Fatalf("failed to push feature: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	service := NewGitService(&DefaultLogger{})
+	result, err := service.UpdateAllBranchesProject(ctx, repoPath, UpdateOptions{NoCheckout: true})
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+
+	if len(result.Updated) != 2 || len(result.Failed) != 0 {
+		t.Errorf("Updated = %v, Failed = %v, want 2 updated", result.Updated, result.Failed)
+	}
+
+	feature, err := repo.Reference(plumbing.NewBranchReferenceName("feature"), true)
+	if err != nil {
+		t.Fatalf("failed to read feature branch: %v", err)
+	}
+	if feature.Hash() != head.Hash() {
+		t.Errorf("feature = %s, want fast-forward to %s", feature.Hash(), head.Hash())
+	}
+	if !result.Verified["feature"] {
+		t.Error("feature branch was not verified")
+	}
+	if result.TotalTime <= 0 || result.FetchTime <= 0 || result.FetchTime > result.TotalTime {
+		t.Errorf("TotalTime = %s, FetchTime = %s, want fetch within total", result.TotalTime, result.FetchTime)
+	}
+	if _, ok := result.BranchTimes["feature"]; !ok {
+		t.Errorf("BranchTimes = %v, want an entry for feature", result.BranchTimes)
+	}
+}
+
+// TestGitModelService_UpdateAllBranchesProject_NoCheckoutUntracked tests the
+// branches that need a checkout are left as they are when it would delete
+// untracked files
+func TestGitModelService_UpdateAllBranchesProject_NoCheckoutUntracked(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test User")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test User")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	repoPath, _, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+	ctx := context.Background()
+	// a local commit on develop makes its update need a checkout and reset
+	local, err := runGit(ctx, repoPath, "commit-tree", "develop^{tree}", "-p", "develop", "-m", "local")
+	if err != nil {
+		t.Fatalf("failed to create commit: %v", err)
+	}
+	if _, err := runGit(ctx, repoPath, "branch", "-f", "develop", strings.TrimSpace(local)); err != nil {
+		t.Fatalf("failed to move develop: %v", err)
+	}
+	untracked := filepath.Join(repoPath, "untracked.txt")
+	if err := os.WriteFile(untracked, []byte("local"), 0644); err != nil {
+		t.Fatalf("failed to write untracked file: %v", err)
+	}
+
+	service := NewGitService(&DefaultLogger{})
+	result, err := service.UpdateAllBranchesProject(ctx, repoPath, UpdateOptions{NoCheckout: true})
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+	if !slices.Contains(result.Skipped, "develop") || slices.Contains(result.Updated, "develop") {
+		t.Errorf("Skipped = %v, Updated = %v, want develop skipped", result.Skipped, result.Updated)
+	}
+	if _, err := os.Stat(untracked); err != nil {
+		t.Errorf("untracked file lost: %v", err)
+	}
+}
+
+// TestGitModelService_UpdateAllBranchesProject_RestoresHeadOnFailure tests a
+// branch failing after its checkout does not leave the repository on it
+func TestGitModelService_UpdateAllBranchesProject_RestoresHeadOnFailure(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, _ := repo.Head()
+
+	// the checkout of broken succeeds, the hard reset to its remote commit,
+	// missing from the object store, fails
+	for _, ref := range []*plumbing.Reference{
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("broken"), head.Hash()),
+		plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "broken"), plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")),
+	} {
+		if err := repo.Storer.SetReference(ref); err != nil {
+			t.Fatalf("failed to create %s: %v", ref.Name(), err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	service := NewGitService(&DefaultLogger{})
+	result, err := service.UpdateAllBranchesProject(ctx, repoPath, UpdateOptions{})
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+	if !slices.Contains(result.Failed, "broken") {
+		t.Errorf("Failed = %v, want broken", result.Failed)
+	}
+	if result.OriginalHead != head.Name().Short() || !result.HeadRestored || result.RestoreError != "" {
+		t.Errorf("OriginalHead = %q, HeadRestored = %v, RestoreError = %q, want %s restored",
+			result.OriginalHead, result.HeadRestored, result.RestoreError, head.Name().Short())
+	}
+	restored, _ := repo.Head()
+	if restored.Name() != head.Name() {
+		t.Errorf("HEAD = %s after the failure, want %s", restored.Name(), head.Name())
+	}
+}
+
+// TestGitModelService_UpdateAllBranchesProject_BranchPatterns tests include/exclude glob filtering
+func TestGitModelService_UpdateAllBranchesProject_BranchPatterns(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         UpdateOptions
+		wantUpdated  []string
+		wantExcluded []string
+	}{
+		{"include", UpdateOptions{Branches: []string{"feat*"}}, []string{"feature"}, []string{"develop"}},
+		{"exclude", UpdateOptions{ExcludeBranches: []string{"feature"}}, []string{"develop"}, []string{"feature"}},
+		{"include and exclude", UpdateOptions{Branches: []string{"*"}, ExcludeBranches: []string{"*"}}, []string{}, []string{"develop", "feature"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoPath, _, cleanup := setupTestRepoWithBranches(t)
+			defer cleanup()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			service := NewGitService(&DefaultLogger{})
+			result, err := service.UpdateAllBranchesProject(ctx, repoPath, tt.opts)
+			if err != nil {
+				t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+			}
+
+			slices.Sort(result.Updated)
+			slices.Sort(result.Excluded)
+			if !slices.Equal(result.Updated, tt.wantUpdated) {
+				t.Errorf("Updated = %v, want %v", result.Updated, tt.wantUpdated)
+			}
+			if !slices.Equal(result.Excluded, tt.wantExcluded) {
+				t.Errorf("Excluded = %v, want %v", result.Excluded, tt.wantExcluded)
+			}
+		})
+	}
+}
+
+// TestGitModelService_UpdateAllBranchesProject_Protected tests that protected
+// branches are fast-forwarded but never hard-reset
+func TestGitModelService_UpdateAllBranchesProject_Protected(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, _ := repo.Head()
+
+	// develop gets a local commit missing from origin/develop
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("develop"), head.Hash())); err != nil {
+		t.Fatalf("failed to move develop: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	service := NewGitService(&DefaultLogger{})
+	result, err := service.UpdateAllBranchesProject(ctx, repoPath, UpdateOptions{Protected: []string{"dev*", "feature"}})
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+
+	if !slices.Equal(result.Protected, []string{"develop"}) || !slices.Contains(result.Skipped, "develop") {
+		t.Errorf("Protected = %v, Skipped = %v, want develop left as is", result.Protected, result.Skipped)
+	}
+	if !slices.Contains(result.Updated, "feature") {
+		t.Errorf("Updated = %v, want the protected feature branch fast-forwarded", result.Updated)
+	}
+	develop, err := repo.Reference(plumbing.NewBranchReferenceName("develop"), true)
+	if err != nil {
+		t.Fatalf("failed to read develop branch: %v", err)
+	}
+	if develop.Hash() != head.Hash() {
+		t.Errorf("develop = %s, want its local commit %s kept", develop.Hash(), head.Hash())
+	}
+}
+
+// TestGitModelService_RepoStatus tests the offline repository snapshot
+func TestGitModelService_RepoStatus(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+
+	service := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+
+	status, err := service.RepoStatus(ctx, repoPath, 24*time.Hour, Options{})
+	if err != nil {
+		t.Fatalf("RepoStatus() error = %v", err)
+	}
+	if status.Branch == "" || !status.HasRemote || status.Dirty {
+		t.Errorf("status = %+v, want clean branch with remote", status)
+	}
+	if len(status.StaleBranches) != 0 {
+		t.Errorf("StaleBranches = %v, want none for fresh branches", status.StaleBranches)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoPath, "test2.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	status, err = service.RepoStatus(ctx, repoPath, -time.Hour, Options{})
+	if err != nil {
+		t.Fatalf("RepoStatus() error = %v", err)
+	}
+	if !status.Dirty {
+		t.Error("Dirty = false after modifying a tracked file")
+	}
+	if status.StaleBranches["feature"] != StaleReasonInactive || status.StaleBranches["develop"] != StaleReasonInactive {
+		t.Errorf("StaleBranches = %v, want feature and develop inactive", status.StaleBranches)
+	}
+}
+
+// commitTestFile writes name in the worktree of repoPath and commits it
+func commitTestFile(t *testing.T, repoPath, name, content string) {
+	t.Helper()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	if _, err := worktree.Add(name); err != nil {
+		t.Fatalf("failed to add %s: %v", name, err)
+	}
+	if _, err := worktree.Commit("add "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatalf("failed to commit %s: %v", name, err)
+	}
+}
+
+// pushRemoteCommit adds a commit to the remote through a separate clone
+func pushRemoteCommit(t *testing.T, bareDir, name string) {
+	t.Helper()
+
+	cloneDir := t.TempDir()
+	repo, err := git.PlainClone(cloneDir, false, &git.CloneOptions{URL: bareDir})
+	if err != nil {
+		t.Fatalf("failed to clone remote: %v", err)
+	}
+	commitTestFile(t, cloneDir, name, "remote content")
+	if err := repo.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatalf("failed to push remote commit: %v", err)
+	}
+}
+
+// TestGitModelService_PullCurrentBranch tests fast-forward, divergence, rebase and skip outcomes
+func TestGitModelService_PullCurrentBranch(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "Test User")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test User")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	tests := []struct {
+		name       string
+		opts       PullOptions
+		setup      func(t *testing.T, repoPath, bareDir string)
+		needsGit   bool
+		wantStatus string
+		wantFiles  []string
+	}{
+		{
+			name:       "up to date",
+			setup:      func(t *testing.T, repoPath, bareDir string) {},
+			wantStatus: PullStatusUpToDate,
+		},
+		{
+			name: "fast-forward",
+			setup: func(t *testing.T, repoPath, bareDir string) {
+				pushRemoteCommit(t, bareDir, "remote.txt")
+			},
+			needsGit:   true,
+			wantStatus: PullStatusFastForwarded,
+			wantFiles:  []string{"remote.txt"},
+		},
+		{
+			name: "fast-forward keeps untracked files",
+			setup: func(t *testing.T, repoPath, bareDir string) {
+				pushRemoteCommit(t, bareDir, "remote.txt")
+				os.WriteFile(filepath.Join(repoPath, "untracked.txt"), []byte("local"), 0644)
+			},
+			needsGit:   true,
+			wantStatus: PullStatusFastForwarded,
+			wantFiles:  []string{"remote.txt", "untracked.txt"},
+		},
+		{
+			name: "ahead",
+			setup: func(t *testing.T, repoPath, bareDir string) {
+				commitTestFile(t, repoPath, "local.txt", "local content")
+			},
+			wantStatus: PullStatusAhead,
+		},
+		{
+			name: "diverged without rebase",
+			setup: func(t *testing.T, repoPath, bareDir string) {
+				pushRemoteCommit(t, bareDir, "remote.txt")
+				commitTestFile(t, repoPath, "local.txt", "local content")
+			},
+			wantStatus: PullStatusDiverged,
+			wantFiles:  []string{"local.txt"},
+		},
+		{
+			name: "diverged with rebase",
+			opts: PullOptions{Rebase: true},
+			setup: func(t *testing.T, repoPath, bareDir string) {
+				pushRemoteCommit(t, bareDir, "remote.txt")
+				commitTestFile(t, repoPath, "local.txt", "local content")
+			},
+			needsGit:   true,
+			wantStatus: PullStatusRebased,
+			wantFiles:  []string{"local.txt", "remote.txt"},
+		},
+		{
+			name: "dirty worktree",
+			setup: func(t *testing.T, repoPath, bareDir string) {
+				pushRemoteCommit(t, bareDir, "remote.txt")
+				os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte("local change"), 0644)
+			},
+			wantStatus: PullStatusSkipped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exec.LookPath("git"); tt.needsGit && err != nil {
+				t.Skip("git executable not available")
+			}
+
+			repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+			defer cleanup()
+			tt.setup(t, repoPath, bareDir)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			service := NewGitService(&DefaultLogger{})
+			result, err := service.PullCurrentBranch(ctx, repoPath, tt.opts)
+			if err != nil {
+				t.Fatalf("PullCurrentBranch() error = %v", err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", result.Status, tt.wantStatus)
+			}
+			for _, name := range tt.wantFiles {
+				if _, err := os.Stat(filepath.Join(repoPath, name)); err != nil {
+					t.Errorf("%s missing after pull: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+// TestGitModelService_UpdateRemote_NamedRemote tests rewriting a non-origin remote with extra URLs
+func TestGitModelService_UpdateRemote_NamedRemote(t *testing.T) {
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	newBaseDir := t.TempDir()
+	newRepoPath := filepath.Join(newBaseDir, "project.git")
+	if _, err := git.PlainInit(newRepoPath, true); err != nil {
+		t.Fatalf("failed to init new remote: %v", err)
+	}
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	mirrorURL := "https://mirror.example.com/project.git"
+	if _, err := repo.CreateRemote(&config.RemoteConfig{
+		Name: "upstream",
+		URLs: []string{newRepoPath, mirrorURL},
+	}); err != nil {
+		t.Fatalf("failed to create upstream remote: %v", err)
+	}
+	if err := repo.Push(&git.PushOptions{RemoteName: "upstream", RemoteURL: newRepoPath}); err != nil {
+		t.Fatalf("failed to push to upstream: %v", err)
+	}
+
+	// Point upstream at a stale location, keeping the mirror URL
+	cfg, _ := repo.Storer.Config()
+	if err := setRemoteURLs(cfg, cfg.Remotes["upstream"], []string{filepath.Join(t.TempDir(), "project.git"), mirrorURL}); err != nil {
+		t.Fatalf("failed to set URLs: %v", err)
+	}
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		t.Fatalf("failed to set config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service := NewGitService(&DefaultLogger{})
+	if err := service.UpdateRemote(ctx, repoPath, newBaseDir, Options{Remote: "upstream", DryRun: true}); err != nil {
+		t.Fatalf("UpdateRemote() dry run error = %v", err)
+	}
+	repo, _ = git.PlainOpen(repoPath)
+	if cfg, _ = repo.Storer.Config(); cfg.Remotes["upstream"].URLs[0] == newRepoPath {
+		t.Error("dry run updated the upstream URL")
+	}
+
+	if err := service.UpdateRemote(ctx, repoPath, newBaseDir, Options{Remote: "upstream"}); err != nil {
+		t.Fatalf("UpdateRemote() error = %v", err)
+	}
+
+	repo, _ = git.PlainOpen(repoPath)
+	cfg, _ = repo.Storer.Config()
+	if got := cfg.Remotes["upstream"].URLs; !slices.Equal(got, []string{newRepoPath, mirrorURL}) {
+		t.Errorf("upstream URLs = %v, want %v", got, []string{newRepoPath, mirrorURL})
+	}
+	if got := cfg.Remotes["origin"].URLs[0]; got != bareDir {
+		t.Errorf("origin URL = %v, want unchanged %v", got, bareDir)
+	}
+
+	if err := service.UpdateRemote(ctx, repoPath, newBaseDir, Options{Remote: "missing"}); err == nil {
+		t.Error("UpdateRemote() expected error for unknown remote")
+	}
+}
+
+func TestGitModelService_RewriteRemote(t *testing.T) {
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	// Move the bare remote under a nested group the base-URL heuristic cannot rebuild
+	newBareDir := filepath.Join(t.TempDir(), "group", "subgroup", "project.git")
+	if err := os.MkdirAll(filepath.Dir(newBareDir), 0755); err != nil {
+		t.Fatalf("failed to create group dir: %v", err)
+	}
+	if err := os.Rename(bareDir, newBareDir); err != nil {
+		t.Fatalf("failed to move remote: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service := NewGitService(&DefaultLogger{})
+
+	noMatch, _ := ParseRemoteRewrite("s#does-not-exist#x#")
+	if err := service.RewriteRemote(ctx, repoPath, noMatch, Options{}); !errors.Is(err, ErrRemoteUnchanged) {
+		t.Errorf("RewriteRemote() error = %v, want ErrRemoteUnchanged", err)
+	}
+
+	rewrite, err := ParseRemoteRewrite("s#^.*$#" + filepath.ToSlash(newBareDir) + "#")
+	if err != nil {
+		t.Fatalf("ParseRemoteRewrite() error = %v", err)
+	}
+	if err := service.RewriteRemote(ctx, repoPath, rewrite, Options{}); err != nil {
+		t.Fatalf("RewriteRemote() error = %v", err)
+	}
+
+	repo, _ := git.PlainOpen(repoPath)
+	cfg, _ := repo.Storer.Config()
+	if got := cfg.Remotes["origin"].URLs[0]; got != filepath.ToSlash(newBareDir) {
+		t.Errorf("origin URL = %v, want %v", got, filepath.ToSlash(newBareDir))
+	}
+
+	// A rewrite pointing nowhere is rolled back
+	broken, _ := ParseRemoteRewrite("s#project\\.git$#missing.git#")
+	if err := service.RewriteRemote(ctx, repoPath, broken, Options{}); err == nil {
+		t.Error("RewriteRemote() expected error for unreachable remote")
+	}
+	repo, _ = git.PlainOpen(repoPath)
+	cfg, _ = repo.Storer.Config()
+	if got := cfg.Remotes["origin"].URLs[0]; got != filepath.ToSlash(newBareDir) {
+		t.Errorf("origin URL after rollback = %v, want %v", got, filepath.ToSlash(newBareDir))
+	}
+}
+
+func TestGitModelService_ConvertRemote(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service := NewGitService(&DefaultLogger{})
+
+	// Local remotes cannot be converted
+	if err := service.ConvertRemote(ctx, repoPath, RemoteProtocolHTTPS, Options{}); err == nil || errors.Is(err, ErrRemoteUnchanged) {
+		t.Errorf("ConvertRemote() error = %v, want conversion error", err)
+	}
+
+	repo, _ := git.PlainOpen(repoPath)
+	cfg, _ := repo.Storer.Config()
+	sshURL := "git@127.0.0.1:org/repo.git"
+	if err := setRemoteURLs(cfg, cfg.Remotes["origin"], []string{sshURL}); err != nil {
+		t.Fatalf("failed to set URLs: %v", err)
+	}
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		t.Fatalf("failed to set config: %v", err)
+	}
+
+	if err := service.ConvertRemote(ctx, repoPath, RemoteProtocolSSH, Options{}); !errors.Is(err, ErrRemoteUnchanged) {
+		t.Errorf("ConvertRemote() error = %v, want ErrRemoteUnchanged", err)
+	}
+
+	// The HTTPS endpoint is unreachable, so the conversion is rolled back
+	if err := service.ConvertRemote(ctx, repoPath, RemoteProtocolHTTPS, Options{}); err == nil {
+		t.Error("ConvertRemote() expected fetch error")
+	}
+	repo, _ = git.PlainOpen(repoPath)
+	cfg, _ = repo.Storer.Config()
+	if got := cfg.Remotes["origin"].URLs[0]; got != sshURL {
+		t.Errorf("origin URL after rollback = %v, want %v", got, sshURL)
+	}
+
+	// With force the converted URL is kept
+	if err := service.ConvertRemote(ctx, repoPath, RemoteProtocolHTTPS, Options{Force: true}); err != nil {
+		t.Errorf("ConvertRemote() with force error = %v", err)
+	}
+	repo, _ = git.PlainOpen(repoPath)
+	cfg, _ = repo.Storer.Config()
+	if got := cfg.Remotes["origin"].URLs[0]; got != "https://127.0.0.1/org/repo.git" {
+		t.Errorf("origin URL = %v, want https://127.0.0.1/org/repo.git", got)
+	}
+}
+
+func TestGitModelService_SoftDeleteAndRestoreBranch(t *testing.T) {
+	repoPath, bareDir, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	gs := &GitModelService{logger: &DefaultLogger{}}
+	repo, _ := git.PlainOpen(repoPath)
+	if err := gs.fetch(ctx, repo); err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	tip, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", "feature"), true)
+	if err != nil {
+		t.Fatalf("failed to resolve origin/feature: %v", err)
+	}
+
+	bare, _ := git.PlainOpen(bareDir)
+	remoteRef := func(name string) *plumbing.Reference {
+		ref, err := bare.Reference(plumbing.ReferenceName(name), false)
+		if err != nil {
+			return nil
+		}
+		return ref
+	}
+
+	policy := &softDeletePolicy{keep: 24 * time.Hour, tagger: goktorIdentity}
+	if err := gs.softDeleteRemoteBranch(ctx, repo, "origin", "feature", policy); err != nil {
+		t.Fatalf("softDeleteRemoteBranch() error = %v", err)
+	}
+	if remoteRef("refs/heads/feature") != nil {
+		t.Error("feature branch still exists on the remote")
+	}
+	if remoteRef(deletedRefPrefix+"feature") == nil {
+		t.Fatal("backup ref missing on the remote")
+	}
+
+	deleted, err := gs.ListDeletedBranches(ctx, repoPath, Options{})
+	if err != nil {
+		t.Fatalf("ListDeletedBranches() error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].Branch != "feature" || deleted[0].Hash != tip.Hash().String() {
+		t.Fatalf("ListDeletedBranches() = %+v, want feature at %s", deleted, tip.Hash())
+	}
+	if expires := time.Until(deleted[0].ExpiresAt); expires < 23*time.Hour || expires > 25*time.Hour {
+		t.Errorf("ExpiresAt = %v, want about 24h from now", deleted[0].ExpiresAt)
+	}
+
+	if err := gs.RestoreBranch(ctx, repoPath, "feature", Options{}); err != nil {
+		t.Fatalf("RestoreBranch() error = %v", err)
+	}
+	if ref := remoteRef("refs/heads/feature"); ref == nil || ref.Hash() != tip.Hash() {
+		t.Errorf("restored feature = %v, want %s", ref, tip.Hash())
+	}
+	if remoteRef(deletedRefPrefix+"feature") != nil {
+		t.Error("backup ref still exists after restore")
+	}
+	if err := gs.RestoreBranch(ctx, repoPath, "feature", Options{}); !errors.Is(err, ErrBranchNotDeleted) {
+		t.Errorf("RestoreBranch() again error = %v, want ErrBranchNotDeleted", err)
+	}
+
+	// Expired backups are purged
+	if err := gs.fetch(ctx, repo); err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	policy.keep = -time.Hour
+	if err := gs.softDeleteRemoteBranch(ctx, repo, "origin", "feature", policy); err != nil {
+		t.Fatalf("softDeleteRemoteBranch() error = %v", err)
+	}
+	purged, err := gs.purgeExpiredBranches(ctx, repo, "origin")
+	if err != nil {
+		t.Fatalf("purgeExpiredBranches() error = %v", err)
+	}
+	if !slices.Equal(purged, []string{"feature"}) {
+		t.Errorf("purged = %v, want [feature]", purged)
+	}
+	if remoteRef(deletedRefPrefix+"feature") != nil {
+		t.Error("expired backup ref still exists on the remote")
+	}
+}
+
+// TestGitModelService_EstimateFetch tests counting the refs a fetch would download
+func TestGitModelService_EstimateFetch(t *testing.T) {
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	gs := NewGitService(&DefaultLogger{})
+	if err := gs.FetchLatest(ctx, repoPath, Options{}); err != nil {
+		t.Fatalf("FetchLatest() error = %v", err)
+	}
+
+	estimate, err := gs.EstimateFetch(ctx, repoPath, nil, Options{})
+	if err != nil {
+		t.Fatalf("EstimateFetch() error = %v", err)
+	}
+	if estimate.Refs != 0 || estimate.Bytes != 0 || estimate.Source != FetchSizeUpToDate {
+		t.Errorf("fetched repository estimate = %+v, want up to date", estimate)
+	}
+
+	pushRemoteCommit(t, bareDir, "remote.txt")
+	estimate, err = gs.EstimateFetch(ctx, repoPath, nil, Options{})
+	if err != nil {
+		t.Fatalf("EstimateFetch() error = %v", err)
+	}
+	// a local remote has no provider to size it
+	if estimate.Refs != 1 || estimate.Missing != 1 || estimate.Bytes != -1 || estimate.Source != FetchSizeUnknown {
+		t.Errorf("estimate after remote commit = %+v, want 1 missing ref of unknown size", estimate)
+	}
+
+	if err := gs.FetchLatest(ctx, repoPath, Options{}); err != nil {
+		t.Fatalf("FetchLatest() error = %v", err)
+	}
+	if estimate, _ = gs.EstimateFetch(ctx, repoPath, nil, Options{}); estimate.Source != FetchSizeUpToDate {
+		t.Errorf("estimate after fetch = %+v, want up to date", estimate)
+	}
+}
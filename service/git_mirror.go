@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// mirrorRefSpec pushes every branch, tag, remote-tracking branch and stash
+// like git push --mirror
+const mirrorRefSpec = "+refs/*:refs/*"
+
+// goktor.mirrorsource in the config of a local mirror records the absolute
+// path of the repository it mirrors
+const (
+	mirrorConfigSection = "goktor"
+	mirrorSourceOption  = "mirrorsource"
+)
+
+// MirrorResult is the outcome of mirroring a repository
+type MirrorResult struct {
+	// Target is the path or URL of the mirror
+	Target string `json:"target"`
+	// Created reports a local mirror initialized by this run
+	Created bool `json:"created,omitempty"`
+	// UpToDate reports a mirror that already had every ref
+	UpToDate bool `json:"up_to_date,omitempty"`
+	// Size is the size of a local mirror in bytes, zero for remote ones
+	Size int64 `json:"size,omitempty"`
+}
+
+// MirrorTarget returns where the repository at repoPath is mirrored under to:
+// <to>/<name>.git, to being a directory or the base URL of a git server.
+// Relative directories are resolved against the working directory.
+func MirrorTarget(repoPath string, to string) string {
+	name := filepath.Base(repoPath) + ".git"
+	if isNetworkRemote(to) {
+		return strings.TrimSuffix(to, "/") + "/" + name
+	}
+	if abs, err := filepath.Abs(to); err == nil {
+		to = abs
+	}
+	return filepath.Join(to, name)
+}
+
+// CheckMirrorTargets verifies no two of repoDirs share a mirror under to, as
+// repositories with the same base name would overwrite each other's mirror
+func CheckMirrorTargets(repoDirs []string, to string) error {
+	seen := make(map[string]string, len(repoDirs))
+	for _, repoDir := range repoDirs {
+		target := MirrorTarget(repoDir, to)
+		if other, ok := seen[target]; ok {
+			return fmt.Errorf("%s and %s would share the mirror %s", other, repoDir, target)
+		}
+		seen[target] = repoDir
+	}
+	return nil
+}
+
+// CheckMirrorSpace verifies the volume of the local mirrors under to has
+// room for the mirrors of repoDirs that do not exist yet, each about the size
+// of the git directory it copies. Mirrors on a git server need no local room.
+func CheckMirrorSpace(repoDirs []string, to string) error {
+	if isNetworkRemote(to) {
+		return nil
+	}
+	var required int64
+	for _, repoDir := range repoDirs {
+		if _, err := os.Stat(MirrorTarget(repoDir, to)); err == nil {
+			continue
+		}
+		gitDir := repoDir
+		if DetectRepoKind(repoDir) == RepoKindWorktree {
+			gitDir = filepath.Join(repoDir, git.GitDirName)
+		}
+		required += pathSize(gitDir)
+	}
+	to, _ = filepath.Abs(to)
+	return CheckDiskSpace(existingAncestor(to), required)
+}
+
+// MirrorRepository updates the bare mirror of repoPath under to, a directory
+// or the base URL of a git server, with every ref of the repository; refs
+// deleted from the repository are deleted from the mirror. Local mirrors are
+// created when missing, remote ones must exist. A local mirror records the
+// path of its repository and is refused to any other repository. With
+// opts.DryRun only the target is resolved.
+func (gs *GitModelService) MirrorRepository(ctx context.Context, repoPath string, to string, opts Options) (*MirrorResult, error) {
+	if to == "" {
+		return nil, fmt.Errorf("mirror target cannot be empty")
+	}
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	result := &MirrorResult{Target: MirrorTarget(repoPath, to)}
+	gs = gs.withRepo(repoPath, "mirror", result.Target)
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	source, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve repository path: %w", err)
+	}
+	local := !isNetworkRemote(to)
+	recorded := ""
+	if local {
+		if _, err := os.Stat(result.Target); errors.Is(err, os.ErrNotExist) {
+			result.Created = true
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read mirror: %w", err)
+		} else if recorded, err = mirrorSource(result.Target); err != nil {
+			return nil, err
+		} else if recorded != "" && recorded != source {
+			return nil, fmt.Errorf("mirror %s belongs to %s", result.Target, recorded)
+		}
+	}
+	if opts.DryRun {
+		gs.logger.Info("dry-run: would update mirror", "create", result.Created)
+		return result, nil
+	}
+
+	if result.Created {
+		if err := createMirror(repo, result.Target); err != nil {
+			return nil, err
+		}
+		gs.logger.Info("created mirror")
+	}
+	if local && recorded == "" {
+		if err := setMirrorSource(result.Target, source); err != nil {
+			return nil, err
+		}
+	}
+
+	// the identity fetch refspec keeps the push from recording remote-tracking
+	// refs of the mirror in the repository
+	remote, err := repo.CreateRemoteAnonymous(&config.RemoteConfig{
+		Name:  "anonymous",
+		URLs:  []string{result.Target},
+		Fetch: []config.RefSpec{mirrorRefSpec},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the mirror remote: %w", err)
+	}
+	err = gs.withAuth(ctx, result.Target, func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		refSpecs, err := mirrorRefSpecs(ctx, repo, remote, auth, proxy)
+		if err != nil {
+			return err
+		}
+		return remote.PushContext(ctx, &git.PushOptions{
+			RemoteName:   "anonymous",
+			RefSpecs:     refSpecs,
+			Auth:         auth,
+			ProxyOptions: proxy,
+			Progress:     gs.progress,
+		})
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		result.UpToDate = true
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to push to mirror: %w", err)
+	}
+	if local {
+		result.Size = pathSize(result.Target)
+	}
+	gs.logger.Info("updated mirror", "up_to_date", result.UpToDate)
+	return result, nil
+}
+
+// mirrorRefSpecs returns mirrorRefSpec and the deletion of every ref of the
+// mirror the repository does not have. go-git prunes wrongly with forced
+// refspecs, so the deletions are listed instead of using PushOptions.Prune.
+func mirrorRefSpecs(ctx context.Context, repo *git.Repository, remote *git.Remote, auth transport.AuthMethod, proxy transport.ProxyOptions) ([]config.RefSpec, error) {
+	refSpecs := []config.RefSpec{mirrorRefSpec}
+	advertised, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth, ProxyOptions: proxy})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return refSpecs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mirror refs: %w", err)
+	}
+	for _, ref := range advertised {
+		if ref.Type() != plumbing.HashReference || ref.Name() == plumbing.HEAD || ref.Name().IsNote() {
+			continue
+		}
+		if _, err := repo.Reference(ref.Name(), false); errors.Is(err, plumbing.ErrReferenceNotFound) {
+			refSpecs = append(refSpecs, config.RefSpec(":"+ref.Name().String()))
+		}
+	}
+	return refSpecs, nil
+}
+
+// mirrorSource returns the repository path recorded in the local mirror at
+// path, empty for mirrors made before it was recorded
+func mirrorSource(path string) (string, error) {
+	mirror, err := git.PlainOpen(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open mirror: %w", err)
+	}
+	cfg, err := mirror.Config()
+	if err != nil {
+		return "", fmt.Errorf("failed to read mirror config: %w", err)
+	}
+	return cfg.Raw.Section(mirrorConfigSection).Option(mirrorSourceOption), nil
+}
+
+// setMirrorSource records source as the repository of the local mirror at path
+func setMirrorSource(path string, source string) error {
+	mirror, err := git.PlainOpen(path)
+	if err != nil {
+		return fmt.Errorf("failed to open mirror: %w", err)
+	}
+	cfg, err := mirror.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read mirror config: %w", err)
+	}
+	cfg.Raw.Section(mirrorConfigSection).SetOption(mirrorSourceOption, source)
+	if err := mirror.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to record mirror source: %w", err)
+	}
+	return nil
+}
+
+// createMirror initializes a bare repository at path whose HEAD is the branch
+// checked out in repo, or its default branch on a detached HEAD
+func createMirror(repo *git.Repository, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	mirror, err := git.PlainInit(path, true)
+	if err != nil {
+		return fmt.Errorf("failed to create mirror: %w", err)
+	}
+	var branch plumbing.ReferenceName
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		branch = head.Name()
+	} else if name, err := localDefaultBranch(repo, DefaultRemote); err == nil {
+		branch = plumbing.NewBranchReferenceName(name)
+	}
+	if branch != "" {
+		if err := mirror.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+			return fmt.Errorf("failed to set mirror HEAD: %w", err)
+		}
+	}
+	return nil
+}
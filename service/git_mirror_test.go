@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestGitModelService_MirrorRepository(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, _, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+	backups := filepath.Join(t.TempDir(), "backups")
+
+	if got := MirrorTarget(repoPath, "https://git.example.com/backup/"); got != "https://git.example.com/backup/"+filepath.Base(repoPath)+".git" {
+		t.Errorf("MirrorTarget() = %s", got)
+	}
+
+	dry, err := gs.MirrorRepository(ctx, repoPath, backups, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("MirrorRepository() dry run error = %v", err)
+	}
+	if !dry.Created || dry.Target != filepath.Join(backups, filepath.Base(repoPath)+".git") {
+		t.Errorf("dry run = %+v", dry)
+	}
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, _ := repo.Head()
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("unpushed"), head.Hash())); err != nil {
+		t.Fatalf("failed to create branch: %v", err)
+	}
+
+	if err := CheckMirrorSpace([]string{repoPath}, backups); err != nil {
+		t.Errorf("CheckMirrorSpace() error = %v", err)
+	}
+	result, err := gs.MirrorRepository(ctx, repoPath, backups, Options{})
+	if err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+	if !result.Created || result.Size == 0 {
+		t.Errorf("result = %+v, want a new mirror", result)
+	}
+	restored := filepath.Join(t.TempDir(), "restored")
+	clone, err := git.PlainClone(restored, false, &git.CloneOptions{URL: result.Target})
+	if err != nil {
+		t.Fatalf("mirror cannot be cloned: %v", err)
+	}
+	if cloneHead, err := clone.Head(); err != nil || cloneHead.Hash() != head.Hash() {
+		t.Errorf("clone of the mirror HEAD = %v, %v", cloneHead, err)
+	}
+
+	if err := repo.Storer.RemoveReference(plumbing.NewBranchReferenceName("unpushed")); err != nil {
+		t.Fatalf("failed to delete branch: %v", err)
+	}
+	commitTestFile(t, repoPath, "next.txt", "next")
+	next, _ := repo.Head()
+	result, err = gs.MirrorRepository(ctx, repoPath, backups, Options{})
+	if err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+	if result.Created || result.UpToDate {
+		t.Errorf("result = %+v, want an updated mirror", result)
+	}
+	mirror, err := git.PlainOpen(result.Target)
+	if err != nil {
+		t.Fatalf("failed to open mirror: %v", err)
+	}
+	if ref, err := mirror.Reference(plumbing.NewBranchReferenceName("master"), true); err != nil || ref.Hash() != next.Hash() {
+		t.Errorf("mirror master = %v, %v, want %s", ref, err, next.Hash())
+	}
+	if _, err := mirror.Reference(plumbing.NewBranchReferenceName("unpushed"), true); err == nil {
+		t.Errorf("branch deleted from the repository still in the mirror")
+	}
+
+	again, err := gs.MirrorRepository(ctx, repoPath, backups, Options{})
+	if err != nil || !again.UpToDate {
+		t.Errorf("MirrorRepository() = %+v, %v, want up to date", again, err)
+	}
+	if _, err := repo.Reference(plumbing.NewRemoteReferenceName("anonymous", "master"), false); err == nil {
+		t.Errorf("mirroring recorded remote-tracking refs in the repository")
+	}
+
+	other, _, cleanupOther := setupTestRepoWithRemote(t)
+	defer cleanupOther()
+	namesake := filepath.Join(t.TempDir(), filepath.Base(repoPath))
+	if err := os.Rename(other, namesake); err != nil {
+		t.Fatalf("failed to move repo: %v", err)
+	}
+	if err := CheckMirrorTargets([]string{repoPath, namesake}, backups); err == nil {
+		t.Error("CheckMirrorTargets() accepted two repositories with the same name")
+	}
+	if _, err := gs.MirrorRepository(ctx, namesake, backups, Options{}); err == nil {
+		t.Error("MirrorRepository() updated the mirror of another repository")
+	}
+	if ref, err := mirror.Reference(plumbing.NewBranchReferenceName("master"), true); err != nil || ref.Hash() != next.Hash() {
+		t.Errorf("mirror master = %v, %v after the refused update, want %s", ref, err, next.Hash())
+	}
+}
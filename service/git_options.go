@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// DefaultRemote is the remote GitService operations work with when Options.Remote is empty
+const DefaultRemote = "origin"
+
+// Options holds the settings shared by every GitService operation. The zero
+// value works with origin, without timeout, resolves credentials from the
+// configured sources and discards the server progress output.
+type Options struct {
+	// Timeout bounds the whole operation on top of the caller context; zero
+	// means no limit
+	Timeout time.Duration
+	// Remote is the remote to fetch from, push to or rewrite, DefaultRemote when empty
+	Remote string
+	// Auth is used for every network call instead of the netrc and askpass lookup
+	Auth transport.AuthMethod
+	// Progress receives the progress messages sent by the git server
+	Progress io.Writer
+	// DryRun reports what the operation would change without changing it. It is
+	// honored by DeleteMergedBranches, PruneBranches, RestoreBranch,
+	// ResolveRemoteRedirect, ArchiveRepository, TarArchiveRepository,
+	// MirrorRepository, DropStash, CreateBranch, PushBranches, SyncFork,
+	// SyncTags and the remote rewrites; other operations ignore it.
+	DryRun bool
+	// Force skips the safety checks of the operation, such as the fetch
+	// verifying a rewritten remote
+	Force bool
+}
+
+// TokenAuth returns the HTTP basic auth GitHub and GitLab accept for an API
+// token on HTTPS remotes, or nil when token is empty or remoteURL is not HTTP
+func TokenAuth(remoteURL string, token string) transport.AuthMethod {
+	if token == "" || !isHTTPRemote(remoteURL) {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "x-access-token", Password: token}
+}
+
+// withOptions returns a copy of the service bound to the remote, credentials
+// and progress writer of opts, and ctx bounded by opts.Timeout. The returned
+// cancel func must be called when the operation is over.
+func (gs *GitModelService) withOptions(ctx context.Context, opts Options) (*GitModelService, context.Context, context.CancelFunc) {
+	scoped := *gs
+	scoped.remote = opts.Remote
+	scoped.auth = opts.Auth
+	scoped.progress = opts.Progress
+
+	if opts.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		return &scoped, ctx, cancel
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &scoped, ctx, cancel
+}
+
+// remoteName returns the remote the current operation works with
+func (gs *GitModelService) remoteName() string {
+	if gs.remote == "" {
+		return DefaultRemote
+	}
+	return gs.remote
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func TestGitModelService_WithOptions(t *testing.T) {
+	gs := &GitModelService{logger: &DefaultLogger{}}
+
+	scoped, ctx, cancel := gs.withOptions(context.Background(), Options{})
+	defer cancel()
+	if scoped.remoteName() != DefaultRemote {
+		t.Errorf("remoteName() = %q, want %q", scoped.remoteName(), DefaultRemote)
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("zero Options set a deadline")
+	}
+
+	auth := &githttp.BasicAuth{Username: "user", Password: "secret"}
+	scoped, ctx, cancel = gs.withOptions(context.Background(), Options{Remote: "upstream", Auth: auth, Timeout: time.Minute})
+	defer cancel()
+	if scoped.remoteName() != "upstream" || scoped.auth != auth {
+		t.Errorf("scoped service = remote %q auth %v, want upstream with the given auth", scoped.remoteName(), scoped.auth)
+	}
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("deadline = %v, %v, want within a minute", deadline, ok)
+	}
+	if gs.remote != "" || gs.auth != nil {
+		t.Error("withOptions modified the original service")
+	}
+}
+
+func TestGitModelService_OptionsTimeout(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+	gs := NewGitService(&DefaultLogger{})
+
+	err := gs.FetchLatest(context.Background(), repoPath, Options{Timeout: time.Nanosecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FetchLatest() error = %v, want the deadline exceeded", err)
+	}
+	if err := gs.FetchLatest(context.Background(), repoPath, Options{Timeout: time.Minute}); err != nil {
+		t.Errorf("FetchLatest() within the timeout error = %v", err)
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	if auth := TokenAuth("https://github.com/org/repo.git", "secret"); auth == nil {
+		t.Error("TokenAuth() = nil for an HTTPS remote")
+	}
+	if auth := TokenAuth("git@github.com:org/repo.git", "secret"); auth != nil {
+		t.Errorf("TokenAuth() = %v for an SSH remote, want nil", auth)
+	}
+	if auth := TokenAuth("https://github.com/org/repo.git", ""); auth != nil {
+		t.Errorf("TokenAuth() = %v without token, want nil", auth)
+	}
+}
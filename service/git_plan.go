@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// RemoteChange is the rewrite of the URLs of a remote done by UpdateRemote
+// (NewRemote), RewriteRemote (Rewrite) or ConvertRemote (Protocol); exactly
+// one field is set
+type RemoteChange struct {
+	NewRemote string
+	Rewrite   *RemoteRewrite
+	Protocol  string
+}
+
+// apply returns the URLs the change gives to a remote with urls
+func (c RemoteChange) apply(urls []string) ([]string, error) {
+	switch {
+	case c.Rewrite != nil:
+		rewritten := make([]string, len(urls))
+		changed := false
+		for i, url := range urls {
+			rewritten[i] = c.Rewrite.Apply(url)
+			changed = changed || rewritten[i] != url
+		}
+		if !changed {
+			return nil, ErrRemoteUnchanged
+		}
+		return rewritten, nil
+	case c.Protocol != "":
+		converted := make([]string, len(urls))
+		changed := false
+		for i, url := range urls {
+			newURL, err := ConvertRemoteURL(url, c.Protocol)
+			if err != nil {
+				if i == 0 {
+					return nil, err
+				}
+				newURL = url
+			}
+			converted[i] = newURL
+			changed = changed || newURL != url
+		}
+		if !changed {
+			return nil, ErrRemoteUnchanged
+		}
+		return converted, nil
+	default:
+		return append([]string{parseRemoteURL(c.NewRemote, urls[0])}, urls[1:]...), nil
+	}
+}
+
+// PlanRemoteChange returns the current URLs of the remote named remoteName
+// (DefaultRemote when empty) and the ones change would set, without changing
+// anything. It returns ErrRemoteUnchanged like the change itself would.
+func PlanRemoteChange(repoPath string, remoteName string, change RemoteChange) (from []string, to []string, err error) {
+	if remoteName == "" {
+		remoteName = DefaultRemote
+	}
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	remote, err := repo.Remote(remoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return nil, nil, fmt.Errorf("remote '%s' not found in config: %w", remoteName, ErrNoOriginRemote)
+	}
+	from = remote.Config().URLs
+	to, err = change.apply(append([]string(nil), from...))
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// BranchUpdatePlan lists the branches UpdateAllBranchesProject would move, as
+// of the last fetch
+type BranchUpdatePlan struct {
+	// Current is the checked-out branch, never touched
+	Current string
+	// Reset are the branches differing from their remote counterpart
+	Reset []string
+	// Protected are the branches of Reset that are only fast-forwarded
+	Protected []string
+}
+
+// PlanBranchUpdate compares the local branches selected by opts with their
+// remote-tracking refs without fetching, so branches the next fetch brings
+// changes to are not listed
+func PlanBranchUpdate(repoPath string, opts UpdateOptions) (*BranchUpdatePlan, error) {
+	remoteName := opts.Remote
+	if remoteName == "" {
+		remoteName = DefaultRemote
+	}
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	plan := &BranchUpdatePlan{}
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		plan.Current = head.Name().Short()
+	}
+	protected := opts.Protected
+	if defaultBranch, err := localDefaultBranch(repo, remoteName); err == nil {
+		protected = append(slices.Clip(protected), defaultBranch)
+	}
+
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		branchName := ref.Name().Short()
+		if branchName == plan.Current || !opts.selectsBranch(branchName) {
+			return nil
+		}
+		remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, branchName), true)
+		if err != nil || remoteRef.Hash() == ref.Hash() {
+			return nil
+		}
+		plan.Reset = append(plan.Reset, branchName)
+		if matchesAny(branchName, protected) {
+			plan.Protected = append(plan.Protected, branchName)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	return plan, nil
+}
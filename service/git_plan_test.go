@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestPlanRemoteChange(t *testing.T) {
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	rewrite, err := ParseRemoteRewrite("s#goktor#moved#")
+	if err != nil {
+		t.Fatalf("ParseRemoteRewrite() error = %v", err)
+	}
+	from, to, err := PlanRemoteChange(repoPath, "", RemoteChange{Rewrite: rewrite})
+	if err != nil {
+		t.Fatalf("PlanRemoteChange() error = %v", err)
+	}
+	if !slices.Equal(from, []string{bareDir}) || len(to) != 1 || to[0] == bareDir {
+		t.Errorf("PlanRemoteChange() = %v -> %v", from, to)
+	}
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	if url := remoteURL(repo, DefaultRemote); url != bareDir {
+		t.Errorf("origin = %s, the plan should not change it", url)
+	}
+
+	unchanged, err := ParseRemoteRewrite("s#no-match#x#")
+	if err != nil {
+		t.Fatalf("ParseRemoteRewrite() error = %v", err)
+	}
+	if _, _, err := PlanRemoteChange(repoPath, "", RemoteChange{Rewrite: unchanged}); !errors.Is(err, ErrRemoteUnchanged) {
+		t.Errorf("PlanRemoteChange() error = %v, want ErrRemoteUnchanged", err)
+	}
+}
+
+func TestPlanBranchUpdate(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithBranches(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, _ := repo.Head()
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("develop"), head.Hash())); err != nil {
+		t.Fatalf("failed to move develop: %v", err)
+	}
+
+	plan, err := PlanBranchUpdate(repoPath, UpdateOptions{Protected: []string{"dev*"}})
+	if err != nil {
+		t.Fatalf("PlanBranchUpdate() error = %v", err)
+	}
+	if plan.Current != head.Name().Short() || !slices.Contains(plan.Reset, "develop") || !slices.Equal(plan.Protected, []string{"develop"}) {
+		t.Errorf("plan = %+v, want develop reset and protected", plan)
+	}
+
+	plan, err = PlanBranchUpdate(repoPath, UpdateOptions{ExcludeBranches: []string{"develop"}})
+	if err != nil {
+		t.Fatalf("PlanBranchUpdate() error = %v", err)
+	}
+	if slices.Contains(plan.Reset, "develop") {
+		t.Errorf("plan = %+v, want the excluded develop left out", plan)
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// DefaultProtectedBranches are never pruned unless the caller overrides the list
+var DefaultProtectedBranches = []string{"main", "master", "develop"}
+
+// BranchesConfig is the "branches" section of the configuration
+type BranchesConfig struct {
+	// Protected are glob patterns, such as "release/*", of the branches never
+	// deleted by prune-branches nor hard-reset by update-branches
+	Protected []string `json:"protected,omitempty"`
+}
+
+// ValidateBranchPatterns reports the first malformed glob pattern
+func ValidateBranchPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid branch pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+const (
+	PruneReasonUpstreamGone = "upstream gone"
+	PruneReasonMerged       = "merged"
+)
+
+// PruneBranchesResult contains the outcome of pruning the local branches of a repository
+type PruneBranchesResult struct {
+	Deleted   []string `json:"deleted"`
+	DryRun    []string `json:"dry_run"`
+	Protected []string `json:"protected"`
+	Kept      []string `json:"kept"`
+	Failed    []string `json:"failed"`
+	// Reasons maps each deleted or dry-run branch to why it was selected
+	Reasons map[string]string `json:"reasons,omitempty"`
+}
+
+// PruneBranches deletes local branches whose upstream is gone or that are fully
+// merged into the default branch. Branches matching a protected glob, the
+// current branch and the default branch are never deleted. With opts.DryRun
+// the selected branches are only reported.
+func (gs *GitModelService) PruneBranches(ctx context.Context, repoPath string, protected []string, opts Options) (*PruneBranchesResult, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	dryRun := opts.DryRun
+	result := &PruneBranchesResult{
+		Deleted:   []string{},
+		DryRun:    []string{},
+		Protected: []string{},
+		Kept:      []string{},
+		Failed:    []string{},
+		Reasons:   map[string]string{},
+	}
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gs.fetchPrune(ctx, repo); err != nil {
+		return nil, err
+	}
+
+	currentBranch, err := gs.getCurrentBranch(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultBranch, err := gs.defaultBranch(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	defaultHash, err := branchTip(repo, gs.remoteName(), defaultBranch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve default branch: %w", err)
+	}
+	defaultCommit, err := repo.CommitObject(defaultHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default branch commit: %w", err)
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	defer branches.Close()
+
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		branchName := ref.Name().Short()
+		log := gs.logger.With("branch", branchName)
+
+		if branchName == currentBranch || branchName == defaultBranch || matchesAny(branchName, protected) {
+			result.Protected = append(result.Protected, branchName)
+			return nil
+		}
+
+		reason := ""
+		if branchCfg, ok := cfg.Branches[branchName]; ok && branchCfg.Remote != "" && branchCfg.Merge != "" {
+			upstream := plumbing.NewRemoteReferenceName(branchCfg.Remote, branchCfg.Merge.Short())
+			if _, err := repo.Reference(upstream, true); errors.Is(err, plumbing.ErrReferenceNotFound) {
+				reason = PruneReasonUpstreamGone
+			}
+		}
+		if reason == "" {
+			commit, err := repo.CommitObject(ref.Hash())
+			if err != nil {
+				log.Error("failed to load branch commit", "error", err)
+				result.Failed = append(result.Failed, branchName)
+				return nil
+			}
+			merged, err := commit.IsAncestor(defaultCommit)
+			if err != nil {
+				log.Error("failed to check ancestry", "error", err)
+				result.Failed = append(result.Failed, branchName)
+				return nil
+			}
+			if merged {
+				reason = PruneReasonMerged
+			}
+		}
+
+		if reason == "" {
+			result.Kept = append(result.Kept, branchName)
+			return nil
+		}
+
+		result.Reasons[branchName] = reason
+		if dryRun {
+			log.Info("dry-run: would delete local branch", "reason", reason)
+			result.DryRun = append(result.DryRun, branchName)
+			return nil
+		}
+
+		if err := deleteLocalBranch(repo, ref.Name()); err != nil {
+			log.Error("failed to delete local branch", "error", err)
+			result.Failed = append(result.Failed, branchName)
+			return nil
+		}
+		log.Info("deleted local branch", "reason", reason)
+		result.Deleted = append(result.Deleted, branchName)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed processing branches: %w", err)
+	}
+
+	return result, nil
+}
+
+// fetchPrune fetches the remote and drops remote-tracking refs removed upstream
+func (gs *GitModelService) fetchPrune(ctx context.Context, repo *git.Repository) error {
+	gs.emit(ProgressEvent{Kind: EventFetchStarted, Remote: gs.remoteName()})
+	err := gs.withAuth(ctx, remoteURL(repo, gs.remoteName()), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		return repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName:   gs.remoteName(),
+			Force:        true,
+			Prune:        true,
+			Auth:         auth,
+			ProxyOptions: proxy,
+			Progress:     gs.progress,
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		err = fmt.Errorf("fetch failed: %w", err)
+		gs.emit(ProgressEvent{Kind: EventFetchDone, Remote: gs.remoteName(), Err: err})
+		return err
+	}
+	gs.emit(ProgressEvent{Kind: EventFetchDone, Remote: gs.remoteName()})
+	return nil
+}
+
+func deleteLocalBranch(repo *git.Repository, refName plumbing.ReferenceName) error {
+	if err := repo.Storer.RemoveReference(refName); err != nil {
+		return err
+	}
+	if err := repo.DeleteBranch(refName.Short()); err != nil && !errors.Is(err, git.ErrBranchNotFound) {
+		return err
+	}
+	return nil
+}
+
+// matchesAny reports whether name matches any of the glob patterns
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
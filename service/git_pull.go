@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Outcomes of PullCurrentBranch
+const (
+	PullStatusUpToDate      = "up to date"
+	PullStatusFastForwarded = "fast-forwarded"
+	PullStatusRebased       = "rebased"
+	PullStatusAhead         = "ahead"
+	PullStatusDiverged      = "diverged"
+	PullStatusConflict      = "rebase conflict"
+	PullStatusSkipped       = "skipped"
+)
+
+const (
+	SkipReasonDetachedHead = "detached HEAD"
+	SkipReasonNoUpstream   = "no upstream"
+)
+
+// PullOptions configures PullCurrentBranch
+type PullOptions struct {
+	Options
+	// Rebase replays local commits on top of the upstream when the branches
+	// diverged, instead of reporting the repository as diverged
+	Rebase bool
+}
+
+// PullResult is the outcome of pulling the checked-out branch of a repository
+type PullResult struct {
+	Branch string `json:"branch"`
+	Status string `json:"status"`
+	// SkipReason is set when Status is PullStatusSkipped
+	SkipReason string `json:"skip_reason,omitempty"`
+	// From and To are the branch hashes before and after the pull
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// PullCurrentBranch fetches origin and fast-forwards the checked-out branch to
+// its upstream with the git executable, keeping untracked files. Diverged
+// branches are rebased when opts.Rebase is set, and a conflicting rebase is
+// aborted so the repository is left as it was.
+// Repositories with uncommitted changes or a detached HEAD are skipped.
+func (gs *GitModelService) PullCurrentBranch(ctx context.Context, repoPath string, opts PullOptions) (_ *PullResult, err error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts.Options)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	defer func() { gs.emit(ProgressEvent{Kind: EventRepoDone, Err: err}) }()
+	result := &PullResult{}
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	result.From = head.Hash().String()
+	result.To = result.From
+	if !head.Name().IsBranch() {
+		return skipPull(result, SkipReasonDetachedHead), nil
+	}
+	result.Branch = head.Name().Short()
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	dirty, err := hasUncommittedChanges(worktree)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return skipPull(result, SkipReasonDirtyWorktree), nil
+	}
+
+	if err := gs.fetch(ctx, repo); err != nil {
+		return nil, err
+	}
+
+	upstream, err := upstreamReference(repo, gs.remoteName(), result.Branch)
+	if err != nil {
+		return nil, err
+	}
+	if upstream == nil {
+		return skipPull(result, SkipReasonNoUpstream), nil
+	}
+	if upstream.Hash() == head.Hash() {
+		result.Status = PullStatusUpToDate
+		return result, nil
+	}
+
+	localCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load local commit: %w", err)
+	}
+	remoteCommit, err := repo.CommitObject(upstream.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load remote commit: %w", err)
+	}
+
+	if ahead, err := remoteCommit.IsAncestor(localCommit); err != nil {
+		return nil, fmt.Errorf("failed to check ancestry: %w", err)
+	} else if ahead {
+		result.Status = PullStatusAhead
+		return result, nil
+	}
+
+	fastForward, err := localCommit.IsAncestor(remoteCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ancestry: %w", err)
+	}
+	if fastForward {
+		if err := mergeFastForward(ctx, repoPath, upstream.Hash()); err != nil {
+			return nil, fmt.Errorf("failed to fast-forward %s: %w", result.Branch, err)
+		}
+		gs.logger.Info("branch fast-forwarded", "branch", result.Branch)
+		gs.emit(ProgressEvent{Kind: EventBranchUpdated, Branch: result.Branch})
+		result.Status = PullStatusFastForwarded
+		result.To = upstream.Hash().String()
+		return result, nil
+	}
+
+	if !opts.Rebase {
+		gs.logger.Warn("branch diverged from upstream, rerun with --rebase", "branch", result.Branch)
+		result.Status = PullStatusDiverged
+		return result, nil
+	}
+
+	if _, err := gs.runGitAs(ctx, repoPath, "rebase", upstream.Name().Short()); err != nil {
+		if errors.Is(err, ErrNoIdentity) {
+			return nil, err
+		}
+		gs.logger.Warn("rebase failed, aborting", "branch", result.Branch, "error", err)
+		if _, abortErr := runGit(context.WithoutCancel(ctx), repoPath, "rebase", "--abort"); abortErr != nil {
+			return nil, fmt.Errorf("failed to abort rebase: %w", abortErr)
+		}
+		result.Status = PullStatusConflict
+		return result, nil
+	}
+
+	rebased, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	gs.logger.Info("branch rebased", "branch", result.Branch)
+	gs.emit(ProgressEvent{Kind: EventBranchUpdated, Branch: result.Branch})
+	result.Status = PullStatusRebased
+	result.To = rebased.Hash().String()
+	return result, nil
+}
+
+// mergeFastForward moves the checked-out branch of repoPath to hash with git
+// merge --ff-only. Unlike a go-git hard reset, it keeps untracked files and
+// fails rather than overwrite one.
+func mergeFastForward(ctx context.Context, repoPath string, hash plumbing.Hash) error {
+	_, err := runGit(ctx, repoPath, "merge", "--ff-only", "--quiet", hash.String())
+	return err
+}
+
+func skipPull(result *PullResult, reason string) *PullResult {
+	result.Status = PullStatusSkipped
+	result.SkipReason = reason
+	return result
+}
+
+// upstreamReference returns the remote-tracking ref configured for branch,
+// falling back to <remoteName>/<branch>. It returns nil when neither exists.
+func upstreamReference(repo *git.Repository, remoteName string, branch string) (*plumbing.Reference, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	name := plumbing.NewRemoteReferenceName(remoteName, branch)
+	if branchCfg, ok := cfg.Branches[branch]; ok && branchCfg.Remote != "" && branchCfg.Merge != "" {
+		name = plumbing.NewRemoteReferenceName(branchCfg.Remote, branchCfg.Merge.Short())
+	}
+
+	ref, err := repo.Reference(name, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve upstream %s: %w", name.Short(), err)
+	}
+	return ref, nil
+}
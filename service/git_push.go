@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Outcomes of a branch pushed by PushBranches
+const (
+	PushStatusUpToDate = "up to date"
+	PushStatusPushed   = "pushed"
+	PushStatusCreated  = "created"
+	PushStatusForced   = "forced"
+	PushStatusBehind   = "behind"
+	PushStatusRejected = "rejected"
+	PushStatusFailed   = "failed"
+)
+
+// PushOptions configures PushBranches
+type PushOptions struct {
+	Options
+	// AllBranches pushes every local branch instead of the checked-out one
+	AllBranches bool
+	// ForceWithLease force-pushes diverged branches, as long as the remote
+	// branch is still where the last fetch saw it
+	ForceWithLease bool
+}
+
+// PushResult is the outcome of pushing the branches of a repository
+type PushResult struct {
+	// Branches maps every branch considered to its PushStatus
+	Branches map[string]string `json:"branches"`
+	// Errors holds the error of every failed or rejected branch
+	Errors map[string]string `json:"errors,omitempty"`
+	// SkipReason is set when no branch was considered, such as on a detached HEAD
+	SkipReason string `json:"skip_reason,omitempty"`
+}
+
+// Count returns how many branches ended with status
+func (r *PushResult) Count(status string) int {
+	n := 0
+	for _, branchStatus := range r.Branches {
+		if branchStatus == status {
+			n++
+		}
+	}
+	return n
+}
+
+// PushBranches pushes the checked-out branch of repoPath, or every local branch
+// with opts.AllBranches, to the branch of the same name on the remote. A
+// branch is compared with its remote-tracking branch first: branches behind
+// the remote are left alone, and diverged ones are rejected unless
+// opts.ForceWithLease is set. With opts.DryRun nothing is pushed and every
+// branch gets the status the push would give it.
+func (gs *GitModelService) PushBranches(ctx context.Context, repoPath string, opts PushOptions) (*PushResult, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts.Options)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	remoteName := gs.remoteName()
+	result := &PushResult{Branches: map[string]string{}, Errors: map[string]string{}}
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := getRemote(repo, remoteName); err != nil {
+		return nil, err
+	}
+
+	var refs []*plumbing.Reference
+	if opts.AllBranches {
+		branches, err := repo.Branches()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list branches: %w", err)
+		}
+		_ = branches.ForEach(func(ref *plumbing.Reference) error {
+			refs = append(refs, ref)
+			return nil
+		})
+		branches.Close()
+		sort.Slice(refs, func(i, j int) bool { return refs[i].Name() < refs[j].Name() })
+	} else {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		if !head.Name().IsBranch() {
+			result.SkipReason = SkipReasonDetachedHead
+			return result, nil
+		}
+		refs = append(refs, head)
+	}
+
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		branchName := ref.Name().Short()
+		log := gs.logger.With("branch", branchName)
+		status, err := planPush(repo, remoteName, ref, opts.ForceWithLease)
+		if err != nil {
+			log.Error("failed to compare with the remote branch", "error", err)
+			result.Branches[branchName], result.Errors[branchName] = PushStatusFailed, err.Error()
+			continue
+		}
+		result.Branches[branchName] = status
+		if status == PushStatusRejected {
+			result.Errors[branchName] = "diverged from the remote branch, use force-with-lease"
+		}
+		if opts.DryRun || status == PushStatusUpToDate || status == PushStatusBehind || status == PushStatusRejected {
+			continue
+		}
+
+		if err := gs.pushBranch(ctx, repo, remoteName, ref, status == PushStatusForced); err != nil {
+			log.Error("failed to push branch", "error", err)
+			result.Branches[branchName], result.Errors[branchName] = PushStatusFailed, err.Error()
+			continue
+		}
+		log.Info("pushed branch", "status", status)
+	}
+	return result, nil
+}
+
+// planPush compares ref with its remote-tracking branch and returns the status
+// pushing it gives
+func planPush(repo *git.Repository, remoteName string, ref *plumbing.Reference, forceWithLease bool) (string, error) {
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, ref.Name().Short()), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return PushStatusCreated, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if remoteRef.Hash() == ref.Hash() {
+		return PushStatusUpToDate, nil
+	}
+
+	local, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return "", fmt.Errorf("failed to load local commit: %w", err)
+	}
+	remote, err := repo.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return "", fmt.Errorf("failed to load remote commit: %w", err)
+	}
+	if ahead, err := remote.IsAncestor(local); err != nil {
+		return "", fmt.Errorf("failed to check ancestry: %w", err)
+	} else if ahead {
+		return PushStatusPushed, nil
+	}
+	if behind, err := local.IsAncestor(remote); err != nil {
+		return "", fmt.Errorf("failed to check ancestry: %w", err)
+	} else if behind {
+		return PushStatusBehind, nil
+	}
+	if forceWithLease {
+		return PushStatusForced, nil
+	}
+	return PushStatusRejected, nil
+}
+
+// pushBranch pushes ref to the branch of the same name. A forced push only
+// goes through when the remote branch is still at its remote-tracking ref.
+func (gs *GitModelService) pushBranch(ctx context.Context, repo *git.Repository, remoteName string, ref *plumbing.Reference, force bool) error {
+	refSpec := config.RefSpec(ref.Name().String() + ":" + ref.Name().String())
+	var lease *git.ForceWithLease
+	if force {
+		refSpec = "+" + refSpec
+		lease = &git.ForceWithLease{RefName: ref.Name()}
+	}
+	err := gs.withAuth(ctx, remoteURL(repo, remoteName), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		return repo.PushContext(ctx, &git.PushOptions{
+			RemoteName:     remoteName,
+			RefSpecs:       []config.RefSpec{refSpec},
+			ForceWithLease: lease,
+			Auth:           auth,
+			ProxyOptions:   proxy,
+			Progress:       gs.progress,
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("push failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestGitModelService_PushBranches(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	bare, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatalf("failed to open bare repo: %v", err)
+	}
+	remoteMaster := func() plumbing.Hash {
+		t.Helper()
+		ref, err := bare.Reference(plumbing.NewBranchReferenceName("master"), true)
+		if err != nil {
+			t.Fatalf("failed to read the remote master: %v", err)
+		}
+		return ref.Hash()
+	}
+	push := func(opts PushOptions) *PushResult {
+		t.Helper()
+		result, err := gs.PushBranches(ctx, repoPath, opts)
+		if err != nil {
+			t.Fatalf("PushBranches() error = %v", err)
+		}
+		return result
+	}
+
+	if result := push(PushOptions{}); result.Branches["master"] != PushStatusUpToDate {
+		t.Errorf("Branches = %v, want master up to date", result.Branches)
+	}
+
+	commitTestFile(t, repoPath, "local.txt", "local content")
+	head, _ := repo.Head()
+	before := remoteMaster()
+	if result := push(PushOptions{Options: Options{DryRun: true}}); result.Branches["master"] != PushStatusPushed || remoteMaster() != before {
+		t.Errorf("dry run Branches = %v, want master to push without pushing it", result.Branches)
+	}
+	if result := push(PushOptions{}); result.Branches["master"] != PushStatusPushed || remoteMaster() != head.Hash() {
+		t.Errorf("Branches = %v, want master pushed", result.Branches)
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("topic"), head.Hash())); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	if result := push(PushOptions{}); len(result.Branches) != 1 {
+		t.Errorf("Branches = %v, want only the checked-out branch", result.Branches)
+	}
+	result := push(PushOptions{AllBranches: true})
+	if result.Branches["topic"] != PushStatusCreated || result.Branches["master"] != PushStatusUpToDate {
+		t.Errorf("Branches = %v, want topic created and master up to date", result.Branches)
+	}
+
+	pushRemoteCommit(t, bareDir, "remote.txt")
+	if err := gs.FetchLatest(ctx, repoPath, Options{}); err != nil {
+		t.Fatalf("FetchLatest() error = %v", err)
+	}
+	if result := push(PushOptions{}); result.Branches["master"] != PushStatusBehind {
+		t.Errorf("Branches = %v, want master behind", result.Branches)
+	}
+
+	commitTestFile(t, repoPath, "diverged.txt", "diverged content")
+	head, _ = repo.Head()
+	result = push(PushOptions{})
+	if result.Branches["master"] != PushStatusRejected || result.Errors["master"] == "" || remoteMaster() == head.Hash() {
+		t.Errorf("result = %+v, want master rejected", result)
+	}
+	if result := push(PushOptions{ForceWithLease: true}); result.Branches["master"] != PushStatusForced || remoteMaster() != head.Hash() {
+		t.Errorf("Branches = %v, Errors = %v, want master force-pushed", result.Branches, result.Errors)
+	}
+}
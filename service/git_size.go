@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// BlobSize is a file version stored in the history of a repository
+type BlobSize struct {
+	Hash string `json:"hash"`
+	// Path is a path the blob was committed at, empty when no ref reaches it
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// RepoSize breaks down the disk usage of a repository
+type RepoSize struct {
+	// WorktreeSize is the size of the checked-out files, .git excluded
+	WorktreeSize int64 `json:"worktree_size"`
+	GitSize      int64 `json:"git_size"`
+	Packfiles    int   `json:"packfiles"`
+	LooseObjects int   `json:"loose_objects"`
+	// LargestBlobs are the largest file versions of the history, largest first
+	LargestBlobs []BlobSize `json:"largest_blobs,omitempty"`
+}
+
+// RepoSize measures the worktree and .git directory of the repository at
+// repoPath and finds its top largest blobs, with a path each was committed at
+func (gs *GitModelService) RepoSize(ctx context.Context, repoPath string, top int, opts Options) (*RepoSize, error) {
+	_, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	gitDir := filepath.Join(repoPath, ".git")
+	size := &RepoSize{GitSize: pathSize(gitDir), WorktreeSize: worktreeSize(repoPath, gitDir)}
+
+	packs, err := filepath.Glob(filepath.Join(gitDir, "objects", "pack", "*.pack"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packfiles: %w", err)
+	}
+	size.Packfiles = len(packs)
+	if entries, err := os.ReadDir(filepath.Join(gitDir, "objects")); err == nil {
+		for _, entry := range entries {
+			if len(entry.Name()) != 2 || !entry.IsDir() {
+				continue
+			}
+			if objects, err := os.ReadDir(filepath.Join(gitDir, "objects", entry.Name())); err == nil {
+				size.LooseObjects += len(objects)
+			}
+		}
+	}
+
+	if top <= 0 {
+		return size, nil
+	}
+	if size.LargestBlobs, err = largestBlobs(ctx, repo, top); err != nil {
+		return nil, err
+	}
+	return size, nil
+}
+
+// worktreeSize is the size of the files under repoPath outside gitDir
+func worktreeSize(repoPath string, gitDir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(repoPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p == gitDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// largestBlobs returns the top largest blobs of the object store, then walks
+// the trees of every commit reachable from the refs to name them
+func largestBlobs(ctx context.Context, repo *git.Repository, top int) ([]BlobSize, error) {
+	blobs, err := repo.BlobObjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	var largest []BlobSize
+	err = blobs.ForEach(func(blob *object.Blob) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(largest) == top && blob.Size <= largest[top-1].Size {
+			return nil
+		}
+		largest = append(largest, BlobSize{Hash: blob.Hash.String(), Size: blob.Size})
+		sort.SliceStable(largest, func(i, j int) bool { return largest[i].Size > largest[j].Size })
+		if len(largest) > top {
+			largest = largest[:top]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blobs: %w", err)
+	}
+
+	wanted := map[plumbing.Hash]int{}
+	for i, blob := range largest {
+		wanted[plumbing.NewHash(blob.Hash)] = i
+	}
+	commits, err := repo.Log(&git.LogOptions{All: true})
+	if err != nil {
+		return largest, nil // no commit yet
+	}
+	seenTrees := map[plumbing.Hash]bool{}
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(wanted) == 0 {
+			return storer.ErrStop
+		}
+		return nameBlobs(repo, commit.TreeHash, "", seenTrees, wanted, largest)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk history: %w", err)
+	}
+	return largest, nil
+}
+
+// nameBlobs walks the tree at hash, setting the path of the wanted blobs it
+// holds and removing them from wanted. Trees already seen are skipped.
+func nameBlobs(repo *git.Repository, hash plumbing.Hash, dir string, seenTrees map[plumbing.Hash]bool, wanted map[plumbing.Hash]int, blobs []BlobSize) error {
+	if seenTrees[hash] || len(wanted) == 0 {
+		return nil
+	}
+	seenTrees[hash] = true
+	tree, err := repo.TreeObject(hash)
+	if err != nil {
+		return err
+	}
+	for _, entry := range tree.Entries {
+		entryPath := path.Join(dir, entry.Name)
+		switch entry.Mode {
+		case filemode.Dir:
+			if err := nameBlobs(repo, entry.Hash, entryPath, seenTrees, wanted, blobs); err != nil {
+				return err
+			}
+		case filemode.Submodule:
+			// a commit of another repository, not stored here
+		default:
+			if i, ok := wanted[entry.Hash]; ok {
+				blobs[i].Path = entryPath
+				delete(wanted, entry.Hash)
+			}
+		}
+	}
+	return nil
+}
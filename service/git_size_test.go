@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestGitModelService_RepoSize(t *testing.T) {
+	repoPath, _, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	// a binary committed then deleted still weighs on the history
+	if err := os.MkdirAll(filepath.Join(repoPath, "assets"), 0755); err != nil {
+		t.Fatalf("failed to create assets: %v", err)
+	}
+	commitTestFile(t, repoPath, filepath.Join("assets", "big.bin"), string(make([]byte, 10240)))
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	worktree, _ := repo.Worktree()
+	if _, err := worktree.Remove("assets/big.bin"); err != nil {
+		t.Fatalf("failed to remove big.bin: %v", err)
+	}
+	if _, err := worktree.Commit("remove big.bin", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	gs := NewGitService(&DefaultLogger{})
+	size, err := gs.RepoSize(context.Background(), repoPath, 2, Options{})
+	if err != nil {
+		t.Fatalf("RepoSize() error = %v", err)
+	}
+	if size.WorktreeSize != int64(len("test content")) {
+		t.Errorf("WorktreeSize = %d, want only test.txt", size.WorktreeSize)
+	}
+	if size.GitSize == 0 || size.Packfiles != 0 || size.LooseObjects == 0 {
+		t.Errorf("size = %+v", size)
+	}
+	if len(size.LargestBlobs) != 2 {
+		t.Fatalf("LargestBlobs = %+v, want 2", size.LargestBlobs)
+	}
+	if big := size.LargestBlobs[0]; big.Path != "assets/big.bin" || big.Size != 10240 {
+		t.Errorf("largest blob = %+v, want assets/big.bin", big)
+	}
+	if size.LargestBlobs[1].Path != "test.txt" {
+		t.Errorf("second blob = %+v, want test.txt", size.LargestBlobs[1])
+	}
+
+	size, err = gs.RepoSize(context.Background(), repoPath, 0, Options{})
+	if err != nil || size.LargestBlobs != nil {
+		t.Errorf("RepoSize() without blobs = %+v, %v", size, err)
+	}
+}
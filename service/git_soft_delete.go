@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// deletedRefPrefix holds, on the remote, an annotated tag per remote branch
+// soft-deleted by goktor. The tag points to the branch tip and records when
+// the backup expires.
+const deletedRefPrefix = "refs/goktor/deleted/"
+
+// restoreRefPrefix holds the temporary local refs pushed by RestoreBranch
+const restoreRefPrefix = "refs/goktor/restore/"
+
+// expiresTrailer is the line of the backup tag message holding its expiry
+const expiresTrailer = "expires: "
+
+// goktorIdentity signs the backups when no git identity is configured
+var goktorIdentity = Identity{Name: "goktor", Email: "goktor@localhost"}
+
+// softDeletePolicy keeps a restorable backup of each deleted remote branch
+type softDeletePolicy struct {
+	keep   time.Duration
+	tagger Identity
+}
+
+// ErrBranchNotDeleted is returned by RestoreBranch when no backup exists for the branch
+var ErrBranchNotDeleted = errors.New("no soft-deleted branch with this name")
+
+// DeletedBranch is a remote branch soft-deleted by DeleteMergedBranches,
+// restorable with RestoreBranch until ExpiresAt
+type DeletedBranch struct {
+	Branch    string    `json:"branch"`
+	Hash      string    `json:"hash"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// ExpiresAt is zero when the backup never expires
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the restore window is over at now
+func (d DeletedBranch) Expired(now time.Time) bool {
+	return !d.ExpiresAt.IsZero() && now.After(d.ExpiresAt)
+}
+
+// softDeleteRemoteBranch deletes a remote branch after pushing a backup of its
+// tip under refs/goktor/deleted/, in the same push
+func (gs *GitModelService) softDeleteRemoteBranch(ctx context.Context, repo *git.Repository, remoteName string, branchName string, policy *softDeletePolicy) error {
+	tip, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, branchName), true)
+	if err != nil {
+		return fmt.Errorf("failed to resolve remote branch %s: %w", branchName, err)
+	}
+
+	now := time.Now()
+	tag := &object.Tag{
+		Name:       branchName,
+		Tagger:     *policy.tagger.Signature(now),
+		Message:    fmt.Sprintf("branch %s deleted by goktor\n\n%s%s\n", branchName, expiresTrailer, now.Add(policy.keep).Format(time.RFC3339)),
+		TargetType: plumbing.CommitObject,
+		Target:     tip.Hash(),
+	}
+	obj := repo.Storer.NewEncodedObject()
+	if err := tag.Encode(obj); err != nil {
+		return fmt.Errorf("failed to encode backup of %s: %w", branchName, err)
+	}
+	tagHash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to store backup of %s: %w", branchName, err)
+	}
+	backupRef := plumbing.ReferenceName(deletedRefPrefix + branchName)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(backupRef, tagHash)); err != nil {
+		return fmt.Errorf("failed to create backup ref of %s: %w", branchName, err)
+	}
+
+	err = gs.pushRefSpecs(ctx, repo, remoteName,
+		config.RefSpec("+"+backupRef+":"+backupRef),
+		config.RefSpec(":"+plumbing.NewBranchReferenceName(branchName)),
+	)
+	if err != nil {
+		_ = repo.Storer.RemoveReference(backupRef)
+		return fmt.Errorf("failed to delete remote branch %s: %w", branchName, err)
+	}
+	return nil
+}
+
+// ListDeletedBranches returns the branches soft-deleted on opts.Remote, oldest first
+func (gs *GitModelService) ListDeletedBranches(ctx context.Context, repoPath string, opts Options) ([]DeletedBranch, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := gs.fetchDeletedRefs(ctx, repo, gs.remoteName()); err != nil {
+		return nil, err
+	}
+	return deletedBranches(repo)
+}
+
+// RestoreBranch recreates a soft-deleted branch on opts.Remote at its backed up
+// tip and drops the backup. It fails when the branch exists again on the remote.
+// A dry run only checks that the backup exists.
+func (gs *GitModelService) RestoreBranch(ctx context.Context, repoPath string, branchName string, opts Options) error {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath, "branch", branchName)
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return err
+	}
+	if err := gs.fetchDeletedRefs(ctx, repo, gs.remoteName()); err != nil {
+		return err
+	}
+
+	deleted, err := deletedBranches(repo)
+	if err != nil {
+		return err
+	}
+	var backup *DeletedBranch
+	for i := range deleted {
+		if deleted[i].Branch == branchName {
+			backup = &deleted[i]
+		}
+	}
+	if backup == nil {
+		return fmt.Errorf("%w: %s", ErrBranchNotDeleted, branchName)
+	}
+	if opts.DryRun {
+		gs.logger.Info("dry-run: would restore remote branch", "hash", backup.Hash)
+		return nil
+	}
+
+	restoreRef := plumbing.ReferenceName(restoreRefPrefix + branchName)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(restoreRef, plumbing.NewHash(backup.Hash))); err != nil {
+		return fmt.Errorf("failed to create restore ref: %w", err)
+	}
+	defer repo.Storer.RemoveReference(restoreRef)
+
+	backupRef := plumbing.ReferenceName(deletedRefPrefix + branchName)
+	err = gs.pushRefSpecs(ctx, repo, gs.remoteName(),
+		config.RefSpec(restoreRef+":"+plumbing.NewBranchReferenceName(branchName)),
+		config.RefSpec(":"+backupRef),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore branch %s: %w", branchName, err)
+	}
+	_ = repo.Storer.RemoveReference(backupRef)
+
+	gs.logger.Info("restored remote branch", "hash", backup.Hash)
+	return nil
+}
+
+// purgeExpiredBranches drops the backups whose restore window is over
+func (gs *GitModelService) purgeExpiredBranches(ctx context.Context, repo *git.Repository, remoteName string) ([]string, error) {
+	if err := gs.fetchDeletedRefs(ctx, repo, remoteName); err != nil {
+		return nil, err
+	}
+	deleted, err := deletedBranches(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var purged []string
+	var refSpecs []config.RefSpec
+	for _, branch := range deleted {
+		if branch.Expired(now) {
+			purged = append(purged, branch.Branch)
+			refSpecs = append(refSpecs, config.RefSpec(":"+deletedRefPrefix+branch.Branch))
+		}
+	}
+	if len(refSpecs) == 0 {
+		return nil, nil
+	}
+	if err := gs.pushRefSpecs(ctx, repo, remoteName, refSpecs...); err != nil {
+		return nil, fmt.Errorf("failed to purge expired branch backups: %w", err)
+	}
+	for _, branch := range purged {
+		_ = repo.Storer.RemoveReference(plumbing.ReferenceName(deletedRefPrefix + branch))
+	}
+	return purged, nil
+}
+
+// fetchDeletedRefs mirrors the remote backups under the local refs/goktor/deleted/
+func (gs *GitModelService) fetchDeletedRefs(ctx context.Context, repo *git.Repository, remoteName string) error {
+	err := gs.withAuth(ctx, remoteURL(repo, remoteName), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		return repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName:   remoteName,
+			RefSpecs:     []config.RefSpec{config.RefSpec("+" + deletedRefPrefix + "*:" + deletedRefPrefix + "*")},
+			Prune:        true,
+			Auth:         auth,
+			ProxyOptions: proxy,
+			Progress:     gs.progress,
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) && !errors.Is(err, git.NoMatchingRefSpecError{}) {
+		return fmt.Errorf("failed to fetch deleted branches: %w", err)
+	}
+	return nil
+}
+
+func (gs *GitModelService) pushRefSpecs(ctx context.Context, repo *git.Repository, remoteName string, refSpecs ...config.RefSpec) error {
+	err := gs.withAuth(ctx, remoteURL(repo, remoteName), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		return repo.PushContext(ctx, &git.PushOptions{
+			RemoteName:   remoteName,
+			RefSpecs:     refSpecs,
+			Auth:         auth,
+			ProxyOptions: proxy,
+			Progress:     gs.progress,
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+	return nil
+}
+
+// deletedBranches reads the local copies of the backup refs
+func deletedBranches(repo *git.Repository) ([]DeletedBranch, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+
+	var deleted []DeletedBranch
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if !strings.HasPrefix(name, deletedRefPrefix) {
+			return nil
+		}
+		branch := DeletedBranch{Branch: strings.TrimPrefix(name, deletedRefPrefix), Hash: ref.Hash().String()}
+		// backups pushed by hand may be plain refs to the commit
+		if tag, err := repo.TagObject(ref.Hash()); err == nil {
+			branch.Hash = tag.Target.String()
+			branch.DeletedAt = tag.Tagger.When
+			branch.ExpiresAt = parseExpires(tag.Message)
+		}
+		deleted = append(deleted, branch)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate references: %w", err)
+	}
+
+	sort.Slice(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt.Before(deleted[j].DeletedAt)
+	})
+	return deleted, nil
+}
+
+func parseExpires(message string) time.Time {
+	for _, line := range strings.Split(message, "\n") {
+		if value, ok := strings.CutPrefix(line, expiresTrailer); ok {
+			expires, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+			if err == nil {
+				return expires
+			}
+		}
+	}
+	return time.Time{}
+}
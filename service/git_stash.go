@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stash is an entry of the stash of a repository
+type Stash struct {
+	// Index is n in stash@{n}, 0 being the latest entry
+	Index int    `json:"index"`
+	Hash  string `json:"hash"`
+	// Branch is the branch the changes were stashed on
+	Branch  string    `json:"branch"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
+}
+
+// Ref returns the stash@{n} name of the entry
+func (s Stash) Ref() string {
+	return fmt.Sprintf("stash@{%d}", s.Index)
+}
+
+// ListStashes returns the stash entries of the repository at repoPath, latest
+// first. It needs the git executable, go-git not implementing stash.
+func (gs *GitModelService) ListStashes(ctx context.Context, repoPath string, opts Options) ([]Stash, error) {
+	_, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+
+	out, err := runGit(ctx, repoPath, "stash", "list", "--format=%gd%x00%H%x00%ct%x00%gs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stashes: %w", err)
+	}
+	var stashes []Stash
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		stash, err := parseStashLine(line)
+		if err != nil {
+			return nil, err
+		}
+		stashes = append(stashes, stash)
+	}
+	return stashes, nil
+}
+
+// parseStashLine reads a "stash@{n} NUL hash NUL unix time NUL subject" line.
+// The subject is "WIP on <branch>: <commit>" or "On <branch>: <message>".
+func parseStashLine(line string) (Stash, error) {
+	fields := strings.SplitN(line, "\x00", 4)
+	if len(fields) != 4 {
+		return Stash{}, fmt.Errorf("unexpected stash entry %q", line)
+	}
+	index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(fields[0], "stash@{"), "}"))
+	if err != nil {
+		return Stash{}, fmt.Errorf("unexpected stash name %q", fields[0])
+	}
+	created, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Stash{}, fmt.Errorf("unexpected stash date %q", fields[2])
+	}
+	stash := Stash{Index: index, Hash: fields[1], Created: time.Unix(created, 0), Message: fields[3]}
+	for _, prefix := range []string{"WIP on ", "On "} {
+		if subject, ok := strings.CutPrefix(fields[3], prefix); ok {
+			if branch, message, ok := strings.Cut(subject, ": "); ok {
+				stash.Branch, stash.Message = branch, message
+			}
+			break
+		}
+	}
+	return stash, nil
+}
+
+// ApplyStash applies stash@{index} to the worktree of the repository at
+// repoPath, dropping it afterwards when pop is set. A stash that conflicts
+// with the worktree is kept.
+func (gs *GitModelService) ApplyStash(ctx context.Context, repoPath string, index int, pop bool, opts Options) error {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+
+	command := "apply"
+	if pop {
+		command = "pop"
+	}
+	ref := Stash{Index: index}.Ref()
+	if _, err := runGit(ctx, repoPath, "stash", command, ref); err != nil {
+		return fmt.Errorf("failed to apply %s: %w", ref, err)
+	}
+	gs.logger.With("repo", repoPath).Info("stash applied", "stash", ref, "dropped", pop)
+	return nil
+}
+
+// DropStash deletes stash@{index} of the repository at repoPath; the indexes
+// of the older entries shift down by one. With opts.DryRun nothing is deleted.
+func (gs *GitModelService) DropStash(ctx context.Context, repoPath string, index int, opts Options) error {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+
+	ref := Stash{Index: index}.Ref()
+	log := gs.logger.With("repo", repoPath, "stash", ref)
+	if opts.DryRun {
+		log.Info("dry-run: would drop stash")
+		return nil
+	}
+	if _, err := runGit(ctx, repoPath, "stash", "drop", ref); err != nil {
+		return fmt.Errorf("failed to drop %s: %w", ref, err)
+	}
+	log.Info("stash dropped")
+	return nil
+}
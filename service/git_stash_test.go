@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStashLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    Stash
+		wantErr bool
+	}{
+		{line: "stash@{0}\x00abc\x001700000000\x00On main: before rebase", want: Stash{Index: 0, Hash: "abc", Branch: "main", Message: "before rebase"}},
+		{line: "stash@{3}\x00def\x001700000000\x00WIP on feature/x: 1a2b3c4 add login", want: Stash{Index: 3, Hash: "def", Branch: "feature/x", Message: "1a2b3c4 add login"}},
+		{line: "stash@{1}\x00fed\x001700000000\x00custom subject", want: Stash{Index: 1, Hash: "fed", Message: "custom subject"}},
+		{line: "stash@{x}\x00abc\x001700000000\x00On main: x", wantErr: true},
+		{line: "garbage", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseStashLine(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStashLine(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got.Index != tt.want.Index || got.Hash != tt.want.Hash || got.Branch != tt.want.Branch || got.Message != tt.want.Message || got.Created.Unix() != 1700000000 {
+			t.Errorf("parseStashLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestGitModelService_Stashes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not available")
+	}
+	repoPath, _, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+	ctx := context.Background()
+	gs := NewGitService(&DefaultLogger{})
+
+	file := filepath.Join(repoPath, "test.txt")
+	for _, content := range []string{"first", "second"} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write test.txt: %v", err)
+		}
+		if _, err := runGit(ctx, repoPath, "-c", "user.name=Test User", "-c", "user.email=test@example.com", "stash", "push", "-m", content); err != nil {
+			t.Fatalf("failed to stash: %v", err)
+		}
+	}
+
+	stashes, err := gs.ListStashes(ctx, repoPath, Options{})
+	if err != nil {
+		t.Fatalf("ListStashes() error = %v", err)
+	}
+	if len(stashes) != 2 || stashes[0].Message != "second" || stashes[1].Message != "first" || stashes[1].Index != 1 || stashes[0].Branch != "master" {
+		t.Fatalf("ListStashes() = %+v", stashes)
+	}
+
+	if err := gs.ApplyStash(ctx, repoPath, 1, true, Options{}); err != nil {
+		t.Fatalf("ApplyStash() error = %v", err)
+	}
+	if content, _ := os.ReadFile(file); string(content) != "first" {
+		t.Errorf("test.txt = %q after applying the first stash", content)
+	}
+
+	if err := gs.DropStash(ctx, repoPath, 0, Options{DryRun: true}); err != nil {
+		t.Fatalf("DropStash() dry run error = %v", err)
+	}
+	if stashes, _ := gs.ListStashes(ctx, repoPath, Options{}); len(stashes) != 1 || stashes[0].Message != "second" {
+		t.Fatalf("stashes = %+v, want only the second one left", stashes)
+	}
+	if err := gs.DropStash(ctx, repoPath, 0, Options{}); err != nil {
+		t.Fatalf("DropStash() error = %v", err)
+	}
+	if stashes, _ := gs.ListStashes(ctx, repoPath, Options{}); len(stashes) != 0 {
+		t.Errorf("stashes = %+v, want none", stashes)
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// StaleReasonInactive marks a branch without commits for longer than the stale threshold
+const StaleReasonInactive = "inactive"
+
+// RepoStatus is a read-only snapshot of a repository, built without network access
+type RepoStatus struct {
+	// Branch is the checked-out branch, empty when HEAD is detached
+	Branch string `json:"branch"`
+	// DefaultBranch is the default branch of origin, empty when it cannot be
+	// told from refs/remotes/origin/HEAD nor from a main or master branch
+	DefaultBranch string `json:"default_branch,omitempty"`
+	// Kind tells a bare repository, which is never dirty, from a worktree
+	Kind RepoKind `json:"kind"`
+	// Dirty reports uncommitted changes to tracked files
+	Dirty bool `json:"dirty"`
+	// HasRemote reports whether an origin remote is configured
+	HasRemote bool `json:"has_remote"`
+	// StaleBranches maps each stale local branch to PruneReasonUpstreamGone or StaleReasonInactive
+	StaleBranches map[string]string `json:"stale_branches"`
+	// LastCommit is the committer date of HEAD
+	LastCommit time.Time `json:"last_commit"`
+}
+
+// RepoStatus inspects repoPath using only local data. Branches other than the
+// current and default ones are stale when their upstream is gone or their tip
+// is older than staleAfter.
+func (gs *GitModelService) RepoStatus(ctx context.Context, repoPath string, staleAfter time.Duration, opts Options) (*RepoStatus, error) {
+	gs, _, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &RepoStatus{Kind: RepoKindWorktree, StaleBranches: map[string]string{}}
+	if isBare(repo) {
+		status.Kind = RepoKindBare
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if head.Name().IsBranch() {
+		status.Branch = head.Name().Short()
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD commit: %w", err)
+	}
+	status.LastCommit = headCommit.Committer.When
+
+	if _, err := repo.Remote(gs.remoteName()); err == nil {
+		status.HasRemote = true
+	}
+	if defaultBranch, err := localDefaultBranch(repo, gs.remoteName()); err == nil {
+		status.DefaultBranch = defaultBranch
+	}
+
+	if status.Kind == RepoKindWorktree {
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get worktree: %w", err)
+		}
+		if status.Dirty, err = hasUncommittedChanges(worktree); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	defer branches.Close()
+
+	cutoff := time.Now().Add(-staleAfter)
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		branchName := ref.Name().Short()
+		if branchName == status.Branch || branchName == status.DefaultBranch {
+			return nil
+		}
+
+		if branchCfg, ok := cfg.Branches[branchName]; ok && branchCfg.Remote != "" && branchCfg.Merge != "" {
+			upstream := plumbing.NewRemoteReferenceName(branchCfg.Remote, branchCfg.Merge.Short())
+			if _, err := repo.Reference(upstream, true); errors.Is(err, plumbing.ErrReferenceNotFound) {
+				status.StaleBranches[branchName] = PruneReasonUpstreamGone
+				return nil
+			}
+		}
+
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			gs.logger.Debug("failed to load branch commit", "repo", repoPath, "branch", branchName, "error", err)
+			return nil
+		}
+		if commit.Committer.When.Before(cutoff) {
+			status.StaleBranches[branchName] = StaleReasonInactive
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed processing branches: %w", err)
+	}
+
+	return status, nil
+}
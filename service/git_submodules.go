@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// SubmoduleResult is the outcome of syncing a submodule to the commit
+// recorded by the checked-out branch of its parent repository
+type SubmoduleResult struct {
+	Path string `json:"path"`
+	// Commit is the commit recorded by the parent branch
+	Commit string `json:"commit"`
+	// Updated reports whether the submodule was initialized or moved to Commit
+	Updated bool   `json:"updated"`
+	Error   string `json:"error,omitempty"`
+}
+
+// syncSubmodules fetches the submodules of the repository at repoPath and
+// checks each out at the commit recorded by the parent branch, nested
+// submodules included. A submodule that fails is reported and the others are
+// still synced.
+func (gs *GitModelService) syncSubmodules(ctx context.Context, repoPath string, worktree *git.Worktree) ([]SubmoduleResult, error) {
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list submodules: %w", err)
+	}
+	if len(submodules) == 0 {
+		return nil, nil
+	}
+
+	// .gitmodules may point to new URLs after the update
+	if _, err := runGit(ctx, repoPath, "submodule", "sync", "--recursive"); err != nil {
+		return nil, fmt.Errorf("failed to sync submodule URLs: %w", err)
+	}
+
+	results := make([]SubmoduleResult, 0, len(submodules))
+	for _, submodule := range submodules {
+		if ctx.Err() != nil {
+			break
+		}
+		result := SubmoduleResult{Path: submodule.Config().Path}
+		log := gs.logger.With("submodule", result.Path)
+		status, err := submodule.Status()
+		if err != nil {
+			result.Error = err.Error()
+			log.Error("failed to read submodule status", "error", err)
+			results = append(results, result)
+			continue
+		}
+		result.Commit = status.Expected.String()
+
+		if _, err := runCommandEnv(ctx, repoPath, gs.proxy.env(), "git", "submodule", "update", "--init", "--recursive", "--", result.Path); err != nil {
+			result.Error = err.Error()
+			log.Error("failed to update submodule", "error", err)
+			results = append(results, result)
+			continue
+		}
+		result.Updated = status.Current != status.Expected
+		if result.Updated {
+			log.Info("submodule updated", "commit", result.Commit)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
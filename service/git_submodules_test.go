@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGitModelService_UpdateAllBranchesProject_RecurseSubmodules(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not available")
+	}
+	// submodules of the tests are local paths, refused by default since git 2.38.1
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	subPath, subBare, subCleanup := setupTestRepoWithRemote(t)
+	defer subCleanup()
+	parentPath, parentBare, parentCleanup := setupTestRepoWithRemote(t)
+	defer parentCleanup()
+
+	subHead, err := runGit(ctx, subPath, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatalf("failed to read submodule head: %v", err)
+	}
+	for _, args := range [][]string{
+		{"submodule", "add", subBare, "lib"},
+		{"-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-m", "add lib"},
+		{"push", "origin", "HEAD"},
+	} {
+		if _, err := runGit(ctx, parentPath, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+
+	// a clone without --recurse-submodules leaves lib uninitialized
+	clonePath := filepath.Join(t.TempDir(), "clone")
+	if _, err := runGit(ctx, parentPath, "clone", parentBare, clonePath); err != nil {
+		t.Fatalf("failed to clone parent: %v", err)
+	}
+
+	gs := NewGitService(&DefaultLogger{})
+	opts := UpdateOptions{RecurseSubmodules: true}
+	result, err := gs.UpdateAllBranchesProject(ctx, clonePath, opts)
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+	if len(result.Submodules) != 1 || result.SubmoduleError != "" {
+		t.Fatalf("Submodules = %+v, SubmoduleError = %q, want lib", result.Submodules, result.SubmoduleError)
+	}
+	lib := result.Submodules[0]
+	if lib.Path != "lib" || lib.Commit != subHead || !lib.Updated || lib.Error != "" {
+		t.Errorf("submodule = %+v, want lib updated to %s", lib, subHead)
+	}
+	if _, err := os.Stat(filepath.Join(clonePath, "lib", "test.txt")); err != nil {
+		t.Errorf("submodule not checked out: %v", err)
+	}
+
+	result, err = gs.UpdateAllBranchesProject(ctx, clonePath, opts)
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+	if len(result.Submodules) != 1 || result.Submodules[0].Updated {
+		t.Errorf("Submodules = %+v, want lib already in sync", result.Submodules)
+	}
+
+	result, err = gs.UpdateAllBranchesProject(ctx, clonePath, UpdateOptions{})
+	if err != nil {
+		t.Fatalf("UpdateAllBranchesProject() error = %v", err)
+	}
+	if result.Submodules != nil {
+		t.Errorf("Submodules = %+v without RecurseSubmodules", result.Submodules)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Tag is a tag of a repository
+type Tag struct {
+	Name string `json:"name"`
+	// Commit is the commit the tag points to, through its tag object when annotated
+	Commit    string `json:"commit"`
+	Annotated bool   `json:"annotated,omitempty"`
+	// Date is the tagger date of an annotated tag, the committer date of the
+	// commit otherwise
+	Date time.Time `json:"date,omitzero"`
+}
+
+// TagSyncResult lists the tags SyncTags changed, or would change with a dry run
+type TagSyncResult struct {
+	// Added are the tags of the remote missing locally
+	Added []string `json:"added"`
+	// Updated are the local tags the remote moved to another object
+	Updated []string `json:"updated"`
+	// Pruned are the local tags the remote does not have
+	Pruned []string `json:"pruned"`
+}
+
+// ListTags returns the tags of repoPath whose name matches one of the glob
+// patterns, every tag when there is none, sorted by name
+func (gs *GitModelService) ListTags(ctx context.Context, repoPath string, patterns []string, opts Options) ([]Tag, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer refs.Close()
+
+	tags := []Tag{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := ref.Name().Short()
+		if len(patterns) > 0 && !matchesAny(name, patterns) {
+			return nil
+		}
+		tag := Tag{Name: name, Commit: ref.Hash().String()}
+		if annotated, err := repo.TagObject(ref.Hash()); err == nil {
+			tag.Annotated = true
+			tag.Commit = annotated.Target.String()
+			tag.Date = annotated.Tagger.When
+		} else if commit, err := repo.CommitObject(ref.Hash()); err == nil {
+			tag.Date = commit.Committer.When
+		} else {
+			gs.logger.Debug("tag does not point to a commit", "repo", repoPath, "tag", name)
+		}
+		tags = append(tags, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// SyncTags makes the local tags of repoPath match the tags of the remote like
+// git fetch --prune --prune-tags: missing and moved tags are fetched, and local
+// tags the remote does not have are deleted, including tags never pushed.
+// With opts.DryRun the remote is only listed.
+func (gs *GitModelService) SyncTags(ctx context.Context, repoPath string, opts Options) (*TagSyncResult, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	remoteName := gs.remoteName()
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := getRemote(repo, remoteName)
+	if err != nil {
+		return nil, err
+	}
+	var advertised []*plumbing.Reference
+	err = gs.withAuth(ctx, remoteURL(repo, remoteName), func(auth transport.AuthMethod, proxy transport.ProxyOptions) error {
+		var err error
+		advertised, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth, ProxyOptions: proxy})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote refs: %w", err)
+	}
+	remoteTags := map[plumbing.ReferenceName]plumbing.Hash{}
+	for _, ref := range advertised {
+		if ref.Name().IsTag() {
+			remoteTags[ref.Name()] = ref.Hash()
+		}
+	}
+
+	localTags := map[plumbing.ReferenceName]plumbing.Hash{}
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		localTags[ref.Name()] = ref.Hash()
+		return nil
+	})
+	refs.Close()
+
+	result := &TagSyncResult{Added: []string{}, Updated: []string{}, Pruned: []string{}}
+	for name, hash := range remoteTags {
+		local, ok := localTags[name]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, name.Short())
+		case local != hash:
+			result.Updated = append(result.Updated, name.Short())
+		}
+	}
+	var pruned []plumbing.ReferenceName
+	for name := range localTags {
+		if _, ok := remoteTags[name]; !ok {
+			pruned = append(pruned, name)
+			result.Pruned = append(result.Pruned, name.Short())
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Pruned)
+	if opts.DryRun {
+		gs.logger.Info("dry-run: tags to sync", "added", len(result.Added), "updated", len(result.Updated), "pruned", len(result.Pruned))
+		return result, nil
+	}
+
+	if len(result.Added) > 0 || len(result.Updated) > 0 {
+		if err := gs.fetch(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range pruned {
+		if err := repo.Storer.RemoveReference(name); err != nil {
+			return nil, fmt.Errorf("failed to delete tag %s: %w", name.Short(), err)
+		}
+		gs.logger.Info("deleted tag removed from the remote", "tag", name.Short())
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestGitModelService_SyncTags(t *testing.T) {
+	gs := NewGitService(&DefaultLogger{})
+	ctx := context.Background()
+	repoPath, bareDir, cleanup := setupTestRepoWithRemote(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	bare, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatalf("failed to open bare repo: %v", err)
+	}
+	if _, err := bare.CreateTag("v1.0.0", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test User", Email: "test@example.com"},
+		Message: "release 1.0.0",
+	}); err != nil {
+		t.Fatalf("failed to tag the remote: %v", err)
+	}
+	if _, err := bare.CreateTag("v1.1.0", head.Hash(), nil); err != nil {
+		t.Fatalf("failed to tag the remote: %v", err)
+	}
+	if _, err := repo.CreateTag("v0.9.0", head.Hash(), nil); err != nil {
+		t.Fatalf("failed to tag locally: %v", err)
+	}
+
+	dry, err := gs.SyncTags(ctx, repoPath, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("SyncTags() dry run error = %v", err)
+	}
+	if !slices.Equal(dry.Added, []string{"v1.0.0", "v1.1.0"}) || !slices.Equal(dry.Pruned, []string{"v0.9.0"}) {
+		t.Errorf("dry run = %+v", dry)
+	}
+	if _, err := repo.Tag("v0.9.0"); err != nil {
+		t.Errorf("dry run deleted v0.9.0")
+	}
+
+	result, err := gs.SyncTags(ctx, repoPath, Options{})
+	if err != nil {
+		t.Fatalf("SyncTags() error = %v", err)
+	}
+	if !slices.Equal(result.Added, dry.Added) || !slices.Equal(result.Pruned, dry.Pruned) {
+		t.Errorf("result = %+v, want the dry run changes", result)
+	}
+	tags, err := gs.ListTags(ctx, repoPath, nil, Options{})
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "v1.0.0" || !tags[0].Annotated || tags[0].Commit != head.Hash().String() || tags[1].Annotated {
+		t.Errorf("tags = %+v, want v1.0.0 annotated and v1.1.0", tags)
+	}
+
+	// a tag moved on the remote is fetched again
+	commitTestFile(t, repoPath, "next.txt", "next")
+	next, _ := repo.Head()
+	if err := repo.Push(&git.PushOptions{RemoteName: DefaultRemote}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+	if err := bare.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.1.0"), next.Hash())); err != nil {
+		t.Fatalf("failed to move v1.1.0: %v", err)
+	}
+	result, err = gs.SyncTags(ctx, repoPath, Options{})
+	if err != nil {
+		t.Fatalf("SyncTags() error = %v", err)
+	}
+	if !slices.Equal(result.Updated, []string{"v1.1.0"}) {
+		t.Errorf("Updated = %v, want v1.1.0", result.Updated)
+	}
+	tags, err = gs.ListTags(ctx, repoPath, []string{"v1.1.*"}, Options{})
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if len(tags) != 1 || tags[0].Commit != next.Hash().String() {
+		t.Errorf("tags = %+v, want v1.1.0 at the new commit", tags)
+	}
+}
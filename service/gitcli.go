@@ -0,0 +1,57 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runGit runs the git executable in dir for operations go-git does not
+// implement, such as stash. It returns the trimmed standard output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	return runCommand(ctx, dir, "git", args...)
+}
+
+// runCommand runs an external program in dir and returns its trimmed standard output
+func runCommand(ctx context.Context, dir string, name string, args ...string) (string, error) {
+	return runCommandEnv(ctx, dir, nil, name, args...)
+}
+
+// runCommandEnv is runCommand with extra environment variables added to the current ones
+func runCommandEnv(ctx context.Context, dir string, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return runCmd(ctx, cmd)
+}
+
+// runCommandInput runs an external program with input on its standard input,
+// keeping secrets out of its arguments
+func runCommandInput(ctx context.Context, input string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(input)
+	return runCmd(ctx, cmd)
+}
+
+// runCmd runs cmd and returns its trimmed standard output, or an error with
+// its standard error
+func runCmd(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	command := strings.Join(cmd.Args, " ")
+	if err := cmd.Run(); err != nil {
+		// a killed process reports a signal, the context tells whether it timed out
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("%s: %w", command, ctxErr)
+		}
+		return "", fmt.Errorf("%s: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
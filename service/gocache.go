@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// GoCaches locates the Go module cache and build cache
+type GoCaches struct {
+	// ModCache is GOMODCACHE, $GOPATH/pkg/mod by default
+	ModCache string
+	// BuildCache is GOCACHE
+	BuildCache string
+}
+
+// FindGoCaches asks the go command for GOMODCACHE and GOCACHE, and falls back
+// on their default locations when it is not installed
+func FindGoCaches(ctx context.Context) (GoCaches, error) {
+	var caches GoCaches
+	if out, err := exec.CommandContext(ctx, "go", "env", "-json", "GOMODCACHE", "GOCACHE").Output(); err == nil {
+		var env struct{ GOMODCACHE, GOCACHE string }
+		if err := json.Unmarshal(out, &env); err == nil {
+			caches = GoCaches{ModCache: env.GOMODCACHE, BuildCache: env.GOCACHE}
+		}
+	}
+	if caches.ModCache == "" {
+		caches.ModCache = os.Getenv("GOMODCACHE")
+	}
+	if caches.ModCache == "" {
+		gopath := filepath.SplitList(os.Getenv("GOPATH"))
+		if len(gopath) > 0 && gopath[0] != "" {
+			caches.ModCache = filepath.Join(gopath[0], "pkg", "mod")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			caches.ModCache = filepath.Join(home, "go", "pkg", "mod")
+		}
+	}
+	if caches.BuildCache == "" {
+		caches.BuildCache = os.Getenv("GOCACHE")
+	}
+	if caches.BuildCache == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			caches.BuildCache = filepath.Join(dir, "go-build")
+		}
+	}
+	if caches.ModCache == "" {
+		return caches, errors.New("failed to locate the Go module cache, set GOMODCACHE")
+	}
+	return caches, nil
+}
+
+// Sizes returns the space taken by the module cache, including its download
+// and VCS caches, and by the build cache
+func (c GoCaches) Sizes() (modCache, buildCache int64) {
+	if c.BuildCache != "" {
+		buildCache = pathSize(c.BuildCache)
+	}
+	return pathSize(c.ModCache), buildCache
+}
+
+// CachedModule is a module version extracted in the module cache
+type CachedModule struct {
+	Path    string
+	Version string
+	// Dir is the extracted source directory
+	Dir string
+	// Size counts the source directory and the download cache files of the
+	// version (zip, mod, info...)
+	Size int64
+}
+
+// String returns path@version
+func (m CachedModule) String() string {
+	return m.Path + "@" + m.Version
+}
+
+// ListCachedModules returns the module versions of the module cache modCache,
+// largest first
+func ListCachedModules(ctx context.Context, modCache string) ([]CachedModule, error) {
+	var modules []CachedModule
+	err := filepath.WalkDir(modCache, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == modCache {
+				return fmt.Errorf("failed to read module cache: %w", err)
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() || path == modCache {
+			return nil
+		}
+		rel, _ := filepath.Rel(modCache, path)
+		if rel == "cache" {
+			return filepath.SkipDir
+		}
+		escapedPath, escapedVersion, ok := strings.Cut(filepath.ToSlash(rel), "@")
+		if !ok {
+			return nil
+		}
+		modPath, err1 := module.UnescapePath(escapedPath)
+		version, err2 := module.UnescapeVersion(escapedVersion)
+		if err1 != nil || err2 != nil {
+			return filepath.SkipDir
+		}
+		m := CachedModule{Path: modPath, Version: version, Dir: path, Size: pathSize(path)}
+		for _, file := range downloadFiles(modCache, escapedPath, escapedVersion) {
+			if info, err := os.Stat(file); err == nil {
+				m.Size += info.Size()
+			}
+		}
+		modules = append(modules, m)
+		return filepath.SkipDir
+	})
+	sort.SliceStable(modules, func(i, j int) bool { return modules[i].Size > modules[j].Size })
+	return modules, err
+}
+
+// downloadFiles returns the files of the download cache of a module version
+func downloadFiles(modCache, escapedPath, escapedVersion string) []string {
+	dir := filepath.Join(modCache, "cache", "download", filepath.FromSlash(escapedPath), "@v")
+	var files []string
+	for _, ext := range []string{".zip", ".ziphash", ".mod", ".info", ".lock", ".partial"} {
+		files = append(files, filepath.Join(dir, escapedVersion+ext))
+	}
+	return files
+}
+
+// ModuleReferences collects the module versions required by the go.mod files
+// found below roots, and listed by the go.sum files next to them, so the
+// dependencies of dependencies are kept too. Vendor, node_modules and hidden
+// directories are skipped.
+func ModuleReferences(ctx context.Context, roots []string) (map[string]bool, error) {
+	refs := make(map[string]bool)
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if path != root && (name == "vendor" || name == "node_modules" || strings.HasPrefix(name, ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch d.Name() {
+			case "go.mod":
+				return addModFileReferences(path, refs)
+			case "go.sum":
+				return addSumFileReferences(path, refs)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+func addModFileReferences(path string, refs map[string]bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	file, err := modfile.ParseLax(path, data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, req := range file.Require {
+		refs[req.Mod.String()] = true
+	}
+	for _, rep := range file.Replace {
+		if rep.New.Version != "" {
+			refs[rep.New.String()] = true
+		}
+	}
+	return nil
+}
+
+// addSumFileReferences adds the "<path> <version> h1:..." lines of a go.sum,
+// leaving the "<version>/go.mod" ones which need no extracted source
+func addSumFileReferences(path string, refs map[string]bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && !strings.HasSuffix(fields[1], "/go.mod") {
+			refs[fields[0]+"@"+fields[1]] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// UnreferencedModules returns the modules missing from refs
+func UnreferencedModules(modules []CachedModule, refs map[string]bool) []CachedModule {
+	var unreferenced []CachedModule
+	for _, m := range modules {
+		if !refs[m.String()] {
+			unreferenced = append(unreferenced, m)
+		}
+	}
+	return unreferenced
+}
+
+// PruneModules deletes the source directories and download cache files of
+// modules from the module cache modCache, going on after a failure and leaving
+// the paths protected by guard. The go command downloads them again when
+// needed. It returns the removed modules and the joined errors of the others.
+func PruneModules(ctx context.Context, modCache string, modules []CachedModule, guard *PathGuard) ([]CachedModule, error) {
+	var removed []CachedModule
+	var errs []error
+	for _, m := range modules {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := guard.Check(m.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("refusing to remove %w", err))
+			continue
+		}
+		// the module cache is read-only
+		if err := removeWritable(m.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", m, err))
+			continue
+		}
+		escapedPath, err1 := module.EscapePath(m.Path)
+		escapedVersion, err2 := module.EscapeVersion(m.Version)
+		if err1 == nil && err2 == nil {
+			for _, file := range downloadFiles(modCache, escapedPath, escapedVersion) {
+				if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					errs = append(errs, fmt.Errorf("failed to remove %s: %w", file, err))
+				}
+			}
+		}
+		removed = append(removed, m)
+	}
+	return removed, errors.Join(errs...)
+}
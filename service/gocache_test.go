@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCachedModulesPrune(t *testing.T) {
+	modCache := t.TempDir()
+	write := func(root, path string, size int) {
+		t.Helper()
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(modCache, "github.com/!burnt!sushi/toml@v1.3.0/decode.go", 300)
+	write(modCache, "cache/download/github.com/!burnt!sushi/toml/@v/v1.3.0.zip", 100)
+	write(modCache, "golang.org/x/text@v0.1.0/doc.go", 200)
+	write(modCache, "golang.org/x/text@v0.2.0/doc.go", 50)
+	write(modCache, "cache/download/golang.org/x/text/@v/v0.1.0.zip", 20)
+	// read-only like the directories extracted by the go command
+	if err := os.Chmod(filepath.Join(modCache, "golang.org", "x", "text@v0.1.0"), 0555); err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := ListCachedModules(context.Background(), modCache)
+	if err != nil {
+		t.Fatalf("ListCachedModules() error = %v", err)
+	}
+	if len(modules) != 3 || modules[0].String() != "github.com/BurntSushi/toml@v1.3.0" || modules[0].Size != 400 {
+		t.Fatalf("ListCachedModules() = %+v, want the unescaped toml module first with its zip", modules)
+	}
+
+	workspace := t.TempDir()
+	write(workspace, "app/go.mod", 0)
+	if err := os.WriteFile(filepath.Join(workspace, "app", "go.mod"), []byte("module example.com/app\n\ngo 1.24\n\nrequire github.com/BurntSushi/toml v1.3.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "app", "go.sum"), []byte(
+		"golang.org/x/text v0.2.0 h1:abc=\ngolang.org/x/text v0.1.0/go.mod h1:def=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	write(workspace, "app/vendor/other/go.mod", 0)
+
+	refs, err := ModuleReferences(context.Background(), []string{workspace})
+	if err != nil {
+		t.Fatalf("ModuleReferences() error = %v", err)
+	}
+	unused := UnreferencedModules(modules, refs)
+	if len(unused) != 1 || unused[0].String() != "golang.org/x/text@v0.1.0" {
+		t.Fatalf("UnreferencedModules() = %+v, want golang.org/x/text@v0.1.0", unused)
+	}
+
+	removed, err := PruneModules(context.Background(), modCache, unused, nil)
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("PruneModules() = %+v, %v", removed, err)
+	}
+	for path, want := range map[string]bool{
+		"golang.org/x/text@v0.1.0":                       false,
+		"cache/download/golang.org/x/text/@v/v0.1.0.zip": false,
+		"golang.org/x/text@v0.2.0":                       true,
+	} {
+		if _, err := os.Stat(filepath.Join(modCache, filepath.FromSlash(path))); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", path, err == nil, want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// hashAlgorithms are the algorithms accepted by HashTree
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// FileChecksum is the checksum of one file of a tree
+type FileChecksum struct {
+	// Path is slash separated and relative to the hashed directory, the file
+	// name when a single file was hashed
+	Path string
+	Size int64
+	Sum  string
+}
+
+// TreeChecksum is the manifest of a hashed file or directory
+type TreeChecksum struct {
+	Algorithm string
+	// Files are sorted by path
+	Files []FileChecksum
+	// Sum is the checksum of the manifest, which changes when any file is
+	// added, removed, renamed or modified
+	Sum string
+}
+
+// Size is the total size of the hashed files
+func (t *TreeChecksum) Size() int64 {
+	var size int64
+	for _, file := range t.Files {
+		size += file.Size
+	}
+	return size
+}
+
+// WriteManifest writes one "<sum>  <path>" line per file, the format read
+// back by sha256sum -c and the like
+func (t *TreeChecksum) WriteManifest(w io.Writer) error {
+	for _, file := range t.Files {
+		if _, err := fmt.Fprintf(w, "%s  %s\n", file.Sum, file.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HashTree computes the checksum of path, a file or every regular file below
+// a directory, hashing up to workers files at a time (the number of CPUs when
+// zero). Symlinks are not followed. The first unreadable file stops it.
+func HashTree(ctx context.Context, path string, algorithm string, workers int) (*TreeChecksum, error) {
+	newHash, ok := hashAlgorithms[strings.ToLower(algorithm)]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm %q, expected md5, sha1, sha256 or sha512", algorithm)
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	files, err := treeFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		jobs     = make(chan int)
+	)
+	for range min(workers, max(len(files), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				sum, err := hashFile(ctx, files[i].fullPath, newHash())
+				if err != nil {
+					once.Do(func() { firstErr = err; cancel() })
+					continue
+				}
+				files[i].Sum = sum
+			}
+		}()
+	}
+feed:
+	for i := range files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tree := &TreeChecksum{Algorithm: strings.ToLower(algorithm)}
+	manifest := newHash()
+	for _, file := range files {
+		tree.Files = append(tree.Files, file.FileChecksum)
+		fmt.Fprintf(manifest, "%s  %s\n", file.Sum, file.Path)
+	}
+	tree.Sum = hex.EncodeToString(manifest.Sum(nil))
+	return tree, nil
+}
+
+// hashedFile is a file of the tree with the path it is read from
+type hashedFile struct {
+	FileChecksum
+	fullPath string
+}
+
+// treeFiles lists path, or the regular files below it, sorted by relative path
+func treeFiles(path string) ([]hashedFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []hashedFile{{FileChecksum: FileChecksum{Path: filepath.Base(path), Size: info.Size()}, fullPath: path}}, nil
+	}
+
+	var files []hashedFile
+	err = filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		rel, err := filepath.Rel(path, filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, hashedFile{FileChecksum: FileChecksum{Path: filepath.ToSlash(rel), Size: info.Size()}, fullPath: filePath})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, err
+}
+
+// hashFile returns the hex checksum of the file at path, stopping early when
+// ctx is cancelled
+func hashFile(ctx context.Context, path string, h hash.Hash) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(h, contextReader{ctx: ctx, r: file}); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contextReader stops reading once ctx is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
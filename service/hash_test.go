@@ -0,0 +1,46 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "hello.txt"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "sub", "empty"), nil, 0644)
+	ctx := context.Background()
+
+	tree, err := HashTree(ctx, tmpDir, "SHA256", 2)
+	if err != nil {
+		t.Fatalf("HashTree() error = %v", err)
+	}
+	var manifest bytes.Buffer
+	tree.WriteManifest(&manifest)
+	want := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  hello.txt\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  sub/empty\n"
+	if manifest.String() != want {
+		t.Errorf("manifest = %q, want %q", manifest.String(), want)
+	}
+	if tree.Size() != 6 || tree.Algorithm != "sha256" {
+		t.Errorf("tree = %+v, want 6 bytes of sha256", tree)
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "hello.txt"), []byte("hello!\n"), 0644)
+	changed, err := HashTree(ctx, tmpDir, "sha256", 0)
+	if err != nil || changed.Sum == tree.Sum {
+		t.Errorf("HashTree() after a change = %+v, %v, want another sum", changed, err)
+	}
+
+	single, err := HashTree(ctx, filepath.Join(tmpDir, "sub", "empty"), "md5", 1)
+	if err != nil || len(single.Files) != 1 || single.Files[0].Path != "empty" || single.Files[0].Sum != "d41d8cd98f00b204e9800998ecf8427e" {
+		t.Errorf("HashTree() of a file = %+v, %v", single, err)
+	}
+	if _, err := HashTree(ctx, tmpDir, "crc", 1); err == nil {
+		t.Errorf("HashTree() with an unknown algorithm should fail")
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Kinds of hooks, passed to the hook commands in GOKTOR_HOOK
+const (
+	HookPre  = "pre"
+	HookPost = "post"
+)
+
+// HooksConfig holds the shell commands the mr-repo commands run around the
+// work on every repository
+type HooksConfig struct {
+	// Pre runs before a repository is processed; when it fails the repository
+	// is recorded as failed and left untouched
+	Pre string `json:"pre,omitempty"`
+	// Post runs once a repository is processed, whatever the outcome
+	Post string `json:"post,omitempty"`
+}
+
+// RepoHook is called by RunRecord with HookPre when the work on a repository
+// starts and with HookPost when its outcome is set
+type RepoHook func(kind string, repo RunRepo) error
+
+// RunHook runs the shell command of a hook for repo and returns its output.
+// It runs in the repository directory, or in its parent when the repository
+// does not exist yet, as before a clone, with these variables set:
+// GOKTOR_HOOK (pre or post), GOKTOR_COMMAND, GOKTOR_REPO_PATH, GOKTOR_REPO_NAME,
+// and for post hooks GOKTOR_RESULT, the run status of the repository, and
+// GOKTOR_ERROR.
+func RunHook(ctx context.Context, shellCommand string, kind string, command string, repo RunRepo) (string, error) {
+	dir := repo.Repo
+	if _, err := os.Stat(dir); err != nil {
+		dir = filepath.Dir(dir)
+	}
+	env := []string{
+		"GOKTOR_HOOK=" + kind,
+		"GOKTOR_COMMAND=" + command,
+		"GOKTOR_REPO_PATH=" + repo.Repo,
+		"GOKTOR_REPO_NAME=" + filepath.Base(repo.Repo),
+	}
+	if kind == HookPost {
+		env = append(env, "GOKTOR_RESULT="+repo.Status, "GOKTOR_ERROR="+repo.Error)
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	out, err := runCommandEnv(ctx, dir, env, shell, flag, shellCommand)
+	if err != nil {
+		return "", fmt.Errorf("%s hook failed: %w", kind, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands are POSIX shell in this test")
+	}
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	repo := RunRepo{Repo: repoPath, Status: RunStatusFailed, Error: "boom"}
+
+	out, err := RunHook(ctx, `echo "$GOKTOR_HOOK $GOKTOR_COMMAND $GOKTOR_REPO_NAME $GOKTOR_RESULT $GOKTOR_ERROR" && pwd`, HookPost, "goktor mr-repo pull", repo)
+	if err != nil {
+		t.Fatalf("RunHook() error = %v", err)
+	}
+	want := "post goktor mr-repo pull " + filepath.Base(repoPath) + " failed boom"
+	if !strings.HasPrefix(out, want+"\n") {
+		t.Errorf("output = %q, want it to start with %q", out, want)
+	}
+
+	out, err = RunHook(ctx, `echo "[$GOKTOR_RESULT]"; pwd`, HookPre, "clone", RunRepo{Repo: filepath.Join(repoPath, "missing")})
+	if err != nil {
+		t.Fatalf("RunHook() error = %v", err)
+	}
+	if resolved, _ := filepath.EvalSymlinks(repoPath); out != "[]\n"+resolved && out != "[]\n"+repoPath {
+		t.Errorf("output = %q, want an empty result run in the parent directory", out)
+	}
+
+	if _, err := RunHook(ctx, "exit 3", HookPre, "pull", repo); err == nil {
+		t.Errorf("RunHook() should fail when the command fails")
+	}
+}
+
+func TestRunRecord_Hook(t *testing.T) {
+	run := NewRunRecord("test", []string{"/ws/a", "/ws/b", "/ws/c"})
+	var calls []string
+	run.Hook = func(kind string, repo RunRepo) error {
+		calls = append(calls, kind+" "+filepath.Base(repo.Repo)+" "+repo.Status)
+		switch {
+		case kind == HookPre && repo.Repo == "/ws/b":
+			return errors.New("pre failed")
+		case kind == HookPost && repo.Repo == "/ws/c":
+			return errors.New("post failed")
+		}
+		return nil
+	}
+
+	if err := run.Start(0); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	run.Set(0, RunStatusDone, nil, nil)
+	if err := run.Start(1); err == nil || run.Repos[1].Status != RunStatusFailed {
+		t.Errorf("a failed pre hook should fail the repository, got %+v", run.Repos[1])
+	}
+	if err := run.Start(2); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	run.Set(2, RunStatusDone, nil, nil)
+	if run.Repos[2].Status != RunStatusDone || run.Repos[2].HookError == "" {
+		t.Errorf("a failed post hook should only be recorded, got %+v", run.Repos[2])
+	}
+
+	want := []string{"pre a " + RunStatusPending, "post a done", "pre b " + RunStatusPending, "pre c " + RunStatusPending, "post c done"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls[%d] = %q, want %q", i, calls[i], want[i])
+		}
+	}
+}
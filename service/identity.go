@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrNoIdentity is returned when no author name or email can be resolved for a repository
+var ErrNoIdentity = errors.New("no git identity configured")
+
+// Identity is the author and committer of the commits goktor creates,
+// including the commits written by git on its behalf (stash, rebase)
+type Identity struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// Signature returns the go-git signature of the identity
+func (id Identity) Signature(when time.Time) *object.Signature {
+	return &object.Signature{Name: id.Name, Email: id.Email, When: when}
+}
+
+// env returns the variables making the git executable commit as the identity
+func (id Identity) env() []string {
+	return []string{
+		"GIT_AUTHOR_NAME=" + id.Name,
+		"GIT_AUTHOR_EMAIL=" + id.Email,
+		"GIT_COMMITTER_NAME=" + id.Name,
+		"GIT_COMMITTER_EMAIL=" + id.Email,
+	}
+}
+
+// ResolveIdentity completes override with the GIT_COMMITTER_*/GIT_AUTHOR_*
+// variables, then the user configured for the repository (local, global and
+// system git config). It returns ErrNoIdentity
+// when the name or email is still missing.
+func ResolveIdentity(repoPath string, override Identity) (Identity, error) {
+	id := override
+	if id.Name == "" {
+		id.Name = firstNonEmpty(os.Getenv("GIT_COMMITTER_NAME"), os.Getenv("GIT_AUTHOR_NAME"))
+	}
+	if id.Email == "" {
+		id.Email = firstNonEmpty(os.Getenv("GIT_COMMITTER_EMAIL"), os.Getenv("GIT_AUTHOR_EMAIL"))
+	}
+	if id.Name == "" || id.Email == "" {
+		repo, err := openRepo(repoPath)
+		if err != nil {
+			return Identity{}, err
+		}
+		cfg, err := repo.ConfigScoped(config.SystemScope)
+		if err != nil {
+			return Identity{}, fmt.Errorf("failed to get config: %w", err)
+		}
+		if id.Name == "" {
+			id.Name = firstNonEmpty(cfg.Committer.Name, cfg.User.Name)
+		}
+		if id.Email == "" {
+			id.Email = firstNonEmpty(cfg.Committer.Email, cfg.User.Email)
+		}
+	}
+
+	if id.Name == "" || id.Email == "" {
+		return Identity{}, fmt.Errorf("%w for %s: set user.name and user.email or pass --git-name and --git-email", ErrNoIdentity, repoPath)
+	}
+	if !strings.Contains(id.Email, "@") {
+		return Identity{}, fmt.Errorf("invalid git email %q for %s", id.Email, repoPath)
+	}
+	return id, nil
+}
+
+// CheckIdentities resolves the identity of every repository before a batch
+// that will commit, so the batch fails up front instead of half way through
+func CheckIdentities(repoDirs []string, override Identity) error {
+	var missing []string
+	for _, repoPath := range repoDirs {
+		if _, err := ResolveIdentity(repoPath, override); err != nil {
+			if errors.Is(err, ErrNotARepository) {
+				continue
+			}
+			missing = append(missing, err.Error())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("identity check failed:\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
+
+// runGitAs runs a committing git command in repoPath as the resolved identity
+func (gs *GitModelService) runGitAs(ctx context.Context, repoPath string, args ...string) (string, error) {
+	id, err := ResolveIdentity(repoPath, gs.identity)
+	if err != nil {
+		return "", err
+	}
+	return runCommandEnv(ctx, repoPath, id.env(), "git", args...)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
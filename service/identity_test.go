@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestResolveIdentity(t *testing.T) {
+	// Keep the global git config of the machine out of the test
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "")
+	}
+
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	if _, err := ResolveIdentity(repoPath, Identity{}); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("ResolveIdentity() error = %v, want ErrNoIdentity", err)
+	}
+	if err := CheckIdentities([]string{repoPath, t.TempDir()}, Identity{}); err == nil {
+		t.Error("CheckIdentities() expected error for repository without identity")
+	}
+
+	override := Identity{Name: "Bot", Email: "bot@example.com"}
+	if id, err := ResolveIdentity(repoPath, override); err != nil || id.Name != "Bot" {
+		t.Errorf("ResolveIdentity(override) = %+v, %v", id, err)
+	}
+	if _, err := ResolveIdentity(repoPath, Identity{Name: "Bot", Email: "not-an-email"}); err == nil {
+		t.Error("expected error for invalid email")
+	}
+
+	cfg, _ := repo.Config()
+	cfg.User.Name = "Repo User"
+	cfg.User.Email = "repo@example.com"
+	if err := repo.SetConfig(cfg); err != nil {
+		t.Fatalf("failed to set config: %v", err)
+	}
+
+	id, err := ResolveIdentity(repoPath, Identity{Name: "Override"})
+	if err != nil {
+		t.Fatalf("ResolveIdentity() error = %v", err)
+	}
+	if id.Name != "Override" || id.Email != "repo@example.com" {
+		t.Errorf("ResolveIdentity() = %+v, want override name and repo email", id)
+	}
+	if err := CheckIdentities([]string{repoPath, t.TempDir()}, Identity{}); err != nil {
+		t.Errorf("CheckIdentities() error = %v", err)
+	}
+}
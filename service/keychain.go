@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// KeychainService is the service name the tokens are stored under in the OS
+// keychain, with the host as account
+const KeychainService = "goktor"
+
+// ErrTokenNotFound is returned when the keychain holds no token for a host
+var ErrTokenNotFound = errors.New("no token stored")
+
+// keychainStore keeps the tokens of the hosts in a secret store
+type keychainStore interface {
+	set(ctx context.Context, host string, token string) error
+	// get returns ErrTokenNotFound when no token is stored for host
+	get(ctx context.Context, host string) (string, error)
+	// delete returns ErrTokenNotFound when no token is stored for host
+	delete(ctx context.Context, host string) error
+}
+
+// keychain is the OS keychain: the macOS Keychain, the Windows Credential
+// Manager, or the Secret Service through secret-tool elsewhere
+var keychain keychainStore = osKeychain{}
+
+// normalizeHost returns host in lower case, rejecting URLs and paths so a
+// token is always stored under the host name a remote resolves to. Only
+// letters, digits, dots, colons and hyphens are accepted, as the host ends up
+// in the command script of the macOS security tool.
+func normalizeHost(host string) (string, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	valid := host != ""
+	for _, r := range host {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == ':' || r == '-') {
+			valid = false
+			break
+		}
+	}
+	if !valid {
+		return "", fmt.Errorf("invalid host %q, expected a host name such as github.com", host)
+	}
+	return host, nil
+}
+
+// StoreToken saves the token of host in the OS keychain, replacing any
+// previous one
+func StoreToken(ctx context.Context, host string, token string) error {
+	host, err := normalizeHost(host)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("token cannot be empty")
+	}
+	if err := keychain.set(ctx, host, token); err != nil {
+		return fmt.Errorf("failed to store the token of %s in the keychain: %w", host, err)
+	}
+	return nil
+}
+
+// LoadToken returns the token of host from the OS keychain, an error
+// wrapping ErrTokenNotFound when none is stored
+func LoadToken(ctx context.Context, host string) (string, error) {
+	host, err := normalizeHost(host)
+	if err != nil {
+		return "", err
+	}
+	token, err := keychain.get(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token of %s from the keychain: %w", host, err)
+	}
+	return token, nil
+}
+
+// DeleteToken removes the token of host from the OS keychain, an error
+// wrapping ErrTokenNotFound when none is stored
+func DeleteToken(ctx context.Context, host string) error {
+	host, err := normalizeHost(host)
+	if err != nil {
+		return err
+	}
+	if err := keychain.delete(ctx, host); err != nil {
+		return fmt.Errorf("failed to delete the token of %s from the keychain: %w", host, err)
+	}
+	return nil
+}
+
+// keychainAuth sends the token stored for the host of HTTPS remotes by
+// goktor auth login; it is never sent in clear to http:// remotes. A remote
+// with a port gets the token stored for host:port, else the one of the host.
+// The keychain is optional: when it is locked, missing or holds no token, the
+// next sources are asked.
+func keychainAuth(ctx context.Context, endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	if endpoint.Protocol != "https" {
+		return nil, nil
+	}
+	hosts := []string{endpoint.Host}
+	if endpoint.Port != 0 {
+		hosts = []string{net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port)), endpoint.Host}
+	}
+	var token string
+	for _, host := range hosts {
+		var err error
+		if token, err = LoadToken(ctx, host); err == nil {
+			break
+		}
+	}
+	if token == "" {
+		return nil, nil
+	}
+	username := endpoint.User
+	if username == "" {
+		username = "git"
+	}
+	return &githttp.BasicAuth{Username: username, Password: token}, nil
+}
@@ -0,0 +1,49 @@
+//go:build darwin
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityItemNotFound is the exit code of the security tool when the
+// keychain holds no matching item
+const securityItemNotFound = 44
+
+// osKeychain stores the tokens as generic passwords of the login keychain
+// through the security tool
+type osKeychain struct{}
+
+func (osKeychain) set(ctx context.Context, host string, token string) error {
+	if strings.ContainsAny(token, "'\\\r\n") {
+		return errors.New("token contains quotes, backslashes or line breaks")
+	}
+	// the interactive mode reads the command from stdin, keeping the token
+	// out of the process list
+	command := fmt.Sprintf("add-generic-password -U -s %s -a '%s' -w '%s'\n", KeychainService, host, token)
+	_, err := runCommandInput(ctx, command, "security", "-i")
+	return err
+}
+
+func (osKeychain) get(ctx context.Context, host string) (string, error) {
+	token, err := runCommand(ctx, "", "security", "find-generic-password", "-s", KeychainService, "-a", host, "-w")
+	return token, securityError(err)
+}
+
+func (osKeychain) delete(ctx context.Context, host string) error {
+	_, err := runCommand(ctx, "", "security", "delete-generic-password", "-s", KeychainService, "-a", host)
+	return securityError(err)
+}
+
+// securityError turns the item not found exit code into ErrTokenNotFound
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return ErrTokenNotFound
+	}
+	return err
+}
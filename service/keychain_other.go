@@ -0,0 +1,49 @@
+//go:build !darwin && !windows
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// osKeychain stores the tokens in the Secret Service (GNOME Keyring,
+// KWallet) through secret-tool, with the service and host as attributes
+type osKeychain struct{}
+
+func (osKeychain) set(ctx context.Context, host string, token string) error {
+	// secret-tool reads the secret from stdin, keeping it out of the process list
+	_, err := runCommandInput(ctx, token, "secret-tool", "store", "--label", KeychainService+" token for "+host,
+		"service", KeychainService, "host", host)
+	return err
+}
+
+func (osKeychain) get(ctx context.Context, host string) (string, error) {
+	cmd := exec.CommandContext(ctx, "secret-tool", "lookup", "service", KeychainService, "host", host)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	// lookup exits with 1 and prints nothing for a missing item, and explains
+	// on stderr when the Secret Service is unavailable
+	var exitErr *exec.ExitError
+	if (errors.As(err, &exitErr) && stderr.Len() == 0) || (err == nil && stdout.Len() == 0) {
+		return "", ErrTokenNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret-tool lookup: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+func (k osKeychain) delete(ctx context.Context, host string) error {
+	// clear succeeds whether or not an item matched
+	if _, err := k.get(ctx, host); err != nil {
+		return err
+	}
+	_, err := runCommand(ctx, "", "secret-tool", "clear", "service", KeychainService, "host", host)
+	return err
+}
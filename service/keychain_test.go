@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// memoryKeychain is a keychainStore kept in memory
+type memoryKeychain map[string]string
+
+func (k memoryKeychain) set(_ context.Context, host string, token string) error {
+	k[host] = token
+	return nil
+}
+
+func (k memoryKeychain) get(_ context.Context, host string) (string, error) {
+	token, ok := k[host]
+	if !ok {
+		return "", ErrTokenNotFound
+	}
+	return token, nil
+}
+
+func (k memoryKeychain) delete(_ context.Context, host string) error {
+	if _, ok := k[host]; !ok {
+		return ErrTokenNotFound
+	}
+	delete(k, host)
+	return nil
+}
+
+func useMemoryKeychain(t *testing.T) memoryKeychain {
+	t.Helper()
+	store := memoryKeychain{}
+	previous := keychain
+	keychain = store
+	t.Cleanup(func() { keychain = previous })
+	return store
+}
+
+func TestKeychainTokens(t *testing.T) {
+	store := useMemoryKeychain(t)
+	ctx := context.Background()
+
+	if err := StoreToken(ctx, " GitHub.com ", "gh-token"); err != nil {
+		t.Fatalf("StoreToken() error = %v", err)
+	}
+	if store["github.com"] != "gh-token" {
+		t.Errorf("stored tokens = %v, want gh-token under github.com", store)
+	}
+	token, err := LoadToken(ctx, "github.com")
+	if err != nil || token != "gh-token" {
+		t.Errorf("LoadToken() = %q, %v, want gh-token", token, err)
+	}
+
+	for _, host := range []string{"https://github.com/org", "github.com' -w 'x", "github.com\nadd-generic-password"} {
+		if err := StoreToken(ctx, host, "gh-token"); err == nil {
+			t.Errorf("StoreToken() accepted %q as host", host)
+		}
+	}
+	if err := StoreToken(ctx, "Git.MyCorp-1.com:8443", "gh-token"); err != nil || store["git.mycorp-1.com:8443"] != "gh-token" {
+		t.Errorf("StoreToken() with port = %v, stored tokens = %v", err, store)
+	}
+	if err := StoreToken(ctx, "gitlab.com", ""); err == nil {
+		t.Error("StoreToken() accepted an empty token")
+	}
+
+	if err := DeleteToken(ctx, "github.com"); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if _, err := LoadToken(ctx, "github.com"); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("LoadToken() after delete error = %v, want ErrTokenNotFound", err)
+	}
+	if err := DeleteToken(ctx, "github.com"); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("DeleteToken() of a missing token error = %v, want ErrTokenNotFound", err)
+	}
+}
+
+func TestKeychainAuth(t *testing.T) {
+	store := useMemoryKeychain(t)
+	store["git.example.com"] = "secret"
+	store["git.example.com:8443"] = "port-secret"
+
+	tests := []struct {
+		url       string
+		wantUser  string
+		wantToken string
+		wantNil   bool
+	}{
+		{url: "https://git.example.com/org/repo.git", wantUser: "git", wantToken: "secret"},
+		{url: "https://deploy@git.example.com/org/repo.git", wantUser: "deploy", wantToken: "secret"},
+		{url: "https://git.example.com:8443/org/repo.git", wantUser: "git", wantToken: "port-secret"},
+		{url: "https://git.example.com:9443/org/repo.git", wantUser: "git", wantToken: "secret"},
+		{url: "https://other.example.com/org/repo.git", wantNil: true},
+		{url: "http://git.example.com/org/repo.git", wantNil: true},
+		{url: "git@git.example.com:org/repo.git", wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			endpoint, err := transport.NewEndpoint(tt.url)
+			if err != nil {
+				t.Fatalf("NewEndpoint() error = %v", err)
+			}
+			auth, err := keychainAuth(context.Background(), endpoint)
+			if err != nil {
+				t.Fatalf("keychainAuth() error = %v", err)
+			}
+			if tt.wantNil {
+				if auth != nil {
+					t.Errorf("keychainAuth() = %v, want nil", auth)
+				}
+				return
+			}
+			basic, ok := auth.(*githttp.BasicAuth)
+			if !ok || basic.Username != tt.wantUser || basic.Password != tt.wantToken {
+				t.Errorf("keychainAuth() = %v, want basic auth %s/%s", auth, tt.wantUser, tt.wantToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+//go:build windows
+
+package service
+
+import (
+	"context"
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+// credential mirrors CREDENTIALW
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// osKeychain stores the tokens as generic credentials of the Windows
+// Credential Manager, targeted goktor:<host>
+type osKeychain struct{}
+
+func credentialTarget(host string) (*uint16, error) {
+	return windows.UTF16PtrFromString(KeychainService + ":" + host)
+}
+
+func (osKeychain) set(_ context.Context, host string, token string) error {
+	target, err := credentialTarget(host)
+	if err != nil {
+		return err
+	}
+	userName, err := windows.UTF16PtrFromString(host)
+	if err != nil {
+		return err
+	}
+	blob := []byte(token)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if ok, _, e := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); ok == 0 {
+		return e
+	}
+	return nil
+}
+
+func (osKeychain) get(_ context.Context, host string) (string, error) {
+	target, err := credentialTarget(host)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	if ok, _, e := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred))); ok == 0 {
+		return "", credentialError(e)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (osKeychain) delete(_ context.Context, host string) error {
+	target, err := credentialTarget(host)
+	if err != nil {
+		return err
+	}
+	if ok, _, e := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); ok == 0 {
+		return credentialError(e)
+	}
+	return nil
+}
+
+// credentialError turns ERROR_NOT_FOUND into ErrTokenNotFound
+func credentialError(err error) error {
+	if errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return ErrTokenNotFound
+	}
+	return err
+}
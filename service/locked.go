@@ -0,0 +1,36 @@
+package service
+
+import (
+	"os"
+	"sync"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// lockedFiles collects the files skipped because another process holds them
+// open or locked (pagefile.sys, hiberfil.sys, mail stores...)
+type lockedFiles struct {
+	mu    sync.Mutex
+	files []model.FileSystem
+}
+
+func (l *lockedFiles) add(file model.FileSystem) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.files = append(l.files, file)
+}
+
+func (l *lockedFiles) list() []model.FileSystem {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]model.FileSystem(nil), l.files...)
+}
+
+// entryMetadataSize returns the size recorded by the directory enumerator,
+// which does not require opening the file. It is 0 when unknown.
+func entryMetadataSize(entry os.DirEntry) int64 {
+	if e, ok := entry.(*dirEntry); ok && e.hasSize {
+		return e.size
+	}
+	return 0
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package service
+
+// isLockedError reports whether err is a sharing or lock violation. Locks are
+// advisory outside Windows and never prevent reading file metadata.
+func isLockedError(err error) bool {
+	return false
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package service
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isLockedError reports whether err is a sharing or lock violation raised
+// because another process holds the file open exclusively.
+func isLockedError(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+}
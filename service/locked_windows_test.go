@@ -0,0 +1,52 @@
+//go:build windows
+
+package service
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestIsLockedError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"sharing violation", &os.PathError{Op: "CreateFile", Path: `C:\pagefile.sys`, Err: windows.ERROR_SHARING_VIOLATION}, true},
+		{"lock violation", &os.PathError{Op: "CreateFile", Path: `C:\mail.ost`, Err: windows.ERROR_LOCK_VIOLATION}, true},
+		{"access denied", &os.PathError{Op: "CreateFile", Path: `C:\secret`, Err: windows.ERROR_ACCESS_DENIED}, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isLockedError(tt.err); got != tt.want {
+				t.Errorf("isLockedError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// lockedEntry is a directory entry whose file another process holds open
+type lockedEntry struct{ dirEntry }
+
+func (e *lockedEntry) Info() (fs.FileInfo, error) {
+	return nil, &os.PathError{Op: "GetFileAttributesEx", Path: filepath.Join(e.parent, e.name), Err: windows.ERROR_SHARING_VIOLATION}
+}
+
+func TestToFileSystemModelLocked(t *testing.T) {
+	service := NewFileService().(*FileSystemService)
+	entry := &lockedEntry{dirEntry{parent: `C:\`, name: "pagefile.sys", size: 1 << 30, hasSize: true}}
+	file := service.toFileSystemModel(`C:\`, entry)
+	if !file.Locked || file.Size != 1<<30 {
+		t.Errorf("file = %+v, want a locked file with the metadata size", file)
+	}
+	if locked := service.LockedFiles(); len(locked) != 1 || locked[0].Name != "pagefile.sys" {
+		t.Errorf("LockedFiles() = %+v, want pagefile.sys", locked)
+	}
+}
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
 )
 
 // Logger interface for flexible logging
@@ -10,56 +14,101 @@ type Logger interface {
 	Warn(msg string, args ...interface{})
 	Error(msg string, args ...interface{})
 	Debug(msg string, args ...interface{})
+	// With returns a child logger that prefixes every entry with the given key/value pairs
+	With(args ...interface{}) Logger
 }
 
+// Supported log formats
 const (
-	ErrorLevel = iota
-	WarnLevel
-	InfoLevel
-	DebugLevel
+	LogFormatText = "text"
+	LogFormatJSON = "json"
 )
 
-// DefaultLogger implements Logger interface using fmt
+// LoggerOptions configures the logger returned by NewLoggerWithOptions
+type LoggerOptions struct {
+	Debug bool
+	// Quiet only logs errors; it wins over Debug
+	Quiet bool
+	// Format is LogFormatText (default) or LogFormatJSON
+	Format string
+	// Output receives the log entries, os.Stdout when nil
+	Output io.Writer
+}
+
+// DefaultLogger implements Logger interface using log/slog.
+// The zero value only reports errors, on stdout.
 type DefaultLogger struct {
-	level int
+	logger *slog.Logger
 }
 
+var errorOnlyLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
 func NewDefaultLogger() Logger {
-	return &DefaultLogger{level: InfoLevel}
+	return NewLogger(false)
 }
 
 func NewLogger(debug bool) Logger {
+	logger, _ := NewLoggerWithOptions(LoggerOptions{Debug: debug})
+	return logger
+}
+
+// NewLoggerWithOptions builds a slog-backed logger writing text or JSON entries
+func NewLoggerWithOptions(opts LoggerOptions) (Logger, error) {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case opts.Quiet:
+		level = slog.LevelError
+	case opts.Debug:
+		level = slog.LevelDebug
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
 
-	if debug {
-		return &DefaultLogger{level: DebugLevel}
+	var handler slog.Handler
+	switch opts.Format {
+	case "", LogFormatText:
+		handler = slog.NewTextHandler(out, handlerOpts)
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected %q or %q", opts.Format, LogFormatText, LogFormatJSON)
 	}
 
-	return &DefaultLogger{level: InfoLevel}
+	return &DefaultLogger{logger: slog.New(handler)}, nil
 }
-func (l *DefaultLogger) Info(msg string, args ...interface{}) {
-	if l.level < InfoLevel {
-		return
+
+func (l *DefaultLogger) base() *slog.Logger {
+	if l.logger == nil {
+		return errorOnlyLogger
 	}
-	fmt.Printf("ℹ [INFO] %s %v\n", msg, args)
+	return l.logger
+}
+
+// With returns a child logger carrying the given key/value pairs
+func (l *DefaultLogger) With(args ...interface{}) Logger {
+	return &DefaultLogger{logger: l.base().With(args...)}
+}
+
+func (l *DefaultLogger) log(level slog.Level, msg string, args []interface{}) {
+	l.base().Log(context.Background(), level, msg, args...)
+}
+
+func (l *DefaultLogger) Info(msg string, args ...interface{}) {
+	l.log(slog.LevelInfo, msg, args)
 }
 
 func (l *DefaultLogger) Warn(msg string, args ...interface{}) {
-	if l.level < WarnLevel {
-		return
-	}
-	fmt.Printf("⚠ [WARN] %s %v\n", msg, args)
+	l.log(slog.LevelWarn, msg, args)
 }
 
 func (l *DefaultLogger) Error(msg string, args ...interface{}) {
-	if l.level < ErrorLevel {
-		return
-	}
-	fmt.Printf("✗ [ERROR] %s %v\n", msg, args)
+	l.log(slog.LevelError, msg, args)
 }
 
 func (l *DefaultLogger) Debug(msg string, args ...interface{}) {
-	if l.level < DebugLevel {
-		return
-	}
-	fmt.Printf("🔍 [DEBUG] %s %v\n", msg, args)
+	l.log(slog.LevelDebug, msg, args)
 }
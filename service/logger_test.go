@@ -0,0 +1,80 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDefaultLogger_With(t *testing.T) {
+	var buf bytes.Buffer
+	parent, err := NewLoggerWithOptions(LoggerOptions{Debug: true, Format: LogFormatJSON, Output: &buf})
+	if err != nil {
+		t.Fatalf("NewLoggerWithOptions() error = %v", err)
+	}
+	child := parent.With("repo", "/tmp/repo").With("branch", "main")
+
+	child.Debug("updated", "error", "boom")
+	parent.Info("parent")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d entries, want 2: %q", len(lines), buf.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid JSON entry %q: %v", lines[0], err)
+	}
+	want := map[string]interface{}{"level": "DEBUG", "msg": "updated", "repo": "/tmp/repo", "branch": "main", "error": "boom"}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("entry[%q] = %v, want %v", key, entry[key], value)
+		}
+	}
+
+	if strings.Contains(lines[1], "repo") {
+		t.Errorf("parent entry carries child fields: %s", lines[1])
+	}
+}
+
+func TestNewLoggerWithOptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLoggerWithOptions(LoggerOptions{Output: &buf})
+	if err != nil {
+		t.Fatalf("NewLoggerWithOptions() error = %v", err)
+	}
+
+	logger.Debug("hidden")
+	logger.Warn("shown", "path", "/tmp")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("debug entry logged without Debug option: %q", out)
+	}
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "path=/tmp") {
+		t.Errorf("text entry = %q, want level and key/value pair", out)
+	}
+
+	if _, err := NewLoggerWithOptions(LoggerOptions{Format: "xml"}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestNewLoggerWithOptions_Quiet(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLoggerWithOptions(LoggerOptions{Debug: true, Quiet: true, Output: &buf})
+	if err != nil {
+		t.Fatalf("NewLoggerWithOptions() error = %v", err)
+	}
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("failed")
+
+	out := buf.String()
+	if strings.Count(strings.TrimSpace(out), "\n") != 0 || !strings.Contains(out, "msg=failed") {
+		t.Errorf("quiet logger output = %q, want the error entry only", out)
+	}
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"unicode/utf16"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// ErrMFTUnavailable is returned when the volume cannot be scanned through its
+// master file table (not NTFS, not a volume root, missing privileges, other OS).
+var ErrMFTUnavailable = errors.New("NTFS MFT scan unavailable")
+
+const (
+	mftRootRecord  = 5
+	mftRecordInUse = 0x01
+	mftRecordIsDir = 0x02
+	attrFileName   = 0x30
+	attrData       = 0x80
+	attrEnd        = 0xFFFFFFFF
+	// nonResidentHeaderSize is the length of a non-resident attribute header,
+	// up to the initialized size at 0x38
+	nonResidentHeaderSize = 0x40
+	fileNameDOS           = 2
+	mftReadBatchBytes     = 4 * 1024 * 1024
+)
+
+type mftRecord struct {
+	parent uint64
+	name   string
+	size   int64
+	isDir  bool
+}
+
+type dataRun struct {
+	lcn    int64
+	length int64
+	sparse bool
+}
+
+type ntfsVolume struct {
+	r               io.ReaderAt
+	bytesPerSector  int
+	bytesPerCluster int64
+	recordSize      int
+	mftOffset       int64
+}
+
+// scanMFT reads every in-use record of the master file table and rebuilds the
+// directory tree rooted at root from the parent references. The scan stops
+// with the context error once ctx is cancelled.
+func scanMFT(ctx context.Context, r io.ReaderAt, root string) (model.Directory, error) {
+	vol, err := readNTFSBoot(r)
+	if err != nil {
+		return model.Directory{}, err
+	}
+
+	records, err := vol.readRecords(ctx)
+	if err != nil {
+		return model.Directory{}, err
+	}
+
+	children := make(map[uint64][]uint64)
+	for ref, rec := range records {
+		if ref == mftRootRecord {
+			continue
+		}
+		children[rec.parent] = append(children[rec.parent], ref)
+	}
+
+	return buildMFTDirectory(records, children, mftRootRecord, filepath.Clean(root)), nil
+}
+
+func readNTFSBoot(r io.ReaderAt) (*ntfsVolume, error) {
+	boot := make([]byte, 512)
+	if _, err := r.ReadAt(boot, 0); err != nil {
+		return nil, fmt.Errorf("failed to read boot sector: %w", err)
+	}
+	if string(boot[3:11]) != "NTFS    " {
+		return nil, fmt.Errorf("%w: volume is not NTFS", ErrMFTUnavailable)
+	}
+
+	bytesPerSector := int(binary.LittleEndian.Uint16(boot[0x0B:]))
+	bytesPerCluster := int64(bytesPerSector) * int64(boot[0x0D])
+	mftCluster := int64(binary.LittleEndian.Uint64(boot[0x30:]))
+
+	recordSize := int(int8(boot[0x40]))
+	if recordSize < 0 {
+		recordSize = 1 << -recordSize
+	} else {
+		recordSize *= int(bytesPerCluster)
+	}
+	if bytesPerSector == 0 || bytesPerCluster == 0 || recordSize < bytesPerSector {
+		return nil, fmt.Errorf("%w: invalid boot sector geometry", ErrMFTUnavailable)
+	}
+
+	return &ntfsVolume{
+		r:               r,
+		bytesPerSector:  bytesPerSector,
+		bytesPerCluster: bytesPerCluster,
+		recordSize:      recordSize,
+		mftOffset:       mftCluster * bytesPerCluster,
+	}, nil
+}
+
+// readRecords locates the $MFT data runs from record 0 and parses all records,
+// checking ctx before each batch of records is read.
+func (v *ntfsVolume) readRecords(ctx context.Context) (map[uint64]mftRecord, error) {
+	first := make([]byte, v.recordSize)
+	if _, err := v.r.ReadAt(first, v.mftOffset); err != nil {
+		return nil, fmt.Errorf("failed to read $MFT record: %w", err)
+	}
+	if err := applyFixup(first, v.bytesPerSector); err != nil {
+		return nil, err
+	}
+	runs, err := mftDataRuns(first)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[uint64]mftRecord)
+	var ref uint64
+	for _, run := range runs {
+		runBytes := run.length * v.bytesPerCluster
+		for done := int64(0); done < runBytes; {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			chunk := min(runBytes-done, mftReadBatchBytes)
+			chunk -= chunk % int64(v.recordSize)
+			if chunk == 0 {
+				break
+			}
+			buf := make([]byte, chunk)
+			if !run.sparse {
+				if _, err := v.r.ReadAt(buf, run.lcn*v.bytesPerCluster+done); err != nil && !errors.Is(err, io.EOF) {
+					return nil, fmt.Errorf("failed to read MFT records: %w", err)
+				}
+			}
+			for off := 0; off < len(buf); off += v.recordSize {
+				if rec, ok := parseMFTRecord(buf[off:off+v.recordSize], v.bytesPerSector); ok {
+					records[ref] = rec
+				}
+				ref++
+			}
+			done += chunk
+		}
+	}
+	return records, nil
+}
+
+// applyFixup restores the last two bytes of each sector from the update
+// sequence array, as NTFS stores them there to detect torn writes.
+func applyFixup(record []byte, bytesPerSector int) error {
+	if len(record) < 8 || string(record[0:4]) != "FILE" {
+		return fmt.Errorf("invalid MFT record signature")
+	}
+	usaOffset := int(binary.LittleEndian.Uint16(record[4:]))
+	usaCount := int(binary.LittleEndian.Uint16(record[6:]))
+	if usaOffset+usaCount*2 > len(record) {
+		return fmt.Errorf("invalid MFT update sequence")
+	}
+	check := record[usaOffset : usaOffset+2]
+	for i := 1; i < usaCount; i++ {
+		end := i * bytesPerSector
+		if end > len(record) {
+			break
+		}
+		if record[end-2] != check[0] || record[end-1] != check[1] {
+			return fmt.Errorf("MFT record torn at sector %d", i)
+		}
+		copy(record[end-2:end], record[usaOffset+i*2:usaOffset+i*2+2])
+	}
+	return nil
+}
+
+func mftDataRuns(record []byte) ([]dataRun, error) {
+	var runs []dataRun
+	var runsErr error
+	err := forEachAttribute(record, func(attrType uint32, attr []byte) bool {
+		if attrType != attrData || attr[8] == 0 {
+			return true
+		}
+		if len(attr) < nonResidentHeaderSize {
+			runsErr = fmt.Errorf("corrupted $MFT data attribute")
+			return false
+		}
+		runOffset := int(binary.LittleEndian.Uint16(attr[0x20:]))
+		if runOffset > len(attr) {
+			runsErr = fmt.Errorf("corrupted $MFT data runs offset %d", runOffset)
+			return false
+		}
+		runs, runsErr = decodeDataRuns(attr[runOffset:])
+		return false
+	})
+	if err == nil {
+		err = runsErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("$MFT has no data runs")
+	}
+	return runs, nil
+}
+
+// decodeDataRuns decodes a non-resident attribute mapping pairs array,
+// failing on a run header whose sizes do not fit in 8 bytes or in b.
+func decodeDataRuns(b []byte) ([]dataRun, error) {
+	var runs []dataRun
+	var lcn int64
+	for len(b) > 0 && b[0] != 0 {
+		lengthSize := int(b[0] & 0x0F)
+		offsetSize := int(b[0] >> 4)
+		if lengthSize == 0 || lengthSize > 8 || offsetSize > 8 || 1+lengthSize+offsetSize > len(b) {
+			return nil, fmt.Errorf("corrupted data run header 0x%02x", b[0])
+		}
+		length := readLittleEndian(b[1:1+lengthSize], false)
+		run := dataRun{length: length, sparse: offsetSize == 0}
+		if !run.sparse {
+			lcn += readLittleEndian(b[1+lengthSize:1+lengthSize+offsetSize], true)
+			run.lcn = lcn
+		}
+		runs = append(runs, run)
+		b = b[1+lengthSize+offsetSize:]
+	}
+	return runs, nil
+}
+
+func readLittleEndian(b []byte, signed bool) int64 {
+	var v int64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | int64(b[i])
+	}
+	if signed && len(b) > 0 && len(b) < 8 && b[len(b)-1]&0x80 != 0 {
+		v -= 1 << (8 * len(b))
+	}
+	return v
+}
+
+func parseMFTRecord(record []byte, bytesPerSector int) (mftRecord, bool) {
+	if applyFixup(record, bytesPerSector) != nil {
+		return mftRecord{}, false
+	}
+	flags := binary.LittleEndian.Uint16(record[0x16:])
+	if flags&mftRecordInUse == 0 || binary.LittleEndian.Uint64(record[0x20:]) != 0 {
+		return mftRecord{}, false // free or extension record
+	}
+
+	rec := mftRecord{isDir: flags&mftRecordIsDir != 0}
+	hasName := false
+	err := forEachAttribute(record, func(attrType uint32, attr []byte) bool {
+		switch attrType {
+		case attrFileName:
+			content := residentContent(attr)
+			if len(content) < 0x42 {
+				return true
+			}
+			nameLen := int(content[0x40])
+			if content[0x41] == fileNameDOS && hasName || 0x42+nameLen*2 > len(content) {
+				return true
+			}
+			u := make([]uint16, nameLen)
+			for i := range u {
+				u[i] = binary.LittleEndian.Uint16(content[0x42+i*2:])
+			}
+			rec.parent = binary.LittleEndian.Uint64(content) & 0xFFFFFFFFFFFF
+			rec.name = string(utf16.Decode(u))
+			hasName = true
+		case attrData:
+			if attr[9] != 0 {
+				return true // named stream
+			}
+			if attr[8] == 0 {
+				rec.size = int64(binary.LittleEndian.Uint32(attr[0x10:]))
+			} else if len(attr) >= nonResidentHeaderSize && binary.LittleEndian.Uint64(attr[0x10:]) == 0 {
+				rec.size = int64(binary.LittleEndian.Uint64(attr[0x30:]))
+			}
+		}
+		return true
+	})
+	return rec, err == nil && hasName
+}
+
+func residentContent(attr []byte) []byte {
+	if attr[8] != 0 || len(attr) < 0x18 {
+		return nil
+	}
+	length := int(binary.LittleEndian.Uint32(attr[0x10:]))
+	offset := int(binary.LittleEndian.Uint16(attr[0x14:]))
+	if offset+length > len(attr) {
+		return nil
+	}
+	return attr[offset : offset+length]
+}
+
+func forEachAttribute(record []byte, fn func(attrType uint32, attr []byte) bool) error {
+	off := int(binary.LittleEndian.Uint16(record[0x14:]))
+	for off+8 <= len(record) {
+		attrType := binary.LittleEndian.Uint32(record[off:])
+		if attrType == attrEnd {
+			return nil
+		}
+		attrLen := int(binary.LittleEndian.Uint32(record[off+4:]))
+		if attrLen < 0x18 || off+attrLen > len(record) {
+			return fmt.Errorf("corrupted MFT attribute")
+		}
+		if !fn(attrType, record[off:off+attrLen]) {
+			return nil
+		}
+		off += attrLen
+	}
+	return nil
+}
+
+func buildMFTDirectory(records map[uint64]mftRecord, children map[uint64][]uint64, ref uint64, fullPath string) model.Directory {
+	dir := model.Directory{FileSystem: model.FileSystem{
+		Name:     filepath.Base(fullPath),
+		FullPath: fullPath,
+		IsDir:    true,
+	}}
+	for _, childRef := range children[ref] {
+		child := records[childRef]
+		childPath := filepath.Join(fullPath, child.name)
+		if child.isDir {
+			sub := buildMFTDirectory(records, children, childRef, childPath)
+			dir.SubDirs = append(dir.SubDirs, sub)
+			dir.Size += sub.Size
+			continue
+		}
+		dir.Files = append(dir.Files, model.FileSystem{Name: child.name, FullPath: childPath, Size: child.size, Extension: model.FileExtension(child.name)})
+		dir.Size += child.size
+	}
+	dir.UpdateCounts()
+	return dir
+}
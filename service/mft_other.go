@@ -0,0 +1,15 @@
+//go:build !windows
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// scanVolumeMFT is only supported on Windows.
+func scanVolumeMFT(ctx context.Context, root string) (model.Directory, error) {
+	return model.Directory{}, fmt.Errorf("%w: only supported on Windows", ErrMFTUnavailable)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestDecodeDataRuns(t *testing.T) {
+	// 0x21: 1 byte length, 2 bytes offset. Second run offset is relative and negative.
+	runs, err := decodeDataRuns([]byte{0x21, 0x10, 0x00, 0x01, 0x21, 0x08, 0x00, 0xFF, 0x01, 0x04, 0x00})
+	if err != nil {
+		t.Fatalf("decodeDataRuns() error = %v", err)
+	}
+
+	want := []dataRun{
+		{lcn: 0x100, length: 0x10},
+		{lcn: 0x100 - 0x100, length: 0x08},
+		{length: 0x04, sparse: true},
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("got %d runs, want %d", len(runs), len(want))
+	}
+	for i := range want {
+		if runs[i] != want[i] {
+			t.Errorf("run %d = %+v, want %+v", i, runs[i], want[i])
+		}
+	}
+}
+
+func TestDecodeDataRunsCorrupted(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x21, 0x10, 0x00},       // truncated offset
+		{0x91, 0x10, 0x00},       // offset size over 8 bytes
+		{0x20, 0x00, 0x00, 0x00}, // no length
+	} {
+		if _, err := decodeDataRuns(b); err == nil {
+			t.Errorf("decodeDataRuns(% x) error = nil, want corrupted run", b)
+		}
+	}
+}
+
+// TestMFTDataRunsCorrupted checks a data attribute whose runs offset or
+// header lies outside the record is an error rather than a panic
+func TestMFTDataRunsCorrupted(t *testing.T) {
+	record := func(attrLen int, runOffset uint16) []byte {
+		b := make([]byte, 0x38+attrLen+8)
+		binary.LittleEndian.PutUint16(b[0x14:], 0x38)
+		attr := b[0x38:]
+		binary.LittleEndian.PutUint32(attr, attrData)
+		binary.LittleEndian.PutUint32(attr[4:], uint32(attrLen))
+		attr[8] = 1
+		if attrLen >= 0x22 {
+			binary.LittleEndian.PutUint16(attr[0x20:], runOffset)
+		}
+		binary.LittleEndian.PutUint32(attr[attrLen:], attrEnd)
+		return b
+	}
+	for name, b := range map[string][]byte{
+		"runs offset past the attribute": record(0x48, 0x400),
+		"truncated header":               record(0x20, 0),
+	} {
+		if _, err := mftDataRuns(b); err == nil {
+			t.Errorf("%s: mftDataRuns() error = nil", name)
+		}
+	}
+}
+
+func TestApplyFixup(t *testing.T) {
+	record := make([]byte, 1024)
+	copy(record, "FILE")
+	record[4], record[6] = 0x30, 3 // usa offset 0x30, 3 entries
+	record[0x30], record[0x31] = 0xAB, 0xCD
+	record[0x32], record[0x33] = 0x11, 0x22
+	record[0x34], record[0x35] = 0x33, 0x44
+	record[510], record[511] = 0xAB, 0xCD
+	record[1022], record[1023] = 0xAB, 0xCD
+
+	if err := applyFixup(record, 512); err != nil {
+		t.Fatalf("applyFixup() error = %v", err)
+	}
+	if !bytes.Equal(record[510:512], []byte{0x11, 0x22}) || !bytes.Equal(record[1022:1024], []byte{0x33, 0x44}) {
+		t.Errorf("fixup not applied: %x %x", record[510:512], record[1022:1024])
+	}
+}
+
+func TestScanMFTRejectsNonNTFS(t *testing.T) {
+	_, err := scanMFT(context.Background(), bytes.NewReader(make([]byte, 4096)), "/")
+	if !errors.Is(err, ErrMFTUnavailable) {
+		t.Errorf("got error %v, want ErrMFTUnavailable", err)
+	}
+}
+
+// TestScanMFTCancelled checks the scan of a valid volume stops with the
+// context error before reading the records
+func TestScanMFTCancelled(t *testing.T) {
+	volume := make([]byte, 4096)
+	copy(volume[3:], "NTFS    ")
+	binary.LittleEndian.PutUint16(volume[0x0B:], 512)
+	volume[0x0D] = 1                                // one sector per cluster
+	binary.LittleEndian.PutUint64(volume[0x30:], 2) // $MFT at cluster 2
+	volume[0x40] = 0xF6                             // 1024 byte records
+
+	record := volume[1024:2048]
+	copy(record, "FILE")
+	record[4], record[6] = 0x30, 3
+	binary.LittleEndian.PutUint16(record[0x14:], 0x38)
+	attr := record[0x38:]
+	binary.LittleEndian.PutUint32(attr, attrData)
+	binary.LittleEndian.PutUint32(attr[4:], 0x48)
+	attr[8] = 1
+	binary.LittleEndian.PutUint16(attr[0x20:], 0x40)
+	copy(attr[0x40:], []byte{0x11, 0x02, 0x02, 0x00}) // 2 clusters at cluster 2
+	binary.LittleEndian.PutUint32(attr[0x48:], attrEnd)
+
+	if _, err := scanMFT(context.Background(), bytes.NewReader(volume), "/"); err != nil {
+		t.Fatalf("scanMFT() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := scanMFT(ctx, bytes.NewReader(volume), "/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,67 @@
+//go:build windows
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/nanaki-93/goktor/model"
+	"golang.org/x/sys/windows"
+)
+
+// scanVolumeMFT opens the raw volume behind a drive root such as `C:\` and
+// enumerates it through the NTFS master file table. Administrator rights are
+// required to open the volume.
+func scanVolumeMFT(ctx context.Context, root string) (model.Directory, error) {
+	volume := filepath.VolumeName(root)
+	if volume == "" || strings.Trim(root[len(volume):], `\/`) != "" {
+		return model.Directory{}, fmt.Errorf("%w: %s is not a volume root", ErrMFTUnavailable, root)
+	}
+
+	devicePath, err := windows.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return model.Directory{}, err
+	}
+	handle, err := windows.CreateFile(devicePath, windows.GENERIC_READ,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return model.Directory{}, fmt.Errorf("%w: failed to open volume %s (administrator rights required): %v", ErrMFTUnavailable, volume, err)
+	}
+	defer windows.CloseHandle(handle)
+
+	return scanMFT(ctx, &sectorReader{handle: handle, sectorSize: 4096}, volume+`\`)
+}
+
+// sectorReader turns arbitrary ReadAt calls into sector-aligned reads, which
+// raw volume handles require.
+type sectorReader struct {
+	handle     windows.Handle
+	sectorSize int64
+}
+
+func (s *sectorReader) ReadAt(p []byte, off int64) (int, error) {
+	start := off - off%s.sectorSize
+	end := off + int64(len(p))
+	if rem := end % s.sectorSize; rem != 0 {
+		end += s.sectorSize - rem
+	}
+
+	buf := make([]byte, end-start)
+	if _, err := windows.Seek(s.handle, start, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var read uint32
+	if err := windows.ReadFile(s.handle, buf, &read, nil); err != nil {
+		return 0, err
+	}
+
+	n := copy(p, buf[off-start:min(int64(read), end-start)])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// notifyTimeout bounds the notification helper, which must never hold up the
+// command that finished
+const notifyTimeout = 10 * time.Second
+
+// windowsToastAppID is the application the Windows toasts are shown for;
+// PowerShell is registered on every installation
+const windowsToastAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// windowsToastScript shows a toast with the title and message passed in the
+// environment, so they need no quoting
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:GOKTOR_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:GOKTOR_NOTIFY_MESSAGE)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($env:GOKTOR_NOTIFY_APP).Show([Windows.UI.Notifications.ToastNotification]::new($template))`
+
+// Notify shows a desktop notification: a toast on Windows, the notification
+// center on macOS and libnotify (notify-send) elsewhere
+func Notify(ctx context.Context, title, message string) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	cmd, err := notifyCommand(ctx, runtime.GOOS, title, message)
+	if err != nil {
+		return err
+	}
+	if cmd.Err != nil {
+		return fmt.Errorf("desktop notifications need %s: %w", cmd.Args[0], cmd.Err)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to send notification with %s: %w: %s", cmd.Args[0], err, out)
+	}
+	return nil
+}
+
+// notifyCommand returns the command showing the notification on goos. The
+// title and message are passed as separate arguments or in the environment,
+// never interpolated in a script.
+func notifyCommand(ctx context.Context, goos, title, message string) (*exec.Cmd, error) {
+	switch goos {
+	case "windows":
+		cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		cmd.Env = append(os.Environ(),
+			"GOKTOR_NOTIFY_TITLE="+title, "GOKTOR_NOTIFY_MESSAGE="+message, "GOKTOR_NOTIFY_APP="+windowsToastAppID)
+		return cmd, nil
+	case "darwin":
+		return exec.CommandContext(ctx, "osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message), nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return exec.CommandContext(ctx, "notify-send", "--app-name=goktor", "--", title, message), nil
+	default:
+		return nil, fmt.Errorf("desktop notifications are not supported on %s", goos)
+	}
+}
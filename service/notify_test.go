@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestNotifyCommand(t *testing.T) {
+	title, message := "goktor", `done "quoted" $(rm -rf ~)`
+	for _, goos := range []string{"linux", "darwin", "windows"} {
+		cmd, err := notifyCommand(context.Background(), goos, title, message)
+		if err != nil {
+			t.Fatalf("notifyCommand(%s) error = %v", goos, err)
+		}
+		if goos == "windows" {
+			if !slices.Contains(cmd.Env, "GOKTOR_NOTIFY_MESSAGE="+message) {
+				t.Errorf("windows command does not pass the message in its environment")
+			}
+			continue
+		}
+		// the message is an argument of its own, never part of a script
+		if got := cmd.Args[len(cmd.Args)-2:]; got[0] != title || got[1] != message {
+			t.Errorf("notifyCommand(%s) args end with %q, want the title and message", goos, got)
+		}
+	}
+	if _, err := notifyCommand(context.Background(), "plan9", title, message); err == nil {
+		t.Error("notifyCommand(plan9) succeeded, want an error")
+	}
+}
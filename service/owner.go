@@ -0,0 +1,36 @@
+package service
+
+import (
+	"fmt"
+	"os/user"
+	"runtime"
+	"strconv"
+)
+
+// LookupOwner returns the id files owned by the user name or id are reported
+// with: the numeric UID on Unix, the SID on Windows
+func LookupOwner(name string) (string, error) {
+	if u, err := user.Lookup(name); err == nil {
+		return u.Uid, nil
+	}
+	if u, err := user.LookupId(name); err == nil {
+		return u.Uid, nil
+	}
+	// files may belong to ids without an account, such as on NFS shares
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil && runtime.GOOS != "windows" {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown user %q", name)
+}
+
+// ownerName returns the user name of an owner id, the id itself when it has
+// no account
+func ownerName(owner string) string {
+	if owner == "" {
+		return "unknown"
+	}
+	if u, err := user.LookupId(owner); err == nil {
+		return u.Username
+	}
+	return owner
+}
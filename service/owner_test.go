@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestFileSystemService_OwnedBy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("numeric owner ids are Unix only")
+	}
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "file.txt"), []byte("content"), 0640)
+	uid := strconv.Itoa(os.Getuid())
+
+	owner, err := LookupOwner(uid)
+	if err != nil || owner != uid {
+		t.Fatalf("LookupOwner(%q) = %q, %v", uid, owner, err)
+	}
+
+	service := NewFileService()
+	service.SetOwnedBy(owner)
+	files, err := service.ListFiles(context.Background(), tmpDir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ListFiles() = %+v, %v, want the file of the current user", files, err)
+	}
+	if files[0].Owner != uid || files[0].Group != strconv.Itoa(os.Getgid()) || files[0].Mode.Perm() != 0640 {
+		t.Errorf("file = %+v, want the owner, group and mode of the file", files[0])
+	}
+
+	service.SetOwnedBy("4000000")
+	dir, err := service.ListDirectories(context.Background(), tmpDir)
+	if err != nil || dir.Size != 0 || len(dir.Files) != 0 {
+		t.Errorf("ListDirectories() = %+v, %v, want no file of another owner", dir, err)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package service
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner returns the numeric user and group ids of the file, empty when
+// the platform does not report them
+func fileOwner(_ string, info os.FileInfo) (string, string) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return strconv.FormatUint(uint64(stat.Uid), 10), strconv.FormatUint(uint64(stat.Gid), 10)
+	}
+	return "", ""
+}
+
+// ownedByOthers reports whether the file belongs to root or to another user
+// than the current one
+func ownedByOthers(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && (stat.Uid == 0 || int(stat.Uid) != os.Getuid())
+}
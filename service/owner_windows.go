@@ -0,0 +1,29 @@
+//go:build windows
+
+package service
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// fileOwner returns the SID of the owner of the file at path; Windows files
+// have no owning group in the POSIX sense, so the group is always empty
+func fileOwner(path string, _ os.FileInfo) (string, string) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return "", ""
+	}
+	owner, _, err := sd.Owner()
+	if err != nil || owner == nil {
+		return "", ""
+	}
+	return owner.String(), ""
+}
+
+// ownedByOthers reports false: the temporary and cache directories on Windows
+// are per user
+func ownedByOthers(os.FileInfo) bool {
+	return false
+}
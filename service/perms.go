@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// PermIssueKind names a permission problem reported by AuditPermissions
+type PermIssueKind string
+
+const (
+	// PermWorldWritable is a file or directory anyone can write to
+	PermWorldWritable PermIssueKind = "world-writable"
+	// PermDataExecutable is an executable file inside a data directory
+	PermDataExecutable PermIssueKind = "data-executable"
+	// PermSetuid is a file running with its owner's or group's privileges
+	PermSetuid PermIssueKind = "setuid"
+)
+
+// PermIssueKinds lists every kind, in report order
+var PermIssueKinds = []PermIssueKind{PermWorldWritable, PermDataExecutable, PermSetuid}
+
+// DefaultDataDirs are the directory names treated as data directories
+var DefaultDataDirs = []string{"data", "assets", "static", "public", "docs", "images", "media", "uploads"}
+
+// ErrPermsUnsupported is returned on platforms without POSIX permission bits
+var ErrPermsUnsupported = errors.New("permission audit requires POSIX permission bits, not available on " + runtime.GOOS)
+
+// PermsConfig is the "perms" section of the configuration
+type PermsConfig struct {
+	// DataDirs overrides DefaultDataDirs; names are matched case-insensitively
+	DataDirs []string `json:"data_dirs,omitempty"`
+	// Fix lists the issue kinds repaired by --fix-mode, every kind when empty
+	Fix []string `json:"fix,omitempty"`
+}
+
+// PermsPolicy decides which files are flagged and which issues get fixed
+type PermsPolicy struct {
+	DataDirs []string
+	Fix      []PermIssueKind
+}
+
+// PermIssue is a file or directory with at least one permission problem
+type PermIssue struct {
+	Path  string
+	Mode  fs.FileMode
+	Kinds []PermIssueKind
+	// FixedMode is the mode applied by FixPermissions, valid when Fixed is set
+	FixedMode fs.FileMode
+	Fixed     bool
+}
+
+// ParsePermIssueKind validates an issue kind name
+func ParsePermIssueKind(value string) (PermIssueKind, error) {
+	kind := PermIssueKind(strings.ToLower(strings.TrimSpace(value)))
+	if !slices.Contains(PermIssueKinds, kind) {
+		return "", fmt.Errorf("unknown permission issue %q, expected one of %v", value, PermIssueKinds)
+	}
+	return kind, nil
+}
+
+// NewPermsPolicy builds the policy from the configuration, filling in the defaults
+func NewPermsPolicy(cfg PermsConfig) (PermsPolicy, error) {
+	policy := PermsPolicy{DataDirs: cfg.DataDirs, Fix: PermIssueKinds}
+	if len(policy.DataDirs) == 0 {
+		policy.DataDirs = DefaultDataDirs
+	}
+	if len(cfg.Fix) > 0 {
+		policy.Fix = nil
+		for _, value := range cfg.Fix {
+			kind, err := ParsePermIssueKind(value)
+			if err != nil {
+				return PermsPolicy{}, err
+			}
+			policy.Fix = append(policy.Fix, kind)
+		}
+	}
+	return policy, nil
+}
+
+// AuditPermissions walks root and reports world-writable entries, executables
+// inside data directories, and setuid/setgid files. Symlinks are not followed
+// and unreadable directories are skipped.
+func AuditPermissions(ctx context.Context, root string, policy PermsPolicy) ([]PermIssue, error) {
+	if runtime.GOOS == "windows" {
+		return nil, ErrPermsUnsupported
+	}
+
+	var issues []PermIssue
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if path != root && d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if kinds := policy.check(root, path, info.Mode()); len(kinds) > 0 {
+			issues = append(issues, PermIssue{Path: path, Mode: info.Mode(), Kinds: kinds})
+		}
+		return nil
+	})
+	if err != nil {
+		return issues, fmt.Errorf("failed to audit %s: %w", root, err)
+	}
+	return issues, nil
+}
+
+func (p PermsPolicy) check(root, path string, mode fs.FileMode) []PermIssueKind {
+	var kinds []PermIssueKind
+	// sticky directories such as /tmp are world-writable by design
+	if mode.Perm()&0o002 != 0 && !(mode.IsDir() && mode&fs.ModeSticky != 0) {
+		kinds = append(kinds, PermWorldWritable)
+	}
+	if mode.IsRegular() && mode.Perm()&0o111 != 0 && p.inDataDir(root, path) {
+		kinds = append(kinds, PermDataExecutable)
+	}
+	if mode.IsRegular() && mode&(fs.ModeSetuid|fs.ModeSetgid) != 0 {
+		kinds = append(kinds, PermSetuid)
+	}
+	return kinds
+}
+
+// inDataDir reports whether a directory between root and path is a data directory
+func (p PermsPolicy) inDataDir(root, path string) bool {
+	rel, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return false
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		for _, name := range p.DataDirs {
+			if strings.EqualFold(part, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fixedMode clears the bits behind the issues the policy fixes
+func (p PermsPolicy) fixedMode(issue PermIssue) fs.FileMode {
+	mode := issue.Mode
+	for _, kind := range issue.Kinds {
+		if !slices.Contains(p.Fix, kind) {
+			continue
+		}
+		switch kind {
+		case PermWorldWritable:
+			mode &^= 0o002
+		case PermDataExecutable:
+			mode &^= 0o111
+		case PermSetuid:
+			mode &^= fs.ModeSetuid | fs.ModeSetgid
+		}
+	}
+	return mode
+}
+
+// FixPermissions applies the policy to the audited issues, recording the new
+// mode on each fixed issue and leaving the paths protected by guard. It keeps
+// going after a failure and returns the joined errors.
+func FixPermissions(issues []PermIssue, policy PermsPolicy, guard *PathGuard) error {
+	var errs []error
+	for i := range issues {
+		mode := policy.fixedMode(issues[i])
+		if mode == issues[i].Mode {
+			continue
+		}
+		if err := guard.Check(issues[i].Path); err != nil {
+			errs = append(errs, fmt.Errorf("refusing to chmod %w", err))
+			continue
+		}
+		if err := os.Chmod(issues[i].Path, mode); err != nil {
+			errs = append(errs, fmt.Errorf("failed to chmod %s: %w", issues[i].Path, err))
+			continue
+		}
+		issues[i].FixedMode = mode
+		issues[i].Fixed = true
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestNewPermsPolicy(t *testing.T) {
+	policy, err := NewPermsPolicy(PermsConfig{})
+	if err != nil {
+		t.Fatalf("NewPermsPolicy() error = %v", err)
+	}
+	if !slices.Equal(policy.DataDirs, DefaultDataDirs) || !slices.Equal(policy.Fix, PermIssueKinds) {
+		t.Errorf("default policy = %+v", policy)
+	}
+
+	policy, err = NewPermsPolicy(PermsConfig{DataDirs: []string{"fixtures"}, Fix: []string{" Setuid "}})
+	if err != nil {
+		t.Fatalf("NewPermsPolicy() error = %v", err)
+	}
+	if !slices.Equal(policy.DataDirs, []string{"fixtures"}) || !slices.Equal(policy.Fix, []PermIssueKind{PermSetuid}) {
+		t.Errorf("custom policy = %+v", policy)
+	}
+
+	if _, err := NewPermsPolicy(PermsConfig{Fix: []string{"sticky"}}); err == nil {
+		t.Error("expected error for unknown issue kind")
+	}
+}
+
+func TestAuditAndFixPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported on Windows")
+	}
+
+	root := t.TempDir()
+	write := func(rel string, mode fs.FileMode) string {
+		path := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	shared := write("shared.txt", 0666)
+	script := write("Assets/img/run.sh", 0755)
+	write("bin/tool", 0755)
+	write("docs/readme.md", 0644)
+	suid := write("bin/suid", 0755|fs.ModeSetuid)
+	sticky := filepath.Join(root, "tmp")
+	os.Mkdir(sticky, 0755)
+	os.Chmod(sticky, 0777|fs.ModeSticky)
+
+	policy, _ := NewPermsPolicy(PermsConfig{Fix: []string{"world-writable", "data-executable"}})
+	issues, err := AuditPermissions(context.Background(), root, policy)
+	if err != nil {
+		t.Fatalf("AuditPermissions() error = %v", err)
+	}
+
+	got := map[string][]PermIssueKind{}
+	for _, issue := range issues {
+		got[issue.Path] = issue.Kinds
+	}
+	want := map[string][]PermIssueKind{
+		shared: {PermWorldWritable},
+		script: {PermDataExecutable},
+	}
+	// setuid may be refused by the file system, only check it when it stuck
+	if info, _ := os.Stat(suid); info.Mode()&fs.ModeSetuid != 0 {
+		want[suid] = []PermIssueKind{PermSetuid}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("issues = %v, want %v", got, want)
+	}
+	for path, kinds := range want {
+		if !slices.Equal(got[path], kinds) {
+			t.Errorf("issues for %s = %v, want %v", path, got[path], kinds)
+		}
+	}
+
+	guard, _ := NewPathGuard(SafetyConfig{}, false)
+	if err := FixPermissions(issues, policy, guard); err != nil {
+		t.Fatalf("FixPermissions() error = %v", err)
+	}
+	for path, mode := range map[string]fs.FileMode{shared: 0664, script: 0644} {
+		info, _ := os.Stat(path)
+		if info.Mode().Perm() != mode {
+			t.Errorf("%s mode = %v, want %v", path, info.Mode().Perm(), mode)
+		}
+	}
+	if info, _ := os.Stat(suid); want[suid] != nil && info.Mode()&fs.ModeSetuid == 0 {
+		t.Error("setuid was cleared although the policy does not fix it")
+	}
+}
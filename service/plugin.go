@@ -0,0 +1,175 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// PluginPrefix starts the name of the executables run as goktor subcommands:
+// goktor-<name> on PATH runs as "goktor <name>"
+const PluginPrefix = "goktor-"
+
+// Log levels passed to the plugins in GOKTOR_LOG_LEVEL
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelError = "error"
+)
+
+// Plugin is an external subcommand found on PATH
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// PluginEnvironment is passed to the plugins as GOKTOR_* environment
+// variables, so they honour the global flags of goktor
+type PluginEnvironment struct {
+	// Executable is the goktor binary, for plugins calling back into it
+	Executable string
+	ConfigPath string
+	LogLevel   string
+	LogFormat  string
+	// LogFile is empty when the log entries go to stdout
+	LogFile string
+	NoColor bool
+}
+
+// Environ returns the environment of the current process with the variables
+// of e added
+func (e PluginEnvironment) Environ() []string {
+	env := append(os.Environ(),
+		"GOKTOR_BIN="+e.Executable,
+		"GOKTOR_CONFIG="+e.ConfigPath,
+		"GOKTOR_LOG_LEVEL="+e.LogLevel,
+		"GOKTOR_LOG_FORMAT="+e.LogFormat,
+		"GOKTOR_LOG_FILE="+e.LogFile,
+	)
+	if e.NoColor {
+		env = append(env, "NO_COLOR=1")
+	}
+	return env
+}
+
+// validPluginName reports whether name can only match an executable of a
+// PATH directory, never a path
+func validPluginName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, "-") && !strings.ContainsAny(name, `/\`) && name != "." && name != ".."
+}
+
+// FindPlugin returns the goktor-<name> executable on PATH
+func FindPlugin(name string) (Plugin, error) {
+	if !validPluginName(name) {
+		return Plugin{}, fmt.Errorf("invalid plugin name %q", name)
+	}
+	path, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return Plugin{}, fmt.Errorf("plugin %s not found: %w", name, err)
+	}
+	return Plugin{Name: name, Path: path}, nil
+}
+
+// ListPlugins returns the goktor-* executables of the PATH directories sorted
+// by name. A plugin found in several directories is the first one, like the
+// one FindPlugin runs.
+func ListPlugins() []Plugin {
+	var plugins []Plugin
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok || entry.IsDir() {
+				continue
+			}
+			if slices.ContainsFunc(plugins, func(p Plugin) bool { return p.Name == name }) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(path); err != nil || !isExecutable(info) {
+				continue
+			}
+			plugins = append(plugins, Plugin{Name: name, Path: path})
+		}
+	}
+	slices.SortFunc(plugins, func(a, b Plugin) int { return strings.Compare(a.Name, b.Name) })
+	return plugins
+}
+
+// pluginName returns the plugin name of the executable file named file,
+// without the extension on Windows
+func pluginName(file string) (string, bool) {
+	name, ok := strings.CutPrefix(file, PluginPrefix)
+	if !ok {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(name))
+		if !slices.Contains(windowsExecutableExts(), ext) {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name, validPluginName(name)
+}
+
+// windowsExecutableExts returns the lowercase extensions of PATHEXT
+func windowsExecutableExts() []string {
+	pathExt := os.Getenv("PATHEXT")
+	if pathExt == "" {
+		pathExt = ".com;.exe;.bat;.cmd"
+	}
+	var exts []string
+	for _, ext := range strings.Split(strings.ToLower(pathExt), ";") {
+		if ext != "" {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+func isExecutable(info os.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		return info.Mode().IsRegular()
+	}
+	return info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
+
+// RunPlugin runs plugin with args and env, attached to the standard streams,
+// and returns its exit code. The plugin shares the terminal, so it receives
+// Ctrl+C itself and decides how to stop; -1 is returned when it was killed by
+// a signal.
+func RunPlugin(plugin Plugin, args []string, env PluginEnvironment) (int, error) {
+	cmd := exec.Command(plugin.Path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = env.Environ()
+
+	// goktor outlives Ctrl+C until the plugin exits, to return its status.
+	// The signal is caught rather than ignored, as an ignored signal would be
+	// ignored by the plugin too.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to run plugin %s: %w", plugin.Name, err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestListPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by PATHEXT on Windows")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	write := func(dir, name string, mode os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(first, "goktor-sync", 0755)
+	write(second, "goktor-sync", 0755)
+	write(second, "goktor-audit", 0755)
+	write(second, "goktor-notes.txt", 0644)
+	write(second, "other-tool", 0755)
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	plugins := ListPlugins()
+	want := []Plugin{
+		{Name: "audit", Path: filepath.Join(second, "goktor-audit")},
+		{Name: "sync", Path: filepath.Join(first, "goktor-sync")},
+	}
+	if !slices.Equal(plugins, want) {
+		t.Errorf("ListPlugins() = %+v, want %+v", plugins, want)
+	}
+
+	plugin, err := FindPlugin("sync")
+	if err != nil || plugin.Path != filepath.Join(first, "goktor-sync") {
+		t.Errorf("FindPlugin(sync) = %+v, %v, want the first one on PATH", plugin, err)
+	}
+	if _, err := FindPlugin("notes.txt"); err == nil {
+		t.Errorf("FindPlugin() should skip the files that are not executable")
+	}
+	if _, err := FindPlugin("../" + filepath.Base(second) + "/goktor-audit"); err == nil {
+		t.Errorf("FindPlugin() should reject a path")
+	}
+}
+
+// TestRunPluginInterrupted checks Ctrl+C leaves goktor waiting for the plugin,
+// which receives it with its default action
+func TestRunPluginInterrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell and signals")
+	}
+	dir := t.TempDir()
+	script := func(name, body string) Plugin {
+		t.Helper()
+		path := filepath.Join(dir, PluginPrefix+name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return Plugin{Name: name, Path: path}
+	}
+
+	code, err := RunPlugin(script("exits", "kill -INT $PPID\nsleep 0.2\nexit 7\n"), nil, PluginEnvironment{})
+	if err != nil || code != 7 {
+		t.Errorf("RunPlugin() = %d, %v, want the exit status 7 of the plugin", code, err)
+	}
+	code, err = RunPlugin(script("killed", "kill -INT $PPID\nsleep 0.2\nkill -INT $$\nexit 1\n"), nil, PluginEnvironment{})
+	if err != nil || code != -1 {
+		t.Errorf("RunPlugin() = %d, %v, want -1 for a plugin killed by Ctrl+C", code, err)
+	}
+}
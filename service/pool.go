@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// workerPool bounds the goroutines reading directories during a whole scan,
+// however wide and deep the tree. A task runs on a new goroutine while the
+// pool has a free worker and inline in the caller otherwise, so a directory
+// waiting for its subdirectories never holds up the pool.
+type workerPool struct {
+	// tokens holds one slot per goroutine running besides the scanning one
+	tokens chan struct{}
+}
+
+// newWorkerPool returns a pool running at most workers tasks at a time,
+// counting the goroutine that starts the scan; 1 scans sequentially
+func newWorkerPool(workers int) *workerPool {
+	return &workerPool{tokens: make(chan struct{}, max(workers, 1)-1)}
+}
+
+// run calls task for every index of [0, n) and returns once all of them
+// returned. The tasks not started yet are dropped when ctx is cancelled.
+func (p *workerPool) run(ctx context.Context, n int, task func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case p.tokens <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-p.tokens }()
+				task(i)
+			}(i)
+		default:
+			task(i)
+		}
+	}
+	wg.Wait()
+}
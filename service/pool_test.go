@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	for _, workers := range []int{0, 1, 3} {
+		pool := newWorkerPool(workers)
+		var running, peak, done atomic.Int32
+		var task func(depth int) func(int)
+		task = func(depth int) func(int) {
+			return func(int) {
+				now := running.Add(1)
+				for old := peak.Load(); now > old && !peak.CompareAndSwap(old, now); old = peak.Load() {
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+				// nested tasks share the pool instead of adding goroutines
+				if depth < 2 {
+					pool.run(context.Background(), 4, task(depth+1))
+				}
+				done.Add(1)
+			}
+		}
+		pool.run(context.Background(), 4, task(0))
+
+		if got := done.Load(); got != 4+16+64 {
+			t.Errorf("workers %d: %d tasks done, want 84", workers, got)
+		}
+		if limit := int32(max(workers, 1)); peak.Load() > limit {
+			t.Errorf("workers %d: %d tasks ran at a time", workers, peak.Load())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := 0
+	newWorkerPool(1).run(ctx, 10, func(int) { ran++ })
+	if ran != 0 {
+		t.Errorf("%d tasks ran after cancellation, want 0", ran)
+	}
+}
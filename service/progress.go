@@ -0,0 +1,33 @@
+package service
+
+import (
+	"sync/atomic"
+)
+
+// ScanProgress is a snapshot of a running directory scan
+type ScanProgress struct {
+	Entries     int64
+	Bytes       int64
+	CurrentPath string
+}
+
+// ProgressFunc receives scan progress updates. It may be called concurrently
+// from several traversal goroutines and must not block.
+type ProgressFunc func(ScanProgress)
+
+// scanCounter accumulates progress across concurrent traversal goroutines
+type scanCounter struct {
+	entries atomic.Int64
+	bytes   atomic.Int64
+}
+
+func (fs *FileSystemService) reportProgress(path string, entries int, size int64) {
+	if fs.progress == nil {
+		return
+	}
+	fs.progress(ScanProgress{
+		Entries:     fs.counter.entries.Add(int64(entries)),
+		Bytes:       fs.counter.bytes.Add(size),
+		CurrentPath: path,
+	})
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrProtectedPath is returned for a destructive operation on a protected path
+var ErrProtectedPath = errors.New("protected path, use --force-unsafe to override")
+
+// subtreeSuffix ends the protected paths whose whole content is protected,
+// such as "/etc/**"; the others protect the path itself and its ancestors
+const subtreeSuffix = "/**"
+
+// SafetyConfig is the "safety" section of the configuration
+type SafetyConfig struct {
+	// ProtectedPaths are added to the built-in protected paths. A leading ~
+	// is the home directory, filepath.Match patterns are accepted and a
+	// trailing /** protects everything below the path.
+	ProtectedPaths []string `json:"protected_paths,omitempty"`
+}
+
+// DefaultProtectedPaths returns the built-in protected paths of the platform:
+// the system directories and the home directory with its dot-directories
+func DefaultProtectedPaths() []string {
+	paths := []string{"~", "~/.*", "~/.ssh/**", "~/.gnupg/**"}
+	if runtime.GOOS == "windows" {
+		systemDrive := os.Getenv("SystemDrive")
+		if systemDrive == "" {
+			systemDrive = "C:"
+		}
+		paths = append(paths, systemDrive+`\`, systemDrive+`\Users`, systemDrive+`\ProgramData`)
+		for _, env := range []string{"SystemRoot", "ProgramFiles", "ProgramFiles(x86)"} {
+			if dir := os.Getenv(env); dir != "" {
+				paths = append(paths, dir+subtreeSuffix)
+			}
+		}
+		return paths
+	}
+	paths = append(paths, "/", "/home", "/Users", "/opt", "/usr", "/usr/local", "/var", "/root",
+		"/Applications", "/Library")
+	for _, dir := range []string{"/bin", "/boot", "/dev", "/etc", "/lib", "/lib64", "/proc", "/sbin", "/sys",
+		"/usr/bin", "/usr/lib", "/usr/sbin", "/System"} {
+		paths = append(paths, dir+subtreeSuffix)
+	}
+	return paths
+}
+
+// ValidateProtectedPaths reports a malformed pattern among paths
+func ValidateProtectedPaths(paths []string) error {
+	for _, path := range paths {
+		if _, err := filepath.Match(filepath.FromSlash(strings.TrimSuffix(path, subtreeSuffix)), ""); err != nil {
+			return fmt.Errorf("invalid protected path %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// protectedPath is a protected path made absolute
+type protectedPath struct {
+	pattern string
+	subtree bool
+	// literal is set when pattern holds no wildcard, which lets it protect
+	// its ancestors too
+	literal bool
+}
+
+// PathGuard refuses the destructive operations on protected paths: deleting
+// or modifying a protected path, anything below a protected subtree, or an
+// ancestor of a protected path
+type PathGuard struct {
+	paths []protectedPath
+	force bool
+}
+
+// NewPathGuard protects the built-in paths and the configured ones. With
+// force every path is allowed.
+func NewPathGuard(cfg SafetyConfig, force bool) (*PathGuard, error) {
+	if err := ValidateProtectedPaths(cfg.ProtectedPaths); err != nil {
+		return nil, err
+	}
+	home, _ := os.UserHomeDir()
+	guard := &PathGuard{force: force}
+	for _, path := range append(DefaultProtectedPaths(), cfg.ProtectedPaths...) {
+		subtree := strings.HasSuffix(path, subtreeSuffix)
+		path = strings.TrimSuffix(path, subtreeSuffix)
+		if path == "~" || strings.HasPrefix(path, "~/") {
+			if home == "" {
+				continue
+			}
+			path = home + path[1:]
+		}
+		path = filepath.Clean(filepath.FromSlash(path))
+		guard.paths = append(guard.paths, protectedPath{
+			pattern: guardCase(path),
+			subtree: subtree,
+			literal: !strings.ContainsAny(path, `*?[`),
+		})
+	}
+	return guard, nil
+}
+
+// Check returns an error wrapping ErrProtectedPath when path is protected
+func (g *PathGuard) Check(path string) error {
+	if g == nil || g.force {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	abs = guardCase(abs)
+	for _, protected := range g.paths {
+		if protected.matches(abs) {
+			return fmt.Errorf("%s: %w", path, ErrProtectedPath)
+		}
+	}
+	return nil
+}
+
+func (p protectedPath) matches(path string) bool {
+	if ok, _ := filepath.Match(p.pattern, path); ok {
+		return true
+	}
+	if p.subtree && isBelow(path, p.pattern) {
+		return true
+	}
+	// removing an ancestor removes the protected path with it
+	return p.literal && isBelow(p.pattern, path)
+}
+
+// isBelow reports whether path is strictly inside dir
+func isBelow(path, dir string) bool {
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir) && path != dir
+}
+
+// guardCase folds the case of paths on Windows, whose file names are case
+// insensitive
+func guardCase(path string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToLower(path)
+	}
+	return path
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPathGuard(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	tmpDir := t.TempDir()
+	guard, err := NewPathGuard(SafetyConfig{ProtectedPaths: []string{filepath.ToSlash(tmpDir) + "/keep/**", filepath.ToSlash(tmpDir) + "/db-*"}}, false)
+	if err != nil {
+		t.Fatalf("NewPathGuard() error = %v", err)
+	}
+
+	tests := []struct {
+		path      string
+		protected bool
+	}{
+		{home, true},
+		{filepath.Join(home, ".ssh"), true},
+		{filepath.Join(home, ".ssh", "id_ed25519"), true},
+		{filepath.Join(home, ".cache"), true},
+		{filepath.Join(home, ".cache", "pip"), false},
+		{filepath.Join(home, "projects", "app", "node_modules"), false},
+		{filepath.Dir(home), true},
+		{filepath.Join(tmpDir, "keep"), true},
+		{filepath.Join(tmpDir, "keep", "sub", "file"), true},
+		{filepath.Join(tmpDir, "db-main"), true},
+		{filepath.Join(tmpDir, "db-main", "data"), false},
+		{filepath.Join(tmpDir, "other"), false},
+		{tmpDir, true},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, []struct {
+			path      string
+			protected bool
+		}{{"/", true}, {"/etc/hosts", true}, {"/usr", true}, {"/usr/local/src/app/target", false}}...)
+	}
+	for _, tt := range tests {
+		err := guard.Check(tt.path)
+		if protected := errors.Is(err, ErrProtectedPath); protected != tt.protected {
+			t.Errorf("Check(%s) = %v, want protected %v", tt.path, err, tt.protected)
+		}
+	}
+
+	forced, _ := NewPathGuard(SafetyConfig{}, true)
+	if err := forced.Check(home); err != nil {
+		t.Errorf("Check() with force = %v, want nil", err)
+	}
+	if _, err := NewPathGuard(SafetyConfig{ProtectedPaths: []string{"/data/["}}, false); err == nil {
+		t.Errorf("NewPathGuard() with a malformed pattern should fail")
+	}
+}
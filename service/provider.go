@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RemoteRepository describes a repository hosted by a git provider
+type RemoteRepository struct {
+	Name     string
+	CloneURL string
+	SSHURL   string
+	// Size is the provider-reported repository size in bytes, 0 when unknown
+	Size int64
+}
+
+// Provider lists the repositories of an organization or group on a git hosting service
+type Provider interface {
+	ListRepositories(ctx context.Context, owner string) ([]RemoteRepository, error)
+	// RepositorySize returns the size in bytes of the repository at path
+	// ("org/repo" or "group/sub/project"), 0 when the provider does not report it
+	RepositorySize(ctx context.Context, path string) (int64, error)
+}
+
+var providerClient = &http.Client{Timeout: 30 * time.Second}
+
+// getJSON performs an authenticated GET request with client and decodes the
+// JSON response into out
+func getJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", res.Status, url)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
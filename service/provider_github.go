@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultGitHubURL = "https://api.github.com"
+	githubPageSize   = 100
+)
+
+// GitHubProvider lists repositories through the GitHub REST API
+type GitHubProvider struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewGitHubProvider creates a provider for github.com, reached through proxy
+func NewGitHubProvider(token string, proxy ProxyConfig) Provider {
+	return NewGitHubProviderWithURL(DefaultGitHubURL, token, proxy)
+}
+
+// NewGitHubProviderWithURL creates a provider for a GitHub API endpoint,
+// reached through proxy
+func NewGitHubProviderWithURL(baseURL string, token string, proxy ProxyConfig) Provider {
+	return &GitHubProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  proxy.withProxy(providerClient),
+	}
+}
+
+type githubRepository struct {
+	Name     string `json:"name"`
+	CloneURL string `json:"clone_url"`
+	SSHURL   string `json:"ssh_url"`
+	Size     int64  `json:"size"`
+}
+
+func (p *GitHubProvider) headers() map[string]string {
+	headers := map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	if p.token != "" {
+		headers["Authorization"] = "Bearer " + p.token
+	}
+	return headers
+}
+
+// ListRepositories returns every repository of a GitHub organization
+func (p *GitHubProvider) ListRepositories(ctx context.Context, org string) ([]RemoteRepository, error) {
+	headers := p.headers()
+
+	var repos []RemoteRepository
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("%s/orgs/%s/repos?per_page=%d&page=%d", p.baseURL, url.PathEscape(org), githubPageSize, page)
+
+		var batch []githubRepository
+		if err := getJSON(ctx, p.client, endpoint, headers, &batch); err != nil {
+			return nil, fmt.Errorf("failed to list repositories of %s: %w", org, err)
+		}
+
+		for _, r := range batch {
+			repos = append(repos, RemoteRepository{
+				Name:     r.Name,
+				CloneURL: r.CloneURL,
+				SSHURL:   r.SSHURL,
+				Size:     r.Size * 1024, // GitHub reports KB
+			})
+		}
+
+		if len(batch) < githubPageSize {
+			return repos, nil
+		}
+	}
+}
+
+// RepositorySize returns the size of an "owner/repo" repository
+func (p *GitHubProvider) RepositorySize(ctx context.Context, path string) (int64, error) {
+	owner, name, ok := strings.Cut(strings.TrimSuffix(path, ".git"), "/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return 0, fmt.Errorf("invalid GitHub repository %q", path)
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s", p.baseURL, url.PathEscape(owner), url.PathEscape(name))
+
+	var repo githubRepository
+	if err := getJSON(ctx, p.client, endpoint, p.headers(), &repo); err != nil {
+		return 0, fmt.Errorf("failed to get repository %s: %w", path, err)
+	}
+	return repo.Size * 1024, nil
+}
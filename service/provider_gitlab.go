@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultGitLabURL = "https://gitlab.com"
+	gitlabPageSize   = 100
+)
+
+// GitLabProvider lists projects through the GitLab REST API
+type GitLabProvider struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewGitLabProvider creates a provider for a GitLab instance, gitlab.com when
+// baseURL is empty, reached through proxy
+func NewGitLabProvider(baseURL string, token string, proxy ProxyConfig) Provider {
+	if baseURL == "" {
+		baseURL = DefaultGitLabURL
+	}
+	return &GitLabProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  proxy.withProxy(providerClient),
+	}
+}
+
+type gitlabProject struct {
+	Path          string `json:"path"`
+	HTTPURLToRepo string `json:"http_url_to_repo"`
+	SSHURLToRepo  string `json:"ssh_url_to_repo"`
+	Statistics    *struct {
+		RepositorySize int64 `json:"repository_size"`
+	} `json:"statistics"`
+}
+
+func (p *GitLabProvider) headers() map[string]string {
+	headers := map[string]string{}
+	if p.token != "" {
+		headers["PRIVATE-TOKEN"] = p.token
+	}
+	return headers
+}
+
+// ListRepositories returns every project of a GitLab group, including subgroups
+func (p *GitLabProvider) ListRepositories(ctx context.Context, group string) ([]RemoteRepository, error) {
+	headers := p.headers()
+
+	var repos []RemoteRepository
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("%s/api/v4/groups/%s/projects?include_subgroups=true&statistics=true&per_page=%d&page=%d",
+			p.baseURL, url.PathEscape(group), gitlabPageSize, page)
+
+		var batch []gitlabProject
+		if err := getJSON(ctx, p.client, endpoint, headers, &batch); err != nil {
+			return nil, fmt.Errorf("failed to list projects of %s: %w", group, err)
+		}
+
+		for _, project := range batch {
+			repo := RemoteRepository{
+				Name:     project.Path,
+				CloneURL: project.HTTPURLToRepo,
+				SSHURL:   project.SSHURLToRepo,
+			}
+			if project.Statistics != nil {
+				repo.Size = project.Statistics.RepositorySize
+			}
+			repos = append(repos, repo)
+		}
+
+		if len(batch) < gitlabPageSize {
+			return repos, nil
+		}
+	}
+}
+
+// RepositorySize returns the size of a project. GitLab only reports project
+// statistics to members with at least reporter access, 0 is returned otherwise.
+func (p *GitLabProvider) RepositorySize(ctx context.Context, path string) (int64, error) {
+	path = strings.TrimSuffix(path, ".git")
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s?statistics=true", p.baseURL, url.PathEscape(path))
+
+	var project gitlabProject
+	if err := getJSON(ctx, p.client, endpoint, p.headers(), &project); err != nil {
+		return 0, fmt.Errorf("failed to get project %s: %w", path, err)
+	}
+	if project.Statistics == nil {
+		return 0, nil
+	}
+	return project.Statistics.RepositorySize, nil
+}
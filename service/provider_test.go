@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubProvider_ListRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/myorg/repos" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var repos []githubRepository
+		if r.URL.Query().Get("page") == "1" {
+			for i := 0; i < githubPageSize; i++ {
+				repos = append(repos, githubRepository{Name: fmt.Sprintf("repo-%d", i), Size: 1})
+			}
+		} else {
+			repos = append(repos, githubRepository{Name: "last", CloneURL: "https://github.com/myorg/last.git"})
+		}
+		json.NewEncoder(w).Encode(repos)
+	}))
+	defer server.Close()
+
+	provider := NewGitHubProviderWithURL(server.URL, "secret", ProxyConfig{})
+	repos, err := provider.ListRepositories(context.Background(), "myorg")
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+
+	if len(repos) != githubPageSize+1 {
+		t.Fatalf("got %d repositories, want %d", len(repos), githubPageSize+1)
+	}
+	if repos[0].Size != 1024 {
+		t.Errorf("got size %d, want 1024", repos[0].Size)
+	}
+	if last := repos[len(repos)-1]; last.Name != "last" || last.CloneURL != "https://github.com/myorg/last.git" {
+		t.Errorf("unexpected last repository %+v", last)
+	}
+
+	if _, err := NewGitHubProviderWithURL(server.URL, "wrong", ProxyConfig{}).ListRepositories(context.Background(), "myorg"); err == nil {
+		t.Error("expected error for unauthorized token, got nil")
+	}
+}
+
+func TestGitLabProvider_ListRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/groups/parent%2Fchild/projects" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("include_subgroups") != "true" || r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[
+			{"path": "api", "http_url_to_repo": "https://gitlab.example.com/parent/child/api.git", "statistics": {"repository_size": 2048}},
+			{"path": "web", "ssh_url_to_repo": "git@gitlab.example.com:parent/child/sub/web.git"}
+		]`))
+	}))
+	defer server.Close()
+
+	repos, err := NewGitLabProvider(server.URL, "secret", ProxyConfig{}).ListRepositories(context.Background(), "parent/child")
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+
+	if len(repos) != 2 {
+		t.Fatalf("got %d repositories, want 2", len(repos))
+	}
+	if repos[0].Name != "api" || repos[0].Size != 2048 {
+		t.Errorf("unexpected first repository %+v", repos[0])
+	}
+	if repos[1].SSHURL != "git@gitlab.example.com:parent/child/sub/web.git" {
+		t.Errorf("unexpected second repository %+v", repos[1])
+	}
+}
+
+func TestProvider_RepositorySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/repos/myorg/api":
+			w.Write([]byte(`{"name": "api", "size": 3}`))
+		case "/api/v4/projects/parent%2Fchild%2Fweb":
+			w.Write([]byte(`{"path": "web", "statistics": {"repository_size": 2048}}`))
+		case "/api/v4/projects/parent%2Fhidden":
+			w.Write([]byte(`{"path": "hidden"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		provider Provider
+		path     string
+		want     int64
+		wantErr  bool
+	}{
+		{name: "github", provider: NewGitHubProviderWithURL(server.URL, "", ProxyConfig{}), path: "myorg/api.git", want: 3 * 1024},
+		{name: "github nested path", provider: NewGitHubProviderWithURL(server.URL, "", ProxyConfig{}), path: "myorg/sub/api", wantErr: true},
+		{name: "gitlab", provider: NewGitLabProvider(server.URL, "", ProxyConfig{}), path: "parent/child/web.git", want: 2048},
+		{name: "gitlab without statistics", provider: NewGitLabProvider(server.URL, "", ProxyConfig{}), path: "parent/hidden", want: 0},
+		{name: "not found", provider: NewGitLabProvider(server.URL, "", ProxyConfig{}), path: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := tt.provider.RepositorySize(context.Background(), tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RepositorySize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if size != tt.want {
+				t.Errorf("RepositorySize() = %d, want %d", size, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvider_Proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host)
+		json.NewEncoder(w).Encode(githubRepository{Name: "api", Size: 1})
+	}))
+	defer proxy.Close()
+
+	provider := NewGitHubProviderWithURL("http://api.github.test", "", ProxyConfig{HTTP: proxy.URL})
+	if size, err := provider.RepositorySize(context.Background(), "myorg/api"); err != nil || size != 1024 {
+		t.Errorf("RepositorySize() = %d, %v, want 1024 through the proxy", size, err)
+	}
+	if len(proxied) != 1 || proxied[0] != "api.github.test" {
+		t.Errorf("proxied requests = %v, want one to api.github.test", proxied)
+	}
+}
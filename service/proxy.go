@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"golang.org/x/net/http/httpproxy"
+)
+
+func init() {
+	// the proxy of every HTTP(S) remote is passed in the ProxyOptions of the
+	// operation by proxyOptions, which already reads the environment, so the
+	// go-git transport must not apply HTTP_PROXY and NO_PROXY on its own
+	client := githttp.NewClient(&http.Client{Transport: directTransport()})
+	gitclient.InstallProtocol("http", client)
+	gitclient.InstallProtocol("https", client)
+}
+
+// directTransport returns a copy of the default transport ignoring the proxy
+// environment variables
+func directTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	return transport
+}
+
+// ProxyConfig routes the HTTP(S) remotes through a proxy. SSH remotes always
+// connect directly.
+type ProxyConfig struct {
+	// HTTP is the proxy URL of the http:// remotes
+	HTTP string `json:"http,omitempty"`
+	// HTTPS is the proxy URL of the https:// remotes
+	HTTPS string `json:"https,omitempty"`
+	// NoProxy lists the hosts reached directly: host names, domains such as
+	// ".mycorp.com", IP addresses, CIDR ranges, or "*" for all of them
+	NoProxy []string `json:"no_proxy,omitempty"`
+}
+
+// ProxyFromEnvironment returns the proxy of the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables, or their lowercase versions
+func ProxyFromEnvironment() ProxyConfig {
+	env := httpproxy.FromEnvironment()
+	proxy := ProxyConfig{HTTP: env.HTTPProxy, HTTPS: env.HTTPSProxy}
+	for _, host := range strings.Split(env.NoProxy, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			proxy.NoProxy = append(proxy.NoProxy, host)
+		}
+	}
+	return proxy
+}
+
+// Or returns p with its empty fields taken from fallback
+func (p ProxyConfig) Or(fallback ProxyConfig) ProxyConfig {
+	if p.HTTP == "" {
+		p.HTTP = fallback.HTTP
+	}
+	if p.HTTPS == "" {
+		p.HTTPS = fallback.HTTPS
+	}
+	if len(p.NoProxy) == 0 {
+		p.NoProxy = fallback.NoProxy
+	}
+	return p
+}
+
+// Validate checks the proxy URLs
+func (p ProxyConfig) Validate() error {
+	for _, proxyURL := range []string{p.HTTP, p.HTTPS} {
+		if proxyURL == "" {
+			continue
+		}
+		if _, err := parseProxyURL(proxyURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseProxyURL parses a proxy URL, defaulting to the http scheme like curl
+// and git
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = "http://" + proxyURL
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", proxyURL)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return u, nil
+	}
+	return nil, fmt.Errorf("invalid proxy URL %q: unsupported scheme %s", proxyURL, u.Scheme)
+}
+
+// proxyFor returns the proxy of the request URL, nil to connect directly.
+// The hosts of NoProxy and the loopback addresses are never proxied.
+func (p ProxyConfig) proxyFor(target *url.URL) (*url.URL, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	config := httpproxy.Config{
+		HTTPProxy:  p.HTTP,
+		HTTPSProxy: p.HTTPS,
+		NoProxy:    strings.Join(p.NoProxy, ","),
+	}
+	return config.ProxyFunc()(target)
+}
+
+// proxyOptions returns the go-git proxy options of the remote, empty for SSH
+// and file remotes and for the hosts reached directly
+func (p ProxyConfig) proxyOptions(remoteURL string) (transport.ProxyOptions, error) {
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil || (endpoint.Protocol != "http" && endpoint.Protocol != "https") {
+		return transport.ProxyOptions{}, nil
+	}
+	target := &url.URL{Scheme: endpoint.Protocol, Host: endpoint.Host}
+	if endpoint.Port != 0 {
+		target.Host = fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port)
+	}
+	proxyURL, err := p.proxyFor(target)
+	if err != nil || proxyURL == nil {
+		return transport.ProxyOptions{}, err
+	}
+
+	options := transport.ProxyOptions{URL: proxyURL.String()}
+	if proxyURL.User != nil {
+		options.Username = proxyURL.User.Username()
+		options.Password, _ = proxyURL.User.Password()
+		withoutUser := *proxyURL
+		withoutUser.User = nil
+		options.URL = withoutUser.String()
+	}
+	return options, nil
+}
+
+// env returns the proxy environment variables of the git executable, for the
+// operations run through it such as the submodule updates
+func (p ProxyConfig) env() []string {
+	var env []string
+	add := func(name string, value string) {
+		if value != "" {
+			env = append(env, strings.ToUpper(name)+"="+value, name+"="+value)
+		}
+	}
+	add("http_proxy", p.HTTP)
+	add("https_proxy", p.HTTPS)
+	add("no_proxy", strings.Join(p.NoProxy, ","))
+	return env
+}
+
+// withProxy returns a copy of client going through the proxy
+func (p ProxyConfig) withProxy(client *http.Client) *http.Client {
+	transport := directTransport()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return p.proxyFor(req.URL)
+	}
+	proxied := *client
+	proxied.Transport = transport
+	return &proxied
+}
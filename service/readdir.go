@@ -0,0 +1,48 @@
+package service
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// dirEntry is a fs.DirEntry produced by the platform-specific enumerators.
+// The file info is the one the enumerator returned when it has one, otherwise
+// it is loaded lazily with os.Lstat, like os.ReadDir does.
+type dirEntry struct {
+	parent string
+	name   string
+	typ    fs.FileMode
+	// size is the file size reported by the enumerator itself, when hasSize is set.
+	// It is used for files that cannot be opened because they are locked.
+	size    int64
+	hasSize bool
+	info    fs.FileInfo
+}
+
+func (e *dirEntry) Name() string      { return e.name }
+func (e *dirEntry) IsDir() bool       { return e.typ.IsDir() }
+func (e *dirEntry) Type() fs.FileMode { return e.typ }
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	if e.info != nil {
+		return e.info, nil
+	}
+	return os.Lstat(filepath.Join(e.parent, e.name))
+}
+func (e *dirEntry) String() string { return fs.FormatDirEntry(e) }
+
+// readDirFast enumerates a directory using the fastest API available on the
+// current platform, falling back to os.ReadDir when the fast path fails.
+// Entries are sorted by name to match os.ReadDir.
+func readDirFast(path string) ([]os.DirEntry, error) {
+	entries, err := readDirPlatform(path)
+	if err == errFastReadDirUnsupported {
+		return os.ReadDir(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
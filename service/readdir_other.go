@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package service
+
+import (
+	"errors"
+	"os"
+)
+
+var errFastReadDirUnsupported = errors.New("fast directory enumeration not supported")
+
+// readDirPlatform has no fast path on this platform.
+func readDirPlatform(path string) ([]os.DirEntry, error) {
+	return nil, errFastReadDirUnsupported
+}
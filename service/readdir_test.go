@@ -0,0 +1,52 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestReadDirFastMatchesOSReadDir verifies the platform enumerator returns the same entries as os.ReadDir
+func TestReadDirFastMatchesOSReadDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 50; i++ {
+		os.WriteFile(filepath.Join(tmpDir, "file"+strconv.Itoa(i)+".txt"), []byte("content"), 0644)
+	}
+	os.MkdirAll(filepath.Join(tmpDir, "sub"), 0755)
+
+	want, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("os.ReadDir() error = %v", err)
+	}
+
+	got, err := readDirFast(tmpDir)
+	if err != nil {
+		t.Fatalf("readDirFast() error = %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Name() != want[i].Name() || got[i].IsDir() != want[i].IsDir() {
+			t.Errorf("entry %d = %s (dir %v), want %s (dir %v)", i, got[i].Name(), got[i].IsDir(), want[i].Name(), want[i].IsDir())
+		}
+		gotInfo, err := got[i].Info()
+		if err != nil {
+			t.Fatalf("Info() error = %v", err)
+		}
+		wantInfo, _ := want[i].Info()
+		if gotInfo.IsDir() != wantInfo.IsDir() || !gotInfo.ModTime().Equal(wantInfo.ModTime()) ||
+			(!gotInfo.IsDir() && gotInfo.Size() != wantInfo.Size()) {
+			t.Errorf("Info(%s) = %v %d %s, want %v %d %s", got[i].Name(),
+				gotInfo.Mode(), gotInfo.Size(), gotInfo.ModTime(), wantInfo.Mode(), wantInfo.Size(), wantInfo.ModTime())
+		}
+	}
+}
+
+func TestReadDirFastNonexistentPath(t *testing.T) {
+	if _, err := readDirFast("/nonexistent/path"); !os.IsNotExist(err) {
+		t.Errorf("got error %v, want not-exist error", err)
+	}
+}
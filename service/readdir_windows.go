@@ -0,0 +1,151 @@
+//go:build windows
+
+package service
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	findExInfoBasic       = 1
+	findExSearchNameOnly  = 0
+	findFirstExLargeFetch = 2
+)
+
+var (
+	errFastReadDirUnsupported = errors.New("fast directory enumeration not supported")
+
+	kernel32             = windows.NewLazySystemDLL("kernel32.dll")
+	procFindFirstFileExW = kernel32.NewProc("FindFirstFileExW")
+	procFindNextFileW    = kernel32.NewProc("FindNextFileW")
+)
+
+// win32FindData mirrors WIN32_FIND_DATAW; windows.Win32finddata truncates
+// the file name so it cannot be passed to FindFirstFileExW directly.
+type win32FindData struct {
+	FileAttributes    uint32
+	CreationTime      windows.Filetime
+	LastAccessTime    windows.Filetime
+	LastWriteTime     windows.Filetime
+	FileSizeHigh      uint32
+	FileSizeLow       uint32
+	Reserved0         uint32
+	Reserved1         uint32
+	FileName          [windows.MAX_PATH]uint16
+	AlternateFileName [14]uint16
+}
+
+// readDirPlatform reads a directory with FindFirstFileExW using the basic
+// info level and large fetch buffers.
+func readDirPlatform(path string) ([]os.DirEntry, error) {
+	if procFindFirstFileExW.Find() != nil || procFindNextFileW.Find() != nil {
+		return nil, errFastReadDirUnsupported
+	}
+
+	pattern, err := windows.UTF16PtrFromString(filepath.Join(path, "*"))
+	if err != nil {
+		return nil, &os.PathError{Op: "FindFirstFileEx", Path: path, Err: err}
+	}
+
+	var data win32FindData
+	r, _, e := procFindFirstFileExW.Call(
+		uintptr(unsafe.Pointer(pattern)),
+		findExInfoBasic,
+		uintptr(unsafe.Pointer(&data)),
+		findExSearchNameOnly,
+		0,
+		findFirstExLargeFetch,
+	)
+	handle := windows.Handle(r)
+	if handle == windows.InvalidHandle {
+		return nil, &os.PathError{Op: "FindFirstFileEx", Path: path, Err: e}
+	}
+	defer windows.FindClose(handle)
+
+	var entries []os.DirEntry
+	for {
+		name := windows.UTF16ToString(data.FileName[:])
+		if name != "." && name != ".." {
+			info := newFindFileInfo(name, &data)
+			entries = append(entries, &dirEntry{
+				parent:  path,
+				name:    name,
+				typ:     attributesType(data.FileAttributes),
+				size:    info.Size(),
+				hasSize: true,
+				info:    info,
+			})
+		}
+
+		r, _, e := procFindNextFileW.Call(uintptr(handle), uintptr(unsafe.Pointer(&data)))
+		if r == 0 {
+			if e == windows.ERROR_NO_MORE_FILES {
+				break
+			}
+			return nil, &os.PathError{Op: "FindNextFile", Path: path, Err: e}
+		}
+	}
+	return entries, nil
+}
+
+func attributesType(attrs uint32) fs.FileMode {
+	if attrs&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+		return fs.ModeSymlink
+	}
+	if attrs&windows.FILE_ATTRIBUTE_DIRECTORY != 0 {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+// findFileInfo is the fs.FileInfo of an entry built from the find data the
+// enumeration already returned, so Info costs no extra syscall
+type findFileInfo struct {
+	name string
+	data syscall.Win32FileAttributeData
+}
+
+func newFindFileInfo(name string, data *win32FindData) *findFileInfo {
+	return &findFileInfo{name: name, data: syscall.Win32FileAttributeData{
+		FileAttributes: data.FileAttributes,
+		CreationTime:   syscall.Filetime(data.CreationTime),
+		LastAccessTime: syscall.Filetime(data.LastAccessTime),
+		LastWriteTime:  syscall.Filetime(data.LastWriteTime),
+		FileSizeHigh:   data.FileSizeHigh,
+		FileSizeLow:    data.FileSizeLow,
+	}}
+}
+
+func (fi *findFileInfo) Name() string { return fi.name }
+func (fi *findFileInfo) Size() int64 {
+	return int64(fi.data.FileSizeHigh)<<32 | int64(fi.data.FileSizeLow)
+}
+
+// Mode follows os.Lstat: read-only files lose their write bits
+func (fi *findFileInfo) Mode() fs.FileMode {
+	mode := fs.FileMode(0o666)
+	if fi.data.FileAttributes&windows.FILE_ATTRIBUTE_READONLY != 0 {
+		mode = 0o444
+	}
+	switch typ := attributesType(fi.data.FileAttributes); {
+	case typ == fs.ModeSymlink:
+		return mode | fs.ModeSymlink
+	case typ.IsDir():
+		return mode | fs.ModeDir | 0o111
+	}
+	return mode
+}
+
+func (fi *findFileInfo) ModTime() time.Time {
+	return time.Unix(0, fi.data.LastWriteTime.Nanoseconds())
+}
+func (fi *findFileInfo) IsDir() bool { return fi.Mode().IsDir() }
+func (fi *findFileInfo) Sys() any    { return &fi.data }
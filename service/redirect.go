@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const infoRefsSuffix = "/info/refs"
+
+// redirectClient never follows redirects so the moved location can be read
+var redirectClient = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// ResolveRemoteRedirect probes the HTTP(S) opts.Remote of a repository and
+// returns the canonical URL when the provider answers with a redirect, which
+// GitHub and GitLab do for moved or renamed repositories. An empty string is
+// returned when the remote has not moved. The remote is rewritten to the new
+// URL unless opts.DryRun is set.
+func (gs *GitModelService) ResolveRemoteRedirect(ctx context.Context, repoPath string, opts Options) (string, error) {
+	gs, ctx, cancel := gs.withOptions(ctx, opts)
+	defer cancel()
+	gs = gs.withRepo(repoPath)
+	remoteName := gs.remoteName()
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	cfg, err := repo.Storer.Config()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config: %w", err)
+	}
+
+	remoteCfg, ok := cfg.Remotes[remoteName]
+	if !ok || len(remoteCfg.URLs) == 0 {
+		return "", fmt.Errorf("remote '%s' not found in config: %w", remoteName, ErrNoOriginRemote)
+	}
+
+	oldRemote := remoteCfg.URLs[0]
+	if !isHTTPRemote(oldRemote) {
+		return "", nil
+	}
+
+	newRemote, err := probeRedirect(ctx, gs.proxy.withProxy(redirectClient), oldRemote)
+	if err != nil || newRemote == "" {
+		return "", err
+	}
+
+	gs.logger.Info("remote has moved", "from", oldRemote, "to", newRemote)
+	if opts.DryRun {
+		return newRemote, nil
+	}
+
+	if err := setRemoteURLs(cfg, remoteCfg, append([]string{newRemote}, remoteCfg.URLs[1:]...)); err != nil {
+		return "", err
+	}
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		return "", fmt.Errorf("failed to set config: %w", err)
+	}
+	gs.logger.Info("remote updated to follow redirect", "remote", remoteName, "new remote", newRemote)
+	return newRemote, nil
+}
+
+// probeRedirect requests the smart HTTP ref advertisement and returns the
+// redirected repository URL, or an empty string when there is no redirect.
+// client must not follow redirects.
+func probeRedirect(ctx context.Context, client *http.Client, remote string) (string, error) {
+	base := strings.TrimSuffix(remote, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+infoRefsSuffix+"?service=git-upload-pack", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build redirect probe: %w", err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("redirect probe failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return "", nil
+	}
+
+	location, err := res.Location()
+	if err != nil {
+		return "", fmt.Errorf("redirect without location: %w", err)
+	}
+	return redirectTarget(location)
+}
+
+// redirectTarget strips the smart HTTP endpoint from a redirect location. A
+// location which is not the ref advertisement of a repository, such as a
+// login page, is rejected rather than taken as the new remote.
+func redirectTarget(location *url.URL) (string, error) {
+	if !strings.HasSuffix(location.Path, infoRefsSuffix) || location.Query().Get("service") != "git-upload-pack" {
+		return "", fmt.Errorf("redirect to %s is not a git repository", location.Redacted())
+	}
+	target := *location
+	target.RawQuery = ""
+	target.Path = strings.TrimSuffix(target.Path, infoRefsSuffix)
+	target.RawPath = ""
+	return target.String(), nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oldorg/project.git/info/refs":
+			http.Redirect(w, r, "/neworg/project.git/info/refs?service=git-upload-pack", http.StatusMovedPermanently)
+		case "/private/project.git/info/refs":
+			http.Redirect(w, r, "/login?return_to=/private/project.git", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		remote string
+		want   string
+		err    bool
+	}{
+		{name: "moved repository", remote: server.URL + "/oldorg/project.git", want: server.URL + "/neworg/project.git"},
+		{name: "repository not moved", remote: server.URL + "/neworg/project.git", want: ""},
+		{name: "redirect to a login page", remote: server.URL + "/private/project.git", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probeRedirect(context.Background(), redirectClient, tt.remote)
+			if (err != nil) != tt.err {
+				t.Fatalf("probeRedirect() error = %v, want error %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("probeRedirect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Remote URL protocols supported by ConvertRemoteURL
+const (
+	RemoteProtocolSSH   = "ssh"
+	RemoteProtocolHTTPS = "https"
+)
+
+// ParseRemoteProtocol validates a --to value
+func ParseRemoteProtocol(value string) (string, error) {
+	switch protocol := strings.ToLower(strings.TrimSpace(value)); protocol {
+	case RemoteProtocolSSH, RemoteProtocolHTTPS:
+		return protocol, nil
+	default:
+		return "", fmt.Errorf("unknown remote protocol %q, expected %q or %q", value, RemoteProtocolSSH, RemoteProtocolHTTPS)
+	}
+}
+
+// ConvertRemoteURL converts an scp-like SSH URL (git@host:org/repo.git) or an
+// ssh:// URL to https://host/org/repo.git, and an HTTP(S) URL back to the
+// scp-like SSH form. URLs already using the target protocol are returned as
+// is; local paths and other protocols cannot be converted.
+func ConvertRemoteURL(remote, protocol string) (string, error) {
+	host, path, err := splitRemoteURL(remote)
+	if err != nil {
+		return "", err
+	}
+	switch protocol {
+	case RemoteProtocolHTTPS:
+		if isHTTPRemote(remote) {
+			return remote, nil
+		}
+		return "https://" + host + "/" + path, nil
+	case RemoteProtocolSSH:
+		if !isHTTPRemote(remote) {
+			return remote, nil
+		}
+		return "git@" + host + ":" + path, nil
+	default:
+		return "", fmt.Errorf("unknown remote protocol %q", protocol)
+	}
+}
+
+// splitRemoteURL returns the host, without user or port, and the repository
+// path, without leading slash, of a network remote
+func splitRemoteURL(remote string) (string, string, error) {
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid remote URL %q: %w", remote, err)
+		}
+		if u.Scheme != "ssh" && u.Scheme != "http" && u.Scheme != "https" {
+			return "", "", fmt.Errorf("cannot convert %s remote %q", u.Scheme, remote)
+		}
+		path := strings.TrimPrefix(u.Path, "/")
+		if u.Hostname() == "" || path == "" {
+			return "", "", fmt.Errorf("invalid remote URL %q", remote)
+		}
+		return u.Hostname(), path, nil
+	}
+
+	// scp-like syntax: [user@]host:path
+	hostPart, path, ok := strings.Cut(remote, ":")
+	if !ok || !strings.Contains(hostPart, "@") || path == "" {
+		return "", "", fmt.Errorf("cannot convert local remote %q", remote)
+	}
+	_, host, _ := strings.Cut(hostPart, "@")
+	return host, strings.TrimPrefix(path, "/"), nil
+}
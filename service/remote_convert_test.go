@@ -0,0 +1,42 @@
+package service
+
+import "testing"
+
+func TestConvertRemoteURL(t *testing.T) {
+	tests := []struct {
+		remote   string
+		protocol string
+		want     string
+		wantErr  bool
+	}{
+		{"git@github.com:org/repo.git", RemoteProtocolHTTPS, "https://github.com/org/repo.git", false},
+		{"git@gitlab.com:group/sub/repo.git", RemoteProtocolHTTPS, "https://gitlab.com/group/sub/repo.git", false},
+		{"ssh://git@gitlab.example.com:2222/group/repo.git", RemoteProtocolHTTPS, "https://gitlab.example.com/group/repo.git", false},
+		{"https://github.com/org/repo.git", RemoteProtocolHTTPS, "https://github.com/org/repo.git", false},
+		{"https://github.com/org/repo.git", RemoteProtocolSSH, "git@github.com:org/repo.git", false},
+		{"https://user@gitlab.com:8443/group/sub/repo", RemoteProtocolSSH, "git@gitlab.com:group/sub/repo", false},
+		{"git@github.com:org/repo.git", RemoteProtocolSSH, "git@github.com:org/repo.git", false},
+		{"/srv/git/repo.git", RemoteProtocolSSH, "", true},
+		{"git://example.com/repo.git", RemoteProtocolHTTPS, "", true},
+		{"https://github.com/", RemoteProtocolSSH, "", true},
+	}
+	for _, tt := range tests {
+		got, err := ConvertRemoteURL(tt.remote, tt.protocol)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ConvertRemoteURL(%q, %q) error = %v, wantErr %v", tt.remote, tt.protocol, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ConvertRemoteURL(%q, %q) = %q, want %q", tt.remote, tt.protocol, got, tt.want)
+		}
+	}
+}
+
+func TestParseRemoteProtocol(t *testing.T) {
+	if got, err := ParseRemoteProtocol(" HTTPS "); err != nil || got != RemoteProtocolHTTPS {
+		t.Errorf("ParseRemoteProtocol(HTTPS) = %q, %v", got, err)
+	}
+	if _, err := ParseRemoteProtocol("ftp"); err == nil {
+		t.Error("expected error for unknown protocol")
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RemoteRewrite is a sed-style substitution applied to remote URLs
+type RemoteRewrite struct {
+	pattern     *regexp.Regexp
+	replacement string
+	global      bool
+}
+
+// sedGroupRef matches the \1 style group references of sed replacements
+var sedGroupRef = regexp.MustCompile(`\\([0-9])`)
+
+// ParseRemoteRewrite parses a sed-style expression such as
+// 's#old-host.com/group#new-host.com/newgroup#'. Any character may follow the
+// "s" as delimiter. The pattern is a Go regular expression, the replacement
+// may reference groups as \1 or ${1}. Flags: g replaces every match instead of
+// the first, i matches case-insensitively.
+func ParseRemoteRewrite(expr string) (*RemoteRewrite, error) {
+	if len(expr) < 2 || expr[0] != 's' {
+		return nil, fmt.Errorf("invalid rewrite %q: expected s<delim>pattern<delim>replacement<delim>[flags]", expr)
+	}
+	delim := expr[1:2]
+	parts := strings.Split(expr[2:], delim)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid rewrite %q: expected s%[2]spattern%[2]sreplacement%[2]s[flags]", expr, delim)
+	}
+	pattern, replacement, flags := parts[0], parts[1], parts[2]
+	if pattern == "" {
+		return nil, fmt.Errorf("invalid rewrite %q: empty pattern", expr)
+	}
+
+	rewrite := &RemoteRewrite{replacement: sedGroupRef.ReplaceAllString(replacement, "$${$1}")}
+	for _, flag := range flags {
+		switch flag {
+		case 'g':
+			rewrite.global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, fmt.Errorf("invalid rewrite %q: unknown flag %q", expr, flag)
+		}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rewrite pattern: %w", err)
+	}
+	rewrite.pattern = re
+	return rewrite, nil
+}
+
+// Apply rewrites a URL, returning it unchanged when the pattern does not match
+func (r *RemoteRewrite) Apply(url string) string {
+	if r.global {
+		return r.pattern.ReplaceAllString(url, r.replacement)
+	}
+	loc := r.pattern.FindStringSubmatchIndex(url)
+	if loc == nil {
+		return url
+	}
+	expanded := r.pattern.ExpandString(nil, r.replacement, url, loc)
+	return url[:loc[0]] + string(expanded) + url[loc[1]:]
+}
@@ -0,0 +1,36 @@
+package service
+
+import "testing"
+
+func TestRemoteRewrite(t *testing.T) {
+	tests := []struct {
+		expr string
+		url  string
+		want string
+	}{
+		{"s#old-host.com/group#new-host.com/newgroup#", "git@old-host.com/group/sub/project.git", "git@new-host.com/newgroup/sub/project.git"},
+		{"s#old-host.com/group#new-host.com/newgroup#", "https://other.com/group/project.git", "https://other.com/group/project.git"},
+		{`s|gitlab\.com:(\w+)/|github.com:\1-mirror/|`, "git@gitlab.com:team/project.git", "git@github.com:team-mirror/project.git"},
+		{"s#a#b#", "aaa", "baa"},
+		{"s#a#b#g", "aaa", "bbb"},
+		{"s#OLD#new#i", "https://old.example.com", "https://new.example.com"},
+	}
+	for _, tt := range tests {
+		rewrite, err := ParseRemoteRewrite(tt.expr)
+		if err != nil {
+			t.Errorf("ParseRemoteRewrite(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got := rewrite.Apply(tt.url); got != tt.want {
+			t.Errorf("%q.Apply(%q) = %q, want %q", tt.expr, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseRemoteRewrite_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "s", "x#a#b#", "s#a#b", "s##b#", "s#a#b#x", "s#(#b#"} {
+		if _, err := ParseRemoteRewrite(expr); err == nil {
+			t.Errorf("ParseRemoteRewrite(%q) expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// ReportOptions tunes the HTML report of a scan
+type ReportOptions struct {
+	// MinSize leaves out the directories smaller than this many bytes, except
+	// the root, keeping the report readable for large trees
+	MinSize int64
+	// OnDisk adds the space taken on disk, measured by the scan
+	OnDisk bool
+}
+
+// diskUsageReport is the data of the report template
+type diskUsageReport struct {
+	GeneratedAt time.Time
+	Root        DiskUsage
+	Rows        []DiskUsage
+	OnDisk      bool
+}
+
+var diskUsageTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"size":    model.FormatSize,
+	"siUnits": func() bool { return model.CurrentSizeUnits() == model.SI },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Goktor disk usage of {{.Root.Path}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { cursor: pointer; background: #f5f5f5; }
+td.number { text-align: right; }
+#treemap { position: relative; width: 100%; height: 480px; margin-bottom: 2em; border: 1px solid #999; }
+.cell { position: absolute; box-sizing: border-box; border: 1px solid #fff; overflow: hidden; font-size: 11px; padding: 1px 3px; white-space: nowrap; }
+.depth0 { background: #9ecae1; } .depth1 { background: #c6dbef; } .depth2 { background: #a1d99b; } .depth3 { background: #fdd0a2; }
+</style>
+</head>
+<body>
+<h1>Disk usage of {{.Root.Path}}</h1>
+<p>{{size .Root.Size}}{{if .OnDisk}}, {{size .Root.Allocated}} on disk{{end}}, {{.Root.Files}} files in {{.Root.Dirs}} directories. Generated {{.GeneratedAt.Format "2006-01-02 15:04"}}</p>
+<div id="treemap"></div>
+<table>
+<thead><tr><th>Path</th><th data-type="number">Size</th>{{if .OnDisk}}<th data-type="number">On disk</th>{{end}}<th data-type="number">Share of parent</th><th data-type="number">Files</th><th data-type="number">Dirs</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr><td data-value="{{.Path}}">{{.Path}}{{if .DepthLimited}} (not scanned deeper){{end}}</td>
+<td class="number" data-value="{{.Size}}">{{size .Size}}</td>{{if $.OnDisk}}<td class="number" data-value="{{.Allocated}}">{{size .Allocated}}</td>{{end}}
+<td class="number" data-value="{{.Share}}">{{printf "%.1f" .Share}}%</td><td class="number" data-value="{{.Files}}">{{.Files}}</td><td class="number" data-value="{{.Dirs}}">{{.Dirs}}</td></tr>
+{{end}}</tbody>
+</table>
+<script>
+const root = {{.Root}};
+const treemap = document.getElementById("treemap");
+
+// formatSize matches the sizes printed by goktor, in the units it ran with
+const siUnits = {{siUnits}};
+function formatSize(size) {
+  const base = siUnits ? 1000 : 1024;
+  const units = siUnits ? ["bytes", "kB", "MB", "GB", "TB"] : ["bytes", "KiB", "MiB", "GiB", "TiB"];
+  let i = 0;
+  while (size >= base && i < units.length - 1) { size /= base; i++; }
+  return size.toFixed(i ? 2 : 0) + " " + units[i];
+}
+
+// draw lays the subdirectories of node out in the rectangle, splitting it
+// along its longer side in proportion to their sizes
+function draw(node, x, y, w, h, depth) {
+  const horizontal = w >= h;
+  let offset = 0;
+  for (const sub of node.subdirs || []) {
+    if (!node.size) break;
+    const share = sub.size / node.size;
+    const cw = horizontal ? w * share : w, ch = horizontal ? h : h * share;
+    const cx = horizontal ? x + offset : x, cy = horizontal ? y : y + offset;
+    offset += horizontal ? cw : ch;
+    if (cw < 3 || ch < 3) continue;
+    const cell = document.createElement("div");
+    cell.className = "cell depth" + (depth % 4);
+    Object.assign(cell.style, { left: cx + "px", top: cy + "px", width: cw + "px", height: ch + "px" });
+    cell.title = sub.path + " (" + formatSize(sub.size) + ")";
+    if (cw > 40 && ch > 14) cell.textContent = sub.name;
+    treemap.appendChild(cell);
+    if (cw > 10 && ch > 30) draw(sub, cx + 2, cy + 16, cw - 4, ch - 18, depth + 1);
+  }
+}
+draw(root, 0, 0, treemap.clientWidth, treemap.clientHeight, 0);
+
+document.querySelectorAll("th").forEach((th, column) => th.addEventListener("click", () => {
+  const body = document.querySelector("tbody");
+  const ascending = th.dataset.order !== "asc";
+  th.dataset.order = ascending ? "asc" : "desc";
+  const value = row => row.cells[column].dataset.value;
+  const compare = th.dataset.type === "number"
+    ? (a, b) => value(a) - value(b)
+    : (a, b) => value(a).localeCompare(value(b));
+  Array.from(body.rows)
+    .sort((a, b) => ascending ? compare(a, b) : compare(b, a))
+    .forEach(row => body.appendChild(row));
+}));
+</script>
+</body>
+</html>
+`))
+
+// WriteDiskUsageHTML renders usage as a standalone HTML page with a treemap
+// of the directories and a sortable table of their sizes
+func WriteDiskUsageHTML(w io.Writer, usage DiskUsage, opts ReportOptions) error {
+	root := pruneDiskUsage(usage, opts.MinSize)
+	return diskUsageTemplate.Execute(w, diskUsageReport{
+		GeneratedAt: time.Now(),
+		Root:        root,
+		Rows:        root.Flatten(),
+		OnDisk:      opts.OnDisk,
+	})
+}
+
+// pruneDiskUsage returns a copy of usage without the subdirectories smaller
+// than minSize
+func pruneDiskUsage(usage DiskUsage, minSize int64) DiskUsage {
+	subDirs := usage.SubDirs
+	usage.SubDirs = nil
+	for _, sub := range subDirs {
+		if sub.Size >= minSize {
+			usage.SubDirs = append(usage.SubDirs, pruneDiskUsage(sub, minSize))
+		}
+	}
+	return usage
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestWriteDiskUsageHTML(t *testing.T) {
+	dir := func(name string, size int64, subDirs ...model.Directory) model.Directory {
+		return model.Directory{FileSystem: model.FileSystem{Name: name, FullPath: "/" + name, Size: size, IsDir: true}, SubDirs: subDirs}
+	}
+	usage := SummarizeDiskUsage(dir("root", 100, dir("big</script>", 5000), dir("tiny", 10)), -1)
+
+	var buf bytes.Buffer
+	if err := WriteDiskUsageHTML(&buf, usage, ReportOptions{MinSize: 1000}); err != nil {
+		t.Fatalf("WriteDiskUsageHTML() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Disk usage of /root", "/big&lt;/script&gt;", "4.88 KiB", "97.8%", `big\u003c/script\u003e`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML report missing %q", want)
+		}
+	}
+	if strings.Contains(out, "/tiny") || strings.Contains(out, "On disk") {
+		t.Errorf("HTML report should leave out directories below the minimum size and the on-disk column")
+	}
+	if len(usage.SubDirs) != 2 {
+		t.Errorf("WriteDiskUsageHTML() modified the usage, subdirs = %+v", usage.SubDirs)
+	}
+}
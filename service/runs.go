@@ -0,0 +1,182 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Statuses of a repository within a batch run
+const (
+	RunStatusDone        = "done"
+	RunStatusFailed      = "failed"
+	RunStatusInterrupted = "interrupted"
+	RunStatusPending     = "not started"
+	// RunStatusSkipped is a repository the user chose not to touch
+	RunStatusSkipped = "skipped"
+)
+
+// RunSchemaVersion is the version of the RunRecord JSON layout. It is bumped
+// whenever a field is renamed, removed or changes meaning; new optional fields
+// keep the version.
+const RunSchemaVersion = 1
+
+// RunRepo is the outcome of one repository in a batch run
+type RunRepo struct {
+	Repo string `json:"repo"`
+	// Root is the workspace root the repository was found in
+	Root   string         `json:"root,omitempty"`
+	Status string         `json:"status"`
+	Counts map[string]int `json:"counts,omitempty"`
+	Error  string         `json:"error,omitempty"`
+	// ErrorCode classifies Error when it wraps one of the typed errors, see
+	// ErrorCode
+	ErrorCode string `json:"error_code,omitempty"`
+	// HookError is set when the post hook failed after the repository was processed
+	HookError string `json:"hook_error,omitempty"`
+	// Started is when the command began working on the repository, and
+	// Duration how long it took; both are zero for repositories not started
+	Started  time.Time     `json:"started,omitzero"`
+	Duration time.Duration `json:"duration,omitempty"`
+	// Result is the command specific outcome: *UpdateResult, *PullResult,
+	// *CheckoutResult, *CreateBranchResult, *PushResult, *ForkSyncResult,
+	// *MirrorResult, *PruneBranchesResult, []DeleteMergedBranchesResult,
+	// *ArchiveCheck, *ArchiveEntry, *TarArchive, *RepoStatus, *FetchEstimate,
+	// *GCResult, *RepoSize, []DeletedBranch, []Stash, []Tag or *TagSyncResult
+	Result any `json:"result,omitempty"`
+}
+
+// RunRecord summarizes a batch run over many repositories. It is the stable,
+// versioned output of the mr-repo commands and is kept in the runs store even
+// when the run was interrupted, so no progress is lost.
+type RunRecord struct {
+	SchemaVersion int       `json:"schema_version"`
+	Command       string    `json:"command"`
+	Started       time.Time `json:"started"`
+	Finished      time.Time `json:"finished"`
+	Interrupted   bool      `json:"interrupted"`
+	Repos         []RunRepo `json:"repos"`
+	// Hook, when set, runs the pre and post hooks around every repository
+	Hook RepoHook `json:"-"`
+}
+
+// NewRunRecord starts a record listing every repository as not started
+func NewRunRecord(command string, repoDirs []string) *RunRecord {
+	run := &RunRecord{SchemaVersion: RunSchemaVersion, Command: command, Started: time.Now(), Repos: make([]RunRepo, len(repoDirs))}
+	for i, repo := range repoDirs {
+		run.Repos[i] = RunRepo{Repo: repo, Root: filepath.Dir(repo), Status: RunStatusPending}
+	}
+	return run
+}
+
+// Start records that the command begins working on the repository at index i
+// and runs the pre hook. When the hook fails the repository is recorded as
+// failed and the error returned, the command must then leave it untouched.
+func (r *RunRecord) Start(i int) error {
+	r.Repos[i].Started = time.Now()
+	if r.Hook == nil {
+		return nil
+	}
+	if err := r.Hook(HookPre, r.Repos[i]); err != nil {
+		r.Repos[i].Duration = time.Since(r.Repos[i].Started)
+		r.Repos[i].Status = RunStatusFailed
+		r.Repos[i].Error = err.Error()
+		r.Repos[i].ErrorCode = ErrorCode(err)
+		return err
+	}
+	return nil
+}
+
+// Set records the outcome of the repository at index i, and its duration
+// when Start was called for it. err is the failure, or the SkipError of a
+// repository the command skipped. The post hook then runs for the started
+// repositories, a failure being recorded in HookError.
+func (r *RunRecord) Set(i int, status string, counts map[string]int, err error) {
+	started := r.Repos[i].Started
+	if !started.IsZero() {
+		r.Repos[i].Duration = time.Since(started)
+	}
+	r.Repos[i].Status = status
+	r.Repos[i].Counts = counts
+	if err != nil {
+		r.Repos[i].Error = err.Error()
+		r.Repos[i].ErrorCode = ErrorCode(err)
+	}
+	if r.Hook != nil && !started.IsZero() {
+		if err := r.Hook(HookPost, r.Repos[i]); err != nil {
+			r.Repos[i].HookError = err.Error()
+		}
+	}
+}
+
+// SetResult attaches the command specific result of the repository at index i
+func (r *RunRecord) SetResult(i int, result any) {
+	r.Repos[i].Result = result
+}
+
+// StatusCounts returns how many repositories ended in each status
+func (r *RunRecord) StatusCounts() map[string]int {
+	counts := map[string]int{}
+	for _, repo := range r.Repos {
+		counts[repo.Status]++
+	}
+	return counts
+}
+
+// Roots returns the workspace roots of the run, in order of appearance
+func (r *RunRecord) Roots() []string {
+	var roots []string
+	seen := map[string]bool{}
+	for _, repo := range r.Repos {
+		if !seen[repo.Root] {
+			seen[repo.Root] = true
+			roots = append(roots, repo.Root)
+		}
+	}
+	return roots
+}
+
+// RootStatusCounts returns, per workspace root, how many repositories ended in each status
+func (r *RunRecord) RootStatusCounts() map[string]map[string]int {
+	counts := map[string]map[string]int{}
+	for _, repo := range r.Repos {
+		if counts[repo.Root] == nil {
+			counts[repo.Root] = map[string]int{}
+		}
+		counts[repo.Root][repo.Status]++
+	}
+	return counts
+}
+
+// DefaultRunsDir returns ~/.goktor/runs
+func DefaultRunsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goktor", "runs"), nil
+}
+
+// SaveRun writes the record as a JSON file in dir and returns its path
+func SaveRun(dir string, run *RunRecord) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create runs directory: %w", err)
+	}
+	if run.Finished.IsZero() {
+		run.Finished = time.Now()
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode run: %w", err)
+	}
+	name := run.Started.Format("20060102-150405") + "-" + strings.ReplaceAll(run.Command, " ", "-") + ".json"
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write run: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveRun(t *testing.T) {
+	run := NewRunRecord("goktor mr-repo update-branches", []string{"/ws/a", "/ws/b", "/ws/c"})
+	run.Start(0)
+	run.Set(0, RunStatusDone, map[string]int{"updated": 2}, nil)
+	run.Set(1, RunStatusInterrupted, nil, errors.New("update interrupted"))
+	run.Interrupted = true
+
+	counts := run.StatusCounts()
+	if counts[RunStatusDone] != 1 || counts[RunStatusInterrupted] != 1 || counts[RunStatusPending] != 1 {
+		t.Errorf("StatusCounts() = %v", counts)
+	}
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	path, err := SaveRun(dir, run)
+	if err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+	if !strings.HasSuffix(path, "-goktor-mr-repo-update-branches.json") {
+		t.Errorf("path = %s, want command in file name", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read run: %v", err)
+	}
+	var saved RunRecord
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("invalid run file: %v", err)
+	}
+	if !saved.Interrupted || saved.Finished.IsZero() || len(saved.Repos) != 3 || saved.SchemaVersion != RunSchemaVersion {
+		t.Errorf("saved run = %+v", saved)
+	}
+	if saved.Repos[1].Error != "update interrupted" || saved.Repos[2].Status != RunStatusPending {
+		t.Errorf("saved repos = %+v", saved.Repos)
+	}
+	if saved.Repos[0].Started.IsZero() || saved.Repos[0].Duration < 0 || !saved.Repos[1].Started.IsZero() {
+		t.Errorf("saved timings = %+v", saved.Repos)
+	}
+}
+
+func TestRunRecord_RootStatusCounts(t *testing.T) {
+	work, oss := filepath.Join("home", "work"), filepath.Join("home", "oss")
+	run := NewRunRecord("goktor mr-repo pull", []string{
+		filepath.Join(work, "a"), filepath.Join(work, "b"), filepath.Join(oss, "c"),
+	})
+	run.Set(0, RunStatusDone, nil, nil)
+	run.Set(1, RunStatusFailed, nil, errors.New("fetch failed"))
+	run.Set(2, RunStatusDone, nil, nil)
+
+	roots := run.Roots()
+	if len(roots) != 2 || roots[0] != work || roots[1] != oss {
+		t.Fatalf("Roots() = %v, want [%s %s]", roots, work, oss)
+	}
+	counts := run.RootStatusCounts()
+	if counts[work][RunStatusDone] != 1 || counts[work][RunStatusFailed] != 1 || counts[oss][RunStatusDone] != 1 {
+		t.Errorf("RootStatusCounts() = %v", counts)
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// ScanCacheSchemaVersion is the version of the scan cache JSON layout. A cache
+// written with another version is ignored.
+const ScanCacheSchemaVersion = 1
+
+// scanCacheEntry is a directory as read by the previous scan: its files, its
+// subdirectories and its modification time at that point
+type scanCacheEntry struct {
+	ModTime time.Time       `json:"mod_time"`
+	Dir     model.Directory `json:"dir"`
+	SubDirs []string        `json:"sub_dirs,omitempty"`
+}
+
+type scanCacheFile struct {
+	SchemaVersion int                       `json:"schema_version"`
+	Dirs          map[string]scanCacheEntry `json:"dirs"`
+}
+
+// ScanCache persists the directories read by a scan so the next scan of the
+// same tree only reads the directories whose modification time changed. A
+// directory modification time changes when entries are added, removed or
+// renamed, not when a file is rewritten in place: such size changes are picked
+// up when the directory changes or by a scan without cache. It is safe for
+// concurrent use.
+type ScanCache struct {
+	path     string
+	mu       sync.Mutex
+	previous map[string]scanCacheEntry
+	current  map[string]scanCacheEntry
+	hits     int
+	misses   int
+}
+
+// DefaultScanCacheDir returns ~/.goktor/cache
+func DefaultScanCacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goktor", "cache"), nil
+}
+
+// ScanCachePath returns the cache file in dir of the scans of root; scope
+// holds the options changing what a scan reads, such as following symlinks
+func ScanCachePath(dir string, root string, scope ...string) string {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	sum := sha256.Sum256([]byte(root + "\x00" + strings.Join(scope, "\x00")))
+	return filepath.Join(dir, "scan-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// NewScanCache returns an empty cache written to path
+func NewScanCache(path string) *ScanCache {
+	return &ScanCache{path: path, previous: map[string]scanCacheEntry{}, current: map[string]scanCacheEntry{}}
+}
+
+// LoadScanCache reads the cache at path. A missing cache, or one written by
+// another schema version, gives an empty cache.
+func LoadScanCache(path string) (*ScanCache, error) {
+	cache := NewScanCache(path)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scan cache: %w", err)
+	}
+	var file scanCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid scan cache %s: %w", path, err)
+	}
+	if file.SchemaVersion == ScanCacheSchemaVersion && file.Dirs != nil {
+		cache.previous = file.Dirs
+	}
+	return cache, nil
+}
+
+// lookup returns the cached content of the directory at path when its
+// modification time is the cached one, and carries the entry over to the
+// cache written by Save
+func (c *ScanCache) lookup(path string, modTime time.Time) (scanCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.previous[path]
+	if !ok || !entry.ModTime.Equal(modTime) {
+		c.misses++
+		return scanCacheEntry{}, false
+	}
+	c.hits++
+	c.current[path] = entry
+	return entry, true
+}
+
+// store records the directory at path as read by the current scan
+func (c *ScanCache) store(path string, modTime time.Time, dir model.Directory, subDirs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current[path] = scanCacheEntry{ModTime: modTime, Dir: dir, SubDirs: subDirs}
+}
+
+// Stats returns how many directories were reused from the cache and how many were read
+func (c *ScanCache) Stats() (hits int, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Save writes the directories seen by the current scan, dropping the ones
+// that no longer exist
+func (c *ScanCache) Save() error {
+	c.mu.Lock()
+	data, err := json.Marshal(scanCacheFile{SchemaVersion: ScanCacheSchemaVersion, Dirs: c.current})
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode scan cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	return nil
+}
+
+// SetScanCache makes the recursive scans reuse the directories of cache whose
+// modification time is unchanged and record the others in it
+func (fs *FileSystemService) SetScanCache(cache *ScanCache) {
+	fs.cache = cache
+}
+
+// cachedDirectory returns the content of path from the scan cache, with the
+// paths of its subdirectories. The modification time it returns is the one to
+// store when the directory has to be read.
+func (fs *FileSystemService) cachedDirectory(path string) (model.Directory, []string, time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return model.Directory{}, nil, time.Time{}, false
+	}
+	entry, ok := fs.cache.lookup(path, info.ModTime())
+	if !ok {
+		return model.Directory{}, nil, info.ModTime(), false
+	}
+	for _, file := range entry.Dir.Files {
+		if file.Locked {
+			fs.locked.add(file)
+		}
+	}
+	return entry.Dir, entry.SubDirs, info.ModTime(), true
+}
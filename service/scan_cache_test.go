@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestFileSystemService_ScanCache(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("failed to create sub: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "a.bin"), make([]byte, 100), 0644); err != nil {
+		t.Fatalf("failed to write a.bin: %v", err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "scan.json")
+	ctx := context.Background()
+
+	scan := func() (model.Directory, int, int) {
+		t.Helper()
+		cache, err := LoadScanCache(cachePath)
+		if err != nil {
+			t.Fatalf("LoadScanCache() error = %v", err)
+		}
+		fs := NewServiceWithLimit(0)
+		fs.SetScanCache(cache)
+		dir, err := fs.ListDirectories(ctx, root)
+		if err != nil {
+			t.Fatalf("ListDirectories() error = %v", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		hits, misses := cache.Stats()
+		return dir, hits, misses
+	}
+	subSize := func(dir model.Directory) int64 {
+		for _, d := range dir.FlattenDirectory() {
+			if d.FullPath == sub {
+				return d.Size
+			}
+		}
+		return -1
+	}
+
+	dir, hits, misses := scan()
+	if hits != 0 || misses != 2 || subSize(dir) != 100 {
+		t.Fatalf("first scan: hits = %d, misses = %d, sub size = %d", hits, misses, subSize(dir))
+	}
+
+	// rewriting a file in place keeps the directory mtime, so the cached size is reused
+	if err := os.WriteFile(filepath.Join(sub, "a.bin"), make([]byte, 300), 0644); err != nil {
+		t.Fatalf("failed to rewrite a.bin: %v", err)
+	}
+	dir, hits, misses = scan()
+	if hits != 2 || misses != 0 || subSize(dir) != 100 {
+		t.Errorf("unchanged scan: hits = %d, misses = %d, sub size = %d", hits, misses, subSize(dir))
+	}
+
+	// a new file changes the directory mtime, so only that directory is read again
+	if err := os.WriteFile(filepath.Join(sub, "b.bin"), make([]byte, 50), 0644); err != nil {
+		t.Fatalf("failed to write b.bin: %v", err)
+	}
+	dir, hits, misses = scan()
+	if hits != 1 || misses != 1 || subSize(dir) != 350 {
+		t.Errorf("changed scan: hits = %d, misses = %d, sub size = %d", hits, misses, subSize(dir))
+	}
+}
+
+func TestLoadScanCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.json")
+	cache, err := LoadScanCache(path)
+	if err != nil {
+		t.Fatalf("LoadScanCache() of a missing cache error = %v", err)
+	}
+	cache.store("/tmp/x", time.Unix(100, 0), model.Directory{FileSystem: model.FileSystem{FullPath: "/tmp/x", Size: 42}}, []string{"/tmp/x/y"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded, err := LoadScanCache(path)
+	if err != nil {
+		t.Fatalf("LoadScanCache() error = %v", err)
+	}
+	entry, ok := reloaded.lookup("/tmp/x", time.Unix(100, 0))
+	if !ok || entry.Dir.Size != 42 || len(entry.SubDirs) != 1 {
+		t.Errorf("lookup() = %+v, %v", entry, ok)
+	}
+	if _, ok := reloaded.lookup("/tmp/x", time.Unix(200, 0)); ok {
+		t.Errorf("lookup() with another mtime should miss")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"schema_version": 99, "dirs": {"/tmp/x": {}}}`), 0644); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+	other, err := LoadScanCache(path)
+	if err != nil {
+		t.Fatalf("LoadScanCache() error = %v", err)
+	}
+	if _, ok := other.lookup("/tmp/x", time.Time{}); ok {
+		t.Errorf("a cache of another schema version should be ignored")
+	}
+}
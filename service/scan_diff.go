@@ -0,0 +1,93 @@
+package service
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// Kinds of change between two scans
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeGrown   = "grown"
+	ChangeShrunk  = "shrunk"
+)
+
+// ScanChange is a file or directory that differs between two scans
+type ScanChange struct {
+	// Path is relative to the scanned roots, "." for the roots themselves
+	Path  string
+	IsDir bool
+	Kind  string
+	// Before and After are the sizes in the two scans, zero when the entry is
+	// missing from one; directories are compared by cumulative size
+	Before int64
+	After  int64
+}
+
+// Delta is the growth of the entry, negative when it shrank
+func (c ScanChange) Delta() int64 {
+	return c.After - c.Before
+}
+
+// DiffScans compares the entries of two scans, as returned by ScanEntries or
+// ScanIndex.LoadScan, by their path relative to the scanned roots. Entries of
+// the same size are left out; the changes are sorted by the size of the delta.
+func DiffScans(beforeRoot string, before []model.FileSystem, afterRoot string, after []model.FileSystem) []ScanChange {
+	beforeEntries := entriesByRelPath(beforeRoot, before)
+	var changes []ScanChange
+	for path, entry := range entriesByRelPath(afterRoot, after) {
+		previous, found := beforeEntries[path]
+		delete(beforeEntries, path)
+		change := ScanChange{Path: path, IsDir: entry.IsDir, Before: previous.Size, After: entry.Size}
+		switch {
+		case !found || previous.IsDir != entry.IsDir:
+			change.Kind = ChangeAdded
+			if found {
+				changes = append(changes, ScanChange{Path: path, IsDir: previous.IsDir, Kind: ChangeRemoved, Before: previous.Size})
+				change.Before = 0
+			}
+		case entry.Size > previous.Size:
+			change.Kind = ChangeGrown
+		case entry.Size < previous.Size:
+			change.Kind = ChangeShrunk
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for path, entry := range beforeEntries {
+		changes = append(changes, ScanChange{Path: path, IsDir: entry.IsDir, Kind: ChangeRemoved, Before: entry.Size})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := abs(changes[i].Delta()), abs(changes[j].Delta())
+		if a != b {
+			return a > b
+		}
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// entriesByRelPath indexes entries by their slash separated path relative to root
+func entriesByRelPath(root string, entries []model.FileSystem) map[string]model.FileSystem {
+	byPath := make(map[string]model.FileSystem, len(entries))
+	for _, entry := range entries {
+		rel, err := filepath.Rel(root, entry.FullPath)
+		if err != nil {
+			rel = entry.FullPath
+		}
+		byPath[filepath.ToSlash(rel)] = entry
+	}
+	return byPath
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
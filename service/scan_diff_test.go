@@ -0,0 +1,52 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestDiffScans(t *testing.T) {
+	tree := func(root string, logSize int64, extra string) model.Directory {
+		file := func(dir, name string, size int64) model.FileSystem {
+			return model.FileSystem{Name: name, FullPath: filepath.Join(root, dir, name), Size: size}
+		}
+		logs := model.Directory{
+			FileSystem: model.FileSystem{Name: "logs", FullPath: filepath.Join(root, "logs"), Size: logSize, IsDir: true},
+			Files:      []model.FileSystem{file("logs", "app.log", logSize)},
+		}
+		dir := model.Directory{
+			FileSystem: model.FileSystem{Name: filepath.Base(root), FullPath: root, Size: 100, IsDir: true},
+			Files:      []model.FileSystem{file("", "same.txt", 100)},
+			SubDirs:    []model.Directory{logs},
+		}
+		if extra != "" {
+			dir.Files = append(dir.Files, file("", extra, 50))
+			dir.Size += 50
+		}
+		return dir
+	}
+	before := tree("/before", 1000, "old.tmp")
+	after := tree("/after", 4000, "new.bin")
+
+	changes := DiffScans(before.FullPath, ScanEntries(before), after.FullPath, ScanEntries(after))
+	want := []ScanChange{
+		{Path: ".", IsDir: true, Kind: ChangeGrown, Before: 1150, After: 4150},
+		{Path: "logs", IsDir: true, Kind: ChangeGrown, Before: 1000, After: 4000},
+		{Path: "logs/app.log", Kind: ChangeGrown, Before: 1000, After: 4000},
+		{Path: "new.bin", Kind: ChangeAdded, After: 50},
+		{Path: "old.tmp", Kind: ChangeRemoved, Before: 50},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("DiffScans() = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+	if d := changes[4].Delta(); d != -50 {
+		t.Errorf("Delta() = %d, want -50", d)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ScanError is a path the scans could not read. The scan goes on without it,
+// so the sizes of its parent directories miss its content.
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+func (e ScanError) Error() string {
+	return fmt.Sprintf("skipped %s: %v", e.Path, e.Err)
+}
+
+func (e ScanError) Unwrap() error {
+	return e.Err
+}
+
+// scanErrors collects the paths skipped by the scans
+type scanErrors struct {
+	mu     sync.Mutex
+	errors []ScanError
+}
+
+func (s *scanErrors) add(path string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, ScanError{Path: path, Err: err})
+}
+
+func (s *scanErrors) list() []ScanError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ScanError(nil), s.errors...)
+}
+
+// SkippedPaths returns the directories and files the scans failed to read,
+// such as directories without read permission or removed during the scan. A
+// failure reading the scanned path itself is returned by the scan instead.
+func (fs *FileSystemService) SkippedPaths() []ScanError {
+	return fs.errors.list()
+}
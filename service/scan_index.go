@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// errScanIndexUnsupported is returned by OpenScanIndex on the platforms the
+// SQLite driver does not build for
+var errScanIndexUnsupported = errors.New("scan index not supported on this platform")
+
+// scanIndexSchema creates the tables of the scan index. Directories are
+// stored with their cumulative size, files with their own.
+const scanIndexSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id INTEGER PRIMARY KEY,
+	root TEXT NOT NULL,
+	scanned_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS entries (
+	scan_id INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+	path TEXT NOT NULL,
+	name TEXT NOT NULL,
+	is_dir INTEGER NOT NULL,
+	size INTEGER NOT NULL,
+	allocated INTEGER NOT NULL,
+	extension TEXT NOT NULL,
+	mod_time INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS entries_scan_size ON entries (scan_id, size);
+`
+
+// IndexedScan is a scan saved in the index
+type IndexedScan struct {
+	ID        int64
+	Root      string
+	ScannedAt time.Time
+	Entries   int64
+}
+
+// IndexQuery selects entries of a saved scan. Zero fields are not applied.
+type IndexQuery struct {
+	// ScanID is the scan to query, the latest one when zero
+	ScanID int64
+	// MinSize and MaxSize bound the size in bytes
+	MinSize int64
+	MaxSize int64
+	// Extensions keeps the files with one of these extensions, without the dot
+	Extensions []string
+	// Age filters on the modification time, relative to Now
+	Age AgeFilter
+	Now time.Time
+	// PathGlob matches the full path, with * and ? also matching separators
+	PathGlob string
+	// DirsOnly and FilesOnly keep a single kind of entry
+	DirsOnly  bool
+	FilesOnly bool
+	// Limit caps the number of entries returned, largest first
+	Limit int
+}
+
+// ScanIndex is a SQLite database of scan results, queried without walking
+// the filesystem again
+type ScanIndex struct {
+	db *sql.DB
+}
+
+// DefaultScanIndexPath returns ~/.goktor/index.db
+func DefaultScanIndexPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goktor", "index.db"), nil
+}
+
+// OpenScanIndex opens the index at path, creating it when missing
+func OpenScanIndex(path string) (*ScanIndex, error) {
+	if !scanIndexSupported {
+		return nil, errScanIndexUnsupported
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create index directory: %w", err)
+		}
+	}
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(path)+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+	if _, err := db.Exec(scanIndexSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open index %s: %w", path, err)
+	}
+	return &ScanIndex{db: db}, nil
+}
+
+// Close closes the database
+func (i *ScanIndex) Close() error {
+	return i.db.Close()
+}
+
+// SaveScan stores every directory and file of root as a new scan and returns
+// its id
+func (i *ScanIndex) SaveScan(ctx context.Context, root model.Directory, scannedAt time.Time) (int64, error) {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save scan: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO scans (root, scanned_at) VALUES (?, ?)", root.FullPath, scannedAt.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to save scan: %w", err)
+	}
+	scanID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to save scan: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO entries (scan_id, path, name, is_dir, size, allocated, extension, mod_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to save scan: %w", err)
+	}
+	defer stmt.Close()
+
+	insert := func(entry model.FileSystem) error {
+		_, err := stmt.ExecContext(ctx, scanID, entry.FullPath, entry.Name, entry.IsDir, entry.Size, entry.Allocated, entry.Extension, unixOrZero(entry.ModTime))
+		return err
+	}
+	if _, _, err := visitScanEntries(root, insert); err != nil {
+		return 0, fmt.Errorf("failed to save scan: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to save scan: %w", err)
+	}
+	return scanID, nil
+}
+
+// ScanEntries returns the files and directories of a scanned tree as saved
+// in the index, the directories with their cumulative sizes
+func ScanEntries(root model.Directory) []model.FileSystem {
+	var entries []model.FileSystem
+	_, _, _ = visitScanEntries(root, func(entry model.FileSystem) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries
+}
+
+// visitScanEntries visits the files of dir, its subdirectories and then dir
+// itself with its cumulative sizes, which it returns
+func visitScanEntries(dir model.Directory, visit func(model.FileSystem) error) (int64, int64, error) {
+	size, allocated := dir.Size, dir.Allocated
+	for _, file := range dir.Files {
+		if err := visit(file); err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, subDir := range dir.SubDirs {
+		subSize, subAllocated, err := visitScanEntries(subDir, visit)
+		if err != nil {
+			return 0, 0, err
+		}
+		size += subSize
+		allocated += subAllocated
+	}
+	entry := dir.FileSystem
+	entry.IsDir = true
+	entry.Size, entry.Allocated = size, allocated
+	return size, allocated, visit(entry)
+}
+
+// Scans lists the saved scans, latest first
+func (i *ScanIndex) Scans(ctx context.Context) ([]IndexedScan, error) {
+	rows, err := i.db.QueryContext(ctx, `SELECT s.id, s.root, s.scanned_at, COUNT(e.scan_id)
+		FROM scans s LEFT JOIN entries e ON e.scan_id = s.id
+		GROUP BY s.id ORDER BY s.id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scans: %w", err)
+	}
+	defer rows.Close()
+
+	var scans []IndexedScan
+	for rows.Next() {
+		var scan IndexedScan
+		var scannedAt int64
+		if err := rows.Scan(&scan.ID, &scan.Root, &scannedAt, &scan.Entries); err != nil {
+			return nil, fmt.Errorf("failed to list scans: %w", err)
+		}
+		scan.ScannedAt = time.Unix(scannedAt, 0)
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
+
+// LoadScan returns a saved scan with all its entries
+func (i *ScanIndex) LoadScan(ctx context.Context, id int64) (IndexedScan, []model.FileSystem, error) {
+	scan := IndexedScan{ID: id}
+	var scannedAt int64
+	err := i.db.QueryRowContext(ctx, "SELECT root, scanned_at FROM scans WHERE id = ?", id).Scan(&scan.Root, &scannedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return scan, nil, fmt.Errorf("no saved scan %d", id)
+	}
+	if err != nil {
+		return scan, nil, fmt.Errorf("failed to load scan %d: %w", id, err)
+	}
+	scan.ScannedAt = time.Unix(scannedAt, 0)
+	entries, err := i.Query(ctx, IndexQuery{ScanID: id})
+	scan.Entries = int64(len(entries))
+	return scan, entries, err
+}
+
+// Query returns the entries of a saved scan matching q, largest first
+func (i *ScanIndex) Query(ctx context.Context, q IndexQuery) ([]model.FileSystem, error) {
+	where := []string{"scan_id = ?"}
+	args := []any{q.ScanID}
+	if q.ScanID == 0 {
+		where[0] = "scan_id = (SELECT MAX(id) FROM scans)"
+		args = nil
+	}
+	if q.MinSize > 0 {
+		where = append(where, "size >= ?")
+		args = append(args, q.MinSize)
+	}
+	if q.MaxSize > 0 {
+		where = append(where, "size <= ?")
+		args = append(args, q.MaxSize)
+	}
+	if len(q.Extensions) > 0 {
+		where = append(where, "is_dir = 0 AND extension IN (?"+strings.Repeat(", ?", len(q.Extensions)-1)+")")
+		for _, ext := range q.Extensions {
+			args = append(args, strings.ToLower(strings.TrimPrefix(ext, ".")))
+		}
+	}
+	if q.Age.OlderThan > 0 || q.Age.NewerThan > 0 {
+		where = append(where, "mod_time != 0")
+	}
+	if q.Age.OlderThan > 0 {
+		where = append(where, "mod_time < ?")
+		args = append(args, q.Now.Add(-q.Age.OlderThan).Unix())
+	}
+	if q.Age.NewerThan > 0 {
+		where = append(where, "mod_time >= ?")
+		args = append(args, q.Now.Add(-q.Age.NewerThan).Unix())
+	}
+	if q.PathGlob != "" {
+		where = append(where, "path GLOB ?")
+		args = append(args, q.PathGlob)
+	}
+	if q.DirsOnly {
+		where = append(where, "is_dir = 1")
+	}
+	if q.FilesOnly {
+		where = append(where, "is_dir = 0")
+	}
+	query := "SELECT path, name, is_dir, size, allocated, extension, mod_time FROM entries WHERE " + strings.Join(where, " AND ") + " ORDER BY size DESC, path"
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []model.FileSystem
+	for rows.Next() {
+		var entry model.FileSystem
+		var modTime int64
+		if err := rows.Scan(&entry.FullPath, &entry.Name, &entry.IsDir, &entry.Size, &entry.Allocated, &entry.Extension, &modTime); err != nil {
+			return nil, fmt.Errorf("failed to query index: %w", err)
+		}
+		if modTime != 0 {
+			entry.ModTime = time.Unix(modTime, 0)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
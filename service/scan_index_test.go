@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestScanIndex(t *testing.T) {
+	ctx := context.Background()
+	index, err := OpenScanIndex(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatalf("OpenScanIndex() error = %v", err)
+	}
+	defer index.Close()
+
+	now := time.Now()
+	file := func(path string, size int64, age time.Duration) model.FileSystem {
+		return model.FileSystem{Name: filepath.Base(path), FullPath: path, Size: size, Extension: model.FileExtension(path), ModTime: now.Add(-age)}
+	}
+	root := model.Directory{
+		FileSystem: model.FileSystem{Name: "root", FullPath: "/root", Size: 10, IsDir: true},
+		Files:      []model.FileSystem{file("/root/notes.txt", 10, time.Hour)},
+		SubDirs: []model.Directory{{
+			FileSystem: model.FileSystem{Name: "logs", FullPath: "/root/logs", Size: 3000, IsDir: true},
+			Files:      []model.FileSystem{file("/root/logs/old.log", 2000, 400*24*time.Hour), file("/root/logs/new.LOG", 1000, time.Hour)},
+		}},
+	}
+	if _, err := index.SaveScan(ctx, model.Directory{FileSystem: model.FileSystem{FullPath: "/other"}}, now); err != nil {
+		t.Fatalf("SaveScan() error = %v", err)
+	}
+	scanID, err := index.SaveScan(ctx, root, now)
+	if err != nil {
+		t.Fatalf("SaveScan() error = %v", err)
+	}
+
+	scans, err := index.Scans(ctx)
+	if err != nil || len(scans) != 2 || scans[0].ID != scanID || scans[0].Entries != 5 {
+		t.Fatalf("Scans() = %+v, %v, want the latest scan first with 5 entries", scans, err)
+	}
+
+	tests := []struct {
+		name  string
+		query IndexQuery
+		want  []string
+	}{
+		{"latest scan by size", IndexQuery{}, []string{"/root", "/root/logs", "/root/logs/old.log", "/root/logs/new.LOG", "/root/notes.txt"}},
+		{"directories", IndexQuery{ScanID: scanID, DirsOnly: true, MaxSize: 3000}, []string{"/root/logs"}},
+		{"extension", IndexQuery{Extensions: []string{".LOG"}}, []string{"/root/logs/old.log", "/root/logs/new.LOG"}},
+		{"older than", IndexQuery{Age: AgeFilter{OlderThan: 30 * 24 * time.Hour}, Now: now, FilesOnly: true}, []string{"/root/logs/old.log"}},
+		{"glob and size", IndexQuery{PathGlob: "/root/logs/*", MinSize: 1500}, []string{"/root/logs/old.log"}},
+		{"limit", IndexQuery{Limit: 1}, []string{"/root"}},
+		{"other scan", IndexQuery{ScanID: scans[1].ID}, []string{"/other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := index.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.FullPath)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Query() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
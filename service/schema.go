@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// runResultTypes are the values RunRepo.Result may hold
+var runResultTypes = []reflect.Type{
+	reflect.TypeOf(UpdateResult{}),
+	reflect.TypeOf(PullResult{}),
+	reflect.TypeOf(CheckoutResult{}),
+	reflect.TypeOf(CreateBranchResult{}),
+	reflect.TypeOf(PushResult{}),
+	reflect.TypeOf(ForkSyncResult{}),
+	reflect.TypeOf(MirrorResult{}),
+	reflect.TypeOf(PruneBranchesResult{}),
+	reflect.TypeOf([]DeleteMergedBranchesResult{}),
+	reflect.TypeOf(ArchiveCheck{}),
+	reflect.TypeOf(ArchiveEntry{}),
+	reflect.TypeOf(TarArchive{}),
+	reflect.TypeOf(RepoStatus{}),
+	reflect.TypeOf(FetchEstimate{}),
+	reflect.TypeOf(GCResult{}),
+	reflect.TypeOf(RepoSize{}),
+	reflect.TypeOf([]DeletedBranch{}),
+	reflect.TypeOf([]Stash{}),
+	reflect.TypeOf([]Tag{}),
+	reflect.TypeOf(TagSyncResult{}),
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// RunSchema returns the JSON Schema of RunRecord, the output of the mr-repo
+// commands. It is generated from the Go types so it never drifts from them.
+func RunSchema() map[string]any {
+	schema := jsonSchema(reflect.TypeOf(RunRecord{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = fmt.Sprintf("goktor mr-repo run, schema version %d", RunSchemaVersion)
+
+	properties := schema["properties"].(map[string]any)
+	properties["schema_version"] = map[string]any{"const": RunSchemaVersion}
+
+	results := []any{map[string]any{"type": "null"}}
+	for _, t := range runResultTypes {
+		result := jsonSchema(t)
+		result["title"] = t.String()
+		results = append(results, result)
+	}
+	repo := properties["repos"].(map[string]any)["items"].(map[string]any)
+	repo["properties"].(map[string]any)["result"] = map[string]any{"anyOf": results}
+	return schema
+}
+
+// jsonSchema describes a Go type using its encoding/json field names
+func jsonSchema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]any{"type": "integer", "description": "duration in nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": []string{"array", "null"}, "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = jsonSchema(field.Type)
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestRunSchema(t *testing.T) {
+	schema := RunSchema()
+
+	properties := schema["properties"].(map[string]any)
+	if got := properties["schema_version"].(map[string]any)["const"]; got != RunSchemaVersion {
+		t.Errorf("schema_version const = %v, want %d", got, RunSchemaVersion)
+	}
+	if required := schema["required"].([]string); !slices.Contains(required, "schema_version") || !slices.Contains(required, "repos") {
+		t.Errorf("required = %v, want schema_version and repos", required)
+	}
+
+	repo := properties["repos"].(map[string]any)["items"].(map[string]any)
+	result := repo["properties"].(map[string]any)["result"].(map[string]any)
+	if got := len(result["anyOf"].([]any)); got != len(runResultTypes)+1 {
+		t.Errorf("result alternatives = %d, want %d", got, len(runResultTypes)+1)
+	}
+
+	// Every field written in a run record must be described by the schema
+	run := NewRunRecord("goktor mr-repo pull", []string{"/ws/a"})
+	run.Set(0, RunStatusDone, map[string]int{"updated": 1}, nil)
+	run.SetResult(0, &PullResult{Branch: "main", Status: PullStatusFastForwarded})
+	data, _ := json.Marshal(run)
+	var record map[string]any
+	json.Unmarshal(data, &record)
+	for key := range record {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("run field %q missing from schema", key)
+		}
+	}
+	for key := range record["repos"].([]any)[0].(map[string]any) {
+		if _, ok := repo["properties"].(map[string]any)[key]; !ok {
+			t.Errorf("repo field %q missing from schema", key)
+		}
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("schema is not serializable: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+package service
+
+import "github.com/nanaki-93/goktor/model"
+
+// DefaultMinSize is the size above which PrintDirectories lists a directory
+// when no minimum is set
+const DefaultMinSize = 10 * model.GiB
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// Sort keys of the listings
+const (
+	// SortBySize lists the largest first
+	SortBySize = "size"
+	// SortByName lists in alphabetical order of the names
+	SortByName = "name"
+	// SortByCount lists the directories holding the most files first,
+	// counting the files of their subdirectories
+	SortByCount = "count"
+	// SortByModTime lists the most recently modified first; a directory is
+	// as recent as the latest of its own files
+	SortByModTime = "mtime"
+)
+
+var sortKeys = []string{SortBySize, SortByName, SortByCount, SortByModTime}
+
+// SortOrder is how listings are ordered. The zero value lists by size.
+type SortOrder struct {
+	Key string
+	// Reverse inverts the natural order of Key
+	Reverse bool
+}
+
+// ParseSortOrder validates a sort key, an empty key standing for size
+func ParseSortOrder(key string, reverse bool) (SortOrder, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		key = SortBySize
+	}
+	for _, known := range sortKeys {
+		if key == known {
+			return SortOrder{Key: key, Reverse: reverse}, nil
+		}
+	}
+	return SortOrder{}, fmt.Errorf("unknown sort key %q, expected one of %s", key, strings.Join(sortKeys, ", "))
+}
+
+// SortFiles orders files in place; SortByCount is not meaningful for files,
+// which are then ordered by size
+func SortFiles(files []model.FileSystem, order SortOrder) {
+	if order.Key == SortByCount {
+		order.Key = SortBySize
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return order.less(files[i], files[j], 0, 0, files[i].ModTime, files[j].ModTime)
+	})
+}
+
+// sortDirectories orders dirs in place
+func sortDirectories(dirs []model.Directory, order SortOrder) {
+	type sortedDir struct {
+		dir   model.Directory
+		count int64
+		mtime time.Time
+	}
+	keyed := make([]sortedDir, len(dirs))
+	for i, dir := range dirs {
+		keyed[i] = sortedDir{dir: dir, count: dir.FileCount, mtime: latestModTime(dir)}
+	}
+	sort.SliceStable(keyed, func(i, j int) bool {
+		a, b := keyed[i], keyed[j]
+		return order.less(a.dir.FileSystem, b.dir.FileSystem, a.count, b.count, a.mtime, b.mtime)
+	})
+	for i := range keyed {
+		dirs[i] = keyed[i].dir
+	}
+}
+
+// less reports whether a is listed before b; count and mtime are the values
+// of the entries for SortByCount and SortByModTime
+func (o SortOrder) less(a, b model.FileSystem, countA, countB int64, mtimeA, mtimeB time.Time) bool {
+	var before, after bool
+	switch o.Key {
+	case SortByName:
+		before, after = a.Name < b.Name, a.Name > b.Name
+	case SortByCount:
+		before, after = countA > countB, countA < countB
+	case SortByModTime:
+		before, after = mtimeA.After(mtimeB), mtimeA.Before(mtimeB)
+	default:
+		before, after = a.Size > b.Size, a.Size < b.Size
+	}
+	if o.Reverse {
+		return after
+	}
+	return before
+}
+
+// latestModTime returns the modification time of the most recently modified
+// file of dir, zero when it has none
+func latestModTime(dir model.Directory) time.Time {
+	var latest time.Time
+	for _, file := range dir.Files {
+		if file.ModTime.After(latest) {
+			latest = file.ModTime
+		}
+	}
+	return latest
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+func TestParseSortOrder(t *testing.T) {
+	if order, err := ParseSortOrder("", false); err != nil || order.Key != SortBySize {
+		t.Errorf("ParseSortOrder(\"\") = %+v, %v, want size", order, err)
+	}
+	if order, err := ParseSortOrder(" MTime ", true); err != nil || order.Key != SortByModTime || !order.Reverse {
+		t.Errorf("ParseSortOrder(\"MTime\") = %+v, %v, want reversed mtime", order, err)
+	}
+	if _, err := ParseSortOrder("age", false); err == nil {
+		t.Errorf("ParseSortOrder(\"age\") should fail")
+	}
+}
+
+func TestReorderDirectory_SortOrder(t *testing.T) {
+	now := time.Now()
+	file := func(size int64, age time.Duration) model.FileSystem {
+		return model.FileSystem{Size: size, ModTime: now.Add(-age)}
+	}
+	root := model.Directory{
+		FileSystem: model.FileSystem{Name: "root", Size: 10},
+		Files:      []model.FileSystem{file(10, time.Hour)},
+		SubDirs: []model.Directory{
+			{FileSystem: model.FileSystem{Name: "b", Size: 30}, Files: []model.FileSystem{file(30, 48*time.Hour)}},
+			{FileSystem: model.FileSystem{Name: "a", Size: 20}, Files: []model.FileSystem{file(5, time.Minute), file(15, 72*time.Hour)}},
+		},
+	}
+	for i := range root.SubDirs {
+		root.SubDirs[i].UpdateCounts()
+	}
+	root.UpdateCounts()
+
+	tests := []struct {
+		order SortOrder
+		want  []string
+	}{
+		{SortOrder{}, []string{"b", "a", "root"}},
+		{SortOrder{Key: SortBySize, Reverse: true}, []string{"root", "a", "b"}},
+		{SortOrder{Key: SortByName}, []string{"a", "b", "root"}},
+		{SortOrder{Key: SortByCount}, []string{"root", "a", "b"}},
+		{SortOrder{Key: SortByModTime}, []string{"a", "root", "b"}},
+		{SortOrder{Key: SortByModTime, Reverse: true}, []string{"b", "root", "a"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, dir := range ReorderDirectory(root, tt.order) {
+			got = append(got, dir.Name)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("ReorderDirectory(%+v) = %v, want %v", tt.order, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ReorderDirectory(%+v) = %v, want %v", tt.order, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSortFiles(t *testing.T) {
+	files := []model.FileSystem{{Name: "b", Size: 1}, {Name: "c", Size: 3}, {Name: "a", Size: 2}}
+	SortFiles(files, SortOrder{Key: SortByCount})
+	if files[0].Name != "c" || files[2].Name != "b" {
+		t.Errorf("count order = %v, want files by size", files)
+	}
+	SortFiles(files, SortOrder{Key: SortByName, Reverse: true})
+	if files[0].Name != "c" || files[1].Name != "b" || files[2].Name != "a" {
+		t.Errorf("reversed name order = %v", files)
+	}
+}
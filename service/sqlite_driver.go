@@ -0,0 +1,10 @@
+//go:build !netbsd
+
+package service
+
+import (
+	// registers the pure Go "sqlite" driver, no cgo needed
+	_ "modernc.org/sqlite"
+)
+
+const scanIndexSupported = true
@@ -0,0 +1,6 @@
+//go:build netbsd
+
+package service
+
+// modernc.org/sqlite does not build on NetBSD, so there is no scan index
+const scanIndexSupported = false
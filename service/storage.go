@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// StorageType classifies the device backing a scanned path
+type StorageType string
+
+const (
+	StorageAuto    StorageType = "auto"
+	StorageUnknown StorageType = "unknown"
+	StorageSSD     StorageType = "ssd"
+	StorageHDD     StorageType = "hdd"
+	StorageNetwork StorageType = "network"
+)
+
+// ParseStorageType validates a storage override from flags or config.
+// An empty value means StorageAuto.
+func ParseStorageType(value string) (StorageType, error) {
+	switch StorageType(strings.ToLower(strings.TrimSpace(value))) {
+	case "", StorageAuto:
+		return StorageAuto, nil
+	case StorageSSD:
+		return StorageSSD, nil
+	case StorageHDD:
+		return StorageHDD, nil
+	case StorageNetwork:
+		return StorageNetwork, nil
+	}
+	return "", fmt.Errorf("unknown storage type %q, expected auto, ssd, hdd or network", value)
+}
+
+// DetectStorage guesses the storage type of path. It returns StorageUnknown
+// when the platform heuristics cannot tell.
+func DetectStorage(path string) StorageType {
+	return detectStoragePlatform(path)
+}
+
+// WorkersForStorage returns the scanner concurrency suited to a storage type:
+// SSDs handle many parallel reads, spinning disks thrash on seeks and network
+// shares pay a round trip per request, so both are kept nearly sequential.
+func WorkersForStorage(storage StorageType) int {
+	switch storage {
+	case StorageSSD:
+		return max(maxWorkers, 4*runtime.NumCPU())
+	case StorageHDD:
+		return 1
+	case StorageNetwork:
+		return 2
+	}
+	return maxWorkers
+}
@@ -0,0 +1,67 @@
+//go:build linux
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Filesystem magic numbers of network filesystems, from statfs(2)
+var networkFilesystems = map[int64]bool{
+	0x6969:     true, // NFS
+	0x517B:     true, // SMB
+	0xFF534D42: true, // CIFS
+	0xFE534D42: true, // SMB2
+	0x564C:     true, // NCP
+	0x01021997: true, // v9fs
+	0x65735546: true, // FUSE (sshfs, rclone...)
+}
+
+func detectStoragePlatform(path string) StorageType {
+	var fsStat unix.Statfs_t
+	if err := unix.Statfs(path, &fsStat); err != nil {
+		return StorageUnknown
+	}
+	if networkFilesystems[int64(fsStat.Type)] {
+		return StorageNetwork
+	}
+
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return StorageUnknown
+	}
+	return rotationalStorage(sysBlockDevices, unix.Major(uint64(stat.Dev)), unix.Minor(uint64(stat.Dev)))
+}
+
+// sysBlockDevices holds a <major>:<minor> symlink into the sysfs device tree
+// for every block device
+const sysBlockDevices = "/sys/dev/block"
+
+// rotationalStorage reads the block queue rotational flag of a device.
+// Partitions expose it on their parent disk. The device symlink is resolved
+// first, as filepath.Join would otherwise clean the parent away lexically.
+func rotationalStorage(blockDevices string, major, minor uint32) StorageType {
+	device, err := filepath.EvalSymlinks(filepath.Join(blockDevices, fmt.Sprintf("%d:%d", major, minor)))
+	if err != nil {
+		return StorageUnknown
+	}
+	for _, candidate := range []string{
+		filepath.Join(device, "queue", "rotational"),
+		filepath.Join(device, "..", "queue", "rotational"),
+	} {
+		data, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(data)) == "1" {
+			return StorageHDD
+		}
+		return StorageSSD
+	}
+	return StorageUnknown
+}
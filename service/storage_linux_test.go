@@ -0,0 +1,37 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRotationalStorage checks a partition reads the rotational flag of its
+// parent disk through the device symlink, as sysfs lays it out
+func TestRotationalStorage(t *testing.T) {
+	sys := t.TempDir()
+	disk := filepath.Join(sys, "devices", "sda")
+	for _, dir := range []string{filepath.Join(disk, "queue"), filepath.Join(disk, "sda1"), filepath.Join(sys, "block")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(disk, "queue", "rotational"), []byte("1\n"), 0644); err != nil {
+		t.Fatalf("failed to write rotational flag: %v", err)
+	}
+	for link, target := range map[string]string{"8:0": "../devices/sda", "8:1": "../devices/sda/sda1"} {
+		if err := os.Symlink(target, filepath.Join(sys, "block", link)); err != nil {
+			t.Fatalf("failed to link %s: %v", link, err)
+		}
+	}
+
+	blockDevices := filepath.Join(sys, "block")
+	for _, minor := range []uint32{0, 1} {
+		if got := rotationalStorage(blockDevices, 8, minor); got != StorageHDD {
+			t.Errorf("rotationalStorage(8, %d) = %q, want %q", minor, got, StorageHDD)
+		}
+	}
+	if got := rotationalStorage(blockDevices, 8, 2); got != StorageUnknown {
+		t.Errorf("rotationalStorage() of a missing device = %q, want %q", got, StorageUnknown)
+	}
+}
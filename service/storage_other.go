@@ -0,0 +1,7 @@
+//go:build !linux && !windows
+
+package service
+
+func detectStoragePlatform(path string) StorageType {
+	return StorageUnknown
+}
@@ -0,0 +1,43 @@
+package service
+
+import "testing"
+
+func TestParseStorageType(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    StorageType
+		wantErr bool
+	}{
+		{"", StorageAuto, false},
+		{"auto", StorageAuto, false},
+		{"SSD", StorageSSD, false},
+		{" hdd ", StorageHDD, false},
+		{"network", StorageNetwork, false},
+		{"tape", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStorageType(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStorageType(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStorageType(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWorkersForStorage(t *testing.T) {
+	if got := WorkersForStorage(StorageHDD); got != 1 {
+		t.Errorf("HDD workers = %d, want 1", got)
+	}
+	if got := WorkersForStorage(StorageNetwork); got != 2 {
+		t.Errorf("network workers = %d, want 2", got)
+	}
+	if got := WorkersForStorage(StorageUnknown); got != maxWorkers {
+		t.Errorf("unknown workers = %d, want %d", got, maxWorkers)
+	}
+	if got := WorkersForStorage(StorageSSD); got < maxWorkers {
+		t.Errorf("SSD workers = %d, want at least %d", got, maxWorkers)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build windows
+
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	ioctlStorageQueryProperty        = 0x2D1400
+	storageDeviceSeekPenaltyProperty = 7
+	propertyStandardQuery            = 0
+)
+
+type storagePropertyQuery struct {
+	PropertyId           uint32
+	QueryType            uint32
+	AdditionalParameters [1]byte
+}
+
+type deviceSeekPenaltyDescriptor struct {
+	Version           uint32
+	Size              uint32
+	IncursSeekPenalty byte
+}
+
+func detectStoragePlatform(path string) StorageType {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return StorageUnknown
+	}
+	volume := filepath.VolumeName(abs)
+	if strings.HasPrefix(volume, `\\`) {
+		return StorageNetwork
+	}
+	if volume == "" {
+		return StorageUnknown
+	}
+
+	root, err := windows.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return StorageUnknown
+	}
+	if windows.GetDriveType(root) == windows.DRIVE_REMOTE {
+		return StorageNetwork
+	}
+	return seekPenaltyStorage(volume)
+}
+
+// seekPenaltyStorage asks the volume whether it incurs a seek penalty, which
+// only rotating disks do. No access rights are needed for this query.
+func seekPenaltyStorage(volume string) StorageType {
+	device, err := windows.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return StorageUnknown
+	}
+	handle, err := windows.CreateFile(device, 0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return StorageUnknown
+	}
+	defer windows.CloseHandle(handle)
+
+	query := storagePropertyQuery{PropertyId: storageDeviceSeekPenaltyProperty, QueryType: propertyStandardQuery}
+	var descriptor deviceSeekPenaltyDescriptor
+	var returned uint32
+	if err := windows.DeviceIoControl(handle, ioctlStorageQueryProperty,
+		(*byte)(unsafe.Pointer(&query)), uint32(unsafe.Sizeof(query)),
+		(*byte)(unsafe.Pointer(&descriptor)), uint32(unsafe.Sizeof(descriptor)),
+		&returned, nil); err != nil {
+		return StorageUnknown
+	}
+	if descriptor.IncursSeekPenalty != 0 {
+		return StorageHDD
+	}
+	return StorageSSD
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// visitedDirs records the real path of every directory entered by a scan that
+// follows symlinks, so a directory reachable through several links, or through
+// a link to one of its ancestors, is scanned once
+type visitedDirs struct {
+	mu    sync.Mutex
+	paths map[string]bool
+	// skipped are the directories not scanned because their real path was
+	// already visited, such as the links closing a cycle
+	skipped []model.FileSystem
+}
+
+func (v *visitedDirs) reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.paths = map[string]bool{}
+	v.skipped = nil
+}
+
+// enter marks realPath as visited, returning false when it already was
+func (v *visitedDirs) enter(realPath string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.paths[realPath] {
+		return false
+	}
+	v.paths[realPath] = true
+	return true
+}
+
+func (v *visitedDirs) skip(dir model.FileSystem) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.skipped = append(v.skipped, dir)
+}
+
+func (v *visitedDirs) listSkipped() []model.FileSystem {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]model.FileSystem(nil), v.skipped...)
+}
+
+// SetFollowSymlinks makes the recursive scans descend into symlinked
+// directories. Symlinks to files are never followed.
+func (fs *FileSystemService) SetFollowSymlinks(follow bool) {
+	fs.followSymlinks = follow
+}
+
+// RevisitedDirs returns the directories skipped by the last recursive scan
+// because they were already scanned through another path: symlinks back to an
+// ancestor, or several links to the same directory. Which path of a directory
+// is scanned first depends on scheduling; its content is counted once either way.
+func (fs *FileSystemService) RevisitedDirs() []model.FileSystem {
+	return fs.visited.listSkipped()
+}
+
+// isSymlinkedDir reports whether path is a symlink whose target is a directory
+func isSymlinkedDir(entry os.DirEntry, path string) bool {
+	if entry.Type()&os.ModeSymlink == 0 {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// enterDir reports whether a recursive scan should read path. When following
+// symlinks, a directory whose real path was already visited is recorded and skipped.
+func (fs *FileSystemService) enterDir(path string) bool {
+	if !fs.followSymlinks {
+		return true
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		fs.logger.Debug("failed to resolve directory", "path", path, "error", err)
+		return false
+	}
+	if fs.visited.enter(realPath) {
+		return true
+	}
+	fs.logger.Debug("skipping already visited directory", "path", path, "target", realPath)
+	fs.visited.skip(dirLinkModel(path))
+	return false
+}
+
+// dirLinkModel describes the directory or directory symlink at path without reading it
+func dirLinkModel(path string) model.FileSystem {
+	fullPath, err := filepath.Abs(path)
+	if err != nil {
+		fullPath = path
+	}
+	dir := model.FileSystem{Name: filepath.Base(path), FullPath: fullPath, IsDir: true}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		dir.Symlink = true
+		dir.LinkTarget, _ = os.Readlink(path)
+	}
+	return dir
+}
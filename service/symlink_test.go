@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nanaki-93/goktor/model"
+)
+
+// newSymlinkTree builds root/data/file.txt, root/data/back linking to root and
+// root/alias linking to data, skipping the test where symlinks cannot be created
+func newSymlinkTree(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temp dir: %v", err)
+	}
+	data := filepath.Join(root, "data")
+	if err := os.MkdirAll(data, 0755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(data, "file.txt"), make([]byte, 100), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Symlink(root, filepath.Join(data, "back")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(data, filepath.Join(root, "alias")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	return root
+}
+
+func TestFileSystemService_SymlinksNotFollowed(t *testing.T) {
+	root := newSymlinkTree(t)
+	fs := NewServiceWithLimit(0)
+
+	dir, err := fs.ListDirectories(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ListDirectories() error = %v", err)
+	}
+	if got := len(dir.FlattenDirectory()); got != 2 {
+		t.Errorf("scanned %d directories, want 2", got)
+	}
+
+	links := map[string]model.FileSystem{}
+	for _, d := range dir.FlattenDirectory() {
+		for _, file := range d.Files {
+			if file.Symlink {
+				links[file.Name] = file
+			}
+		}
+	}
+	if len(links) != 2 {
+		t.Fatalf("found symlinks %v, want alias and back", links)
+	}
+	if links["alias"].LinkTarget != filepath.Join(root, "data") {
+		t.Errorf("alias target = %q, want %q", links["alias"].LinkTarget, filepath.Join(root, "data"))
+	}
+	if skipped := fs.RevisitedDirs(); len(skipped) != 0 {
+		t.Errorf("RevisitedDirs() = %v, want none", skipped)
+	}
+}
+
+func TestFileSystemService_SymlinksFollowed(t *testing.T) {
+	root := newSymlinkTree(t)
+	fs := NewServiceWithLimit(0)
+	fs.SetFollowSymlinks(true)
+
+	dir, err := fs.ListDirectories(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ListDirectories() error = %v", err)
+	}
+	if got := len(dir.FlattenDirectory()); got != 2 {
+		t.Errorf("scanned %d directories, want root and data once", got)
+	}
+	var size int64
+	for _, d := range dir.FlattenDirectory() {
+		size += d.Size
+	}
+	if size != 100 {
+		t.Errorf("scanned size = %d, want 100: the file must be counted once", size)
+	}
+
+	skipped := fs.RevisitedDirs()
+	if len(skipped) != 2 {
+		t.Fatalf("RevisitedDirs() = %v, want the back link and one of data and alias", skipped)
+	}
+	var back bool
+	for _, d := range skipped {
+		if d.Name == "back" {
+			back = d.Symlink && d.LinkTarget == root
+		}
+	}
+	if !back {
+		t.Errorf("RevisitedDirs() = %v, want the back link to %s", skipped, root)
+	}
+
+	// a second scan starts from an empty visited set
+	if _, err := fs.ListDirectories(context.Background(), root); err != nil {
+		t.Fatalf("second ListDirectories() error = %v", err)
+	}
+	if got := len(fs.RevisitedDirs()); got != 2 {
+		t.Errorf("second scan RevisitedDirs() has %d entries, want 2", got)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UsageRecord is one line of the local usage log
+type UsageRecord struct {
+	Time     time.Time      `json:"time"`
+	Command  string         `json:"command"`
+	Duration time.Duration  `json:"duration"`
+	Success  bool           `json:"success"`
+	Counts   map[string]int `json:"counts,omitempty"`
+}
+
+// UsageSummary aggregates the usage records of one command
+type UsageSummary struct {
+	Command       string
+	Runs          int
+	Failures      int
+	TotalDuration time.Duration
+	Counts        map[string]int
+}
+
+// UsageTracker collects result counts while a command runs. A nil tracker
+// ignores every call, so commands can count unconditionally.
+type UsageTracker struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{counts: map[string]int{}}
+}
+
+// Count adds n to the named result counter
+func (u *UsageTracker) Count(key string, n int) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.counts[key] += n
+}
+
+// Counts returns a copy of the collected counters
+func (u *UsageTracker) Counts() map[string]int {
+	if u == nil {
+		return nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	counts := make(map[string]int, len(u.counts))
+	for key, value := range u.counts {
+		counts[key] = value
+	}
+	return counts
+}
+
+// DefaultUsageLogPath returns ~/.goktor/usage.log
+func DefaultUsageLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".goktor", "usage.log"), nil
+}
+
+// AppendUsage appends a record as a JSON line to the usage log at path
+func AppendUsage(path string, record UsageRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create usage log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer file.Close()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage record: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage log: %w", err)
+	}
+	return nil
+}
+
+// ReadUsage reads every record of the usage log, skipping malformed lines
+func ReadUsage(path string) ([]UsageRecord, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer file.Close()
+
+	var records []UsageRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage log: %w", err)
+	}
+	return records, nil
+}
+
+// SummarizeUsage groups records by command, most used first
+func SummarizeUsage(records []UsageRecord) []UsageSummary {
+	byCommand := map[string]*UsageSummary{}
+	for _, record := range records {
+		summary, ok := byCommand[record.Command]
+		if !ok {
+			summary = &UsageSummary{Command: record.Command, Counts: map[string]int{}}
+			byCommand[record.Command] = summary
+		}
+		summary.Runs++
+		if !record.Success {
+			summary.Failures++
+		}
+		summary.TotalDuration += record.Duration
+		for key, value := range record.Counts {
+			summary.Counts[key] += value
+		}
+	}
+
+	summaries := make([]UsageSummary, 0, len(byCommand))
+	for _, summary := range byCommand {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Runs != summaries[j].Runs {
+			return summaries[i].Runs > summaries[j].Runs
+		}
+		return summaries[i].Command < summaries[j].Command
+	})
+	return summaries
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageLogRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "usage.log")
+
+	records := []UsageRecord{
+		{Command: "goktor mr-repo update-branches", Duration: 2 * time.Second, Success: true, Counts: map[string]int{"updated": 3}},
+		{Command: "goktor mr-repo update-branches", Duration: 4 * time.Second, Success: false, Counts: map[string]int{"updated": 1}},
+		{Command: "goktor file-list", Duration: time.Second, Success: true},
+	}
+	for _, record := range records {
+		if err := AppendUsage(path, record); err != nil {
+			t.Fatalf("AppendUsage() error = %v", err)
+		}
+	}
+
+	got, err := ReadUsage(path)
+	if err != nil {
+		t.Fatalf("ReadUsage() error = %v", err)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("got %d records, want %d", len(got), len(records))
+	}
+
+	summaries := SummarizeUsage(got)
+	if len(summaries) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(summaries))
+	}
+	first := summaries[0]
+	if first.Command != "goktor mr-repo update-branches" || first.Runs != 2 || first.Failures != 1 {
+		t.Errorf("unexpected summary %+v", first)
+	}
+	if first.TotalDuration != 6*time.Second || first.Counts["updated"] != 4 {
+		t.Errorf("unexpected totals %+v", first)
+	}
+}
+
+func TestUsageTracker_NilIsNoop(t *testing.T) {
+	var tracker *UsageTracker
+	tracker.Count("updated", 1)
+	if tracker.Counts() != nil {
+		t.Error("nil tracker returned counts")
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nanaki-93/goktor/model"
+)
+
+// SizeIndex keeps the size of every directory of a scanned tree, counted as in
+// ListDirectories: the files directly in the directory. It is updated in place
+// by UpdateIndex and is safe for concurrent use.
+type SizeIndex struct {
+	mu    sync.Mutex
+	files map[string]int64
+	dirs  map[string]int64
+}
+
+// NewSizeIndex indexes the directories and files of a scan result
+func NewSizeIndex(root model.Directory) *SizeIndex {
+	index := &SizeIndex{files: map[string]int64{}, dirs: map[string]int64{}}
+	index.addDirectory(root)
+	return index
+}
+
+func (idx *SizeIndex) addDirectory(dir model.Directory) {
+	if dir.FullPath == "" {
+		return
+	}
+	idx.mu.Lock()
+	idx.dirs[dir.FullPath] = 0
+	for _, file := range dir.Files {
+		idx.files[file.FullPath] = file.Size
+		idx.dirs[dir.FullPath] += file.Size
+	}
+	idx.mu.Unlock()
+	for _, sub := range dir.SubDirs {
+		idx.addDirectory(sub)
+	}
+}
+
+// setFile records the current size of the file at path
+func (idx *SizeIndex) setFile(path string, size int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	parent := filepath.Dir(path)
+	if _, ok := idx.dirs[parent]; !ok {
+		return
+	}
+	idx.dirs[parent] += size - idx.files[path]
+	idx.files[path] = size
+}
+
+// remove drops the file or the directory tree at path
+func (idx *SizeIndex) remove(path string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if size, ok := idx.files[path]; ok {
+		delete(idx.files, path)
+		idx.dirs[filepath.Dir(path)] -= size
+		return
+	}
+	if _, ok := idx.dirs[path]; !ok {
+		return
+	}
+	prefix := path + string(filepath.Separator)
+	delete(idx.dirs, path)
+	for dir := range idx.dirs {
+		if strings.HasPrefix(dir, prefix) {
+			delete(idx.dirs, dir)
+		}
+	}
+	for file := range idx.files {
+		if strings.HasPrefix(file, prefix) {
+			delete(idx.files, file)
+		}
+	}
+}
+
+func (idx *SizeIndex) hasDir(path string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	_, ok := idx.dirs[path]
+	return ok
+}
+
+// Dirs returns the path of every indexed directory
+func (idx *SizeIndex) Dirs() []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	dirs := make([]string, 0, len(idx.dirs))
+	for dir := range idx.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Top returns the n largest directories, largest first
+func (idx *SizeIndex) Top(n int) []model.Directory {
+	idx.mu.Lock()
+	result := make([]model.Directory, 0, len(idx.dirs))
+	for path, size := range idx.dirs {
+		result = append(result, model.Directory{FileSystem: model.FileSystem{
+			Name:     filepath.Base(path),
+			FullPath: path,
+			IsDir:    true,
+			Size:     size,
+		}})
+	}
+	idx.mu.Unlock()
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Size != result[j].Size {
+			return result[i].Size > result[j].Size
+		}
+		return result[i].FullPath < result[j].FullPath
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// UpdateIndex applies a change of path to index without rescanning the tree:
+// a file is stat-ed again, a new directory is scanned on its own and a missing
+// path is dropped with everything below it. It returns the directories added
+// to the index.
+func (fs *FileSystemService) UpdateIndex(ctx context.Context, index *SizeIndex, path string) ([]string, error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		index.remove(path)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		index.setFile(path, info.Size())
+		return nil, nil
+	}
+	if index.hasDir(path) {
+		return nil, nil
+	}
+
+	dir, err := fs.ListDirectories(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	added := NewSizeIndex(dir).Dirs()
+	index.addDirectory(dir)
+	return added, nil
+}
+
+// WatchDirectories scans path, then keeps the resulting index up to date from
+// filesystem notifications until ctx is done. onChange is called with the
+// index after the initial scan, then at most once per interval when something
+// changed. Directories that cannot be watched, for instance past the inotify
+// watch limit on Linux, are logged and keep their last known size.
+func (fs *FileSystemService) WatchDirectories(ctx context.Context, path string, interval time.Duration, onChange func(*SizeIndex)) error {
+	root, err := fs.ListDirectories(ctx, path)
+	if err != nil {
+		return err
+	}
+	index := NewSizeIndex(root)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watcher: %w", err)
+	}
+	defer watcher.Close()
+	fs.watchDirs(watcher, index.Dirs())
+	onChange(index)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	changed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			added, err := fs.UpdateIndex(ctx, index, event.Name)
+			if err != nil {
+				fs.logger.Debug("failed to update index", "path", event.Name, "error", err)
+				continue
+			}
+			fs.watchDirs(watcher, added)
+			changed = true
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fs.logger.Warn("watcher error", "error", err)
+		case <-ticker.C:
+			if changed {
+				changed = false
+				onChange(index)
+			}
+		}
+	}
+}
+
+// watchDirs adds dirs to watcher, logging the failures once
+func (fs *FileSystemService) watchDirs(watcher *fsnotify.Watcher, dirs []string) {
+	var failed int
+	var firstErr error
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			if failed == 0 {
+				firstErr = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		fs.logger.Warn("some directories are not watched", "count", failed, "error", firstErr)
+	}
+}
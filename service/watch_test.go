@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func indexSizes(index *SizeIndex) map[string]int64 {
+	sizes := map[string]int64{}
+	for _, dir := range index.Top(0) {
+		sizes[dir.FullPath] = dir.Size
+	}
+	return sizes
+}
+
+func TestFileSystemService_UpdateIndex(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("failed to create sub: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "a.bin"), make([]byte, 100), 0644); err != nil {
+		t.Fatalf("failed to write a.bin: %v", err)
+	}
+
+	fs := NewServiceWithLimit(0)
+	ctx := context.Background()
+	dir, err := fs.ListDirectories(ctx, root)
+	if err != nil {
+		t.Fatalf("ListDirectories() error = %v", err)
+	}
+	index := NewSizeIndex(dir)
+	if got := indexSizes(index)[sub]; got != 100 {
+		t.Fatalf("sub size = %d, want 100", got)
+	}
+
+	// a file grows
+	if err := os.WriteFile(filepath.Join(sub, "a.bin"), make([]byte, 250), 0644); err != nil {
+		t.Fatalf("failed to grow a.bin: %v", err)
+	}
+	if _, err := fs.UpdateIndex(ctx, index, filepath.Join(sub, "a.bin")); err != nil {
+		t.Fatalf("UpdateIndex() error = %v", err)
+	}
+	if got := indexSizes(index)[sub]; got != 250 {
+		t.Errorf("sub size after growth = %d, want 250", got)
+	}
+
+	// a directory appears with content
+	fresh := filepath.Join(root, "fresh")
+	if err := os.MkdirAll(filepath.Join(fresh, "deep"), 0755); err != nil {
+		t.Fatalf("failed to create fresh: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(fresh, "deep", "b.bin"), make([]byte, 40), 0644); err != nil {
+		t.Fatalf("failed to write b.bin: %v", err)
+	}
+	added, err := fs.UpdateIndex(ctx, index, fresh)
+	if err != nil {
+		t.Fatalf("UpdateIndex() error = %v", err)
+	}
+	if len(added) != 2 {
+		t.Errorf("added = %v, want fresh and fresh/deep", added)
+	}
+	if got := indexSizes(index)[filepath.Join(fresh, "deep")]; got != 40 {
+		t.Errorf("fresh/deep size = %d, want 40", got)
+	}
+
+	// a file then a whole tree disappear
+	if err := os.Remove(filepath.Join(sub, "a.bin")); err != nil {
+		t.Fatalf("failed to remove a.bin: %v", err)
+	}
+	if _, err := fs.UpdateIndex(ctx, index, filepath.Join(sub, "a.bin")); err != nil {
+		t.Fatalf("UpdateIndex() error = %v", err)
+	}
+	if err := os.RemoveAll(fresh); err != nil {
+		t.Fatalf("failed to remove fresh: %v", err)
+	}
+	if _, err := fs.UpdateIndex(ctx, index, fresh); err != nil {
+		t.Fatalf("UpdateIndex() error = %v", err)
+	}
+	sizes := indexSizes(index)
+	if sizes[sub] != 0 {
+		t.Errorf("sub size after removal = %d, want 0", sizes[sub])
+	}
+	if _, ok := sizes[filepath.Join(fresh, "deep")]; ok {
+		t.Errorf("removed directory still indexed: %v", sizes)
+	}
+}
+
+func TestFileSystemService_WatchDirectories(t *testing.T) {
+	root := t.TempDir()
+	fs := NewServiceWithLimit(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	updates := make(chan map[string]int64, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- fs.WatchDirectories(ctx, root, 20*time.Millisecond, func(index *SizeIndex) {
+			updates <- indexSizes(index)
+		})
+	}()
+
+	select {
+	case <-updates:
+	case <-ctx.Done():
+		t.Fatalf("no initial index")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "new.bin"), make([]byte, 64), 0644); err != nil {
+		t.Fatalf("failed to write new.bin: %v", err)
+	}
+	for {
+		select {
+		case sizes := <-updates:
+			if sizes[root] != 64 {
+				continue
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("WatchDirectories() error = %v", err)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatalf("the new file was never indexed")
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ValidateWorkspaces checks the names and path patterns of the workspaces of the config
+func ValidateWorkspaces(workspaces map[string][]string) error {
+	for name, paths := range workspaces {
+		if name == "" {
+			return fmt.Errorf("workspace name cannot be empty")
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("workspace %s has no repository", name)
+		}
+		for _, path := range paths {
+			if _, err := filepath.Match(path, ""); err != nil {
+				return fmt.Errorf("workspace %s: invalid path pattern %q: %w", name, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WorkspaceNames returns the names of the workspaces of the config, sorted
+func WorkspaceNames(cfg *Config) []string {
+	names := make([]string, 0, len(cfg.Workspaces))
+	for name := range cfg.Workspaces {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// WorkspaceRepos returns the absolute paths of the repositories of the
+// workspace named name in the config. Its entries are repository paths or
+// glob patterns such as "~/oss/*", a leading ~ standing for the home
+// directory; patterns only select repositories, worktrees or bare ones, and
+// leave out the other files and directories they match. Repository paths that
+// do not exist are returned in missing rather than failing the whole workspace.
+func WorkspaceRepos(cfg *Config, name string) (repos []string, missing []string, err error) {
+	paths, ok := cfg.Workspaces[name]
+	if !ok {
+		names := WorkspaceNames(cfg)
+		if len(names) == 0 {
+			return nil, nil, fmt.Errorf("unknown workspace %q, no workspace is defined in the config", name)
+		}
+		return nil, nil, fmt.Errorf("unknown workspace %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+
+	add := func(path string) {
+		if !slices.Contains(repos, path) {
+			repos = append(repos, path)
+		}
+	}
+	for _, path := range paths {
+		path, err := expandHome(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if path, err = filepath.Abs(path); err != nil {
+			return nil, nil, fmt.Errorf("workspace %s: invalid path %s: %w", name, path, err)
+		}
+		if !hasGlobMeta(path) {
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				missing = append(missing, path)
+				continue
+			}
+			add(path)
+			continue
+		}
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("workspace %s: invalid path pattern %s: %w", name, path, err)
+		}
+		for _, match := range matches {
+			if DetectRepoKind(match) != RepoKindNone {
+				add(match)
+			}
+		}
+	}
+	return repos, missing, nil
+}
+
+// expandHome replaces a leading ~ of path with the home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// hasGlobMeta reports whether path holds a filepath.Match metacharacter
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
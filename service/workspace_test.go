@@ -0,0 +1,52 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestWorkspaceRepos(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, dir := range []string{"work/api", "oss/docs"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	if _, err := git.PlainInit(filepath.Join(home, "oss", "lib-a"), false); err != nil {
+		t.Fatalf("failed to create lib-a: %v", err)
+	}
+	if _, err := git.PlainInit(filepath.Join(home, "oss", "lib-b"), true); err != nil {
+		t.Fatalf("failed to create lib-b: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "oss", "notes.txt"), nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	cfg := &Config{Workspaces: map[string][]string{
+		"all": {"~/work/api", "~/oss/*", "~/oss/lib-a", "~/work/gone"},
+	}}
+
+	repos, missing, err := WorkspaceRepos(cfg, "all")
+	if err != nil {
+		t.Fatalf("WorkspaceRepos() error = %v", err)
+	}
+	want := []string{filepath.Join(home, "work", "api"), filepath.Join(home, "oss", "lib-a"), filepath.Join(home, "oss", "lib-b")}
+	if !slices.Equal(repos, want) {
+		t.Errorf("repos = %v, want %v", repos, want)
+	}
+	if !slices.Equal(missing, []string{filepath.Join(home, "work", "gone")}) {
+		t.Errorf("missing = %v", missing)
+	}
+
+	if _, _, err := WorkspaceRepos(cfg, "infra"); err == nil {
+		t.Errorf("WorkspaceRepos() should reject an unknown workspace")
+	}
+	if err := ValidateWorkspaces(map[string][]string{"bad": {"~/oss/["}}); err == nil {
+		t.Errorf("ValidateWorkspaces() should reject a malformed pattern")
+	}
+}